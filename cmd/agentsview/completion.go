@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// completionCommand describes a subcommand and its flags for
+// shell completion scripts.
+type completionCommand struct {
+	name  string
+	desc  string
+	flags []completionFlag
+}
+
+type completionFlag struct {
+	name string
+	desc string
+}
+
+// serveCompletionFlags are completed both after "serve" and
+// for the bare command, which also starts the server.
+var serveCompletionFlags = []completionFlag{
+	{"host", "Host to bind to"},
+	{"port", "Port to listen on"},
+	{"no-browser", "Don't open browser on startup"},
+	{"log-level", "Minimum log level"},
+	{"tls-cert", "PEM certificate file"},
+	{"tls-key", "PEM private key file"},
+	{"demo", "Serve a generated demo dataset"},
+	{"sync-workers", "Session files to parse at once"},
+}
+
+// completionCommands mirrors the subcommands dispatched in
+// main and the flags each one parses.
+var completionCommands = []completionCommand{
+	{name: "serve", desc: "Start the server", flags: serveCompletionFlags},
+	{name: "prune", desc: "Delete sessions matching filters", flags: []completionFlag{
+		{"project", "Sessions whose project contains this substring"},
+		{"agent", "Sessions from this agent"},
+		{"orphaned", "Sessions whose source file no longer exists"},
+		{"max-messages", "Sessions with at most N user messages"},
+		{"before", "Sessions that ended before this date"},
+		{"first-message", "Sessions whose first message starts with this text"},
+		{"include-chain", "Prune parent/child session chains as units"},
+		{"interactive", "Pick which matching sessions to delete"},
+		{"delete-files", "Also delete source files of local sessions"},
+		{"dry-run", "Show what would be pruned without deleting"},
+		{"yes", "Skip confirmation prompt"},
+	}},
+	{name: "scan-secrets", desc: "Report sessions containing likely secrets", flags: []completionFlag{
+		{"project", "Only scan projects containing this substring"},
+		{"redact", "Replace detected secrets in the database"},
+		{"prune", "Delete affected sessions from the database"},
+		{"delete-files", "With --prune, also delete local source files"},
+		{"json", "Print the report as JSON"},
+		{"yes", "Skip confirmation prompt"},
+	}},
+	{name: "import", desc: "Import exported sessions from files", flags: []completionFlag{
+		{"format", "Input format"},
+		{"machine", "Machine name for imported sessions"},
+		{"project", "Override the project name from the file"},
+	}},
+	{name: "export", desc: "Export sessions to Markdown, HTML, or JSON", flags: []completionFlag{
+		{"format", "Output format"},
+		{"project", "Export every session in this project"},
+		{"o", "Output file or directory"},
+	}},
+	{name: "push", desc: "Send sessions to a central agentsview server", flags: []completionFlag{
+		{"url", "Server to push to"},
+		{"token", "Server's ingest token"},
+		{"machine", "Machine name for this machine's sessions"},
+		{"project", "Only push this project"},
+		{"dry-run", "Report what would be pushed without sending"},
+	}},
+	{name: "merge", desc: "Import sessions from another agentsview database"},
+	{name: "shadow", desc: "Reparse sampled files and compare with stored sessions", flags: []completionFlag{
+		{"sample", "Session files to reparse (0 for all)"},
+		{"json", "Print the report as JSON"},
+	}},
+	{name: "mcp", desc: "Serve session data to coding agents over MCP"},
+	{name: "update", desc: "Check for and install updates", flags: []completionFlag{
+		{"check", "Check for updates without installing"},
+		{"yes", "Install without confirmation prompt"},
+		{"force", "Force check (ignore cache)"},
+	}},
+	{name: "completion", desc: "Print a shell completion script"},
+	{name: "version", desc: "Show version information"},
+	{name: "help", desc: "Show help"},
+}
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr,
+			"usage: agentsview completion bash|zsh|fish")
+		os.Exit(1)
+	}
+	if err := writeCompletion(os.Stdout, args[0]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// writeCompletion writes the completion script for shell to w.
+func writeCompletion(w io.Writer, shell string) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion()
+	case "zsh":
+		script = zshCompletion()
+	case "fish":
+		script = fishCompletion()
+	default:
+		return fmt.Errorf(
+			"unsupported shell %q (want bash, zsh, or fish)", shell,
+		)
+	}
+	_, err := io.WriteString(w, script)
+	return err
+}
+
+func commandNames() string {
+	names := make([]string, len(completionCommands))
+	for i, c := range completionCommands {
+		names[i] = c.name
+	}
+	return strings.Join(names, " ")
+}
+
+func flagWords(flags []completionFlag) string {
+	words := make([]string, len(flags))
+	for i, f := range flags {
+		words[i] = "-" + f.name
+	}
+	return strings.Join(words, " ")
+}
+
+func bashCompletion() string {
+	var b strings.Builder
+	b.WriteString(`# bash completion for agentsview
+# Load with: source <(agentsview completion bash)
+_agentsview() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local words
+    if [[ ${COMP_CWORD} -eq 1 ]]; then
+`)
+	fmt.Fprintf(&b, "        words=%q\n", commandNames()+" "+
+		flagWords(serveCompletionFlags))
+	b.WriteString(`    else
+        case "${COMP_WORDS[1]}" in
+`)
+	for _, c := range completionCommands {
+		switch {
+		case c.name == "completion":
+			fmt.Fprintf(&b, "            completion) words=%q ;;\n",
+				strings.Join(completionShells, " "))
+		case len(c.flags) > 0:
+			fmt.Fprintf(&b, "            %s) words=%q ;;\n",
+				c.name, flagWords(c.flags))
+		}
+	}
+	fmt.Fprintf(&b, "            *) words=%q ;;\n",
+		flagWords(serveCompletionFlags))
+	b.WriteString(`        esac
+    fi
+    COMPREPLY=($(compgen -W "${words}" -- "${cur}"))
+}
+complete -o default -F _agentsview agentsview
+`)
+	return b.String()
+}
+
+func zshCompletion() string {
+	var b strings.Builder
+	b.WriteString(`#compdef agentsview
+# zsh completion for agentsview
+# Load with: source <(agentsview completion zsh)
+_agentsview() {
+    if (( CURRENT == 2 )); then
+        local -a commands
+        commands=(
+`)
+	for _, c := range completionCommands {
+		fmt.Fprintf(&b, "            %s\n", zshQuote(c.name+":"+c.desc))
+	}
+	b.WriteString(`        )
+        _describe command commands
+`)
+	fmt.Fprintf(&b, "        compadd -- %s\n",
+		flagWords(serveCompletionFlags))
+	b.WriteString(`        return
+    fi
+    case ${words[2]} in
+`)
+	for _, c := range completionCommands {
+		switch {
+		case c.name == "completion":
+			fmt.Fprintf(&b, "        completion) compadd -- %s ;;\n",
+				strings.Join(completionShells, " "))
+		case c.name == "import":
+			fmt.Fprintf(&b, "        import) compadd -- %s; _files ;;\n",
+				flagWords(c.flags))
+		case len(c.flags) > 0:
+			fmt.Fprintf(&b, "        %s) compadd -- %s ;;\n",
+				c.name, flagWords(c.flags))
+		}
+	}
+	fmt.Fprintf(&b, "        *) compadd -- %s ;;\n",
+		flagWords(serveCompletionFlags))
+	// Autoloaded from fpath the file body is the completion
+	// function; sourced, it registers the function instead.
+	b.WriteString(`    esac
+}
+if [[ "${funcstack[1]}" == _agentsview ]]; then
+    _agentsview "$@"
+else
+    compdef _agentsview agentsview
+fi
+`)
+	return b.String()
+}
+
+func fishCompletion() string {
+	var b strings.Builder
+	b.WriteString("# fish completion for agentsview\n" +
+		"# Load with: agentsview completion fish | source\n")
+	b.WriteString("complete -c agentsview -f\n")
+	for _, c := range completionCommands {
+		fmt.Fprintf(&b,
+			"complete -c agentsview -n __fish_use_subcommand -a %s -d %s\n",
+			c.name, fishQuote(c.desc))
+	}
+	for _, f := range serveCompletionFlags {
+		fmt.Fprintf(&b,
+			"complete -c agentsview -n __fish_use_subcommand -o %s -d %s\n",
+			f.name, fishQuote(f.desc))
+	}
+	for _, c := range completionCommands {
+		cond := fishQuote("__fish_seen_subcommand_from " + c.name)
+		if c.name == "completion" {
+			fmt.Fprintf(&b, "complete -c agentsview -n %s -a %s\n",
+				cond, fishQuote(strings.Join(completionShells, " ")))
+			continue
+		}
+		for _, f := range c.flags {
+			fmt.Fprintf(&b, "complete -c agentsview -n %s -o %s -d %s\n",
+				cond, f.name, fishQuote(f.desc))
+		}
+	}
+	fmt.Fprintf(&b, "complete -c agentsview -n %s -F\n",
+		fishQuote("__fish_seen_subcommand_from import"))
+	return b.String()
+}
+
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
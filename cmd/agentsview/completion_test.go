@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/config"
+)
+
+func TestWriteCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeCompletion(&buf, shell); err != nil {
+				t.Fatalf("writeCompletion: %v", err)
+			}
+			out := buf.String()
+			for _, want := range []string{
+				"agentsview", "scan-secrets", "interactive",
+				"max-messages", "tls-cert",
+			} {
+				if !strings.Contains(out, want) {
+					t.Errorf("script missing %q", want)
+				}
+			}
+		})
+	}
+
+	err := writeCompletion(&bytes.Buffer{}, "powershell")
+	if err == nil || !strings.Contains(err.Error(), "unsupported shell") {
+		t.Errorf("err = %v, want unsupported shell", err)
+	}
+}
+
+// TestCompletionFlagsMatchParsers guards against the completion
+// table drifting from the flags the commands actually parse.
+func TestCompletionFlagsMatchParsers(t *testing.T) {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	config.RegisterServeFlags(fs)
+	var serve []string
+	fs.VisitAll(func(f *flag.Flag) { serve = append(serve, f.Name) })
+	var table []string
+	for _, f := range serveCompletionFlags {
+		table = append(table, f.name)
+		if fs.Lookup(f.name) == nil {
+			t.Errorf("serve flag %q is not registered", f.name)
+		}
+	}
+	if len(serve) != len(table) {
+		t.Errorf("serve flags = %v, completion has %v", serve, table)
+	}
+
+	parsers := map[string]func([]string) error{
+		"prune": func(args []string) error {
+			_, err := parsePruneFlags(args)
+			return err
+		},
+		"scan-secrets": func(args []string) error {
+			_, err := parseScanSecretsFlags(args)
+			return err
+		},
+		"import": func(args []string) error {
+			_, err := parseImportFlags(args)
+			return err
+		},
+		"export": func(args []string) error {
+			_, err := parseExportFlags(args)
+			return err
+		},
+		"push": func(args []string) error {
+			_, err := parsePushFlags(args)
+			return err
+		},
+		"merge": func(args []string) error {
+			_, err := parseMergeFlags(args)
+			return err
+		},
+		"shadow": func(args []string) error {
+			_, err := parseShadowFlags(args)
+			return err
+		},
+	}
+	for _, c := range completionCommands {
+		parse, ok := parsers[c.name]
+		if !ok {
+			continue
+		}
+		for _, f := range c.flags {
+			err := parse([]string{"-" + f.name + "=1"})
+			if err != nil &&
+				strings.Contains(err.Error(), "not defined") {
+				t.Errorf("%s: completed flag -%s is not defined",
+					c.name, f.name)
+			}
+		}
+	}
+}
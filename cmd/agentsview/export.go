@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/server"
+)
+
+// ExportConfig holds parsed CLI options for the export command.
+type ExportConfig struct {
+	Format     string
+	Project    string // export every session in this project
+	Output     string // file or directory; empty writes to stdout
+	SessionIDs []string
+}
+
+func parseExportFlags(args []string) (ExportConfig, error) {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String(
+		"format", server.ExportMarkdown,
+		"Output format: "+strings.Join(server.ExportFormats, ", "),
+	)
+	project := fs.String(
+		"project", "",
+		"Export every session in this project",
+	)
+	output := fs.String(
+		"o", "",
+		"Output file, or directory for several sessions",
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return ExportConfig{}, err
+	}
+	if !server.ValidExportFormat(*format) {
+		return ExportConfig{}, fmt.Errorf(
+			"unsupported format %q (supported: %s)",
+			*format, strings.Join(server.ExportFormats, ", "),
+		)
+	}
+	if (*project == "") == (fs.NArg() == 0) {
+		return ExportConfig{}, fmt.Errorf(
+			"give either session IDs or --project",
+		)
+	}
+	return ExportConfig{
+		Format:     *format,
+		Project:    *project,
+		Output:     *output,
+		SessionIDs: fs.Args(),
+	}, nil
+}
+
+// Exporter renders stored sessions to files or a writer.
+type Exporter struct {
+	DB *db.DB
+	// Out receives a single session exported without an output
+	// path, and the progress of multi-session exports.
+	Out io.Writer
+}
+
+// Export writes the sessions selected by cfg. A single session
+// goes to cfg.Output (a file, or a directory to name it in) or
+// to Out; several sessions go one file each into the cfg.Output
+// directory, or the current directory.
+func (e *Exporter) Export(ctx context.Context, cfg ExportConfig) error {
+	ids := cfg.SessionIDs
+	if cfg.Project != "" {
+		var err error
+		ids, err = e.DB.MatchSessionIDs(
+			ctx, db.SessionFilter{Project: cfg.Project}, "",
+		)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return fmt.Errorf("no sessions in project %q", cfg.Project)
+		}
+	}
+
+	if len(ids) == 1 && cfg.Project == "" {
+		return e.exportOne(ctx, cfg, ids[0])
+	}
+
+	dir := cfg.Output
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		session, msgs, err := e.load(ctx, id)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(
+			dir, server.ProjectExportFilename(session, cfg.Format),
+		)
+		if err := writeExportFile(path, cfg.Format, session, msgs); err != nil {
+			return err
+		}
+		fmt.Fprintf(e.Out, "  %s: %s\n", id, path)
+	}
+	fmt.Fprintf(e.Out, "\nExported %d sessions to %s\n", len(ids), dir)
+	return nil
+}
+
+func (e *Exporter) exportOne(
+	ctx context.Context, cfg ExportConfig, id string,
+) error {
+	session, msgs, err := e.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if cfg.Output == "" {
+		return server.WriteExport(e.Out, cfg.Format, session, msgs)
+	}
+	path := cfg.Output
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(
+			path, server.ExportFilename(session, cfg.Format),
+		)
+	}
+	return writeExportFile(path, cfg.Format, session, msgs)
+}
+
+func (e *Exporter) load(
+	ctx context.Context, id string,
+) (*db.Session, []db.Message, error) {
+	session, err := e.DB.GetSession(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading session %s: %w", id, err)
+	}
+	if session == nil {
+		return nil, nil, fmt.Errorf("session %s not found", id)
+	}
+	msgs, err := e.DB.GetAllMessages(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf(
+			"loading messages for %s: %w", id, err,
+		)
+	}
+	return session, msgs, nil
+}
+
+func writeExportFile(
+	path, format string, session *db.Session, msgs []db.Message,
+) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := server.WriteExport(f, format, session, msgs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func runExport(args []string) {
+	cfg, err := parseExportFlags(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	appCfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+
+	database, err := db.Open(appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	ex := &Exporter{DB: database, Out: os.Stdout}
+	if err := ex.Export(context.Background(), cfg); err != nil {
+		log.Fatalf("export: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+)
+
+func TestParseExportFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"nothing selected", nil, "either session IDs or --project"},
+		{"both selected", []string{"--project", "p", "s1"}, "either session IDs or --project"},
+		{"unknown format", []string{"--format", "pdf", "s1"}, "unsupported format"},
+		{"ok", []string{"--format", "json", "s1", "s2"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseExportFlags(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Format != "json" || len(cfg.SessionIDs) != 2 {
+				t.Errorf("cfg = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestExporter(t *testing.T) {
+	d := dbtest.OpenTestDB(t)
+	ctx := context.Background()
+	for _, id := range []string{"s1", "s2"} {
+		dbtest.SeedSession(t, d, id, "site", func(s *db.Session) {
+			s.MessageCount = 1
+		})
+		dbtest.SeedMessages(t, d, dbtest.UserMsg(id, 0, "hello from "+id))
+	}
+
+	var out bytes.Buffer
+	ex := &Exporter{DB: d, Out: &out}
+	err := ex.Export(ctx, ExportConfig{
+		Format: "md", SessionIDs: []string{"s1"},
+	})
+	if err != nil {
+		t.Fatalf("Export s1: %v", err)
+	}
+	if !strings.Contains(out.String(), "hello from s1") {
+		t.Errorf("stdout export = %q", out.String())
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	out.Reset()
+	err = ex.Export(ctx, ExportConfig{
+		Format: "md", Project: "site", Output: dir,
+	})
+	if err != nil {
+		t.Fatalf("Export project: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.md"))
+	if len(files) != 2 {
+		t.Fatalf("files = %v, want 2", files)
+	}
+	data, err := os.ReadFile(files[1])
+	if err != nil || !strings.Contains(string(data), "hello from s2") {
+		t.Errorf("%s = %q (%v)", files[1], data, err)
+	}
+	if !strings.Contains(out.String(), "Exported 2 sessions") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	err = ex.Export(ctx, ExportConfig{
+		Format: "md", SessionIDs: []string{"nope"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing session err = %v", err)
+	}
+}
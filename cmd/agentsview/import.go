@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+	"github.com/wesm/agentsview/internal/sync"
+)
+
+// Import formats accepted by the import command.
+const (
+	importFormatOpenCodeShare = "opencode-share"
+	importFormatSpecStory     = "specstory"
+)
+
+var importFormats = []string{
+	importFormatOpenCodeShare, importFormatSpecStory,
+}
+
+// ImportConfig holds parsed CLI options for the import command.
+type ImportConfig struct {
+	Format  string
+	Machine string
+	Project string // overrides the project derived from the file
+	Files   []string
+}
+
+func parseImportFlags(args []string) (ImportConfig, error) {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String(
+		"format", "",
+		"Input format: "+strings.Join(importFormats, ", "),
+	)
+	machine := fs.String(
+		"machine", "imported",
+		"Machine name to record for imported sessions",
+	)
+	project := fs.String(
+		"project", "",
+		"Project name to use instead of the one in the file",
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return ImportConfig{}, err
+	}
+
+	switch *format {
+	case importFormatOpenCodeShare, importFormatSpecStory:
+	case "":
+		return ImportConfig{}, fmt.Errorf("--format is required")
+	default:
+		return ImportConfig{}, fmt.Errorf(
+			"unsupported format %q (supported: %s)",
+			*format, strings.Join(importFormats, ", "),
+		)
+	}
+	if fs.NArg() == 0 {
+		return ImportConfig{}, fmt.Errorf(
+			"at least one file is required",
+		)
+	}
+	if *machine == "" {
+		return ImportConfig{}, fmt.Errorf("--machine must not be empty")
+	}
+
+	return ImportConfig{
+		Format:  *format,
+		Machine: *machine,
+		Project: *project,
+		Files:   fs.Args(),
+	}, nil
+}
+
+// Importer loads exported sessions from files into a database.
+type Importer struct {
+	DB                *db.DB
+	Out               io.Writer
+	BlockedCategories []string
+}
+
+// Import parses and stores each file, reporting per-file
+// results. Files that fail are reported and skipped; the
+// returned error is non-nil if any file failed.
+func (im *Importer) Import(cfg ImportConfig) error {
+	imported, failed := 0, 0
+	for _, path := range cfg.Files {
+		id, err := im.importFile(cfg, path)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(im.Out, "  %s: error: %v\n", path, err)
+		case id == "":
+			fmt.Fprintf(im.Out, "  %s: no messages, skipped\n", path)
+		default:
+			imported++
+			fmt.Fprintf(im.Out, "  %s: imported %s\n", path, id)
+		}
+	}
+
+	fmt.Fprintf(im.Out, "\nImported %d sessions", imported)
+	if failed > 0 {
+		fmt.Fprintf(im.Out, ", %d failed", failed)
+	}
+	fmt.Fprintln(im.Out)
+	if failed > 0 {
+		return fmt.Errorf("%d files failed to import", failed)
+	}
+	return nil
+}
+
+func (im *Importer) importFile(
+	cfg ImportConfig, path string,
+) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	parse := parser.ParseOpenCodeExport
+	if cfg.Format == importFormatSpecStory {
+		parse = parser.ParseSpecStoryExport
+	}
+	sess, msgs, err := parse(abs, cfg.Machine)
+	if err != nil {
+		return "", err
+	}
+	if sess == nil {
+		return "", nil
+	}
+	if cfg.Project != "" {
+		sess.Project = cfg.Project
+	}
+	if hash, err := sync.ComputeFileHash(abs); err == nil {
+		sess.File.Hash = hash
+	}
+
+	if err := sync.WriteSession(
+		im.DB, *sess, msgs, im.BlockedCategories,
+	); err != nil {
+		return "", err
+	}
+	return sess.ID, nil
+}
+
+func runImport(args []string) {
+	cfg, err := parseImportFlags(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	appCfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+
+	database, err := db.Open(appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	im := &Importer{
+		DB:                database,
+		Out:               os.Stdout,
+		BlockedCategories: appCfg.ResultContentBlockedCategories,
+	}
+	if err := im.Import(cfg); err != nil {
+		log.Fatalf("import: %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/dbtest"
+)
+
+func TestParseImportFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"missing format", []string{"a.json"}, "--format is required"},
+		{"unknown format", []string{"--format", "aider", "a.json"}, "unsupported format"},
+		{"no files", []string{"--format", "opencode-share"}, "at least one file"},
+		{"empty machine", []string{"--format", "opencode-share", "--machine", "", "a.json"}, "--machine"},
+		{"ok", []string{"--format", "opencode-share", "a.json", "b.json"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parseImportFlags(tt.args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.Machine != "imported" || len(cfg.Files) != 2 {
+				t.Errorf("cfg = %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestImporter_OpenCodeShare(t *testing.T) {
+	d := dbtest.OpenTestDB(t)
+	dir := t.TempDir()
+
+	good := filepath.Join(dir, "thread.json")
+	writeFile(t, good, `{
+		"info": {"id": "ses_abc", "directory": "/work/site",
+			"time": {"created": 1717232400000, "updated": 1717232460000}},
+		"messages": [
+			{"info": {"id": "m1", "role": "user", "time": {"created": 1717232400000}},
+			 "parts": [{"type": "text", "text": "hello"}]},
+			{"info": {"id": "m2", "role": "assistant", "time": {"created": 1717232401000}},
+			 "parts": [{"type": "text", "text": "hi there"}]}
+		]
+	}`)
+	bad := filepath.Join(dir, "bad.json")
+	writeFile(t, bad, "{")
+
+	var out bytes.Buffer
+	im := &Importer{DB: d, Out: &out}
+	err := im.Import(ImportConfig{
+		Format:  importFormatOpenCodeShare,
+		Machine: "teammate",
+		Project: "shared",
+		Files:   []string{good, bad},
+	})
+	if err == nil {
+		t.Fatal("expected error for bad file")
+	}
+	if !strings.Contains(out.String(), "Imported 1 sessions, 1 failed") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	sess, err := d.GetSession(context.Background(), "opencode:ses_abc")
+	if err != nil || sess == nil {
+		t.Fatalf("GetSession: %v, %v", sess, err)
+	}
+	if sess.Project != "shared" || sess.Machine != "teammate" ||
+		sess.MessageCount != 2 || sess.UserMessageCount != 1 {
+		t.Errorf("session = %+v", sess)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImporter_SpecStory(t *testing.T) {
+	d := dbtest.OpenTestDB(t)
+	dir := filepath.Join(t.TempDir(), "site", ".specstory", "history")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "2024-12-01_10-00Z-hello.md")
+	writeFile(t, path, "# Hello (2024-12-01 10:00Z)\n\n"+
+		"_**User**_\n\nhello\n\n---\n\n_**Assistant**_\n\nhi there\n")
+
+	var out bytes.Buffer
+	im := &Importer{DB: d, Out: &out}
+	err := im.Import(ImportConfig{
+		Format:  importFormatSpecStory,
+		Machine: "teammate",
+		Files:   []string{path},
+	})
+	if err != nil {
+		t.Fatalf("Import: %v (%s)", err, out.String())
+	}
+
+	sess, err := d.GetSession(
+		context.Background(), "specstory:2024-12-01_10-00Z-hello",
+	)
+	if err != nil || sess == nil {
+		t.Fatalf("GetSession: %v, %v", sess, err)
+	}
+	if sess.Project != "site" || sess.Agent != "cursor" ||
+		sess.MessageCount != 2 || sess.UserMessageCount != 1 {
+		t.Errorf("session = %+v", sess)
+	}
+}
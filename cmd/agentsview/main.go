@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
@@ -14,11 +16,17 @@ import (
 	"time"
 	_ "time/tzdata"
 
+	"github.com/wesm/agentsview/internal/alert"
 	"github.com/wesm/agentsview/internal/config"
 	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/demo"
+	"github.com/wesm/agentsview/internal/digest"
+	"github.com/wesm/agentsview/internal/logging"
 	"github.com/wesm/agentsview/internal/parser"
+	"github.com/wesm/agentsview/internal/pricing"
 	"github.com/wesm/agentsview/internal/server"
 	"github.com/wesm/agentsview/internal/sync"
+	"github.com/wesm/agentsview/internal/synchook"
 )
 
 var (
@@ -33,6 +41,8 @@ const (
 	watcherDebounce       = 500 * time.Millisecond
 	browserPollInterval   = 100 * time.Millisecond
 	browserPollAttempts   = 60
+	// compactionHour is the local hour nightly compaction runs.
+	compactionHour = 3
 )
 
 func main() {
@@ -41,9 +51,33 @@ func main() {
 		case "prune":
 			runPrune(os.Args[2:])
 			return
+		case "scan-secrets":
+			runScanSecrets(os.Args[2:])
+			return
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "push":
+			runPush(os.Args[2:])
+			return
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "shadow":
+			runShadow(os.Args[2:])
+			return
+		case "mcp":
+			runMCP(os.Args[2:])
+			return
 		case "update":
 			runUpdate(os.Args[2:])
 			return
+		case "completion":
+			runCompletion(os.Args[2:])
+			return
 		case "serve":
 			runServe(os.Args[2:])
 			return
@@ -71,7 +105,22 @@ Usage:
   agentsview [flags]          Start the server (default command)
   agentsview serve [flags]    Start the server (explicit)
   agentsview prune [flags]    Delete sessions matching filters
+  agentsview scan-secrets [flags]
+                              Report sessions containing likely secrets
+  agentsview import [flags] <file>...
+                              Import exported sessions from files
+  agentsview export [flags] <session-id>...
+                              Export sessions to Markdown, HTML, or JSON
+  agentsview push [flags]     Send sessions to a central agentsview server
+  agentsview merge <other.db> Import sessions from another agentsview
+                              database, such as a backup
+  agentsview shadow [flags]   Reparse sampled files and compare with
+                              stored sessions
+  agentsview mcp              Serve session search and transcripts to
+                              coding agents over MCP (stdio)
   agentsview update [flags]   Check for and install updates
+  agentsview completion bash|zsh|fish
+                              Print a shell completion script
   agentsview version          Show version information
   agentsview help             Show this help
 
@@ -79,15 +128,63 @@ Server flags:
   -host string        Host to bind to (default "127.0.0.1")
   -port int           Port to listen on (default 8080)
   -no-browser         Don't open browser on startup
+  -log-level string   Minimum log level: debug, info, warn, error
+                      (default "info")
+  -demo               Serve a generated demo dataset instead of your
+                      sessions
+  -sync-workers int   Session files to parse at once during sync
+                      (default: by CPU count)
 
 Prune flags:
   -project string     Sessions whose project contains this substring
+  -agent string       Sessions from this agent (e.g. claude, codex)
+  -orphaned           Sessions whose source file no longer exists
   -max-messages int   Sessions with at most N messages (default -1)
   -before string      Sessions that ended before this date (YYYY-MM-DD)
   -first-message str  Sessions whose first message starts with this text
+  -include-chain      Prune parent/child chains whole, only when every
+                      session in the chain matches
+  -interactive        List matches with sizes and pick which to delete
+  -delete-files       Also delete source files of sessions synced on
+                      this machine (otherwise the next sync
+                      imports sessions whose files remain)
   -dry-run            Show what would be pruned without deleting
   -yes                Skip confirmation prompt
 
+Scan-secrets flags:
+  -project string     Only scan projects containing this substring
+  -redact             Replace detected secrets in the database
+  -prune              Delete affected sessions from the database
+  -delete-files       With -prune, also delete source files of
+                      sessions synced on this machine
+  -json               Print the report as JSON
+  -yes                Skip confirmation prompt
+
+Import flags:
+  -format string      Input format (required): opencode-share,
+                      specstory
+  -machine string     Machine name for imported sessions
+                      (default "imported")
+  -project string     Override the project name from the file
+
+Export flags:
+  -format string      Output format: html, md, json (default "md")
+  -project string     Export every session in this project
+  -o string           Output file, or directory for several sessions
+                      (default: stdout for one session, else ".")
+
+Push flags:
+  -url string         Server to push to (default: push.url in config)
+  -token string       Server's ingest token (default: push.token)
+  -machine string     Machine name for this machine's sessions
+                      (default: push.machine, else the host name)
+  -project string     Only push this project
+  -dry-run            Report what would be pushed without sending
+
+Shadow flags:
+  -sample int         Session files to reparse, 0 for all (default 200)
+  -json               Print the report as JSON
+
 Update flags:
   -check              Check for updates without installing
   -yes                Install without confirmation prompt
@@ -99,9 +196,13 @@ Environment variables:
   COPILOT_DIR             Copilot CLI directory
   GEMINI_DIR              Gemini CLI directory
   OPENCODE_DIR            OpenCode data directory
+  CURSOR_DIR              Cursor data directory (uses its projects/)
   CURSOR_PROJECTS_DIR     Cursor projects directory
   AMP_DIR                 Amp threads directory
+  AIDER_DIR               Project root searched for Aider chat logs
+  GOOSE_DIR               Goose sessions directory
   AGENT_VIEWER_DATA_DIR   Data directory (database, config)
+  AGENT_VIEWER_LOG_LEVEL  Minimum log level for debug.log
 
 Multiple directories:
   Add arrays to ~/.agentsview/config.json to scan multiple locations:
@@ -142,7 +243,17 @@ func warnMissingDirs(dirs []string, label string) {
 func runServe(args []string) {
 	start := time.Now()
 	cfg := mustLoadConfig(args)
-	setupLogFile(cfg.DataDir)
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		fatal("%v", err)
+	}
+	if f := setupLogFile(cfg.DataDir, level); f != nil {
+		defer f.Close()
+	}
+	if cfg.Demo {
+		runDemo(cfg, start)
+		return
+	}
 	database := mustOpenDB(cfg)
 	defer database.Close()
 
@@ -156,17 +267,25 @@ func runServe(args []string) {
 		)
 	}
 
-	// Remove stale temp DB from a prior crashed resync.
-	cleanResyncTemp(cfg.DBPath)
+	// A resync interrupted by a crash or shutdown resumes
+	// from its checkpoint.
+	resumeResync := sync.ResyncInterrupted(cfg.DBPath)
 
-	engine := sync.NewEngine(database, sync.EngineConfig{
+	engineCfg := sync.EngineConfig{
 		AgentDirs:               cfg.AgentDirs,
-		Machine:                 "local",
+		Machine:                 localMachine,
 		BlockedResultCategories: cfg.ResultContentBlockedCategories,
-	})
+		Workers:                 cfg.SyncWorkers,
+	}
+	if len(cfg.SyncHooks) > 0 {
+		hooks := synchook.New(cfg.SyncHooks)
+		go hooks.Run(context.Background())
+		engineCfg.OnSessionWritten = hooks.Enqueue
+	}
+	engine := sync.NewEngine(database, engineCfg)
 
-	if database.NeedsResync() {
-		runInitialResync(engine)
+	if database.NeedsResync() || resumeResync {
+		runInitialResync(engine, resumeResync)
 	} else {
 		runInitialSync(engine)
 	}
@@ -178,22 +297,72 @@ func runServe(args []string) {
 	if len(unwatchedDirs) > 0 {
 		go startUnwatchedPoll(engine)
 	}
+	if cfg.DailyDigest.Enabled {
+		go startDailyDigest(cfg, database)
+	}
+	if cfg.Compaction.HorizonDays > 0 {
+		go startNightlyCompaction(cfg.Compaction, database)
+	}
+	var alerts *alert.Evaluator
+	if len(cfg.Alerts.Rules) > 0 {
+		alerts = alert.New(database, cfg.Alerts)
+		go alerts.Run(context.Background())
+	}
+
+	serve(cfg, database, engine, start, server.WithAlerts(alerts))
+}
+
+// runDemo serves a generated dataset from a temporary database
+// that is removed on exit. Nothing is synced, so the user's own
+// sessions are never read.
+func runDemo(cfg config.Config, start time.Time) {
+	dir, err := os.MkdirTemp("", "agentsview-demo-")
+	if err != nil {
+		fatal("creating demo dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	cfg.DataDir = dir
+	cfg.DBPath = filepath.Join(dir, "sessions.db")
+
+	database, err := db.Open(cfg.DBPath)
+	if err != nil {
+		fatal("opening demo database: %v", err)
+	}
+	defer database.Close()
+	n, err := demo.Populate(context.Background(), database, time.Now())
+	if err != nil {
+		fatal("generating demo data: %v", err)
+	}
+	fmt.Printf("Demo mode: %d generated sessions\n", n)
+
+	engine := sync.NewEngine(database, sync.EngineConfig{Machine: localMachine})
+	serve(cfg, database, engine, start)
+}
 
+// serve starts the HTTP server on cfg's host, moving to a free
+// port if needed, and blocks until it stops.
+func serve(
+	cfg config.Config, database *db.DB, engine *sync.Engine,
+	start time.Time, opts ...server.Option,
+) {
 	port := server.FindAvailablePort(cfg.Host, cfg.Port)
 	if port != cfg.Port {
 		fmt.Printf("Port %d in use, using %d\n", cfg.Port, port)
 	}
 	cfg.Port = port
 
-	srv := server.New(cfg, database, engine,
-		server.WithVersion(server.VersionInfo{
-			Version:   version,
-			Commit:    commit,
-			BuildDate: buildDate,
-		}),
-	)
+	opts = append(opts, server.WithVersion(server.VersionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+	}))
+	srv := server.New(cfg, database, engine, opts...)
 
-	url := fmt.Sprintf("http://%s:%d", cfg.Host, cfg.Port)
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		scheme = "https"
+	}
+	url := fmt.Sprintf("%s://%s:%d", scheme, cfg.Host, cfg.Port)
 	fmt.Printf(
 		"agentsview %s listening at %s (started in %s)\n",
 		version, url,
@@ -204,8 +373,7 @@ func runServe(args []string) {
 		go openBrowser(url)
 	}
 
-	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
-	if err := http.ListenAndServe(addr, srv.Handler()); err != nil {
+	if err := srv.ListenAndServe(); err != nil {
 		fatal("server error: %v", err)
 	}
 }
@@ -226,6 +394,7 @@ func mustLoadConfig(args []string) config.Config {
 	if err != nil {
 		log.Fatalf("loading config: %v", err)
 	}
+	pricing.SetOverrides(cfg.Pricing)
 
 	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
 		log.Fatalf("creating data dir: %v", err)
@@ -237,17 +406,21 @@ func mustLoadConfig(args []string) config.Config {
 // truncated on startup to prevent unbounded growth.
 const maxLogSize = 10 * 1024 * 1024 // 10 MB
 
-func setupLogFile(dataDir string) {
-	logPath := filepath.Join(dataDir, "debug.log")
+// setupLogFile routes structured JSON logging at the given
+// level to the debug log in dataDir and returns the open file,
+// or nil if it could not be opened.
+func setupLogFile(dataDir string, level slog.Level) *os.File {
+	logPath := filepath.Join(dataDir, logging.FileName)
 	truncateLogFile(logPath, maxLogSize)
 	f, err := os.OpenFile(
 		logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644,
 	)
 	if err != nil {
-		log.Printf("warning: cannot open log file: %v", err)
-		return
+		slog.Warn("cannot open log file", "err", err)
+		return nil
 	}
-	log.SetOutput(f)
+	logging.Setup(f, level)
+	return f
 }
 
 // truncateLogFile truncates the log file if it exceeds limit
@@ -270,6 +443,13 @@ func mustOpenDB(cfg config.Config) *db.DB {
 	if err != nil {
 		fatal("opening database: %v", err)
 	}
+	if rep := database.Recovery(); rep != nil {
+		fmt.Fprintf(os.Stderr,
+			"warning: database was corrupt and has been rebuilt"+
+				" (%s); resyncing from session files\n",
+			rep.Summary(),
+		)
+	}
 
 	if cfg.CursorSecret != "" {
 		secret, err := base64.StdEncoding.DecodeString(cfg.CursorSecret)
@@ -278,6 +458,17 @@ func mustOpenDB(cfg config.Config) *db.DB {
 		}
 		database.SetCursorSecret(secret)
 	}
+	if cfg.PreviousCursorSecret != "" {
+		secret, err := base64.StdEncoding.DecodeString(
+			cfg.PreviousCursorSecret,
+		)
+		if err != nil {
+			fatal("invalid previous cursor secret: %v", err)
+		}
+		database.SetPreviousCursorSecret(
+			secret, cfg.PreviousCursorSecretUntil,
+		)
+	}
 
 	return database
 }
@@ -290,15 +481,6 @@ func fatal(format string, args ...any) {
 	os.Exit(1)
 }
 
-// cleanResyncTemp removes leftover temp database files from
-// a prior crashed resync.
-func cleanResyncTemp(dbPath string) {
-	tempPath := dbPath + "-resync"
-	for _, suffix := range []string{"", "-wal", "-shm"} {
-		os.Remove(tempPath + suffix)
-	}
-}
-
 func runInitialSync(engine *sync.Engine) {
 	fmt.Println("Running initial sync...")
 	t := time.Now()
@@ -306,8 +488,12 @@ func runInitialSync(engine *sync.Engine) {
 	printSyncSummary(stats, t)
 }
 
-func runInitialResync(engine *sync.Engine) {
-	fmt.Println("Data version changed, running full resync...")
+func runInitialResync(engine *sync.Engine, interrupted bool) {
+	if interrupted {
+		fmt.Println("Resuming interrupted full resync...")
+	} else {
+		fmt.Println("Data version changed, running full resync...")
+	}
 	t := time.Now()
 	stats := engine.ResyncAll(printSyncProgress)
 	printSyncSummary(stats, t)
@@ -328,6 +514,12 @@ func printSyncSummary(stats sync.SyncStats, t time.Time) {
 		"\nSync complete: %d sessions synced",
 		stats.Synced,
 	)
+	if stats.Resumed > 0 {
+		summary += fmt.Sprintf(
+			" (%d files done before the interruption)",
+			stats.Resumed,
+		)
+	}
 	if stats.OrphanedCopied > 0 {
 		summary += fmt.Sprintf(
 			", %d archived sessions preserved",
@@ -353,6 +545,9 @@ func printSyncProgress(p sync.Progress) {
 			p.SessionsDone, p.SessionsTotal,
 			p.Percent(), p.MessagesIndexed,
 		)
+		if p.ResumedFiles > 0 {
+			fmt.Printf(" · resumed after %d", p.ResumedFiles)
+		}
 	}
 }
 
@@ -365,10 +560,9 @@ func startFileWatcher(
 	}
 	watcher, err := sync.NewWatcher(watcherDebounce, onChange)
 	if err != nil {
-		log.Printf(
-			"warning: file watcher unavailable: %v"+
-				"; will poll every %s",
-			err, unwatchedPollInterval,
+		slog.Warn(
+			"file watcher unavailable; polling instead",
+			"err", err, "interval", unwatchedPollInterval,
 		)
 		return func() {}, []string{"all"}
 	}
@@ -405,13 +599,24 @@ func startFileWatcher(
 
 	var totalWatched int
 	for _, r := range roots {
+		if parser.WSLRoot(r.root) != "" {
+			// Change notifications don't cross the \\wsl$
+			// file share, so WSL directories are polled.
+			unwatchedDirs = append(unwatchedDirs, r.dir)
+			slog.Info(
+				"polling WSL directory",
+				"root", r.dir, "interval", unwatchedPollInterval,
+			)
+			continue
+		}
 		watched, uw, _ := watcher.WatchRecursive(r.root)
 		totalWatched += watched
 		if uw > 0 {
 			unwatchedDirs = append(unwatchedDirs, r.dir)
-			log.Printf(
-				"Couldn't watch %d directories under %s, will poll every %s",
-				uw, r.dir, unwatchedPollInterval,
+			slog.Warn(
+				"couldn't watch directories; polling instead",
+				"count", uw, "root", r.dir,
+				"interval", unwatchedPollInterval,
 			)
 		}
 	}
@@ -428,16 +633,60 @@ func startPeriodicSync(engine *sync.Engine) {
 	ticker := time.NewTicker(periodicSyncInterval)
 	defer ticker.Stop()
 	for range ticker.C {
-		log.Println("Running scheduled sync...")
+		slog.Info("running scheduled sync")
 		engine.SyncAll(nil)
 	}
 }
 
+func startDailyDigest(cfg config.Config, database *db.DB) {
+	s := &digest.Scheduler{
+		DB:        database,
+		Notifier:  digest.DesktopNotifier{},
+		Minute:    cfg.DailyDigest.Minute(),
+		Location:  cfg.DailyDigest.Location(),
+		StatePath: filepath.Join(cfg.DataDir, "digest_sent"),
+	}
+	s.Run(context.Background())
+}
+
+// startNightlyCompaction compacts data older than the
+// configured horizon once a night at compactionHour local time.
+func startNightlyCompaction(cfg config.Compaction, database *db.DB) {
+	for {
+		now := time.Now()
+		time.Sleep(nextCompaction(now).Sub(now))
+
+		t := time.Now()
+		stats, err := database.Compact(context.Background(), db.CompactOptions{
+			Before:        t.AddDate(0, 0, -cfg.HorizonDays),
+			StripPayloads: cfg.StripPayloads,
+		})
+		if err != nil {
+			slog.Warn("nightly compaction", "err", err)
+			continue
+		}
+		slog.Info("nightly compaction",
+			"months", stats.Months,
+			"stripped_tool_calls", stats.StrippedToolCalls,
+			"elapsed", time.Since(t).Round(time.Millisecond))
+	}
+}
+
+// nextCompaction returns the first compactionHour after now.
+func nextCompaction(now time.Time) time.Time {
+	y, m, d := now.Date()
+	next := time.Date(y, m, d, compactionHour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
 func startUnwatchedPoll(engine *sync.Engine) {
 	ticker := time.NewTicker(unwatchedPollInterval)
 	defer ticker.Stop()
 	for range ticker.C {
-		log.Println("Polling unwatched directories...")
+		slog.Info("polling unwatched directories")
 		engine.SyncAll(nil)
 	}
 }
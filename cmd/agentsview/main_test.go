@@ -5,11 +5,13 @@ import (
 	"errors"
 	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"syscall"
 	"testing"
+	"time"
 )
 
 func TestMustLoadConfig(t *testing.T) {
@@ -71,32 +73,44 @@ func TestMustLoadConfig(t *testing.T) {
 
 func TestSetupLogFile(t *testing.T) {
 	origOutput := log.Writer()
+	origLogger := slog.Default()
 
 	dir := t.TempDir()
-	setupLogFile(dir)
+	f := setupLogFile(dir, slog.LevelInfo)
+	if f == nil {
+		t.Fatal("setupLogFile returned nil")
+	}
 
 	// Close the log file before TempDir cleanup removes the
 	// directory. On Windows, open files can't be deleted.
 	// Registered after TempDir so LIFO ordering runs this first.
 	t.Cleanup(func() {
-		if c, ok := log.Writer().(io.Closer); ok {
-			c.Close()
-		}
+		f.Close()
+		slog.SetDefault(origLogger)
 		log.SetOutput(origOutput)
 	})
 
-	// Log something and verify it reaches the file.
+	// Log through both the standard log package and slog and
+	// verify both reach the file as JSON records.
 	log.Print("test-log-message")
+	slog.Warn("test-slog-message", "key", "value")
+	slog.Debug("test-debug-message")
 
 	logPath := filepath.Join(dir, "debug.log")
 	data, err := os.ReadFile(logPath)
 	if err != nil {
 		t.Fatalf("reading log file: %v", err)
 	}
-	if !strings.Contains(string(data), "test-log-message") {
-		t.Errorf(
-			"log file missing message, got: %q", data,
-		)
+	for _, want := range []string{
+		`"msg":"test-log-message"`,
+		`"level":"WARN","msg":"test-slog-message","key":"value"`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("log file missing %s, got: %q", want, data)
+		}
+	}
+	if strings.Contains(string(data), "test-debug-message") {
+		t.Errorf("debug message logged at info level: %q", data)
 	}
 }
 
@@ -113,7 +127,10 @@ func TestSetupLogFileOpenFailure(t *testing.T) {
 	tmpFile := filepath.Join(t.TempDir(), "notadir")
 	os.WriteFile(tmpFile, []byte("x"), 0o644)
 
-	setupLogFile(tmpFile)
+	if f := setupLogFile(tmpFile, slog.LevelInfo); f != nil {
+		f.Close()
+		t.Fatal("expected nil file for unopenable path")
+	}
 
 	if !strings.Contains(buf.String(), "cannot open log file") {
 		t.Errorf(
@@ -203,3 +220,29 @@ func TestTruncateLogFileSymlink(t *testing.T) {
 		)
 	}
 }
+
+func TestNextCompaction(t *testing.T) {
+	loc := time.FixedZone("test", 2*3600)
+	tests := []struct {
+		now, want time.Time
+	}{
+		{
+			time.Date(2025, 3, 1, 1, 30, 0, 0, loc),
+			time.Date(2025, 3, 1, compactionHour, 0, 0, 0, loc),
+		},
+		{
+			time.Date(2025, 3, 1, compactionHour, 0, 0, 0, loc),
+			time.Date(2025, 3, 2, compactionHour, 0, 0, 0, loc),
+		},
+		{
+			time.Date(2025, 3, 31, 23, 0, 0, 0, loc),
+			time.Date(2025, 4, 1, compactionHour, 0, 0, 0, loc),
+		},
+	}
+	for _, tt := range tests {
+		if got := nextCompaction(tt.now); !got.Equal(tt.want) {
+			t.Errorf("nextCompaction(%v) = %v, want %v",
+				tt.now, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/mcp"
+	"github.com/wesm/agentsview/internal/pricing"
+)
+
+// runMCP serves the session database to a coding agent over
+// MCP on stdin and stdout. Logs go to stderr so they never mix
+// with protocol messages.
+func runMCP(args []string) {
+	if len(args) > 0 {
+		log.Fatalf("mcp takes no arguments")
+	}
+	appCfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+	pricing.SetOverrides(appCfg.Pricing)
+	database, err := db.Open(appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	srv := mcp.NewServer("agentsview", version, mcp.SessionTools(database))
+	if err := srv.Serve(ctx, os.Stdin, os.Stdout); err != nil &&
+		ctx.Err() == nil {
+		log.Fatalf("mcp: %v", err)
+	}
+}
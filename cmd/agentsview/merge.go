@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// MergeConfig holds parsed CLI options for the merge command.
+type MergeConfig struct {
+	Path string // the agentsview database to import from
+}
+
+func parseMergeFlags(args []string) (MergeConfig, error) {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return MergeConfig{}, err
+	}
+	if fs.NArg() != 1 {
+		return MergeConfig{}, fmt.Errorf(
+			"expected one database file, got %d arguments", fs.NArg(),
+		)
+	}
+	return MergeConfig{Path: fs.Arg(0)}, nil
+}
+
+// printMergeStats reports what a merge did.
+func printMergeStats(w io.Writer, stats db.MergeStats) {
+	fmt.Fprintf(w, "%d sessions: %d imported, %d unchanged",
+		stats.Sessions, stats.Stored, stats.Unchanged)
+	if stats.Stale > 0 {
+		fmt.Fprintf(w, ", %d kept newer local copies", stats.Stale)
+	}
+	fmt.Fprintln(w)
+}
+
+func runMerge(args []string) {
+	cfg, err := parseMergeFlags(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	appCfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+	database, err := db.Open(appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	stats, err := database.MergeFrom(context.Background(), cfg.Path)
+	if err != nil {
+		log.Fatalf("merge: %v", err)
+	}
+	printMergeStats(os.Stdout, stats)
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+func TestParseMergeFlags(t *testing.T) {
+	cfg, err := parseMergeFlags([]string{"backup.db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Path != "backup.db" {
+		t.Errorf("Path = %q, want backup.db", cfg.Path)
+	}
+	for _, args := range [][]string{nil, {"a.db", "b.db"}} {
+		if _, err := parseMergeFlags(args); err == nil {
+			t.Errorf("parseMergeFlags(%q): want error", args)
+		}
+	}
+}
+
+func TestPrintMergeStats(t *testing.T) {
+	var out bytes.Buffer
+	printMergeStats(&out, db.MergeStats{
+		Sessions: 4, Stored: 2, Unchanged: 1, Stale: 1,
+	})
+	want := "4 sessions: 2 imported, 1 unchanged, 1 kept newer local copies\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
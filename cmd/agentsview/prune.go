@@ -8,19 +8,22 @@ import (
 	"io"
 	"log"
 	"os"
-	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/wesm/agentsview/internal/config"
 	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
 )
 
 // PruneConfig holds parsed CLI options for the prune command.
 type PruneConfig struct {
-	Filter db.PruneFilter
-	DryRun bool
-	Yes    bool
+	Filter      db.PruneFilter
+	DryRun      bool
+	Yes         bool
+	Interactive bool
+	DeleteFiles bool
 }
 
 func parsePruneFlags(args []string) (PruneConfig, error) {
@@ -29,6 +32,14 @@ func parsePruneFlags(args []string) (PruneConfig, error) {
 		"project", "",
 		"Sessions whose project contains this substring",
 	)
+	agent := fs.String(
+		"agent", "",
+		"Sessions from this agent (e.g. claude, codex)",
+	)
+	orphaned := fs.Bool(
+		"orphaned", false,
+		"Sessions synced on this machine whose source file no longer exists",
+	)
 	maxMessages := fs.Int(
 		"max-messages", -1,
 		"Sessions with at most N user messages",
@@ -41,6 +52,11 @@ func parsePruneFlags(args []string) (PruneConfig, error) {
 		"first-message", "",
 		"Sessions whose first message starts with this text",
 	)
+	includeChain := fs.Bool(
+		"include-chain", false,
+		"Prune parent/child session chains as units, only when"+
+			" every session in the chain matches",
+	)
 	dryRun := fs.Bool(
 		"dry-run", false,
 		"Show what would be pruned without deleting",
@@ -49,6 +65,16 @@ func parsePruneFlags(args []string) (PruneConfig, error) {
 		"yes", false,
 		"Skip confirmation prompt",
 	)
+	interactive := fs.Bool(
+		"interactive", false,
+		"Pick which matching sessions to delete",
+	)
+	deleteFiles := fs.Bool(
+		"delete-files", false,
+		"Also delete the source files of sessions synced on this"+
+			" machine; without it, sessions whose files remain"+
+			" are imported again on the next sync",
+	)
 
 	if err := fs.Parse(args); err != nil {
 		return PruneConfig{}, err
@@ -57,6 +83,11 @@ func parsePruneFlags(args []string) (PruneConfig, error) {
 	if *maxMessages < 0 && *maxMessages != -1 {
 		return PruneConfig{}, fmt.Errorf("max-messages must be >= 0")
 	}
+	if *agent != "" {
+		if _, ok := parser.AgentByType(parser.AgentType(*agent)); !ok {
+			return PruneConfig{}, fmt.Errorf("unknown agent %q", *agent)
+		}
+	}
 
 	var mm *int
 	if *maxMessages != -1 {
@@ -66,19 +97,25 @@ func parsePruneFlags(args []string) (PruneConfig, error) {
 	cfg := PruneConfig{
 		Filter: db.PruneFilter{
 			Project:      *project,
+			Agent:        *agent,
 			MaxMessages:  mm,
 			Before:       *before,
 			FirstMessage: *firstMessage,
+			Orphaned:     *orphaned,
+			LocalMachine: localMachine,
+			IncludeChain: *includeChain,
 		},
-		DryRun: *dryRun,
-		Yes:    *yes,
+		DryRun:      *dryRun,
+		Yes:         *yes,
+		Interactive: *interactive,
+		DeleteFiles: *deleteFiles,
 	}
 
 	if !cfg.Filter.HasFilters() {
 		return PruneConfig{}, fmt.Errorf(
 			"at least one filter is required\n" +
-				"use --project, --max-messages, --before," +
-				" or --first-message",
+				"use --project, --agent, --max-messages, --before," +
+				" --first-message, or --orphaned",
 		)
 	}
 
@@ -92,7 +129,8 @@ type Pruner struct {
 	In  io.Reader
 }
 
-// Prune finds matching sessions and deletes them.
+// Prune finds matching sessions and deletes them, along with
+// their local source files when cfg.DeleteFiles is set.
 func (p *Pruner) Prune(cfg PruneConfig) error {
 	if !cfg.Filter.HasFilters() {
 		return fmt.Errorf(
@@ -114,6 +152,22 @@ func (p *Pruner) Prune(cfg PruneConfig) error {
 
 	writeSummary(p.Out, candidates)
 
+	// One scanner serves the picker and the confirmation, so
+	// input buffered by one is not lost to the other.
+	in := bufio.NewScanner(p.In)
+	if cfg.Interactive {
+		var ok bool
+		candidates, ok = pickSessions(in, p.Out, candidates)
+		if !ok {
+			fmt.Fprintln(p.Out, "Aborted.")
+			return nil
+		}
+		if len(candidates) == 0 {
+			fmt.Fprintln(p.Out, "No sessions selected.")
+			return nil
+		}
+	}
+
 	if cfg.DryRun {
 		fmt.Fprintln(p.Out, "\nDry run: no changes made.")
 		return nil
@@ -123,7 +177,7 @@ func (p *Pruner) Prune(cfg PruneConfig) error {
 		msg := fmt.Sprintf(
 			"\nDelete %d sessions?", len(candidates),
 		)
-		if !confirm(p.In, p.Out, msg) {
+		if !confirmScanner(in, p.Out, msg) {
 			fmt.Fprintln(p.Out, "Aborted.")
 			return nil
 		}
@@ -139,7 +193,18 @@ func (p *Pruner) Prune(cfg PruneConfig) error {
 		return fmt.Errorf("deleting sessions: %w", err)
 	}
 
-	filesRemoved, bytesReclaimed := deleteFiles(candidates)
+	if !cfg.DeleteFiles {
+		fmt.Fprintf(p.Out, "\nDeleted %d sessions\n", deleted)
+		return nil
+	}
+
+	local, err := p.DB.LocalSessions(
+		candidates, cfg.Filter.LocalMachine,
+	)
+	if err != nil {
+		return fmt.Errorf("finding local sessions: %w", err)
+	}
+	filesRemoved, bytesReclaimed := db.RemoveSessionFiles(local)
 
 	fmt.Fprintf(p.Out,
 		"\nDeleted %d sessions, removed %d files"+
@@ -150,13 +215,144 @@ func (p *Pruner) Prune(cfg PruneConfig) error {
 }
 
 func confirm(r io.Reader, w io.Writer, msg string) bool {
+	return confirmScanner(bufio.NewScanner(r), w, msg)
+}
+
+func confirmScanner(
+	scanner *bufio.Scanner, w io.Writer, msg string,
+) bool {
 	fmt.Fprintf(w, "%s [y/N] ", msg)
-	scanner := bufio.NewScanner(r)
 	scanner.Scan()
 	ans := strings.ToLower(strings.TrimSpace(scanner.Text()))
 	return ans == "y" || ans == "yes"
 }
 
+// pickSessions lists sessions with their sizes and lets the
+// user toggle which to keep selected, all selected to start.
+// Returns the selected sessions, or false if the user quit or
+// input ended before the selection was accepted.
+func pickSessions(
+	scanner *bufio.Scanner, w io.Writer, sessions []db.Session,
+) ([]db.Session, bool) {
+	selected := make([]bool, len(sessions))
+	for i := range selected {
+		selected[i] = true
+	}
+	for {
+		fmt.Fprintln(w)
+		writePickList(w, sessions, selected)
+		fmt.Fprint(w, "\nToggle by number or range (e.g. 1 3-5),"+
+			" a=all, n=none, Enter=done, q=quit: ")
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return nil, false
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch strings.ToLower(line) {
+		case "":
+			var picked []db.Session
+			for i, s := range sessions {
+				if selected[i] {
+					picked = append(picked, s)
+				}
+			}
+			return picked, true
+		case "q", "quit":
+			return nil, false
+		case "a", "all":
+			for i := range selected {
+				selected[i] = true
+			}
+		case "n", "none":
+			for i := range selected {
+				selected[i] = false
+			}
+		default:
+			idxs, err := parseSelection(line, len(sessions))
+			if err != nil {
+				fmt.Fprintln(w, "error:", err)
+				continue
+			}
+			for _, i := range idxs {
+				selected[i] = !selected[i]
+			}
+		}
+	}
+}
+
+func writePickList(
+	w io.Writer, sessions []db.Session, selected []bool,
+) {
+	var total int64
+	count := 0
+	for i, s := range sessions {
+		var size int64
+		if s.FileSize != nil {
+			size = *s.FileSize
+		}
+		mark := " "
+		if selected[i] {
+			mark = "x"
+			count++
+			total += size
+		}
+		date := ""
+		if s.EndedAt != nil && len(*s.EndedAt) >= 10 {
+			date = (*s.EndedAt)[:10]
+		}
+		first := ""
+		if s.FirstMessage != nil {
+			first = truncateLine(*s.FirstMessage, 40)
+		}
+		fmt.Fprintf(w, "  [%s] %3d  %-10s  %-24s %4d msgs %9s  %s\n",
+			mark, i+1, date, truncateLine(s.Project, 24),
+			s.MessageCount, formatBytes(size), first)
+	}
+	fmt.Fprintf(w, "\n%d of %d selected (%s)\n",
+		count, len(sessions), formatBytes(total))
+}
+
+// parseSelection parses space- or comma-separated 1-based
+// numbers and ranges like "1 3-5" into 0-based indexes below n.
+func parseSelection(s string, n int) ([]int, error) {
+	var idxs []int
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+	for _, f := range fields {
+		lo, hi, isRange := strings.Cut(f, "-")
+		start, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q", f)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("invalid selection %q", f)
+			}
+		}
+		if start < 1 || end > n || start > end {
+			return nil, fmt.Errorf(
+				"selection %q out of range 1-%d", f, n,
+			)
+		}
+		for i := start; i <= end; i++ {
+			idxs = append(idxs, i-1)
+		}
+	}
+	return idxs, nil
+}
+
+// truncateLine flattens s to one line of at most max runes.
+func truncateLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
+
 func writeSummary(w io.Writer, sessions []db.Session) {
 	var totalSize int64
 	byProject := map[string]int{}
@@ -184,43 +380,6 @@ func writeSummary(w io.Writer, sessions []db.Session) {
 	}
 }
 
-func deleteFiles(sessions []db.Session) (int, int64) {
-	removed := 0
-	var reclaimed int64
-
-	for _, s := range sessions {
-		if s.FilePath == nil {
-			continue
-		}
-		path := *s.FilePath
-
-		info, err := os.Stat(path)
-		size := int64(0)
-		if err == nil {
-			size = info.Size()
-		}
-
-		if err := os.Remove(path); err != nil {
-			if !os.IsNotExist(err) {
-				log.Printf(
-					"warning: removing %s: %v", path, err,
-				)
-			}
-			continue
-		}
-		removed++
-		reclaimed += size
-
-		// Remove parent directory if empty (session subdirs).
-		dir := filepath.Dir(path)
-		entries, err := os.ReadDir(dir)
-		if err == nil && len(entries) == 0 {
-			_ = os.Remove(dir)
-		}
-	}
-	return removed, reclaimed
-}
-
 func formatBytes(b int64) string {
 	switch {
 	case b >= 1<<30:
@@ -39,7 +39,7 @@ func TestParsePruneFlags(t *testing.T) {
 						cfg.Filter.Project, "myapp",
 					)
 				}
-				if cfg.DryRun || cfg.Yes {
+				if cfg.DryRun || cfg.Yes || cfg.DeleteFiles {
 					t.Error("unexpected flag defaults")
 				}
 			},
@@ -51,6 +51,9 @@ func TestParsePruneFlags(t *testing.T) {
 				"--max-messages", "5",
 				"--before", "2024-01-01",
 				"--first-message", "hello",
+				"--include-chain",
+				"--interactive",
+				"--delete-files",
 				"--dry-run",
 				"--yes",
 			},
@@ -73,12 +76,21 @@ func TestParsePruneFlags(t *testing.T) {
 						cfg.Filter.FirstMessage,
 					)
 				}
+				if !cfg.Filter.IncludeChain {
+					t.Error("IncludeChain should be true")
+				}
+				if !cfg.Interactive {
+					t.Error("Interactive should be true")
+				}
 				if !cfg.DryRun {
 					t.Error("DryRun should be true")
 				}
 				if !cfg.Yes {
 					t.Error("Yes should be true")
 				}
+				if !cfg.DeleteFiles {
+					t.Error("DeleteFiles should be true")
+				}
 			},
 		},
 		{
@@ -86,6 +98,24 @@ func TestParsePruneFlags(t *testing.T) {
 			args:    []string{"--bogus"},
 			wantErr: "flag provided but not defined",
 		},
+		{
+			name: "agent and orphaned",
+			args: []string{"--agent", "codex", "--orphaned"},
+			check: func(t *testing.T, cfg PruneConfig) {
+				t.Helper()
+				if cfg.Filter.Agent != "codex" {
+					t.Errorf("Agent = %q", cfg.Filter.Agent)
+				}
+				if !cfg.Filter.Orphaned {
+					t.Error("Orphaned should be true")
+				}
+			},
+		},
+		{
+			name:    "unknown agent",
+			args:    []string{"--agent", "clippy"},
+			wantErr: "unknown agent",
+		},
 		{
 			name:    "negative max-messages",
 			args:    []string{"--max-messages", "-2"},
@@ -352,66 +382,154 @@ func TestPruner_PruneScenarios(t *testing.T) {
 	}
 }
 
-func TestDeleteFilesRemovesFiles(t *testing.T) {
-	dir := t.TempDir()
-	subdir := filepath.Join(dir, "session1")
-	if err := os.MkdirAll(subdir, 0o755); err != nil {
-		t.Fatal(err)
-	}
-
-	f := filepath.Join(subdir, "data.jsonl")
-	if err := os.WriteFile(f, []byte("test data"), 0o644); err != nil {
-		t.Fatal(err)
-	}
-
-	sessions := []db.Session{
-		{ID: "s1", FilePath: dbtest.Ptr(f)},
-	}
-
-	removed, reclaimed := deleteFiles(sessions)
-	if removed != 1 {
-		t.Errorf("removed = %d, want 1", removed)
-	}
-	if reclaimed != 9 {
-		t.Errorf("reclaimed = %d, want 9", reclaimed)
-	}
+func TestPrunerDeleteFiles(t *testing.T) {
+	for _, deleteFiles := range []bool{false, true} {
+		t.Run(fmt.Sprint("delete_files=", deleteFiles), func(t *testing.T) {
+			d := dbtest.OpenTestDB(t)
+			dir := t.TempDir()
+			paths := map[string]string{}
+			for _, id := range []string{"local1", "pushed1"} {
+				paths[id] = filepath.Join(dir, id+".jsonl")
+				if err := os.WriteFile(paths[id], []byte("data"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				dbtest.SeedSession(t, d, id, "test", func(s *db.Session) {
+					s.FilePath = dbtest.Ptr(paths[id])
+					if id == "pushed1" {
+						s.Machine = "laptop"
+					}
+				})
+			}
 
-	// File should be gone.
-	if _, err := os.Stat(f); !os.IsNotExist(err) {
-		t.Error("file still exists")
-	}
+			pruner, buf := newTestPruner(t, d, "")
+			cfg := PruneConfig{
+				Filter:      db.PruneFilter{Project: "test"},
+				Yes:         true,
+				DeleteFiles: deleteFiles,
+			}
+			if err := pruner.Prune(cfg); err != nil {
+				t.Fatalf("Prune: %v", err)
+			}
+			if !strings.Contains(buf.String(), "Deleted 2 sessions") {
+				t.Errorf("output = %q", buf.String())
+			}
 
-	// Empty parent dir should be removed.
-	if _, err := os.Stat(subdir); !os.IsNotExist(err) {
-		t.Error("empty parent dir still exists")
+			_, err := os.Stat(paths["local1"])
+			if gone := os.IsNotExist(err); gone != deleteFiles {
+				t.Errorf("local file removed = %v, want %v",
+					gone, deleteFiles)
+			}
+			// Pushed sessions' paths belong to another machine.
+			if _, err := os.Stat(paths["pushed1"]); err != nil {
+				t.Errorf("pushed session file: %v", err)
+			}
+		})
 	}
 }
 
-func TestDeleteFilesMissingFile(t *testing.T) {
-	sessions := []db.Session{
-		{ID: "s1", FilePath: dbtest.Ptr("/nonexistent/path/file.jsonl")},
-	}
-
-	removed, reclaimed := deleteFiles(sessions)
-	if removed != 0 {
-		t.Errorf("removed = %d, want 0", removed)
+func TestParseSelection(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"1", "[0]", false},
+		{"1 3-5", "[0 2 3 4]", false},
+		{"2,4", "[1 3]", false},
+		{"5-5", "[4]", false},
+		{"0", "", true},
+		{"6", "", true},
+		{"4-2", "", true},
+		{"x", "", true},
+		{"1-x", "", true},
 	}
-	if reclaimed != 0 {
-		t.Errorf("reclaimed = %d, want 0", reclaimed)
+	for _, tt := range tests {
+		got, err := parseSelection(tt.in, 5)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseSelection(%q) = %v, want error",
+					tt.in, got)
+			}
+			continue
+		}
+		if err != nil || fmt.Sprint(got) != tt.want {
+			t.Errorf("parseSelection(%q) = %v, %v; want %s",
+				tt.in, got, err, tt.want)
+		}
 	}
 }
 
-func TestDeleteFilesNilPath(t *testing.T) {
-	sessions := []db.Session{
-		{ID: "s1", FilePath: nil},
+func TestPrunerInteractive(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantOut string
+		kept    []string
+	}{
+		{
+			name:    "deselect one",
+			input:   "2\n\ny\n",
+			wantOut: "Deleted 2 sessions",
+			kept:    []string{"s2"},
+		},
+		{
+			// Candidates list most recent first, so 1 is s3.
+			name:    "bad selection then none",
+			input:   "9\nn\n1\n\ny\n",
+			wantOut: "out of range",
+			kept:    []string{"s1", "s2"},
+		},
+		{
+			name:    "select none",
+			input:   "n\n\n",
+			wantOut: "No sessions selected",
+			kept:    []string{"s1", "s2", "s3"},
+		},
+		{
+			name:    "quit",
+			input:   "q\n",
+			wantOut: "Aborted",
+			kept:    []string{"s1", "s2", "s3"},
+		},
+		{
+			name:    "input ends",
+			input:   "",
+			wantOut: "Aborted",
+			kept:    []string{"s1", "s2", "s3"},
+		},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dbtest.OpenTestDB(t)
+			for _, id := range []string{"s1", "s2", "s3"} {
+				dbtest.SeedSession(t, d, id, "test", func(s *db.Session) {
+					s.EndedAt = dbtest.Ptr("2024-01-0" + id[1:] + "T00:00:00Z")
+					s.MessageCount = 0
+				})
+			}
 
-	removed, reclaimed := deleteFiles(sessions)
-	if removed != 0 {
-		t.Errorf("removed = %d, want 0", removed)
-	}
-	if reclaimed != 0 {
-		t.Errorf("reclaimed = %d, want 0", reclaimed)
+			pruner, buf := newTestPruner(t, d, tt.input)
+			cfg := PruneConfig{
+				Filter:      db.PruneFilter{Project: "test"},
+				Interactive: true,
+			}
+			if err := pruner.Prune(cfg); err != nil {
+				t.Fatalf("Prune: %v", err)
+			}
+			if out := buf.String(); !strings.Contains(out, tt.wantOut) {
+				t.Errorf("output missing %q:\n%s", tt.wantOut, out)
+			}
+
+			var kept []string
+			for _, id := range []string{"s1", "s2", "s3"} {
+				if s, _ := d.GetSession(context.Background(), id); s != nil {
+					kept = append(kept, id)
+				}
+			}
+			if fmt.Sprint(kept) != fmt.Sprint(tt.kept) {
+				t.Errorf("kept = %v, want %v", kept, tt.kept)
+			}
+		})
 	}
 }
 
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/server"
+)
+
+const (
+	// pushCheckBatch is how many session sources one check
+	// request carries.
+	pushCheckBatch = 500
+	// pushBatch is how many sessions one ingest request carries.
+	pushBatch = 20
+	// localMachine is the machine label sync gives sessions
+	// found on this machine.
+	localMachine = "local"
+)
+
+// PushConfig holds parsed CLI options for the push command.
+// Empty URL, Token, and Machine fall back to the push section
+// of the config file.
+type PushConfig struct {
+	URL     string
+	Token   string
+	Machine string // label for sessions synced on this machine
+	Project string // push only this project
+	DryRun  bool
+}
+
+func parsePushFlags(args []string) (PushConfig, error) {
+	fs := flag.NewFlagSet("push", flag.ContinueOnError)
+	url := fs.String(
+		"url", "", "Base URL of the agentsview server to push to",
+	)
+	token := fs.String(
+		"token", "", "Ingest token configured on that server",
+	)
+	machine := fs.String(
+		"machine", "",
+		"Machine name for this machine's sessions (default: host name)",
+	)
+	project := fs.String("project", "", "Only push this project")
+	dryRun := fs.Bool(
+		"dry-run", false, "Report what would be pushed without sending",
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return PushConfig{}, err
+	}
+	if fs.NArg() > 0 {
+		return PushConfig{}, fmt.Errorf(
+			"unexpected arguments: %s", strings.Join(fs.Args(), " "),
+		)
+	}
+	return PushConfig{
+		URL:     *url,
+		Token:   *token,
+		Machine: *machine,
+		Project: *project,
+		DryRun:  *dryRun,
+	}, nil
+}
+
+// withDefaults fills unset options from the config file's push
+// target and the host name.
+func (c PushConfig) withDefaults(t config.PushTarget) (PushConfig, error) {
+	if c.URL == "" {
+		c.URL = t.URL
+	}
+	if c.Token == "" {
+		c.Token = t.Token
+	}
+	if c.Machine == "" {
+		c.Machine = t.Machine
+	}
+	if c.Machine == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return c, fmt.Errorf("reading host name: %w", err)
+		}
+		c.Machine = host
+	}
+	if c.URL == "" {
+		return c, fmt.Errorf("no server: pass --url or set push.url in config")
+	}
+	if c.Token == "" {
+		return c, fmt.Errorf("no token: pass --token or set push.token in config")
+	}
+	c.URL = strings.TrimRight(c.URL, "/")
+	return c, nil
+}
+
+// Pusher sends stored sessions to a central agentsview server.
+type Pusher struct {
+	DB     *db.DB
+	Client *http.Client
+	Out    io.Writer
+}
+
+// Push asks the server which sessions it lacks or holds an
+// older copy of, by file hash, and sends those with their
+// messages. Sessions synced on this machine are labeled with
+// cfg.Machine; imported ones keep their label.
+func (p *Pusher) Push(ctx context.Context, cfg PushConfig) error {
+	srcs, err := p.DB.ListSessionSources(ctx, cfg.Project)
+	if err != nil {
+		return err
+	}
+
+	var needed []string
+	for i := 0; i < len(srcs); i += pushCheckBatch {
+		var resp server.IngestCheckResponse
+		err := p.post(ctx, cfg, "/api/v1/ingest/check",
+			server.IngestCheckRequest{
+				Sessions: srcs[i:min(i+pushCheckBatch, len(srcs))],
+			}, &resp)
+		if err != nil {
+			return err
+		}
+		needed = append(needed, resp.Needed...)
+	}
+	fmt.Fprintf(p.Out, "%d sessions, %d to push\n", len(srcs), len(needed))
+	if cfg.DryRun {
+		return nil
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < len(needed); i += pushBatch {
+		var req server.IngestRequest
+		for _, id := range needed[i:min(i+pushBatch, len(needed))] {
+			in, err := p.load(ctx, id, cfg.Machine)
+			if err != nil {
+				return err
+			}
+			if in != nil {
+				req.Sessions = append(req.Sessions, *in)
+			}
+		}
+		var resp server.IngestResponse
+		if err := p.post(ctx, cfg, "/api/v1/ingest", req, &resp); err != nil {
+			return err
+		}
+		for _, r := range resp.Results {
+			counts[r.Status]++
+		}
+	}
+
+	fmt.Fprintf(p.Out, "Pushed %d sessions", counts[db.IngestStored])
+	if n := counts[db.IngestStale]; n > 0 {
+		fmt.Fprintf(p.Out, ", %d kept newer copies on the server", n)
+	}
+	fmt.Fprintln(p.Out)
+	return nil
+}
+
+// load reads a session to push, or nil if it has since been
+// deleted.
+func (p *Pusher) load(
+	ctx context.Context, id, machine string,
+) (*db.IngestSession, error) {
+	s, err := p.DB.GetSessionFull(ctx, id)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	msgs, err := p.DB.GetAllMessages(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("loading messages for %s: %w", id, err)
+	}
+	if s.Machine == localMachine {
+		s.Machine = machine
+	}
+	return &db.IngestSession{Session: *s, Messages: msgs}, nil
+}
+
+// post sends body as JSON to the server's path and decodes the
+// response into out.
+func (p *Pusher) post(
+	ctx context.Context, cfg PushConfig, path string, body, out any,
+) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, cfg.URL+path, bytes.NewReader(data),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+cfg.Token)
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(msg, &e) == nil && e.Error != "" {
+			msg = []byte(e.Error)
+		}
+		return fmt.Errorf("%s: %s: %s",
+			path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func runPush(args []string) {
+	cfg, err := parsePushFlags(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	appCfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+	cfg, err = cfg.withDefaults(appCfg.Push)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	database, err := db.Open(appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	p := &Pusher{
+		DB:     database,
+		Client: &http.Client{Timeout: 5 * time.Minute},
+		Out:    os.Stdout,
+	}
+	if err := p.Push(context.Background(), cfg); err != nil {
+		log.Fatalf("push: %v", err)
+	}
+}
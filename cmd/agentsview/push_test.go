@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+	"github.com/wesm/agentsview/internal/server"
+	"github.com/wesm/agentsview/internal/sync"
+)
+
+func TestPushConfigDefaults(t *testing.T) {
+	cfg, err := parsePushFlags([]string{"--url", "http://central:8080/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = cfg.withDefaults(config.PushTarget{
+		URL: "http://other", Token: "t0ken", Machine: "laptop",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PushConfig{
+		URL: "http://central:8080", Token: "t0ken", Machine: "laptop",
+	}
+	if cfg != want {
+		t.Errorf("cfg = %+v, want %+v", cfg, want)
+	}
+
+	_, err = PushConfig{URL: "http://central"}.withDefaults(config.PushTarget{})
+	if err == nil || !strings.Contains(err.Error(), "no token") {
+		t.Errorf("err = %v, want missing token", err)
+	}
+}
+
+func TestPusher(t *testing.T) {
+	central := dbtest.OpenTestDB(t)
+	srv := server.New(config.Config{
+		Host: "127.0.0.1", IngestToken: "s3cret", WriteTimeout: 30 * time.Second,
+	}, central, sync.NewEngine(central, sync.EngineConfig{}))
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	local := dbtest.OpenTestDB(t)
+	ctx := context.Background()
+	dbtest.SeedSession(t, local, "s1", "site", func(s *db.Session) {
+		s.FileHash = dbtest.Ptr("h1")
+	})
+	dbtest.SeedSession(t, local, "s2", "site", func(s *db.Session) {
+		s.Machine = "imported"
+	})
+	dbtest.SeedMessages(t, local,
+		dbtest.UserMsg("s1", 0, "hello"), dbtest.UserMsg("s2", 0, "hi"),
+	)
+
+	var out bytes.Buffer
+	p := &Pusher{DB: local, Client: ts.Client(), Out: &out}
+	cfg := PushConfig{URL: ts.URL, Token: "s3cret", Machine: "laptop"}
+	if err := p.Push(ctx, cfg); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if !strings.Contains(out.String(), "Pushed 2 sessions") {
+		t.Errorf("output = %q", out.String())
+	}
+	for id, machine := range map[string]string{"s1": "laptop", "s2": "imported"} {
+		s, err := central.GetSession(ctx, id)
+		if err != nil || s == nil {
+			t.Fatalf("central %s = %v, %v", id, s, err)
+		}
+		if s.Machine != machine {
+			t.Errorf("%s machine = %q, want %q", id, s.Machine, machine)
+		}
+		msgs, err := central.GetAllMessages(ctx, id)
+		if err != nil || len(msgs) != 1 {
+			t.Errorf("%s messages = %v, %v", id, msgs, err)
+		}
+	}
+
+	// s1 is unchanged by hash; s2 has no hash and is resent.
+	out.Reset()
+	if err := p.Push(ctx, cfg); err != nil {
+		t.Fatalf("second Push: %v", err)
+	}
+	if !strings.Contains(out.String(), "2 sessions, 1 to push") {
+		t.Errorf("second output = %q", out.String())
+	}
+
+	cfg.Token = "wrong"
+	err := p.Push(ctx, cfg)
+	if err == nil || !strings.Contains(err.Error(), "invalid ingest token") {
+		t.Errorf("err = %v, want invalid token", err)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// ScanSecretsConfig holds parsed CLI options for the
+// scan-secrets command.
+type ScanSecretsConfig struct {
+	Project     string
+	Redact      bool
+	Prune       bool
+	DeleteFiles bool
+	JSON        bool
+	Yes         bool
+}
+
+func parseScanSecretsFlags(args []string) (ScanSecretsConfig, error) {
+	fs := flag.NewFlagSet("scan-secrets", flag.ContinueOnError)
+	project := fs.String(
+		"project", "",
+		"Only scan sessions whose project contains this substring",
+	)
+	redact := fs.Bool(
+		"redact", false,
+		"Replace detected secrets in the database with markers",
+	)
+	prune := fs.Bool(
+		"prune", false,
+		"Delete affected sessions from the database",
+	)
+	deleteFiles := fs.Bool(
+		"delete-files", false,
+		"With --prune, also delete the source files of sessions"+
+			" synced on this machine; without it, the next sync"+
+			" imports those sessions again",
+	)
+	jsonOut := fs.Bool(
+		"json", false,
+		"Print the report as JSON",
+	)
+	yes := fs.Bool(
+		"yes", false,
+		"Skip confirmation prompt",
+	)
+
+	if err := fs.Parse(args); err != nil {
+		return ScanSecretsConfig{}, err
+	}
+	if *redact && *prune {
+		return ScanSecretsConfig{}, fmt.Errorf(
+			"--redact and --prune are mutually exclusive",
+		)
+	}
+	if *deleteFiles && !*prune {
+		return ScanSecretsConfig{}, fmt.Errorf(
+			"--delete-files requires --prune",
+		)
+	}
+	if *jsonOut && (*redact || *prune) && !*yes {
+		return ScanSecretsConfig{}, fmt.Errorf(
+			"--json with --redact or --prune requires --yes",
+		)
+	}
+	return ScanSecretsConfig{
+		Project:     *project,
+		Redact:      *redact,
+		Prune:       *prune,
+		DeleteFiles: *deleteFiles,
+		JSON:        *jsonOut,
+		Yes:         *yes,
+	}, nil
+}
+
+// SecretScanner reports sessions containing likely secrets and
+// optionally redacts or prunes them.
+type SecretScanner struct {
+	DB  *db.DB
+	Out io.Writer
+	In  io.Reader
+}
+
+// Scan runs the detectors and applies the requested action.
+// Secret values are never printed.
+func (s *SecretScanner) Scan(
+	ctx context.Context, cfg ScanSecretsConfig,
+) error {
+	found, err := s.DB.ScanSecrets(ctx, cfg.Project)
+	if err != nil {
+		return fmt.Errorf("scanning: %w", err)
+	}
+
+	if cfg.JSON {
+		enc := json.NewEncoder(s.Out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(found); err != nil {
+			return err
+		}
+	} else {
+		writeSecretsReport(s.Out, found)
+	}
+	if len(found) == 0 || (!cfg.Redact && !cfg.Prune) {
+		return nil
+	}
+
+	ids := make([]string, len(found))
+	for i, f := range found {
+		ids[i] = f.SessionID
+	}
+
+	if cfg.Redact {
+		if !cfg.Yes && !confirm(s.In, s.Out, fmt.Sprintf(
+			"\nRedact secrets in %d sessions?", len(ids),
+		)) {
+			fmt.Fprintln(s.Out, "Aborted.")
+			return nil
+		}
+		res, err := s.DB.RedactSecrets(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("redacting: %w", err)
+		}
+		if !cfg.JSON {
+			fmt.Fprintf(s.Out,
+				"\nRedacted %d findings in %d messages,"+
+					" %d tool calls, and %d hook events.\n"+
+					"Source files are unchanged; a full resync"+
+					" restores the original text.\n",
+				res.Findings, res.Messages,
+				res.ToolCalls, res.HookEvents,
+			)
+		}
+		return nil
+	}
+
+	sessions := make([]db.Session, 0, len(ids))
+	for _, id := range ids {
+		sess, err := s.DB.GetSessionFull(ctx, id)
+		if err != nil {
+			return fmt.Errorf("loading session %s: %w", id, err)
+		}
+		if sess != nil {
+			sessions = append(sessions, *sess)
+		}
+	}
+	if !cfg.Yes {
+		fmt.Fprintln(s.Out)
+		writeSummary(s.Out, sessions)
+		msg := fmt.Sprintf("\nDelete %d sessions?", len(sessions))
+		if cfg.DeleteFiles {
+			msg = fmt.Sprintf(
+				"\nDelete %d sessions and their local source files?",
+				len(sessions),
+			)
+		}
+		if !confirm(s.In, s.Out, msg) {
+			fmt.Fprintln(s.Out, "Aborted.")
+			return nil
+		}
+	}
+	deleted, err := s.DB.DeleteSessions(ids)
+	if err != nil {
+		return fmt.Errorf("deleting sessions: %w", err)
+	}
+	if !cfg.DeleteFiles {
+		if !cfg.JSON {
+			fmt.Fprintf(s.Out,
+				"\nDeleted %d sessions. Source files are"+
+					" unchanged; the next sync imports them again.\n",
+				deleted,
+			)
+		}
+		return nil
+	}
+	local, err := s.DB.LocalSessions(sessions, localMachine)
+	if err != nil {
+		return fmt.Errorf("finding local sessions: %w", err)
+	}
+	filesRemoved, bytesReclaimed := db.RemoveSessionFiles(local)
+	if !cfg.JSON {
+		fmt.Fprintf(s.Out,
+			"\nDeleted %d sessions, removed %d files"+
+				" (%s reclaimed)\n",
+			deleted, filesRemoved, formatBytes(bytesReclaimed),
+		)
+	}
+	return nil
+}
+
+func writeSecretsReport(w io.Writer, found []db.SessionSecrets) {
+	if len(found) == 0 {
+		fmt.Fprintln(w, "No likely secrets found.")
+		return
+	}
+	total := 0
+	for _, f := range found {
+		total += f.Findings
+	}
+	fmt.Fprintf(w,
+		"Found %d likely secrets in %d sessions\n\n",
+		total, len(found),
+	)
+	for _, f := range found {
+		rules := make([]string, 0, len(f.Rules))
+		for r, n := range f.Rules {
+			rules = append(rules, fmt.Sprintf("%s=%d", r, n))
+		}
+		sort.Strings(rules)
+		fmt.Fprintf(w, "%s  %s  (%s)\n",
+			f.SessionID, f.Project, f.Agent)
+		fmt.Fprintf(w, "  %d findings: %s\n",
+			f.Findings, strings.Join(rules, ", "))
+		if len(f.Ordinals) > 0 {
+			ords := make([]string, len(f.Ordinals))
+			for i, o := range f.Ordinals {
+				ords[i] = fmt.Sprint(o)
+			}
+			fmt.Fprintf(w, "  messages: %s\n",
+				strings.Join(ords, ", "))
+		}
+	}
+}
+
+func runScanSecrets(args []string) {
+	cfg, err := parseScanSecretsFlags(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	appCfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+
+	database, err := db.Open(appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	scanner := &SecretScanner{
+		DB:  database,
+		Out: os.Stdout,
+		In:  os.Stdin,
+	}
+	if err := scanner.Scan(context.Background(), cfg); err != nil {
+		log.Fatalf("scan-secrets: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/sync"
+)
+
+// defaultShadowSample is how many files a shadow run reparses
+// unless -sample says otherwise.
+const defaultShadowSample = 200
+
+// ShadowConfig holds parsed CLI options for the shadow command.
+type ShadowConfig struct {
+	Sample int
+	JSON   bool
+}
+
+func parseShadowFlags(args []string) (ShadowConfig, error) {
+	fs := flag.NewFlagSet("shadow", flag.ContinueOnError)
+	sample := fs.Int(
+		"sample", defaultShadowSample,
+		"Number of session files to reparse (0 for all)",
+	)
+	jsonOut := fs.Bool(
+		"json", false,
+		"Print the report as JSON",
+	)
+	if err := fs.Parse(args); err != nil {
+		return ShadowConfig{}, err
+	}
+	if *sample < 0 {
+		return ShadowConfig{}, fmt.Errorf(
+			"-sample must be 0 or more",
+		)
+	}
+	return ShadowConfig{Sample: *sample, JSON: *jsonOut}, nil
+}
+
+func writeShadowReport(w io.Writer, r sync.ShadowReport) {
+	fmt.Fprintf(w,
+		"Reparsed %d of %d session files: %d sessions,"+
+			" %d unchanged, %d differ, %d files failed\n",
+		r.FilesSampled, r.FilesDiscovered, r.Sessions,
+		r.Unchanged, len(r.Diffs), len(r.Errors),
+	)
+	for _, d := range r.Diffs {
+		fmt.Fprintf(w, "\n%-8s %s\n", d.Status, d.SessionID)
+		switch {
+		case d.Stored != nil && d.Shadow != nil:
+			writeShapeDelta(w, "messages",
+				d.Stored.MessageCount, d.Shadow.MessageCount)
+			writeShapeDelta(w, "user messages",
+				d.Stored.UserMessageCount, d.Shadow.UserMessageCount)
+			writeShapeDelta(w, "tool calls",
+				d.Stored.ToolCallCount, d.Shadow.ToolCallCount)
+			writeShapeDelta(w, "skipped records",
+				d.Stored.UnknownRecords, d.Shadow.UnknownRecords)
+		case d.Stored != nil:
+			writeShape(w, *d.Stored)
+		case d.Shadow != nil:
+			writeShape(w, *d.Shadow)
+		}
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintf(w, "\nfailed   %s (%s)\n  %s\n",
+			e.Path, e.Agent, e.Error)
+	}
+}
+
+func writeShapeDelta(w io.Writer, label string, stored, shadow int) {
+	if stored != shadow {
+		fmt.Fprintf(w, "  %s: %d -> %d\n", label, stored, shadow)
+	}
+}
+
+func writeShape(w io.Writer, s db.SessionShape) {
+	fmt.Fprintf(w, "  %s (%s): %d messages, %d tool calls\n",
+		s.FilePath, s.Agent, s.MessageCount, s.ToolCallCount)
+}
+
+func runShadow(args []string) {
+	cfg, err := parseShadowFlags(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+
+	appCfg, err := config.LoadMinimal()
+	if err != nil {
+		log.Fatalf("loading config: %v", err)
+	}
+	database, err := db.Open(appCfg.DBPath)
+	if err != nil {
+		log.Fatalf("opening database: %v", err)
+	}
+	defer database.Close()
+
+	engine := sync.NewEngine(database, sync.EngineConfig{
+		AgentDirs:               appCfg.AgentDirs,
+		Machine:                 localMachine,
+		BlockedResultCategories: appCfg.ResultContentBlockedCategories,
+		Workers:                 appCfg.SyncWorkers,
+	})
+	report, err := engine.Shadow(context.Background(), cfg.Sample)
+	if err != nil {
+		log.Fatalf("shadow: %v", err)
+	}
+	if cfg.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatalf("shadow: %v", err)
+		}
+		return
+	}
+	writeShadowReport(os.Stdout, report)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/sync"
+)
+
+func TestParseShadowFlags(t *testing.T) {
+	cfg, err := parseShadowFlags(nil)
+	if err != nil || cfg.Sample != defaultShadowSample || cfg.JSON {
+		t.Errorf("defaults = %+v, %v", cfg, err)
+	}
+	cfg, err = parseShadowFlags([]string{"-sample", "0", "-json"})
+	if err != nil || cfg != (ShadowConfig{Sample: 0, JSON: true}) {
+		t.Errorf("cfg = %+v, %v", cfg, err)
+	}
+	_, err = parseShadowFlags([]string{"-sample", "-1"})
+	if err == nil || !strings.Contains(err.Error(), "0 or more") {
+		t.Errorf("err = %v, want sample error", err)
+	}
+}
+
+func TestWriteShadowReport(t *testing.T) {
+	var buf bytes.Buffer
+	writeShadowReport(&buf, sync.ShadowReport{
+		FilesDiscovered: 10, FilesSampled: 3, Sessions: 3,
+		Unchanged: 1,
+		Diffs: []sync.ShadowDiff{
+			{
+				SessionID: "a", Status: sync.ShadowChanged,
+				Stored: &db.SessionShape{MessageCount: 4, ToolCallCount: 2},
+				Shadow: &db.SessionShape{MessageCount: 3, ToolCallCount: 2},
+			},
+			{
+				SessionID: "b", Status: sync.ShadowDropped,
+				Stored: &db.SessionShape{
+					FilePath: "/x/b.jsonl", Agent: "claude",
+					MessageCount: 5,
+				},
+			},
+		},
+		Errors: []sync.ShadowError{
+			{Path: "/x/c.jsonl", Agent: "codex", Error: "bad json"},
+		},
+	})
+	want := `Reparsed 3 of 10 session files: 3 sessions, 1 unchanged, 2 differ, 1 files failed
+
+changed  a
+  messages: 4 -> 3
+
+dropped  b
+  /x/b.jsonl (claude): 5 messages, 0 tool calls
+
+failed   /x/c.jsonl (codex)
+  bad json
+`
+	if got := buf.String(); got != want {
+		t.Errorf("report:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/demo"
 )
 
 type sessionSpec struct {
@@ -45,9 +47,13 @@ var specs = []sessionSpec{
 
 func main() {
 	out := flag.String("out", "", "output database path")
+	demoData := flag.Bool(
+		"demo", false,
+		"write the demo dataset instead of the test fixtures",
+	)
 	flag.Parse()
 	if *out == "" {
-		fmt.Fprintln(os.Stderr, "usage: testfixture -out <path>")
+		fmt.Fprintln(os.Stderr, "usage: testfixture [-demo] -out <path>")
 		os.Exit(1)
 	}
 
@@ -62,6 +68,17 @@ func main() {
 	}
 	defer database.Close()
 
+	if *demoData {
+		n, err := demo.Populate(
+			context.Background(), database, time.Now(),
+		)
+		if err != nil {
+			log.Fatalf("generating demo data: %v", err)
+		}
+		fmt.Printf("Demo DB with %d sessions written to %s\n", n, *out)
+		return
+	}
+
 	// Use a recent base date so fixture data stays within the
 	// default 1-year analytics window.
 	base := time.Now().UTC().AddDate(0, 0, -30).
@@ -1,17 +1,39 @@
 import type {
   SessionPage,
+  BulkActionName,
+  BulkResponse,
+  PruneFilter,
+  PrunePreview,
+  PruneResult,
   Session,
+  SessionNote,
+  NoteSearchResponse,
+  Message,
   MessagesResponse,
+  ShareLink,
+  SharedSessionResponse,
   MinimapResponse,
+  LocaleFormat,
+  SessionSource,
+  RevealResponse,
   SearchResponse,
+  SearchFacetsResponse,
+  RecentFilesResponse,
+  LogLevel,
+  LogsResponse,
   ProjectsResponse,
   MachinesResponse,
   AgentsResponse,
   Stats,
   VersionInfo,
+  HealthResponse,
+  PricingResponse,
   SyncStatus,
   SyncProgress,
   SyncStats,
+  QuarantineResponse,
+  RetryQuarantineResponse,
+  AlertsResponse,
   PublishResponse,
   GithubConfig,
   SetGithubConfigResponse,
@@ -19,10 +41,43 @@ import type {
   ActivityResponse,
   HeatmapResponse,
   ProjectsAnalyticsResponse,
+  BranchesAnalyticsResponse,
+  EntryPointsAnalyticsResponse,
+  LanguagesAnalyticsResponse,
+  SkillUsage,
+  UsageKind,
   HourOfWeekResponse,
   SessionShapeResponse,
   VelocityResponse,
+  SLOResponse,
+  SLOGroupBy,
+  CacheAnalyticsResponse,
+  UsageAnalyticsResponse,
+  CostPhasesResponse,
+  WarmupResponse,
+  ForecastResponse,
+  MonthToDateResponse,
+  MonthlyUsageResponse,
+  ProgressResponse,
+  AnalyticsDefaultsResponse,
+  AnalyticsSnapshot,
+  AnalyticsSnapshotsResponse,
+  HooksAnalyticsResponse,
+  HookEvent,
+  ModelSegment,
+  Todo,
+  OpenTodosResponse,
+  SessionEnv,
+  EnvValue,
+  ModelSwitchesResponse,
+  EditThrashResponse,
+  QualityResponse,
+  RetriesResponse,
+  PasteResponse,
+  TruncationResponse,
   ToolsAnalyticsResponse,
+  ToolSequencesResponse,
+  CommandsAnalyticsResponse,
   TopSessionsResponse,
   Granularity,
   HeatmapMetric,
@@ -81,9 +136,25 @@ export interface ListSessionsParams {
   date_from?: string;
   date_to?: string;
   active_since?: string;
+  /** RFC3339; after bounds are inclusive, before bounds exclusive. */
+  started_after?: string;
+  started_before?: string;
+  ended_after?: string;
+  ended_before?: string;
   min_messages?: number;
   max_messages?: number;
   min_user_messages?: number;
+  quality_below?: number;
+  /** Dominant prompt language, ISO 639-1. */
+  language?: string;
+  tag?: string;
+  /** Words that must all appear in the session's first message. */
+  q?: string;
+  include_archived?: boolean;
+  /** Only pinned sessions. */
+  pinned?: boolean;
+  /** Only archived sessions. */
+  archived?: boolean;
   cursor?: string;
   limit?: number;
 }
@@ -98,10 +169,258 @@ export function getSession(id: string, init?: RequestInit): Promise<Session> {
   return fetchJSON(`/sessions/${id}`, init);
 }
 
+export function getSessionSource(
+  id: string,
+  init?: RequestInit,
+): Promise<SessionSource> {
+  return fetchJSON(`/sessions/${id}/source`, init);
+}
+
+/**
+ * Opens the folder holding a session's source file in the
+ * server machine's file manager. Loopback clients only.
+ */
+export function revealSessionSource(
+  id: string,
+): Promise<RevealResponse> {
+  return fetchJSON(`/sessions/${id}/reveal`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ confirm: true }),
+  });
+}
+
+export function getSessionHooks(
+  id: string,
+  init?: RequestInit,
+): Promise<HookEvent[]> {
+  return fetchJSON(`/sessions/${id}/hooks`, init);
+}
+
+export function getSessionModels(
+  id: string,
+  init?: RequestInit,
+): Promise<ModelSegment[]> {
+  return fetchJSON(`/sessions/${id}/models`, init);
+}
+
+export function getSessionTodos(
+  id: string,
+  init?: RequestInit,
+): Promise<Todo[]> {
+  return fetchJSON(`/sessions/${id}/todos`, init);
+}
+
+export function getSessionEnv(
+  id: string,
+  init?: RequestInit,
+): Promise<SessionEnv> {
+  return fetchJSON(`/sessions/${id}/env`, init);
+}
+
+/** Lists recorded environment settings with session counts. */
+export function listEnvValues(): Promise<{ values: EnvValue[] }> {
+  return fetchJSON("/env");
+}
+
+export interface OpenTodosParams {
+  project?: string;
+  agent?: string;
+  machine?: string;
+  since?: string;
+  days?: number;
+  limit?: number;
+}
+
+/** Lists unfinished todo items across recent sessions. */
+export function listOpenTodos(
+  params: OpenTodosParams = {},
+): Promise<OpenTodosResponse> {
+  return fetchJSON(`/todos${buildQuery({ ...params })}`);
+}
+
+export type BulkFilter = Omit<ListSessionsParams, "cursor" | "limit"> & {
+  q?: string;
+};
+
+/**
+ * Applies a curation action to every session matching filter.
+ * Call without a token to preview the match count; resend with
+ * the returned token to apply.
+ */
+export function bulkSessions(req: {
+  action: BulkActionName;
+  tag?: string;
+  filter: BulkFilter;
+  token?: string;
+}): Promise<BulkResponse> {
+  return fetchJSON("/sessions/bulk", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(req),
+  });
+}
+
+/** Pins or unpins a session; pinned sessions are listed first. */
+export function setSessionPinned(
+  id: string,
+  pinned: boolean,
+): Promise<{ id: string; pinned: boolean }> {
+  return fetchJSON(`/sessions/${id}/pin`, {
+    method: pinned ? "PUT" : "DELETE",
+  });
+}
+
+/** Lists a session's tags. */
+export function getSessionTags(
+  id: string,
+): Promise<{ id: string; tags: string[] }> {
+  return fetchJSON(`/sessions/${id}/tags`);
+}
+
+/** Adds a tag to a session and returns its tags. */
+export function addSessionTag(
+  id: string,
+  tag: string,
+): Promise<{ id: string; tags: string[] }> {
+  return fetchJSON(`/sessions/${id}/tags`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ tag }),
+  });
+}
+
+/** Removes a tag from a session and returns its tags. */
+export function removeSessionTag(
+  id: string,
+  tag: string,
+): Promise<{ id: string; tags: string[] }> {
+  const q = new URLSearchParams({ tag });
+  return fetchJSON(`/sessions/${id}/tags?${q}`, {
+    method: "DELETE",
+  });
+}
+
+/** Lists every tag in use with its session count. */
+export function listTags(): Promise<{
+  tags: { tag: string; count: number }[];
+}> {
+  return fetchJSON("/tags");
+}
+
+/**
+ * Archives or unarchives a session; archived sessions are left
+ * out of listings and analytics unless requested.
+ */
+export function setSessionArchived(
+  id: string,
+  archived: boolean,
+): Promise<{ id: string; archived: boolean }> {
+  return fetchJSON(`/sessions/${id}/archive`, {
+    method: archived ? "PUT" : "DELETE",
+  });
+}
+
+/** Lists a session's notes, whole-session notes first. */
+export function listSessionNotes(
+  id: string,
+): Promise<{ notes: SessionNote[] }> {
+  return fetchJSON(`/sessions/${id}/notes`);
+}
+
+/**
+ * Adds a Markdown note to a session, or to the message at
+ * ordinal when given.
+ */
+export function addSessionNote(
+  id: string,
+  body: string,
+  ordinal?: number,
+): Promise<SessionNote> {
+  return fetchJSON(`/sessions/${id}/notes`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ body, ordinal }),
+  });
+}
+
+/** Replaces the body of a session's note. */
+export function updateSessionNote(
+  id: string,
+  noteId: number,
+  body: string,
+): Promise<SessionNote> {
+  return fetchJSON(`/sessions/${id}/notes/${noteId}`, {
+    method: "PUT",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ body }),
+  });
+}
+
+/** Deletes a session's note. */
+export async function deleteSessionNote(
+  id: string,
+  noteId: number,
+): Promise<void> {
+  const res = await fetch(`${BASE}/sessions/${id}/notes/${noteId}`, {
+    method: "DELETE",
+  });
+  if (!res.ok) {
+    const body = await res.text();
+    throw new ApiError(res.status, apiErrorMessage(res.status, body));
+  }
+}
+
+/** Full-text searches note bodies across all sessions. */
+export function searchNotes(
+  query: string,
+  limit?: number,
+): Promise<NoteSearchResponse> {
+  return fetchJSON(`/search/notes${buildQuery({ q: query, limit })}`);
+}
+
+/**
+ * Lists sessions the prune filters would delete, one page at a
+ * time, with totals and a token for executePrune.
+ */
+export function previewPrune(
+  filter: PruneFilter,
+  page: { limit?: number; offset?: number } = {},
+): Promise<PrunePreview> {
+  return fetchJSON("/prune/preview", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ ...filter, ...page }),
+  });
+}
+
+/**
+ * Deletes the sessions previewed with token. Their source files
+ * are removed only when deleteFiles is set, and only for
+ * sessions synced on the server's machine.
+ */
+export function executePrune(
+  filter: PruneFilter,
+  token: string,
+  deleteFiles = false,
+): Promise<PruneResult> {
+  return fetchJSON("/prune/execute", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({
+      ...filter,
+      token,
+      delete_files: deleteFiles,
+    }),
+  });
+}
+
 /* Messages */
 
 export interface GetMessagesParams {
   from?: number;
+  /** Starts at the message with this source UUID; overrides from. */
+  uuid?: string;
   limit?: number;
   direction?: "asc" | "desc";
 }
@@ -117,6 +436,104 @@ export function getMessages(
   );
 }
 
+/**
+ * Reads a session's messages as a stream of JSON lines, calling
+ * onBatch with the messages parsed from each chunk received, so a
+ * long transcript can render before it has fully arrived.
+ */
+export async function streamMessages(
+  sessionId: string,
+  onBatch: (msgs: Message[]) => void,
+  opts: { from?: number; signal?: AbortSignal } = {},
+): Promise<void> {
+  const res = await fetch(
+    `${BASE}/sessions/${sessionId}/messages/stream` +
+      buildQuery({ from: opts.from }),
+    { signal: opts.signal },
+  );
+  if (!res.ok || !res.body) {
+    const body = await res.text();
+    throw new ApiError(res.status, apiErrorMessage(res.status, body));
+  }
+
+  const reader = res.body.getReader();
+  const decoder = new TextDecoder();
+  let buf = "";
+  const emit = (text: string) => {
+    const msgs = text
+      .split("\n")
+      .filter((line) => line.trim())
+      .map((line) => JSON.parse(line) as Message);
+    if (msgs.length > 0) onBatch(msgs);
+  };
+
+  for (;;) {
+    const { done: eof, value } = await reader.read();
+    if (eof) break;
+    buf += decoder.decode(value, { stream: true });
+    const last = buf.lastIndexOf("\n");
+    if (last !== -1) {
+      emit(buf.slice(0, last));
+      buf = buf.slice(last + 1);
+    }
+  }
+
+  // Flush any remaining multibyte bytes from decoder
+  buf += decoder.decode();
+  emit(buf);
+}
+
+export interface GetMessageContextParams {
+  ordinal?: number;
+  /** Addresses the hit by source UUID instead of ordinal. */
+  uuid?: string;
+  radius?: number;
+}
+
+/** Fetches the messages around one message, e.g. a search hit. */
+export function getMessageContext(
+  sessionId: string,
+  params: GetMessageContextParams,
+  init?: RequestInit,
+): Promise<MessagesResponse> {
+  return fetchJSON(
+    `/sessions/${sessionId}/context${buildQuery({ ...params })}`,
+    init,
+  );
+}
+
+/* Sharing */
+
+/** Creates a signed, expiring read-only link to one session. */
+export function createShareLink(
+  sessionId: string,
+  expiresInHours?: number,
+): Promise<ShareLink> {
+  return fetchJSON(`/sessions/${sessionId}/share`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ expires_in_hours: expiresInHours ?? 0 }),
+  });
+}
+
+export function getSharedSession(
+  token: string,
+  init?: RequestInit,
+): Promise<SharedSessionResponse> {
+  return fetchJSON(`/shared/${encodeURIComponent(token)}`, init);
+}
+
+export function getSharedMessages(
+  token: string,
+  params: GetMessagesParams = {},
+  init?: RequestInit,
+): Promise<MessagesResponse> {
+  return fetchJSON(
+    `/shared/${encodeURIComponent(token)}/messages${buildQuery({ ...params })}`,
+    init,
+  );
+}
+
 export interface GetMinimapParams {
   from?: number;
   max?: number;
@@ -133,10 +550,26 @@ export function getMinimap(
 
 /* Search */
 
+export interface SearchFilterParams {
+  project?: string;
+  agent?: string;
+  machine?: string;
+  tag?: string;
+  role?: "user" | "assistant";
+  has_tool_use?: boolean;
+  /** YYYY-MM */
+  month?: string;
+  /** YYYY-MM-DD, inclusive */
+  date_from?: string;
+  /** YYYY-MM-DD, inclusive */
+  date_to?: string;
+  /** Scope to one session; results are ordered by ordinal. */
+  session_id?: string;
+}
+
 export function search(
   query: string,
-  params: {
-    project?: string;
+  params: SearchFilterParams & {
     limit?: number;
     cursor?: number;
   } = {},
@@ -148,16 +581,87 @@ export function search(
   return fetchJSON(`/search${buildQuery({ q: query, ...params })}`, init);
 }
 
+/** Fetches hit counts by project, agent, and month for a query. */
+export function searchFacets(
+  query: string,
+  params: SearchFilterParams = {},
+  init?: RequestInit,
+): Promise<SearchFacetsResponse> {
+  if (!query) {
+    throw new Error("search query must not be empty");
+  }
+  return fetchJSON(
+    `/search/facets${buildQuery({ q: query, ...params })}`,
+    init,
+  );
+}
+
+/* Files */
+
+export interface RecentFilesParams {
+  project?: string;
+  path?: string;
+  limit?: number;
+  touches?: number;
+}
+
+export function getRecentFiles(
+  params: RecentFilesParams = {},
+): Promise<RecentFilesResponse> {
+  return fetchJSON(`/files/recent${buildQuery({ ...params })}`);
+}
+
+/* Logs */
+
+export interface LogsParams {
+  level?: LogLevel;
+  /** RFC 3339 timestamp or a duration such as "15m" */
+  since?: string;
+  limit?: number;
+}
+
+export function getLogs(
+  params: LogsParams = {},
+): Promise<LogsResponse> {
+  return fetchJSON(`/logs${buildQuery({ ...params })}`);
+}
+
 /* Metadata */
 
-export function getProjects(): Promise<ProjectsResponse> {
-  return fetchJSON("/projects");
+export function getProjects(
+  params: { include_inactive?: boolean } = {},
+): Promise<ProjectsResponse> {
+  return fetchJSON(`/projects${buildQuery({ ...params })}`);
 }
 
 export function getMachines(): Promise<MachinesResponse> {
   return fetchJSON("/machines");
 }
 
+/** Renames a machine to an unused name. */
+export function renameMachine(
+  name: string,
+  to: string,
+): Promise<{ machine: string }> {
+  return fetchJSON(`/machines/${encodeURIComponent(name)}/rename`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ to }),
+  });
+}
+
+/** Merges a machine into another existing machine. */
+export function mergeMachine(
+  name: string,
+  into: string,
+): Promise<{ machine: string }> {
+  return fetchJSON(`/machines/${encodeURIComponent(name)}/merge`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ into }),
+  });
+}
+
 export function getAgents(): Promise<AgentsResponse> {
   return fetchJSON("/agents");
 }
@@ -170,12 +674,39 @@ export function getVersion(): Promise<VersionInfo> {
   return fetchJSON("/version");
 }
 
+export function getHealth(): Promise<HealthResponse> {
+  return fetchJSON("/health");
+}
+
+export function getPricing(): Promise<PricingResponse> {
+  return fetchJSON("/pricing");
+}
+
 /* Sync */
 
 export function getSyncStatus(): Promise<SyncStatus> {
   return fetchJSON("/sync/status");
 }
 
+export function getQuarantine(): Promise<QuarantineResponse> {
+  return fetchJSON("/sync/quarantine");
+}
+
+/** Retries one quarantined file now, or all when path is omitted. */
+export function retryQuarantine(
+  path?: string,
+): Promise<RetryQuarantineResponse> {
+  return fetchJSON("/sync/quarantine/retry", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(path ? { path } : {}),
+  });
+}
+
+export function getAlerts(): Promise<AlertsResponse> {
+  return fetchJSON("/alerts");
+}
+
 export interface SyncHandle {
   abort: () => void;
   done: Promise<SyncStats>;
@@ -314,9 +845,74 @@ export function watchSession(
   return es;
 }
 
+export interface TailHandlers {
+  onSession?: (session: Session) => void;
+  onMessages: (messages: Message[]) => void;
+  /** The session shrank or vanished; reload it. */
+  onReset?: () => void;
+}
+
+/**
+ * Streams messages appended to an in-progress session, starting
+ * at ordinal from when given and otherwise with new messages
+ * only. EventSource reconnects resend from the original ordinal,
+ * so handlers should skip ordinals they already hold.
+ */
+export function tailSession(
+  sessionId: string,
+  handlers: TailHandlers,
+  from?: number,
+): EventSource {
+  const es = new EventSource(
+    `${BASE}/sessions/${sessionId}/stream${buildQuery({ from })}`,
+  );
+
+  es.addEventListener("session", (e) => {
+    handlers.onSession?.(JSON.parse((e as MessageEvent).data));
+  });
+  es.addEventListener("messages", (e) => {
+    const batch = JSON.parse((e as MessageEvent).data) as {
+      messages: Message[];
+    };
+    handlers.onMessages(batch.messages);
+  });
+  es.addEventListener("reset", () => {
+    handlers.onReset?.();
+  });
+
+  es.onerror = () => {
+    // Connection will auto-retry via EventSource spec
+  };
+
+  return es;
+}
+
+/** Lists sessions with activity within window (default 10m). */
+export function listActiveSessions(window?: string): Promise<{
+  sessions: Session[];
+  since: string;
+  count: number;
+}> {
+  return fetchJSON(`/sessions/active${buildQuery({ window })}`);
+}
+
+export type ExportFormat = "html" | "md" | "json";
+
 /** Get the export URL for a session */
-export function getExportUrl(sessionId: string): string {
-  return `${BASE}/sessions/${sessionId}/export`;
+export function getExportUrl(
+  sessionId: string,
+  format: ExportFormat = "html",
+): string {
+  const qs = format === "html" ? "" : buildQuery({ format });
+  return `${BASE}/sessions/${sessionId}/export${qs}`;
+}
+
+/** Get the URL of a zip of every session in a project */
+export function getProjectExportUrl(
+  project: string,
+  format: ExportFormat = "html",
+): string {
+  return `${BASE}/projects/${encodeURIComponent(project)}/export${buildQuery({ format })}`;
 }
 
 /* Publish / GitHub config */
@@ -327,6 +923,10 @@ export function publishSession(sessionId: string): Promise<PublishResponse> {
   });
 }
 
+export function getLocale(): Promise<LocaleFormat> {
+  return fetchJSON("/config/locale");
+}
+
 export function getGithubConfig(): Promise<GithubConfig> {
   return fetchJSON("/config/github");
 }
@@ -350,10 +950,29 @@ export interface AnalyticsParams {
   machine?: string;
   project?: string;
   agent?: string;
+  tag?: string;
+  /** Dominant prompt language, ISO 639-1. */
+  language?: string;
   dow?: number;
   hour?: number;
   min_user_messages?: number;
   active_since?: string;
+  include_bots?: boolean;
+  include_archived?: boolean;
+  /** Keeps projects hidden by inactive_project_days. */
+  include_inactive?: boolean;
+  /** Lists the sessions behind each summary/tools/velocity bucket. */
+  include_session_ids?: boolean;
+  /** Saved filter whose values fill in omitted params. */
+  filter?: string;
+  /** false skips the server's configured defaults. */
+  defaults?: boolean;
+}
+
+export function getAnalyticsDefaults(
+  params: { filter?: string } = {},
+): Promise<AnalyticsDefaultsResponse> {
+  return fetchJSON(`/analytics/defaults${buildQuery({ ...params })}`);
 }
 
 export function getAnalyticsSummary(
@@ -384,6 +1003,34 @@ export function getAnalyticsProjects(
   return fetchJSON(`/analytics/projects${buildQuery({ ...params })}`);
 }
 
+export function getAnalyticsBranches(
+  params: AnalyticsParams,
+): Promise<BranchesAnalyticsResponse> {
+  return fetchJSON(`/analytics/branches${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsEntryPoints(
+  params: AnalyticsParams,
+): Promise<EntryPointsAnalyticsResponse> {
+  return fetchJSON(`/analytics/entry-points${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsLanguages(
+  params: AnalyticsParams,
+): Promise<LanguagesAnalyticsResponse> {
+  return fetchJSON(`/analytics/languages${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsSkillUsage(
+  name: string,
+  kind: UsageKind,
+  params: AnalyticsParams,
+): Promise<SkillUsage> {
+  return fetchJSON(
+    `/analytics/skill-usage${buildQuery({ ...params, name, kind })}`,
+  );
+}
+
 export function getAnalyticsHourOfWeek(
   params: AnalyticsParams,
 ): Promise<HourOfWeekResponse> {
@@ -402,6 +1049,124 @@ export function getAnalyticsVelocity(
   return fetchJSON(`/analytics/velocity${buildQuery({ ...params })}`);
 }
 
+export function getAnalyticsSLO(
+  params: AnalyticsParams & {
+    granularity?: Granularity;
+    group_by?: SLOGroupBy;
+  },
+): Promise<SLOResponse> {
+  return fetchJSON(`/analytics/slo${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsCache(
+  params: AnalyticsParams & { granularity?: Granularity },
+): Promise<CacheAnalyticsResponse> {
+  return fetchJSON(`/analytics/cache${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsUsage(
+  params: AnalyticsParams & { granularity?: Granularity },
+): Promise<UsageAnalyticsResponse> {
+  return fetchJSON(`/analytics/usage${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsCostPhases(
+  params: AnalyticsParams,
+): Promise<CostPhasesResponse> {
+  return fetchJSON(`/analytics/cost-phases${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsForecast(
+  params: AnalyticsParams,
+): Promise<ForecastResponse> {
+  return fetchJSON(`/analytics/forecast${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsMonthToDate(
+  params: AnalyticsParams,
+): Promise<MonthToDateResponse> {
+  return fetchJSON(`/analytics/month-to-date${buildQuery({ ...params })}`);
+}
+
+/** Monthly totals for months before the compaction horizon. */
+export function getAnalyticsMonthly(
+  params: { project?: string; agent?: string } = {},
+): Promise<MonthlyUsageResponse> {
+  return fetchJSON(`/analytics/monthly${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsWarmup(
+  params: AnalyticsParams & { min_user_messages?: number },
+): Promise<WarmupResponse> {
+  return fetchJSON(`/analytics/warmup${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsProgress(
+  params: AnalyticsParams & { n?: number },
+): Promise<ProgressResponse> {
+  return fetchJSON(`/analytics/progress${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsToolSequences(
+  params: AnalyticsParams & { n?: number; limit?: number },
+): Promise<ToolSequencesResponse> {
+  return fetchJSON(
+    `/analytics/tool-sequences${buildQuery({ ...params })}`,
+  );
+}
+
+export function getAnalyticsCommands(
+  params: AnalyticsParams & { limit?: number },
+): Promise<CommandsAnalyticsResponse> {
+  return fetchJSON(`/analytics/commands${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsModelSwitches(
+  params: AnalyticsParams,
+): Promise<ModelSwitchesResponse> {
+  return fetchJSON(
+    `/analytics/model-switches${buildQuery({ ...params })}`,
+  );
+}
+
+export function getAnalyticsEditThrash(
+  params: AnalyticsParams & { threshold?: number },
+): Promise<EditThrashResponse> {
+  return fetchJSON(
+    `/analytics/edit-thrash${buildQuery({ ...params })}`,
+  );
+}
+
+export function getAnalyticsQuality(
+  params: AnalyticsParams & { granularity?: Granularity },
+): Promise<QualityResponse> {
+  return fetchJSON(`/analytics/quality${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsRetries(
+  params: AnalyticsParams & { window?: number },
+): Promise<RetriesResponse> {
+  return fetchJSON(`/analytics/retries${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsPaste(
+  params: AnalyticsParams & { granularity?: Granularity },
+): Promise<PasteResponse> {
+  return fetchJSON(`/analytics/paste${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsTruncation(
+  params: AnalyticsParams,
+): Promise<TruncationResponse> {
+  return fetchJSON(`/analytics/truncation${buildQuery({ ...params })}`);
+}
+
+export function getAnalyticsHooks(
+  params: AnalyticsParams,
+): Promise<HooksAnalyticsResponse> {
+  return fetchJSON(`/analytics/hooks${buildQuery({ ...params })}`);
+}
+
 export function getAnalyticsTools(
   params: AnalyticsParams,
 ): Promise<ToolsAnalyticsResponse> {
@@ -416,6 +1181,55 @@ export function getAnalyticsTopSessions(
   return fetchJSON(`/analytics/top-sessions${buildQuery({ ...params })}`);
 }
 
+/* Analytics snapshots */
+
+export function listAnalyticsSnapshots(): Promise<AnalyticsSnapshotsResponse> {
+  return fetchJSON("/analytics/snapshots");
+}
+
+/** Captures the analytics for params into a stored snapshot. */
+export function createAnalyticsSnapshot(
+  name: string,
+  params: AnalyticsParams,
+): Promise<AnalyticsSnapshot> {
+  return fetchJSON(`/analytics/snapshots${buildQuery({ ...params })}`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ name }),
+  });
+}
+
+export function getAnalyticsSnapshot(
+  id: number,
+): Promise<AnalyticsSnapshot> {
+  return fetchJSON(`/analytics/snapshots/${id}`);
+}
+
+export function getAnalyticsSnapshotExportUrl(id: number): string {
+  return `${BASE}/analytics/snapshots/${id}?download=true`;
+}
+
+/** Stores a snapshot previously downloaded via the export URL. */
+export function importAnalyticsSnapshot(
+  snapshot: AnalyticsSnapshot,
+): Promise<AnalyticsSnapshot> {
+  return fetchJSON("/analytics/snapshots/import", {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify(snapshot),
+  });
+}
+
+export async function deleteAnalyticsSnapshot(id: number): Promise<void> {
+  const res = await fetch(`${BASE}/analytics/snapshots/${id}`, {
+    method: "DELETE",
+  });
+  if (!res.ok) {
+    const body = await res.text();
+    throw new ApiError(res.status, apiErrorMessage(res.status, body));
+  }
+}
+
 /* Insights */
 
 export interface ListInsightsParams {
@@ -4,12 +4,36 @@ export type Granularity = "day" | "week" | "month";
 export type HeatmapMetric = "messages" | "sessions";
 export type TopSessionsMetric = "messages" | "duration";
 
+/**
+ * Sessions behind one analytics number, present when the request
+ * sets include_session_ids. ids is capped; total is the full count.
+ */
+export interface ContributingSessions {
+  ids: string[];
+  total: number;
+}
+
+/**
+ * User-role messages by origin. Only human counts prompts a
+ * person typed; the rest were injected by tools, hooks, or the
+ * agent itself.
+ */
+export interface UserOriginCounts {
+  human: number;
+  tool_result: number;
+  hook: number;
+  system: number;
+}
+
 export interface AgentSummary {
   sessions: number;
   messages: number;
+  user_messages: UserOriginCounts;
+  tokens: TokenTotals;
+  session_ids?: ContributingSessions;
 }
 
-export interface AnalyticsSummary {
+export interface AnalyticsSummary extends AnalyticsEcho {
   total_sessions: number;
   total_messages: number;
   active_projects: number;
@@ -19,7 +43,10 @@ export interface AnalyticsSummary {
   p90_messages: number;
   most_active_project: string;
   concentration: number;
+  user_messages: UserOriginCounts;
+  tokens: TokenTotals;
   agents: Record<string, AgentSummary>;
+  session_ids?: ContributingSessions;
 }
 
 export interface ActivityEntry {
@@ -27,21 +54,30 @@ export interface ActivityEntry {
   sessions: number;
   messages: number;
   user_messages: number;
+  user_origins: UserOriginCounts;
   assistant_messages: number;
   tool_calls: number;
   thinking_messages: number;
   by_agent: Record<string, number>;
 }
 
-export interface ActivityResponse {
+export interface ActivityResponse extends AnalyticsEcho {
   granularity: string;
   series: ActivityEntry[];
 }
 
+/** Activity inside vs. outside configured working hours. */
+export interface WindowSplit {
+  in_window: number;
+  out_of_window: number;
+}
+
 export interface HeatmapEntry {
   date: string;
   value: number;
   level: number;
+  /** Present when working hours are configured. */
+  window?: WindowSplit;
 }
 
 export interface HeatmapLevels {
@@ -51,11 +87,12 @@ export interface HeatmapLevels {
   l4: number;
 }
 
-export interface HeatmapResponse {
+export interface HeatmapResponse extends AnalyticsEcho {
   metric: string;
   entries: HeatmapEntry[];
   levels: HeatmapLevels;
   entries_from: string;
+  window?: WindowSplit;
 }
 
 export interface ProjectAnalytics {
@@ -70,18 +107,102 @@ export interface ProjectAnalytics {
   daily_trend: number;
 }
 
-export interface ProjectsAnalyticsResponse {
+export interface ProjectsAnalyticsResponse extends AnalyticsEcho {
   projects: ProjectAnalytics[];
 }
 
+/** Matches Go BranchAnalytics; branch is "" without git metadata. */
+export interface BranchAnalytics {
+  project: string;
+  branch: string;
+  worktrees: string[];
+  sessions: number;
+  messages: number;
+  user_messages: number;
+  first_session: string;
+  last_session: string;
+  agents: Record<string, number>;
+}
+
+export interface BranchesAnalyticsResponse extends AnalyticsEcho {
+  branches: BranchAnalytics[];
+}
+
+/** Matches Go EntryPointAnalytics; entry_point is "" when not recorded. */
+export interface EntryPointAnalytics {
+  entry_point: string;
+  clients: string[];
+  sessions: number;
+  messages: number;
+  user_messages: number;
+  machines: number;
+  agents: Record<string, number>;
+}
+
+export interface EntryPointsAnalyticsResponse extends AnalyticsEcho {
+  entry_points: EntryPointAnalytics[];
+}
+
+export interface LanguageAgentAnalytics {
+  agent: string;
+  sessions: number;
+  avg_quality: number;
+  low_sessions: number;
+}
+
+/** Matches Go LanguageAnalytics; language is "" when undetermined. */
+export interface LanguageAnalytics {
+  language: string;
+  sessions: number;
+  messages: number;
+  user_messages: number;
+  avg_quality: number;
+  low_sessions: number;
+  agents: LanguageAgentAnalytics[];
+}
+
+export interface LanguagesAnalyticsResponse extends AnalyticsEcho {
+  threshold: number;
+  languages: LanguageAnalytics[];
+}
+
+export type UsageKind = "skill" | "command";
+
+export interface SkillUsageWeek {
+  /** Monday of the ISO week, YYYY-MM-DD. */
+  week: string;
+  invocations: number;
+}
+
+/** Matches Go SkillUsage. */
+export interface SkillUsage extends AnalyticsEcho {
+  kind: UsageKind;
+  name: string;
+  invocations: number;
+  sessions: number;
+  machines: number;
+  projects: number;
+  first_used?: string;
+  last_used?: string;
+  avg_messages_after: number;
+  /** Percentage of invoking sessions that were interrupted. */
+  interrupt_rate: number;
+  agents: Record<string, number>;
+  trend: SkillUsageWeek[];
+  session_ids?: ContributingSessions;
+}
+
 export interface HourOfWeekCell {
   day_of_week: number;
   hour: number;
   messages: number;
+  /** Present when working hours are configured. */
+  window?: WindowSplit;
 }
 
-export interface HourOfWeekResponse {
+export interface HourOfWeekResponse extends AnalyticsEcho {
   cells: HourOfWeekCell[];
+  window?: WindowSplit;
 }
 
 export interface DistributionBucket {
@@ -89,7 +210,7 @@ export interface DistributionBucket {
   count: number;
 }
 
-export interface SessionShapeResponse {
+export interface SessionShapeResponse extends AnalyticsEcho {
   count: number;
   length_distribution: DistributionBucket[];
   duration_distribution: DistributionBucket[];
@@ -113,12 +234,565 @@ export interface VelocityBreakdown {
   label: string;
   sessions: number;
   overview: VelocityOverview;
+  session_ids?: ContributingSessions;
 }
 
-export interface VelocityResponse {
+export interface VelocityResponse extends AnalyticsEcho {
   overall: VelocityOverview;
   by_agent: VelocityBreakdown[];
   by_complexity: VelocityBreakdown[];
+  session_ids?: ContributingSessions;
+}
+
+export type SLOMetric = "first_response" | "turn_cycle";
+export type SLOGroupBy = "agent" | "model";
+
+export interface SLOPoint {
+  date?: string;
+  samples: number;
+  value_sec: number;
+  within_pct: number;
+  met: boolean;
+}
+
+export interface SLOGroup {
+  label: string;
+  overall: SLOPoint;
+  series: SLOPoint[];
+}
+
+export interface SLOReport {
+  name: string;
+  metric: SLOMetric;
+  percentile: number;
+  threshold_sec: number;
+  overall: SLOPoint;
+  attainment: number;
+  series: SLOPoint[];
+  groups: SLOGroup[];
+}
+
+export interface SLOResponse extends AnalyticsEcho {
+  granularity: Granularity;
+  group_by: SLOGroupBy;
+  slos: SLOReport[];
+}
+
+export interface CacheStats {
+  input_tokens: number;
+  cache_read_tokens: number;
+  cache_creation_tokens: number;
+  total_input_tokens: number;
+  hit_ratio: number;
+}
+
+export interface CacheTrendEntry extends CacheStats {
+  date: string;
+}
+
+export interface CacheBreakdown extends CacheStats {
+  label: string;
+}
+
+export interface CacheAnalyticsResponse extends AnalyticsEcho {
+  granularity: Granularity;
+  overall: CacheStats;
+  trend: CacheTrendEntry[];
+  by_agent: CacheBreakdown[];
+  by_model: CacheBreakdown[];
+  by_project: CacheBreakdown[];
+}
+
+export interface TokenTotals {
+  input_tokens: number;
+  output_tokens: number;
+  cache_read_tokens: number;
+  cache_creation_tokens: number;
+  reasoning_tokens: number;
+  cost: number;
+}
+
+export interface UsageTrendEntry extends TokenTotals {
+  date: string;
+}
+
+export interface UsageBreakdown extends TokenTotals {
+  label: string;
+}
+
+export interface UsageAnalyticsResponse extends AnalyticsEcho {
+  granularity: Granularity;
+  total: TokenTotals;
+  trend: UsageTrendEntry[];
+  by_agent: UsageBreakdown[];
+  by_model: UsageBreakdown[];
+  by_project: UsageBreakdown[];
+  currency: string;
+}
+
+export type CostPhase =
+  | "exploration"
+  | "implementation"
+  | "verification"
+  | "other"
+  | "discussion";
+
+export interface PhaseCost {
+  phase: CostPhase;
+  turns: number;
+  tokens: number;
+  cost: number;
+  share: number;
+}
+
+export interface ProjectCostPhases {
+  project: string;
+  cost: number;
+  phases: PhaseCost[];
+}
+
+export interface CostPhasesResponse extends AnalyticsEcho {
+  cost: number;
+  phases: PhaseCost[];
+  projects: ProjectCostPhases[];
+  currency: string;
+}
+
+export interface WarmupDay {
+  date: string;
+  day_of_week: number;
+  sessions: number;
+  first_minute: number;
+  first_session_id: string;
+  substantive_minute: number | null;
+  substantive_session_id?: string;
+  warmup_minutes: number | null;
+}
+
+export interface WarmupWeekday {
+  day_of_week: number;
+  days: number;
+  substantive_days: number;
+  median_first_minute: number | null;
+  median_warmup_minutes: number | null;
+}
+
+export interface WarmupResponse extends AnalyticsEcho {
+  min_user_messages: number;
+  days: WarmupDay[];
+  weekdays: WarmupWeekday[];
+  median_first_minute: number | null;
+  median_warmup_minutes: number | null;
+}
+
+export interface ForecastValue {
+  predicted: number;
+  low: number;
+  high: number;
+}
+
+export interface ProjectForecast {
+  project: string;
+  history_messages: number;
+  history_cost: number;
+  messages: ForecastValue;
+  cost: ForecastValue;
+  /** ISO 4217 code of history_cost and cost. */
+  currency: string;
+}
+
+export interface MonthProjection {
+  month: string;
+  to_date: number;
+  days_left: number;
+  projected: ForecastValue;
+}
+
+export interface ForecastResponse extends AnalyticsEcho {
+  history_from: string;
+  history_to: string;
+  from: string;
+  to: string;
+  overall: ProjectForecast;
+  projects: ProjectForecast[];
+  month: MonthProjection;
+  /** Currency of overall and month; projects carry their own. */
+  currency: string;
+}
+
+/** Matches Go MonthPeriod in internal/db/month_to_date.go */
+export interface MonthPeriod {
+  from: string;
+  to: string;
+  sessions: number;
+  cost: number;
+  active_minutes: number;
+}
+
+/** Percent change from last month; null when it was zero. */
+export interface MonthChange {
+  sessions: number | null;
+  cost: number | null;
+  active_minutes: number | null;
+}
+
+/** Matches Go MonthToDateResponse in internal/db/month_to_date.go */
+export interface MonthToDateResponse extends AnalyticsEcho {
+  month: string;
+  days_elapsed: number;
+  days_in_month: number;
+  current: MonthPeriod;
+  previous: MonthPeriod;
+  change: MonthChange;
+  projected: MonthPeriod;
+  currency: string;
+}
+
+/** Matches Go MonthlyUsage in internal/db/compaction.go */
+export interface MonthlyUsage {
+  month: string;
+  project: string;
+  agent: string;
+  sessions: number;
+  messages: number;
+  user_messages: number;
+  tool_calls: number;
+  input_tokens: number;
+  output_tokens: number;
+}
+
+export interface MonthlyUsageResponse {
+  months: MonthlyUsage[];
+}
+
+/** Matches Go ProgressCohort in internal/db/progress.go */
+export interface ProgressCohort {
+  sessions: number;
+  from: string;
+  to: string;
+  messages_per_session: number;
+  tasks_per_session: number;
+  messages_per_task: number;
+  tool_calls_per_session: number;
+  interrupts_per_session: number;
+  interrupted_pct: number;
+  tool_mix: Record<string, number>;
+}
+
+export interface ProgressChange {
+  messages_per_task: number | null;
+  tool_calls_per_session: number | null;
+  interrupts_per_session: number | null;
+}
+
+export interface ProjectProgress {
+  project: string;
+  total_sessions: number;
+  n: number;
+  first: ProgressCohort;
+  recent: ProgressCohort;
+  change: ProgressChange;
+}
+
+export interface ProgressResponse extends AnalyticsEcho {
+  n: number;
+  projects: ProjectProgress[];
+}
+
+/** Matches Go db.AnalyticsFilter JSON encoding */
+export interface SnapshotFilter {
+  from: string;
+  to: string;
+  machine?: string;
+  project?: string;
+  agent?: string;
+  tag?: string;
+  timezone?: string;
+  dow?: number;
+  hour?: number;
+  min_user_messages?: number;
+  active_since?: string;
+  include_bots?: boolean;
+  include_archived?: boolean;
+  include_session_ids?: boolean;
+}
+
+/**
+ * Added to every analytics response: the filter the numbers were
+ * computed with, after defaults, and warnings about request
+ * params that were ignored or match no sessions.
+ */
+export interface AnalyticsEcho {
+  filter?: SnapshotFilter;
+  warnings?: string[];
+}
+
+/**
+ * Matches Go AnalyticsSnapshot struct in internal/db/snapshots.go.
+ * data is keyed by analytics endpoint name and omitted in listings.
+ */
+export interface AnalyticsSnapshot {
+  id: number;
+  name: string;
+  filter: SnapshotFilter;
+  data?: Record<string, unknown>;
+  created_at: string;
+  imported_at?: string;
+}
+
+export interface AnalyticsSnapshotsResponse {
+  snapshots: AnalyticsSnapshot[];
+}
+
+/** Matches Go ToolSequence struct in internal/db/sequences.go */
+export interface ToolSequence {
+  steps: string[];
+  count: number;
+  sessions: number;
+  success_rate: number;
+  lift: number;
+  repeat: boolean;
+}
+
+export interface AgentToolSequences {
+  agent: string;
+  sessions: number;
+  baseline_success_rate: number;
+  sequences: ToolSequence[];
+  spins: ToolSequence[];
+}
+
+export interface ToolSequencesResponse extends AnalyticsEcho {
+  length: number;
+  agents: AgentToolSequences[];
+}
+
+/** Matches Go CommandStats in internal/db/shell_commands.go */
+export interface CommandStats {
+  command: string;
+  kind?: "test" | "build" | "git";
+  runs: number;
+  sessions: number;
+  failures: number;
+  failure_rate: number;
+}
+
+export interface CommandCount {
+  command: string;
+  runs: number;
+}
+
+export interface ProjectCommandMix {
+  project: string;
+  sessions: number;
+  test_sessions: number;
+  runs: number;
+  test_runs: number;
+  build_runs: number;
+  git_runs: number;
+  top: CommandCount[];
+}
+
+export interface CommandsAnalyticsResponse extends AnalyticsEcho {
+  sessions: number;
+  test_sessions: number;
+  runs: number;
+  commands: CommandStats[];
+  failure_prone: CommandStats[];
+  projects: ProjectCommandMix[];
+}
+
+/** Matches Go ModelSwitchStats in internal/db/model_switches.go */
+export interface ModelSwitchStats {
+  sessions: number;
+  switched_sessions: number;
+  fallback_sessions: number;
+  fallbacks: number;
+  commands: number;
+  fallback_rate: number;
+}
+
+export interface AgentModelSwitches extends ModelSwitchStats {
+  agent: string;
+}
+
+export interface ModelTransition {
+  from: string;
+  to: string;
+  reason: "command" | "fallback";
+  count: number;
+}
+
+export interface ModelSwitchesResponse extends AnalyticsEcho {
+  overall: ModelSwitchStats;
+  by_agent: AgentModelSwitches[];
+  transitions: ModelTransition[];
+}
+
+/** Matches Go SessionEditThrash in internal/db/edit_thrash.go */
+export interface SessionEditThrash {
+  session_id: string;
+  project: string;
+  agent: string;
+  first_message: string | null;
+  started_at: string;
+  score: number;
+  edits: number;
+  files: { path: string; edits: number }[];
+}
+
+export interface ProjectEditThrash {
+  project: string;
+  edit_sessions: number;
+  thrashed_sessions: number;
+  thrash_rate: number;
+  score: number;
+}
+
+export interface EditThrashResponse extends AnalyticsEcho {
+  threshold: number;
+  sessions: SessionEditThrash[];
+  projects: ProjectEditThrash[];
+}
+
+/** Matches Go QualityTrendEntry in internal/db/quality.go */
+export interface QualityTrendEntry {
+  date: string;
+  sessions: number;
+  avg_score: number;
+  low_sessions: number;
+}
+
+export interface LowQualitySession {
+  session_id: string;
+  project: string;
+  agent: string;
+  first_message: string | null;
+  started_at: string;
+  score: number;
+}
+
+export interface QualityResponse extends AnalyticsEcho {
+  granularity: Granularity;
+  threshold: number;
+  sessions: number;
+  avg_score: number;
+  low_sessions: number;
+  trend: QualityTrendEntry[];
+  lowest: LowQualitySession[];
+}
+
+/** Matches Go RetrySession in internal/db/retries.go */
+export interface RetrySession {
+  session_id: string;
+  previous_id: string;
+  project: string;
+  agent: string;
+  started_at: string;
+  gap_minutes: number;
+  reason: string;
+}
+
+export interface RetryGroup {
+  project: string;
+  agent: string;
+  sessions: number;
+  failed_sessions: number;
+  retry_sessions: number;
+  retry_rate: number;
+  cost: number;
+  currency: string;
+}
+
+export interface RetriesResponse extends AnalyticsEcho {
+  window_minutes: number;
+  sessions: number;
+  failed_sessions: number;
+  retry_sessions: number;
+  groups: RetryGroup[];
+  recent: RetrySession[];
+}
+
+export interface PasteTotals {
+  user_messages: number;
+  paste_messages: number;
+  typed_chars: number;
+  pasted_chars: number;
+  read_chars: number;
+  paste_share: number;
+}
+
+export interface PasteTrendEntry extends PasteTotals {
+  date: string;
+}
+
+export interface ProjectPaste extends PasteTotals {
+  project: string;
+}
+
+export interface PasteResponse extends AnalyticsEcho {
+  granularity: string;
+  overall: PasteTotals;
+  projects: ProjectPaste[];
+  trend: PasteTrendEntry[];
+}
+
+export interface TruncationTotals {
+  results: number;
+  truncated: number;
+  rate: number;
+  original_bytes: number;
+}
+
+export interface ToolTruncation extends TruncationTotals {
+  tool: string;
+}
+
+export interface ProjectTruncation extends TruncationTotals {
+  project: string;
+}
+
+export interface TruncationResponse extends AnalyticsEcho {
+  overall: TruncationTotals;
+  tools: ToolTruncation[];
+  projects: ProjectTruncation[];
+}
+
+/** Matches Go config.AnalyticsDefaults; zero fields are unset. */
+export interface AnalyticsDefaults {
+  range_days: number;
+  project: string;
+  min_user_messages: number;
+}
+
+export interface SavedFilter extends Partial<AnalyticsDefaults> {
+  name: string;
+}
+
+export interface AnalyticsDefaultsResponse extends AnalyticsDefaults {
+  saved_filters: SavedFilter[];
+}
+
+export interface HookStats {
+  total: number;
+  allowed: number;
+  blocked: number;
+  modified: number;
+  stopped: number;
+  errors: number;
+  block_rate: number;
+  modify_rate: number;
+}
+
+export interface HookBreakdown extends HookStats {
+  name: string;
+}
+
+export interface HooksAnalyticsResponse extends AnalyticsEcho {
+  sessions_with_hooks: number;
+  overall: HookStats;
+  by_event: HookBreakdown[];
+  by_hook: HookBreakdown[];
 }
 
 export interface TopSession {
@@ -129,7 +803,7 @@ export interface TopSession {
   duration_min: number;
 }
 
-export interface TopSessionsResponse {
+export interface TopSessionsResponse extends AnalyticsEcho {
   metric: string;
   sessions: TopSession[];
 }
@@ -138,22 +812,26 @@ export interface ToolCategoryCount {
   category: string;
   count: number;
   pct: number;
+  session_ids?: ContributingSessions;
 }
 
 export interface ToolAgentBreakdown {
   agent: string;
   total: number;
   categories: ToolCategoryCount[];
+  session_ids?: ContributingSessions;
 }
 
 export interface ToolTrendEntry {
   date: string;
   by_category: Record<string, number>;
+  session_ids?: Record<string, ContributingSessions>;
 }
 
-export interface ToolsAnalyticsResponse {
+export interface ToolsAnalyticsResponse extends AnalyticsEcho {
   total_calls: number;
   by_category: ToolCategoryCount[];
   by_agent: ToolAgentBreakdown[];
   trend: ToolTrendEntry[];
+  session_ids?: ContributingSessions;
 }
@@ -5,6 +5,53 @@ export interface VersionInfo {
   build_date: string;
 }
 
+/** Matches Go WriteQueueStats struct in internal/db/writer.go */
+export interface WriteQueueStats {
+  depth: number;
+  priority_depth: number;
+  capacity: number;
+  writes: number;
+  errors: number;
+  wait_p50_ms: number;
+  wait_p95_ms: number;
+  wait_max_ms: number;
+  exec_p50_ms: number;
+  exec_p95_ms: number;
+  exec_max_ms: number;
+}
+
+/** Matches Go UnknownRecordType struct in internal/db/unknown_records.go */
+export interface UnknownRecordType {
+  agent: string;
+  type: string;
+  count: number;
+  sessions: number;
+  last_seen: string;
+  sample: string;
+}
+
+export interface HealthResponse {
+  status: "ok" | "busy";
+  write_queue: WriteQueueStats;
+  unknown_record_types: UnknownRecordType[];
+}
+
+/** Matches Go Entry struct in internal/pricing/pricing.go */
+export interface PricingEntry {
+  model: string;
+  source: "builtin" | "config";
+  input: number;
+  output: number;
+  cache_read: number;
+  cache_write: number;
+}
+
+/** Matches Go pricingResponse struct in internal/server/pricing.go */
+export interface PricingResponse {
+  currency: string;
+  models: PricingEntry[];
+}
+
 /** Matches Go Session struct in internal/db/sessions.go */
 export interface Session {
   id: string;
@@ -16,12 +63,84 @@ export interface Session {
   ended_at: string | null;
   message_count: number;
   user_message_count: number;
+  interrupt_count?: number;
+  headless?: boolean;
+  git_branch?: string;
+  worktree?: string;
+  entry_point?: string;
+  client?: string;
+  quality_score?: number;
+  /** Dominant prompt language, ISO 639-1. */
+  language?: string;
   parent_session_id?: string;
   relationship_type?: string;
   file_path?: string;
   file_size?: number;
   file_mtime?: number;
+  file_hash?: string;
   created_at: string;
+  pinned?: boolean;
+  archived?: boolean;
+  notes?: SessionNote[];
+}
+
+/** Matches Go SessionNote struct in internal/db/notes.go */
+export interface SessionNote {
+  id: number;
+  session_id: string;
+  ordinal?: number;
+  body: string;
+  created_at: string;
+  updated_at: string;
+}
+
+/** Matches Go NoteSearchResult struct in internal/db/notes.go */
+export interface NoteSearchResult extends SessionNote {
+  project: string;
+  snippet: string;
+}
+
+export interface NoteSearchResponse {
+  query: string;
+  results: NoteSearchResult[];
+  count: number;
+}
+
+/** Matches Go sessionSource struct in internal/server/source.go */
+export interface SessionSource {
+  session_id: string;
+  agent: string;
+  machine: string;
+  path?: string;
+  root?: string;
+  exists: boolean;
+  size?: number;
+  mtime?: number;
+  stored_path?: string;
+  stored_size?: number;
+  stored_mtime?: number;
+  stored_hash?: string;
+  changed: boolean;
+}
+
+/** Matches Go revealResponse struct in internal/server/source.go */
+export interface RevealResponse {
+  path: string;
+  dir: string;
+}
+
+export type HookDecision = "allow" | "block" | "modify" | "stop" | "error";
+
+/** Matches Go HookEvent struct in internal/db/hooks.go */
+export interface HookEvent {
+  session_id: string;
+  hook_event: string;
+  hook_name: string;
+  tool_name?: string;
+  command?: string;
+  decision: HookDecision;
+  message?: string;
+  timestamp?: string;
 }
 
 /** Matches Go SessionPage struct */
@@ -31,10 +150,65 @@ export interface SessionPage {
   total: number;
 }
 
+export type BulkActionName =
+  | "tag"
+  | "untag"
+  | "mute"
+  | "unmute"
+  | "archive"
+  | "unarchive"
+  | "pin"
+  | "unpin";
+
+/** Matches Go bulkResponse struct in internal/server/bulk.go */
+export interface BulkResponse {
+  action: BulkActionName;
+  tag?: string;
+  count: number;
+  applied: boolean;
+  token?: string;
+}
+
+/** Matches Go pruneRequest filters in internal/server/prune.go */
+export interface PruneFilter {
+  project?: string;
+  agent?: string;
+  max_messages?: number;
+  before?: string;
+  first_message?: string;
+  /** Local sessions whose source file no longer exists. */
+  orphaned?: boolean;
+}
+
+export interface PruneProject {
+  project: string;
+  sessions: number;
+  bytes: number;
+}
+
+/** Matches Go prunePreviewResponse struct */
+export interface PrunePreview {
+  total: number;
+  total_bytes: number;
+  by_project: PruneProject[];
+  sessions: Session[];
+  limit: number;
+  offset: number;
+  token: string;
+}
+
+/** Matches Go pruneExecuteResponse struct */
+export interface PruneResult {
+  deleted: number;
+  files_removed: number;
+  bytes_reclaimed: number;
+}
+
 /** Matches Go ProjectInfo struct */
 export interface ProjectInfo {
   name: string;
   session_count: number;
+  last_active_at: string;
 }
 
 /** Matches Go ToolCall struct in internal/db/messages.go */
@@ -47,6 +221,12 @@ export interface ToolCall {
   result_content_length?: number;
   result_content?: string;
   subagent_session_id?: string;
+  file_path?: string;
+  /** Normalized command of a Bash call, e.g. "go test". */
+  command?: string;
+  result_error?: boolean;
+  result_truncated?: boolean;
+  result_original_length?: number;
 }
 
 /** Matches Go Message struct in internal/db/messages.go */
@@ -60,7 +240,73 @@ export interface Message {
   has_thinking: boolean;
   has_tool_use: boolean;
   content_length: number;
+  model?: string;
+  model_switch?: ModelSwitchReason;
+  kind?: MessageKind;
+  /** Id of the source record; stable across re-parses. */
+  source_uuid?: string;
+  /** Estimated pasted characters; user messages only. */
+  pasted_chars?: number;
+  /** Detected language, ISO 639-1; typed user messages only. */
+  language?: string;
   tool_calls?: ToolCall[];
+  input_tokens?: number;
+  output_tokens?: number;
+  cache_read_tokens?: number;
+  cache_creation_tokens?: number;
+  reasoning_tokens?: number;
+}
+
+export type ModelSwitchReason = "command" | "fallback";
+
+/** Slash command invocations and local command output. */
+export type MessageKind = "command" | "command_output";
+
+/** Matches Go ModelSegment struct in internal/db/model_switches.go */
+export interface ModelSegment {
+  model: string;
+  reason?: ModelSwitchReason;
+  first_ordinal: number;
+  last_ordinal: number;
+  messages: number;
+  started_at?: string;
+}
+
+export type TodoStatus = "pending" | "in_progress" | "completed";
+
+/** Matches Go Todo struct in internal/db/todos.go */
+export interface Todo {
+  position: number;
+  content: string;
+  status: TodoStatus;
+  active_form?: string;
+}
+
+/** Matches Go SessionTodos struct */
+export interface SessionTodos {
+  session_id: string;
+  project: string;
+  agent: string;
+  first_message: string | null;
+  updated_at: string;
+  todos: Todo[];
+}
+
+/** Matches Go OpenTodosResponse struct */
+export interface OpenTodosResponse {
+  sessions: SessionTodos[];
+  total: number;
+}
+
+/** Allowlisted agent settings of a session, keyed like
+ *  client_version, thinking, or beta.<flag>. */
+export type SessionEnv = Record<string, string>;
+
+/** Matches Go EnvValue struct */
+export interface EnvValue {
+  key: string;
+  value: string;
+  sessions: number;
 }
 
 /** Matches Go MinimapEntry struct */
@@ -74,6 +320,7 @@ export interface SearchResult {
   session_id: string;
   project: string;
   ordinal: number;
+  source_uuid?: string;
   role: string;
   timestamp: string;
   snippet: string;
@@ -94,6 +341,19 @@ export interface MessagesResponse {
   count: number;
 }
 
+/** Returned when a share link is created for a session. */
+export interface ShareLink {
+  token: string;
+  /** API path serving the shared session; append /messages to page. */
+  path: string;
+  expires_at: string;
+}
+
+export interface SharedSessionResponse {
+  session: Session;
+  expires_at: string;
+}
+
 export interface MinimapResponse {
   entries: MinimapEntry[];
   count: number;
@@ -106,12 +366,42 @@ export interface SearchResponse {
   next: number;
 }
 
+/** Matches Go SearchFacet in internal/db/search.go */
+export interface SearchFacet {
+  value: string;
+  count: number;
+}
+
+export interface SearchFacetsResponse {
+  query: string;
+  total: number;
+  projects: SearchFacet[];
+  agents: SearchFacet[];
+  /** YYYY-MM, newest first. */
+  months: SearchFacet[];
+}
+
 export interface ProjectsResponse {
   projects: ProjectInfo[];
+  /** Projects hidden for inactivity; 0 with include_inactive. */
+  inactive_count: number;
+}
+
+/** Matches Go MachineInfo struct */
+export interface MachineInfo {
+  name: string;
+  session_count: number;
+  is_bot: boolean;
+  bot_reason?: string;
+  /** Former names renamed or merged into this machine. */
+  aliases: string[];
 }
 
 export interface MachinesResponse {
   machines: string[];
+  /** Machines labeled as CI runners or other bots. */
+  bots: string[];
+  details: MachineInfo[];
 }
 
 /** Matches Go AgentInfo struct */
@@ -123,3 +413,54 @@ export interface AgentInfo {
 export interface AgentsResponse {
   agents: AgentInfo[];
 }
+
+/** Matches Go FileTouch struct in internal/db/files.go */
+export interface FileTouch {
+  session_id: string;
+  project: string;
+  ordinal: number;
+  timestamp: string;
+  tool_name: string;
+  category: string;
+}
+
+/** Matches Go RecentFile struct in internal/db/files.go */
+export interface RecentFile {
+  path: string;
+  last_touched_at: string;
+  touch_count: number;
+  session_count: number;
+  edit_count: number;
+  touches: FileTouch[];
+}
+
+export interface RecentFilesResponse {
+  files: RecentFile[];
+}
+
+export type LogLevel = "debug" | "info" | "warn" | "error";
+
+export interface LogEntry {
+  time: string;
+  level: string;
+  msg: string;
+  attrs?: Record<string, unknown>;
+}
+
+export interface LogsResponse {
+  entries: LogEntry[];
+}
+
+/** Matches Go localeResponse in internal/server/locale.go */
+export interface LocaleFormat {
+  configured: boolean;
+  locale: string;
+  decimal_separator: string;
+  group_separator: string;
+  currency: string;
+  currency_symbol: string;
+  currency_after: boolean;
+  /** ISO weekday, 0=Mon, 6=Sun. */
+  week_start: number;
+  date_format: string;
+}
@@ -7,6 +7,8 @@ export interface SyncProgress {
   sessions_total: number;
   sessions_done: number;
   messages_indexed: number;
+  /** Files an interrupted resync had already finished. */
+  resumed_files?: number;
 }
 
 /** Matches Go SyncStats struct */
@@ -16,6 +18,7 @@ export interface SyncStats {
   skipped: number;
   failed: number;
   orphaned_copied?: number;
+  resumed?: number;
   warnings?: string[];
   aborted?: boolean;
 }
@@ -24,3 +27,45 @@ export interface SyncStatus {
   last_sync: string;
   stats: SyncStats | null;
 }
+
+/** Matches Go db.QuarantinedFile: a file whose last parse failed. */
+export interface QuarantinedFile {
+  path: string;
+  agent: string;
+  error: string;
+  attempts: number;
+  first_failed_at: string;
+  last_failed_at: string;
+  next_retry_at: string;
+}
+
+export interface QuarantineResponse {
+  files: QuarantinedFile[];
+}
+
+export interface RetryQuarantineResponse extends QuarantineResponse {
+  retried: number;
+}
+
+export type AlertKind =
+  | "cost_per_day"
+  | "parse_error_rate"
+  | "machine_silent";
+
+/** Matches Go alert.State: a rule's outcome as of its last check. */
+export interface AlertState {
+  rule: string;
+  kind: AlertKind;
+  machine?: string;
+  threshold: number;
+  firing: boolean;
+  value: number;
+  message: string;
+  since?: string;
+  checked_at?: string;
+  error?: string;
+}
+
+export interface AlertsResponse {
+  alerts: AlertState[];
+}
@@ -3,11 +3,14 @@ import type {
   ActivityResponse,
   HeatmapResponse,
   ProjectsAnalyticsResponse,
+  BranchesAnalyticsResponse,
   HourOfWeekResponse,
   SessionShapeResponse,
   VelocityResponse,
   ToolsAnalyticsResponse,
   TopSessionsResponse,
+  ForecastResponse,
+  MonthToDateResponse,
   Granularity,
   HeatmapMetric,
   TopSessionsMetric,
@@ -17,11 +20,15 @@ import {
   getAnalyticsActivity,
   getAnalyticsHeatmap,
   getAnalyticsProjects,
+  getAnalyticsBranches,
   getAnalyticsHourOfWeek,
   getAnalyticsSessionShape,
   getAnalyticsVelocity,
   getAnalyticsTools,
   getAnalyticsTopSessions,
+  getAnalyticsForecast,
+  getAnalyticsMonthToDate,
+  getAnalyticsDefaults,
   type AnalyticsParams,
 } from "../api/client.js";
 import { sessions } from "./sessions.svelte.js";
@@ -50,11 +57,14 @@ type Panel =
   | "activity"
   | "heatmap"
   | "projects"
+  | "branches"
   | "hourOfWeek"
   | "sessionShape"
   | "velocity"
   | "tools"
-  | "topSessions";
+  | "topSessions"
+  | "forecast"
+  | "monthToDate";
 
 class AnalyticsStore {
   from: string = $state(daysAgo(365));
@@ -66,6 +76,7 @@ class AnalyticsStore {
   agent: string = $state("");
   minUserMessages: number = $state(0);
   recentlyActive: boolean = $state(false);
+  includeBots: boolean = $state(false);
   selectedDow: number | null = $state(null);
   selectedHour: number | null = $state(null);
 
@@ -73,23 +84,30 @@ class AnalyticsStore {
   activity = $state<ActivityResponse | null>(null);
   heatmap = $state<HeatmapResponse | null>(null);
   projects = $state<ProjectsAnalyticsResponse | null>(null);
+  branches = $state<BranchesAnalyticsResponse | null>(null);
   hourOfWeek = $state<HourOfWeekResponse | null>(null);
   sessionShape = $state<SessionShapeResponse | null>(null);
   velocity = $state<VelocityResponse | null>(null);
   tools = $state<ToolsAnalyticsResponse | null>(null);
   topSessions = $state<TopSessionsResponse | null>(null);
+  forecast = $state<ForecastResponse | null>(null);
+  monthToDate = $state<MonthToDateResponse | null>(null);
   topMetric: TopSessionsMetric = $state("messages");
+  private defaultsLoaded = false;
 
   loading = $state({
     summary: false,
     activity: false,
     heatmap: false,
     projects: false,
+    branches: false,
     hourOfWeek: false,
     sessionShape: false,
     velocity: false,
     tools: false,
     topSessions: false,
+    forecast: false,
+    monthToDate: false,
   });
 
   errors = $state<Record<Panel, string | null>>({
@@ -97,11 +115,14 @@ class AnalyticsStore {
     activity: null,
     heatmap: null,
     projects: null,
+    branches: null,
     hourOfWeek: null,
     sessionShape: null,
     velocity: null,
     tools: null,
     topSessions: null,
+    forecast: null,
+    monthToDate: null,
   });
 
   private versions: Record<Panel, number> = {
@@ -109,11 +130,14 @@ class AnalyticsStore {
     activity: 0,
     heatmap: 0,
     projects: 0,
+    branches: 0,
     hourOfWeek: 0,
     sessionShape: 0,
     velocity: 0,
     tools: 0,
     topSessions: 0,
+    forecast: 0,
+    monthToDate: 0,
   };
 
   get timezone(): string {
@@ -162,6 +186,11 @@ class AnalyticsStore {
     this.fetchAll();
   }
 
+  setIncludeBots(include: boolean) {
+    this.includeBots = include;
+    this.fetchAll();
+  }
+
   clearRecentlyActive() {
     this.recentlyActive = false;
     sessions.filters.recentlyActive = false;
@@ -173,6 +202,7 @@ class AnalyticsStore {
     this.selectedDate = null;
     this.fetchSummary();
     this.fetchProjects();
+    this.fetchBranches();
     this.fetchSessionShape();
     this.fetchVelocity();
     this.fetchTools();
@@ -193,12 +223,45 @@ class AnalyticsStore {
     this.fetchActivity();
     this.fetchHeatmap();
     this.fetchProjects();
+    this.fetchBranches();
     this.fetchSessionShape();
     this.fetchVelocity();
     this.fetchTools();
     this.fetchTopSessions();
   }
 
+  /**
+   * Seeds the date range and landing filters from the server's
+   * configured defaults. Runs once per page load so later
+   * changes by the user are kept; requests then pass
+   * defaults=false since the store holds the effective values.
+   */
+  async loadDefaults() {
+    if (this.defaultsLoaded) return;
+    this.defaultsLoaded = true;
+    try {
+      const d = await getAnalyticsDefaults();
+      if (d.range_days > 0) {
+        this.to = today();
+        this.from = daysAgo(d.range_days);
+      }
+      let filtersChanged = false;
+      if (d.project && !this.project) {
+        this.project = d.project;
+        sessions.filters.project = d.project;
+        filtersChanged = true;
+      }
+      if (d.min_user_messages > 0 && this.minUserMessages === 0) {
+        this.minUserMessages = d.min_user_messages;
+        sessions.filters.minUserMessages = d.min_user_messages;
+        filtersChanged = true;
+      }
+      if (filtersChanged) sessions.load();
+    } catch {
+      // Defaults are optional; fall back to built-in filters.
+    }
+  }
+
   private baseParams(
     opts: {
       includeProject?: boolean;
@@ -211,6 +274,7 @@ class AnalyticsStore {
       from: this.from,
       to: this.to,
       timezone: this.timezone,
+      defaults: false,
     };
     if (includeProject && this.project) {
       p.project = this.project;
@@ -224,6 +288,7 @@ class AnalyticsStore {
         Date.now() - 24 * 60 * 60 * 1000,
       ).toISOString();
     }
+    if (this.includeBots) p.include_bots = true;
     if (includeTime) {
       if (this.selectedDow !== null) p.dow = this.selectedDow;
       if (this.selectedHour !== null) {
@@ -246,6 +311,7 @@ class AnalyticsStore {
         from: this.selectedDate,
         to: this.selectedDate,
         timezone: this.timezone,
+        defaults: false,
       };
       if (includeProject && this.project) {
         p.project = this.project;
@@ -259,6 +325,7 @@ class AnalyticsStore {
           Date.now() - 24 * 60 * 60 * 1000,
         ).toISOString();
       }
+      if (this.includeBots) p.include_bots = true;
       if (includeTime) {
         if (this.selectedDow !== null) {
           p.dow = this.selectedDow;
@@ -302,11 +369,14 @@ class AnalyticsStore {
       this.fetchActivity(),
       this.fetchHeatmap(),
       this.fetchProjects(),
+      this.fetchBranches(),
       this.fetchHourOfWeek(),
       this.fetchSessionShape(),
       this.fetchVelocity(),
       this.fetchTools(),
       this.fetchTopSessions(),
+      this.fetchForecast(),
+      this.fetchMonthToDate(),
     ]);
   }
 
@@ -364,6 +434,24 @@ class AnalyticsStore {
     );
   }
 
+  // Branches drill into the selected project; with no project
+  // selected there is nothing to show.
+  async fetchBranches() {
+    if (!this.project) {
+      this.versions.branches++;
+      this.branches = null;
+      this.loading.branches = false;
+      return;
+    }
+    await this.executeFetch(
+      "branches",
+      () => getAnalyticsBranches(this.filterParams()),
+      (data) => {
+        this.branches = data;
+      },
+    );
+  }
+
   async fetchHourOfWeek() {
     await this.executeFetch(
       "hourOfWeek",
@@ -418,6 +506,29 @@ class AnalyticsStore {
     );
   }
 
+  // Forecast fits the full date range, like activity.
+  async fetchForecast() {
+    await this.executeFetch(
+      "forecast",
+      () => getAnalyticsForecast(this.baseParams()),
+      (data) => {
+        this.forecast = data;
+      },
+    );
+  }
+
+  // Month to date always runs through today, whatever range
+  // the dashboard shows.
+  async fetchMonthToDate() {
+    await this.executeFetch(
+      "monthToDate",
+      () => getAnalyticsMonthToDate({ ...this.baseParams(), to: today() }),
+      (data) => {
+        this.monthToDate = data;
+      },
+    );
+  }
+
   setTopMetric(m: TopSessionsMetric) {
     this.topMetric = m;
     this.fetchTopSessions();
@@ -440,6 +551,7 @@ class AnalyticsStore {
     }
     this.fetchSummary();
     this.fetchProjects();
+    this.fetchBranches();
     this.fetchSessionShape();
     this.fetchVelocity();
     this.fetchTools();
@@ -469,6 +581,7 @@ class AnalyticsStore {
     this.fetchActivity();
     this.fetchHeatmap();
     this.fetchProjects();
+    this.fetchBranches();
     this.fetchSessionShape();
     this.fetchVelocity();
     this.fetchTools();
@@ -29,6 +29,8 @@ vi.mock("../api/client.js", () => ({
   getAnalyticsVelocity: vi.fn(),
   getAnalyticsTools: vi.fn(),
   getAnalyticsTopSessions: vi.fn(),
+  getAnalyticsForecast: vi.fn(),
+  getAnalyticsDefaults: vi.fn(),
 }));
 
 
@@ -43,6 +45,15 @@ function makeSummary(): AnalyticsSummary {
     p90_messages: 20,
     most_active_project: "proj",
     concentration: 0.5,
+    user_messages: { human: 40, tool_result: 0, hook: 0, system: 0 },
+    tokens: {
+      input_tokens: 0,
+      output_tokens: 0,
+      cache_read_tokens: 0,
+      cache_creation_tokens: 0,
+      reasoning_tokens: 0,
+      cost: 0,
+    },
     agents: {},
   };
 }
@@ -17,6 +17,8 @@ describe("KNOWN_AGENTS", () => {
       "amp",
       "vscode-copilot",
       "openclaw",
+      "aider",
+      "goose",
     ]);
   });
 
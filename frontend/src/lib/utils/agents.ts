@@ -13,6 +13,8 @@ export const KNOWN_AGENTS: readonly AgentMeta[] = [
   { name: "amp", color: "var(--accent-coral)" },
   { name: "vscode-copilot", color: "var(--accent-teal)" },
   { name: "openclaw", color: "var(--accent-orange)" },
+  { name: "aider", color: "var(--accent-red)" },
+  { name: "goose", color: "var(--accent-indigo)" },
 ];
 
 const agentColorMap = new Map(
@@ -33,6 +33,15 @@ describe("generateAnalyticsCSV", () => {
       p90_messages: 35,
       most_active_project: "my-project",
       concentration: 0.456,
+      user_messages: { human: 12, tool_result: 0, hook: 0, system: 1 },
+      tokens: {
+        input_tokens: 0,
+        output_tokens: 0,
+        cache_read_tokens: 0,
+        cache_creation_tokens: 0,
+        reasoning_tokens: 0,
+        cost: 0,
+      },
       agents: {},
     };
 
@@ -56,6 +65,7 @@ describe("generateAnalyticsCSV", () => {
           sessions: 2,
           messages: 50,
           user_messages: 20,
+          user_origins: { human: 18, tool_result: 0, hook: 0, system: 2 },
           assistant_messages: 25,
           tool_calls: 5,
           thinking_messages: 0,
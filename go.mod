@@ -9,6 +9,7 @@ require (
 	github.com/stretchr/testify v1.11.1
 	github.com/tidwall/gjson v1.18.0
 	golang.org/x/mod v0.33.0
+	golang.org/x/sync v0.19.0
 )
 
 require (
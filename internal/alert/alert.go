@@ -0,0 +1,262 @@
+// Package alert checks configured rules, such as a daily spend
+// limit or a machine that has stopped syncing, on a schedule
+// and notifies webhooks when a rule starts or stops firing.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	gosync "sync"
+	"time"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// deliveryTimeout bounds each webhook request.
+const deliveryTimeout = 10 * time.Second
+
+// EventHeader names the event in webhook requests.
+const (
+	EventHeader   = "X-Agentsview-Event"
+	EventFiring   = "alert.firing"
+	EventResolved = "alert.resolved"
+)
+
+// State is a rule's outcome as of its last check.
+type State struct {
+	Rule      string  `json:"rule"`
+	Kind      string  `json:"kind"`
+	Machine   string  `json:"machine,omitempty"`
+	Threshold float64 `json:"threshold"`
+	Firing    bool    `json:"firing"`
+	// Value is the measured spend, percentage, or hours,
+	// matching Threshold.
+	Value   float64 `json:"value"`
+	Message string  `json:"message"`
+	// Since is when the rule last started or stopped firing.
+	// Times are RFC 3339 UTC; empty until the first check.
+	Since     string `json:"since,omitempty"`
+	CheckedAt string `json:"checked_at,omitempty"`
+	// Error is why the last check failed, if it did. The rule
+	// keeps its previous firing state until a check succeeds.
+	Error string `json:"error,omitempty"`
+}
+
+// Evaluator checks rules against the database and keeps their
+// latest states for the alerts endpoint.
+type Evaluator struct {
+	db       *db.DB
+	rules    []config.AlertRule
+	interval time.Duration
+	client   *http.Client
+	now      func() time.Time
+
+	mu     gosync.Mutex
+	states []State
+}
+
+// New returns an evaluator for cfg's rules. Call Run to start
+// checking them.
+func New(d *db.DB, cfg config.Alerts) *Evaluator {
+	states := make([]State, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		states[i] = State{
+			Rule:      r.Name,
+			Kind:      r.Kind,
+			Machine:   r.Machine,
+			Threshold: r.Threshold,
+			Message:   "not checked yet",
+		}
+	}
+	return &Evaluator{
+		db:       d,
+		rules:    cfg.Rules,
+		interval: cfg.Interval(),
+		client:   &http.Client{Timeout: deliveryTimeout},
+		now:      time.Now,
+		states:   states,
+	}
+}
+
+// States returns a copy of every rule's latest state, in
+// config order.
+func (e *Evaluator) States() []State {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.states)
+}
+
+// Run checks all rules right away and then every interval
+// until ctx is cancelled.
+func (e *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		e.Evaluate(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Evaluate checks every rule once, records the outcomes, and
+// notifies webhooks of rules that started or stopped firing.
+// A rule firing on its first check counts as starting to.
+func (e *Evaluator) Evaluate(ctx context.Context) {
+	now := e.now().UTC()
+	checked := now.Format(time.RFC3339)
+	for i, r := range e.rules {
+		value, firing, msg, err := e.check(ctx, r, now)
+
+		e.mu.Lock()
+		st := e.states[i]
+		st.CheckedAt = checked
+		if err != nil {
+			st.Error = err.Error()
+			e.states[i] = st
+			e.mu.Unlock()
+			slog.Warn("alert check failed",
+				"rule", r.Name, "err", err)
+			continue
+		}
+		changed := firing != st.Firing || st.Since == ""
+		notify := firing != st.Firing
+		st.Error = ""
+		st.Value = value
+		st.Message = msg
+		st.Firing = firing
+		if changed {
+			st.Since = checked
+		}
+		e.states[i] = st
+		e.mu.Unlock()
+
+		if notify {
+			slog.Info("alert state changed",
+				"rule", r.Name, "firing", firing, "message", msg)
+			e.deliver(ctx, r, st)
+		}
+	}
+}
+
+// check measures r's value at now and reports whether it
+// exceeds the threshold, with a one-line description.
+func (e *Evaluator) check(
+	ctx context.Context, r config.AlertRule, now time.Time,
+) (value float64, firing bool, msg string, err error) {
+	switch r.Kind {
+	case config.AlertCostPerDay:
+		today := now.In(time.Local).Format("2006-01-02")
+		value, err = e.db.GetEstimatedCost(ctx, db.AnalyticsFilter{
+			From: today, To: today, Timezone: time.Local.String(),
+		})
+		if err != nil {
+			return 0, false, "", fmt.Errorf("estimating cost: %w", err)
+		}
+		msg = fmt.Sprintf(
+			"est. $%.2f spent today (limit $%.2f)", value, r.Threshold,
+		)
+	case config.AlertParseErrorRate:
+		failed, err := e.db.ListQuarantinedFiles(ctx)
+		if err != nil {
+			return 0, false, "", err
+		}
+		stored, err := e.db.FileBackedSessionCount(ctx)
+		if err != nil {
+			return 0, false, "", err
+		}
+		if total := len(failed) + stored; total > 0 {
+			value = 100 * float64(len(failed)) / float64(total)
+		}
+		msg = fmt.Sprintf(
+			"%d of %d session files failing to parse (%.1f%%, limit %g%%)",
+			len(failed), len(failed)+stored, value, r.Threshold,
+		)
+	case config.AlertMachineSilent:
+		last, err := e.db.MachineLastSyncedAt(ctx, r.Machine)
+		if err != nil {
+			return 0, false, "", err
+		}
+		if last.IsZero() {
+			return 0, true, fmt.Sprintf(
+				"no sessions ever synced from %s", r.Machine,
+			), nil
+		}
+		value = now.Sub(last).Hours()
+		msg = fmt.Sprintf(
+			"last session from %s %.1f hours ago (limit %g)",
+			r.Machine, value, r.Threshold,
+		)
+	default:
+		return 0, false, "", fmt.Errorf("unknown kind %q", r.Kind)
+	}
+	return value, value > r.Threshold, msg, nil
+}
+
+// deliver sends st to each of r's webhooks. Failures are
+// logged and not retried; the rule's state is still recorded.
+func (e *Evaluator) deliver(
+	ctx context.Context, r config.AlertRule, st State,
+) {
+	event := EventResolved
+	if st.Firing {
+		event = EventFiring
+	}
+	for _, wh := range r.Webhooks {
+		body, err := payload(wh.Format, st)
+		if err == nil {
+			err = e.post(ctx, wh.URL, event, body)
+		}
+		if err != nil {
+			slog.Warn("alert webhook failed",
+				"rule", r.Name, "err", err)
+		}
+	}
+}
+
+// payload encodes st for a webhook: the state itself, or a
+// Slack message with a one-line summary.
+func payload(format string, st State) ([]byte, error) {
+	if format != config.AlertFormatSlack {
+		return json.Marshal(st)
+	}
+	status := "RESOLVED"
+	if st.Firing {
+		status = "FIRING"
+	}
+	return json.Marshal(map[string]string{
+		"text": fmt.Sprintf(
+			"[%s] agentsview alert %q: %s", status, st.Rule, st.Message,
+		),
+	})
+}
+
+func (e *Evaluator) post(
+	ctx context.Context, url, event string, body []byte,
+) error {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, url, bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+	return nil
+}
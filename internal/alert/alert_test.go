@@ -0,0 +1,144 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+)
+
+type notice struct {
+	event string
+	body  string
+}
+
+// webhook records every request it receives.
+func webhook(t *testing.T) (string, chan notice) {
+	t.Helper()
+	got := make(chan notice, 10)
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			got <- notice{r.Header.Get(EventHeader), string(body)}
+		},
+	))
+	t.Cleanup(srv.Close)
+	return srv.URL, got
+}
+
+func expectNone(t *testing.T, got chan notice) {
+	t.Helper()
+	select {
+	case n := <-got:
+		t.Fatalf("unexpected notice %+v", n)
+	default:
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+	d := dbtest.OpenTestDB(t)
+	base := time.Now().UTC().Truncate(time.Second)
+	dbtest.SeedSession(t, d, "s1", "alpha", func(s *db.Session) {
+		s.Machine = "laptop"
+		s.StartedAt = dbtest.Ptr(base.Format(time.RFC3339))
+		s.EndedAt = dbtest.Ptr(base.Format(time.RFC3339))
+	})
+	m := dbtest.AsstMsg("s1", 0, "x")
+	m.Model = "claude-sonnet-4-20250514"
+	m.InputTokens = 1_000_000
+	dbtest.SeedMessages(t, d, m)
+	if err := d.UpsertQuarantinedFile(db.QuarantinedFile{
+		Path: "/x.jsonl", Agent: "claude", Error: "bad", Attempts: 1,
+	}); err != nil {
+		t.Fatalf("UpsertQuarantinedFile: %v", err)
+	}
+
+	jsonURL, jsonGot := webhook(t)
+	slackURL, slackGot := webhook(t)
+	e := New(d, config.Alerts{Rules: []config.AlertRule{
+		{Name: "spend", Kind: config.AlertCostPerDay, Threshold: 2},
+		{
+			Name: "parse", Kind: config.AlertParseErrorRate, Threshold: 40,
+			Webhooks: []config.AlertWebhook{
+				{URL: slackURL, Format: config.AlertFormatSlack},
+			},
+		},
+		{
+			Name: "laptop", Kind: config.AlertMachineSilent,
+			Machine: "laptop", Threshold: 2,
+			Webhooks: []config.AlertWebhook{
+				{URL: jsonURL, Format: config.AlertFormatJSON},
+			},
+		},
+	}})
+	now := base.Add(time.Hour)
+	e.now = func() time.Time { return now }
+
+	e.Evaluate(ctx)
+	states := e.States()
+	if !states[0].Firing || states[0].Value != 3 {
+		t.Errorf("spend = %+v, want firing at $3", states[0])
+	}
+	if !states[1].Firing || states[1].Value != 50 {
+		t.Errorf("parse = %+v, want firing at 50%%", states[1])
+	}
+	if states[2].Firing || states[2].Since == "" {
+		t.Errorf("laptop = %+v, want checked and quiet", states[2])
+	}
+	n := <-slackGot
+	var slack struct{ Text string }
+	if err := json.Unmarshal([]byte(n.body), &slack); err != nil {
+		t.Fatalf("slack body %q: %v", n.body, err)
+	}
+	if n.event != EventFiring ||
+		!strings.HasPrefix(slack.Text, `[FIRING] agentsview alert "parse"`) {
+		t.Errorf("slack notice = %+v", n)
+	}
+	expectNone(t, jsonGot)
+
+	now = base.Add(3 * time.Hour)
+	e.Evaluate(ctx)
+	n = <-jsonGot
+	var st State
+	if err := json.Unmarshal([]byte(n.body), &st); err != nil {
+		t.Fatalf("json body %q: %v", n.body, err)
+	}
+	if n.event != EventFiring || st.Rule != "laptop" || !st.Firing {
+		t.Errorf("json notice = %+v", n)
+	}
+	expectNone(t, slackGot)
+
+	dbtest.SeedSession(t, d, "s2", "alpha", func(s *db.Session) {
+		s.Machine = "laptop"
+		s.EndedAt = dbtest.Ptr(now.Format(time.RFC3339))
+	})
+	e.Evaluate(ctx)
+	n = <-jsonGot
+	if n.event != EventResolved {
+		t.Errorf("event = %q, want %q", n.event, EventResolved)
+	}
+	if st := e.States()[2]; st.Firing || st.Value != 0 {
+		t.Errorf("laptop = %+v, want resolved", st)
+	}
+}
+
+func TestEvaluateSilentMachineWithoutSessions(t *testing.T) {
+	e := New(dbtest.OpenTestDB(t), config.Alerts{Rules: []config.AlertRule{{
+		Name: "ci", Kind: config.AlertMachineSilent,
+		Machine: "ci-box", Threshold: 1,
+	}}})
+	e.Evaluate(context.Background())
+	st := e.States()[0]
+	if !st.Firing || st.Message != "no sessions ever synced from ci-box" {
+		t.Errorf("state = %+v", st)
+	}
+}
@@ -2,17 +2,26 @@ package config
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/wesm/agentsview/internal/locale"
 	"github.com/wesm/agentsview/internal/parser"
+	"github.com/wesm/agentsview/internal/pricing"
+	"github.com/wesm/agentsview/internal/slo"
 )
 
 // Config holds all application configuration.
@@ -25,6 +34,24 @@ type Config struct {
 	CursorSecret string        `json:"cursor_secret"`
 	GithubToken  string        `json:"github_token,omitempty"`
 	WriteTimeout time.Duration `json:"-"`
+	LogLevel     string        `json:"log_level,omitempty"`
+
+	// TLSCert and TLSKey are PEM files for serving HTTPS, which
+	// also lets browsers use HTTP/2. Both or neither must be
+	// set.
+	TLSCert string `json:"tls_cert,omitempty"`
+	TLSKey  string `json:"tls_key,omitempty"`
+
+	// Demo serves a generated dataset from a temporary database
+	// instead of syncing the user's sessions.
+	Demo bool `json:"-"`
+
+	// PreviousCursorSecret is a rotated-out cursor_secret that
+	// still verifies cursors and links issued before the
+	// rotation, until PreviousCursorSecretUntil. The grace
+	// period starts on the first load that sees this value.
+	PreviousCursorSecret      string    `json:"previous_cursor_secret,omitempty"`
+	PreviousCursorSecretUntil time.Time `json:"-"`
 
 	// AgentDirs maps each AgentType to its configured
 	// directories. Single-dir agents store a one-element
@@ -35,7 +62,539 @@ type Config struct {
 	// set so loadFile doesn't override env-set values.
 	agentDirSource map[parser.AgentType]dirSource
 
+	// logLevelFromEnv records that LogLevel came from the
+	// environment so the config file does not override it.
+	logLevelFromEnv bool
+
+	// previousCursorSecretID fingerprints the previous secret
+	// PreviousCursorSecretUntil was set for, so a later
+	// rotation starts a new grace period.
+	previousCursorSecretID string
+
 	ResultContentBlockedCategories []string `json:"result_content_blocked_categories,omitempty"`
+
+	// SLOs are latency objectives reported by the analytics
+	// SLO endpoint.
+	SLOs []SLO `json:"slos,omitempty"`
+
+	// AnalyticsDefaults fill in analytics parameters that a
+	// request omits, so a shared instance opens onto a chosen
+	// view.
+	AnalyticsDefaults AnalyticsDefaults `json:"analytics_defaults"`
+
+	// SavedFilters are named sets of analytics defaults,
+	// selected with the filter query parameter.
+	SavedFilters []SavedFilter `json:"saved_filters,omitempty"`
+
+	// WorkingHours are the windows the heatmap and hour-of-week
+	// analytics split activity by, so after-hours usage can be
+	// shaded.
+	WorkingHours []WorkingHours `json:"working_hours,omitempty"`
+
+	// Projects holds per-project reporting settings keyed by
+	// project name, so work tracked for a client project can
+	// be reported in that client's timezone and currency.
+	Projects map[string]ProjectSettings `json:"projects,omitempty"`
+
+	// DailyDigest configures the once-a-day desktop
+	// notification with the previous day's headline numbers.
+	DailyDigest DailyDigest `json:"daily_digest"`
+
+	// Locale sets number, currency, and week-start conventions
+	// for the UI and server-rendered text.
+	Locale LocaleSettings `json:"locale"`
+
+	// SyncHooks are notified with each session's metadata after
+	// sync stores it, so external indexers can mirror ingestion.
+	SyncHooks []SyncHook `json:"sync_hooks,omitempty"`
+
+	// Alerts are rules checked on a schedule that notify
+	// webhooks when they start or stop firing.
+	Alerts Alerts `json:"alerts"`
+
+	// SyncWorkers is how many session files sync parses at
+	// once. Zero picks a count from the number of CPUs.
+	SyncWorkers int `json:"sync_workers,omitempty"`
+
+	// InactiveProjectDays hides projects with no session in
+	// this many days from the project list and analytics
+	// unless inactive projects are asked for. Zero disables.
+	InactiveProjectDays int `json:"inactive_project_days,omitempty"`
+
+	// Pricing overrides or adds per-model rates, in USD per
+	// million tokens, keyed by model name prefix. Apply it
+	// with pricing.SetOverrides.
+	Pricing map[string]pricing.Rate `json:"pricing,omitempty"`
+
+	// IngestToken enables the ingest endpoint that other
+	// machines push their sessions to; each push must present
+	// it as a bearer token. Empty disables ingest.
+	IngestToken string `json:"ingest_token,omitempty"`
+
+	// Push is the server `agentsview push` sends this
+	// machine's sessions to.
+	Push PushTarget `json:"push"`
+
+	// Compaction rolls old months up into monthly totals each
+	// night and can strip their raw tool call payloads.
+	Compaction Compaction `json:"compaction"`
+}
+
+// Compaction configures the nightly compaction of old data.
+type Compaction struct {
+	// HorizonDays is how far back data stays in full detail;
+	// months that started before the horizon are compacted.
+	// Zero disables compaction.
+	HorizonDays int `json:"horizon_days,omitempty"`
+	// StripPayloads clears compacted months' raw tool call
+	// inputs and results; analytics read derived columns and
+	// stay exact. Rows are kept, so the row count still grows
+	// with every session.
+	StripPayloads bool `json:"strip_payloads,omitempty"`
+}
+
+// PushTarget is a central agentsview server that accepts pushed
+// sessions.
+type PushTarget struct {
+	URL   string `json:"url,omitempty"`
+	Token string `json:"token,omitempty"`
+	// Machine labels sessions synced on this machine; empty
+	// uses the host name.
+	Machine string `json:"machine,omitempty"`
+}
+
+// SyncHook is an external receiver for stored sessions: either
+// a URL the session JSON is POSTed to, or a command that reads
+// it on stdin.
+type SyncHook struct {
+	URL string `json:"url,omitempty"`
+	// Command is the program and its arguments; it is run
+	// directly, not through a shell.
+	Command []string `json:"command,omitempty"`
+	// TimeoutSeconds bounds each delivery. Zero means 10.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// normalize validates h and fills in the default timeout.
+func (h *SyncHook) normalize() error {
+	if (h.URL == "") == (len(h.Command) == 0) {
+		return fmt.Errorf("set exactly one of url and command")
+	}
+	if h.URL != "" {
+		if !isHTTPURL(h.URL) {
+			return fmt.Errorf("url must be an http(s) URL")
+		}
+	}
+	if len(h.Command) > 0 && h.Command[0] == "" {
+		return fmt.Errorf("command must name a program")
+	}
+	if h.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout_seconds must not be negative")
+	}
+	if h.TimeoutSeconds == 0 {
+		h.TimeoutSeconds = 10
+	}
+	return nil
+}
+
+// Timeout returns the per-delivery timeout.
+func (h SyncHook) Timeout() time.Duration {
+	return time.Duration(h.TimeoutSeconds) * time.Second
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") &&
+		u.Host != ""
+}
+
+// Alert rule kinds accepted in the config file.
+const (
+	// AlertCostPerDay fires when today's estimated spend, in
+	// USD, exceeds the threshold.
+	AlertCostPerDay = "cost_per_day"
+	// AlertParseErrorRate fires when the percentage of
+	// session files failing to parse exceeds the threshold.
+	AlertParseErrorRate = "parse_error_rate"
+	// AlertMachineSilent fires when a machine has synced no
+	// session for more than the threshold in hours.
+	AlertMachineSilent = "machine_silent"
+)
+
+// Alert webhook payload formats.
+const (
+	AlertFormatJSON  = "json"
+	AlertFormatSlack = "slack"
+)
+
+// Alerts configures the scheduled alert rules.
+type Alerts struct {
+	// IntervalMinutes is how often rules are checked. Zero
+	// means 5.
+	IntervalMinutes int         `json:"interval_minutes,omitempty"`
+	Rules           []AlertRule `json:"rules,omitempty"`
+}
+
+// Interval returns how often rules are checked.
+func (a Alerts) Interval() time.Duration {
+	if a.IntervalMinutes <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(a.IntervalMinutes) * time.Minute
+}
+
+// AlertRule is a condition such as "today's spend is over $50"
+// and the webhooks told when it starts and stops holding.
+type AlertRule struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Threshold is USD for cost_per_day, a percentage for
+	// parse_error_rate, and hours for machine_silent.
+	Threshold float64 `json:"threshold"`
+	// Machine is the machine machine_silent watches.
+	Machine  string         `json:"machine,omitempty"`
+	Webhooks []AlertWebhook `json:"webhooks,omitempty"`
+}
+
+// AlertWebhook is a URL alert notices are POSTed to.
+type AlertWebhook struct {
+	URL string `json:"url"`
+	// Format is "json" for the alert itself or "slack" for a
+	// Slack incoming-webhook message. Empty means json.
+	Format string `json:"format,omitempty"`
+}
+
+// normalize validates r and fills in a default name.
+func (r *AlertRule) normalize() error {
+	switch r.Kind {
+	case AlertCostPerDay, AlertParseErrorRate:
+	case AlertMachineSilent:
+		if r.Machine == "" {
+			return fmt.Errorf("%s needs a machine", r.Kind)
+		}
+	default:
+		return fmt.Errorf(
+			"kind must be %s, %s, or %s",
+			AlertCostPerDay, AlertParseErrorRate, AlertMachineSilent,
+		)
+	}
+	if r.Threshold <= 0 {
+		return fmt.Errorf("threshold must be positive")
+	}
+	if r.Kind == AlertParseErrorRate && r.Threshold > 100 {
+		return fmt.Errorf("threshold must be a percentage")
+	}
+	for i := range r.Webhooks {
+		wh := &r.Webhooks[i]
+		if !isHTTPURL(wh.URL) {
+			return fmt.Errorf("webhook url must be an http(s) URL")
+		}
+		switch wh.Format {
+		case "":
+			wh.Format = AlertFormatJSON
+		case AlertFormatJSON, AlertFormatSlack:
+		default:
+			return fmt.Errorf(
+				"webhook format must be %s or %s",
+				AlertFormatJSON, AlertFormatSlack,
+			)
+		}
+	}
+	if r.Name == "" {
+		r.Name = fmt.Sprintf("%s %g", r.Kind, r.Threshold)
+		if r.Machine != "" {
+			r.Name = fmt.Sprintf(
+				"%s %s %g", r.Kind, r.Machine, r.Threshold,
+			)
+		}
+	}
+	return nil
+}
+
+// DailyDigest configures the daily desktop notification.
+type DailyDigest struct {
+	Enabled bool `json:"enabled"`
+	// Time is when the digest fires, HH:MM. Empty means 09:00.
+	Time string `json:"time,omitempty"`
+	// Timezone is the IANA zone for Time and for what counts
+	// as yesterday. Empty uses the system's local zone.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// normalize validates d and fills in the default time.
+func (d *DailyDigest) normalize() error {
+	if d.Time == "" {
+		d.Time = "09:00"
+	}
+	if clockMinute(d.Time) < 0 {
+		return fmt.Errorf("time must be HH:MM")
+	}
+	if d.Timezone != "" {
+		if _, err := time.LoadLocation(d.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", d.Timezone)
+		}
+	}
+	return nil
+}
+
+// Minute returns Time as minutes after midnight. Call only on
+// a normalized digest.
+func (d DailyDigest) Minute() int { return clockMinute(d.Time) }
+
+// Location returns the digest's timezone.
+func (d DailyDigest) Location() *time.Location {
+	if d.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// ProjectSettings are reporting settings for one project.
+type ProjectSettings struct {
+	// Timezone is the IANA zone analytics scoped to the
+	// project bucket days in, overriding the request's.
+	Timezone string `json:"timezone,omitempty"`
+	// Currency is the ISO 4217 code cost figures for the
+	// project are reported in. Empty means USD.
+	Currency string `json:"currency,omitempty"`
+	// RateMultiplier converts estimated USD cost into the
+	// amount billed in Currency, covering both exchange rate
+	// and any markup. Zero means 1.
+	RateMultiplier float64 `json:"rate_multiplier,omitempty"`
+}
+
+// normalize validates p and upper-cases the currency code.
+func (p *ProjectSettings) normalize() error {
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", p.Timezone)
+		}
+	}
+	if p.Currency != "" {
+		code, err := currencyCode(p.Currency)
+		if err != nil {
+			return err
+		}
+		p.Currency = code
+	}
+	if p.RateMultiplier < 0 {
+		return fmt.Errorf("rate_multiplier must not be negative")
+	}
+	return nil
+}
+
+// currencyCode upper-cases and validates an ISO 4217 code.
+func currencyCode(s string) (string, error) {
+	code := strings.ToUpper(s)
+	if len(code) != 3 ||
+		strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf(
+			"currency must be a 3-letter code, got %q", code,
+		)
+	}
+	return code, nil
+}
+
+// LocaleSettings choose how numbers, amounts, and dates are
+// rendered. Currency and WeekStart override the locale's own.
+type LocaleSettings struct {
+	Tag      string `json:"tag"` // BCP 47, e.g. de-DE
+	Currency string `json:"currency,omitempty"`
+	// WeekStart is monday, saturday, or sunday.
+	WeekStart string `json:"week_start,omitempty"`
+}
+
+// normalize validates l and upper-cases the currency code.
+func (l *LocaleSettings) normalize() error {
+	if _, ok := locale.Lookup(l.Tag); !ok {
+		return fmt.Errorf("unsupported locale %q", l.Tag)
+	}
+	if l.Currency != "" {
+		code, err := currencyCode(l.Currency)
+		if err != nil {
+			return err
+		}
+		l.Currency = code
+	}
+	if l.WeekStart != "" {
+		if _, ok := locale.ParseWeekStart(l.WeekStart); !ok {
+			return fmt.Errorf(
+				"week_start must be monday, saturday, or sunday",
+			)
+		}
+	}
+	return nil
+}
+
+// LocaleFormat returns the configured formatting conventions,
+// or locale.Default when no locale is configured.
+func (c *Config) LocaleFormat() locale.Format {
+	f, ok := locale.Lookup(c.Locale.Tag)
+	if !ok {
+		return locale.Default
+	}
+	if c.Locale.Currency != "" {
+		f = f.WithCurrency(c.Locale.Currency)
+	}
+	if ws, ok := locale.ParseWeekStart(c.Locale.WeekStart); ok {
+		f.WeekStart = ws
+	}
+	return f
+}
+
+// InactiveProjectCutoff returns the RFC3339 time before which
+// a project's last session makes it inactive, or "" when
+// archival is disabled.
+func (c *Config) InactiveProjectCutoff(now time.Time) string {
+	if c.InactiveProjectDays <= 0 {
+		return ""
+	}
+	return now.UTC().AddDate(0, 0, -c.InactiveProjectDays).
+		Format(time.RFC3339)
+}
+
+// ProjectSettings returns the settings configured for project.
+func (c *Config) ProjectSettings(project string) (ProjectSettings, bool) {
+	p, ok := c.Projects[project]
+	return p, ok
+}
+
+// WorkingHours is a recurring working-time window such as
+// Monday-Friday 09:00-17:00. An End before Start wraps past
+// midnight into the next day.
+type WorkingHours struct {
+	// Days are ISO weekdays, 0=Mon through 6=Sun. Empty means
+	// Monday-Friday.
+	Days  []int  `json:"days,omitempty"`
+	Start string `json:"start"` // HH:MM
+	End   string `json:"end"`   // HH:MM
+	// Timezone is the IANA zone the window is defined in.
+	// Empty uses the timezone of each analytics request.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// StartMinute and EndMinute return the window bounds as
+// minutes after midnight. Call only on validated windows.
+func (w WorkingHours) StartMinute() int { return clockMinute(w.Start) }
+func (w WorkingHours) EndMinute() int   { return clockMinute(w.End) }
+
+// clockMinute parses HH:MM into minutes after midnight, or -1.
+func clockMinute(s string) int {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return -1
+	}
+	return t.Hour()*60 + t.Minute()
+}
+
+// normalize validates w and fills in the default days.
+func (w *WorkingHours) normalize() error {
+	start, end := clockMinute(w.Start), clockMinute(w.End)
+	if start < 0 || end < 0 {
+		return fmt.Errorf("start and end must be HH:MM")
+	}
+	if start == end {
+		return fmt.Errorf("start and end must differ")
+	}
+	for _, d := range w.Days {
+		if d < 0 || d > 6 {
+			return fmt.Errorf("days must be 0-6 (Mon=0, Sun=6)")
+		}
+	}
+	if len(w.Days) == 0 {
+		w.Days = []int{0, 1, 2, 3, 4}
+	}
+	if w.Timezone != "" {
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", w.Timezone)
+		}
+	}
+	return nil
+}
+
+// AnalyticsDefaults are analytics filter values applied when a
+// request does not set them. Zero values mean no default.
+type AnalyticsDefaults struct {
+	// RangeDays is the length of the default date range,
+	// ending today. Zero keeps the built-in 30 days.
+	RangeDays       int    `json:"range_days,omitempty"`
+	Project         string `json:"project,omitempty"`
+	MinUserMessages int    `json:"min_user_messages,omitempty"`
+}
+
+func (d AnalyticsDefaults) validate() error {
+	if d.RangeDays < 0 {
+		return fmt.Errorf("range_days must not be negative")
+	}
+	if d.MinUserMessages < 0 {
+		return fmt.Errorf("min_user_messages must not be negative")
+	}
+	return nil
+}
+
+// Merge returns d with non-zero fields of o taking precedence.
+func (d AnalyticsDefaults) Merge(o AnalyticsDefaults) AnalyticsDefaults {
+	if o.RangeDays > 0 {
+		d.RangeDays = o.RangeDays
+	}
+	if o.Project != "" {
+		d.Project = o.Project
+	}
+	if o.MinUserMessages > 0 {
+		d.MinUserMessages = o.MinUserMessages
+	}
+	return d
+}
+
+// SavedFilter is a named set of analytics defaults.
+type SavedFilter struct {
+	Name string `json:"name"`
+	AnalyticsDefaults
+}
+
+// SavedFilter returns the saved filter with the given name.
+func (c *Config) SavedFilter(name string) (SavedFilter, bool) {
+	for _, f := range c.SavedFilters {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return SavedFilter{}, false
+}
+
+// SLO is a latency objective such as "p90 first response under
+// 30 seconds".
+type SLO struct {
+	Name         string  `json:"name"`
+	Metric       string  `json:"metric"`
+	Percentile   float64 `json:"percentile"`
+	ThresholdSec float64 `json:"threshold_sec"`
+}
+
+// normalize validates s and fills in a default name.
+func (s *SLO) normalize() error {
+	switch s.Metric {
+	case slo.MetricFirstResponse, slo.MetricTurnCycle:
+	default:
+		return fmt.Errorf(
+			"metric must be %s or %s",
+			slo.MetricFirstResponse, slo.MetricTurnCycle,
+		)
+	}
+	if s.Percentile <= 0 || s.Percentile > 100 {
+		return fmt.Errorf("percentile must be in (0, 100]")
+	}
+	if s.ThresholdSec <= 0 {
+		return fmt.Errorf("threshold_sec must be positive")
+	}
+	if s.Name == "" {
+		s.Name = fmt.Sprintf("%s p%g", s.Metric, s.Percentile)
+	}
+	return nil
 }
 
 type dirSource int
@@ -89,6 +648,7 @@ func Default() (Config, error) {
 		DataDir:                        dataDir,
 		DBPath:                         filepath.Join(dataDir, "sessions.db"),
 		WriteTimeout:                   30 * time.Second,
+		LogLevel:                       "info",
 		AgentDirs:                      agentDirs,
 		agentDirSource:                 agentDirSource,
 		ResultContentBlockedCategories: []string{"Read", "Glob"},
@@ -104,9 +664,23 @@ func Load(fs *flag.FlagSet) (Config, error) {
 		return cfg, err
 	}
 	applyFlags(&cfg, fs)
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return cfg, fmt.Errorf(
+			"tls_cert and tls_key must be set together",
+		)
+	}
+	if cfg.SyncWorkers < 0 {
+		return cfg, fmt.Errorf("sync-workers must not be negative")
+	}
 	return cfg, nil
 }
 
+// TLSEnabled reports whether the server is configured to serve
+// HTTPS.
+func (c Config) TLSEnabled() bool {
+	return c.TLSCert != "" && c.TLSKey != ""
+}
+
 // LoadMinimal builds a Config from defaults, env, and config file,
 // without parsing CLI flags. Use this for subcommands that manage
 // their own flag sets.
@@ -123,6 +697,9 @@ func LoadMinimal() (Config, error) {
 	if err := cfg.ensureCursorSecret(); err != nil {
 		return cfg, fmt.Errorf("ensuring cursor secret: %w", err)
 	}
+	if err := cfg.startCursorSecretGrace(time.Now()); err != nil {
+		return cfg, fmt.Errorf("starting cursor secret grace: %w", err)
+	}
 	cfg.DBPath = filepath.Join(cfg.DataDir, "sessions.db")
 	return cfg, nil
 }
@@ -141,9 +718,30 @@ func (c *Config) loadFile() error {
 	}
 
 	var file struct {
-		GithubToken                    string   `json:"github_token"`
-		CursorSecret                   string   `json:"cursor_secret"`
-		ResultContentBlockedCategories []string `json:"result_content_blocked_categories"`
+		GithubToken                    string                     `json:"github_token"`
+		CursorSecret                   string                     `json:"cursor_secret"`
+		PreviousCursorSecret           string                     `json:"previous_cursor_secret"`
+		PreviousCursorSecretUntil      string                     `json:"previous_cursor_secret_until"`
+		PreviousCursorSecretID         string                     `json:"previous_cursor_secret_id"`
+		LogLevel                       string                     `json:"log_level"`
+		TLSCert                        string                     `json:"tls_cert"`
+		TLSKey                         string                     `json:"tls_key"`
+		ResultContentBlockedCategories []string                   `json:"result_content_blocked_categories"`
+		SLOs                           []SLO                      `json:"slos"`
+		AnalyticsDefaults              *AnalyticsDefaults         `json:"analytics_defaults"`
+		SavedFilters                   []SavedFilter              `json:"saved_filters"`
+		WorkingHours                   []WorkingHours             `json:"working_hours"`
+		Projects                       map[string]ProjectSettings `json:"projects"`
+		DailyDigest                    *DailyDigest               `json:"daily_digest"`
+		Locale                         *LocaleSettings            `json:"locale"`
+		InactiveProjectDays            int                        `json:"inactive_project_days"`
+		SyncWorkers                    int                        `json:"sync_workers"`
+		SyncHooks                      []SyncHook                 `json:"sync_hooks"`
+		Alerts                         *Alerts                    `json:"alerts"`
+		Pricing                        map[string]pricing.Rate    `json:"pricing"`
+		IngestToken                    string                     `json:"ingest_token"`
+		Push                           *PushTarget                `json:"push"`
+		Compaction                     *Compaction                `json:"compaction"`
 	}
 	if err := json.Unmarshal(data, &file); err != nil {
 		return fmt.Errorf("parsing config: %w", err)
@@ -154,9 +752,180 @@ func (c *Config) loadFile() error {
 	if file.CursorSecret != "" {
 		c.CursorSecret = file.CursorSecret
 	}
+	if file.PreviousCursorSecret != "" {
+		c.PreviousCursorSecret = file.PreviousCursorSecret
+	}
+	if file.PreviousCursorSecretUntil != "" {
+		until, err := time.Parse(
+			time.RFC3339, file.PreviousCursorSecretUntil,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"invalid previous_cursor_secret_until: %w", err,
+			)
+		}
+		c.PreviousCursorSecretUntil = until
+	}
+	c.previousCursorSecretID = file.PreviousCursorSecretID
+	if file.LogLevel != "" && !c.logLevelFromEnv {
+		c.LogLevel = file.LogLevel
+	}
+	if file.TLSCert != "" {
+		c.TLSCert = file.TLSCert
+	}
+	if file.TLSKey != "" {
+		c.TLSKey = file.TLSKey
+	}
 	if file.ResultContentBlockedCategories != nil {
 		c.ResultContentBlockedCategories = file.ResultContentBlockedCategories
 	}
+	for i, target := range file.SLOs {
+		if err := target.normalize(); err != nil {
+			slog.Warn(
+				"config: skipping invalid slo",
+				"index", i, "name", target.Name, "err", err,
+			)
+			continue
+		}
+		c.SLOs = append(c.SLOs, target)
+	}
+	if d := file.AnalyticsDefaults; d != nil {
+		if err := d.validate(); err != nil {
+			slog.Warn(
+				"config: ignoring invalid analytics_defaults",
+				"err", err,
+			)
+		} else {
+			c.AnalyticsDefaults = *d
+		}
+	}
+	for i, sf := range file.SavedFilters {
+		err := sf.validate()
+		switch {
+		case sf.Name == "":
+			err = fmt.Errorf("name is required")
+		case err == nil:
+			if _, dup := c.SavedFilter(sf.Name); dup {
+				err = fmt.Errorf("duplicate name")
+			}
+		}
+		if err != nil {
+			slog.Warn(
+				"config: skipping invalid saved filter",
+				"index", i, "name", sf.Name, "err", err,
+			)
+			continue
+		}
+		c.SavedFilters = append(c.SavedFilters, sf)
+	}
+	for i, wh := range file.WorkingHours {
+		if err := wh.normalize(); err != nil {
+			slog.Warn(
+				"config: skipping invalid working_hours window",
+				"index", i, "err", err,
+			)
+			continue
+		}
+		c.WorkingHours = append(c.WorkingHours, wh)
+	}
+	for name, ps := range file.Projects {
+		if err := ps.normalize(); err != nil {
+			slog.Warn(
+				"config: skipping invalid project settings",
+				"project", name, "err", err,
+			)
+			continue
+		}
+		if c.Projects == nil {
+			c.Projects = make(map[string]ProjectSettings)
+		}
+		c.Projects[name] = ps
+	}
+	if d := file.DailyDigest; d != nil {
+		if err := d.normalize(); err != nil {
+			slog.Warn(
+				"config: ignoring invalid daily_digest",
+				"err", err,
+			)
+		} else {
+			c.DailyDigest = *d
+		}
+	}
+	for i, h := range file.SyncHooks {
+		if err := h.normalize(); err != nil {
+			slog.Warn(
+				"config: skipping invalid sync hook",
+				"index", i, "err", err,
+			)
+			continue
+		}
+		c.SyncHooks = append(c.SyncHooks, h)
+	}
+	if a := file.Alerts; a != nil {
+		if a.IntervalMinutes < 0 {
+			slog.Warn(
+				"config: ignoring negative alerts.interval_minutes",
+				"value", a.IntervalMinutes,
+			)
+			a.IntervalMinutes = 0
+		}
+		c.Alerts.IntervalMinutes = a.IntervalMinutes
+		for i, r := range a.Rules {
+			if err := r.normalize(); err != nil {
+				slog.Warn(
+					"config: skipping invalid alert rule",
+					"index", i, "name", r.Name, "err", err,
+				)
+				continue
+			}
+			c.Alerts.Rules = append(c.Alerts.Rules, r)
+		}
+	}
+	if err := pricing.Validate(file.Pricing); err != nil {
+		slog.Warn("config: ignoring invalid pricing", "err", err)
+	} else {
+		c.Pricing = file.Pricing
+	}
+	c.IngestToken = file.IngestToken
+	if file.Push != nil {
+		c.Push = *file.Push
+	}
+	if cp := file.Compaction; cp != nil {
+		if cp.HorizonDays < 0 {
+			slog.Warn(
+				"config: ignoring negative compaction.horizon_days",
+				"value", cp.HorizonDays,
+			)
+		} else {
+			c.Compaction = *cp
+		}
+	}
+	if file.InactiveProjectDays < 0 {
+		slog.Warn(
+			"config: ignoring negative inactive_project_days",
+			"value", file.InactiveProjectDays,
+		)
+	} else {
+		c.InactiveProjectDays = file.InactiveProjectDays
+	}
+	if file.SyncWorkers < 0 {
+		slog.Warn(
+			"config: ignoring negative sync_workers",
+			"value", file.SyncWorkers,
+		)
+	} else if file.SyncWorkers > 0 {
+		c.SyncWorkers = file.SyncWorkers
+	}
+	if l := file.Locale; l != nil {
+		if err := l.normalize(); err != nil {
+			slog.Warn(
+				"config: ignoring invalid locale",
+				"err", err,
+			)
+		} else {
+			c.Locale = *l
+		}
+	}
 
 	// Parse config-file dir arrays for agents that have a
 	// ConfigKey. Only apply when not already set by env var.
@@ -177,9 +946,9 @@ func (c *Config) loadFile() error {
 		}
 		var dirs []string
 		if err := json.Unmarshal(rawVal, &dirs); err != nil {
-			log.Printf(
-				"config: %s: expected string array: %v",
-				def.ConfigKey, err,
+			slog.Warn(
+				"config: expected string array",
+				"key", def.ConfigKey, "err", err,
 			)
 			continue
 		}
@@ -202,7 +971,52 @@ func (c *Config) ensureCursorSecret() error {
 	}
 	secret := base64.StdEncoding.EncodeToString(b)
 	c.CursorSecret = secret
+	return c.saveFileKey("cursor_secret", secret)
+}
+
+// CursorSecretGrace is how long a rotated-out cursor secret
+// keeps verifying, so open tabs can finish paginating.
+const CursorSecretGrace = 24 * time.Hour
 
+// startCursorSecretGrace persists the expiry of a newly set
+// previous_cursor_secret, with a fingerprint of the secret it
+// applies to, so the grace period runs from the first load
+// after each rotation rather than every restart.
+func (c *Config) startCursorSecretGrace(now time.Time) error {
+	if c.PreviousCursorSecret == "" {
+		return nil
+	}
+	id := cursorSecretID(c.PreviousCursorSecret)
+	if !c.PreviousCursorSecretUntil.IsZero() &&
+		c.previousCursorSecretID == id {
+		return nil
+	}
+	c.PreviousCursorSecretUntil = now.Add(CursorSecretGrace).UTC().
+		Truncate(time.Second)
+	c.previousCursorSecretID = id
+	return c.saveFileKeys(map[string]any{
+		"previous_cursor_secret_until": c.PreviousCursorSecretUntil.
+			Format(time.RFC3339),
+		"previous_cursor_secret_id": id,
+	})
+}
+
+// cursorSecretID returns a short fingerprint of secret that
+// identifies it in the config file without repeating it.
+func cursorSecretID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// saveFileKey sets one top-level key in the config file,
+// keeping the others as they are.
+func (c *Config) saveFileKey(key string, value any) error {
+	return c.saveFileKeys(map[string]any{key: value})
+}
+
+// saveFileKeys sets top-level keys in the config file in one
+// write, keeping the others as they are.
+func (c *Config) saveFileKeys(kv map[string]any) error {
 	if err := os.MkdirAll(c.DataDir, 0o700); err != nil {
 		return fmt.Errorf("creating data dir: %w", err)
 	}
@@ -218,7 +1032,7 @@ func (c *Config) ensureCursorSecret() error {
 		}
 	}
 
-	existing["cursor_secret"] = secret
+	maps.Copy(existing, kv)
 	out, err := json.MarshalIndent(existing, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshaling config: %w", err)
@@ -237,9 +1051,35 @@ func (c *Config) loadEnv() {
 			c.agentDirSource[def.Type] = dirEnv
 		}
 	}
+	// Agent SDK and headless Claude runs launched with a custom
+	// CLAUDE_CONFIG_DIR write transcripts under its projects
+	// directory; scan it alongside the defaults.
+	if v := os.Getenv("CLAUDE_CONFIG_DIR"); v != "" &&
+		c.agentDirSource[parser.AgentClaude] == dirDefault {
+		dir := filepath.Join(v, "projects")
+		if !slices.Contains(c.AgentDirs[parser.AgentClaude], dir) {
+			c.AgentDirs[parser.AgentClaude] = append(
+				c.AgentDirs[parser.AgentClaude], dir,
+			)
+		}
+	}
+	// CURSOR_DIR names the Cursor data directory (~/.cursor);
+	// agent transcripts live in its projects subdirectory. An
+	// explicit CURSOR_PROJECTS_DIR takes precedence.
+	if v := os.Getenv("CURSOR_DIR"); v != "" &&
+		c.agentDirSource[parser.AgentCursor] != dirEnv {
+		c.AgentDirs[parser.AgentCursor] = []string{
+			filepath.Join(v, "projects"),
+		}
+		c.agentDirSource[parser.AgentCursor] = dirEnv
+	}
 	if v := os.Getenv("AGENT_VIEWER_DATA_DIR"); v != "" {
 		c.DataDir = v
 	}
+	if v := os.Getenv("AGENT_VIEWER_LOG_LEVEL"); v != "" {
+		c.LogLevel = v
+		c.logLevelFromEnv = true
+	}
 }
 
 // RegisterServeFlags registers serve-command flags on fs.
@@ -251,6 +1091,26 @@ func RegisterServeFlags(fs *flag.FlagSet) {
 		"no-browser", false,
 		"Don't open browser on startup",
 	)
+	fs.String(
+		"log-level", "info",
+		"Minimum log level (debug, info, warn, error)",
+	)
+	fs.String(
+		"tls-cert", "",
+		"PEM certificate file; serves HTTPS with -tls-key",
+	)
+	fs.String(
+		"tls-key", "",
+		"PEM private key file for -tls-cert",
+	)
+	fs.Bool(
+		"demo", false,
+		"Serve a generated demo dataset instead of your sessions",
+	)
+	fs.Int(
+		"sync-workers", 0,
+		"Session files to parse at once during sync (0 = by CPU count)",
+	)
 }
 
 // applyFlags copies explicitly-set flags from fs into cfg.
@@ -267,6 +1127,16 @@ func applyFlags(cfg *Config, fs *flag.FlagSet) {
 			cfg.Port, _ = strconv.Atoi(f.Value.String())
 		case "no-browser":
 			cfg.NoBrowser = f.Value.String() == "true"
+		case "log-level":
+			cfg.LogLevel = f.Value.String()
+		case "tls-cert":
+			cfg.TLSCert = f.Value.String()
+		case "tls-key":
+			cfg.TLSKey = f.Value.String()
+		case "demo":
+			cfg.Demo = f.Value.String() == "true"
+		case "sync-workers":
+			cfg.SyncWorkers, _ = strconv.Atoi(f.Value.String())
 		}
 	})
 }
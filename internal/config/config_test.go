@@ -10,7 +10,9 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/wesm/agentsview/internal/locale"
 	"github.com/wesm/agentsview/internal/parser"
 )
 
@@ -76,10 +78,14 @@ func TestLoadEnv_OverridesDataDir(t *testing.T) {
 }
 
 func TestLoad_AppliesExplicitFlags(t *testing.T) {
-	cfg, err := loadConfigFromFlags(t, "-host", "0.0.0.0", "-port", "9090")
+	cfg, err := loadConfigFromFlags(t,
+		"-host", "0.0.0.0", "-port", "9090", "-demo")
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !cfg.Demo {
+		t.Error("Demo = false with -demo")
+	}
 
 	if cfg.Host != "0.0.0.0" {
 		t.Errorf("Host = %q, want %q", cfg.Host, "0.0.0.0")
@@ -108,6 +114,22 @@ func TestLoad_DefaultsWithoutFlags(t *testing.T) {
 	}
 }
 
+func TestLoad_TLS(t *testing.T) {
+	setupTestEnv(t)
+	cfg, err := loadConfigFromFlags(t,
+		"-tls-cert", "cert.pem", "-tls-key", "key.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.TLSEnabled() {
+		t.Errorf("TLSEnabled = false with cert and key")
+	}
+
+	if _, err := loadConfigFromFlags(t, "-tls-cert", "cert.pem"); err == nil {
+		t.Error("expected error for tls-cert without tls-key")
+	}
+}
+
 func TestLoad_NilFlagSet(t *testing.T) {
 	cfg, err := Load(nil)
 	if err != nil {
@@ -198,6 +220,7 @@ func TestLoadFile_ReadsDirArrays(t *testing.T) {
 	writeConfig(t, dir, map[string]any{
 		"claude_project_dirs": []string{"/path/one", "/path/two"},
 		"codex_sessions_dirs": []string{"/codex/a"},
+		"amp_dirs":            []string{"/amp/threads"},
 	})
 
 	cfg, err := LoadMinimal()
@@ -220,6 +243,10 @@ func TestLoadFile_ReadsDirArrays(t *testing.T) {
 	if len(codexDirs) != 1 || codexDirs[0] != "/codex/a" {
 		t.Errorf("codex dirs = %v", codexDirs)
 	}
+	ampDirs := cfg.ResolveDirs(parser.AgentAmp)
+	if len(ampDirs) != 1 || ampDirs[0] != "/amp/threads" {
+		t.Errorf("amp dirs = %v", ampDirs)
+	}
 }
 
 func TestResolveDirs(t *testing.T) {
@@ -482,3 +509,528 @@ func TestLoadFile_ResultContentBlockedCategories(t *testing.T) {
 		})
 	}
 }
+
+func TestLogLevel_Layering(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		env   string
+		flags []string
+		want  string
+	}{
+		{"default", "", "", nil, "info"},
+		{"config file", "warn", "", nil, "warn"},
+		{"env overrides file", "warn", "debug", nil, "debug"},
+		{
+			"flag overrides env", "warn", "debug",
+			[]string{"-log-level", "error"}, "error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupTestEnv(t)
+			if tt.file != "" {
+				writeConfig(t, dir, map[string]any{
+					"log_level": tt.file,
+				})
+			}
+			t.Setenv("AGENT_VIEWER_LOG_LEVEL", tt.env)
+
+			cfg, err := loadConfigFromFlags(t, tt.flags...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.LogLevel != tt.want {
+				t.Errorf(
+					"LogLevel = %q, want %q",
+					cfg.LogLevel, tt.want,
+				)
+			}
+		})
+	}
+}
+
+func TestLoadFile_WorkingHours(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"working_hours": []map[string]any{
+			{"start": "09:00", "end": "17:30"},
+			{
+				"days":     []int{5},
+				"start":    "22:00",
+				"end":      "02:00",
+				"timezone": "Europe/Berlin",
+			},
+			{"start": "9am", "end": "5pm"},
+			{"start": "09:00", "end": "09:00"},
+			{"days": []int{7}, "start": "09:00", "end": "17:00"},
+			{"start": "09:00", "end": "17:00", "timezone": "Mars/Base"},
+		},
+	})
+
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.WorkingHours) != 2 {
+		t.Fatalf("len(WorkingHours) = %d, want 2",
+			len(cfg.WorkingHours))
+	}
+	wh := cfg.WorkingHours[0]
+	if len(wh.Days) != 5 {
+		t.Errorf("default Days = %v, want Mon-Fri", wh.Days)
+	}
+	if wh.StartMinute() != 540 || wh.EndMinute() != 1050 {
+		t.Errorf("minutes = %d-%d, want 540-1050",
+			wh.StartMinute(), wh.EndMinute())
+	}
+	if tz := cfg.WorkingHours[1].Timezone; tz != "Europe/Berlin" {
+		t.Errorf("Timezone = %q, want Europe/Berlin", tz)
+	}
+}
+
+func TestLoadFile_Projects(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"projects": map[string]any{
+			"acme": map[string]any{
+				"timezone":        "Europe/Berlin",
+				"currency":        "eur",
+				"rate_multiplier": 1.35,
+			},
+			"tz-only":  map[string]any{"timezone": "Asia/Tokyo"},
+			"bad-tz":   map[string]any{"timezone": "Mars/Base"},
+			"bad-code": map[string]any{"currency": "EURO"},
+			"bad-rate": map[string]any{"rate_multiplier": -1},
+		},
+	})
+
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Projects) != 2 {
+		t.Fatalf("Projects = %v, want acme and tz-only", cfg.Projects)
+	}
+	acme, ok := cfg.ProjectSettings("acme")
+	if !ok {
+		t.Fatal("acme settings missing")
+	}
+	want := ProjectSettings{
+		Timezone:       "Europe/Berlin",
+		Currency:       "EUR",
+		RateMultiplier: 1.35,
+	}
+	if acme != want {
+		t.Errorf("acme = %+v, want %+v", acme, want)
+	}
+	if _, ok := cfg.ProjectSettings("bad-tz"); ok {
+		t.Error("invalid project settings were kept")
+	}
+}
+
+func TestLoadFile_DailyDigest(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"daily_digest": map[string]any{
+			"enabled":  true,
+			"timezone": "Europe/Berlin",
+		},
+	})
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := cfg.DailyDigest
+	if !d.Enabled || d.Time != "09:00" || d.Minute() != 9*60 {
+		t.Errorf("DailyDigest = %+v, want enabled at 09:00", d)
+	}
+	if d.Location().String() != "Europe/Berlin" {
+		t.Errorf("Location = %v, want Europe/Berlin", d.Location())
+	}
+
+	writeConfig(t, dir, map[string]any{
+		"daily_digest": map[string]any{"enabled": true, "time": "9am"},
+	})
+	cfg, err = loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DailyDigest.Enabled {
+		t.Error("invalid daily_digest was kept")
+	}
+}
+
+func TestLoadFile_Locale(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"locale": map[string]any{
+			"tag":        "fr_FR",
+			"currency":   "chf",
+			"week_start": "Sunday",
+		},
+	})
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := cfg.LocaleFormat()
+	if f.Locale != "fr-FR" || f.Currency != "CHF" ||
+		f.WeekStart != locale.Sunday {
+		t.Errorf("LocaleFormat = %+v", f)
+	}
+
+	writeConfig(t, dir, map[string]any{
+		"locale": map[string]any{"tag": "xx-YY"},
+	})
+	cfg, err = loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Locale.Tag != "" || cfg.LocaleFormat() != locale.Default {
+		t.Errorf("invalid locale was kept: %+v", cfg.Locale)
+	}
+}
+
+func TestLoadFile_InactiveProjectDays(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{"inactive_project_days": 90})
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	if got := cfg.InactiveProjectCutoff(now); got != "2024-04-01T12:00:00Z" {
+		t.Errorf("InactiveProjectCutoff = %q", got)
+	}
+
+	writeConfig(t, dir, map[string]any{"inactive_project_days": -1})
+	cfg, err = loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.InactiveProjectCutoff(now); got != "" {
+		t.Errorf("negative days cutoff = %q, want disabled", got)
+	}
+}
+
+func TestLoad_SyncWorkers(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{"sync_workers": 4})
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SyncWorkers != 4 {
+		t.Errorf("SyncWorkers = %d, want 4 from file", cfg.SyncWorkers)
+	}
+
+	cfg, err = loadConfigFromFlags(t, "-sync-workers", "16")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SyncWorkers != 16 {
+		t.Errorf("SyncWorkers = %d, want 16 from flag", cfg.SyncWorkers)
+	}
+
+	if _, err := loadConfigFromFlags(t, "-sync-workers", "-1"); err == nil {
+		t.Error("expected error for negative sync-workers")
+	}
+}
+
+func TestLoadFile_Pricing(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"pricing": map[string]any{
+			"claude-sonnet-4": map[string]any{"input": 2.5, "output": 12},
+			"my-local-model":  map[string]any{"input": 0.1},
+		},
+	})
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := cfg.Pricing["claude-sonnet-4"]; r.Input != 2.5 || r.Output != 12 {
+		t.Errorf("claude-sonnet-4 rate = %+v", r)
+	}
+	if len(cfg.Pricing) != 2 {
+		t.Errorf("Pricing = %+v, want 2 models", cfg.Pricing)
+	}
+
+	writeConfig(t, dir, map[string]any{
+		"pricing": map[string]any{
+			"gpt-4o": map[string]any{"input": -1},
+		},
+	})
+	cfg, err = loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Pricing != nil {
+		t.Errorf("negative rate accepted: %+v", cfg.Pricing)
+	}
+}
+
+func TestLoadFile_IngestAndPush(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"ingest_token": "s3cret",
+		"push": map[string]any{
+			"url":     "http://central:8080",
+			"token":   "t0ken",
+			"machine": "laptop",
+		},
+	})
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.IngestToken != "s3cret" {
+		t.Errorf("IngestToken = %q", cfg.IngestToken)
+	}
+	want := PushTarget{
+		URL: "http://central:8080", Token: "t0ken", Machine: "laptop",
+	}
+	if cfg.Push != want {
+		t.Errorf("Push = %+v, want %+v", cfg.Push, want)
+	}
+}
+
+func TestLoadFile_Compaction(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"compaction": map[string]any{"horizon_days": 730, "strip_payloads": true},
+	})
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Compaction{HorizonDays: 730, StripPayloads: true}
+	if cfg.Compaction != want {
+		t.Errorf("Compaction = %+v, want %+v", cfg.Compaction, want)
+	}
+
+	writeConfig(t, dir, map[string]any{
+		"compaction": map[string]any{"horizon_days": -1},
+	})
+	cfg, err = loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Compaction != (Compaction{}) {
+		t.Errorf("negative horizon: Compaction = %+v", cfg.Compaction)
+	}
+}
+
+func TestLoadFile_SyncHooks(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"sync_hooks": []map[string]any{
+			{"url": "http://127.0.0.1:9000/ingest"},
+			{"command": []string{"indexer", "--stdin"}, "timeout_seconds": 30},
+			{"url": "ftp://example.com"},
+			{"url": "http://x", "command": []string{"both"}},
+			{},
+		},
+	})
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.SyncHooks) != 2 {
+		t.Fatalf("SyncHooks = %+v, want 2 valid hooks", cfg.SyncHooks)
+	}
+	if h := cfg.SyncHooks[0]; h.Timeout() != 10*time.Second {
+		t.Errorf("default timeout = %v, want 10s", h.Timeout())
+	}
+	if h := cfg.SyncHooks[1]; h.Command[0] != "indexer" ||
+		h.Timeout() != 30*time.Second {
+		t.Errorf("command hook = %+v", h)
+	}
+}
+
+func TestLoadFile_Alerts(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"alerts": map[string]any{
+			"interval_minutes": 15,
+			"rules": []map[string]any{
+				{"kind": "cost_per_day", "threshold": 50, "webhooks": []map[string]any{
+					{"url": "https://hooks.example.com/x", "format": "slack"},
+					{"url": "http://127.0.0.1:9000/alerts"},
+				}},
+				{"name": "ci quiet", "kind": "machine_silent", "machine": "ci", "threshold": 24},
+				{"kind": "machine_silent", "threshold": 24},
+				{"kind": "parse_error_rate", "threshold": 150},
+				{"kind": "cost_per_day", "threshold": 0},
+				{"kind": "queue_depth", "threshold": 1},
+				{"kind": "cost_per_day", "threshold": 5, "webhooks": []map[string]any{
+					{"url": "https://x", "format": "teams"},
+				}},
+			},
+		},
+	})
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Alerts.Interval(); got != 15*time.Minute {
+		t.Errorf("Interval = %v, want 15m", got)
+	}
+	rules := cfg.Alerts.Rules
+	if len(rules) != 2 {
+		t.Fatalf("Rules = %+v, want 2 valid rules", rules)
+	}
+	if r := rules[0]; r.Name != "cost_per_day 50" ||
+		r.Webhooks[0].Format != AlertFormatSlack ||
+		r.Webhooks[1].Format != AlertFormatJSON {
+		t.Errorf("cost rule = %+v", r)
+	}
+	if r := rules[1]; r.Name != "ci quiet" || r.Machine != "ci" {
+		t.Errorf("machine rule = %+v", r)
+	}
+	if got := (Alerts{}).Interval(); got != 5*time.Minute {
+		t.Errorf("default Interval = %v, want 5m", got)
+	}
+}
+
+func TestLoadFile_SLOs(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"slos": []map[string]any{
+			{
+				"metric":        "first_response",
+				"percentile":    90,
+				"threshold_sec": 30,
+			},
+			{
+				"name":          "bad",
+				"metric":        "latency",
+				"percentile":    90,
+				"threshold_sec": 30,
+			},
+			{
+				"name":          "turns",
+				"metric":        "turn_cycle",
+				"percentile":    50,
+				"threshold_sec": 0,
+			},
+		},
+	})
+
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.SLOs) != 1 {
+		t.Fatalf("len(SLOs) = %d, want 1", len(cfg.SLOs))
+	}
+	if got := cfg.SLOs[0].Name; got != "first_response p90" {
+		t.Errorf("Name = %q, want %q", got, "first_response p90")
+	}
+}
+
+func TestLoadFile_AnalyticsDefaults(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"analytics_defaults": map[string]any{
+			"range_days": 14,
+			"project":    "web",
+		},
+		"saved_filters": []map[string]any{
+			{"name": "busy", "min_user_messages": 5},
+			{"name": "busy", "project": "dup"},
+			{"project": "unnamed"},
+			{"name": "bad", "range_days": -1},
+		},
+	})
+
+	cfg, err := loadConfigFromFlags(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AnalyticsDefaults{RangeDays: 14, Project: "web"}
+	if cfg.AnalyticsDefaults != want {
+		t.Errorf("AnalyticsDefaults = %+v, want %+v",
+			cfg.AnalyticsDefaults, want)
+	}
+	if len(cfg.SavedFilters) != 1 {
+		t.Fatalf("len(SavedFilters) = %d, want 1", len(cfg.SavedFilters))
+	}
+	sf, ok := cfg.SavedFilter("busy")
+	if !ok {
+		t.Fatal("saved filter busy not found")
+	}
+	merged := cfg.AnalyticsDefaults.Merge(sf.AnalyticsDefaults)
+	want.MinUserMessages = 5
+	if merged != want {
+		t.Errorf("Merge = %+v, want %+v", merged, want)
+	}
+}
+
+func TestLoadEnv_ClaudeConfigDir(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{})
+	t.Setenv("CLAUDE_CONFIG_DIR", "/sdk/claude")
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := cfg.ResolveDirs(parser.AgentClaude)
+	want := filepath.Join("/sdk/claude", "projects")
+	if len(dirs) != 2 || dirs[1] != want {
+		t.Errorf("claude dirs = %v, want default plus %q", dirs, want)
+	}
+	if cfg.IsUserConfigured(parser.AgentClaude) {
+		t.Error("CLAUDE_CONFIG_DIR should not mark dirs user-configured")
+	}
+
+	// An explicit projects dir takes precedence.
+	t.Setenv("CLAUDE_PROJECTS_DIR", "/explicit")
+	cfg, err = LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs = cfg.ResolveDirs(parser.AgentClaude)
+	if len(dirs) != 1 || dirs[0] != "/explicit" {
+		t.Errorf("claude dirs = %v, want [/explicit]", dirs)
+	}
+}
+
+func TestCursorDirs(t *testing.T) {
+	dir := setupTestEnv(t)
+	writeConfig(t, dir, map[string]any{
+		"cursor_dirs": []string{"/cursor/a", "/cursor/b"},
+	})
+
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := cfg.ResolveDirs(parser.AgentCursor)
+	if len(dirs) != 2 || dirs[0] != "/cursor/a" {
+		t.Errorf("cursor dirs = %v, want config array", dirs)
+	}
+
+	t.Setenv("CURSOR_DIR", "/home/me/.cursor")
+	cfg, err = LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs = cfg.ResolveDirs(parser.AgentCursor)
+	want := filepath.Join("/home/me/.cursor", "projects")
+	if len(dirs) != 1 || dirs[0] != want {
+		t.Errorf("cursor dirs = %v, want [%s]", dirs, want)
+	}
+
+	t.Setenv("CURSOR_PROJECTS_DIR", "/explicit")
+	cfg, err = LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs = cfg.ResolveDirs(parser.AgentCursor)
+	if len(dirs) != 1 || dirs[0] != "/explicit" {
+		t.Errorf("cursor dirs = %v, want [/explicit]", dirs)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func readConfigFile(t *testing.T, dir string) Config {
@@ -96,6 +97,94 @@ func TestCursorSecret_LoadErrorOnInvalidConfig(t *testing.T) {
 	}
 }
 
+func TestPreviousCursorSecret_GraceStartsOnce(t *testing.T) {
+	dir := setupTestEnv(t)
+
+	if err := os.WriteFile(filepath.Join(dir, configFileName), []byte(
+		`{"cursor_secret": "bmV3", "previous_cursor_secret": "b2xk"}`,
+	), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	before := time.Now()
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := cfg.PreviousCursorSecretUntil
+	if until.Before(before.Add(CursorSecretGrace-time.Second)) ||
+		until.After(time.Now().Add(CursorSecretGrace)) {
+		t.Errorf("until = %v, want about %v from now", until, CursorSecretGrace)
+	}
+
+	// A later load keeps the persisted expiry.
+	cfg2, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg2.PreviousCursorSecretUntil.Equal(until) {
+		t.Errorf("second until = %v, want %v",
+			cfg2.PreviousCursorSecretUntil, until)
+	}
+	if cfg2.PreviousCursorSecret != "b2xk" || cfg2.CursorSecret != "bmV3" {
+		t.Errorf("secrets = %q, %q", cfg2.CursorSecret, cfg2.PreviousCursorSecret)
+	}
+}
+
+func TestPreviousCursorSecret_GraceRestartsOnRotation(t *testing.T) {
+	dir := setupTestEnv(t)
+	path := filepath.Join(dir, configFileName)
+
+	if err := os.WriteFile(path, []byte(
+		`{"cursor_secret": "YQ==", "previous_cursor_secret": "b2xk"}`,
+	), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadMinimal(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate again long after the first grace ended: the old
+	// cursor_secret becomes previous_cursor_secret while the
+	// stale expiry from the first rotation is still on file.
+	var file map[string]any
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	file["previous_cursor_secret"] = "YQ=="
+	file["cursor_secret"] = "Yg=="
+	file["previous_cursor_secret_until"] = "2020-01-01T00:00:00Z"
+	data, _ = json.Marshal(file)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now()
+	cfg, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := cfg.PreviousCursorSecretUntil; until.Before(
+		before.Add(CursorSecretGrace - time.Second),
+	) {
+		t.Errorf("until = %v, want a new grace period from now", until)
+	}
+
+	// The new expiry is persisted for the new secret and kept.
+	cfg2, err := LoadMinimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg2.PreviousCursorSecretUntil.Equal(cfg.PreviousCursorSecretUntil) {
+		t.Errorf("second until = %v, want %v",
+			cfg2.PreviousCursorSecretUntil, cfg.PreviousCursorSecretUntil)
+	}
+}
+
 func TestCursorSecret_PreservesOtherFields(t *testing.T) {
 	dir := setupTestEnv(t)
 
@@ -3,10 +3,15 @@ package db
 import (
 	"context"
 	"fmt"
+	"maps"
 	"math"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 // maxSQLVars is the maximum bind variables per IN clause to stay
@@ -40,18 +45,178 @@ func queryChunked(
 	return nil
 }
 
+// maxActiveGapSec caps the gap between consecutive messages
+// counted as active time, so idle stretches do not inflate it.
+const maxActiveGapSec = 300.0
+
+// maxChunkWorkers bounds the chunks queryChunkedParallel runs at
+// once. It matches the reader pool size so chunks do not queue
+// for connections.
+const maxChunkWorkers = 4
+
+// queryChunkedParallel is queryChunked with the chunks run
+// concurrently on the reader pool. fn must be safe for
+// concurrent use. The first error cancels the context passed to
+// chunks still running.
+func queryChunkedParallel(
+	ctx context.Context,
+	ids []string,
+	fn func(ctx context.Context, chunk []string) error,
+) error {
+	if len(ids) <= maxSQLVars {
+		if len(ids) == 0 {
+			return nil
+		}
+		return fn(ctx, ids)
+	}
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(maxChunkWorkers)
+	for i := 0; i < len(ids); i += maxSQLVars {
+		chunk := ids[i:min(i+maxSQLVars, len(ids))]
+		g.Go(func() error { return fn(gctx, chunk) })
+	}
+	return g.Wait()
+}
+
 // AnalyticsFilter is the shared filter for all analytics queries.
 type AnalyticsFilter struct {
-	From            string // ISO date YYYY-MM-DD, inclusive
-	To              string // ISO date YYYY-MM-DD, inclusive
-	Machine         string // optional machine filter
-	Project         string // optional project filter
-	Agent           string // optional agent filter
-	Timezone        string // IANA timezone for day bucketing
-	DayOfWeek       *int   // nil = all, 0=Mon, 6=Sun (ISO)
-	Hour            *int   // nil = all, 0-23
-	MinUserMessages int    // user_message_count >= N
-	ActiveSince     string // ISO timestamp cutoff
+	From            string `json:"from"`                        // ISO date YYYY-MM-DD, inclusive
+	To              string `json:"to"`                          // ISO date YYYY-MM-DD, inclusive
+	Machine         string `json:"machine,omitempty"`           // optional machine filter
+	Project         string `json:"project,omitempty"`           // optional project filter
+	Agent           string `json:"agent,omitempty"`             // optional agent filter
+	Tag             string `json:"tag,omitempty"`               // optional session tag filter
+	Language        string `json:"language,omitempty"`          // dominant prompt language, ISO 639-1
+	Timezone        string `json:"timezone,omitempty"`          // IANA timezone for day bucketing
+	DayOfWeek       *int   `json:"dow,omitempty"`               // nil = all, 0=Mon, 6=Sun (ISO)
+	Hour            *int   `json:"hour,omitempty"`              // nil = all, 0-23
+	MinUserMessages int    `json:"min_user_messages,omitempty"` // user_message_count >= N
+	ActiveSince     string `json:"active_since,omitempty"`      // ISO timestamp cutoff
+	IncludeBots     bool   `json:"include_bots,omitempty"`      // include machines labeled as bots
+	IncludeArchived bool   `json:"include_archived,omitempty"`  // include sessions flagged archived
+
+	// ActiveProjectsSince drops projects with no session
+	// active at or after this ISO timestamp, unless Project
+	// selects one explicitly.
+	ActiveProjectsSince string `json:"active_projects_since,omitempty"`
+
+	// IncludeSessionIDs asks summary, tools, and velocity to list
+	// the sessions behind each bucket.
+	IncludeSessionIDs bool `json:"include_session_ids,omitempty"`
+
+	// WorkingHours makes heatmap and hour-of-week split their
+	// counts into in-window and out-of-window activity.
+	WorkingHours []WorkingWindow `json:"working_hours,omitempty"`
+
+	// CostRates make the forecast report each listed project's
+	// cost in that project's billing currency.
+	CostRates map[string]CostRate `json:"cost_rates,omitempty"`
+}
+
+// WorkingWindow is a recurring working-time window. Times are
+// matched in the window's own timezone, independent of the
+// timezone used for day bucketing. An EndMinute at or before
+// StartMinute wraps past midnight.
+type WorkingWindow struct {
+	Days        []int  `json:"days"`         // ISO weekdays, 0=Mon
+	StartMinute int    `json:"start_minute"` // minutes after midnight
+	EndMinute   int    `json:"end_minute"`
+	Timezone    string `json:"timezone,omitempty"` // empty = filter timezone
+}
+
+// WindowSplit divides a count into activity inside and outside
+// the configured working hours.
+type WindowSplit struct {
+	InWindow    int `json:"in_window"`
+	OutOfWindow int `json:"out_of_window"`
+}
+
+// windowMatcher tests timestamps against working windows with
+// their locations resolved once per query.
+type windowMatcher struct {
+	windows []WorkingWindow
+	locs    []*time.Location
+}
+
+// windowMatcher returns nil when no working hours are set.
+func (f AnalyticsFilter) windowMatcher() *windowMatcher {
+	if len(f.WorkingHours) == 0 {
+		return nil
+	}
+	m := &windowMatcher{windows: f.WorkingHours}
+	for _, w := range f.WorkingHours {
+		loc := f.location()
+		if w.Timezone != "" {
+			if l, err := time.LoadLocation(w.Timezone); err == nil {
+				loc = l
+			}
+		}
+		m.locs = append(m.locs, loc)
+	}
+	return m
+}
+
+// contains reports whether t falls inside any window.
+func (m *windowMatcher) contains(t time.Time) bool {
+	for i, w := range m.windows {
+		lt := t.In(m.locs[i])
+		dow := (int(lt.Weekday()) + 6) % 7 // ISO Mon=0
+		minute := lt.Hour()*60 + lt.Minute()
+		if w.StartMinute < w.EndMinute {
+			if slices.Contains(w.Days, dow) &&
+				minute >= w.StartMinute && minute < w.EndMinute {
+				return true
+			}
+			continue
+		}
+		// Overnight: the part after midnight belongs to the
+		// previous day's window.
+		if slices.Contains(w.Days, dow) && minute >= w.StartMinute {
+			return true
+		}
+		if slices.Contains(w.Days, (dow+6)%7) && minute < w.EndMinute {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxContributingSessionIDs caps each contributing session list
+// so a wide date range cannot blow up the response.
+const MaxContributingSessionIDs = 200
+
+// ContributingSessions lists the sessions behind one analytics
+// number, for click-through from a chart datapoint. IDs holds
+// at most MaxContributingSessionIDs entries; Total is the full
+// count.
+type ContributingSessions struct {
+	IDs   []string `json:"ids"`
+	Total int      `json:"total"`
+
+	seen map[string]bool
+}
+
+// contributors returns an empty list when the filter asks for
+// session IDs, or nil so the field is omitted.
+func (f AnalyticsFilter) contributors() *ContributingSessions {
+	if !f.IncludeSessionIDs {
+		return nil
+	}
+	return &ContributingSessions{
+		IDs: []string{}, seen: make(map[string]bool),
+	}
+}
+
+// add records a session once. It is a no-op on a nil list.
+func (c *ContributingSessions) add(id string) {
+	if c == nil || c.seen[id] {
+		return
+	}
+	c.seen[id] = true
+	c.Total++
+	if len(c.IDs) < MaxContributingSessionIDs {
+		c.IDs = append(c.IDs, id)
+	}
 }
 
 // location loads the timezone or returns UTC on error.
@@ -93,12 +258,32 @@ func (f AnalyticsFilter) utcRange() (string, string) {
 func (f AnalyticsFilter) buildWhere(
 	dateCol string,
 ) (string, []any) {
+	return f.buildWhereAs("", dateCol)
+}
+
+// buildWhereAs is buildWhere for queries that alias the
+// sessions table, where columns shared with joined tables (id)
+// must be qualified.
+func (f AnalyticsFilter) buildWhereAs(
+	alias, dateCol string,
+) (string, []any) {
+	idCol := "id"
+	if alias != "" {
+		idCol = alias + ".id"
+	}
 	preds := []string{
 		"message_count > 0",
 		"relationship_type NOT IN ('subagent', 'fork')",
+		idCol + ` NOT IN
+			(SELECT session_id FROM session_flags WHERE muted = 1)`,
 	}
 	var args []any
 
+	if !f.IncludeArchived {
+		preds = append(preds, idCol+` NOT IN
+			(SELECT session_id FROM session_flags WHERE archived = 1)`)
+	}
+
 	utcFrom, utcTo := f.utcRange()
 	preds = append(preds, dateCol+" >= ?")
 	args = append(args, utcFrom)
@@ -108,6 +293,9 @@ func (f AnalyticsFilter) buildWhere(
 	if f.Machine != "" {
 		preds = append(preds, "machine = ?")
 		args = append(args, f.Machine)
+	} else if !f.IncludeBots {
+		preds = append(preds, `machine NOT IN
+			(SELECT name FROM machines WHERE is_bot = 1)`)
 	}
 
 	if f.Project != "" {
@@ -120,6 +308,17 @@ func (f AnalyticsFilter) buildWhere(
 		args = append(args, f.Agent)
 	}
 
+	if f.Tag != "" {
+		preds = append(preds, idCol+` IN
+			(SELECT session_id FROM session_tags WHERE tag = ?)`)
+		args = append(args, f.Tag)
+	}
+
+	if f.Language != "" {
+		preds = append(preds, "language = ?")
+		args = append(args, f.Language)
+	}
+
 	if f.MinUserMessages > 0 {
 		preds = append(preds, "user_message_count >= ?")
 		args = append(args, f.MinUserMessages)
@@ -131,9 +330,49 @@ func (f AnalyticsFilter) buildWhere(
 		args = append(args, f.ActiveSince)
 	}
 
+	if f.ActiveProjectsSince != "" && f.Project == "" {
+		preds = append(preds, `project IN
+			(SELECT project FROM sessions
+			WHERE message_count > 0 AND `+sessionActivityCol+` >= ?)`)
+		args = append(args, f.ActiveProjectsSince)
+	}
+
 	return strings.Join(preds, " AND "), args
 }
 
+// UnmatchedFilterFields returns the names of the project,
+// machine, agent, and tag fields that f sets to a value no
+// stored session has, in any date range. Such a filter is usually a
+// client mistake rather than a real empty result.
+func (db *DB) UnmatchedFilterFields(
+	ctx context.Context, f AnalyticsFilter,
+) ([]string, error) {
+	var out []string
+	for _, c := range []struct{ field, table, column, value string }{
+		{"project", "sessions", "project", f.Project},
+		{"machine", "sessions", "machine", f.Machine},
+		{"agent", "sessions", "agent", f.Agent},
+		{"tag", "session_tags", "tag", f.Tag},
+	} {
+		if c.value == "" {
+			continue
+		}
+		var found bool
+		err := db.getReader().QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM "+c.table+" WHERE "+
+				c.column+" = ?)",
+			c.value,
+		).Scan(&found)
+		if err != nil {
+			return nil, fmt.Errorf("checking %s filter: %w", c.field, err)
+		}
+		if !found {
+			out = append(out, c.field)
+		}
+	}
+	return out, nil
+}
+
 // HasTimeFilter returns true when hour-of-day or day-of-week
 // filtering is active.
 func (f AnalyticsFilter) HasTimeFilter() bool {
@@ -168,7 +407,7 @@ func (db *DB) filteredSessionIDs(
 ) (map[string]bool, error) {
 	loc := f.location()
 	dateCol := "COALESCE(NULLIF(s.started_at, ''), s.created_at)"
-	where, args := f.buildWhere(dateCol)
+	where, args := f.buildWhereAs("s", dateCol)
 
 	query := `SELECT s.id, m.timestamp
 		FROM sessions s
@@ -274,8 +513,11 @@ func medianInt(sorted []int, n int) int {
 
 // AgentSummary holds per-agent counts for the summary.
 type AgentSummary struct {
-	Sessions int `json:"sessions"`
-	Messages int `json:"messages"`
+	Sessions     int                   `json:"sessions"`
+	Messages     int                   `json:"messages"`
+	UserMessages UserOriginCounts      `json:"user_messages"`
+	Tokens       TokenTotals           `json:"tokens"`
+	SessionIDs   *ContributingSessions `json:"session_ids,omitempty"`
 }
 
 // AnalyticsSummary is the response for the summary endpoint.
@@ -289,7 +531,10 @@ type AnalyticsSummary struct {
 	P90Messages    int                      `json:"p90_messages"`
 	MostActive     string                   `json:"most_active_project"`
 	Concentration  float64                  `json:"concentration"`
+	UserMessages   UserOriginCounts         `json:"user_messages"`
+	Tokens         TokenTotals              `json:"tokens"` // cost in BaseCurrency
 	Agents         map[string]*AgentSummary `json:"agents"`
+	SessionIDs     *ContributingSessions    `json:"session_ids,omitempty"`
 }
 
 // GetAnalyticsSummary returns aggregate statistics.
@@ -323,6 +568,7 @@ func (db *DB) GetAnalyticsSummary(
 	defer rows.Close()
 
 	type sessionRow struct {
+		id       string
 		date     string
 		messages int
 		agent    string
@@ -348,7 +594,7 @@ func (db *DB) GetAnalyticsSummary(
 			continue
 		}
 		all = append(all, sessionRow{
-			date: date, messages: mc,
+			id: id, date: date, messages: mc,
 			agent: agent, project: project,
 		})
 	}
@@ -359,11 +605,31 @@ func (db *DB) GetAnalyticsSummary(
 
 	var s AnalyticsSummary
 	s.Agents = make(map[string]*AgentSummary)
+	s.SessionIDs = f.contributors()
 
 	if len(all) == 0 {
 		return s, nil
 	}
 
+	ids := make([]string, len(all))
+	for i, r := range all {
+		ids[i] = r.id
+	}
+	origins, err := db.userOriginsBySession(ctx, ids)
+	if err != nil {
+		return AnalyticsSummary{}, err
+	}
+	tokens := make(map[string]TokenTotals)
+	err = db.usageByModel(ctx, ids,
+		func(sid, _ string, t TokenTotals) {
+			sum := tokens[sid]
+			sum.add(t)
+			tokens[sid] = sum
+		})
+	if err != nil {
+		return AnalyticsSummary{}, err
+	}
+
 	days := make(map[string]bool)
 	projects := make(map[string]int) // project -> message count
 	msgCounts := make([]int, 0, len(all))
@@ -376,12 +642,24 @@ func (db *DB) GetAnalyticsSummary(
 		msgCounts = append(msgCounts, r.messages)
 
 		if s.Agents[r.agent] == nil {
-			s.Agents[r.agent] = &AgentSummary{}
+			s.Agents[r.agent] = &AgentSummary{
+				SessionIDs: f.contributors(),
+			}
 		}
 		s.Agents[r.agent].Sessions++
 		s.Agents[r.agent].Messages += r.messages
+		s.UserMessages.merge(origins[r.id])
+		s.Agents[r.agent].UserMessages.merge(origins[r.id])
+		s.Tokens.add(tokens[r.id])
+		s.Agents[r.agent].Tokens.add(tokens[r.id])
+		s.SessionIDs.add(r.id)
+		s.Agents[r.agent].SessionIDs.add(r.id)
 	}
 
+	s.Tokens.finish(1)
+	for _, a := range s.Agents {
+		a.Tokens.finish(1)
+	}
 	s.ActiveProjects = len(projects)
 	s.ActiveDays = len(days)
 	s.AvgMessages = math.Round(
@@ -434,14 +712,15 @@ func (db *DB) GetAnalyticsSummary(
 
 // ActivityEntry is one time bucket in the activity timeline.
 type ActivityEntry struct {
-	Date              string         `json:"date"`
-	Sessions          int            `json:"sessions"`
-	Messages          int            `json:"messages"`
-	UserMessages      int            `json:"user_messages"`
-	AssistantMessages int            `json:"assistant_messages"`
-	ToolCalls         int            `json:"tool_calls"`
-	ThinkingMessages  int            `json:"thinking_messages"`
-	ByAgent           map[string]int `json:"by_agent"`
+	Date              string           `json:"date"`
+	Sessions          int              `json:"sessions"`
+	Messages          int              `json:"messages"`
+	UserMessages      int              `json:"user_messages"`
+	UserOrigins       UserOriginCounts `json:"user_origins"`
+	AssistantMessages int              `json:"assistant_messages"`
+	ToolCalls         int              `json:"tool_calls"`
+	ThinkingMessages  int              `json:"thinking_messages"`
+	ByAgent           map[string]int   `json:"by_agent"`
 }
 
 // ActivityResponse wraps the activity series.
@@ -483,7 +762,7 @@ func (db *DB) GetAnalyticsActivity(
 	}
 	loc := f.location()
 	dateCol := "COALESCE(NULLIF(s.started_at, ''), s.created_at)"
-	where, args := f.buildWhere(dateCol)
+	where, args := f.buildWhereAs("s", dateCol)
 
 	var timeIDs map[string]bool
 	if f.HasTimeFilter() {
@@ -569,7 +848,8 @@ func (db *DB) GetAnalyticsActivity(
 			fmt.Errorf("iterating activity rows: %w", err)
 	}
 
-	// Merge tool_call counts per session into buckets.
+	// Merge tool_call and user origin counts per session
+	// into buckets.
 	if len(sessionIDs) > 0 {
 		err = queryChunked(sessionIDs,
 			func(chunk []string) error {
@@ -580,6 +860,15 @@ func (db *DB) GetAnalyticsActivity(
 		if err != nil {
 			return ActivityResponse{}, err
 		}
+		origins, err := db.userOriginsBySession(ctx, sessionIDs)
+		if err != nil {
+			return ActivityResponse{}, err
+		}
+		for sid, c := range origins {
+			if entry, ok := buckets[sessionSeen[sid]]; ok {
+				entry.UserOrigins.merge(c)
+			}
+		}
 	}
 
 	// Sort by date
@@ -636,11 +925,14 @@ func (db *DB) mergeActivityToolCalls(
 
 // --- Heatmap ---
 
-// HeatmapEntry is one day in the heatmap calendar.
+// HeatmapEntry is one day in the heatmap calendar. Window is
+// set when working hours are configured and splits Value by
+// whether each session started inside them.
 type HeatmapEntry struct {
-	Date  string `json:"date"`
-	Value int    `json:"value"`
-	Level int    `json:"level"`
+	Date   string       `json:"date"`
+	Value  int          `json:"value"`
+	Level  int          `json:"level"`
+	Window *WindowSplit `json:"window,omitempty"`
 }
 
 // HeatmapLevels defines the quartile thresholds for levels 1-4.
@@ -657,6 +949,7 @@ type HeatmapResponse struct {
 	Entries     []HeatmapEntry `json:"entries"`
 	Levels      HeatmapLevels  `json:"levels"`
 	EntriesFrom string         `json:"entries_from"`
+	Window      *WindowSplit   `json:"window,omitempty"`
 }
 
 // GetAnalyticsHeatmap returns daily counts with intensity levels.
@@ -693,6 +986,9 @@ func (db *DB) GetAnalyticsHeatmap(
 
 	dayCounts := make(map[string]int) // date -> count
 	daySessions := make(map[string]int)
+	windows := f.windowMatcher()
+	dayCountsIn := make(map[string]int) // in working hours
+	daySessionsIn := make(map[string]int)
 
 	for rows.Next() {
 		var id, ts string
@@ -710,6 +1006,12 @@ func (db *DB) GetAnalyticsHeatmap(
 		}
 		dayCounts[date] += mc
 		daySessions[date]++
+		if windows != nil {
+			if t, ok := localTime(ts, loc); ok && windows.contains(t) {
+				dayCountsIn[date] += mc
+				daySessionsIn[date]++
+			}
+		}
 	}
 	if err := rows.Err(); err != nil {
 		return HeatmapResponse{},
@@ -717,9 +1019,9 @@ func (db *DB) GetAnalyticsHeatmap(
 	}
 
 	// Choose which map to use based on metric
-	source := dayCounts
+	source, sourceIn := dayCounts, dayCountsIn
 	if metric == "sessions" {
-		source = daySessions
+		source, sourceIn = daySessions, daySessionsIn
 	}
 
 	// Determine effective date range (clamped to MaxHeatmapDays)
@@ -742,12 +1044,25 @@ func (db *DB) GetAnalyticsHeatmap(
 		entriesFrom, f.To, source, levels,
 	)
 
-	return HeatmapResponse{
+	resp := HeatmapResponse{
 		Metric:      metric,
 		Entries:     entries,
 		Levels:      levels,
 		EntriesFrom: entriesFrom,
-	}, nil
+	}
+	if windows != nil {
+		resp.Window = &WindowSplit{}
+		for i := range resp.Entries {
+			e := &resp.Entries[i]
+			in := sourceIn[e.Date]
+			e.Window = &WindowSplit{
+				InWindow: in, OutOfWindow: e.Value - in,
+			}
+			resp.Window.InWindow += in
+			resp.Window.OutOfWindow += e.Value - in
+		}
+	}
+	return resp, nil
 }
 
 // computeQuartileLevels computes thresholds from sorted values.
@@ -986,18 +1301,278 @@ func (db *DB) GetAnalyticsProjects(
 	return ProjectsAnalyticsResponse{Projects: projects}, nil
 }
 
+// --- Branches ---
+
+// BranchAnalytics holds effort on one branch of a project.
+// Sessions without branch metadata share the empty branch.
+type BranchAnalytics struct {
+	Project      string         `json:"project"`
+	Branch       string         `json:"branch"`
+	Worktrees    []string       `json:"worktrees"`
+	Sessions     int            `json:"sessions"`
+	Messages     int            `json:"messages"`
+	UserMessages int            `json:"user_messages"`
+	FirstSession string         `json:"first_session"`
+	LastSession  string         `json:"last_session"`
+	Agents       map[string]int `json:"agents"`
+}
+
+// BranchesAnalyticsResponse wraps the branches list.
+type BranchesAnalyticsResponse struct {
+	Branches []BranchAnalytics `json:"branches"`
+}
+
+// GetAnalyticsBranches returns per-branch analytics, the
+// drill-down below GetAnalyticsProjects. Worktree sessions are
+// already grouped under their parent project; this splits
+// them back out by the branch they worked on.
+func (db *DB) GetAnalyticsBranches(
+	ctx context.Context, f AnalyticsFilter,
+) (BranchesAnalyticsResponse, error) {
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return BranchesAnalyticsResponse{}, err
+		}
+	}
+
+	query := `SELECT id, project, git_branch, worktree, ` +
+		dateCol + `, message_count, user_message_count, agent
+		FROM sessions WHERE ` + where
+
+	rows, err := db.getReader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return BranchesAnalyticsResponse{},
+			fmt.Errorf("querying analytics branches: %w", err)
+	}
+	defer rows.Close()
+
+	type branchKey struct{ project, branch string }
+	type branchData struct {
+		BranchAnalytics
+		worktrees map[string]bool
+	}
+	branches := make(map[branchKey]*branchData)
+
+	for rows.Next() {
+		var id, project, branch, worktree, ts, agent string
+		var mc, umc int
+		if err := rows.Scan(
+			&id, &project, &branch, &worktree, &ts,
+			&mc, &umc, &agent,
+		); err != nil {
+			return BranchesAnalyticsResponse{},
+				fmt.Errorf("scanning branch row: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+
+		key := branchKey{project, branch}
+		bd, ok := branches[key]
+		if !ok {
+			bd = &branchData{
+				BranchAnalytics: BranchAnalytics{
+					Project: project,
+					Branch:  branch,
+					Agents:  make(map[string]int),
+				},
+				worktrees: make(map[string]bool),
+			}
+			branches[key] = bd
+		}
+		bd.Sessions++
+		bd.Messages += mc
+		bd.UserMessages += umc
+		bd.Agents[agent]++
+		if worktree != "" {
+			bd.worktrees[worktree] = true
+		}
+		if bd.FirstSession == "" || date < bd.FirstSession {
+			bd.FirstSession = date
+		}
+		if date > bd.LastSession {
+			bd.LastSession = date
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return BranchesAnalyticsResponse{},
+			fmt.Errorf("iterating branch rows: %w", err)
+	}
+
+	resp := BranchesAnalyticsResponse{
+		Branches: make([]BranchAnalytics, 0, len(branches)),
+	}
+	for _, bd := range branches {
+		bd.Worktrees = make([]string, 0, len(bd.worktrees))
+		for wt := range bd.worktrees {
+			bd.Worktrees = append(bd.Worktrees, wt)
+		}
+		sort.Strings(bd.Worktrees)
+		resp.Branches = append(resp.Branches, bd.BranchAnalytics)
+	}
+	sort.Slice(resp.Branches, func(i, j int) bool {
+		a, b := resp.Branches[i], resp.Branches[j]
+		if a.Messages != b.Messages {
+			return a.Messages > b.Messages
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Branch < b.Branch
+	})
+	return resp, nil
+}
+
+// --- Entry Points ---
+
+// EntryPointAnalytics holds usage from one entry point
+// (terminal, VS Code, SDK, ...). Sessions whose agent records
+// no client share the empty entry point.
+type EntryPointAnalytics struct {
+	EntryPoint   string         `json:"entry_point"`
+	Clients      []string       `json:"clients"`
+	Sessions     int            `json:"sessions"`
+	Messages     int            `json:"messages"`
+	UserMessages int            `json:"user_messages"`
+	Machines     int            `json:"machines"`
+	Agents       map[string]int `json:"agents"`
+}
+
+// EntryPointsAnalyticsResponse wraps the entry points list.
+type EntryPointsAnalyticsResponse struct {
+	EntryPoints []EntryPointAnalytics `json:"entry_points"`
+}
+
+// GetAnalyticsEntryPoints breaks sessions down by where they
+// were started from, e.g. an IDE extension or a terminal.
+func (db *DB) GetAnalyticsEntryPoints(
+	ctx context.Context, f AnalyticsFilter,
+) (EntryPointsAnalyticsResponse, error) {
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return EntryPointsAnalyticsResponse{}, err
+		}
+	}
+
+	query := `SELECT id, entry_point, client, machine, ` +
+		dateCol + `, message_count, user_message_count, agent
+		FROM sessions WHERE ` + where
+
+	rows, err := db.getReader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return EntryPointsAnalyticsResponse{},
+			fmt.Errorf("querying analytics entry points: %w", err)
+	}
+	defer rows.Close()
+
+	type entryData struct {
+		EntryPointAnalytics
+		clients  map[string]bool
+		machines map[string]bool
+	}
+	entries := make(map[string]*entryData)
+
+	for rows.Next() {
+		var id, entry, client, machine, ts, agent string
+		var mc, umc int
+		if err := rows.Scan(
+			&id, &entry, &client, &machine, &ts,
+			&mc, &umc, &agent,
+		); err != nil {
+			return EntryPointsAnalyticsResponse{},
+				fmt.Errorf("scanning entry point row: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+
+		ed, ok := entries[entry]
+		if !ok {
+			ed = &entryData{
+				EntryPointAnalytics: EntryPointAnalytics{
+					EntryPoint: entry,
+					Agents:     make(map[string]int),
+				},
+				clients:  make(map[string]bool),
+				machines: make(map[string]bool),
+			}
+			entries[entry] = ed
+		}
+		ed.Sessions++
+		ed.Messages += mc
+		ed.UserMessages += umc
+		ed.Agents[agent]++
+		ed.machines[machine] = true
+		if client != "" {
+			ed.clients[client] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return EntryPointsAnalyticsResponse{},
+			fmt.Errorf("iterating entry point rows: %w", err)
+	}
+
+	resp := EntryPointsAnalyticsResponse{
+		EntryPoints: make([]EntryPointAnalytics, 0, len(entries)),
+	}
+	for _, ed := range entries {
+		ed.Clients = make([]string, 0, len(ed.clients))
+		for c := range ed.clients {
+			ed.Clients = append(ed.Clients, c)
+		}
+		sort.Strings(ed.Clients)
+		ed.Machines = len(ed.machines)
+		resp.EntryPoints = append(resp.EntryPoints, ed.EntryPointAnalytics)
+	}
+	sort.Slice(resp.EntryPoints, func(i, j int) bool {
+		a, b := resp.EntryPoints[i], resp.EntryPoints[j]
+		if a.Sessions != b.Sessions {
+			return a.Sessions > b.Sessions
+		}
+		return a.EntryPoint < b.EntryPoint
+	})
+	return resp, nil
+}
+
 // --- Hour-of-Week ---
 
 // HourOfWeekCell is one cell in the 7x24 hour-of-week grid.
+// Window is set when working hours are configured. A cell can
+// be split when a window boundary falls inside the hour or the
+// window uses another timezone.
 type HourOfWeekCell struct {
-	DayOfWeek int `json:"day_of_week"` // 0=Mon, 6=Sun
-	Hour      int `json:"hour"`        // 0-23
-	Messages  int `json:"messages"`
+	DayOfWeek int          `json:"day_of_week"` // 0=Mon, 6=Sun
+	Hour      int          `json:"hour"`        // 0-23
+	Messages  int          `json:"messages"`
+	Window    *WindowSplit `json:"window,omitempty"`
 }
 
 // HourOfWeekResponse wraps the hour-of-week heatmap data.
 type HourOfWeekResponse struct {
-	Cells []HourOfWeekCell `json:"cells"`
+	Cells  []HourOfWeekCell `json:"cells"`
+	Window *WindowSplit     `json:"window,omitempty"`
 }
 
 // GetAnalyticsHourOfWeek returns message counts bucketed by
@@ -1007,7 +1582,7 @@ func (db *DB) GetAnalyticsHourOfWeek(
 ) (HourOfWeekResponse, error) {
 	loc := f.location()
 	dateCol := "COALESCE(NULLIF(s.started_at, ''), s.created_at)"
-	where, args := f.buildWhere(dateCol)
+	where, args := f.buildWhereAs("s", dateCol)
 
 	query := `SELECT ` + dateCol + `, m.timestamp
 		FROM sessions s
@@ -1022,6 +1597,8 @@ func (db *DB) GetAnalyticsHourOfWeek(
 	defer rows.Close()
 
 	var grid [7][24]int
+	var inGrid [7][24]int
+	windows := f.windowMatcher()
 
 	for rows.Next() {
 		var sessTS, msgTS string
@@ -1040,24 +1617,41 @@ func (db *DB) GetAnalyticsHourOfWeek(
 		// Go Sunday=0, convert to ISO Monday=0
 		dow := (int(t.Weekday()) + 6) % 7
 		grid[dow][t.Hour()]++
+		if windows != nil && windows.contains(t) {
+			inGrid[dow][t.Hour()]++
+		}
 	}
 	if err := rows.Err(); err != nil {
 		return HourOfWeekResponse{},
 			fmt.Errorf("iterating hour-of-week rows: %w", err)
 	}
 
-	cells := make([]HourOfWeekCell, 0, 168)
+	resp := HourOfWeekResponse{
+		Cells: make([]HourOfWeekCell, 0, 168),
+	}
+	if windows != nil {
+		resp.Window = &WindowSplit{}
+	}
 	for d := range 7 {
 		for h := range 24 {
-			cells = append(cells, HourOfWeekCell{
+			cell := HourOfWeekCell{
 				DayOfWeek: d,
 				Hour:      h,
 				Messages:  grid[d][h],
-			})
+			}
+			if windows != nil {
+				in, out := inGrid[d][h], grid[d][h]-inGrid[d][h]
+				cell.Window = &WindowSplit{
+					InWindow: in, OutOfWindow: out,
+				}
+				resp.Window.InWindow += in
+				resp.Window.OutOfWindow += out
+			}
+			resp.Cells = append(resp.Cells, cell)
 		}
 	}
 
-	return HourOfWeekResponse{Cells: cells}, nil
+	return resp, nil
 }
 
 // --- Session Shape ---
@@ -1247,16 +1841,24 @@ func (db *DB) GetAnalyticsSessionShape(
 
 	// Query autonomy data for filtered sessions
 	autonomyCounts := make(map[string]int)
-	if len(sessionIDs) > 0 {
-		err := queryChunked(sessionIDs,
-			func(chunk []string) error {
-				return db.queryAutonomyChunk(
-					ctx, chunk, autonomyCounts,
-				)
-			})
-		if err != nil {
-			return SessionShapeResponse{}, err
-		}
+	var mu sync.Mutex
+	err = queryChunkedParallel(ctx, sessionIDs,
+		func(ctx context.Context, chunk []string) error {
+			counts := make(map[string]int)
+			if err := db.queryAutonomyChunk(
+				ctx, chunk, counts,
+			); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for k, n := range counts {
+				autonomyCounts[k] += n
+			}
+			return nil
+		})
+	if err != nil {
+		return SessionShapeResponse{}, err
 	}
 
 	return SessionShapeResponse{
@@ -1310,30 +1912,35 @@ func (db *DB) queryAutonomyChunk(
 // ToolCategoryCount holds a count and percentage for one tool
 // category.
 type ToolCategoryCount struct {
-	Category string  `json:"category"`
-	Count    int     `json:"count"`
-	Pct      float64 `json:"pct"`
+	Category   string                `json:"category"`
+	Count      int                   `json:"count"`
+	Pct        float64               `json:"pct"`
+	SessionIDs *ContributingSessions `json:"session_ids,omitempty"`
 }
 
 // ToolAgentBreakdown holds tool usage breakdown for one agent.
 type ToolAgentBreakdown struct {
-	Agent      string              `json:"agent"`
-	Total      int                 `json:"total"`
-	Categories []ToolCategoryCount `json:"categories"`
+	Agent      string                `json:"agent"`
+	Total      int                   `json:"total"`
+	Categories []ToolCategoryCount   `json:"categories"`
+	SessionIDs *ContributingSessions `json:"session_ids,omitempty"`
 }
 
 // ToolTrendEntry holds tool call counts for one time bucket.
 type ToolTrendEntry struct {
 	Date  string         `json:"date"`
 	ByCat map[string]int `json:"by_category"`
+	// SessionIDs is keyed by category, like ByCat.
+	SessionIDs map[string]*ContributingSessions `json:"session_ids,omitempty"`
 }
 
 // ToolsAnalyticsResponse wraps tool usage analytics.
 type ToolsAnalyticsResponse struct {
-	TotalCalls int                  `json:"total_calls"`
-	ByCategory []ToolCategoryCount  `json:"by_category"`
-	ByAgent    []ToolAgentBreakdown `json:"by_agent"`
-	Trend      []ToolTrendEntry     `json:"trend"`
+	TotalCalls int                   `json:"total_calls"`
+	ByCategory []ToolCategoryCount   `json:"by_category"`
+	ByAgent    []ToolAgentBreakdown  `json:"by_agent"`
+	Trend      []ToolTrendEntry      `json:"trend"`
+	SessionIDs *ContributingSessions `json:"session_ids,omitempty"`
 }
 
 // GetAnalyticsTools returns tool usage analytics aggregated
@@ -1397,6 +2004,7 @@ func (db *DB) GetAnalyticsTools(
 		ByCategory: []ToolCategoryCount{},
 		ByAgent:    []ToolAgentBreakdown{},
 		Trend:      []ToolTrendEntry{},
+		SessionIDs: f.contributors(),
 	}
 
 	if len(sessionIDs) == 0 {
@@ -1407,15 +2015,17 @@ func (db *DB) GetAnalyticsTools(
 	type toolRow struct {
 		sessionID string
 		category  string
+		calls     int
 	}
 	var toolRows []toolRow
 
 	err = queryChunked(sessionIDs,
 		func(chunk []string) error {
 			ph, chunkArgs := inPlaceholders(chunk)
-			q := `SELECT session_id, category
+			q := `SELECT session_id, category, COUNT(*)
 				FROM tool_calls
-				WHERE session_id IN ` + ph
+				WHERE session_id IN ` + ph + `
+				GROUP BY session_id, category`
 			rows, qErr := db.getReader().QueryContext(
 				ctx, q, chunkArgs...,
 			)
@@ -1427,13 +2037,14 @@ func (db *DB) GetAnalyticsTools(
 			defer rows.Close()
 			for rows.Next() {
 				var sid, cat string
-				if err := rows.Scan(&sid, &cat); err != nil {
+				var calls int
+				if err := rows.Scan(&sid, &cat, &calls); err != nil {
 					return fmt.Errorf(
 						"scanning tool_call: %w", err,
 					)
 				}
 				toolRows = append(toolRows, toolRow{
-					sessionID: sid, category: cat,
+					sessionID: sid, category: cat, calls: calls,
 				})
 			}
 			return rows.Err()
@@ -1451,23 +2062,54 @@ func (db *DB) GetAnalyticsTools(
 	agentCats := make(map[string]map[string]int)    // agent → cat → count
 	trendBuckets := make(map[string]map[string]int) // week → cat → count
 
+	// Contributing sessions, keyed like the counts above; only
+	// populated when the filter asks for them.
+	catIDs := make(map[string]*ContributingSessions)
+	agentIDs := make(map[string]*ContributingSessions)
+	agentCatIDs := make(map[string]map[string]*ContributingSessions)
+	trendIDs := make(map[string]map[string]*ContributingSessions)
+	addID := func(
+		m map[string]*ContributingSessions, key, id string,
+	) {
+		if m[key] == nil {
+			m[key] = f.contributors()
+		}
+		m[key].add(id)
+	}
+
 	for _, tr := range toolRows {
 		info := sessionMap[tr.sessionID]
-		catCounts[tr.category]++
+		catCounts[tr.category] += tr.calls
+		resp.TotalCalls += tr.calls
 
 		if agentCats[info.agent] == nil {
 			agentCats[info.agent] = make(map[string]int)
 		}
-		agentCats[info.agent][tr.category]++
+		agentCats[info.agent][tr.category] += tr.calls
 
 		week := bucketDate(info.date, "week")
 		if trendBuckets[week] == nil {
 			trendBuckets[week] = make(map[string]int)
 		}
-		trendBuckets[week][tr.category]++
-	}
+		trendBuckets[week][tr.category] += tr.calls
 
-	resp.TotalCalls = len(toolRows)
+		if f.IncludeSessionIDs {
+			sid := tr.sessionID
+			resp.SessionIDs.add(sid)
+			addID(catIDs, tr.category, sid)
+			addID(agentIDs, info.agent, sid)
+			if agentCatIDs[info.agent] == nil {
+				agentCatIDs[info.agent] =
+					make(map[string]*ContributingSessions)
+			}
+			addID(agentCatIDs[info.agent], tr.category, sid)
+			if trendIDs[week] == nil {
+				trendIDs[week] =
+					make(map[string]*ContributingSessions)
+			}
+			addID(trendIDs[week], tr.category, sid)
+		}
+	}
 
 	// Build ByCategory sorted by count desc.
 	resp.ByCategory = make(
@@ -1480,6 +2122,7 @@ func (db *DB) GetAnalyticsTools(
 		resp.ByCategory = append(resp.ByCategory,
 			ToolCategoryCount{
 				Category: cat, Count: count, Pct: pct,
+				SessionIDs: catIDs[cat],
 			})
 	}
 	sort.Slice(resp.ByCategory, func(i, j int) bool {
@@ -1513,6 +2156,7 @@ func (db *DB) GetAnalyticsTools(
 			) / 10
 			catList = append(catList, ToolCategoryCount{
 				Category: cat, Count: count, Pct: pct,
+				SessionIDs: agentCatIDs[agent][cat],
 			})
 		}
 		sort.Slice(catList, func(i, j int) bool {
@@ -1526,6 +2170,7 @@ func (db *DB) GetAnalyticsTools(
 				Agent:      agent,
 				Total:      total,
 				Categories: catList,
+				SessionIDs: agentIDs[agent],
 			})
 	}
 
@@ -1535,7 +2180,7 @@ func (db *DB) GetAnalyticsTools(
 	)
 	for week, cats := range trendBuckets {
 		resp.Trend = append(resp.Trend, ToolTrendEntry{
-			Date: week, ByCat: cats,
+			Date: week, ByCat: cats, SessionIDs: trendIDs[week],
 		})
 	}
 	sort.Slice(resp.Trend, func(i, j int) bool {
@@ -1554,6 +2199,65 @@ type velocityMsg struct {
 	ts            time.Time
 	valid         bool
 	contentLength int
+	model         string
+}
+
+// maxTurnCycleSec caps user→assistant gaps counted as turn
+// cycles; longer gaps are treated as the user stepping away.
+const maxTurnCycleSec = 1800.0
+
+// eachTurnCycle calls fn with the delay in seconds and the
+// replying message for every user→assistant transition within
+// maxTurnCycleSec.
+func eachTurnCycle(
+	msgs []velocityMsg, fn func(sec float64, reply velocityMsg),
+) {
+	for i := 1; i < len(msgs); i++ {
+		prev := msgs[i-1]
+		cur := msgs[i]
+		if !prev.valid || !cur.valid {
+			continue
+		}
+		if prev.role == "user" && cur.role == "assistant" {
+			delta := cur.ts.Sub(prev.ts).Seconds()
+			if delta > 0 && delta <= maxTurnCycleSec {
+				fn(delta, cur)
+			}
+		}
+	}
+}
+
+// firstResponse returns the delay in seconds between the first
+// user message and the first assistant message after it, along
+// with that reply. Messages are scanned by ordinal
+// (conversation order), not timestamp.
+func firstResponse(
+	msgs []velocityMsg,
+) (sec float64, reply velocityMsg, ok bool) {
+	firstUserIdx := -1
+	for i := range msgs {
+		if msgs[i].role == "user" && msgs[i].valid {
+			firstUserIdx = i
+			break
+		}
+	}
+	if firstUserIdx < 0 {
+		return 0, velocityMsg{}, false
+	}
+	for i := firstUserIdx + 1; i < len(msgs); i++ {
+		if msgs[i].role != "assistant" || !msgs[i].valid {
+			continue
+		}
+		delta := msgs[i].ts.Sub(msgs[firstUserIdx].ts).Seconds()
+		// Clamp negative deltas to 0: ordinal order is
+		// authoritative, so a negative delta means clock
+		// skew, not a missing response.
+		if delta < 0 {
+			delta = 0
+		}
+		return delta, msgs[i], true
+	}
+	return 0, velocityMsg{}, false
 }
 
 // queryVelocityMsgs fetches messages for a chunk of session IDs
@@ -1566,7 +2270,7 @@ func (db *DB) queryVelocityMsgs(
 ) error {
 	ph, args := inPlaceholders(chunk)
 	q := `SELECT session_id, ordinal, role,
-		timestamp, content_length
+		timestamp, content_length, model
 		FROM messages
 		WHERE session_id IN ` + ph + `
 		ORDER BY session_id, ordinal`
@@ -1582,10 +2286,10 @@ func (db *DB) queryVelocityMsgs(
 	for rows.Next() {
 		var sid string
 		var ordinal int
-		var role, ts string
+		var role, ts, model string
 		var cl int
 		if err := rows.Scan(
-			&sid, &ordinal, &role, &ts, &cl,
+			&sid, &ordinal, &role, &ts, &cl, &model,
 		); err != nil {
 			return fmt.Errorf(
 				"scanning velocity msg: %w", err,
@@ -1595,7 +2299,7 @@ func (db *DB) queryVelocityMsgs(
 		sessionMsgs[sid] = append(sessionMsgs[sid],
 			velocityMsg{
 				role: role, ts: t, valid: ok,
-				contentLength: cl,
+				contentLength: cl, model: model,
 			})
 	}
 	return rows.Err()
@@ -1618,9 +2322,10 @@ type VelocityOverview struct {
 
 // VelocityBreakdown is velocity metrics for a subgroup.
 type VelocityBreakdown struct {
-	Label    string           `json:"label"`
-	Sessions int              `json:"sessions"`
-	Overview VelocityOverview `json:"overview"`
+	Label      string                `json:"label"`
+	Sessions   int                   `json:"sessions"`
+	Overview   VelocityOverview      `json:"overview"`
+	SessionIDs *ContributingSessions `json:"session_ids,omitempty"`
 }
 
 // VelocityResponse wraps overall and grouped velocity metrics.
@@ -1628,6 +2333,8 @@ type VelocityResponse struct {
 	Overall      VelocityOverview    `json:"overall"`
 	ByAgent      []VelocityBreakdown `json:"by_agent"`
 	ByComplexity []VelocityBreakdown `json:"by_complexity"`
+	// SessionIDs lists the sessions behind Overall.
+	SessionIDs *ContributingSessions `json:"session_ids,omitempty"`
 }
 
 // complexityBucket returns the complexity label based on
@@ -1652,6 +2359,7 @@ type velocityAccumulator struct {
 	totalToolCalls int
 	activeMinutes  float64
 	sessions       int
+	ids            *ContributingSessions
 }
 
 func (a *velocityAccumulator) computeOverview() VelocityOverview {
@@ -1748,16 +2456,27 @@ func (db *DB) GetAnalyticsVelocity(
 		return VelocityResponse{
 			ByAgent:      []VelocityBreakdown{},
 			ByComplexity: []VelocityBreakdown{},
+			SessionIDs:   f.contributors(),
 		}, nil
 	}
 
-	// Phase 2: Fetch messages for filtered sessions (chunked)
+	// Phase 2: Fetch messages for filtered sessions (chunked).
+	// Chunks hold disjoint sessions, so each fills its own map
+	// and is merged under mu.
+	var mu sync.Mutex
 	sessionMsgs := make(map[string][]velocityMsg)
-	err = queryChunked(sessionIDs,
-		func(chunk []string) error {
-			return db.queryVelocityMsgs(
-				ctx, chunk, loc, sessionMsgs,
-			)
+	err = queryChunkedParallel(ctx, sessionIDs,
+		func(ctx context.Context, chunk []string) error {
+			msgs := make(map[string][]velocityMsg)
+			if err := db.queryVelocityMsgs(
+				ctx, chunk, loc, msgs,
+			); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			maps.Copy(sessionMsgs, msgs)
+			return nil
 		})
 	if err != nil {
 		return VelocityResponse{}, err
@@ -1765,8 +2484,8 @@ func (db *DB) GetAnalyticsVelocity(
 
 	// Phase 2b: Fetch tool call counts per session (chunked)
 	toolCountMap := make(map[string]int)
-	err = queryChunked(sessionIDs,
-		func(chunk []string) error {
+	err = queryChunkedParallel(ctx, sessionIDs,
+		func(ctx context.Context, chunk []string) error {
 			ph, chunkArgs := inPlaceholders(chunk)
 			q := `SELECT session_id, COUNT(*)
 				FROM tool_calls
@@ -1782,6 +2501,7 @@ func (db *DB) GetAnalyticsVelocity(
 				)
 			}
 			defer rows.Close()
+			counts := make(map[string]int)
 			for rows.Next() {
 				var sid string
 				var count int
@@ -1791,22 +2511,25 @@ func (db *DB) GetAnalyticsVelocity(
 						err,
 					)
 				}
-				toolCountMap[sid] = count
+				counts[sid] = count
 			}
-			return rows.Err()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			maps.Copy(toolCountMap, counts)
+			return nil
 		})
 	if err != nil {
 		return VelocityResponse{}, err
 	}
 
 	// Process per-session metrics
-	overall := &velocityAccumulator{}
+	overall := &velocityAccumulator{ids: f.contributors()}
 	byAgent := make(map[string]*velocityAccumulator)
 	byComplexity := make(map[string]*velocityAccumulator)
 
-	const maxCycleSec = 1800.0
-	const maxGapSec = 300.0
-
 	for _, sid := range sessionIDs {
 		info := sessionMap[sid]
 		msgs := sessionMsgs[sid]
@@ -1818,10 +2541,14 @@ func (db *DB) GetAnalyticsVelocity(
 		compKey := complexityBucket(info.mc)
 
 		if byAgent[agentKey] == nil {
-			byAgent[agentKey] = &velocityAccumulator{}
+			byAgent[agentKey] = &velocityAccumulator{
+				ids: f.contributors(),
+			}
 		}
 		if byComplexity[compKey] == nil {
-			byComplexity[compKey] = &velocityAccumulator{}
+			byComplexity[compKey] = &velocityAccumulator{
+				ids: f.contributors(),
+			}
 		}
 
 		accums := []*velocityAccumulator{
@@ -1830,55 +2557,18 @@ func (db *DB) GetAnalyticsVelocity(
 
 		for _, a := range accums {
 			a.sessions++
+			a.ids.add(sid)
 		}
 
 		// Turn cycles: user→assistant transitions
-		for i := 1; i < len(msgs); i++ {
-			prev := msgs[i-1]
-			cur := msgs[i]
-			if !prev.valid || !cur.valid {
-				continue
-			}
-			if prev.role == "user" && cur.role == "assistant" {
-				delta := cur.ts.Sub(prev.ts).Seconds()
-				if delta > 0 && delta <= maxCycleSec {
-					for _, a := range accums {
-						a.turnCycles = append(
-							a.turnCycles, delta,
-						)
-					}
-				}
+		eachTurnCycle(msgs, func(delta float64, _ velocityMsg) {
+			for _, a := range accums {
+				a.turnCycles = append(a.turnCycles, delta)
 			}
-		}
+		})
 
 		// First response: first user → first assistant after it
-		// Scan by ordinal (conversation order), not timestamp.
-		var firstUser, firstAsst *velocityMsg
-		firstUserIdx := -1
-		for i := range msgs {
-			if msgs[i].role == "user" && msgs[i].valid {
-				firstUser = &msgs[i]
-				firstUserIdx = i
-				break
-			}
-		}
-		if firstUserIdx >= 0 {
-			for i := firstUserIdx + 1; i < len(msgs); i++ {
-				if msgs[i].role == "assistant" &&
-					msgs[i].valid {
-					firstAsst = &msgs[i]
-					break
-				}
-			}
-		}
-		if firstUser != nil && firstAsst != nil {
-			delta := firstAsst.ts.Sub(firstUser.ts).Seconds()
-			// Clamp negative deltas to 0: ordinal order is
-			// authoritative, so a negative delta means clock
-			// skew, not a missing response.
-			if delta < 0 {
-				delta = 0
-			}
+		if delta, _, ok := firstResponse(msgs); ok {
 			for _, a := range accums {
 				a.firstResponses = append(
 					a.firstResponses, delta,
@@ -1896,8 +2586,8 @@ func (db *DB) GetAnalyticsVelocity(
 			if i > 0 && msgs[i-1].valid && m.valid {
 				gap := m.ts.Sub(msgs[i-1].ts).Seconds()
 				if gap > 0 {
-					if gap > maxGapSec {
-						gap = maxGapSec
+					if gap > maxActiveGapSec {
+						gap = maxActiveGapSec
 					}
 					activeSec += gap
 				}
@@ -1916,7 +2606,8 @@ func (db *DB) GetAnalyticsVelocity(
 	}
 
 	resp := VelocityResponse{
-		Overall: overall.computeOverview(),
+		Overall:    overall.computeOverview(),
+		SessionIDs: overall.ids,
 	}
 
 	// Build by-agent breakdowns
@@ -1929,9 +2620,10 @@ func (db *DB) GetAnalyticsVelocity(
 	for _, k := range agentKeys {
 		a := byAgent[k]
 		resp.ByAgent = append(resp.ByAgent, VelocityBreakdown{
-			Label:    k,
-			Sessions: a.sessions,
-			Overview: a.computeOverview(),
+			Label:      k,
+			Sessions:   a.sessions,
+			Overview:   a.computeOverview(),
+			SessionIDs: a.ids,
 		})
 	}
 
@@ -1953,9 +2645,10 @@ func (db *DB) GetAnalyticsVelocity(
 		a := byComplexity[k]
 		resp.ByComplexity = append(resp.ByComplexity,
 			VelocityBreakdown{
-				Label:    k,
-				Sessions: a.sessions,
-				Overview: a.computeOverview(),
+				Label:      k,
+				Sessions:   a.sessions,
+				Overview:   a.computeOverview(),
+				SessionIDs: a.ids,
 			})
 	}
 
@@ -2,9 +2,15 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/wesm/agentsview/internal/slo"
 )
 
 type seedStats struct {
@@ -863,6 +869,121 @@ func TestGetAnalyticsHourOfWeek(t *testing.T) {
 	})
 }
 
+func TestAnalyticsWorkingHoursSplit(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	// 2024-06-01 is a Saturday (ISO day 5).
+	insertSession(t, d, "wh1", "proj", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+		s.MessageCount = 2
+	})
+	insertMessages(t, d,
+		Message{
+			SessionID: "wh1", Ordinal: 0, Role: "user",
+			Content: "hi", Timestamp: "2024-06-01T09:00:00Z",
+		},
+		Message{
+			SessionID: "wh1", Ordinal: 1, Role: "assistant",
+			Content: "hello", Timestamp: "2024-06-01T09:30:00Z",
+		},
+	)
+	insertSession(t, d, "wh2", "proj", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-01T23:00:00Z")
+		s.MessageCount = 1
+	})
+	insertMessages(t, d, Message{
+		SessionID: "wh2", Ordinal: 0, Role: "user",
+		Content: "late", Timestamp: "2024-06-01T23:00:00Z",
+	})
+
+	f := baseFilter()
+	f.WorkingHours = []WorkingWindow{{
+		Days: []int{5}, StartMinute: 9 * 60, EndMinute: 9*60 + 20,
+	}}
+
+	how, err := d.GetAnalyticsHourOfWeek(ctx, f)
+	requireNoError(t, err, "GetAnalyticsHourOfWeek")
+	if how.Window == nil ||
+		*how.Window != (WindowSplit{InWindow: 1, OutOfWindow: 2}) {
+		t.Errorf("hour-of-week window = %+v", how.Window)
+	}
+	for _, c := range how.Cells {
+		if c.DayOfWeek == 5 && c.Hour == 9 &&
+			*c.Window != (WindowSplit{InWindow: 1, OutOfWindow: 1}) {
+			t.Errorf("Sat 09:xx window = %+v", c.Window)
+		}
+	}
+
+	hm, err := d.GetAnalyticsHeatmap(ctx, f, "sessions")
+	requireNoError(t, err, "GetAnalyticsHeatmap")
+	if hm.Window == nil ||
+		*hm.Window != (WindowSplit{InWindow: 1, OutOfWindow: 1}) {
+		t.Errorf("heatmap window = %+v", hm.Window)
+	}
+
+	// Without working hours the split is omitted.
+	hm, err = d.GetAnalyticsHeatmap(ctx, baseFilter(), "sessions")
+	requireNoError(t, err, "GetAnalyticsHeatmap")
+	if hm.Window != nil || hm.Entries[0].Window != nil {
+		t.Error("window split set without working hours")
+	}
+}
+
+func TestWindowMatcherContains(t *testing.T) {
+	weekdays := []int{0, 1, 2, 3, 4}
+	tests := []struct {
+		name   string
+		window WorkingWindow
+		ts     string
+		want   bool
+	}{
+		{"WeekdayInside",
+			WorkingWindow{Days: weekdays, StartMinute: 540, EndMinute: 1020},
+			"2024-06-03T10:00:00Z", true},
+		{"EndExclusive",
+			WorkingWindow{Days: weekdays, StartMinute: 540, EndMinute: 1020},
+			"2024-06-03T17:00:00Z", false},
+		{"Weekend",
+			WorkingWindow{Days: weekdays, StartMinute: 540, EndMinute: 1020},
+			"2024-06-01T10:00:00Z", false},
+		{"WindowTimezone",
+			WorkingWindow{
+				Days: weekdays, StartMinute: 540, EndMinute: 1020,
+				Timezone: "America/New_York",
+			},
+			"2024-06-03T14:00:00Z", true}, // 10:00 EDT
+		{"WindowTimezoneEarly",
+			WorkingWindow{
+				Days: weekdays, StartMinute: 540, EndMinute: 1020,
+				Timezone: "America/New_York",
+			},
+			"2024-06-03T10:00:00Z", false}, // 06:00 EDT
+		{"OvernightAfterMidnight",
+			WorkingWindow{Days: []int{4}, StartMinute: 1320, EndMinute: 120},
+			"2024-06-08T01:00:00Z", true}, // Sat 01:00, Fri window
+		{"OvernightWrongDay",
+			WorkingWindow{Days: []int{4}, StartMinute: 1320, EndMinute: 120},
+			"2024-06-07T01:00:00Z", false}, // Fri 01:00
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := AnalyticsFilter{
+				Timezone:     "UTC",
+				WorkingHours: []WorkingWindow{tt.window},
+			}
+			ts, ok := localTime(tt.ts, time.UTC)
+			if !ok {
+				t.Fatalf("bad timestamp %s", tt.ts)
+			}
+			if got := f.windowMatcher().contains(ts); got != tt.want {
+				t.Errorf("contains(%s) = %v, want %v",
+					tt.ts, got, tt.want)
+			}
+		})
+	}
+}
+
 func findHOWCell(cells []HourOfWeekCell, dow, hour int) int {
 	for _, c := range cells {
 		if c.DayOfWeek == dow && c.Hour == hour {
@@ -1315,6 +1436,147 @@ func TestGetAnalyticsVelocity_ToolUsage(t *testing.T) {
 	})
 }
 
+func TestGetAnalyticsSLO(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	// s1: first response 10s, turn cycles 10s and 40s.
+	insertConversation(t, d, "s1", "proj", "claude", "2024-06-01T09:00:00Z", []time.Duration{
+		0, 10 * time.Second, 50 * time.Second, 40 * time.Second,
+	})
+	// s2: first response 50s, turn cycle 50s.
+	insertConversation(t, d, "s2", "proj", "codex", "2024-06-02T09:00:00Z", []time.Duration{
+		0, 50 * time.Second,
+	})
+	for sid, model := range map[string]string{"s1": "model-a", "s2": "model-b"} {
+		if _, err := d.getWriter().Exec(
+			"UPDATE messages SET model = ? WHERE session_id = ? AND role = 'assistant'",
+			model, sid,
+		); err != nil {
+			t.Fatalf("setting model: %v", err)
+		}
+	}
+
+	targets := []SLOTarget{
+		{Name: "fr", Metric: slo.MetricFirstResponse, Percentile: 90, ThresholdSec: 30},
+		{Name: "tc", Metric: slo.MetricTurnCycle, Percentile: 50, ThresholdSec: 45},
+	}
+
+	t.Run("NoTargets", func(t *testing.T) {
+		resp, err := d.GetAnalyticsSLO(ctx, baseFilter(), nil, "", "")
+		requireNoError(t, err, "GetAnalyticsSLO")
+		assertEq(t, "len(SLOs)", len(resp.SLOs), 0)
+		assertEq(t, "Granularity", resp.Granularity, "day")
+		assertEq(t, "GroupBy", resp.GroupBy, SLOGroupByAgent)
+	})
+
+	t.Run("ByAgent", func(t *testing.T) {
+		resp, err := d.GetAnalyticsSLO(ctx, baseFilter(), targets, "day", SLOGroupByAgent)
+		requireNoError(t, err, "GetAnalyticsSLO")
+		if len(resp.SLOs) != 2 {
+			t.Fatalf("len(SLOs) = %d, want 2", len(resp.SLOs))
+		}
+
+		fr := resp.SLOs[0]
+		assertEq(t, "fr.Overall.Samples", fr.Overall.Samples, 2)
+		assertEq(t, "fr.Overall.ValueSec", fr.Overall.ValueSec, 50.0)
+		assertEq(t, "fr.Overall.WithinPct", fr.Overall.WithinPct, 50.0)
+		assertEq(t, "fr.Overall.Met", fr.Overall.Met, false)
+		assertEq(t, "fr.Attainment", fr.Attainment, 50.0)
+		if len(fr.Series) != 2 {
+			t.Fatalf("len(fr.Series) = %d, want 2", len(fr.Series))
+		}
+		assertEq(t, "fr.Series[0].Date", fr.Series[0].Date, "2024-06-01")
+		assertEq(t, "fr.Series[0].Met", fr.Series[0].Met, true)
+		assertEq(t, "fr.Series[1].Met", fr.Series[1].Met, false)
+		if len(fr.Groups) != 2 {
+			t.Fatalf("len(fr.Groups) = %d, want 2", len(fr.Groups))
+		}
+		assertEq(t, "fr.Groups[0].Label", fr.Groups[0].Label, "claude")
+		assertEq(t, "fr.Groups[0].Overall.Met", fr.Groups[0].Overall.Met, true)
+		assertEq(t, "fr.Groups[1].Label", fr.Groups[1].Label, "codex")
+
+		tc := resp.SLOs[1]
+		assertEq(t, "tc.Overall.Samples", tc.Overall.Samples, 3)
+		assertEq(t, "tc.Overall.ValueSec", tc.Overall.ValueSec, 40.0)
+		assertEq(t, "tc.Overall.WithinPct", tc.Overall.WithinPct, 66.7)
+		assertEq(t, "tc.Overall.Met", tc.Overall.Met, true)
+	})
+
+	t.Run("ByModelWeekly", func(t *testing.T) {
+		resp, err := d.GetAnalyticsSLO(ctx, baseFilter(), targets[:1], "week", SLOGroupByModel)
+		requireNoError(t, err, "GetAnalyticsSLO")
+		fr := resp.SLOs[0]
+		assertEq(t, "len(Series)", len(fr.Series), 1)
+		assertEq(t, "Series[0].Date", fr.Series[0].Date, "2024-05-27")
+		assertEq(t, "Groups[0].Label", fr.Groups[0].Label, "model-a")
+		assertEq(t, "Groups[1].Label", fr.Groups[1].Label, "model-b")
+	})
+}
+
+func TestGetAnalyticsCache(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	insertSession(t, d, "c1", "alpha", func(s *Session) {
+		s.Agent = "claude"
+		s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+		s.MessageCount = 3
+	})
+	insertSession(t, d, "c2", "beta", func(s *Session) {
+		s.Agent = "codex"
+		s.StartedAt = Ptr("2024-06-02T09:00:00Z")
+		s.MessageCount = 2
+	})
+	withUsage := func(
+		m Message, model string, input, read, creation int,
+	) Message {
+		m.Model = model
+		m.InputTokens = input
+		m.CacheReadTokens = read
+		m.CacheCreationTokens = creation
+		return m
+	}
+	insertMessages(t, d,
+		userMsg("c1", 0, "hi"),
+		withUsage(asstMsg("c1", 1, "a"), "sonnet", 100, 0, 900),
+		withUsage(asstMsg("c1", 2, "b"), "sonnet", 100, 900, 0),
+		userMsg("c2", 0, "hi"),
+		withUsage(asstMsg("c2", 1, "c"), "gpt", 500, 500, 0),
+	)
+
+	resp, err := d.GetAnalyticsCache(ctx, baseFilter(), "")
+	requireNoError(t, err, "GetAnalyticsCache")
+
+	assertEq(t, "Granularity", resp.Granularity, "day")
+	assertEq(t, "Overall.TotalInputTokens", resp.Overall.TotalInputTokens, 3000)
+	assertEq(t, "Overall.CacheReadTokens", resp.Overall.CacheReadTokens, 1400)
+	assertEq(t, "Overall.HitRatio", resp.Overall.HitRatio, 0.4667)
+
+	if len(resp.Trend) != 2 {
+		t.Fatalf("len(Trend) = %d, want 2", len(resp.Trend))
+	}
+	assertEq(t, "Trend[0].Date", resp.Trend[0].Date, "2024-06-01")
+	assertEq(t, "Trend[0].HitRatio", resp.Trend[0].HitRatio, 0.45)
+	assertEq(t, "Trend[1].HitRatio", resp.Trend[1].HitRatio, 0.5)
+
+	if len(resp.ByModel) != 2 {
+		t.Fatalf("len(ByModel) = %d, want 2", len(resp.ByModel))
+	}
+	assertEq(t, "ByModel[0].Label", resp.ByModel[0].Label, "sonnet")
+	assertEq(t, "ByAgent[1].Label", resp.ByAgent[1].Label, "codex")
+	assertEq(t, "ByProject[1].Label", resp.ByProject[1].Label, "beta")
+
+	t.Run("Empty", func(t *testing.T) {
+		f := baseFilter()
+		f.Project = "nope"
+		resp, err := d.GetAnalyticsCache(ctx, f, "week")
+		requireNoError(t, err, "GetAnalyticsCache")
+		assertEq(t, "len(Trend)", len(resp.Trend), 0)
+		assertEq(t, "HitRatio", resp.Overall.HitRatio, 0.0)
+	})
+}
+
 func TestVelocityChunkedQuery(t *testing.T) {
 	d := testDB(t)
 	ctx := context.Background()
@@ -1365,6 +1627,57 @@ func TestVelocityChunkedQuery(t *testing.T) {
 	if shape.Count != n {
 		t.Errorf("Count = %d, want %d", shape.Count, n)
 	}
+	autonomy := 0
+	for _, b := range shape.AutonomyDistribution {
+		autonomy += b.Count
+	}
+	if autonomy != n {
+		t.Errorf("autonomy sessions = %d, want %d", autonomy, n)
+	}
+}
+
+func TestQueryChunkedParallel(t *testing.T) {
+	ctx := context.Background()
+	ids := make([]string, 3*maxSQLVars+7)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("id-%d", i)
+	}
+
+	var mu sync.Mutex
+	seen := map[string]int{}
+	err := queryChunkedParallel(ctx, ids,
+		func(_ context.Context, chunk []string) error {
+			if len(chunk) > maxSQLVars {
+				t.Errorf("chunk of %d ids", len(chunk))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range chunk {
+				seen[id]++
+			}
+			return nil
+		})
+	requireNoError(t, err, "queryChunkedParallel")
+	if len(seen) != len(ids) {
+		t.Errorf("visited %d ids, want %d", len(seen), len(ids))
+	}
+	for id, c := range seen {
+		if c != 1 {
+			t.Errorf("%s visited %d times", id, c)
+		}
+	}
+
+	boom := errors.New("boom")
+	err = queryChunkedParallel(ctx, ids,
+		func(_ context.Context, chunk []string) error {
+			if chunk[0] == ids[maxSQLVars] {
+				return boom
+			}
+			return nil
+		})
+	if !errors.Is(err, boom) {
+		t.Errorf("err = %v, want boom", err)
+	}
 }
 
 func TestPercentileFloat(t *testing.T) {
@@ -1971,3 +2284,439 @@ func TestLocalTime(t *testing.T) {
 		})
 	}
 }
+
+func TestFitDaily(t *testing.T) {
+	// Linear trend: 10, 12, 14, ... predicts the continuation
+	// exactly, with no band.
+	trend := make([]float64, 21)
+	for i := range trend {
+		trend[i] = 10 + 2*float64(i)
+	}
+	got := fitDaily(trend, time.Monday).predict(2)
+	// 10+2*21 + 10+2*22
+	assertEq(t, "trend Predicted", math.Round(got.Predicted*1e6)/1e6, 106.0)
+	assertEq(t, "trend High", math.Round(got.High*1e6)/1e6, 106.0)
+
+	// Weekday seasonality: busy weekdays, idle weekends.
+	weekly := make([]float64, 28)
+	for i := range weekly {
+		if i%7 < 5 {
+			weekly[i] = 10
+		}
+	}
+	got = fitDaily(weekly, time.Monday).predict(7)
+	if math.Abs(got.Predicted-50) > 0.5 {
+		t.Errorf("weekly Predicted = %v, want ~50", got.Predicted)
+	}
+
+	if got := fitDaily(nil, time.Monday).predict(7); got != (ForecastValue{}) {
+		t.Errorf("empty series = %+v, want zero", got)
+	}
+}
+
+func TestGetAnalyticsForecast(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	// alpha: one session of 4 messages every day in June, each
+	// with one priced assistant message ($3 per day).
+	var msgs []Message
+	for day := 1; day <= 28; day++ {
+		id := fmt.Sprintf("a%02d", day)
+		insertSession(t, d, id, "alpha", func(s *Session) {
+			s.StartedAt = Ptr(fmt.Sprintf(
+				"2024-06-%02dT09:00:00Z", day,
+			))
+			s.MessageCount = 4
+		})
+		m := asstMsg(id, 0, "x")
+		m.Model = "claude-sonnet-4-20250514"
+		m.InputTokens = 1_000_000
+		msgs = append(msgs, m)
+	}
+	insertSession(t, d, "b1", "beta", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-10T09:00:00Z")
+		s.MessageCount = 2
+	})
+	insertMessages(t, d, msgs...)
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-28", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsForecast(ctx, f)
+	requireNoError(t, err, "GetAnalyticsForecast")
+
+	assertEq(t, "From", resp.From, "2024-06-29")
+	assertEq(t, "To", resp.To, "2024-07-05")
+	if len(resp.Projects) != 2 {
+		t.Fatalf("len(Projects) = %d, want 2", len(resp.Projects))
+	}
+	alpha := resp.Projects[0]
+	assertEq(t, "alpha Project", alpha.Project, "alpha")
+	assertEq(t, "alpha HistoryMessages", alpha.HistoryMessages, 112)
+	assertEq(t, "alpha HistoryCost", alpha.HistoryCost, 84.0)
+	assertEq(t, "alpha Messages.Predicted", alpha.Messages.Predicted, 28.0)
+	assertEq(t, "alpha Cost.Predicted", alpha.Cost.Predicted, 21.0)
+	assertEq(t, "beta HistoryMessages", resp.Projects[1].HistoryMessages, 2)
+
+	assertEq(t, "Month", resp.Month.Month, "2024-06")
+	assertEq(t, "Month.DaysLeft", resp.Month.DaysLeft, 2)
+	assertEq(t, "Month.ToDate", resp.Month.ToDate, 84.0)
+	assertEq(t, "Month.Projected", resp.Month.Projected.Predicted, 90.0)
+	assertEq(t, "Currency", resp.Currency, "USD")
+
+	t.Run("cost rates", func(t *testing.T) {
+		rf := f
+		rf.CostRates = map[string]CostRate{
+			"alpha": {Currency: "EUR", Multiplier: 0.5},
+		}
+		resp, err := d.GetAnalyticsForecast(ctx, rf)
+		requireNoError(t, err, "GetAnalyticsForecast")
+		alpha := resp.Projects[0]
+		assertEq(t, "alpha Currency", alpha.Currency, "EUR")
+		assertEq(t, "alpha HistoryCost", alpha.HistoryCost, 42.0)
+		assertEq(t, "alpha Cost.Predicted", alpha.Cost.Predicted, 10.5)
+		assertEq(t, "beta Currency", resp.Projects[1].Currency, "USD")
+		// Cross-project totals stay in USD.
+		assertEq(t, "Currency", resp.Currency, "USD")
+		assertEq(t, "Overall.HistoryCost", resp.Overall.HistoryCost, 84.0)
+
+		rf.Project = "alpha"
+		resp, err = d.GetAnalyticsForecast(ctx, rf)
+		requireNoError(t, err, "GetAnalyticsForecast scoped")
+		assertEq(t, "scoped Currency", resp.Currency, "EUR")
+		assertEq(t, "scoped Month.ToDate", resp.Month.ToDate, 42.0)
+		assertEq(t, "scoped Overall.Currency", resp.Overall.Currency, "EUR")
+	})
+}
+
+func TestGetAnalyticsProgress(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	// alpha: six sessions. The first three take 10 messages for
+	// 2 tasks with an interrupt each; the last three take 4.
+	var msgs []Message
+	for i := range 6 {
+		id := fmt.Sprintf("a%d", i)
+		early := i < 3
+		insertSession(t, d, id, "alpha", func(s *Session) {
+			s.StartedAt = Ptr(fmt.Sprintf(
+				"2024-06-%02dT09:00:00Z", i+1,
+			))
+			s.UserMessageCount = 2
+			s.MessageCount = 4
+			if early {
+				s.MessageCount = 10
+				s.InterruptCount = 1
+			}
+		})
+		m := asstMsg(id, 0, "x")
+		m.HasToolUse = true
+		m.ToolCalls = []ToolCall{{ToolName: "Read", Category: "Read"}}
+		if early {
+			m.ToolCalls = append(m.ToolCalls,
+				ToolCall{ToolName: "Bash", Category: "Bash"})
+		}
+		msgs = append(msgs, m)
+	}
+	insertSession(t, d, "b1", "beta", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-02T09:00:00Z")
+	})
+	insertMessages(t, d, msgs...)
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsProgress(ctx, f, 3)
+	requireNoError(t, err, "GetAnalyticsProgress")
+
+	assertEq(t, "N", resp.N, 3)
+	if len(resp.Projects) != 1 {
+		t.Fatalf("len(Projects) = %d, want 1 (beta has one session)",
+			len(resp.Projects))
+	}
+	p := resp.Projects[0]
+	assertEq(t, "Project", p.Project, "alpha")
+	assertEq(t, "first From", p.First.From, "2024-06-01")
+	assertEq(t, "recent To", p.Recent.To, "2024-06-06")
+	assertEq(t, "first MessagesPerTask", p.First.MessagesPerTask, 5.0)
+	assertEq(t, "recent MessagesPerTask", p.Recent.MessagesPerTask, 2.0)
+	assertEq(t, "first InterruptedPct", p.First.InterruptedPct, 100.0)
+	assertEq(t, "recent InterruptsPerSession", p.Recent.InterruptsPerSession, 0.0)
+	assertEq(t, "first Bash mix", p.First.ToolMix["Bash"], 50.0)
+	assertEq(t, "recent Read mix", p.Recent.ToolMix["Read"], 100.0)
+	assertEq(t, "change MessagesPerTask", *p.Change.MessagesPerTask, -60.0)
+	assertEq(t, "change ToolCalls", *p.Change.ToolCallsPerSession, -50.0)
+	assertEq(t, "change Interrupts", *p.Change.InterruptsPerSession, -100.0)
+
+	// Cohorts shrink to avoid overlap.
+	resp, err = d.GetAnalyticsProgress(ctx, f, 10)
+	requireNoError(t, err, "GetAnalyticsProgress n=10")
+	assertEq(t, "shrunk N", resp.Projects[0].N, 3)
+}
+
+func TestSequenceStep(t *testing.T) {
+	tests := []struct {
+		category, command, want string
+	}{
+		{"Read", "", "Read"},
+		{"Bash", "", "Bash"},
+		{"Bash", "go test ./...", "Bash(test)"},
+		{"Bash", `["bash","-lc","npx vitest run"]`, "Bash(test)"},
+		{"Bash", "make build", "Bash(build)"},
+		{"Bash", "git commit -m 'fix jest config'", "Bash(git)"},
+		{"Bash", "ls -la", "Bash"},
+	}
+	for _, tt := range tests {
+		got := sequenceStep(tt.category, tt.command)
+		assertEq(t, tt.category+" "+tt.command, got, tt.want)
+	}
+}
+
+func TestGetAnalyticsToolSequences(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	// Three claude sessions run Read→Edit→Bash(test); the two
+	// that also spin on Grep were interrupted.
+	var msgs []Message
+	for i := range 3 {
+		id := fmt.Sprintf("s%d", i)
+		spin := i > 0
+		insertSession(t, d, id, "alpha", func(s *Session) {
+			s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+			if spin {
+				s.InterruptCount = 1
+			}
+		})
+		calls := []ToolCall{
+			{ToolName: "Read", Category: "Read"},
+			{ToolName: "Edit", Category: "Edit"},
+			{ToolName: "Bash", Category: "Bash",
+				InputJSON: `{"command":"go test ./..."}`},
+		}
+		m := asstMsg(id, 0, "x")
+		m.HasToolUse = true
+		m.ToolCalls = calls
+		msgs = append(msgs, m)
+		if spin {
+			g := asstMsg(id, 1, "y")
+			g.HasToolUse = true
+			for range 4 {
+				g.ToolCalls = append(g.ToolCalls,
+					ToolCall{ToolName: "Grep", Category: "Grep"})
+			}
+			msgs = append(msgs, g)
+		}
+	}
+	insertMessages(t, d, msgs...)
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsToolSequences(ctx, f, 3, 0)
+	requireNoError(t, err, "GetAnalyticsToolSequences")
+
+	assertEq(t, "Length", resp.Length, 3)
+	if len(resp.Agents) != 1 {
+		t.Fatalf("len(Agents) = %d, want 1", len(resp.Agents))
+	}
+	a := resp.Agents[0]
+	assertEq(t, "Sessions", a.Sessions, 3)
+	assertEq(t, "Baseline", a.BaselineSuccessRate, 33.3)
+
+	top := a.Sequences[0]
+	assertEq(t, "top steps",
+		strings.Join(top.Steps, ","), "Read,Edit,Bash(test)")
+	assertEq(t, "top sessions", top.Sessions, 3)
+	assertEq(t, "top lift", top.Lift, 0.0)
+
+	if len(a.Spins) != 1 {
+		t.Fatalf("len(Spins) = %d, want 1: %+v", len(a.Spins), a.Spins)
+	}
+	spin := a.Spins[0]
+	assertEq(t, "spin steps",
+		strings.Join(spin.Steps, ","), "Grep,Grep,Grep")
+	assertEq(t, "spin count", spin.Count, 4)
+	assertEq(t, "spin sessions", spin.Sessions, 2)
+	assertEq(t, "spin success", spin.SuccessRate, 0.0)
+	assertEq(t, "spin lift", spin.Lift, -33.3)
+}
+
+func TestContributingSessions(t *testing.T) {
+	if got := (AnalyticsFilter{}).contributors(); got != nil {
+		t.Fatalf("contributors() = %+v, want nil when not requested", got)
+	}
+	// A nil list ignores adds.
+	var none *ContributingSessions
+	none.add("s")
+
+	c := AnalyticsFilter{IncludeSessionIDs: true}.contributors()
+	for i := range MaxContributingSessionIDs + 10 {
+		id := fmt.Sprintf("s%d", i)
+		c.add(id)
+		c.add(id)
+	}
+	assertEq(t, "Total", c.Total, MaxContributingSessionIDs+10)
+	assertEq(t, "len(IDs)", len(c.IDs), MaxContributingSessionIDs)
+	assertEq(t, "IDs[0]", c.IDs[0], "s0")
+}
+
+func TestGetAnalyticsBranches(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	add := func(id, project, branch, worktree string, msgs int) {
+		insertSession(t, d, id, project, func(s *Session) {
+			s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+			s.MessageCount = msgs
+			s.GitBranch = branch
+			s.Worktree = worktree
+		})
+	}
+	add("s1", "app", "login", "app-login", 10)
+	add("s2", "app", "login", "app-login-2", 5)
+	add("s3", "app", "main", "", 3)
+	add("s4", "other", "login", "", 1)
+
+	resp, err := d.GetAnalyticsBranches(ctx, AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Project: "app",
+	})
+	requireNoError(t, err, "GetAnalyticsBranches")
+	if len(resp.Branches) != 2 {
+		t.Fatalf("branches = %+v, want 2", resp.Branches)
+	}
+	login := resp.Branches[0]
+	assertEq(t, "branch", login.Branch, "login")
+	assertEq(t, "sessions", login.Sessions, 2)
+	assertEq(t, "messages", login.Messages, 15)
+	assertEq(t, "worktrees", strings.Join(login.Worktrees, ","),
+		"app-login,app-login-2")
+	assertEq(t, "main worktrees", len(resp.Branches[1].Worktrees), 0)
+
+	full, err := d.GetSessionFull(ctx, "s1")
+	requireNoError(t, err, "GetSessionFull")
+	assertEq(t, "GitBranch", full.GitBranch, "login")
+	assertEq(t, "Worktree", full.Worktree, "app-login")
+}
+
+func TestGetAnalyticsEntryPoints(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	add := func(id, machine, entry, client string, msgs int) {
+		insertSession(t, d, id, "app", func(s *Session) {
+			s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+			s.Machine = machine
+			s.MessageCount = msgs
+			s.EntryPoint = entry
+			s.Client = client
+		})
+	}
+	add("s1", "laptop", "vscode", "claude-vscode", 10)
+	add("s2", "desktop", "vscode", "codex_vscode", 5)
+	add("s3", "laptop", "terminal", "cli", 3)
+	add("s4", "laptop", "", "", 1)
+
+	resp, err := d.GetAnalyticsEntryPoints(ctx, AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30",
+	})
+	requireNoError(t, err, "GetAnalyticsEntryPoints")
+	if len(resp.EntryPoints) != 3 {
+		t.Fatalf("entry points = %+v, want 3", resp.EntryPoints)
+	}
+	vs := resp.EntryPoints[0]
+	assertEq(t, "entry", vs.EntryPoint, "vscode")
+	assertEq(t, "sessions", vs.Sessions, 2)
+	assertEq(t, "messages", vs.Messages, 15)
+	assertEq(t, "machines", vs.Machines, 2)
+	assertEq(t, "clients", strings.Join(vs.Clients, ","),
+		"claude-vscode,codex_vscode")
+
+	full, err := d.GetSessionFull(ctx, "s3")
+	requireNoError(t, err, "GetSessionFull")
+	assertEq(t, "EntryPoint", full.EntryPoint, "terminal")
+	assertEq(t, "Client", full.Client, "cli")
+}
+
+func TestGetSkillUsage(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	skillMsg := func(sid string, ordinal int, skill, ts string) Message {
+		m := asstMsg(sid, ordinal, "[Skill: "+skill+"]")
+		m.Timestamp = ts
+		m.ToolCalls = []ToolCall{{
+			SessionID: sid,
+			ToolName:  "Skill",
+			Category:  "Tool",
+			ToolUseID: fmt.Sprintf("toolu_%s_%d", sid, ordinal),
+			SkillName: skill,
+		}}
+		return m
+	}
+	add := func(id, machine, start string, interrupts int) {
+		insertSession(t, d, id, "app", func(s *Session) {
+			s.StartedAt = Ptr(start)
+			s.Machine = machine
+			s.InterruptCount = interrupts
+		})
+	}
+
+	add("s1", "laptop", "2024-06-03T09:00:00Z", 0)
+	insertMessages(t, d,
+		userMsgAt("s1", 0, "plan it", "2024-06-03T09:00:00Z"),
+		skillMsg("s1", 1, "brainstorm", "2024-06-03T09:01:00Z"),
+		userMsgAt("s1", 2, "ok", "2024-06-03T09:02:00Z"),
+		userMsgAt("s1", 3, "go", "2024-06-03T09:03:00Z"),
+	)
+	add("s2", "desktop", "2024-06-12T10:00:00Z", 1)
+	insertMessages(t, d,
+		skillMsg("s2", 0, "brainstorm", "2024-06-12T10:00:00Z"),
+		skillMsg("s2", 1, "brainstorm", "2024-06-12T10:05:00Z"),
+		userMsgAt("s2", 2, "stop", "2024-06-12T10:06:00Z"),
+	)
+	// Out of range, and a different skill.
+	add("s3", "laptop", "2024-07-10T10:00:00Z", 0)
+	insertMessages(t, d,
+		skillMsg("s3", 0, "brainstorm", "2024-07-10T10:00:00Z"))
+	add("s4", "laptop", "2024-06-05T10:00:00Z", 0)
+	insertMessages(t, d,
+		skillMsg("s4", 0, "debug", "2024-06-05T10:00:00Z"))
+	requireNoError(t, d.ReplaceSessionCommands("s1", []Command{
+		{Name: "review", Timestamp: "2024-06-03T09:00:00Z"},
+	}), "ReplaceSessionCommands")
+
+	f := AnalyticsFilter{From: "2024-06-01", To: "2024-06-30"}
+	u, err := d.GetSkillUsage(ctx, f, UsageKindSkill, "brainstorm")
+	requireNoError(t, err, "GetSkillUsage")
+	assertEq(t, "invocations", u.Invocations, 3)
+	assertEq(t, "sessions", u.Sessions, 2)
+	assertEq(t, "machines", u.Machines, 2)
+	assertEq(t, "projects", u.Projects, 1)
+	assertEq(t, "first used", u.FirstUsed, "2024-06-03")
+	assertEq(t, "last used", u.LastUsed, "2024-06-12")
+	assertEq(t, "avg after", u.AvgMessagesAfter, 2.0)
+	assertEq(t, "interrupt rate", u.InterruptRate, 50.0)
+	assertEq(t, "claude sessions", u.Agents["claude"], 2)
+	if len(u.Trend) != 5 {
+		t.Fatalf("trend = %+v, want 5 weeks", u.Trend)
+	}
+	assertEq(t, "first week", u.Trend[0].Week, "2024-05-27")
+	assertEq(t, "week 2", u.Trend[1].Invocations, 1)
+	assertEq(t, "week 3", u.Trend[2].Invocations, 2)
+
+	u, err = d.GetSkillUsage(ctx, f, UsageKindCommand, "review")
+	requireNoError(t, err, "GetSkillUsage command")
+	assertEq(t, "command invocations", u.Invocations, 1)
+	assertEq(t, "command sessions", u.Sessions, 1)
+	assertEq(t, "command avg after", u.AvgMessagesAfter, 3.0)
+
+	u, err = d.GetSkillUsage(ctx, f, UsageKindSkill, "unused")
+	requireNoError(t, err, "GetSkillUsage unused")
+	assertEq(t, "unused invocations", u.Invocations, 0)
+	assertEq(t, "unused trend", len(u.Trend), 5)
+
+	_, err = d.GetSkillUsage(ctx, f, "plugin", "x")
+	if !errors.Is(err, ErrInvalidUsageKind) {
+		t.Errorf("err = %v, want ErrInvalidUsageKind", err)
+	}
+}
@@ -0,0 +1,216 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CacheStats summarizes prompt-cache usage over a set of
+// assistant messages. Input tokens are split the way the
+// Anthropic API reports them: uncached input, cache reads, and
+// cache writes are disjoint.
+type CacheStats struct {
+	InputTokens         int `json:"input_tokens"` // uncached
+	CacheReadTokens     int `json:"cache_read_tokens"`
+	CacheCreationTokens int `json:"cache_creation_tokens"`
+	TotalInputTokens    int `json:"total_input_tokens"`
+	// HitRatio is cache reads over total input, 0-1.
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+func (c *CacheStats) add(input, read, creation int) {
+	c.InputTokens += input
+	c.CacheReadTokens += read
+	c.CacheCreationTokens += creation
+	c.TotalInputTokens += input + read + creation
+}
+
+func (c *CacheStats) finish() {
+	if c.TotalInputTokens > 0 {
+		c.HitRatio = math.Round(float64(c.CacheReadTokens)/
+			float64(c.TotalInputTokens)*10000) / 10000
+	}
+}
+
+// CacheTrendEntry is cache usage for one date bucket.
+type CacheTrendEntry struct {
+	Date string `json:"date"`
+	CacheStats
+}
+
+// CacheBreakdown is cache usage for one agent, model, or
+// project.
+type CacheBreakdown struct {
+	Label string `json:"label"`
+	CacheStats
+}
+
+// CacheAnalyticsResponse wraps prompt-cache analytics.
+type CacheAnalyticsResponse struct {
+	Granularity string            `json:"granularity"`
+	Overall     CacheStats        `json:"overall"`
+	Trend       []CacheTrendEntry `json:"trend"`
+	ByAgent     []CacheBreakdown  `json:"by_agent"`
+	ByModel     []CacheBreakdown  `json:"by_model"`
+	ByProject   []CacheBreakdown  `json:"by_project"`
+}
+
+// GetAnalyticsCache reports cached vs. uncached input tokens
+// over time and per agent, model, and project. Only messages
+// with reported token usage contribute; trend buckets are by
+// session start date.
+func (db *DB) GetAnalyticsCache(
+	ctx context.Context, f AnalyticsFilter, granularity string,
+) (CacheAnalyticsResponse, error) {
+	if granularity == "" {
+		granularity = "day"
+	}
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return CacheAnalyticsResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, agent, project
+		FROM sessions WHERE `+where, args...,
+	)
+	if err != nil {
+		return CacheAnalyticsResponse{},
+			fmt.Errorf("querying cache sessions: %w", err)
+	}
+	defer rows.Close()
+
+	type sessInfo struct {
+		bucket  string
+		agent   string
+		project string
+	}
+	sessionMap := make(map[string]sessInfo)
+	var sessionIDs []string
+	for rows.Next() {
+		var id, ts, agent, project string
+		if err := rows.Scan(&id, &ts, &agent, &project); err != nil {
+			return CacheAnalyticsResponse{},
+				fmt.Errorf("scanning cache session: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		sessionMap[id] = sessInfo{
+			bucket:  bucketDate(date, granularity),
+			agent:   agent,
+			project: project,
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return CacheAnalyticsResponse{},
+			fmt.Errorf("iterating cache sessions: %w", err)
+	}
+
+	resp := CacheAnalyticsResponse{Granularity: granularity}
+	trend := map[string]*CacheStats{}
+	byAgent := map[string]*CacheStats{}
+	byModel := map[string]*CacheStats{}
+	byProject := map[string]*CacheStats{}
+	bump := func(m map[string]*CacheStats, key string,
+		input, read, creation int,
+	) {
+		if m[key] == nil {
+			m[key] = &CacheStats{}
+		}
+		m[key].add(input, read, creation)
+	}
+
+	err = queryChunked(sessionIDs, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		msgRows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, model, input_tokens,
+				cache_read_tokens, cache_creation_tokens
+			FROM messages
+			WHERE session_id IN `+ph+`
+				AND input_tokens + cache_read_tokens
+					+ cache_creation_tokens > 0`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying cache messages: %w", err)
+		}
+		defer msgRows.Close()
+		for msgRows.Next() {
+			var sid, model string
+			var input, read, creation int
+			if err := msgRows.Scan(
+				&sid, &model, &input, &read, &creation,
+			); err != nil {
+				return fmt.Errorf(
+					"scanning cache message: %w", err,
+				)
+			}
+			if model == "" {
+				model = "unknown"
+			}
+			info := sessionMap[sid]
+			resp.Overall.add(input, read, creation)
+			bump(trend, info.bucket, input, read, creation)
+			bump(byAgent, info.agent, input, read, creation)
+			bump(byModel, model, input, read, creation)
+			bump(byProject, info.project, input, read, creation)
+		}
+		return msgRows.Err()
+	})
+	if err != nil {
+		return CacheAnalyticsResponse{}, err
+	}
+
+	resp.Overall.finish()
+
+	dates := make([]string, 0, len(trend))
+	for d := range trend {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+	resp.Trend = make([]CacheTrendEntry, 0, len(dates))
+	for _, d := range dates {
+		trend[d].finish()
+		resp.Trend = append(resp.Trend, CacheTrendEntry{
+			Date: d, CacheStats: *trend[d],
+		})
+	}
+
+	resp.ByAgent = cacheBreakdowns(byAgent)
+	resp.ByModel = cacheBreakdowns(byModel)
+	resp.ByProject = cacheBreakdowns(byProject)
+	return resp, nil
+}
+
+// cacheBreakdowns flattens m, ordered by total input tokens
+// descending.
+func cacheBreakdowns(m map[string]*CacheStats) []CacheBreakdown {
+	out := make([]CacheBreakdown, 0, len(m))
+	for label, s := range m {
+		s.finish()
+		out = append(out, CacheBreakdown{Label: label, CacheStats: *s})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalInputTokens != out[j].TotalInputTokens {
+			return out[i].TotalInputTokens > out[j].TotalInputTokens
+		}
+		return out[i].Label < out[j].Label
+	})
+	return out
+}
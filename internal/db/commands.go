@@ -0,0 +1,49 @@
+package db
+
+import "fmt"
+
+// Command is one slash command invocation in a session.
+type Command struct {
+	Name      string `json:"name"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// ReplaceSessionCommands replaces all slash command
+// invocations for a session.
+func (db *DB) ReplaceSessionCommands(
+	sessionID string, cmds []Command,
+) error {
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.Exec(
+			"DELETE FROM session_commands WHERE session_id = ?",
+			sessionID,
+		); err != nil {
+			return fmt.Errorf("deleting old commands: %w", err)
+		}
+
+		if len(cmds) > 0 {
+			stmt, err := tx.Prepare(`
+				INSERT INTO session_commands
+					(session_id, name, timestamp)
+				VALUES (?, ?, ?)`)
+			if err != nil {
+				return fmt.Errorf("preparing command insert: %w", err)
+			}
+			defer stmt.Close()
+			for _, c := range cmds {
+				if _, err := stmt.Exec(
+					sessionID, c.Name, nilIfEmpty(c.Timestamp),
+				); err != nil {
+					return fmt.Errorf("inserting command: %w", err)
+				}
+			}
+		}
+		return tx.Commit()
+	})
+}
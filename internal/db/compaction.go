@@ -0,0 +1,164 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// sessionMonthExpr is the UTC month a session started in, for
+// a sessions table aliased s.
+const sessionMonthExpr = `strftime('%Y-%m',
+	COALESCE(NULLIF(s.started_at, ''), s.created_at))`
+
+// CompactOptions controls a compaction run.
+type CompactOptions struct {
+	// Before is the horizon: months that started before the
+	// month containing Before are compacted.
+	Before time.Time
+	// StripPayloads clears those sessions' raw tool call inputs
+	// and results. Rows and the columns derived from them
+	// (category, file_path, command, skill_name, result flags and
+	// lengths) are kept, so tool analytics stay exact but the
+	// tool_calls row count is not capped.
+	StripPayloads bool
+}
+
+// CompactStats reports what a compaction run did.
+type CompactStats struct {
+	Months            int   `json:"months"`
+	StrippedToolCalls int64 `json:"stripped_tool_calls"`
+}
+
+// MonthlyUsage is one month's totals for a project and agent.
+type MonthlyUsage struct {
+	Month        string `json:"month"`
+	Project      string `json:"project"`
+	Agent        string `json:"agent"`
+	Sessions     int    `json:"sessions"`
+	Messages     int    `json:"messages"`
+	UserMessages int    `json:"user_messages"`
+	ToolCalls    int    `json:"tool_calls"`
+	InputTokens  int64  `json:"input_tokens"`
+	OutputTokens int64  `json:"output_tokens"`
+}
+
+// Compact rolls sessions from months before the horizon up into
+// monthly_usage and, with StripPayloads, clears their tool calls'
+// raw input_json and result_content. Analytics read only derived
+// columns and are unchanged; the session viewer no longer shows
+// those inputs and results, and secret scans skip them. No rows
+// are deleted. Runs are idempotent: the rollup is rebuilt each
+// time.
+// Freed pages are reused by later writes rather than returned
+// to the file system.
+func (db *DB) Compact(
+	ctx context.Context, opts CompactOptions,
+) (CompactStats, error) {
+	var stats CompactStats
+	cutoff := opts.Before.UTC().Format("2006-01")
+
+	err := db.write(func() error {
+		tx, err := db.getWriter().BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin compaction tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if opts.StripPayloads {
+			res, err := tx.ExecContext(ctx, `
+				UPDATE tool_calls
+				SET input_json = NULL, result_content = NULL
+				WHERE session_id IN (
+					SELECT s.id FROM sessions s
+					WHERE `+sessionMonthExpr+` < ?
+				)
+				AND (input_json IS NOT NULL
+					OR result_content IS NOT NULL)`, cutoff,
+			)
+			if err != nil {
+				return fmt.Errorf("stripping tool_calls: %w", err)
+			}
+			stats.StrippedToolCalls, _ = res.RowsAffected()
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			"DELETE FROM monthly_usage",
+		); err != nil {
+			return fmt.Errorf("clearing monthly_usage: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO monthly_usage (
+				month, project, agent, sessions, messages,
+				user_messages, tool_calls, input_tokens,
+				output_tokens
+			)
+			SELECT month, project, agent, COUNT(*),
+				SUM(message_count), SUM(user_message_count),
+				SUM(tools), SUM(input), SUM(output)
+			FROM (
+				SELECT `+sessionMonthExpr+` AS month,
+					s.project, s.agent, s.message_count,
+					s.user_message_count,
+					(SELECT COUNT(*) FROM tool_calls t
+						WHERE t.session_id = s.id) AS tools,
+					(SELECT COALESCE(SUM(input_tokens), 0)
+						FROM messages m
+						WHERE m.session_id = s.id) AS input,
+					(SELECT COALESCE(SUM(output_tokens), 0)
+						FROM messages m
+						WHERE m.session_id = s.id) AS output
+				FROM sessions s
+				WHERE s.message_count > 0
+			)
+			WHERE month < ?
+			GROUP BY month, project, agent`, cutoff,
+		); err != nil {
+			return fmt.Errorf("rolling up monthly_usage: %w", err)
+		}
+		if err := tx.QueryRowContext(ctx,
+			"SELECT COUNT(DISTINCT month) FROM monthly_usage",
+		).Scan(&stats.Months); err != nil {
+			return fmt.Errorf("counting months: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing compaction: %w", err)
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// GetMonthlyUsage returns the compacted monthly totals, oldest
+// first, optionally limited to a project and agent.
+func (db *DB) GetMonthlyUsage(
+	ctx context.Context, project, agent string,
+) ([]MonthlyUsage, error) {
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT month, project, agent, sessions, messages,
+			user_messages, tool_calls, input_tokens,
+			output_tokens
+		FROM monthly_usage
+		WHERE (? = '' OR project = ?) AND (? = '' OR agent = ?)
+		ORDER BY month, project, agent`,
+		project, project, agent, agent,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying monthly usage: %w", err)
+	}
+	defer rows.Close()
+
+	out := []MonthlyUsage{}
+	for rows.Next() {
+		var u MonthlyUsage
+		if err := rows.Scan(
+			&u.Month, &u.Project, &u.Agent, &u.Sessions,
+			&u.Messages, &u.UserMessages, &u.ToolCalls,
+			&u.InputTokens, &u.OutputTokens,
+		); err != nil {
+			return nil, fmt.Errorf("scanning monthly usage: %w", err)
+		}
+		out = append(out, u)
+	}
+	return out, rows.Err()
+}
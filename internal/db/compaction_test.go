@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompact(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	seed := func(id, project, started string, tools ...string) {
+		t.Helper()
+		insertSession(t, d, id, project, func(s *Session) {
+			s.StartedAt = Ptr(started)
+			s.MessageCount = 2
+			s.UserMessageCount = 1
+		})
+		m := asstMsg(id, 1, "working")
+		m.InputTokens, m.OutputTokens = 100, 10
+		for _, name := range tools {
+			m.ToolCalls = append(m.ToolCalls, ToolCall{
+				SessionID: id, ToolName: name, Category: name,
+				InputJSON: `{}`,
+			})
+		}
+		insertMessages(t, d, userMsg(id, 0, "go"), m)
+	}
+	seed("old1", "alpha", "2022-03-05T10:00:00Z", "Read", "Read", "Bash")
+	seed("old2", "alpha", "2022-03-20T10:00:00Z", "Edit")
+	seed("new", "alpha", "2024-06-02T10:00:00Z", "Read")
+
+	filter := AnalyticsFilter{
+		From: "2022-01-01", To: "2024-12-31", Timezone: "UTC",
+	}
+	before, err := d.GetAnalyticsTools(ctx, filter)
+	requireNoError(t, err, "GetAnalyticsTools before")
+
+	stats, err := d.Compact(ctx, CompactOptions{
+		Before:        time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		StripPayloads: true,
+	})
+	requireNoError(t, err, "Compact")
+	want := CompactStats{Months: 1, StrippedToolCalls: 4}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	after, err := d.GetAnalyticsTools(ctx, filter)
+	requireNoError(t, err, "GetAnalyticsTools after")
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("tools analytics changed:\nbefore %+v\nafter  %+v",
+			before, after)
+	}
+
+	months, err := d.GetMonthlyUsage(ctx, "", "")
+	requireNoError(t, err, "GetMonthlyUsage")
+	wantMonths := []MonthlyUsage{{
+		Month: "2022-03", Project: "alpha", Agent: defaultAgent,
+		Sessions: 2, Messages: 4, UserMessages: 2, ToolCalls: 4,
+		InputTokens: 200, OutputTokens: 20,
+	}}
+	if !reflect.DeepEqual(months, wantMonths) {
+		t.Errorf("months = %+v, want %+v", months, wantMonths)
+	}
+
+	// A second run finds nothing left to strip and rebuilds the
+	// same rollup.
+	stats, err = d.Compact(ctx, CompactOptions{
+		Before:        time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		StripPayloads: true,
+	})
+	requireNoError(t, err, "second Compact")
+	if stats.StrippedToolCalls != 0 || stats.Months != 1 {
+		t.Errorf("second stats = %+v", stats)
+	}
+	again, err := d.GetMonthlyUsage(ctx, "alpha", "")
+	requireNoError(t, err, "GetMonthlyUsage alpha")
+	if !reflect.DeepEqual(again, wantMonths) {
+		t.Errorf("months after rerun = %+v", again)
+	}
+}
+
+// TestCompactStripPayloadsKeepsAnalytics runs every analytics reader
+// of tool_calls across a StripPayloads compaction and requires the
+// same answer, since strip_payloads only clears raw payloads.
+func TestCompactStripPayloadsKeepsAnalytics(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	for _, id := range []string{"a", "b"} {
+		insertSession(t, d, id, "alpha", func(s *Session) {
+			s.StartedAt = Ptr("2022-03-05T10:00:00Z")
+			s.EndedAt = Ptr("2022-03-05T11:00:00Z")
+		})
+	}
+	edit := func(sid string, ordinal int, file string) Message {
+		m := asstMsg(sid, ordinal, "[Edit]")
+		m.HasToolUse = true
+		m.ToolCalls = []ToolCall{{
+			SessionID: sid, ToolName: "Edit", Category: "Edit",
+			InputJSON:     fmt.Sprintf(`{"file_path":%q}`, file),
+			ResultContent: "ok", ResultContentLength: 2,
+		}}
+		return m
+	}
+	skill := asstMsg("b", 2, "[Skill]")
+	skill.HasToolUse = true
+	skill.ToolCalls = []ToolCall{{
+		SessionID: "b", ToolName: "Skill", Category: "Tool",
+		SkillName: "review", InputJSON: `{"skill":"review"}`,
+		ResultContent: "done", ResultContentLength: 4,
+		ResultTruncated: true, ResultOriginalLength: 9000,
+	}}
+	insertMessages(t, d,
+		userMsg("a", 0, "fix the tests"),
+		edit("a", 1, "db.go"),
+		bashMsg("a", 2, []string{"go test ./...", "git status"}, 0),
+		edit("a", 3, "db.go"),
+		bashMsg("a", 4, []string{"go test ./..."}, 0),
+		edit("a", 5, "db.go"),
+		edit("a", 6, "db.go"),
+		bashMsg("a", 7, []string{"go test ./..."}),
+		userMsg("b", 0, "review this"),
+		bashMsg("b", 1, []string{"make build", "ls"}, 1),
+		skill,
+		edit("b", 3, "db_test.go"),
+		bashMsg("b", 4, []string{"go test ./..."}),
+	)
+
+	f := AnalyticsFilter{
+		From: "2022-01-01", To: "2022-12-31", Timezone: "UTC",
+	}
+	readers := []struct {
+		name string
+		get  func() (any, error)
+	}{
+		{"tools", func() (any, error) {
+			return d.GetAnalyticsTools(ctx, f)
+		}},
+		{"activity", func() (any, error) {
+			return d.GetAnalyticsActivity(ctx, f, "day")
+		}},
+		{"velocity", func() (any, error) {
+			return d.GetAnalyticsVelocity(ctx, f)
+		}},
+		{"cost phases", func() (any, error) {
+			return d.GetAnalyticsCostPhases(ctx, f)
+		}},
+		{"edit thrash", func() (any, error) {
+			return d.GetAnalyticsEditThrash(ctx, f, 0)
+		}},
+		{"sequences", func() (any, error) {
+			return d.GetAnalyticsToolSequences(ctx, f, 2, 0)
+		}},
+		{"skill usage", func() (any, error) {
+			return d.GetSkillUsage(ctx, f, UsageKindSkill, "review")
+		}},
+		{"retries", func() (any, error) {
+			return d.GetAnalyticsRetries(ctx, f, 0)
+		}},
+		{"files", func() (any, error) {
+			return d.GetRecentFiles(ctx, RecentFilesFilter{})
+		}},
+		{"quality", func() (any, error) {
+			return d.GetAnalyticsQuality(ctx, f, "month")
+		}},
+		{"truncation", func() (any, error) {
+			return d.GetAnalyticsTruncation(ctx, f)
+		}},
+		{"commands", func() (any, error) {
+			return d.GetAnalyticsCommands(ctx, f, 0)
+		}},
+	}
+	before := make([]any, len(readers))
+	for i, r := range readers {
+		var err error
+		before[i], err = r.get()
+		requireNoError(t, err, r.name+" before")
+	}
+
+	stats, err := d.Compact(ctx, CompactOptions{
+		Before:        time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		StripPayloads: true,
+	})
+	requireNoError(t, err, "Compact")
+	assertEq(t, "stripped", stats.StrippedToolCalls, int64(13))
+	msgs, err := d.GetAllMessages(ctx, "b")
+	requireNoError(t, err, "GetAllMessages")
+	tc := msgs[2].ToolCalls[0]
+	if tc.InputJSON != "" || tc.ResultContent != "" {
+		t.Errorf("raw payload kept: %+v", tc)
+	}
+
+	for i, r := range readers {
+		after, err := r.get()
+		requireNoError(t, err, r.name+" after")
+		if !reflect.DeepEqual(before[i], after) {
+			t.Errorf("%s changed:\nbefore %+v\nafter  %+v",
+				r.name, before[i], after)
+		}
+	}
+}
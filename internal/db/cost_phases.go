@@ -0,0 +1,317 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/wesm/agentsview/internal/pricing"
+)
+
+// Work phases a turn's cost is attributed to.
+const (
+	PhaseExploration    = "exploration"
+	PhaseImplementation = "implementation"
+	PhaseVerification   = "verification"
+	PhaseOther          = "other"
+	PhaseDiscussion     = "discussion"
+)
+
+// costPhases lists the phases in report order. Ties between
+// tool phases go to the earlier one.
+var costPhases = []string{
+	PhaseExploration, PhaseImplementation, PhaseVerification,
+	PhaseOther, PhaseDiscussion,
+}
+
+// categoryPhase maps a normalized tool category to its phase.
+func categoryPhase(category string) string {
+	switch category {
+	case "Read", "Grep", "Glob":
+		return PhaseExploration
+	case "Edit", "Write":
+		return PhaseImplementation
+	case "Bash":
+		return PhaseVerification
+	default:
+		return PhaseOther
+	}
+}
+
+// PhaseCost is the cost of the turns attributed to one phase.
+// Share is the percentage of the enclosing total's cost.
+type PhaseCost struct {
+	Phase  string  `json:"phase"`
+	Turns  int     `json:"turns"`
+	Tokens int     `json:"tokens"`
+	Cost   float64 `json:"cost"`
+	Share  float64 `json:"share"`
+}
+
+// ProjectCostPhases splits one project's cost by phase.
+type ProjectCostPhases struct {
+	Project string      `json:"project"`
+	Cost    float64     `json:"cost"`
+	Phases  []PhaseCost `json:"phases"`
+}
+
+// CostPhasesResponse wraps cost-by-phase analytics. Phases
+// always lists every phase in the same order.
+type CostPhasesResponse struct {
+	Cost     float64             `json:"cost"`
+	Phases   []PhaseCost         `json:"phases"`
+	Projects []ProjectCostPhases `json:"projects"`
+	// Currency of the costs, chosen as in
+	// UsageAnalyticsResponse.
+	Currency string `json:"currency"`
+}
+
+// phaseTotals accumulates unrounded phase costs.
+type phaseTotals map[string]*PhaseCost
+
+func (p phaseTotals) add(phase string, tokens int, cost float64) {
+	pc := p[phase]
+	if pc == nil {
+		pc = &PhaseCost{Phase: phase}
+		p[phase] = pc
+	}
+	pc.Turns++
+	pc.Tokens += tokens
+	pc.Cost += cost
+}
+
+// finish returns every phase in order with costs converted by
+// multiplier, and the rounded total.
+func (p phaseTotals) finish(multiplier float64) ([]PhaseCost, float64) {
+	var total float64
+	for _, pc := range p {
+		total += pc.Cost
+	}
+	out := make([]PhaseCost, 0, len(costPhases))
+	for _, phase := range costPhases {
+		pc := PhaseCost{Phase: phase}
+		if p[phase] != nil {
+			pc = *p[phase]
+		}
+		if total > 0 {
+			pc.Share = round1(pc.Cost / total * 100)
+		}
+		pc.Cost = roundCost(pc.Cost * multiplier)
+		out = append(out, pc)
+	}
+	return out, roundCost(total * multiplier)
+}
+
+// GetAnalyticsCostPhases attributes estimated cost to phases of
+// work. A turn is a user message and the agent messages that
+// follow it; its cost is the priced token usage of those
+// messages, and its phase is the one most of its tool calls fall
+// in: exploration (Read, Grep, Glob), implementation (Edit,
+// Write), verification (Bash), or other. Turns without tool
+// calls count as discussion.
+func (db *DB) GetAnalyticsCostPhases(
+	ctx context.Context, f AnalyticsFilter,
+) (CostPhasesResponse, error) {
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return CostPhasesResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, project
+		FROM sessions WHERE `+where, args...,
+	)
+	if err != nil {
+		return CostPhasesResponse{},
+			fmt.Errorf("querying cost phase sessions: %w", err)
+	}
+	defer rows.Close()
+
+	projectOf := map[string]string{}
+	var sessionIDs []string
+	for rows.Next() {
+		var id, ts, project string
+		if err := rows.Scan(&id, &ts, &project); err != nil {
+			return CostPhasesResponse{},
+				fmt.Errorf("scanning cost phase session: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		projectOf[id] = project
+		sessionIDs = append(sessionIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return CostPhasesResponse{},
+			fmt.Errorf("iterating cost phase sessions: %w", err)
+	}
+
+	total := phaseTotals{}
+	byProject := map[string]phaseTotals{}
+	err = db.costTurns(ctx, sessionIDs,
+		func(sid, phase string, tokens int, cost float64) {
+			total.add(phase, tokens, cost)
+			project := projectOf[sid]
+			if byProject[project] == nil {
+				byProject[project] = phaseTotals{}
+			}
+			byProject[project].add(phase, tokens, cost)
+		})
+	if err != nil {
+		return CostPhasesResponse{}, err
+	}
+
+	rate := CostRate{Currency: BaseCurrency, Multiplier: 1}
+	if f.Project != "" {
+		rate = f.costRate(f.Project)
+	}
+	resp := CostPhasesResponse{Currency: rate.Currency}
+	resp.Phases, resp.Cost = total.finish(rate.Multiplier)
+	resp.Projects = make([]ProjectCostPhases, 0, len(byProject))
+	for project, p := range byProject {
+		pcp := ProjectCostPhases{Project: project}
+		pcp.Phases, pcp.Cost = p.finish(rate.Multiplier)
+		resp.Projects = append(resp.Projects, pcp)
+	}
+	sort.Slice(resp.Projects, func(i, j int) bool {
+		a, b := resp.Projects[i], resp.Projects[j]
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		return a.Project < b.Project
+	})
+	return resp, nil
+}
+
+// costTurns calls fn with the phase, token count, and unrounded
+// cost of each turn among the sessions in ids that has at least
+// one agent message.
+func (db *DB) costTurns(
+	ctx context.Context, ids []string,
+	fn func(sessionID, phase string, tokens int, cost float64),
+) error {
+	return queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		tools, err := db.toolPhaseCounts(ctx, ph, chunkArgs)
+		if err != nil {
+			return err
+		}
+
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT id, session_id, role, model,
+				input_tokens, output_tokens,
+				cache_read_tokens, cache_creation_tokens
+			FROM messages
+			WHERE session_id IN `+ph+`
+			ORDER BY session_id, ordinal`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying turn messages: %w", err)
+		}
+		defer rows.Close()
+
+		var sid string
+		var agentMsgs, tokens int
+		var cost float64
+		counts := map[string]int{}
+		flush := func() {
+			if agentMsgs > 0 {
+				fn(sid, dominantPhase(counts), tokens, cost)
+			}
+			agentMsgs, tokens, cost = 0, 0, 0
+			clear(counts)
+		}
+		for rows.Next() {
+			var id int64
+			var msgSID, role, model string
+			var t TokenTotals
+			if err := rows.Scan(
+				&id, &msgSID, &role, &model,
+				&t.InputTokens, &t.OutputTokens,
+				&t.CacheReadTokens, &t.CacheCreationTokens,
+			); err != nil {
+				return fmt.Errorf("scanning turn message: %w", err)
+			}
+			if msgSID != sid || role == "user" {
+				flush()
+				sid = msgSID
+			}
+			if role == "user" {
+				continue
+			}
+			agentMsgs++
+			tokens += t.total()
+			cost += pricing.Cost(model, pricing.Usage{
+				Input:      t.InputTokens,
+				Output:     t.OutputTokens,
+				CacheRead:  t.CacheReadTokens,
+				CacheWrite: t.CacheCreationTokens,
+			})
+			for phase, n := range tools[id] {
+				counts[phase] += n
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		flush()
+		return nil
+	})
+}
+
+// toolPhaseCounts returns tool call counts by message ID, then
+// phase, for the sessions matched by the IN placeholder list ph.
+func (db *DB) toolPhaseCounts(
+	ctx context.Context, ph string, args []any,
+) (map[int64]map[string]int, error) {
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT message_id, category, COUNT(*)
+		FROM tool_calls
+		WHERE session_id IN `+ph+`
+		GROUP BY message_id, category`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying turn tool calls: %w", err)
+	}
+	defer rows.Close()
+	out := map[int64]map[string]int{}
+	for rows.Next() {
+		var id int64
+		var category string
+		var n int
+		if err := rows.Scan(&id, &category, &n); err != nil {
+			return nil, fmt.Errorf("scanning turn tool call: %w", err)
+		}
+		if out[id] == nil {
+			out[id] = map[string]int{}
+		}
+		out[id][categoryPhase(category)] += n
+	}
+	return out, rows.Err()
+}
+
+// dominantPhase returns the phase with the most tool calls, or
+// PhaseDiscussion when there are none.
+func dominantPhase(counts map[string]int) string {
+	best, bestN := PhaseDiscussion, 0
+	for _, phase := range costPhases {
+		if n := counts[phase]; n > bestN {
+			best, bestN = phase, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetAnalyticsCostPhases(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	const ts = "2024-06-01T10:00:00Z"
+	priced := func(m Message, input, output int) Message {
+		m.Model = "claude-sonnet-4" // $3 in, $15 out per million
+		m.InputTokens = input
+		m.OutputTokens = output
+		return m
+	}
+	insertSession(t, d, "c1", "alpha", func(s *Session) {
+		s.StartedAt = Ptr(ts)
+	})
+	insertSession(t, d, "c2", "beta", func(s *Session) {
+		s.StartedAt = Ptr(ts)
+	})
+	insertMessages(t, d,
+		userMsg("c1", 0, "find the bug"),
+		priced(toolMsg("c1", 1, ts,
+			ToolCall{ToolName: "Read", Category: "Read"},
+			ToolCall{ToolName: "Grep", Category: "Grep"},
+		), 0, 1_000_000),
+		toolMsg("c1", 2, ts,
+			ToolCall{ToolName: "Edit", Category: "Edit"}),
+		userMsg("c1", 3, "now fix it"),
+		priced(toolMsg("c1", 4, ts,
+			ToolCall{ToolName: "Edit", Category: "Edit"},
+			ToolCall{ToolName: "Write", Category: "Write"},
+			ToolCall{ToolName: "Bash", Category: "Bash"},
+		), 0, 2_000_000),
+		userMsg("c1", 5, "thanks"),
+		priced(asstMsg("c1", 6, "you're welcome"), 1_000_000, 0),
+		userMsg("c2", 0, "run the tests"),
+		priced(toolMsg("c2", 1, ts,
+			ToolCall{ToolName: "Bash", Category: "Bash"},
+		), 0, 1_000_000),
+	)
+
+	resp, err := d.GetAnalyticsCostPhases(ctx, baseFilter())
+	requireNoError(t, err, "GetAnalyticsCostPhases")
+
+	assertEq(t, "Currency", resp.Currency, BaseCurrency)
+	assertEq(t, "Cost", resp.Cost, 63.0)
+	want := []PhaseCost{
+		{PhaseExploration, 1, 1_000_000, 15, 23.8},
+		{PhaseImplementation, 1, 2_000_000, 30, 47.6},
+		{PhaseVerification, 1, 1_000_000, 15, 23.8},
+		{PhaseOther, 0, 0, 0, 0},
+		{PhaseDiscussion, 1, 1_000_000, 3, 4.8},
+	}
+	if len(resp.Phases) != len(want) {
+		t.Fatalf("Phases = %+v", resp.Phases)
+	}
+	for i, w := range want {
+		assertEq(t, "Phases["+w.Phase+"]", resp.Phases[i], w)
+	}
+
+	if len(resp.Projects) != 2 {
+		t.Fatalf("Projects = %+v", resp.Projects)
+	}
+	alpha := resp.Projects[0]
+	assertEq(t, "Projects[0].Project", alpha.Project, "alpha")
+	assertEq(t, "Projects[0].Cost", alpha.Cost, 48.0)
+	assertEq(t, "alpha verification", alpha.Phases[2].Turns, 0)
+	assertEq(t, "alpha implementation share",
+		alpha.Phases[1].Share, 62.5)
+	assertEq(t, "Projects[1].Cost", resp.Projects[1].Cost, 15.0)
+}
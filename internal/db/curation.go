@@ -0,0 +1,315 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// Bulk curation actions applied to a set of sessions.
+const (
+	BulkActionTag       = "tag"
+	BulkActionUntag     = "untag"
+	BulkActionMute      = "mute"
+	BulkActionUnmute    = "unmute"
+	BulkActionArchive   = "archive"
+	BulkActionUnarchive = "unarchive"
+	BulkActionPin       = "pin"
+	BulkActionUnpin     = "unpin"
+)
+
+// MaxBulkSessions bounds how many sessions a single bulk
+// action may touch.
+const MaxBulkSessions = 50000
+
+// MaxTagLength bounds the length of a session tag.
+const MaxTagLength = 64
+
+// ErrBulkTooLarge is returned when a selection exceeds
+// MaxBulkSessions.
+var ErrBulkTooLarge = errors.New("too many sessions selected")
+
+// ErrBulkTokenMismatch is returned when a confirmation token
+// does not match the current selection, e.g. because sessions
+// were synced between preview and confirm.
+var ErrBulkTokenMismatch = errors.New(
+	"confirmation token does not match selection",
+)
+
+// BulkAction is a curation action and its argument.
+type BulkAction struct {
+	Action string
+	Tag    string // required for tag and untag
+}
+
+// Validate checks that the action is known and has the
+// arguments it needs.
+func (a BulkAction) Validate() error {
+	switch a.Action {
+	case BulkActionTag, BulkActionUntag:
+		if strings.TrimSpace(a.Tag) == "" {
+			return fmt.Errorf("%s requires a tag", a.Action)
+		}
+		if len(a.Tag) > MaxTagLength {
+			return fmt.Errorf(
+				"tag exceeds %d characters", MaxTagLength,
+			)
+		}
+	case BulkActionMute, BulkActionUnmute,
+		BulkActionArchive, BulkActionUnarchive,
+		BulkActionPin, BulkActionUnpin:
+	default:
+		return fmt.Errorf("unknown action %q", a.Action)
+	}
+	return nil
+}
+
+// MatchSessionIDs returns the IDs of sessions matching f, and
+// additionally containing a message matching the FTS query
+// when query is non-empty. Pagination fields are ignored.
+// Returns ErrBulkTooLarge past MaxBulkSessions.
+func (db *DB) MatchSessionIDs(
+	ctx context.Context, f SessionFilter, query string,
+) ([]string, error) {
+	where, args := buildSessionFilter(f)
+	if query != "" {
+		where += ` AND id IN (
+			SELECT m.session_id FROM messages_fts
+			JOIN messages m ON messages_fts.rowid = m.id
+			WHERE messages_fts MATCH ?)`
+		args = append(args, query)
+	}
+	args = append(args, MaxBulkSessions+1)
+
+	rows, err := db.getReader().QueryContext(ctx,
+		"SELECT id FROM sessions WHERE "+where+
+			" ORDER BY id LIMIT ?",
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("matching sessions: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scanning session id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) > MaxBulkSessions {
+		return nil, ErrBulkTooLarge
+	}
+	return ids, nil
+}
+
+// BulkToken returns a confirmation token binding action a to
+// the exact selection ids (which must be sorted, as returned
+// by MatchSessionIDs). Signed with the cursor secret.
+func (db *DB) BulkToken(a BulkAction, ids []string) string {
+	return base64.RawURLEncoding.EncodeToString(
+		db.sign(bulkInput(a, ids)),
+	)
+}
+
+// bulkInput is the signing input for a bulk confirmation
+// token.
+func bulkInput(a BulkAction, ids []string) func(hash.Hash) {
+	return func(h hash.Hash) {
+		h.Write([]byte("bulk\x00" + a.Action + "\x00" + a.Tag))
+		for _, id := range ids {
+			h.Write([]byte("\x00" + id))
+		}
+	}
+}
+
+// CheckBulkToken verifies token against action a and ids.
+func (db *DB) CheckBulkToken(
+	a BulkAction, ids []string, token string,
+) error {
+	sig, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !db.verify(sig, bulkInput(a, ids)) {
+		return ErrBulkTokenMismatch
+	}
+	return nil
+}
+
+// ApplyBulkAction applies a to every session in ids in a
+// single transaction.
+func (db *DB) ApplyBulkAction(
+	ctx context.Context, a BulkAction, ids []string,
+) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	var stmt string
+	switch a.Action {
+	case BulkActionTag:
+		stmt = `INSERT OR IGNORE INTO session_tags
+			(session_id, tag) VALUES (?, ?)`
+	case BulkActionUntag:
+		stmt = `DELETE FROM session_tags
+			WHERE session_id = ? AND tag = ?`
+	default:
+		col, val := "muted", 1
+		switch a.Action {
+		case BulkActionUnmute:
+			val = 0
+		case BulkActionArchive:
+			col = "archived"
+		case BulkActionUnarchive:
+			col, val = "archived", 0
+		case BulkActionPin:
+			col = "pinned"
+		case BulkActionUnpin:
+			col, val = "pinned", 0
+		}
+		stmt = fmt.Sprintf(`INSERT INTO session_flags
+			(session_id, %[1]s) VALUES (?, %[2]d)
+			ON CONFLICT(session_id) DO UPDATE SET
+				%[1]s = excluded.%[1]s,
+				updated_at = strftime('%%Y-%%m-%%dT%%H:%%M:%%fZ','now')`,
+			col, val,
+		)
+	}
+
+	return db.writePriority(func() error {
+		tx, err := db.getWriter().BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin bulk tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		prepared, err := tx.PrepareContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("preparing bulk %s: %w", a.Action, err)
+		}
+		defer prepared.Close()
+
+		tag := strings.TrimSpace(a.Tag)
+		for _, id := range ids {
+			args := []any{id}
+			if a.Action == BulkActionTag || a.Action == BulkActionUntag {
+				args = append(args, tag)
+			}
+			if _, err := prepared.ExecContext(ctx, args...); err != nil {
+				return fmt.Errorf("bulk %s %s: %w", a.Action, id, err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing bulk %s: %w", a.Action, err)
+		}
+		return nil
+	})
+}
+
+// GetSessionTags returns the tags on a session, sorted.
+func (db *DB) GetSessionTags(
+	ctx context.Context, sessionID string,
+) ([]string, error) {
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT tag FROM session_tags
+		WHERE session_id = ? ORDER BY tag`, sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying session tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("scanning tag: %w", err)
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+// TagCount is a tag and how many sessions carry it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// ListTags returns every tag in use with its session count,
+// sorted by tag.
+func (db *DB) ListTags(ctx context.Context) ([]TagCount, error) {
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT tag, COUNT(*) FROM session_tags
+		GROUP BY tag ORDER BY tag`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []TagCount{}
+	for rows.Next() {
+		var tc TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, fmt.Errorf("scanning tag count: %w", err)
+		}
+		tags = append(tags, tc)
+	}
+	return tags, rows.Err()
+}
+
+// CopyCurationFrom copies session tags, flags, and notes from
+// the database at sourcePath. Used during resync so user curation
+// survives the swap.
+func (db *DB) CopyCurationFrom(sourcePath string) error {
+	return db.write(func() error {
+		// ATTACH is connection-scoped; pin one connection.
+		ctx := context.Background()
+		conn, err := db.getWriter().Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("acquiring connection: %w", err)
+		}
+		defer conn.Close()
+
+		if _, err := conn.ExecContext(
+			ctx, "ATTACH DATABASE ? AS old_db", sourcePath,
+		); err != nil {
+			return fmt.Errorf("attaching source db: %w", err)
+		}
+		defer func() {
+			_, _ = conn.ExecContext(
+				ctx, "DETACH DATABASE old_db",
+			)
+		}()
+
+		if _, err := conn.ExecContext(ctx, `
+			INSERT OR IGNORE INTO session_tags
+				(session_id, tag, created_at)
+			SELECT session_id, tag, created_at
+			FROM old_db.session_tags`); err != nil {
+			return fmt.Errorf("copying session tags: %w", err)
+		}
+		if _, err := conn.ExecContext(ctx, `
+			INSERT OR IGNORE INTO session_flags
+				(session_id, muted, archived, pinned, updated_at)
+			SELECT session_id, muted, archived, pinned, updated_at
+			FROM old_db.session_flags`); err != nil {
+			return fmt.Errorf("copying session flags: %w", err)
+		}
+		if _, err := conn.ExecContext(ctx, `
+			INSERT OR IGNORE INTO session_notes
+				(id, session_id, ordinal, body, created_at, updated_at)
+			SELECT id, session_id, ordinal, body, created_at, updated_at
+			FROM old_db.session_notes`); err != nil {
+			return fmt.Errorf("copying session notes: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,344 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestBulkAction_Validate(t *testing.T) {
+	tests := []struct {
+		action  BulkAction
+		wantErr bool
+	}{
+		{BulkAction{Action: BulkActionTag, Tag: "refactor"}, false},
+		{BulkAction{Action: BulkActionTag, Tag: "  "}, true},
+		{BulkAction{Action: BulkActionUntag}, true},
+		{BulkAction{Action: BulkActionMute}, false},
+		{BulkAction{Action: BulkActionUnarchive}, false},
+		{BulkAction{Action: BulkActionPin}, false},
+		{BulkAction{Action: "delete"}, true},
+	}
+	for _, tt := range tests {
+		err := tt.action.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) err = %v, wantErr %v",
+				tt.action, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMatchSessionIDs(t *testing.T) {
+	d := testDB(t)
+	requireFTS(t, d)
+	ctx := context.Background()
+
+	insertSession(t, d, "a1", "alpha")
+	insertSession(t, d, "a2", "alpha")
+	insertSession(t, d, "b1", "beta")
+	insertMessages(t, d,
+		userMsg("a1", 0, "fix the flaky login test"),
+		userMsg("a2", 0, "write docs"),
+		userMsg("b1", 0, "login page redesign"),
+	)
+
+	tests := []struct {
+		name  string
+		f     SessionFilter
+		query string
+		want  []string
+	}{
+		{"all", SessionFilter{}, "", []string{"a1", "a2", "b1"}},
+		{"project", SessionFilter{Project: "alpha"}, "", []string{"a1", "a2"}},
+		{"query", SessionFilter{}, "login", []string{"a1", "b1"}},
+		{"project and query", SessionFilter{Project: "alpha"}, "login", []string{"a1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.MatchSessionIDs(ctx, tt.f, tt.query)
+			requireNoError(t, err, "MatchSessionIDs")
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulkToken(t *testing.T) {
+	d := testDB(t)
+	tag := BulkAction{Action: BulkActionTag, Tag: "x"}
+	ids := []string{"a", "b"}
+	token := d.BulkToken(tag, ids)
+
+	requireNoError(t, d.CheckBulkToken(tag, ids, token), "same selection")
+
+	mismatches := []struct {
+		name   string
+		action BulkAction
+		ids    []string
+	}{
+		{"different tag", BulkAction{Action: BulkActionTag, Tag: "y"}, ids},
+		{"different action", BulkAction{Action: BulkActionArchive}, ids},
+		{"selection grew", tag, []string{"a", "b", "c"}},
+	}
+	for _, tt := range mismatches {
+		err := d.CheckBulkToken(tt.action, tt.ids, token)
+		if !errors.Is(err, ErrBulkTokenMismatch) {
+			t.Errorf("%s: err = %v, want ErrBulkTokenMismatch",
+				tt.name, err)
+		}
+	}
+}
+
+func TestApplyBulkAction(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	for _, id := range []string{"s1", "s2", "s3"} {
+		insertSession(t, d, id, "proj")
+	}
+	apply := func(a BulkAction, ids ...string) {
+		t.Helper()
+		requireNoError(t, d.ApplyBulkAction(ctx, a, ids), a.Action)
+	}
+	listIDs := func(f SessionFilter) []string {
+		t.Helper()
+		page, err := d.ListSessions(ctx, f)
+		requireNoError(t, err, "ListSessions")
+		var ids []string
+		for _, s := range page.Sessions {
+			ids = append(ids, s.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	apply(BulkAction{Action: BulkActionTag, Tag: "bugfix"}, "s1", "s2")
+	apply(BulkAction{Action: BulkActionTag, Tag: "bugfix"}, "s1")
+	tags, err := d.GetSessionTags(ctx, "s1")
+	requireNoError(t, err, "GetSessionTags")
+	if !slices.Equal(tags, []string{"bugfix"}) {
+		t.Errorf("tags = %v, want [bugfix]", tags)
+	}
+	if got := listIDs(SessionFilter{Tag: "bugfix"}); !slices.Equal(got, []string{"s1", "s2"}) {
+		t.Errorf("tagged = %v, want [s1 s2]", got)
+	}
+
+	apply(BulkAction{Action: BulkActionUntag, Tag: "bugfix"}, "s2")
+	if got := listIDs(SessionFilter{Tag: "bugfix"}); !slices.Equal(got, []string{"s1"}) {
+		t.Errorf("after untag = %v, want [s1]", got)
+	}
+
+	apply(BulkAction{Action: BulkActionArchive}, "s3")
+	if got := listIDs(SessionFilter{}); !slices.Equal(got, []string{"s1", "s2"}) {
+		t.Errorf("default list = %v, want [s1 s2]", got)
+	}
+	if got := listIDs(SessionFilter{IncludeArchived: true}); len(got) != 3 {
+		t.Errorf("include archived = %v, want 3 sessions", got)
+	}
+	if got := listIDs(SessionFilter{Archived: true}); !slices.Equal(got, []string{"s3"}) {
+		t.Errorf("archived only = %v, want [s3]", got)
+	}
+	s3, err := d.GetSession(ctx, "s3")
+	requireNoError(t, err, "GetSession")
+	if !s3.Archived {
+		t.Error("s3 not marked archived")
+	}
+
+	apply(BulkAction{Action: BulkActionPin}, "s2")
+	if got := listIDs(SessionFilter{Pinned: true}); !slices.Equal(got, []string{"s2"}) {
+		t.Errorf("pinned only = %v, want [s2]", got)
+	}
+
+	// Muting leaves the archive flag alone.
+	apply(BulkAction{Action: BulkActionMute}, "s3")
+	apply(BulkAction{Action: BulkActionUnarchive}, "s3")
+	var muted, archived int
+	requireNoError(t, d.getReader().QueryRow(
+		"SELECT muted, archived FROM session_flags WHERE session_id = 's3'",
+	).Scan(&muted, &archived), "read flags")
+	if muted != 1 || archived != 0 {
+		t.Errorf("flags = muted %d archived %d, want 1 0",
+			muted, archived)
+	}
+}
+
+func TestListSessionsPinnedFirst(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	// s1 is the oldest session, s5 the newest.
+	for i, id := range []string{"s1", "s2", "s3", "s4", "s5"} {
+		insertSession(t, d, id, "proj", func(s *Session) {
+			s.EndedAt = Ptr(fmt.Sprintf("2024-06-0%dT00:00:00Z", i+1))
+		})
+	}
+	requireNoError(t, d.ApplyBulkAction(ctx,
+		BulkAction{Action: BulkActionPin}, []string{"s1", "s3"},
+	), "pin")
+
+	// Page through two at a time so the cursor crosses from the
+	// pinned run into the unpinned one.
+	var ids []string
+	var pinned []bool
+	f := SessionFilter{Limit: 2}
+	for {
+		page, err := d.ListSessions(ctx, f)
+		requireNoError(t, err, "ListSessions")
+		if page.Total != 5 {
+			t.Errorf("Total = %d, want 5", page.Total)
+		}
+		for _, s := range page.Sessions {
+			ids = append(ids, s.ID)
+			pinned = append(pinned, s.Pinned)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		f.Cursor = page.NextCursor
+	}
+	if want := []string{"s3", "s1", "s5", "s4", "s2"}; !slices.Equal(ids, want) {
+		t.Errorf("order = %v, want %v", ids, want)
+	}
+	if want := []bool{true, true, false, false, false}; !slices.Equal(pinned, want) {
+		t.Errorf("pinned = %v, want %v", pinned, want)
+	}
+
+	requireNoError(t, d.ApplyBulkAction(ctx,
+		BulkAction{Action: BulkActionUnpin}, []string{"s3"},
+	), "unpin")
+	s, err := d.GetSession(ctx, "s3")
+	requireNoError(t, err, "GetSession")
+	if s.Pinned {
+		t.Error("s3 still pinned after unpin")
+	}
+}
+
+func TestMutedSessionsExcludedFromAnalytics(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	stats := seedAnalyticsData(t, d)
+
+	f := baseFilter()
+	ids, err := d.MatchSessionIDs(ctx, SessionFilter{}, "")
+	requireNoError(t, err, "MatchSessionIDs")
+	requireNoError(t, d.ApplyBulkAction(ctx,
+		BulkAction{Action: BulkActionMute}, ids[:1],
+	), "mute")
+
+	s := mustSummary(t, d, ctx, f)
+	if s.TotalSessions != stats.TotalSessions-1 {
+		t.Errorf("TotalSessions = %d, want %d",
+			s.TotalSessions, stats.TotalSessions-1)
+	}
+}
+
+func TestArchivedSessionsExcludedFromAnalytics(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	stats := seedAnalyticsData(t, d)
+
+	ids, err := d.MatchSessionIDs(ctx, SessionFilter{}, "")
+	requireNoError(t, err, "MatchSessionIDs")
+	requireNoError(t, d.ApplyBulkAction(ctx,
+		BulkAction{Action: BulkActionArchive}, ids[:1],
+	), "archive")
+
+	f := baseFilter()
+	if s := mustSummary(t, d, ctx, f); s.TotalSessions != stats.TotalSessions-1 {
+		t.Errorf("TotalSessions = %d, want %d",
+			s.TotalSessions, stats.TotalSessions-1)
+	}
+	f.IncludeArchived = true
+	if s := mustSummary(t, d, ctx, f); s.TotalSessions != stats.TotalSessions {
+		t.Errorf("with archived TotalSessions = %d, want %d",
+			s.TotalSessions, stats.TotalSessions)
+	}
+}
+
+func TestTaggedSessionsFilterAnalytics(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	stats := seedAnalyticsData(t, d)
+
+	ids, err := d.MatchSessionIDs(ctx, SessionFilter{}, "")
+	requireNoError(t, err, "MatchSessionIDs")
+	requireNoError(t, d.ApplyBulkAction(ctx,
+		BulkAction{Action: BulkActionTag, Tag: "refactor"}, ids[:2],
+	), "tag")
+	requireNoError(t, d.ApplyBulkAction(ctx,
+		BulkAction{Action: BulkActionTag, Tag: "bugfix"}, ids[:1],
+	), "tag")
+
+	f := baseFilter()
+	f.Tag = "refactor"
+	if s := mustSummary(t, d, ctx, f); s.TotalSessions != 2 {
+		t.Errorf("tagged TotalSessions = %d, want 2 of %d",
+			s.TotalSessions, stats.TotalSessions)
+	}
+
+	tags, err := d.ListTags(ctx)
+	requireNoError(t, err, "ListTags")
+	want := []TagCount{{"bugfix", 1}, {"refactor", 2}}
+	if !slices.Equal(tags, want) {
+		t.Errorf("ListTags = %v, want %v", tags, want)
+	}
+
+	f.Tag = "nonexistent"
+	fields, err := d.UnmatchedFilterFields(ctx, f)
+	requireNoError(t, err, "UnmatchedFilterFields")
+	if !slices.Equal(fields, []string{"tag"}) {
+		t.Errorf("unmatched = %v, want [tag]", fields)
+	}
+}
+
+func TestCopyCurationFrom(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	srcPath := filepath.Join(dir, "src.db")
+	srcDB, err := Open(srcPath)
+	requireNoError(t, err, "Open src")
+	requireNoError(t, srcDB.ApplyBulkAction(ctx,
+		BulkAction{Action: BulkActionTag, Tag: "keep"}, []string{"s1"},
+	), "tag")
+	requireNoError(t, srcDB.ApplyBulkAction(ctx,
+		BulkAction{Action: BulkActionArchive}, []string{"s1"},
+	), "archive")
+	_, err = srcDB.AddSessionNote(ctx, "s1", nil, "keep this note")
+	requireNoError(t, err, "AddSessionNote")
+	srcDB.Close()
+
+	dstDB, err := Open(filepath.Join(dir, "dst.db"))
+	requireNoError(t, err, "Open dst")
+	defer dstDB.Close()
+
+	requireNoError(t,
+		dstDB.CopyCurationFrom(srcPath), "CopyCurationFrom",
+	)
+	tags, err := dstDB.GetSessionTags(ctx, "s1")
+	requireNoError(t, err, "GetSessionTags")
+	if !slices.Equal(tags, []string{"keep"}) {
+		t.Errorf("tags = %v, want [keep]", tags)
+	}
+	var archived int
+	requireNoError(t, dstDB.getReader().QueryRow(
+		"SELECT archived FROM session_flags WHERE session_id = 's1'",
+	).Scan(&archived), "read flags")
+	if archived != 1 {
+		t.Errorf("archived = %d, want 1", archived)
+	}
+	notes, err := dstDB.ListSessionNotes(ctx, "s1")
+	requireNoError(t, err, "ListSessionNotes")
+	if len(notes) != 1 || notes[0].Body != "keep this note" {
+		t.Errorf("notes = %+v, want the copied note", notes)
+	}
+	if dstDB.HasFTS() {
+		hits, err := dstDB.SearchNotes(ctx, "keep", 10)
+		requireNoError(t, err, "SearchNotes")
+		if len(hits) != 1 {
+			t.Errorf("copied note not searchable: %+v", hits)
+		}
+	}
+}
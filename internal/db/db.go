@@ -1,18 +1,22 @@
 package db
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	_ "embed"
 	"errors"
 	"fmt"
-	"log"
+	"hash"
+	"log/slog"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -23,7 +27,7 @@ import (
 // formatting changes). Old databases with a lower user_version
 // trigger a non-destructive re-sync (mtime reset + skip cache
 // clear) so existing session data is preserved.
-const dataVersion = 2
+const dataVersion = 22
 
 //go:embed schema.sql
 var schemaSQL string
@@ -50,6 +54,28 @@ CREATE TRIGGER IF NOT EXISTS messages_au AFTER UPDATE ON messages BEGIN
         VALUES('delete', old.id, old.content);
     INSERT INTO messages_fts(rowid, content) VALUES (new.id, new.content);
 END;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS session_notes_fts USING fts5(
+    body,
+    content='session_notes',
+    content_rowid='id',
+    tokenize='porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS session_notes_ai AFTER INSERT ON session_notes BEGIN
+    INSERT INTO session_notes_fts(rowid, body) VALUES (new.id, new.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_notes_ad AFTER DELETE ON session_notes BEGIN
+    INSERT INTO session_notes_fts(session_notes_fts, rowid, body)
+        VALUES('delete', old.id, old.body);
+END;
+
+CREATE TRIGGER IF NOT EXISTS session_notes_au AFTER UPDATE ON session_notes BEGIN
+    INSERT INTO session_notes_fts(session_notes_fts, rowid, body)
+        VALUES('delete', old.id, old.body);
+    INSERT INTO session_notes_fts(rowid, body) VALUES (new.id, new.body);
+END;
 `
 
 // DB manages a write connection and a read-only pool.
@@ -60,12 +86,17 @@ type DB struct {
 	path      string
 	writer    atomic.Pointer[sql.DB]
 	reader    atomic.Pointer[sql.DB]
-	mu        sync.Mutex // serializes writes
-	retired   []*sql.DB  // old pools kept open for in-flight reads
-	dataStale bool       // set by Open when user_version < dataVersion
+	writes    *writeQueue     // serializes writes
+	retired   []*sql.DB       // old pools; only touched by writes
+	dataStale bool            // set by Open when user_version < dataVersion
+	recovery  *RecoveryReport // set by Open after salvaging a corrupt file
 
 	cursorMu     sync.RWMutex
 	cursorSecret []byte
+	// prevSecret still verifies cursors and tokens signed
+	// before a rotation, until prevSecretUntil.
+	prevSecret      []byte
+	prevSecretUntil time.Time
 }
 
 // getReader returns the current read-only connection pool.
@@ -86,6 +117,46 @@ func (db *DB) SetCursorSecret(secret []byte) {
 	db.cursorSecret = append([]byte(nil), secret...)
 }
 
+// SetPreviousCursorSecret sets a retired secret that is still
+// accepted when verifying cursors and tokens until until, so
+// open clients keep paginating across a rotation. Signing
+// always uses the current secret.
+func (db *DB) SetPreviousCursorSecret(secret []byte, until time.Time) {
+	db.cursorMu.Lock()
+	defer db.cursorMu.Unlock()
+	db.prevSecret = append([]byte(nil), secret...)
+	db.prevSecretUntil = until
+}
+
+// sign returns the HMAC of what write writes, keyed with the
+// current cursor secret.
+func (db *DB) sign(write func(hash.Hash)) []byte {
+	db.cursorMu.RLock()
+	mac := hmac.New(sha256.New, db.cursorSecret)
+	db.cursorMu.RUnlock()
+
+	write(mac)
+	return mac.Sum(nil)
+}
+
+// verify reports whether sig is the HMAC of what write writes
+// under the current cursor secret, or under the previous one
+// during its grace period.
+func (db *DB) verify(sig []byte, write func(hash.Hash)) bool {
+	if hmac.Equal(sig, db.sign(write)) {
+		return true
+	}
+	db.cursorMu.RLock()
+	prev, until := db.prevSecret, db.prevSecretUntil
+	db.cursorMu.RUnlock()
+	if len(prev) == 0 || !time.Now().Before(until) {
+		return false
+	}
+	mac := hmac.New(sha256.New, prev)
+	write(mac)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
 // makeDSN builds a SQLite connection string with shared pragmas.
 func makeDSN(path string, readOnly bool) string {
 	params := url.Values{}
@@ -111,13 +182,39 @@ func makeDSN(path string, readOnly bool) string {
 // If the schema is current but the data version is stale,
 // the database is preserved and file mtimes are reset to
 // trigger a re-sync on the next cycle.
+//
+// If SQLite reports the file as malformed, it is moved aside,
+// readable rows are salvaged into a fresh file, and the result
+// is marked stale so a full resync restores the rest from
+// source files. Recovery returns the salvage report.
 func Open(path string) (*DB, error) {
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, fmt.Errorf("creating db directory: %w", err)
 	}
 
+	var recovery *RecoveryReport
+	recoverCorrupt := func(cause error) error {
+		slog.Error("database is corrupt; attempting recovery",
+			"path", path, "err", cause)
+		rep, err := recoverDatabase(path)
+		if err != nil {
+			return fmt.Errorf(
+				"recovering corrupt database (%v): %w", cause, err,
+			)
+		}
+		slog.Warn("database recovered", "summary", rep.Summary())
+		recovery = rep
+		return nil
+	}
+
 	schemaStale, dataStale, err := probeDatabase(path)
+	if err != nil && isCorruptErr(err) {
+		if err = recoverCorrupt(err); err != nil {
+			return nil, err
+		}
+		schemaStale, dataStale = false, true
+	}
 	if err != nil {
 		return nil, fmt.Errorf("checking schema: %w", err)
 	}
@@ -130,15 +227,21 @@ func Open(path string) (*DB, error) {
 	}
 
 	d, err := openAndInit(path)
+	if err != nil && recovery == nil && isCorruptErr(err) {
+		if err = recoverCorrupt(err); err != nil {
+			return nil, err
+		}
+		dataStale = true
+		d, err = openAndInit(path)
+	}
 	if err != nil {
 		return nil, err
 	}
+	d.recovery = recovery
 
 	if dataStale && !schemaStale {
 		d.dataStale = true
-		log.Printf(
-			"data version outdated; full resync required",
-		)
+		slog.Warn("data version outdated; full resync required")
 	} else {
 		// Only stamp user_version when data is current.
 		// When data is stale, preserve the old version so
@@ -280,12 +383,13 @@ func openAndInit(path string) (*DB, error) {
 	}
 	reader.SetMaxOpenConns(4)
 
-	db := &DB{path: path}
+	db := &DB{path: path, writes: newWriteQueue()}
 	db.writer.Store(writer)
 	db.reader.Store(reader)
 
 	db.cursorSecret = make([]byte, 32)
 	if _, err := rand.Read(db.cursorSecret); err != nil {
+		db.writes.stop()
 		writer.Close()
 		reader.Close()
 		return nil, fmt.Errorf(
@@ -304,40 +408,40 @@ func openAndInit(path string) (*DB, error) {
 // bulk message delete+reinsert fast by avoiding per-row FTS
 // index updates. Call RebuildFTS after to restore search.
 func (db *DB) DropFTS() error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	stmts := []string{
-		"DROP TRIGGER IF EXISTS messages_ai",
-		"DROP TRIGGER IF EXISTS messages_ad",
-		"DROP TRIGGER IF EXISTS messages_au",
-		"DROP TABLE IF EXISTS messages_fts",
-	}
-	w := db.getWriter()
-	for _, s := range stmts {
-		if _, err := w.Exec(s); err != nil {
-			return fmt.Errorf("drop fts (%s): %w", s, err)
+	return db.write(func() error {
+		stmts := []string{
+			"DROP TRIGGER IF EXISTS messages_ai",
+			"DROP TRIGGER IF EXISTS messages_ad",
+			"DROP TRIGGER IF EXISTS messages_au",
+			"DROP TABLE IF EXISTS messages_fts",
 		}
-	}
-	return nil
+		w := db.getWriter()
+		for _, s := range stmts {
+			if _, err := w.Exec(s); err != nil {
+				return fmt.Errorf("drop fts (%s): %w", s, err)
+			}
+		}
+		return nil
+	})
 }
 
 // RebuildFTS recreates the FTS table, triggers, and
 // repopulates the index from the messages table.
 func (db *DB) RebuildFTS() error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	w := db.getWriter()
-	if _, err := w.Exec(schemaFTS); err != nil {
-		return fmt.Errorf("recreate fts: %w", err)
-	}
-	_, err := w.Exec(
-		"INSERT INTO messages_fts(messages_fts)" +
-			" VALUES('rebuild')",
-	)
-	if err != nil {
-		return fmt.Errorf("rebuild fts index: %w", err)
-	}
-	return nil
+	return db.write(func() error {
+		w := db.getWriter()
+		if _, err := w.Exec(schemaFTS); err != nil {
+			return fmt.Errorf("recreate fts: %w", err)
+		}
+		_, err := w.Exec(
+			"INSERT INTO messages_fts(messages_fts)" +
+				" VALUES('rebuild')",
+		)
+		if err != nil {
+			return fmt.Errorf("rebuild fts index: %w", err)
+		}
+		return nil
+	})
 }
 
 // HasFTS checks if Full Text Search is available.
@@ -357,9 +461,10 @@ func (db *DB) HasFTS() bool {
 // current (not stale), so the marker survives until
 // ResyncAll completes.
 func (db *DB) setDataVersion() error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
+	return db.write(db.setDataVersionLocked)
+}
 
+func (db *DB) setDataVersionLocked() error {
 	var current int
 	if err := db.getWriter().QueryRow(
 		"PRAGMA user_version",
@@ -380,39 +485,146 @@ func (db *DB) setDataVersion() error {
 }
 
 func (db *DB) init() error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
+	return db.write(db.initLocked)
+}
+
+func (db *DB) initLocked() error {
 	w := db.getWriter()
+	var hadTokenUsage int
+	if err := w.QueryRow(
+		"SELECT count(*) FROM sqlite_master" +
+			" WHERE type='table' AND name='token_usage'",
+	).Scan(&hadTokenUsage); err != nil {
+		return fmt.Errorf("checking token_usage table: %w", err)
+	}
 	if _, err := w.Exec(schemaSQL); err != nil {
 		return err
 	}
 
-	// Add result_content column to tool_calls if not present
-	// (non-destructive migration for existing databases).
-	var rcCount int
-	if err := w.QueryRow(
-		`SELECT count(*) FROM pragma_table_info('tool_calls')` +
-			` WHERE name = 'result_content'`,
-	).Scan(&rcCount); err != nil {
-		return fmt.Errorf("probing result_content column: %w", err)
+	// Non-destructive column migrations for existing databases.
+	if _, err := addColumnIfMissing(
+		w, "tool_calls", "result_content", "TEXT",
+	); err != nil {
+		return err
 	}
-	if rcCount == 0 {
-		if _, err := w.Exec(
-			`ALTER TABLE tool_calls ADD COLUMN result_content TEXT`,
+	if _, err := addColumnIfMissing(
+		w, "tool_calls", "result_error", "INTEGER NOT NULL DEFAULT 0",
+	); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(
+		w, "tool_calls", "result_truncated", "INTEGER NOT NULL DEFAULT 0",
+	); err != nil {
+		return err
+	}
+	if _, err := addColumnIfMissing(
+		w, "tool_calls", "result_original_length", "INTEGER",
+	); err != nil {
+		return err
+	}
+	addedCommand, err := addColumnIfMissing(
+		w, "tool_calls", "command", "TEXT",
+	)
+	if err != nil {
+		return err
+	}
+	addedFilePath, err := addColumnIfMissing(
+		w, "tool_calls", "file_path", "TEXT",
+	)
+	if err != nil {
+		return err
+	}
+	for _, col := range []struct{ name, decl string }{
+		{"model", "TEXT NOT NULL DEFAULT ''"},
+		{"model_switch", "TEXT NOT NULL DEFAULT ''"},
+		{"kind", "TEXT NOT NULL DEFAULT ''"},
+		{"input_tokens", "INTEGER NOT NULL DEFAULT 0"},
+		{"output_tokens", "INTEGER NOT NULL DEFAULT 0"},
+		{"cache_read_tokens", "INTEGER NOT NULL DEFAULT 0"},
+		{"cache_creation_tokens", "INTEGER NOT NULL DEFAULT 0"},
+		{"reasoning_tokens", "INTEGER NOT NULL DEFAULT 0"},
+		{"source_uuid", "TEXT NOT NULL DEFAULT ''"},
+		{"pasted_chars", "INTEGER NOT NULL DEFAULT 0"},
+		{"language", "TEXT NOT NULL DEFAULT ''"},
+	} {
+		if _, err := addColumnIfMissing(
+			w, "messages", col.name, col.decl,
 		); err != nil {
-			return fmt.Errorf("adding result_content column: %w", err)
+			return err
 		}
 	}
-
-	// Check if FTS table exists before trying to create it
-	var ftsCount int
-	if err := w.QueryRow(
-		"SELECT count(*) FROM sqlite_master" +
-			" WHERE type='table' AND name='messages_fts'",
-	).Scan(&ftsCount); err != nil {
-		return fmt.Errorf("checking fts table: %w", err)
+	for _, col := range []struct{ name, decl string }{
+		{"interrupt_count", "INTEGER NOT NULL DEFAULT 0"},
+		{"headless", "INTEGER NOT NULL DEFAULT 0"},
+		{"git_branch", "TEXT NOT NULL DEFAULT ''"},
+		{"worktree", "TEXT NOT NULL DEFAULT ''"},
+		{"entry_point", "TEXT NOT NULL DEFAULT ''"},
+		{"client", "TEXT NOT NULL DEFAULT ''"},
+		{"quality_score", "INTEGER"},
+		{"language", "TEXT NOT NULL DEFAULT ''"},
+	} {
+		if _, err := addColumnIfMissing(
+			w, "sessions", col.name, col.decl,
+		); err != nil {
+			return err
+		}
+	}
+	if _, err := addColumnIfMissing(
+		w, "session_flags", "pinned", "INTEGER NOT NULL DEFAULT 0",
+	); err != nil {
+		return err
+	}
+	if hadTokenUsage == 0 {
+		if _, err := w.Exec(backfillTokenUsage); err != nil {
+			return fmt.Errorf("backfilling token_usage: %w", err)
+		}
+	}
+	if addedFilePath {
+		if _, err := w.Exec(backfillToolCallFilePaths); err != nil {
+			return fmt.Errorf("backfilling file_path: %w", err)
+		}
+	}
+	if _, err := w.Exec(
+		`CREATE INDEX IF NOT EXISTS idx_tool_calls_file_path
+			ON tool_calls(file_path)
+			WHERE file_path IS NOT NULL`,
+	); err != nil {
+		return fmt.Errorf("creating file_path index: %w", err)
+	}
+	if addedCommand {
+		if err := backfillToolCallCommands(w); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Exec(
+		`CREATE INDEX IF NOT EXISTS idx_tool_calls_command
+			ON tool_calls(command)
+			WHERE command IS NOT NULL`,
+	); err != nil {
+		return fmt.Errorf("creating command index: %w", err)
+	}
+	if _, err := w.Exec(
+		`CREATE INDEX IF NOT EXISTS idx_messages_source_uuid
+			ON messages(session_id, source_uuid)
+			WHERE source_uuid != ''`,
+	); err != nil {
+		return fmt.Errorf("creating source_uuid index: %w", err)
+	}
+
+	// Check which FTS tables exist before trying to create them
+	var missing []string
+	for _, table := range []string{"messages_fts", "session_notes_fts"} {
+		var ftsCount int
+		if err := w.QueryRow(
+			"SELECT count(*) FROM sqlite_master"+
+				" WHERE type='table' AND name=?", table,
+		).Scan(&ftsCount); err != nil {
+			return fmt.Errorf("checking fts table: %w", err)
+		}
+		if ftsCount == 0 {
+			missing = append(missing, table)
+		}
 	}
-	hadFTS := ftsCount > 0
 
 	// Attempt to initialize FTS. Failure is non-fatal
 	// (might be missing module).
@@ -422,11 +634,13 @@ func (db *DB) init() error {
 		) {
 			return fmt.Errorf("initializing FTS: %w", err)
 		}
-	} else if !hadFTS {
-		// Schema init succeeded and we didn't have FTS
-		// before. Populate the index for existing messages.
+		return nil
+	}
+	// Populate indexes that did not exist before from their
+	// existing rows.
+	for _, table := range missing {
 		if _, err := w.Exec(
-			"INSERT INTO messages_fts(messages_fts)" +
+			"INSERT INTO " + table + "(" + table + ")" +
 				" VALUES('rebuild')",
 		); err != nil {
 			return fmt.Errorf("backfilling FTS: %w", err)
@@ -436,15 +650,45 @@ func (db *DB) init() error {
 	return nil
 }
 
+// addColumnIfMissing runs ALTER TABLE ADD COLUMN when column is
+// absent from table. Reports whether the column was added so
+// callers can backfill it.
+func addColumnIfMissing(
+	w *sql.DB, table, column, decl string,
+) (bool, error) {
+	var count int
+	if err := w.QueryRow(
+		"SELECT count(*) FROM pragma_table_info(?)"+
+			" WHERE name = ?",
+		table, column,
+	).Scan(&count); err != nil {
+		return false, fmt.Errorf(
+			"probing %s column: %w", column, err,
+		)
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if _, err := w.Exec(fmt.Sprintf(
+		"ALTER TABLE %s ADD COLUMN %s %s", table, column, decl,
+	)); err != nil {
+		return false, fmt.Errorf(
+			"adding %s column: %w", column, err,
+		)
+	}
+	return true, nil
+}
+
 // Close closes both writer and reader connections, plus any
 // retired pools left over from previous Reopen calls.
 func (db *DB) Close() error {
-	db.mu.Lock()
+	// Stopping the queue runs pending writes first; after it
+	// returns, nothing else touches the writer or retired.
+	db.writes.stop()
 	w := db.getWriter()
 	r := db.getReader()
 	retired := db.retired
 	db.retired = nil
-	db.mu.Unlock()
 
 	errs := []error{w.Close(), r.Close()}
 	for _, p := range retired {
@@ -458,31 +702,28 @@ func (db *DB) Close() error {
 // Also drains any retired pools from previous Reopen calls.
 // Callers must call Reopen afterwards to restore service.
 func (db *DB) CloseConnections() error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-
-	errs := []error{
-		db.getWriter().Close(),
-		db.getReader().Close(),
-	}
-	for _, p := range db.retired {
-		errs = append(errs, p.Close())
-	}
-	db.retired = nil
-	return errors.Join(errs...)
+	return db.writePriority(func() error {
+		errs := []error{
+			db.getWriter().Close(),
+			db.getReader().Close(),
+		}
+		for _, p := range db.retired {
+			errs = append(errs, p.Close())
+		}
+		db.retired = nil
+		return errors.Join(errs...)
+	})
 }
 
 // Reopen closes and reopens both connections to the same
 // path. Used after an atomic file swap to pick up the new
 // database contents. Preserves cursorSecret.
 func (db *DB) Reopen() error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	return db.reopenLocked()
+	return db.writePriority(db.reopenLocked)
 }
 
-// reopenLocked performs the reopen while db.mu is already
-// held. New connections are opened before closing old ones
+// reopenLocked performs the reopen on the writer goroutine.
+// New connections are opened before closing old ones
 // so the struct never points at closed handles on failure.
 func (db *DB) reopenLocked() error {
 	writer, err := sql.Open(
@@ -507,9 +748,7 @@ func (db *DB) reopenLocked() error {
 	// in-flight queries on them have long since completed.
 	for _, p := range db.retired {
 		if err := p.Close(); err != nil {
-			log.Printf(
-				"warning: closing retired db pool: %v", err,
-			)
+			slog.Warn("closing retired db pool", "err", err)
 		}
 	}
 	db.retired = db.retired[:0]
@@ -525,23 +764,22 @@ func (db *DB) reopenLocked() error {
 	return nil
 }
 
-// Update executes fn within a write lock and transaction.
-// The transaction is committed if fn returns nil, rolled back
-// otherwise.
+// Update executes fn on the writer goroutine within a
+// transaction. The transaction is committed if fn returns nil,
+// rolled back otherwise.
 func (db *DB) Update(fn func(tx *sql.Tx) error) error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-
-	tx, err := db.getWriter().Begin()
-	if err != nil {
-		return fmt.Errorf("beginning transaction: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning transaction: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
 
-	if err := fn(tx); err != nil {
-		return err
-	}
-	return tx.Commit()
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
 }
 
 // Reader returns the read-only connection pool.
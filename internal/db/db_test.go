@@ -12,10 +12,12 @@ import (
 	"path/filepath"
 	"runtime"
 	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -985,6 +987,39 @@ func TestListSessionsProjectFilter(t *testing.T) {
 	}), 2)
 }
 
+func TestListSessionsQueryFilter(t *testing.T) {
+	d := testDB(t)
+
+	for id, first := range map[string]string{
+		"s1": "Fix the flaky login test",
+		"s2": "Add a login page",
+		"s3": "Refactor 100% of the parser",
+	} {
+		insertSession(t, d, id, "p", func(s *Session) {
+			s.FirstMessage = Ptr(first)
+		})
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"login", 2},
+		{"LOGIN flaky", 1},
+		{"flaky page", 0},
+		{"100%", 1},
+		{"0%_", 0},
+		{"  ", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			requireCount(t, d, filterWith(func(f *SessionFilter) {
+				f.Query = tt.query
+			}), tt.want)
+		})
+	}
+}
+
 func TestMessageCRUD(t *testing.T) {
 	d := testDB(t)
 
@@ -1090,6 +1125,7 @@ func TestSearch(t *testing.T) {
 	})
 
 	m1 := userMsg("s1", 0, "Fix the authentication bug")
+	m1.SourceUUID = "u-1"
 	m2 := asstMsgAt("s1", 1, "Looking at the auth module",
 		tsZeroS1)
 
@@ -1106,6 +1142,113 @@ func TestSearch(t *testing.T) {
 	if page.Results[0].SessionID != "s1" {
 		t.Errorf("session_id = %q", page.Results[0].SessionID)
 	}
+	if page.Results[0].SourceUUID != "u-1" {
+		t.Errorf("source_uuid = %q, want u-1",
+			page.Results[0].SourceUUID)
+	}
+}
+
+func TestSearch_SessionScope(t *testing.T) {
+	d := testDB(t)
+	requireFTS(t, d)
+
+	insertSession(t, d, "s1", "p")
+	insertSession(t, d, "s2", "p")
+	insertMessages(t, d,
+		userMsg("s1", 0, "retry the flaky test"),
+		asstMsgAt("s1", 1, "no match here", tsZeroS1),
+		userMsg("s1", 2, "flaky flaky flaky test again"),
+		userMsg("s1", 3, "the flaky one"),
+		userMsg("s2", 0, "flaky elsewhere"),
+	)
+
+	page, err := d.Search(context.Background(), SearchFilter{
+		Query: "flaky", SessionID: "s1", Limit: 2,
+	})
+	requireNoError(t, err, "Search page 1")
+	var ordinals []int
+	for _, r := range page.Results {
+		if r.SessionID != "s1" {
+			t.Errorf("result from session %q", r.SessionID)
+		}
+		ordinals = append(ordinals, r.Ordinal)
+	}
+	if page.NextCursor != 2 {
+		t.Errorf("next cursor = %d, want 2", page.NextCursor)
+	}
+
+	page, err = d.Search(context.Background(), SearchFilter{
+		Query: "flaky", SessionID: "s1",
+		Cursor: page.NextCursor, Limit: 2,
+	})
+	requireNoError(t, err, "Search page 2")
+	for _, r := range page.Results {
+		ordinals = append(ordinals, r.Ordinal)
+	}
+	if fmt.Sprint(ordinals) != "[0 2 3]" {
+		t.Errorf("ordinals = %v, want [0 2 3]", ordinals)
+	}
+	if page.NextCursor != 0 {
+		t.Errorf("next cursor = %d, want 0", page.NextCursor)
+	}
+}
+
+func TestSearch_Filters(t *testing.T) {
+	d := testDB(t)
+	requireFTS(t, d)
+
+	insertSession(t, d, "s1", "p", func(s *Session) {
+		s.Machine = "laptop"
+	})
+	insertSession(t, d, "s2", "p", func(s *Session) {
+		s.Machine = "desktop"
+	})
+	tool := asstMsgAt("s1", 1, "deploy with the script",
+		"2024-06-02T10:00:00Z")
+	tool.HasToolUse = true
+	insertMessages(t, d,
+		userMsgAt("s1", 0, "deploy the app", "2024-06-01T09:00:00Z"),
+		tool,
+		asstMsgAt("s1", 2, "deploy finished", "2024-06-03T10:00:00Z"),
+		userMsgAt("s2", 0, "deploy elsewhere", "2024-06-02T11:00:00Z"),
+	)
+	requireNoError(t, d.ApplyBulkAction(context.Background(),
+		BulkAction{Action: BulkActionTag, Tag: "wasted"}, []string{"s2"},
+	), "tag")
+
+	tests := []struct {
+		name string
+		f    SearchFilter
+		want []string
+	}{
+		{"machine", SearchFilter{Machine: "desktop"}, []string{"s2#0"}},
+		{"tag", SearchFilter{Tag: "wasted"}, []string{"s2#0"}},
+		{"role", SearchFilter{Role: "assistant"},
+			[]string{"s1#1", "s1#2"}},
+		{"tool use", SearchFilter{HasToolUse: true}, []string{"s1#1"}},
+		{"date range", SearchFilter{
+			DateFrom: "2024-06-02", DateTo: "2024-06-02",
+		}, []string{"s1#1", "s2#0"}},
+		{"combined", SearchFilter{
+			Machine: "laptop", Role: "user", DateTo: "2024-06-02",
+		}, []string{"s1#0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.f.Query = "deploy"
+			page, err := d.Search(context.Background(), tt.f)
+			requireNoError(t, err, "Search")
+			var got []string
+			for _, r := range page.Results {
+				got = append(got,
+					fmt.Sprintf("%s#%d", r.SessionID, r.Ordinal))
+			}
+			sort.Strings(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("results = %v, want %v", got, tt.want)
+			}
+		})
+	}
 }
 
 func TestCanceledContext(t *testing.T) {
@@ -1283,6 +1426,9 @@ func TestGetProjects(t *testing.T) {
 	if projects[0].Name != "alpha" || projects[0].SessionCount != 2 {
 		t.Errorf("alpha: %+v", projects[0])
 	}
+	if projects[0].LastActiveAt == "" {
+		t.Errorf("alpha LastActiveAt is empty")
+	}
 }
 
 // setupPruneData inserts the standard sessions used by the prune
@@ -1449,6 +1595,81 @@ func TestFindPruneCandidates(t *testing.T) {
 	})
 }
 
+func TestFindPruneCandidatesAgentAndOrphaned(t *testing.T) {
+	d := testDB(t)
+	dir := t.TempDir()
+	present := filepath.Join(dir, "present.jsonl")
+	opencodeDB := filepath.Join(dir, "opencode.db")
+	for _, p := range []string{present, opencodeDB} {
+		if err := os.WriteFile(p, []byte("{}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gone := filepath.Join(dir, "gone.jsonl")
+
+	for _, s := range []struct {
+		id, agent, machine string
+		path               *string
+	}{
+		{"present", "claude", "local", Ptr(present)},
+		{"gone", "claude", "local", Ptr(gone)},
+		{"gone-codex", "codex", "local", Ptr(gone)},
+		{"opencode", "opencode", "local", Ptr(opencodeDB + "#ses_1")},
+		{"remote", "claude", "laptop", Ptr(gone)},
+		{"no-file", "claude", "local", nil},
+	} {
+		insertSession(t, d, s.id, "p", func(sess *Session) {
+			sess.Agent = s.agent
+			sess.Machine = s.machine
+			sess.FilePath = s.path
+		})
+	}
+
+	tests := []struct {
+		name   string
+		filter PruneFilter
+		want   []string
+	}{
+		{"Agent", PruneFilter{Agent: "codex"}, []string{"gone-codex"}},
+		{"Orphaned", PruneFilter{Orphaned: true}, []string{"gone", "gone-codex"}},
+		{
+			"OrphanedByAgent",
+			PruneFilter{Orphaned: true, Agent: "claude"},
+			[]string{"gone"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := d.FindPruneCandidates(tt.filter)
+			requireNoError(t, err, "FindPruneCandidates")
+			ids := collectIDs(got)
+			slices.Sort(ids)
+			if diff := cmp.Diff(tt.want, ids); diff != "" {
+				t.Errorf("candidates mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// A renamed local machine is still local.
+	requireNoError(t,
+		d.RenameMachine(context.Background(), "local", "desk"),
+		"RenameMachine")
+	got, err := d.FindPruneCandidates(PruneFilter{Orphaned: true})
+	requireNoError(t, err, "FindPruneCandidates after rename")
+	ids := collectIDs(got)
+	slices.Sort(ids)
+	if diff := cmp.Diff([]string{"gone", "gone-codex"}, ids); diff != "" {
+		t.Errorf("renamed candidates mismatch (-want +got):\n%s", diff)
+	}
+	got, err = d.FindPruneCandidates(PruneFilter{
+		Orphaned: true, LocalMachine: "laptop",
+	})
+	requireNoError(t, err, "FindPruneCandidates laptop")
+	if ids := collectIDs(got); !slices.Equal(ids, []string{"remote"}) {
+		t.Errorf("laptop candidates = %v, want [remote]", ids)
+	}
+}
+
 // collectIDs extracts session IDs for error messages.
 func collectIDs(sessions []Session) []string {
 	ids := make([]string, len(sessions))
@@ -1497,6 +1718,56 @@ func TestFindPruneCandidatesExcludesParents(t *testing.T) {
 	}
 }
 
+func TestFindPruneCandidatesIncludeChain(t *testing.T) {
+	d := testDB(t)
+
+	// Chain a: root -> resumed -> subagent, all scratch.
+	insertSession(t, d, "a-root", "scratch")
+	insertSession(t, d, "a-resumed", "scratch", func(s *Session) {
+		s.ParentSessionID = Ptr("a-root")
+	})
+	insertSession(t, d, "a-sub", "scratch", func(s *Session) {
+		s.ParentSessionID = Ptr("a-resumed")
+	})
+	// Chain b: the child is in another project.
+	insertSession(t, d, "b-root", "scratch")
+	insertSession(t, d, "b-child", "keep", func(s *Session) {
+		s.ParentSessionID = Ptr("b-root")
+	})
+	// A child whose parent is already gone.
+	insertSession(t, d, "orphan", "scratch", func(s *Session) {
+		s.ParentSessionID = Ptr("missing")
+	})
+	insertSession(t, d, "solo", "scratch")
+
+	got, err := d.FindPruneCandidates(PruneFilter{
+		Project: "scratch", IncludeChain: true,
+	})
+	requireNoError(t, err, "FindPruneCandidates")
+	ids := collectIDs(got)
+	sort.Strings(ids)
+	want := []string{"a-resumed", "a-root", "a-sub", "orphan", "solo"}
+	if !slices.Equal(ids, want) {
+		t.Errorf("candidates = %v, want %v", ids, want)
+	}
+}
+
+func TestDeleteSessionsClearsParentLinks(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "parent", "proj")
+	insertSession(t, d, "child", "proj", func(s *Session) {
+		s.ParentSessionID = Ptr("parent")
+	})
+
+	_, err := d.DeleteSessions([]string{"parent"})
+	requireNoError(t, err, "DeleteSessions")
+
+	child := requireSessionExists(t, d, "child")
+	if child.ParentSessionID != nil {
+		t.Errorf("child parent = %q, want nil", *child.ParentSessionID)
+	}
+}
+
 func TestFindPruneCandidatesLikeEscaping(t *testing.T) {
 	d := testDB(t)
 
@@ -1682,6 +1953,100 @@ func TestDeleteSessions(t *testing.T) {
 	}
 }
 
+func TestRemoveSessionFilesRemovesFiles(t *testing.T) {
+	dir := t.TempDir()
+	subdir := filepath.Join(dir, "session1")
+	if err := os.MkdirAll(subdir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	f := filepath.Join(subdir, "data.jsonl")
+	if err := os.WriteFile(f, []byte("test data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := []Session{
+		{ID: "s1", FilePath: Ptr(f)},
+	}
+
+	removed, reclaimed := RemoveSessionFiles(sessions)
+	if removed != 1 {
+		t.Errorf("removed = %d, want 1", removed)
+	}
+	if reclaimed != 9 {
+		t.Errorf("reclaimed = %d, want 9", reclaimed)
+	}
+
+	// File should be gone.
+	if _, err := os.Stat(f); !os.IsNotExist(err) {
+		t.Error("file still exists")
+	}
+
+	// Empty parent dir should be removed.
+	if _, err := os.Stat(subdir); !os.IsNotExist(err) {
+		t.Error("empty parent dir still exists")
+	}
+}
+
+func TestRemoveSessionFilesMissingFile(t *testing.T) {
+	sessions := []Session{
+		{ID: "s1", FilePath: Ptr("/nonexistent/path/file.jsonl")},
+	}
+
+	removed, reclaimed := RemoveSessionFiles(sessions)
+	if removed != 0 {
+		t.Errorf("removed = %d, want 0", removed)
+	}
+	if reclaimed != 0 {
+		t.Errorf("reclaimed = %d, want 0", reclaimed)
+	}
+}
+
+func TestRemoveSessionFilesNilPath(t *testing.T) {
+	sessions := []Session{
+		{ID: "s1", FilePath: nil},
+	}
+
+	removed, reclaimed := RemoveSessionFiles(sessions)
+	if removed != 0 {
+		t.Errorf("removed = %d, want 0", removed)
+	}
+	if reclaimed != 0 {
+		t.Errorf("reclaimed = %d, want 0", reclaimed)
+	}
+}
+
+func TestLocalSessions(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	insertSession(t, d, "mine", "p", func(s *Session) {
+		s.Machine = "local"
+	})
+	insertSession(t, d, "pushed", "p", func(s *Session) {
+		s.Machine = "laptop"
+	})
+	sessions, err := d.FindPruneCandidates(PruneFilter{Project: "p"})
+	requireNoError(t, err, "FindPruneCandidates")
+
+	local, err := d.LocalSessions(sessions, "")
+	requireNoError(t, err, "LocalSessions")
+	if len(local) != 1 || local[0].ID != "mine" {
+		t.Errorf("LocalSessions = %v, want [mine]", local)
+	}
+
+	// After a rename the old name resolves to the new one.
+	requireNoError(t,
+		d.RenameMachine(ctx, "local", "desktop"), "RenameMachine")
+	sessions, err = d.FindPruneCandidates(PruneFilter{Project: "p"})
+	requireNoError(t, err, "FindPruneCandidates")
+	local, err = d.LocalSessions(sessions, "local")
+	requireNoError(t, err, "LocalSessions after rename")
+	if len(local) != 1 || local[0].ID != "mine" {
+		t.Errorf("LocalSessions after rename = %v, want [mine]", local)
+	}
+}
+
 func TestSessionFileInfo(t *testing.T) {
 	d := testDB(t)
 
@@ -1718,6 +2083,8 @@ func TestGetSessionFull(t *testing.T) {
 			s.StartedAt = Ptr(tsZero)
 			s.EndedAt = Ptr(tsHour1)
 			s.MessageCount = 5
+			s.InterruptCount = 2
+			s.Headless = true
 			s.FilePath = Ptr("/tmp/session.jsonl")
 			s.FileSize = Ptr(int64(2048))
 			s.FileMtime = Ptr(int64(1700000000))
@@ -1730,19 +2097,21 @@ func TestGetSessionFull(t *testing.T) {
 			t.Fatal("expected non-nil session")
 		}
 		want := &Session{
-			ID:           "full-1",
-			Project:      "proj",
-			MessageCount: 5,
-			FilePath:     Ptr("/tmp/session.jsonl"),
-			FileSize:     Ptr(int64(2048)),
-			FileMtime:    Ptr(int64(1700000000)),
-			FileHash:     Ptr("abc123"),
-			FirstMessage: Ptr("hello"),
-			StartedAt:    Ptr(tsZero),
-			EndedAt:      Ptr(tsHour1),
-			Machine:      defaultMachine,
-			Agent:        defaultAgent,
-			CreatedAt:    got.CreatedAt,
+			ID:             "full-1",
+			Project:        "proj",
+			MessageCount:   5,
+			InterruptCount: 2,
+			Headless:       true,
+			FilePath:       Ptr("/tmp/session.jsonl"),
+			FileSize:       Ptr(int64(2048)),
+			FileMtime:      Ptr(int64(1700000000)),
+			FileHash:       Ptr("abc123"),
+			FirstMessage:   Ptr("hello"),
+			StartedAt:      Ptr(tsZero),
+			EndedAt:        Ptr(tsHour1),
+			Machine:        defaultMachine,
+			Agent:          defaultAgent,
+			CreatedAt:      got.CreatedAt,
 		}
 		if diff := cmp.Diff(want, got); diff != "" {
 			t.Errorf("GetSessionFull mismatch (-want +got):\n%s", diff)
@@ -1938,6 +2307,47 @@ func TestSetCursorSecretDefensiveCopy(t *testing.T) {
 	}
 }
 
+func TestPreviousCursorSecret(t *testing.T) {
+	d := testDB(t)
+	d.SetCursorSecret([]byte("old-secret"))
+	cursor := d.EncodeCursor(tsZero, "s1", 7)
+	share := d.ShareToken("s1", time.Now().Add(time.Hour))
+	bulk := d.BulkToken(BulkAction{Action: BulkActionMute}, []string{"s1"})
+
+	d.SetCursorSecret([]byte("new-secret"))
+	check := func(wantValid bool) {
+		t.Helper()
+		c, err := d.DecodeCursor(cursor)
+		if (err == nil) != wantValid {
+			t.Errorf("DecodeCursor err = %v, want valid %v", err, wantValid)
+		}
+		if err == nil && c.Total != 7 {
+			t.Errorf("cursor Total = %d, want 7", c.Total)
+		}
+		if _, err := d.DecodeShareToken(share, time.Now()); (err == nil) != wantValid {
+			t.Errorf("DecodeShareToken err = %v, want valid %v", err, wantValid)
+		}
+		err = d.CheckBulkToken(
+			BulkAction{Action: BulkActionMute}, []string{"s1"}, bulk,
+		)
+		if (err == nil) != wantValid {
+			t.Errorf("CheckBulkToken err = %v, want valid %v", err, wantValid)
+		}
+	}
+	check(false)
+
+	d.SetPreviousCursorSecret([]byte("old-secret"), time.Now().Add(time.Hour))
+	check(true)
+
+	d.SetPreviousCursorSecret([]byte("old-secret"), time.Now().Add(-time.Second))
+	check(false)
+
+	// New cursors are signed with the current secret.
+	if _, err := d.DecodeCursor(d.EncodeCursor(tsZero, "s2")); err != nil {
+		t.Errorf("DecodeCursor of new cursor: %v", err)
+	}
+}
+
 func TestSampleMinimap(t *testing.T) {
 	// Create a helper to generate n entries
 	makeEntries := func(n int) []MinimapEntry {
@@ -2404,6 +2814,57 @@ func TestToolCallNewColumns(t *testing.T) {
 	}
 }
 
+func TestMessageModelAndUsage(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "s1", "proj")
+	m := asstMsg("s1", 0, "answer")
+	m.Model = "gemini-2.5-pro"
+	m.InputTokens = 210
+	m.OutputTokens = 80
+	m.CacheReadTokens = 1000
+	m.CacheCreationTokens = 5
+	m.ReasoningTokens = 40
+	insertMessages(t, d, userMsg("s1", 1, "q"), m)
+
+	msgs, err := d.GetAllMessages(context.Background(), "s1")
+	requireNoError(t, err, "GetAllMessages")
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2", len(msgs))
+	}
+	got := msgs[0]
+	got.ID = 0
+	if got.Model != m.Model ||
+		got.InputTokens != 210 || got.OutputTokens != 80 ||
+		got.CacheReadTokens != 1000 ||
+		got.CacheCreationTokens != 5 ||
+		got.ReasoningTokens != 40 {
+		t.Errorf("round trip = %+v", got)
+	}
+
+	single, err := d.GetMessageByOrdinal("s1", 1)
+	requireNoError(t, err, "GetMessageByOrdinal")
+	if single.Model != "" || single.InputTokens != 0 {
+		t.Errorf("user message has usage: %+v", single)
+	}
+}
+
+func TestMessageKind(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "s1", "proj")
+	cmd := userMsg("s1", 0, "/review PR 12")
+	cmd.Kind = "command"
+	insertMessages(t, d, cmd, userMsg("s1", 1, "go on"))
+
+	msgs, err := d.GetAllMessages(context.Background(), "s1")
+	requireNoError(t, err, "GetAllMessages")
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2", len(msgs))
+	}
+	if msgs[0].Kind != "command" || msgs[1].Kind != "" {
+		t.Errorf("kinds = %q, %q", msgs[0].Kind, msgs[1].Kind)
+	}
+}
+
 func TestToolCallSkillName(t *testing.T) {
 	d := testDB(t)
 	insertSession(t, d, "s1", "proj")
@@ -2570,6 +3031,51 @@ func TestGetAllMessagesReturnsToolCallsAcrossBatches(t *testing.T) {
 	}
 }
 
+func TestStreamMessages(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "s1", "proj")
+
+	total := 2*streamMessageBatch + 10
+	msgs := make([]Message, 0, total)
+	for i := range total {
+		msgs = append(msgs, userMsg("s1", i, fmt.Sprintf("m%d", i)))
+	}
+	insertMessages(t, d, msgs...)
+
+	var batches []int
+	next := 5
+	err := d.StreamMessages(context.Background(), "s1", 5,
+		func(batch []Message) error {
+			batches = append(batches, len(batch))
+			for _, m := range batch {
+				if m.Ordinal != next {
+					t.Fatalf("ordinal = %d, want %d", m.Ordinal, next)
+				}
+				next++
+			}
+			return nil
+		})
+	requireNoError(t, err, "StreamMessages")
+	if next != total {
+		t.Errorf("streamed up to %d, want %d", next, total)
+	}
+	want := []int{streamMessageBatch, streamMessageBatch, 5}
+	if !slices.Equal(batches, want) {
+		t.Errorf("batches = %v, want %v", batches, want)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = d.StreamMessages(context.Background(), "s1", 0,
+		func([]Message) error {
+			calls++
+			return stop
+		})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("err = %v after %d calls, want stop after 1", err, calls)
+	}
+}
+
 func TestToolCallSubagentSessionID(t *testing.T) {
 	d := testDB(t)
 	insertSession(t, d, "s1", "proj")
@@ -2959,9 +3465,11 @@ func TestRepeatedReopenBoundsRetiredPools(t *testing.T) {
 
 	// After 20 reopens the retired slice should hold at most
 	// the last pair (2 entries), not 40.
-	d.mu.Lock()
-	n := len(d.retired)
-	d.mu.Unlock()
+	var n int
+	requireNoError(t, d.write(func() error {
+		n = len(d.retired)
+		return nil
+	}), "read retired")
 	if n > 2 {
 		t.Errorf("retired pool count = %d, want <= 2", n)
 	}
@@ -3230,6 +3738,40 @@ func TestCopyOrphanedDataFrom_WithToolCalls(t *testing.T) {
 	}
 }
 
+func TestCopyOrphanedDataFrom_WithTokenUsage(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "old.db")
+	srcDB, err := Open(srcPath)
+	requireNoError(t, err, "Open src")
+	insertSession(t, srcDB, "s1", "proj")
+	insertMessages(t, srcDB,
+		userMsg("s1", 0, "hello"),
+		usageMsg("s1", 1, "gpt-5", 120, 12),
+	)
+	srcDB.Close()
+
+	dstDB, err := Open(filepath.Join(dir, "new.db"))
+	requireNoError(t, err, "Open dst")
+	defer dstDB.Close()
+	// Shift message ids so a copy that kept the old ids would
+	// point at the wrong message.
+	insertSession(t, dstDB, "other", "proj")
+	insertMessages(t, dstDB, userMsg("other", 0, "hi"))
+
+	_, err = dstDB.CopyOrphanedDataFrom(srcPath)
+	requireNoError(t, err, "CopyOrphanedDataFrom")
+
+	var ordinal, input int
+	err = dstDB.getReader().QueryRow(`
+		SELECT m.ordinal, tu.input_tokens
+		FROM token_usage tu JOIN messages m ON m.id = tu.message_id
+		WHERE tu.session_id = 's1'`,
+	).Scan(&ordinal, &input)
+	requireNoError(t, err, "query token_usage")
+	assertEq(t, "ordinal", ordinal, 1)
+	assertEq(t, "input", input, 120)
+}
+
 func TestCopyOrphanedDataFrom_AtomicOnFailure(t *testing.T) {
 	dir := t.TempDir()
 
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Bounds for GetAnalyticsEditThrash.
+const (
+	DefaultEditThrashThreshold = 5
+	MinEditThrashThreshold     = 1
+	MaxEditThrashThreshold     = 100
+
+	// maxThrashSessions caps the sessions listed by
+	// GetAnalyticsEditThrash.
+	maxThrashSessions = 50
+)
+
+// FileEdits counts the Edit/Write calls on one file.
+type FileEdits struct {
+	Path  string `json:"path"`
+	Edits int    `json:"edits"`
+}
+
+// SessionEditThrash flags a session that edited at least one
+// file more than the threshold. Score is the number of edits
+// past the threshold, summed over those files.
+type SessionEditThrash struct {
+	SessionID    string      `json:"session_id"`
+	Project      string      `json:"project"`
+	Agent        string      `json:"agent"`
+	FirstMessage *string     `json:"first_message"`
+	StartedAt    string      `json:"started_at"`
+	Score        int         `json:"score"`
+	Edits        int         `json:"edits"`
+	Files        []FileEdits `json:"files"` // thrashed files, most edited first
+}
+
+// ProjectEditThrash rolls edit thrash up to one project.
+// EditSessions counts sessions with any Edit/Write call;
+// ThrashRate is the percentage of those that were flagged.
+type ProjectEditThrash struct {
+	Project          string  `json:"project"`
+	EditSessions     int     `json:"edit_sessions"`
+	ThrashedSessions int     `json:"thrashed_sessions"`
+	ThrashRate       float64 `json:"thrash_rate"`
+	Score            int     `json:"score"`
+}
+
+// EditThrashResponse wraps edit thrash analytics.
+type EditThrashResponse struct {
+	Threshold int                 `json:"threshold"`
+	Sessions  []SessionEditThrash `json:"sessions"`
+	Projects  []ProjectEditThrash `json:"projects"`
+}
+
+// GetAnalyticsEditThrash finds sessions that edited the same
+// file more than threshold times, a sign the agent was flailing.
+// A threshold of zero uses DefaultEditThrashThreshold.
+func (db *DB) GetAnalyticsEditThrash(
+	ctx context.Context, f AnalyticsFilter, threshold int,
+) (EditThrashResponse, error) {
+	if threshold <= 0 {
+		threshold = DefaultEditThrashThreshold
+	}
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return EditThrashResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, project, agent, first_message
+		FROM sessions WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return EditThrashResponse{},
+			fmt.Errorf("querying edit thrash sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := map[string]*SessionEditThrash{}
+	var ids []string
+	for rows.Next() {
+		var s SessionEditThrash
+		if err := rows.Scan(
+			&s.SessionID, &s.StartedAt, &s.Project, &s.Agent,
+			&s.FirstMessage,
+		); err != nil {
+			return EditThrashResponse{},
+				fmt.Errorf("scanning edit thrash session: %w", err)
+		}
+		if !inDateRange(localDate(s.StartedAt, loc), f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[s.SessionID] {
+			continue
+		}
+		sessions[s.SessionID] = &s
+		ids = append(ids, s.SessionID)
+	}
+	if err := rows.Err(); err != nil {
+		return EditThrashResponse{},
+			fmt.Errorf("iterating edit thrash sessions: %w", err)
+	}
+
+	edited := map[string]bool{}
+	err = queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, file_path, COUNT(*)
+			FROM tool_calls
+			WHERE session_id IN `+ph+`
+			  AND category IN ('Edit', 'Write')
+			  AND file_path IS NOT NULL AND file_path != ''
+			GROUP BY session_id, file_path`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying file edits: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sid, path string
+			var n int
+			if err := rows.Scan(&sid, &path, &n); err != nil {
+				return fmt.Errorf("scanning file edits: %w", err)
+			}
+			edited[sid] = true
+			if n <= threshold {
+				continue
+			}
+			s := sessions[sid]
+			s.Score += n - threshold
+			s.Edits += n
+			s.Files = append(s.Files, FileEdits{Path: path, Edits: n})
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return EditThrashResponse{}, err
+	}
+
+	resp := EditThrashResponse{
+		Threshold: threshold,
+		Sessions:  []SessionEditThrash{},
+		Projects:  []ProjectEditThrash{},
+	}
+	projects := map[string]*ProjectEditThrash{}
+	for sid := range edited {
+		s := sessions[sid]
+		p := projects[s.Project]
+		if p == nil {
+			p = &ProjectEditThrash{Project: s.Project}
+			projects[s.Project] = p
+		}
+		p.EditSessions++
+		if s.Score == 0 {
+			continue
+		}
+		p.ThrashedSessions++
+		p.Score += s.Score
+		sort.Slice(s.Files, func(i, j int) bool {
+			if s.Files[i].Edits != s.Files[j].Edits {
+				return s.Files[i].Edits > s.Files[j].Edits
+			}
+			return s.Files[i].Path < s.Files[j].Path
+		})
+		resp.Sessions = append(resp.Sessions, *s)
+	}
+	sort.Slice(resp.Sessions, func(i, j int) bool {
+		a, b := resp.Sessions[i], resp.Sessions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.SessionID < b.SessionID
+	})
+	if len(resp.Sessions) > maxThrashSessions {
+		resp.Sessions = resp.Sessions[:maxThrashSessions]
+	}
+
+	for _, p := range projects {
+		p.ThrashRate = round1(
+			float64(p.ThrashedSessions) / float64(p.EditSessions) * 100,
+		)
+		resp.Projects = append(resp.Projects, *p)
+	}
+	sort.Slice(resp.Projects, func(i, j int) bool {
+		a, b := resp.Projects[i], resp.Projects[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Project < b.Project
+	})
+	return resp, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// editMsgs returns n messages from ordinal start, each editing
+// path once.
+func editMsgs(sid string, start, n int, path string) []Message {
+	msgs := make([]Message, n)
+	for i := range n {
+		msgs[i] = toolMsg(sid, start+i, "2024-06-01T09:00:00Z", ToolCall{
+			ToolName: "Edit", Category: "Edit",
+			InputJSON: fmt.Sprintf(`{"file_path":%q}`, path),
+		})
+	}
+	return msgs
+}
+
+func TestGetAnalyticsEditThrash(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	started := func(s *Session) {
+		s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+	}
+	insertSession(t, d, "thrash", "alpha", started)
+	insertSession(t, d, "calm", "alpha", started)
+	insertSession(t, d, "other", "beta", started)
+
+	// thrash: 5 edits to a.go (2 over), 4 to b.go (1 over),
+	// 2 to c.go (under).
+	msgs := editMsgs("thrash", 0, 5, "/a.go")
+	msgs = append(msgs, editMsgs("thrash", 5, 4, "/b.go")...)
+	msgs = append(msgs, editMsgs("thrash", 9, 2, "/c.go")...)
+	insertMessages(t, d, msgs...)
+	insertMessages(t, d, editMsgs("calm", 0, 3, "/a.go")...)
+	insertMessages(t, d, editMsgs("other", 0, 4, "/x.go")...)
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsEditThrash(ctx, f, 3)
+	requireNoError(t, err, "GetAnalyticsEditThrash")
+	assertEq(t, "Threshold", resp.Threshold, 3)
+	if len(resp.Sessions) != 2 {
+		t.Fatalf("Sessions = %+v, want thrash and other", resp.Sessions)
+	}
+	s := resp.Sessions[0]
+	assertEq(t, "session", s.SessionID, "thrash")
+	assertEq(t, "score", s.Score, 3)
+	assertEq(t, "edits", s.Edits, 9)
+	if len(s.Files) != 2 || s.Files[0] != (FileEdits{"/a.go", 5}) {
+		t.Errorf("Files = %+v", s.Files)
+	}
+	assertEq(t, "projects", len(resp.Projects), 2)
+	assertEq(t, "alpha", resp.Projects[0], ProjectEditThrash{
+		Project:          "alpha",
+		EditSessions:     2,
+		ThrashedSessions: 1,
+		ThrashRate:       50,
+		Score:            3,
+	})
+
+	// The default threshold flags nothing here.
+	resp, err = d.GetAnalyticsEditThrash(ctx, f, 0)
+	requireNoError(t, err, "GetAnalyticsEditThrash default")
+	assertEq(t, "default threshold", resp.Threshold,
+		DefaultEditThrashThreshold)
+	assertEq(t, "default sessions", len(resp.Sessions), 0)
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SessionFilePart is an additional source file merged into a
+// session alongside its primary file_path.
+type SessionFilePart struct {
+	Path  string
+	Size  int64
+	Mtime int64
+}
+
+// ReplaceSessionFileParts replaces the extra source files
+// recorded for a session. An empty parts slice clears them.
+func (db *DB) ReplaceSessionFileParts(
+	sessionID string, parts []SessionFilePart,
+) error {
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.Exec(
+			"DELETE FROM session_file_parts WHERE session_id = ?",
+			sessionID,
+		); err != nil {
+			return fmt.Errorf("deleting old file parts: %w", err)
+		}
+
+		if len(parts) > 0 {
+			stmt, err := tx.Prepare(`
+				INSERT INTO session_file_parts
+					(file_path, session_id, file_size, file_mtime)
+				VALUES (?, ?, ?, ?)
+				ON CONFLICT(file_path) DO UPDATE SET
+					session_id = excluded.session_id,
+					file_size = excluded.file_size,
+					file_mtime = excluded.file_mtime`)
+			if err != nil {
+				return fmt.Errorf("preparing file part insert: %w", err)
+			}
+			defer stmt.Close()
+			for _, p := range parts {
+				if _, err := stmt.Exec(
+					p.Path, sessionID, p.Size, p.Mtime,
+				); err != nil {
+					return fmt.Errorf("inserting file part: %w", err)
+				}
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// GetSessionFileParts returns the extra source files recorded
+// for a session, ordered by path.
+func (db *DB) GetSessionFileParts(
+	sessionID string,
+) ([]SessionFilePart, error) {
+	rows, err := db.getReader().Query(`
+		SELECT file_path, COALESCE(file_size, 0),
+			COALESCE(file_mtime, 0)
+		FROM session_file_parts
+		WHERE session_id = ?
+		ORDER BY file_path`, sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying file parts: %w", err)
+	}
+	defer rows.Close()
+
+	var parts []SessionFilePart
+	for rows.Next() {
+		var p SessionFilePart
+		if err := rows.Scan(&p.Path, &p.Size, &p.Mtime); err != nil {
+			return nil, fmt.Errorf("scanning file part: %w", err)
+		}
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
+
+// GetFilePartInfo returns the stored size and mtime for a file
+// recorded as an extra part of some session.
+func (db *DB) GetFilePartInfo(
+	path string,
+) (size int64, mtime int64, ok bool) {
+	var s, m sql.NullInt64
+	err := db.getReader().QueryRow(
+		"SELECT file_size, file_mtime FROM session_file_parts"+
+			" WHERE file_path = ?",
+		path,
+	).Scan(&s, &m)
+	if err != nil {
+		return 0, 0, false
+	}
+	return s.Int64, m.Int64, true
+}
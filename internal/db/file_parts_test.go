@@ -0,0 +1,46 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSessionFileParts(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "codex:s1", "p")
+
+	parts := []SessionFilePart{
+		{Path: "/a/rollout-1.jsonl", Size: 10, Mtime: 100},
+		{Path: "/a/rollout-2.jsonl", Size: 20, Mtime: 200},
+	}
+	requireNoError(t,
+		d.ReplaceSessionFileParts("codex:s1", parts),
+		"replace parts",
+	)
+
+	got, err := d.GetSessionFileParts("codex:s1")
+	requireNoError(t, err, "get parts")
+	if diff := cmp.Diff(parts, got); diff != "" {
+		t.Errorf("parts mismatch (-want +got):\n%s", diff)
+	}
+
+	size, mtime, ok := d.GetFilePartInfo("/a/rollout-2.jsonl")
+	if !ok || size != 20 || mtime != 200 {
+		t.Errorf("GetFilePartInfo = (%d, %d, %v), want (20, 200, true)",
+			size, mtime, ok)
+	}
+	if _, _, ok := d.GetFilePartInfo("/a/missing.jsonl"); ok {
+		t.Error("GetFilePartInfo found unknown path")
+	}
+
+	requireNoError(t,
+		d.ReplaceSessionFileParts("codex:s1", nil),
+		"clear parts",
+	)
+	got, err = d.GetSessionFileParts("codex:s1")
+	requireNoError(t, err, "get parts after clear")
+	if len(got) != 0 {
+		t.Errorf("got %d parts after clear, want 0", len(got))
+	}
+}
@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+const (
+	// DefaultRecentFilesLimit is the default number of files
+	// returned by GetRecentFiles.
+	DefaultRecentFilesLimit = 50
+	// MaxRecentFilesLimit is the maximum number of files
+	// returned by GetRecentFiles.
+	MaxRecentFilesLimit = 500
+	// DefaultFileTouchLimit caps the touches listed per file.
+	DefaultFileTouchLimit = 10
+	// MaxFileTouchLimit is the upper bound for touches per file.
+	MaxFileTouchLimit = 100
+)
+
+// fileInputKeys lists the input_json keys that name the target
+// file across agents, in priority order. Claude Code uses
+// file_path and notebook_path; Amp, Codex, and Gemini use path;
+// Cursor and VSCode Copilot use filePath or target_file.
+var fileInputKeys = []string{
+	"file_path", "notebook_path", "path", "filePath", "target_file",
+}
+
+// toolCallFilePath returns the file a tool call touched, or ""
+// when the call is not a single-file Read/Edit/Write.
+func toolCallFilePath(category, inputJSON string) string {
+	switch category {
+	case "Read", "Edit", "Write":
+	default:
+		return ""
+	}
+	if inputJSON == "" || !gjson.Valid(inputJSON) {
+		return ""
+	}
+	input := gjson.Parse(inputJSON)
+	for _, key := range fileInputKeys {
+		v := input.Get(key)
+		if v.Type == gjson.String && v.Str != "" {
+			return v.Str
+		}
+	}
+	return ""
+}
+
+// backfillToolCallFilePaths populates file_path for tool calls
+// written before the column existed. Mirrors toolCallFilePath.
+const backfillToolCallFilePaths = `
+	UPDATE tool_calls SET file_path = COALESCE(
+		NULLIF(json_extract(input_json, '$.file_path'), ''),
+		NULLIF(json_extract(input_json, '$.notebook_path'), ''),
+		NULLIF(json_extract(input_json, '$.path'), ''),
+		NULLIF(json_extract(input_json, '$.filePath'), ''),
+		NULLIF(json_extract(input_json, '$.target_file'), '')
+	)
+	WHERE category IN ('Read', 'Edit', 'Write')
+	  AND input_json IS NOT NULL
+	  AND json_valid(input_json)`
+
+// RecentFilesFilter specifies how to query recently touched files.
+type RecentFilesFilter struct {
+	Project    string // exact project match ("" = all)
+	Path       string // substring match on file path
+	Limit      int    // max files returned
+	TouchLimit int    // max touches listed per file
+}
+
+// FileTouch is a single tool call that touched a file.
+type FileTouch struct {
+	SessionID string `json:"session_id"`
+	Project   string `json:"project"`
+	Ordinal   int    `json:"ordinal"`
+	Timestamp string `json:"timestamp"`
+	ToolName  string `json:"tool_name"`
+	Category  string `json:"category"`
+}
+
+// RecentFile summarizes agent activity on a single file path.
+type RecentFile struct {
+	Path          string      `json:"path"`
+	LastTouchedAt string      `json:"last_touched_at"`
+	TouchCount    int         `json:"touch_count"`
+	SessionCount  int         `json:"session_count"`
+	EditCount     int         `json:"edit_count"`
+	Touches       []FileTouch `json:"touches"`
+}
+
+// GetRecentFiles returns the most recently touched file paths
+// from tool_call inputs, newest first, each with the most
+// recent tool calls that touched it.
+func (db *DB) GetRecentFiles(
+	ctx context.Context, f RecentFilesFilter,
+) ([]RecentFile, error) {
+	if f.Limit <= 0 || f.Limit > MaxRecentFilesLimit {
+		f.Limit = DefaultRecentFilesLimit
+	}
+	if f.TouchLimit <= 0 || f.TouchLimit > MaxFileTouchLimit {
+		f.TouchLimit = DefaultFileTouchLimit
+	}
+
+	preds := []string{"tc.file_path IS NOT NULL"}
+	var args []any
+	if f.Project != "" {
+		preds = append(preds, "s.project = ?")
+		args = append(args, f.Project)
+	}
+	if f.Path != "" {
+		preds = append(preds, `tc.file_path LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(f.Path)+"%")
+	}
+	where := strings.Join(preds, " AND ")
+
+	const from = `
+		FROM tool_calls tc
+		JOIN messages m ON m.id = tc.message_id
+		JOIN sessions s ON s.id = tc.session_id`
+
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT tc.file_path,
+			MAX(COALESCE(m.timestamp, '')) AS last_at,
+			COUNT(*),
+			COUNT(DISTINCT tc.session_id),
+			SUM(CASE WHEN tc.category IN ('Edit', 'Write')
+				THEN 1 ELSE 0 END)`+from+`
+		WHERE `+where+`
+		GROUP BY tc.file_path
+		ORDER BY last_at DESC, tc.file_path
+		LIMIT ?`, append(args, f.Limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("querying recent files: %w", err)
+	}
+	defer rows.Close()
+
+	files := []RecentFile{}
+	byPath := make(map[string]int)
+	for rows.Next() {
+		var rf RecentFile
+		if err := rows.Scan(
+			&rf.Path, &rf.LastTouchedAt, &rf.TouchCount,
+			&rf.SessionCount, &rf.EditCount,
+		); err != nil {
+			return nil, fmt.Errorf("scanning recent file: %w", err)
+		}
+		rf.Touches = []FileTouch{}
+		byPath[rf.Path] = len(files)
+		files = append(files, rf)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return files, nil
+	}
+
+	paths := make([]string, len(files))
+	for i, rf := range files {
+		paths[i] = rf.Path
+	}
+	err = queryChunked(paths, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		touchWhere := "tc.file_path IN " + ph
+		if f.Project != "" {
+			touchWhere += " AND s.project = ?"
+			chunkArgs = append(chunkArgs, f.Project)
+		}
+		// Number each path's touches newest first so only
+		// TouchLimit rows per path leave SQLite.
+		trows, err := db.getReader().QueryContext(ctx, `
+			SELECT file_path, session_id, project, ordinal, ts,
+				tool_name, category
+			FROM (
+				SELECT tc.file_path, tc.session_id, s.project,
+					m.ordinal, COALESCE(m.timestamp, '') AS ts,
+					tc.tool_name, tc.category,
+					m.timestamp AS m_ts, tc.id AS tc_id,
+					ROW_NUMBER() OVER (
+						PARTITION BY tc.file_path
+						ORDER BY m.timestamp DESC, tc.id DESC
+					) AS rn`+from+`
+				WHERE `+touchWhere+`
+			)
+			WHERE rn <= ?
+			ORDER BY m_ts DESC, tc_id DESC`,
+			append(chunkArgs, f.TouchLimit)...)
+		if err != nil {
+			return fmt.Errorf("querying file touches: %w", err)
+		}
+		defer trows.Close()
+		for trows.Next() {
+			var path string
+			var t FileTouch
+			if err := trows.Scan(
+				&path, &t.SessionID, &t.Project,
+				&t.Ordinal, &t.Timestamp,
+				&t.ToolName, &t.Category,
+			); err != nil {
+				return fmt.Errorf("scanning file touch: %w", err)
+			}
+			rf := &files[byPath[path]]
+			rf.Touches = append(rf.Touches, t)
+		}
+		return trows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
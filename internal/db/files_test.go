@@ -0,0 +1,204 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func toolMsg(
+	sid string, ordinal int, ts string, calls ...ToolCall,
+) Message {
+	for i := range calls {
+		calls[i].SessionID = sid
+	}
+	return Message{
+		SessionID:  sid,
+		Ordinal:    ordinal,
+		Role:       "assistant",
+		Content:    "tool use",
+		Timestamp:  ts,
+		HasToolUse: true,
+		ToolCalls:  calls,
+	}
+}
+
+func TestToolCallFilePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		category string
+		input    string
+		want     string
+	}{
+		{"claude read", "Read", `{"file_path":"/a/b.go"}`, "/a/b.go"},
+		{"notebook", "Write", `{"notebook_path":"/n.ipynb"}`, "/n.ipynb"},
+		{"amp path", "Edit", `{"path":"src/x.ts"}`, "src/x.ts"},
+		{"cursor", "Edit", `{"filePath":"c.ts"}`, "c.ts"},
+		{"grep ignored", "Grep", `{"path":"src"}`, ""},
+		{"bash ignored", "Bash", `{"command":"ls"}`, ""},
+		{"empty input", "Read", "", ""},
+		{"invalid json", "Read", `{"file_path":`, ""},
+		{"non-string", "Read", `{"file_path":42}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toolCallFilePath(tt.category, tt.input)
+			if got != tt.want {
+				t.Errorf("toolCallFilePath = %q, want %q",
+					got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRecentFiles(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+	insertSession(t, d, "s2", "proj")
+	insertSession(t, d, "s3", "other")
+
+	insertMessages(t, d,
+		toolMsg("s1", 0, "2024-01-01T10:00:00Z", ToolCall{
+			ToolName: "Read", Category: "Read",
+			InputJSON: `{"file_path":"/src/auth.ts"}`,
+		}),
+		toolMsg("s1", 1, "2024-01-01T10:01:00Z", ToolCall{
+			ToolName: "Edit", Category: "Edit",
+			InputJSON: `{"file_path":"/src/auth.ts"}`,
+		}, ToolCall{
+			ToolName: "Bash", Category: "Bash",
+			InputJSON: `{"command":"go test"}`,
+		}),
+	)
+	insertMessages(t, d,
+		toolMsg("s2", 0, "2024-01-02T09:00:00Z", ToolCall{
+			ToolName: "Write", Category: "Write",
+			InputJSON: `{"file_path":"/src/main.go"}`,
+		}),
+		toolMsg("s2", 1, "2024-01-02T09:05:00Z", ToolCall{
+			ToolName: "Read", Category: "Read",
+			InputJSON: `{"file_path":"/src/auth.ts"}`,
+		}),
+	)
+	insertMessages(t, d,
+		toolMsg("s3", 0, "2024-01-03T00:00:00Z", ToolCall{
+			ToolName: "Read", Category: "Read",
+			InputJSON: `{"file_path":"/other/readme.md"}`,
+		}),
+	)
+
+	t.Run("all projects", func(t *testing.T) {
+		files, err := d.GetRecentFiles(ctx, RecentFilesFilter{})
+		requireNoError(t, err, "GetRecentFiles")
+		if len(files) != 3 {
+			t.Fatalf("got %d files, want 3", len(files))
+		}
+		want := []string{
+			"/other/readme.md", "/src/auth.ts", "/src/main.go",
+		}
+		for i, p := range want {
+			if files[i].Path != p {
+				t.Errorf("files[%d] = %q, want %q",
+					i, files[i].Path, p)
+			}
+		}
+		auth := files[1]
+		if auth.TouchCount != 3 || auth.SessionCount != 2 ||
+			auth.EditCount != 1 {
+			t.Errorf("auth counts = %d/%d/%d, want 3/2/1",
+				auth.TouchCount, auth.SessionCount,
+				auth.EditCount)
+		}
+		if auth.LastTouchedAt != "2024-01-02T09:05:00Z" {
+			t.Errorf("LastTouchedAt = %q", auth.LastTouchedAt)
+		}
+		if len(auth.Touches) != 3 {
+			t.Fatalf("got %d touches, want 3", len(auth.Touches))
+		}
+		first := auth.Touches[0]
+		if first.SessionID != "s2" || first.Ordinal != 1 {
+			t.Errorf("newest touch = %s#%d, want s2#1",
+				first.SessionID, first.Ordinal)
+		}
+	})
+
+	t.Run("project filter", func(t *testing.T) {
+		files, err := d.GetRecentFiles(ctx, RecentFilesFilter{
+			Project: "other",
+		})
+		requireNoError(t, err, "GetRecentFiles")
+		if len(files) != 1 || files[0].Path != "/other/readme.md" {
+			t.Fatalf("got %+v, want only readme.md", files)
+		}
+	})
+
+	t.Run("path substring", func(t *testing.T) {
+		files, err := d.GetRecentFiles(ctx, RecentFilesFilter{
+			Path: "auth",
+		})
+		requireNoError(t, err, "GetRecentFiles")
+		if len(files) != 1 || files[0].Path != "/src/auth.ts" {
+			t.Fatalf("got %+v, want only auth.ts", files)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		files, err := d.GetRecentFiles(ctx, RecentFilesFilter{
+			Limit: 2, TouchLimit: 1,
+		})
+		requireNoError(t, err, "GetRecentFiles")
+		if len(files) != 2 {
+			t.Fatalf("got %d files, want 2", len(files))
+		}
+		if len(files[1].Touches) != 1 {
+			t.Fatalf("got %d touches, want 1",
+				len(files[1].Touches))
+		}
+		if kept := files[1].Touches[0]; kept.SessionID != "s2" ||
+			kept.Ordinal != 1 {
+			t.Errorf("kept touch = %s#%d, want newest s2#1",
+				kept.SessionID, kept.Ordinal)
+		}
+		if files[1].TouchCount != 3 {
+			t.Errorf("TouchCount = %d, want 3 (uncapped)",
+				files[1].TouchCount)
+		}
+	})
+}
+
+func TestMigration_ToolCallFilePathBackfill(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.db")
+
+	d, err := Open(path)
+	requireNoError(t, err, "initial open")
+	insertSession(t, d, "s1", "proj")
+	insertMessages(t, d, toolMsg("s1", 0, tsZero, ToolCall{
+		ToolName: "Read", Category: "Read",
+		InputJSON: `{"file_path":"/a.go"}`,
+	}))
+	d.Close()
+
+	conn, err := sql.Open("sqlite3", path)
+	requireNoError(t, err, "raw open")
+	_, err = conn.Exec(`
+		DROP INDEX IF EXISTS idx_tool_calls_file_path;
+		ALTER TABLE tool_calls DROP COLUMN file_path;
+	`)
+	requireNoError(t, err, "drop file_path column")
+	conn.Close()
+
+	d2, err := Open(path)
+	requireNoError(t, err, "reopen after migration")
+	defer d2.Close()
+
+	files, err := d2.GetRecentFiles(
+		context.Background(), RecentFilesFilter{},
+	)
+	requireNoError(t, err, "GetRecentFiles")
+	if len(files) != 1 || files[0].Path != "/a.go" {
+		t.Fatalf("got %+v, want backfilled /a.go", files)
+	}
+}
@@ -296,3 +296,50 @@ func TestActiveSinceUsesEndedAtOverStartedAt(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionFilterStartedEndedBounds(t *testing.T) {
+	d := testDB(t)
+
+	for _, s := range []struct{ id, started, ended string }{
+		{"old", "2024-05-20T10:00:00Z", "2024-05-20T11:00:00Z"},
+		{"running", "2024-06-03T10:00:00Z", "2024-06-12T09:00:00Z"},
+		{"finished", "2024-06-04T10:00:00Z", "2024-06-05T10:00:00Z"},
+		{"new", "2024-06-11T10:00:00Z", "2024-06-12T08:00:00Z"},
+	} {
+		insertSession(t, d, s.id, "proj", func(sess *Session) {
+			sess.StartedAt = Ptr(s.started)
+			sess.EndedAt = Ptr(s.ended)
+		})
+	}
+
+	tests := []struct {
+		name   string
+		filter SessionFilter
+		want   []string
+	}{
+		{
+			name: "StartedLastWeekStillRunning",
+			filter: SessionFilter{
+				StartedAfter:  "2024-06-03T00:00:00Z",
+				StartedBefore: "2024-06-10T00:00:00Z",
+				EndedAfter:    "2024-06-12T00:00:00Z",
+			},
+			want: []string{"running"},
+		},
+		{
+			name:   "EndedBefore",
+			filter: SessionFilter{EndedBefore: "2024-06-05T10:00:00Z"},
+			want:   []string{"old"},
+		},
+		{
+			name:   "StartedAfterInclusive",
+			filter: SessionFilter{StartedAfter: "2024-06-11T10:00:00Z"},
+			want:   []string{"new"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requireSessions(t, d, tt.filter, tt.want)
+		})
+	}
+}
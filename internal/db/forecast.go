@@ -0,0 +1,477 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/wesm/agentsview/internal/pricing"
+)
+
+// ForecastHorizonDays is the length of the forecast window
+// following the filter's end date.
+const ForecastHorizonDays = 7
+
+// forecastZ is the normal quantile for the 95% band.
+const forecastZ = 1.96
+
+// BaseCurrency is the currency of the built-in model prices.
+const BaseCurrency = "USD"
+
+// CostRate converts estimated cost from BaseCurrency into a
+// project's billing currency. A zero Multiplier means 1.
+type CostRate struct {
+	Currency   string  `json:"currency"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// costRate returns the rate for project, or the identity rate
+// when none is configured.
+func (f AnalyticsFilter) costRate(project string) CostRate {
+	r := f.CostRates[project]
+	if r.Currency == "" {
+		r.Currency = BaseCurrency
+	}
+	if r.Multiplier == 0 {
+		r.Multiplier = 1
+	}
+	return r
+}
+
+// ForecastValue is a predicted total with a 95% band. Low is
+// clamped at zero.
+type ForecastValue struct {
+	Predicted float64 `json:"predicted"`
+	Low       float64 `json:"low"`
+	High      float64 `json:"high"`
+}
+
+// ProjectForecast is the next-week forecast for one project.
+type ProjectForecast struct {
+	Project string `json:"project"`
+	// History totals over the fitted window.
+	HistoryMessages int           `json:"history_messages"`
+	HistoryCost     float64       `json:"history_cost"`
+	Messages        ForecastValue `json:"messages"`
+	Cost            ForecastValue `json:"cost"`
+	// Currency of HistoryCost and Cost.
+	Currency string `json:"currency"`
+}
+
+// MonthProjection projects the month containing the filter's
+// end date: actual cost through the end date plus forecast cost
+// for the rest of the month.
+type MonthProjection struct {
+	Month     string        `json:"month"` // YYYY-MM
+	ToDate    float64       `json:"to_date"`
+	DaysLeft  int           `json:"days_left"`
+	Projected ForecastValue `json:"projected"`
+}
+
+// ForecastResponse wraps the activity forecast.
+type ForecastResponse struct {
+	HistoryFrom string            `json:"history_from"`
+	HistoryTo   string            `json:"history_to"`
+	From        string            `json:"from"` // forecast window
+	To          string            `json:"to"`
+	Overall     ProjectForecast   `json:"overall"`
+	Projects    []ProjectForecast `json:"projects"`
+	Month       MonthProjection   `json:"month"`
+	// Currency of Overall and Month: the project's billing
+	// currency when the filter names a project with a cost
+	// rate, otherwise BaseCurrency.
+	Currency string `json:"currency"`
+}
+
+// GetAnalyticsForecast fits each project's daily message count
+// and estimated cost over [f.From, f.To] and predicts the
+// following ForecastHorizonDays. Days are bucketed by session
+// start date, as in the activity endpoint; cost is estimated
+// from per-message token usage with built-in model prices and
+// converted with f.CostRates per project.
+func (db *DB) GetAnalyticsForecast(
+	ctx context.Context, f AnalyticsFilter,
+) (ForecastResponse, error) {
+	start, err := time.Parse("2006-01-02", f.From)
+	if err != nil {
+		return ForecastResponse{}, fmt.Errorf("invalid from: %w", err)
+	}
+	end, err := time.Parse("2006-01-02", f.To)
+	if err != nil {
+		return ForecastResponse{}, fmt.Errorf("invalid to: %w", err)
+	}
+	monthStart := time.Date(
+		end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC,
+	)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+
+	// Month-to-date cost may predate the fitted window, so
+	// query from whichever starts first.
+	qf := f
+	if monthStart.Before(start) {
+		qf.From = monthStart.Format("2006-01-02")
+	}
+	msgs, costs, err := db.dailyProjectTotals(ctx, qf)
+	if err != nil {
+		return ForecastResponse{}, err
+	}
+
+	days := int(end.Sub(start).Hours()/24) + 1
+	dates := make([]string, days)
+	for i := range dates {
+		dates[i] = start.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	daysLeft := int(monthEnd.Sub(end).Hours() / 24)
+
+	resp := ForecastResponse{
+		HistoryFrom: f.From,
+		HistoryTo:   f.To,
+		From:        end.AddDate(0, 0, 1).Format("2006-01-02"),
+		To: end.AddDate(0, 0, ForecastHorizonDays).
+			Format("2006-01-02"),
+		Projects: []ProjectForecast{},
+	}
+
+	totalMsgs := map[string]float64{}
+	totalCost := map[string]float64{}
+	projects := make([]string, 0, len(msgs))
+	for p := range msgs {
+		projects = append(projects, p)
+	}
+	sort.Strings(projects)
+	// basePredicted ranks projects by predicted cost in
+	// BaseCurrency, since their own currencies may differ.
+	basePredicted := map[string]float64{}
+	for _, p := range projects {
+		for d, v := range msgs[p] {
+			totalMsgs[d] += v
+		}
+		for d, v := range costs[p] {
+			totalCost[d] += v
+		}
+		rate := f.costRate(p)
+		pf := buildProjectForecast(
+			p, dates, start.Weekday(), msgs[p],
+			scaleCosts(costs[p], rate.Multiplier),
+		)
+		if pf.HistoryMessages == 0 && pf.HistoryCost == 0 {
+			continue // active only before the fitted window
+		}
+		pf.Currency = rate.Currency
+		basePredicted[p] = pf.Cost.Predicted / rate.Multiplier
+		resp.Projects = append(resp.Projects, pf)
+	}
+	sort.SliceStable(resp.Projects, func(i, j int) bool {
+		return basePredicted[resp.Projects[i].Project] >
+			basePredicted[resp.Projects[j].Project]
+	})
+
+	// Totals span projects, so they stay in BaseCurrency
+	// unless the filter is scoped to a single project.
+	rate := CostRate{Currency: BaseCurrency, Multiplier: 1}
+	if f.Project != "" {
+		rate = f.costRate(f.Project)
+	}
+	totalCost = scaleCosts(totalCost, rate.Multiplier)
+	resp.Currency = rate.Currency
+	resp.Overall = buildProjectForecast(
+		"", dates, start.Weekday(), totalMsgs, totalCost,
+	)
+	resp.Overall.Currency = rate.Currency
+
+	resp.Month = MonthProjection{
+		Month:    monthStart.Format("2006-01"),
+		DaysLeft: daysLeft,
+	}
+	for d, v := range totalCost {
+		if d >= monthStart.Format("2006-01-02") &&
+			d <= f.To {
+			resp.Month.ToDate += v
+		}
+	}
+	resp.Month.ToDate = roundCost(resp.Month.ToDate)
+	costFit := fitDaily(series(dates, totalCost), start.Weekday())
+	rest := costFit.predict(daysLeft)
+	resp.Month.Projected = ForecastValue{
+		Predicted: roundCost(resp.Month.ToDate + rest.Predicted),
+		Low:       roundCost(resp.Month.ToDate + rest.Low),
+		High:      roundCost(resp.Month.ToDate + rest.High),
+	}
+	return resp, nil
+}
+
+// GetEstimatedCost returns the estimated cost in BaseCurrency
+// of the sessions matching f, priced like the forecast.
+func (db *DB) GetEstimatedCost(
+	ctx context.Context, f AnalyticsFilter,
+) (float64, error) {
+	_, costs, err := db.dailyProjectTotals(ctx, f)
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, days := range costs {
+		for _, v := range days {
+			total += v
+		}
+	}
+	return roundCost(total), nil
+}
+
+// dailyProjectTotals returns message counts and estimated cost
+// keyed by project, then local date.
+func (db *DB) dailyProjectTotals(
+	ctx context.Context, f AnalyticsFilter,
+) (msgs, costs map[string]map[string]float64, err error) {
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, project, message_count
+		FROM sessions WHERE `+where, args...,
+	)
+	if err != nil {
+		return nil, nil,
+			fmt.Errorf("querying forecast sessions: %w", err)
+	}
+	defer rows.Close()
+
+	type sessInfo struct {
+		date    string
+		project string
+	}
+	sessionMap := make(map[string]sessInfo)
+	var sessionIDs []string
+	msgs = map[string]map[string]float64{}
+	costs = map[string]map[string]float64{}
+	for rows.Next() {
+		var id, ts, project string
+		var mc int
+		if err := rows.Scan(&id, &ts, &project, &mc); err != nil {
+			return nil, nil,
+				fmt.Errorf("scanning forecast session: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		if msgs[project] == nil {
+			msgs[project] = map[string]float64{}
+			costs[project] = map[string]float64{}
+		}
+		msgs[project][date] += float64(mc)
+		sessionMap[id] = sessInfo{date: date, project: project}
+		sessionIDs = append(sessionIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil,
+			fmt.Errorf("iterating forecast sessions: %w", err)
+	}
+
+	err = queryChunked(sessionIDs, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		msgRows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, model, input_tokens,
+				output_tokens, cache_read_tokens,
+				cache_creation_tokens
+			FROM messages
+			WHERE session_id IN `+ph+`
+				AND model != ''`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying forecast messages: %w", err)
+		}
+		defer msgRows.Close()
+		for msgRows.Next() {
+			var sid, model string
+			var u pricing.Usage
+			if err := msgRows.Scan(
+				&sid, &model, &u.Input, &u.Output,
+				&u.CacheRead, &u.CacheWrite,
+			); err != nil {
+				return fmt.Errorf(
+					"scanning forecast message: %w", err,
+				)
+			}
+			info := sessionMap[sid]
+			costs[info.project][info.date] += pricing.Cost(model, u)
+		}
+		return msgRows.Err()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return msgs, costs, nil
+}
+
+func buildProjectForecast(
+	project string, dates []string, first time.Weekday,
+	msgs, costs map[string]float64,
+) ProjectForecast {
+	pf := ProjectForecast{Project: project}
+	ms := series(dates, msgs)
+	cs := series(dates, costs)
+	for i := range dates {
+		pf.HistoryMessages += int(ms[i])
+		pf.HistoryCost += cs[i]
+	}
+	pf.HistoryCost = roundCost(pf.HistoryCost)
+
+	m := fitDaily(ms, first).predict(ForecastHorizonDays)
+	pf.Messages = ForecastValue{
+		Predicted: math.Round(m.Predicted),
+		Low:       math.Round(m.Low),
+		High:      math.Round(m.High),
+	}
+	c := fitDaily(cs, first).predict(ForecastHorizonDays)
+	pf.Cost = ForecastValue{
+		Predicted: roundCost(c.Predicted),
+		Low:       roundCost(c.Low),
+		High:      roundCost(c.High),
+	}
+	return pf
+}
+
+// series returns values for each date, zero-filling gaps.
+func series(dates []string, values map[string]float64) []float64 {
+	out := make([]float64, len(dates))
+	for i, d := range dates {
+		out[i] = values[d]
+	}
+	return out
+}
+
+// scaleCosts returns costs multiplied by m.
+func scaleCosts(costs map[string]float64, m float64) map[string]float64 {
+	if m == 1 {
+		return costs
+	}
+	out := make(map[string]float64, len(costs))
+	for d, v := range costs {
+		out[d] = v * m
+	}
+	return out
+}
+
+func roundCost(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// dailyFit is a linear trend plus day-of-week offsets fitted to
+// a daily series.
+type dailyFit struct {
+	n         int
+	intercept float64
+	slope     float64
+	first     time.Weekday
+	weekday   [7]float64 // per-weekday intercept when seasonal
+	sigma     float64    // residual standard deviation
+}
+
+// fitDaily fits y = b*t + c by least squares. Given at least
+// two weeks of data, c is fitted per weekday (a dummy-variable
+// regression), so a weekly rhythm does not bias the trend.
+func fitDaily(y []float64, first time.Weekday) dailyFit {
+	fit := dailyFit{n: len(y), first: first}
+	if len(y) == 0 {
+		return fit
+	}
+	seasonal := len(y) >= 14
+	group := func(i int) int {
+		if seasonal {
+			return int(fit.dayOf(i))
+		}
+		return 0
+	}
+
+	var sumT, sumY [7]float64
+	var counts [7]int
+	for i, v := range y {
+		g := group(i)
+		sumT[g] += float64(i)
+		sumY[g] += v
+		counts[g]++
+	}
+	var meanT, meanY [7]float64
+	for g := range counts {
+		if counts[g] > 0 {
+			meanT[g] = sumT[g] / float64(counts[g])
+			meanY[g] = sumY[g] / float64(counts[g])
+		}
+	}
+
+	var sxx, sxy float64
+	for i, v := range y {
+		g := group(i)
+		dt := float64(i) - meanT[g]
+		sxx += dt * dt
+		sxy += dt * (v - meanY[g])
+	}
+	if sxx > 0 {
+		fit.slope = sxy / sxx
+	}
+	if seasonal {
+		for g := range fit.weekday {
+			fit.weekday[g] = meanY[g] - fit.slope*meanT[g]
+		}
+	} else {
+		fit.intercept = meanY[0] - fit.slope*meanT[0]
+	}
+
+	params := 2
+	if seasonal {
+		params = 8
+	}
+	if len(y) > params {
+		var ss float64
+		for i, v := range y {
+			e := v - fit.at(i)
+			ss += e * e
+		}
+		fit.sigma = math.Sqrt(ss / float64(len(y)-params))
+	}
+	return fit
+}
+
+// at is the fitted value for day i.
+func (f dailyFit) at(i int) float64 {
+	return f.intercept + f.slope*float64(i) + f.weekday[f.dayOf(i)]
+}
+
+func (f dailyFit) dayOf(i int) time.Weekday {
+	return (f.first + time.Weekday(i%7)) % 7
+}
+
+// predict sums the forecast for the given number of days
+// following the fitted series. The band widens with the square
+// root of the number of days summed.
+func (f dailyFit) predict(days int) ForecastValue {
+	if f.n == 0 || days <= 0 {
+		return ForecastValue{}
+	}
+	var total float64
+	for k := range days {
+		total += math.Max(f.at(f.n+k), 0)
+	}
+	band := forecastZ * f.sigma * math.Sqrt(float64(days))
+	return ForecastValue{
+		Predicted: total,
+		Low:       math.Max(total-band, 0),
+		High:      total + band,
+	}
+}
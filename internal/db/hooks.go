@@ -0,0 +1,260 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// HookEvent is one hook outcome recorded in a session.
+type HookEvent struct {
+	SessionID string `json:"session_id"`
+	HookEvent string `json:"hook_event"`
+	HookName  string `json:"hook_name"`
+	ToolName  string `json:"tool_name,omitempty"`
+	Command   string `json:"command,omitempty"`
+	Decision  string `json:"decision"`
+	Message   string `json:"message,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+}
+
+// ReplaceHookEvents replaces all hook events for a session.
+func (db *DB) ReplaceHookEvents(
+	sessionID string, events []HookEvent,
+) error {
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.Exec(
+			"DELETE FROM hook_events WHERE session_id = ?",
+			sessionID,
+		); err != nil {
+			return fmt.Errorf("deleting old hook events: %w", err)
+		}
+
+		if len(events) > 0 {
+			stmt, err := tx.Prepare(`
+				INSERT INTO hook_events
+					(session_id, hook_event, hook_name, tool_name,
+					 command, decision, message, timestamp)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+			if err != nil {
+				return fmt.Errorf("preparing hook insert: %w", err)
+			}
+			defer stmt.Close()
+			for _, e := range events {
+				if _, err := stmt.Exec(
+					sessionID, e.HookEvent, e.HookName, e.ToolName,
+					e.Command, e.Decision, e.Message,
+					nilIfEmpty(e.Timestamp),
+				); err != nil {
+					return fmt.Errorf("inserting hook event: %w", err)
+				}
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// GetSessionHookEvents returns a session's hook events in
+// recorded order.
+func (db *DB) GetSessionHookEvents(
+	ctx context.Context, sessionID string,
+) ([]HookEvent, error) {
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT session_id, hook_event, hook_name, tool_name,
+			command, decision, message, COALESCE(timestamp, '')
+		FROM hook_events
+		WHERE session_id = ?
+		ORDER BY id`, sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying hook events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []HookEvent{}
+	for rows.Next() {
+		var e HookEvent
+		if err := rows.Scan(
+			&e.SessionID, &e.HookEvent, &e.HookName, &e.ToolName,
+			&e.Command, &e.Decision, &e.Message, &e.Timestamp,
+		); err != nil {
+			return nil, fmt.Errorf("scanning hook event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// HookStats counts hook outcomes by decision.
+type HookStats struct {
+	Total    int `json:"total"`
+	Allowed  int `json:"allowed"`
+	Blocked  int `json:"blocked"`
+	Modified int `json:"modified"`
+	Stopped  int `json:"stopped"`
+	Errors   int `json:"errors"`
+	// BlockRate and ModifyRate are percentages of Total.
+	BlockRate  float64 `json:"block_rate"`
+	ModifyRate float64 `json:"modify_rate"`
+}
+
+func (h *HookStats) add(decision string) {
+	h.Total++
+	switch decision {
+	case "allow":
+		h.Allowed++
+	case "block":
+		h.Blocked++
+	case "modify":
+		h.Modified++
+	case "stop":
+		h.Stopped++
+	default:
+		h.Errors++
+	}
+}
+
+func (h *HookStats) finish() {
+	if h.Total == 0 {
+		return
+	}
+	h.BlockRate = math.Round(
+		float64(h.Blocked)/float64(h.Total)*1000,
+	) / 10
+	h.ModifyRate = math.Round(
+		float64(h.Modified)/float64(h.Total)*1000,
+	) / 10
+}
+
+// HookBreakdown is hook outcomes for one hook name.
+type HookBreakdown struct {
+	Name string `json:"name"`
+	HookStats
+}
+
+// HooksAnalyticsResponse wraps hook analytics.
+type HooksAnalyticsResponse struct {
+	SessionsWithHooks int             `json:"sessions_with_hooks"`
+	Overall           HookStats       `json:"overall"`
+	ByEvent           []HookBreakdown `json:"by_event"`
+	ByHook            []HookBreakdown `json:"by_hook"`
+}
+
+// GetAnalyticsHooks reports how often hooks allow, block, or
+// modify agent behavior across the filtered sessions.
+func (db *DB) GetAnalyticsHooks(
+	ctx context.Context, f AnalyticsFilter,
+) (HooksAnalyticsResponse, error) {
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return HooksAnalyticsResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+` FROM sessions WHERE `+where+`
+		AND id IN (SELECT session_id FROM hook_events)`,
+		args...,
+	)
+	if err != nil {
+		return HooksAnalyticsResponse{},
+			fmt.Errorf("querying hook sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessionIDs []string
+	for rows.Next() {
+		var id, ts string
+		if err := rows.Scan(&id, &ts); err != nil {
+			return HooksAnalyticsResponse{},
+				fmt.Errorf("scanning hook session: %w", err)
+		}
+		if !inDateRange(localDate(ts, loc), f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return HooksAnalyticsResponse{},
+			fmt.Errorf("iterating hook sessions: %w", err)
+	}
+
+	resp := HooksAnalyticsResponse{
+		SessionsWithHooks: len(sessionIDs),
+	}
+	byEvent := map[string]*HookStats{}
+	byHook := map[string]*HookStats{}
+	bump := func(m map[string]*HookStats, key, decision string) {
+		if m[key] == nil {
+			m[key] = &HookStats{}
+		}
+		m[key].add(decision)
+	}
+
+	err = queryChunked(sessionIDs, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		hookRows, err := db.getReader().QueryContext(ctx,
+			`SELECT hook_event, hook_name, decision
+			FROM hook_events
+			WHERE session_id IN `+ph,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying hook events: %w", err)
+		}
+		defer hookRows.Close()
+		for hookRows.Next() {
+			var event, name, decision string
+			if err := hookRows.Scan(
+				&event, &name, &decision,
+			); err != nil {
+				return fmt.Errorf("scanning hook event: %w", err)
+			}
+			resp.Overall.add(decision)
+			bump(byEvent, event, decision)
+			bump(byHook, name, decision)
+		}
+		return hookRows.Err()
+	})
+	if err != nil {
+		return HooksAnalyticsResponse{}, err
+	}
+
+	resp.Overall.finish()
+	resp.ByEvent = hookBreakdowns(byEvent)
+	resp.ByHook = hookBreakdowns(byHook)
+	return resp, nil
+}
+
+// hookBreakdowns flattens m, ordered by total descending.
+func hookBreakdowns(m map[string]*HookStats) []HookBreakdown {
+	out := make([]HookBreakdown, 0, len(m))
+	for name, s := range m {
+		s.finish()
+		out = append(out, HookBreakdown{Name: name, HookStats: *s})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReplaceHookEvents(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+
+	requireNoError(t, d.ReplaceHookEvents("s1", []HookEvent{
+		{HookEvent: "PreToolUse", HookName: "PreToolUse:Bash", Decision: "allow"},
+		{HookEvent: "Stop", HookName: "Stop", Decision: "block", Message: "tests failing"},
+	}), "ReplaceHookEvents")
+	requireNoError(t, d.ReplaceHookEvents("s1", []HookEvent{
+		{HookEvent: "Stop", HookName: "Stop", Decision: "block", Message: "tests failing"},
+	}), "ReplaceHookEvents again")
+
+	got, err := d.GetSessionHookEvents(ctx, "s1")
+	requireNoError(t, err, "GetSessionHookEvents")
+	if len(got) != 1 {
+		t.Fatalf("len = %d, want 1", len(got))
+	}
+	assertEq(t, "SessionID", got[0].SessionID, "s1")
+	assertEq(t, "Message", got[0].Message, "tests failing")
+
+	// Deleting the session removes its hook events.
+	requireNoError(t, d.DeleteSession("s1"), "DeleteSession")
+	got, err = d.GetSessionHookEvents(ctx, "s1")
+	requireNoError(t, err, "GetSessionHookEvents after delete")
+	if len(got) != 0 {
+		t.Errorf("len after delete = %d, want 0", len(got))
+	}
+}
+
+func TestGetAnalyticsHooks(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "h1", "proj", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+	})
+	insertSession(t, d, "h2", "proj", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-02T09:00:00Z")
+	})
+	insertSession(t, d, "none", "proj", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-02T09:00:00Z")
+	})
+
+	requireNoError(t, d.ReplaceHookEvents("h1", []HookEvent{
+		{HookEvent: "PostToolUse", HookName: "PostToolUse:Edit", Decision: "allow"},
+		{HookEvent: "PostToolUse", HookName: "PostToolUse:Edit", Decision: "block"},
+		{HookEvent: "Stop", HookName: "Stop", Decision: "block"},
+	}), "ReplaceHookEvents h1")
+	requireNoError(t, d.ReplaceHookEvents("h2", []HookEvent{
+		{HookEvent: "UserPromptSubmit", HookName: "UserPromptSubmit", Decision: "modify"},
+	}), "ReplaceHookEvents h2")
+
+	resp, err := d.GetAnalyticsHooks(ctx, baseFilter())
+	requireNoError(t, err, "GetAnalyticsHooks")
+
+	assertEq(t, "SessionsWithHooks", resp.SessionsWithHooks, 2)
+	assertEq(t, "Overall.Total", resp.Overall.Total, 4)
+	assertEq(t, "Overall.Blocked", resp.Overall.Blocked, 2)
+	assertEq(t, "Overall.BlockRate", resp.Overall.BlockRate, 50.0)
+	assertEq(t, "Overall.ModifyRate", resp.Overall.ModifyRate, 25.0)
+
+	if len(resp.ByEvent) != 3 {
+		t.Fatalf("len(ByEvent) = %d, want 3", len(resp.ByEvent))
+	}
+	assertEq(t, "ByEvent[0].Name", resp.ByEvent[0].Name, "PostToolUse")
+	assertEq(t, "ByEvent[0].Total", resp.ByEvent[0].Total, 2)
+	assertEq(t, "ByHook[0].Name", resp.ByHook[0].Name, "PostToolUse:Edit")
+}
@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Outcomes of ingesting a session pushed from another machine.
+const (
+	// IngestStored means the pushed copy was written.
+	IngestStored = "stored"
+	// IngestUnchanged means the stored copy came from the same
+	// source file contents, so there was nothing to write.
+	IngestUnchanged = "unchanged"
+	// IngestStale means the stored copy came from a newer
+	// version of the source file than the pushed one.
+	IngestStale = "stale"
+)
+
+// SessionSource identifies the source file contents a session
+// was parsed from, for deciding whether a pushed copy differs
+// from the stored one.
+type SessionSource struct {
+	ID        string `json:"id"`
+	FileHash  string `json:"file_hash,omitempty"`
+	FileMtime int64  `json:"file_mtime,omitempty"`
+}
+
+// IngestSession is a session and its messages as pushed by
+// another agentsview instance.
+type IngestSession struct {
+	Session  Session   `json:"session"`
+	Messages []Message `json:"messages"`
+}
+
+// Source returns the source identity of the pushed session.
+func (in IngestSession) Source() SessionSource {
+	src := SessionSource{ID: in.Session.ID}
+	if in.Session.FileHash != nil {
+		src.FileHash = *in.Session.FileHash
+	}
+	if in.Session.FileMtime != nil {
+		src.FileMtime = *in.Session.FileMtime
+	}
+	return src
+}
+
+// ListSessionSources returns the source identity of every
+// session, subagent sessions included, optionally limited to
+// one project, ordered by ID.
+func (db *DB) ListSessionSources(
+	ctx context.Context, project string,
+) ([]SessionSource, error) {
+	query := `SELECT id, COALESCE(file_hash, ''),
+		COALESCE(file_mtime, 0) FROM sessions`
+	var args []any
+	if project != "" {
+		query += " WHERE project = ?"
+		args = append(args, project)
+	}
+	rows, err := db.getReader().QueryContext(
+		ctx, query+" ORDER BY id", args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing session sources: %w", err)
+	}
+	defer rows.Close()
+
+	var out []SessionSource
+	for rows.Next() {
+		var s SessionSource
+		if err := rows.Scan(&s.ID, &s.FileHash, &s.FileMtime); err != nil {
+			return nil, fmt.Errorf("scanning session source: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// IngestDecision reports what ingesting a session from src
+// would do. A stored copy with the same file hash is
+// unchanged; one whose file is newer than src's is stale and
+// kept. Anything else, including copies without a hash, is
+// replaced.
+func (db *DB) IngestDecision(
+	ctx context.Context, src SessionSource,
+) (string, error) {
+	var hash sql.NullString
+	var mtime sql.NullInt64
+	err := db.getReader().QueryRowContext(ctx,
+		"SELECT file_hash, file_mtime FROM sessions WHERE id = ?",
+		src.ID,
+	).Scan(&hash, &mtime)
+	if err == sql.ErrNoRows {
+		return IngestStored, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading session %s source: %w", src.ID, err)
+	}
+	if src.FileHash != "" && hash.String == src.FileHash {
+		return IngestUnchanged, nil
+	}
+	if src.FileMtime != 0 && mtime.Int64 > src.FileMtime {
+		return IngestStale, nil
+	}
+	return IngestStored, nil
+}
+
+// Ingest writes a pushed session and its messages unless
+// IngestDecision says the stored copy should be kept, and
+// returns the outcome. Everything is written in one
+// transaction. A machine whose name looks like a CI host is
+// labeled a bot; a headless session is only recorded as such,
+// since one scripted run does not make the machine that pushed
+// it a bot.
+func (db *DB) Ingest(
+	ctx context.Context, in IngestSession,
+) (string, error) {
+	status, err := db.IngestDecision(ctx, in.Source())
+	if err != nil || status != IngestStored {
+		return status, err
+	}
+
+	msgs := make([]Message, len(in.Messages))
+	for i, m := range in.Messages {
+		m.ID = 0
+		m.SessionID = in.Session.ID
+		msgs[i] = m
+	}
+	err = db.write(func() error {
+		tx, err := db.getWriter().BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("beginning ingest tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if err := upsertSession(tx, in.Session); err != nil {
+			return err
+		}
+		if reason := BotReason(in.Session.Machine, false); reason != "" {
+			if err := markBotMachine(
+				tx, in.Session.Machine, reason,
+			); err != nil {
+				return fmt.Errorf("labeling machine: %w", err)
+			}
+		}
+		if err := db.replaceSessionMessagesTx(
+			tx, in.Session.ID, msgs,
+		); err != nil {
+			return fmt.Errorf("storing messages: %w", err)
+		}
+		if err := updateSessionQuality(tx, in.Session.ID); err != nil {
+			return fmt.Errorf("storing quality score: %w", err)
+		}
+		if err := updateSessionLanguage(tx, in.Session.ID); err != nil {
+			return fmt.Errorf("storing session language: %w", err)
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return "", err
+	}
+	return IngestStored, nil
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func ingestFixture(id, hash string, mtime int64, content string) IngestSession {
+	return IngestSession{
+		Session: Session{
+			ID:           id,
+			Project:      "proj",
+			Machine:      "laptop",
+			Agent:        defaultAgent,
+			MessageCount: 1,
+			FileHash:     Ptr(hash),
+			FileMtime:    Ptr(mtime),
+		},
+		Messages: []Message{{
+			ID:      99,
+			Ordinal: 0,
+			Role:    "user",
+			Content: content,
+			ToolCalls: []ToolCall{{
+				ToolName: "Read", Category: "Read",
+			}},
+		}},
+	}
+}
+
+func TestIngest(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	ingest := func(in IngestSession, want string) {
+		t.Helper()
+		got, err := d.Ingest(ctx, in)
+		requireNoError(t, err, "Ingest")
+		if got != want {
+			t.Errorf("Ingest(%s) = %q, want %q", in.Session.ID, got, want)
+		}
+	}
+	content := func() string {
+		t.Helper()
+		msgs, err := d.GetAllMessages(ctx, "s1")
+		requireNoError(t, err, "GetAllMessages")
+		if len(msgs) != 1 || len(msgs[0].ToolCalls) != 1 {
+			t.Fatalf("messages = %+v, want one with a tool call", msgs)
+		}
+		return msgs[0].Content
+	}
+
+	ingest(ingestFixture("s1", "h1", 100, "first"), IngestStored)
+	assertEq(t, "content", content(), "first")
+	s, err := d.GetSessionFull(ctx, "s1")
+	requireNoError(t, err, "GetSessionFull")
+	if s.Machine != "laptop" || s.QualityScore == nil {
+		t.Errorf("session = %+v, want machine laptop with a score", s)
+	}
+
+	ingest(ingestFixture("s1", "h1", 200, "same hash"), IngestUnchanged)
+	assertEq(t, "content", content(), "first")
+
+	ingest(ingestFixture("s1", "h0", 50, "older file"), IngestStale)
+	assertEq(t, "content", content(), "first")
+
+	ingest(ingestFixture("s1", "h2", 300, "changed"), IngestStored)
+	assertEq(t, "content", content(), "changed")
+
+	srcs, err := d.ListSessionSources(ctx, "")
+	requireNoError(t, err, "ListSessionSources")
+	want := SessionSource{ID: "s1", FileHash: "h2", FileMtime: 300}
+	if len(srcs) != 1 || srcs[0] != want {
+		t.Errorf("sources = %+v, want [%+v]", srcs, want)
+	}
+	srcs, err = d.ListSessionSources(ctx, "other")
+	requireNoError(t, err, "ListSessionSources other")
+	if len(srcs) != 0 {
+		t.Errorf("other project sources = %+v, want none", srcs)
+	}
+}
+
+func TestIngestLabelsBotMachines(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	runner := ingestFixture("ci", "h1", 100, "from ci")
+	runner.Session.Machine = "runner-7f3a"
+	headless := ingestFixture("cron", "h1", 100, "scheduled")
+	headless.Session.Headless = true
+	for _, in := range []IngestSession{
+		runner, headless, ingestFixture("s1", "h1", 100, "human"),
+	} {
+		_, err := d.Ingest(ctx, in)
+		requireNoError(t, err, "Ingest "+in.Session.ID)
+	}
+
+	// A headless session is recorded on the session and leaves
+	// the laptop that pushed it unlabeled.
+	bots, err := d.GetBotMachines(ctx)
+	requireNoError(t, err, "GetBotMachines")
+	if len(bots) != 1 || bots[0] != "runner-7f3a" {
+		t.Errorf("bots = %v, want [runner-7f3a]", bots)
+	}
+	s, err := d.GetSessionFull(ctx, "cron")
+	requireNoError(t, err, "GetSessionFull")
+	if !s.Headless {
+		t.Errorf("cron session not recorded as headless")
+	}
+}
+
+func TestIngestIsAtomic(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	in := ingestFixture("ci", "h1", 100, "first")
+	in.Session.Machine = "runner-7f3a"
+	// A repeated ordinal fails the message insert after the
+	// session and machine label were written.
+	in.Messages = append(in.Messages, in.Messages[0])
+	if _, err := d.Ingest(ctx, in); err == nil {
+		t.Fatal("Ingest with duplicate ordinals succeeded")
+	}
+
+	s, err := d.GetSession(ctx, "ci")
+	requireNoError(t, err, "GetSession")
+	if s != nil {
+		t.Errorf("session stored after failed ingest: %+v", s)
+	}
+	bots, err := d.GetBotMachines(ctx)
+	requireNoError(t, err, "GetBotMachines")
+	if len(bots) != 0 {
+		t.Errorf("bots = %v after failed ingest, want none", bots)
+	}
+}
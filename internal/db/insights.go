@@ -66,21 +66,26 @@ func buildInsightFilter(
 
 // InsertInsight inserts an insight and returns its ID.
 func (db *DB) InsertInsight(s Insight) (int64, error) {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-
-	res, err := db.getWriter().Exec(`
-		INSERT INTO insights (
-			type, date_from, date_to, project,
-			agent, model, prompt, content
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		s.Type, s.DateFrom, s.DateTo, s.Project,
-		s.Agent, s.Model, s.Prompt, s.Content,
-	)
+	var id int64
+	err := db.writePriority(func() error {
+		res, err := db.getWriter().Exec(`
+			INSERT INTO insights (
+				type, date_from, date_to, project,
+				agent, model, prompt, content
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.Type, s.DateFrom, s.DateTo, s.Project,
+			s.Agent, s.Model, s.Prompt, s.Content,
+		)
+		if err != nil {
+			return fmt.Errorf("inserting insight: %w", err)
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
 	if err != nil {
-		return 0, fmt.Errorf("inserting insight: %w", err)
+		return 0, err
 	}
-	return res.LastInsertId()
+	return id, nil
 }
 
 const maxInsights = 500
@@ -139,50 +144,49 @@ func (db *DB) GetInsight(
 // CopyInsightsFrom copies all insights from the database at
 // sourcePath into this database using ATTACH/DETACH.
 func (db *DB) CopyInsightsFrom(sourcePath string) error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-
-	// Pin a single connection for the ATTACH/INSERT/DETACH
-	// sequence. database/sql's pool doesn't guarantee the
-	// same underlying connection across separate Exec calls,
-	// and ATTACH is connection-scoped.
-	ctx := context.Background()
-	conn, err := db.getWriter().Conn(ctx)
-	if err != nil {
-		return fmt.Errorf("acquiring connection: %w", err)
-	}
-	defer conn.Close()
+	return db.write(func() error {
+		// Pin a single connection for the ATTACH/INSERT/DETACH
+		// sequence. database/sql's pool doesn't guarantee the
+		// same underlying connection across separate Exec calls,
+		// and ATTACH is connection-scoped.
+		ctx := context.Background()
+		conn, err := db.getWriter().Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("acquiring connection: %w", err)
+		}
+		defer conn.Close()
 
-	if _, err := conn.ExecContext(
-		ctx, "ATTACH DATABASE ? AS old_db", sourcePath,
-	); err != nil {
-		return fmt.Errorf("attaching source db: %w", err)
-	}
-	defer func() {
-		_, _ = conn.ExecContext(
-			ctx, "DETACH DATABASE old_db",
-		)
-	}()
-
-	_, err = conn.ExecContext(ctx, `
-		INSERT OR IGNORE INTO insights
-			(type, date_from, date_to, project,
-			 agent, model, prompt, content, created_at)
-		SELECT type, date_from, date_to, project,
-			agent, model, prompt, content, created_at
-		FROM old_db.insights`)
-	if err != nil {
-		return fmt.Errorf("copying insights: %w", err)
-	}
-	return nil
+		if _, err := conn.ExecContext(
+			ctx, "ATTACH DATABASE ? AS old_db", sourcePath,
+		); err != nil {
+			return fmt.Errorf("attaching source db: %w", err)
+		}
+		defer func() {
+			_, _ = conn.ExecContext(
+				ctx, "DETACH DATABASE old_db",
+			)
+		}()
+
+		_, err = conn.ExecContext(ctx, `
+			INSERT OR IGNORE INTO insights
+				(type, date_from, date_to, project,
+				 agent, model, prompt, content, created_at)
+			SELECT type, date_from, date_to, project,
+				agent, model, prompt, content, created_at
+			FROM old_db.insights`)
+		if err != nil {
+			return fmt.Errorf("copying insights: %w", err)
+		}
+		return nil
+	})
 }
 
 // DeleteInsight removes an insight by ID.
 func (db *DB) DeleteInsight(id int64) error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	_, err := db.getWriter().Exec(
-		"DELETE FROM insights WHERE id = ?", id,
-	)
-	return err
+	return db.writePriority(func() error {
+		_, err := db.getWriter().Exec(
+			"DELETE FROM insights WHERE id = ?", id,
+		)
+		return err
+	})
 }
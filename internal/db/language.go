@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// UpdateSessionLanguage sets a session's language to the one
+// most of its typed user messages are written in, breaking
+// ties by the text written in each. Sessions whose prompts are
+// all undetermined get an empty language.
+func (db *DB) UpdateSessionLanguage(sessionID string) error {
+	return db.write(func() error {
+		return updateSessionLanguage(db.getWriter(), sessionID)
+	})
+}
+
+// updateSessionLanguage runs UpdateSessionLanguage on x.
+func updateSessionLanguage(x execer, sessionID string) error {
+	var lang string
+	err := x.QueryRow(`
+		SELECT language FROM messages
+		WHERE session_id = ? AND role = 'user' AND language != ''
+		GROUP BY language
+		ORDER BY COUNT(*) DESC, SUM(content_length) DESC, language
+		LIMIT 1`,
+		sessionID,
+	).Scan(&lang)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("reading session language: %w", err)
+	}
+	if _, err := x.Exec(
+		"UPDATE sessions SET language = ? WHERE id = ?",
+		lang, sessionID,
+	); err != nil {
+		return fmt.Errorf("updating session language: %w", err)
+	}
+	return nil
+}
+
+// LanguageAgentAnalytics is one agent's sessions within a
+// prompt language, with their quality scores for comparing
+// how agents fare across languages.
+type LanguageAgentAnalytics struct {
+	Agent       string  `json:"agent"`
+	Sessions    int     `json:"sessions"`
+	AvgQuality  float64 `json:"avg_quality"`
+	LowSessions int     `json:"low_sessions"`
+}
+
+// LanguageAnalytics holds usage from sessions whose prompts
+// are mostly in one language. Sessions whose language could
+// not be told share the empty language.
+type LanguageAnalytics struct {
+	Language     string                   `json:"language"`
+	Sessions     int                      `json:"sessions"`
+	Messages     int                      `json:"messages"`
+	UserMessages int                      `json:"user_messages"`
+	AvgQuality   float64                  `json:"avg_quality"`
+	LowSessions  int                      `json:"low_sessions"`
+	Agents       []LanguageAgentAnalytics `json:"agents"`
+}
+
+// LanguagesAnalyticsResponse wraps the languages list.
+type LanguagesAnalyticsResponse struct {
+	Threshold int                 `json:"threshold"`
+	Languages []LanguageAnalytics `json:"languages"`
+}
+
+// GetAnalyticsLanguages breaks sessions down by the dominant
+// language of their prompts, and each language down by agent.
+// Average quality covers scored sessions only; low sessions
+// score below LowQualityThreshold.
+func (db *DB) GetAnalyticsLanguages(
+	ctx context.Context, f AnalyticsFilter,
+) (LanguagesAnalyticsResponse, error) {
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return LanguagesAnalyticsResponse{}, err
+		}
+	}
+
+	query := `SELECT id, language, agent, ` + dateCol + `,
+		message_count, user_message_count, quality_score
+		FROM sessions WHERE ` + where
+
+	rows, err := db.getReader().QueryContext(ctx, query, args...)
+	if err != nil {
+		return LanguagesAnalyticsResponse{},
+			fmt.Errorf("querying analytics languages: %w", err)
+	}
+	defer rows.Close()
+
+	type agentData struct {
+		LanguageAgentAnalytics
+		qualityScores
+	}
+	type langData struct {
+		LanguageAnalytics
+		qualityScores
+		agents map[string]*agentData
+	}
+	langs := make(map[string]*langData)
+
+	for rows.Next() {
+		var id, lang, agent, ts string
+		var mc, umc int
+		var score sql.NullInt64
+		if err := rows.Scan(
+			&id, &lang, &agent, &ts, &mc, &umc, &score,
+		); err != nil {
+			return LanguagesAnalyticsResponse{},
+				fmt.Errorf("scanning language row: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+
+		ld, ok := langs[lang]
+		if !ok {
+			ld = &langData{
+				LanguageAnalytics: LanguageAnalytics{Language: lang},
+				agents:            make(map[string]*agentData),
+			}
+			langs[lang] = ld
+		}
+		ad, ok := ld.agents[agent]
+		if !ok {
+			ad = &agentData{
+				LanguageAgentAnalytics: LanguageAgentAnalytics{Agent: agent},
+			}
+			ld.agents[agent] = ad
+		}
+		ld.Sessions++
+		ld.Messages += mc
+		ld.UserMessages += umc
+		ad.Sessions++
+		if score.Valid {
+			s := int(score.Int64)
+			ld.n++
+			ld.sum += s
+			ad.n++
+			ad.sum += s
+			if s < LowQualityThreshold {
+				ld.LowSessions++
+				ad.LowSessions++
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return LanguagesAnalyticsResponse{},
+			fmt.Errorf("iterating language rows: %w", err)
+	}
+
+	resp := LanguagesAnalyticsResponse{
+		Threshold: LowQualityThreshold,
+		Languages: make([]LanguageAnalytics, 0, len(langs)),
+	}
+	for _, ld := range langs {
+		ld.AvgQuality = ld.avg()
+		ld.Agents = make([]LanguageAgentAnalytics, 0, len(ld.agents))
+		for _, ad := range ld.agents {
+			ad.AvgQuality = ad.avg()
+			ld.Agents = append(ld.Agents, ad.LanguageAgentAnalytics)
+		}
+		sort.Slice(ld.Agents, func(i, j int) bool {
+			a, b := ld.Agents[i], ld.Agents[j]
+			if a.Sessions != b.Sessions {
+				return a.Sessions > b.Sessions
+			}
+			return a.Agent < b.Agent
+		})
+		resp.Languages = append(resp.Languages, ld.LanguageAnalytics)
+	}
+	sort.Slice(resp.Languages, func(i, j int) bool {
+		a, b := resp.Languages[i], resp.Languages[j]
+		if a.Sessions != b.Sessions {
+			return a.Sessions > b.Sessions
+		}
+		return a.Language < b.Language
+	})
+	return resp, nil
+}
+
+// qualityScores sums the quality scores of scored sessions.
+type qualityScores struct{ n, sum int }
+
+// avg returns the mean score, or 0 when nothing was scored.
+func (q qualityScores) avg() float64 {
+	if q.n == 0 {
+		return 0
+	}
+	return round1(float64(q.sum) / float64(q.n))
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+// langMsgs returns typed user messages in the given languages.
+func langMsgs(sid string, langs ...string) []Message {
+	msgs := make([]Message, len(langs))
+	for i, lang := range langs {
+		msgs[i] = userMsg(sid, i, "prompt")
+		msgs[i].Language = lang
+	}
+	return msgs
+}
+
+func TestUpdateSessionLanguage(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		id    string
+		langs []string
+		want  string
+	}{
+		{"mostly-ja", []string{"en", "ja", "ja", ""}, "ja"},
+		{"tie-by-text", []string{"en", "ja"}, "en"},
+		{"undetermined", []string{"", ""}, ""},
+	}
+	for _, tt := range tests {
+		insertSession(t, d, tt.id, "alpha")
+		msgs := langMsgs(tt.id, tt.langs...)
+		if tt.id == "tie-by-text" {
+			msgs[0].ContentLength = 100
+		}
+		insertMessages(t, d, msgs...)
+		requireNoError(t, d.UpdateSessionLanguage(tt.id), tt.id)
+
+		s, err := d.GetSession(ctx, tt.id)
+		requireNoError(t, err, "GetSession")
+		assertEq(t, tt.id, s.Language, tt.want)
+	}
+	requireNoError(t, d.UpdateSessionLanguage("missing"), "missing")
+
+	requireCount(t, d, filterWith(func(f *SessionFilter) {
+		f.Language = "ja"
+	}), 1)
+}
+
+func TestGetAnalyticsLanguages(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	for _, s := range []struct {
+		id, agent, lang string
+		interrupts      int
+	}{
+		{"a", "claude", "ja", 0},
+		{"b", "codex", "ja", 5},
+		{"c", "claude", "ja", 1},
+		{"d", "claude", "en", 0},
+	} {
+		insertSession(t, d, s.id, "alpha", func(sess *Session) {
+			sess.Agent = s.agent
+			sess.StartedAt = Ptr("2024-06-03T09:00:00Z")
+			sess.InterruptCount = s.interrupts
+		})
+		insertMessages(t, d, langMsgs(s.id, s.lang)...)
+		requireNoError(t, d.UpdateSessionQuality(s.id), s.id)
+		requireNoError(t, d.UpdateSessionLanguage(s.id), s.id)
+	}
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsLanguages(ctx, f)
+	requireNoError(t, err, "GetAnalyticsLanguages")
+	if len(resp.Languages) != 2 {
+		t.Fatalf("Languages = %+v, want 2", resp.Languages)
+	}
+	ja := resp.Languages[0]
+	assertEq(t, "first language", ja.Language, "ja")
+	assertEq(t, "ja sessions", ja.Sessions, 3)
+	assertEq(t, "ja avg quality", ja.AvgQuality, 86.7)
+	if len(ja.Agents) != 2 {
+		t.Fatalf("ja Agents = %+v, want 2", ja.Agents)
+	}
+	assertEq(t, "ja claude", ja.Agents[0], LanguageAgentAnalytics{
+		Agent: "claude", Sessions: 2, AvgQuality: 95,
+	})
+	assertEq(t, "ja codex", ja.Agents[1], LanguageAgentAnalytics{
+		Agent: "codex", Sessions: 1, AvgQuality: 70,
+	})
+
+	f.Language = "en"
+	resp, err = d.GetAnalyticsLanguages(ctx, f)
+	requireNoError(t, err, "GetAnalyticsLanguages en")
+	if len(resp.Languages) != 1 || resp.Languages[0].Sessions != 1 {
+		t.Errorf("en Languages = %+v, want d only", resp.Languages)
+	}
+}
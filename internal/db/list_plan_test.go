@@ -0,0 +1,98 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+// explainPlan returns the EXPLAIN QUERY PLAN detail lines for query.
+func explainPlan(
+	t *testing.T, d *DB, query string, args ...any,
+) string {
+	t.Helper()
+	rows, err := d.getReader().Query(
+		"EXPLAIN QUERY PLAN "+query, args...,
+	)
+	requireNoError(t, err, "explain query plan")
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		requireNoError(t,
+			rows.Scan(&id, &parent, &notUsed, &detail),
+			"scanning plan row",
+		)
+		lines = append(lines, detail)
+	}
+	requireNoError(t, rows.Err(), "iterating plan rows")
+	return strings.Join(lines, "\n")
+}
+
+func TestListSessionsQueryPlans(t *testing.T) {
+	d := testDB(t)
+	cur := SessionCursor{EndedAt: "2024-06-01T00:00:00Z", ID: "s1"}
+
+	tests := []struct {
+		name      string
+		filter    SessionFilter
+		wantIndex string
+	}{
+		{"NoFilter", SessionFilter{}, "idx_sessions_list_recency"},
+		{"Project", SessionFilter{Project: "p"}, "idx_sessions_list_project"},
+		{"Agent", SessionFilter{Agent: "codex"}, "idx_sessions_list_agent"},
+		{"Machine", SessionFilter{Machine: "m"}, "idx_sessions_list_machine"},
+		{"Query", SessionFilter{Query: "login"}, "idx_sessions_list_recency"},
+		{
+			"ProjectWithCursor",
+			SessionFilter{Project: "p", Cursor: "c"},
+			"idx_sessions_list_project",
+		},
+		{
+			"DateRange",
+			SessionFilter{DateFrom: "2024-01-01", DateTo: "2024-12-31"},
+			"idx_sessions_list_recency",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.filter
+			f.Limit = DefaultSessionLimit
+			where, args := buildSessionFilter(f)
+			// The unpinned run holds nearly every session; the
+			// pinned run is a primary key lookup per pinned row.
+			where += " AND NOT " + pinnedPred
+			query, qargs := sessionPageQuery(where, args, f, cur)
+
+			plan := explainPlan(t, d, query, qargs...)
+			if !strings.Contains(plan, tt.wantIndex) {
+				t.Errorf("plan does not use %s:\n%s",
+					tt.wantIndex, plan)
+			}
+			if strings.Contains(plan, "TEMP B-TREE") {
+				t.Errorf("plan sorts in a temp b-tree:\n%s", plan)
+			}
+		})
+	}
+}
+
+func TestListSessionsCountPlan(t *testing.T) {
+	d := testDB(t)
+
+	for _, f := range []SessionFilter{
+		{Project: "p"},
+		{Project: "p", Agent: "claude"},
+	} {
+		where, args := buildSessionFilter(f)
+		plan := explainPlan(t, d,
+			"SELECT COUNT(*) FROM sessions WHERE "+where, args...,
+		)
+		if !strings.Contains(plan,
+			"COVERING INDEX idx_sessions_list_covering",
+		) {
+			t.Errorf("count for %+v not covered:\n%s", f, plan)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Reasons recorded when a machine is labeled as a bot.
+const (
+	BotReasonHostname  = "hostname"
+	BotReasonAutomated = "automated"
+)
+
+// botHostnamePrefixes lists lowercase hostname prefixes used by
+// hosted CI runners. "fv-az" is the GitHub-hosted runner
+// naming scheme.
+var botHostnamePrefixes = []string{
+	"runner-", "gha-", "github-actions", "gitlab-runner",
+	"buildkite", "jenkins", "circleci", "travis-", "ci-",
+	"fv-az",
+}
+
+// IsBotHostname reports whether a machine name looks like a CI
+// runner rather than a personal machine.
+func IsBotHostname(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range botHostnamePrefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return strings.HasSuffix(name, "-ci") ||
+		strings.Contains(name, "-runner-")
+}
+
+// BotReason returns why a machine that produced a session
+// should be labeled as a bot, or "" if it should not.
+// automated reports that the session itself recorded a
+// non-interactive origin. The local machine is never labeled.
+func BotReason(machine string, automated bool) string {
+	switch {
+	case machine == "" || machine == "local":
+		return ""
+	case IsBotHostname(machine):
+		return BotReasonHostname
+	case automated:
+		return BotReasonAutomated
+	}
+	return ""
+}
+
+// MarkBotMachine labels a machine as a bot. Machines that
+// already have a label keep it, so an explicit classification
+// is never overwritten by detection.
+func (db *DB) MarkBotMachine(name, reason string) error {
+	return db.writePriority(func() error {
+		return markBotMachine(db.getWriter(), name, reason)
+	})
+}
+
+// markBotMachine runs MarkBotMachine's statement on x.
+func markBotMachine(x execer, name, reason string) error {
+	_, err := x.Exec(`
+		INSERT INTO machines (name, is_bot, bot_reason)
+		VALUES (
+			COALESCE(
+				(SELECT target FROM machine_aliases WHERE name = ?),
+				?
+			),
+			1, ?
+		)
+		ON CONFLICT(name) DO NOTHING`,
+		name, name, reason,
+	)
+	if err != nil {
+		return fmt.Errorf("marking bot machine %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetBotMachines returns the names of machines labeled as bots.
+func (db *DB) GetBotMachines(
+	ctx context.Context,
+) ([]string, error) {
+	rows, err := db.getReader().QueryContext(ctx,
+		"SELECT name FROM machines WHERE is_bot = 1 ORDER BY name",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying bot machines: %w", err)
+	}
+	defer rows.Close()
+
+	bots := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning bot machine: %w", err)
+		}
+		bots = append(bots, name)
+	}
+	return bots, rows.Err()
+}
+
+// CopyMachinesFrom copies machine labels from the database at
+// sourcePath. Used during resync so labels survive the swap.
+func (db *DB) CopyMachinesFrom(sourcePath string) error {
+	return db.write(func() error {
+		// ATTACH is connection-scoped; pin one connection.
+		ctx := context.Background()
+		conn, err := db.getWriter().Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("acquiring connection: %w", err)
+		}
+		defer conn.Close()
+
+		if _, err := conn.ExecContext(
+			ctx, "ATTACH DATABASE ? AS old_db", sourcePath,
+		); err != nil {
+			return fmt.Errorf("attaching source db: %w", err)
+		}
+		defer func() {
+			_, _ = conn.ExecContext(
+				ctx, "DETACH DATABASE old_db",
+			)
+		}()
+
+		_, err = conn.ExecContext(ctx, `
+			INSERT OR IGNORE INTO machines (name, is_bot, bot_reason)
+			SELECT name, is_bot, bot_reason FROM old_db.machines`)
+		if err != nil {
+			return fmt.Errorf("copying machines: %w", err)
+		}
+
+		// Sessions were re-synced under their original machine
+		// names; apply the copied renames to them.
+		_, err = conn.ExecContext(ctx, `
+			INSERT OR IGNORE INTO machine_aliases (name, target)
+			SELECT name, target FROM old_db.machine_aliases`)
+		if err != nil {
+			return fmt.Errorf("copying machine aliases: %w", err)
+		}
+		_, err = conn.ExecContext(ctx, `
+			UPDATE sessions SET machine = (
+				SELECT target FROM machine_aliases
+				WHERE name = sessions.machine
+			)
+			WHERE machine IN (SELECT name FROM machine_aliases)`)
+		if err != nil {
+			return fmt.Errorf("applying machine aliases: %w", err)
+		}
+		return nil
+	})
+}
+
+// Errors returned by RenameMachine and MergeMachines.
+var (
+	ErrMachineNotFound = errors.New("machine not found")
+	ErrMachineExists   = errors.New("machine already exists")
+)
+
+// MachineInfo describes a machine that has sessions in the
+// database.
+type MachineInfo struct {
+	Name         string   `json:"name"`
+	SessionCount int      `json:"session_count"`
+	IsBot        bool     `json:"is_bot"`
+	BotReason    string   `json:"bot_reason,omitempty"`
+	Aliases      []string `json:"aliases"`
+}
+
+// GetMachineInfos returns every machine with sessions, its
+// session count, bot label, and the former names that were
+// renamed or merged into it.
+func (db *DB) GetMachineInfos(
+	ctx context.Context,
+) ([]MachineInfo, error) {
+	aliases, err := db.machineAliasesByTarget(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT s.machine, COUNT(*),
+			COALESCE(m.is_bot, 0), COALESCE(m.bot_reason, '')
+		FROM sessions s
+		LEFT JOIN machines m ON m.name = s.machine
+		GROUP BY s.machine
+		ORDER BY s.machine`)
+	if err != nil {
+		return nil, fmt.Errorf("querying machines: %w", err)
+	}
+	defer rows.Close()
+
+	machines := []MachineInfo{}
+	for rows.Next() {
+		var m MachineInfo
+		if err := rows.Scan(
+			&m.Name, &m.SessionCount, &m.IsBot, &m.BotReason,
+		); err != nil {
+			return nil, fmt.Errorf("scanning machine: %w", err)
+		}
+		m.Aliases = aliases[m.Name]
+		if m.Aliases == nil {
+			m.Aliases = []string{}
+		}
+		machines = append(machines, m)
+	}
+	return machines, rows.Err()
+}
+
+// MachineLastSyncedAt returns the later of when a session from
+// machine was last stored and when one last ended, or the zero
+// time if the machine has no sessions.
+func (db *DB) MachineLastSyncedAt(
+	ctx context.Context, machine string,
+) (time.Time, error) {
+	var created, ended sql.NullString
+	err := db.getReader().QueryRowContext(ctx, `
+		SELECT MAX(created_at), MAX(NULLIF(ended_at, ''))
+		FROM sessions WHERE machine = ?`,
+		machine,
+	).Scan(&created, &ended)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(
+			"querying machine last sync: %w", err,
+		)
+	}
+	var last time.Time
+	for _, ts := range []sql.NullString{created, ended} {
+		t, err := time.Parse(time.RFC3339Nano, ts.String)
+		if ts.Valid && err == nil && t.After(last) {
+			last = t
+		}
+	}
+	return last, nil
+}
+
+func (db *DB) machineAliasesByTarget(
+	ctx context.Context,
+) (map[string][]string, error) {
+	rows, err := db.getReader().QueryContext(ctx,
+		"SELECT name, target FROM machine_aliases ORDER BY name",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying machine aliases: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string][]string)
+	for rows.Next() {
+		var name, target string
+		if err := rows.Scan(&name, &target); err != nil {
+			return nil, fmt.Errorf("scanning machine alias: %w", err)
+		}
+		out[target] = append(out[target], name)
+	}
+	return out, rows.Err()
+}
+
+// RenameMachine relabels every session of machine from as to.
+// The target name must not already have sessions; use
+// MergeMachines to combine two existing machines.
+func (db *DB) RenameMachine(
+	ctx context.Context, from, to string,
+) error {
+	return db.relabelMachine(ctx, from, to, false)
+}
+
+// MergeMachines moves every session of machine from onto the
+// existing machine into, e.g. after a hostname change.
+func (db *DB) MergeMachines(
+	ctx context.Context, from, into string,
+) error {
+	return db.relabelMachine(ctx, from, into, true)
+}
+
+// relabelMachine moves sessions and the bot label from one
+// machine name to another and records the rename as an alias.
+// Aliases that pointed at from are redirected so chains of
+// renames resolve in one step.
+func (db *DB) relabelMachine(
+	ctx context.Context, from, to string, merge bool,
+) error {
+	if from == "" || to == "" {
+		return errors.New("machine names must not be empty")
+	}
+	if from == to {
+		return errors.New("source and target machine are the same")
+	}
+	return db.write(func() error {
+		tx, err := db.getWriter().BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		exists := func(name string) (bool, error) {
+			var n int
+			err := tx.QueryRowContext(ctx,
+				"SELECT COUNT(*) FROM sessions WHERE machine = ?",
+				name,
+			).Scan(&n)
+			return n > 0, err
+		}
+		fromExists, err := exists(from)
+		if err != nil {
+			return fmt.Errorf("checking machine %s: %w", from, err)
+		}
+		toExists, err := exists(to)
+		if err != nil {
+			return fmt.Errorf("checking machine %s: %w", to, err)
+		}
+		switch {
+		case !fromExists:
+			return fmt.Errorf("%w: %s", ErrMachineNotFound, from)
+		case merge && !toExists:
+			return fmt.Errorf("%w: %s", ErrMachineNotFound, to)
+		case !merge && toExists:
+			return fmt.Errorf("%w: %s", ErrMachineExists, to)
+		}
+
+		for _, stmt := range []struct {
+			sql  string
+			args []any
+		}{
+			{"UPDATE sessions SET machine = ? WHERE machine = ?",
+				[]any{to, from}},
+			// to is canonical again if it was renamed earlier.
+			{"DELETE FROM machine_aliases WHERE name = ?",
+				[]any{to}},
+			{"UPDATE machine_aliases SET target = ? WHERE target = ?",
+				[]any{to, from}},
+			{`INSERT INTO machine_aliases (name, target)
+				VALUES (?, ?)
+				ON CONFLICT(name) DO UPDATE SET
+					target = excluded.target`,
+				[]any{from, to}},
+			// An existing label on the target wins.
+			{`INSERT OR IGNORE INTO machines
+				(name, is_bot, bot_reason)
+				SELECT ?, is_bot, bot_reason
+				FROM machines WHERE name = ?`,
+				[]any{to, from}},
+			{"DELETE FROM machines WHERE name = ?",
+				[]any{from}},
+		} {
+			if _, err := tx.ExecContext(
+				ctx, stmt.sql, stmt.args...,
+			); err != nil {
+				return fmt.Errorf(
+					"relabeling machine %s: %w", from, err,
+				)
+			}
+		}
+		return tx.Commit()
+	})
+}
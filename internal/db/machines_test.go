@@ -0,0 +1,250 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBotReason(t *testing.T) {
+	tests := []struct {
+		machine   string
+		automated bool
+		want      string
+	}{
+		{"local", true, ""},
+		{"", true, ""},
+		{"wes-macbook", false, ""},
+		{"wes-macbook", true, BotReasonAutomated},
+		{"runner-abc123", false, BotReasonHostname},
+		{"fv-az123-456", false, BotReasonHostname},
+		{"GitHub-Actions-7", false, BotReasonHostname},
+		{"build-ci", false, BotReasonHostname},
+		{"k8s-runner-pool-1", false, BotReasonHostname},
+		{"running-man", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.machine, func(t *testing.T) {
+			got := BotReason(tt.machine, tt.automated)
+			if got != tt.want {
+				t.Errorf(
+					"BotReason(%q, %v) = %q, want %q",
+					tt.machine, tt.automated, got, tt.want,
+				)
+			}
+		})
+	}
+}
+
+func TestBotMachinesExcludedFromAnalytics(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	stats := seedAnalyticsData(t, d)
+
+	insertSession(t, d, "ci1", "project-alpha", func(s *Session) {
+		s.Machine = "runner-7"
+		s.StartedAt = Ptr("2024-06-02T03:00:00Z")
+		s.EndedAt = Ptr("2024-06-02T03:10:00Z")
+		s.MessageCount = 4
+	})
+	requireNoError(t,
+		d.MarkBotMachine("runner-7", BotReasonHostname),
+		"MarkBotMachine",
+	)
+
+	bots, err := d.GetBotMachines(ctx)
+	requireNoError(t, err, "GetBotMachines")
+	if len(bots) != 1 || bots[0] != "runner-7" {
+		t.Errorf("bots = %v, want [runner-7]", bots)
+	}
+
+	tests := []struct {
+		name string
+		f    func(*AnalyticsFilter)
+		want int
+	}{
+		{"excluded by default", func(*AnalyticsFilter) {}, stats.TotalSessions},
+		{"include_bots", func(f *AnalyticsFilter) {
+			f.IncludeBots = true
+		}, stats.TotalSessions + 1},
+		{"explicit machine filter", func(f *AnalyticsFilter) {
+			f.Machine = "runner-7"
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := baseFilter()
+			tt.f(&f)
+			s := mustSummary(t, d, ctx, f)
+			if s.TotalSessions != tt.want {
+				t.Errorf(
+					"TotalSessions = %d, want %d",
+					s.TotalSessions, tt.want,
+				)
+			}
+		})
+	}
+}
+
+func TestMarkBotMachine_KeepsExistingLabel(t *testing.T) {
+	d := testDB(t)
+	_, err := d.getWriter().Exec(
+		"INSERT INTO machines (name, is_bot) VALUES ('runner-1', 0)",
+	)
+	requireNoError(t, err, "insert label")
+
+	requireNoError(t,
+		d.MarkBotMachine("runner-1", BotReasonHostname),
+		"MarkBotMachine",
+	)
+	bots, err := d.GetBotMachines(context.Background())
+	requireNoError(t, err, "GetBotMachines")
+	if len(bots) != 0 {
+		t.Errorf("bots = %v, want none", bots)
+	}
+}
+
+func TestCopyMachinesFrom(t *testing.T) {
+	dir := t.TempDir()
+
+	srcPath := filepath.Join(dir, "src.db")
+	srcDB, err := Open(srcPath)
+	requireNoError(t, err, "Open src")
+	requireNoError(t,
+		srcDB.MarkBotMachine("runner-1", BotReasonAutomated),
+		"MarkBotMachine",
+	)
+	srcDB.Close()
+
+	dstDB, err := Open(filepath.Join(dir, "dst.db"))
+	requireNoError(t, err, "Open dst")
+	defer dstDB.Close()
+
+	requireNoError(t,
+		dstDB.CopyMachinesFrom(srcPath), "CopyMachinesFrom",
+	)
+	bots, err := dstDB.GetBotMachines(context.Background())
+	requireNoError(t, err, "GetBotMachines")
+	if len(bots) != 1 || bots[0] != "runner-1" {
+		t.Errorf("bots = %v, want [runner-1]", bots)
+	}
+}
+
+func TestRenameAndMergeMachines(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	onMachine := func(m string) func(*Session) {
+		return func(s *Session) { s.Machine = m }
+	}
+	insertSession(t, d, "s1", "p", onMachine("old-host"))
+	insertSession(t, d, "s2", "p", onMachine("old-host"))
+	insertSession(t, d, "s3", "p", onMachine("laptop"))
+	requireNoError(t,
+		d.MarkBotMachine("old-host", BotReasonAutomated),
+		"MarkBotMachine",
+	)
+
+	err := d.RenameMachine(ctx, "old-host", "laptop")
+	if !errors.Is(err, ErrMachineExists) {
+		t.Fatalf("rename onto existing: err = %v", err)
+	}
+	err = d.MergeMachines(ctx, "old-host", "desktop")
+	if !errors.Is(err, ErrMachineNotFound) {
+		t.Fatalf("merge into missing: err = %v", err)
+	}
+
+	requireNoError(t,
+		d.RenameMachine(ctx, "old-host", "new-host"), "rename",
+	)
+	requireNoError(t,
+		d.MergeMachines(ctx, "new-host", "laptop"), "merge",
+	)
+
+	infos, err := d.GetMachineInfos(ctx)
+	requireNoError(t, err, "GetMachineInfos")
+	want := []MachineInfo{{
+		Name:         "laptop",
+		SessionCount: 3,
+		IsBot:        true,
+		BotReason:    BotReasonAutomated,
+		Aliases:      []string{"new-host", "old-host"},
+	}}
+	if diff := cmp.Diff(want, infos); diff != "" {
+		t.Errorf("machines mismatch (-want +got):\n%s", diff)
+	}
+
+	// Sessions re-synced under a former name follow the alias.
+	insertSession(t, d, "s4", "p", onMachine("old-host"))
+	s, err := d.GetSession(ctx, "s4")
+	requireNoError(t, err, "GetSession")
+	if s.Machine != "laptop" {
+		t.Errorf("s4 machine = %q, want laptop", s.Machine)
+	}
+}
+
+func TestCopyMachinesFromAppliesAliases(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	srcPath := filepath.Join(dir, "src.db")
+	srcDB, err := Open(srcPath)
+	requireNoError(t, err, "Open src")
+	insertSession(t, srcDB, "s1", "p", func(s *Session) {
+		s.Machine = "old-host"
+	})
+	requireNoError(t,
+		srcDB.RenameMachine(ctx, "old-host", "new-host"),
+		"RenameMachine",
+	)
+	srcDB.Close()
+
+	dstDB, err := Open(filepath.Join(dir, "dst.db"))
+	requireNoError(t, err, "Open dst")
+	defer dstDB.Close()
+	insertSession(t, dstDB, "s1", "p", func(s *Session) {
+		s.Machine = "old-host"
+	})
+
+	requireNoError(t,
+		dstDB.CopyMachinesFrom(srcPath), "CopyMachinesFrom",
+	)
+	s, err := dstDB.GetSession(ctx, "s1")
+	requireNoError(t, err, "GetSession")
+	if s.Machine != "new-host" {
+		t.Errorf("machine = %q, want new-host", s.Machine)
+	}
+}
+
+func TestMachineLastSyncedAt(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Minute)
+	insertSession(t, d, "s1", "p", func(s *Session) {
+		s.Machine = "laptop"
+		s.EndedAt = Ptr("2099-01-01T00:00:00Z")
+	})
+	insertSession(t, d, "s2", "p", func(s *Session) {
+		s.Machine = "desktop"
+		s.EndedAt = Ptr("2024-01-01T00:00:00Z")
+	})
+
+	got, err := d.MachineLastSyncedAt(ctx, "laptop")
+	requireNoError(t, err, "laptop")
+	if want := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("laptop = %v, want its session's end %v", got, want)
+	}
+	got, err = d.MachineLastSyncedAt(ctx, "desktop")
+	requireNoError(t, err, "desktop")
+	if got.Before(start) {
+		t.Errorf("desktop = %v, want the session's store time", got)
+	}
+	got, err = d.MachineLastSyncedAt(ctx, "missing")
+	requireNoError(t, err, "missing")
+	if !got.IsZero() {
+		t.Errorf("missing = %v, want zero", got)
+	}
+}
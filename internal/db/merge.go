@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MergeStats counts what MergeFrom did with the other
+// database's sessions.
+type MergeStats struct {
+	Sessions  int // sessions in the other database
+	Stored    int // new here, or from a newer version of the file
+	Unchanged int // same file hash as the copy here
+	Stale     int // the copy here is from a newer file
+}
+
+// MergeFrom imports sessions, their messages, and tool calls
+// from the agentsview database at path. Sessions are matched by
+// ID and deduplicated by file hash the same way pushed sessions
+// are (see Ingest). The other database is never written: it is
+// snapshotted to a temporary file, which is brought up to the
+// current schema and read from.
+func (d *DB) MergeFrom(
+	ctx context.Context, path string,
+) (MergeStats, error) {
+	var stats MergeStats
+	if same, err := sameFile(path, d.path); err != nil {
+		return stats, err
+	} else if same {
+		return stats, fmt.Errorf("cannot merge a database into itself")
+	}
+
+	src, cleanup, err := openMergeSource(path)
+	if err != nil {
+		return stats, err
+	}
+	defer cleanup()
+
+	srcs, err := src.ListSessionSources(ctx, "")
+	if err != nil {
+		return stats, err
+	}
+	stats.Sessions = len(srcs)
+	for _, s := range srcs {
+		status, err := d.IngestDecision(ctx, s)
+		if err != nil {
+			return stats, err
+		}
+		if status == IngestStored {
+			status, err = d.mergeSession(ctx, src, s.ID)
+			if err != nil {
+				return stats, err
+			}
+		}
+		switch status {
+		case IngestStored:
+			stats.Stored++
+		case IngestUnchanged:
+			stats.Unchanged++
+		case IngestStale:
+			stats.Stale++
+		}
+	}
+	return stats, nil
+}
+
+// mergeSession copies one session and its messages from src.
+func (d *DB) mergeSession(
+	ctx context.Context, src *DB, id string,
+) (string, error) {
+	s, err := src.GetSessionFull(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("reading session %s: %w", id, err)
+	}
+	if s == nil {
+		return IngestUnchanged, nil
+	}
+	msgs, err := src.GetAllMessages(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("reading messages for %s: %w", id, err)
+	}
+	return d.Ingest(ctx, IngestSession{Session: *s, Messages: msgs})
+}
+
+// openMergeSource snapshots the database at path into a
+// temporary directory and opens the snapshot. Snapshots of
+// databases too old to migrate in place are refused rather
+// than rebuilt empty, as Open would. cleanup closes and removes
+// the snapshot.
+func openMergeSource(path string) (*DB, func(), error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	conn, err := sql.Open("sqlite3", makeDSN(path, true))
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer conn.Close()
+
+	stale, err := needsSchemaRebuild(conn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if stale {
+		return nil, nil, fmt.Errorf(
+			"%s is not an agentsview database or its schema is too old to merge",
+			path,
+		)
+	}
+
+	dir, err := os.MkdirTemp("", "agentsview-merge-")
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot := filepath.Join(dir, "merge.db")
+	if _, err := conn.Exec("VACUUM INTO ?", snapshot); err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("snapshotting %s: %w", path, err)
+	}
+	src, err := openAndInit(snapshot)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("migrating %s: %w", path, err)
+	}
+	return src, func() {
+		src.Close()
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// sameFile reports whether a and b name the same file. A
+// missing file is never the same as another.
+func sameFile(a, b string) (bool, error) {
+	fa, err := os.Stat(a)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	fb, err := os.Stat(b)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return os.SameFile(fa, fb), nil
+}
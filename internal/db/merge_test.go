@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeFrom(t *testing.T) {
+	ctx := context.Background()
+	dst := testDB(t)
+	other := testDB(t)
+
+	for _, in := range []IngestSession{
+		ingestFixture("same", "h1", 100, "dst"),
+		ingestFixture("newer", "h1", 100, "dst"),
+		ingestFixture("older", "h1", 200, "dst"),
+	} {
+		_, err := dst.Ingest(ctx, in)
+		requireNoError(t, err, "seeding dst")
+	}
+	for _, in := range []IngestSession{
+		ingestFixture("same", "h1", 300, "other"),
+		ingestFixture("newer", "h2", 200, "other"),
+		ingestFixture("older", "h0", 100, "other"),
+		ingestFixture("added", "h1", 100, "other"),
+	} {
+		_, err := other.Ingest(ctx, in)
+		requireNoError(t, err, "seeding other")
+	}
+
+	stats, err := dst.MergeFrom(ctx, other.path)
+	requireNoError(t, err, "MergeFrom")
+	want := MergeStats{Sessions: 4, Stored: 2, Unchanged: 1, Stale: 1}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	for id, content := range map[string]string{
+		"same": "dst", "newer": "other", "older": "dst", "added": "other",
+	} {
+		msgs, err := dst.GetAllMessages(ctx, id)
+		requireNoError(t, err, "GetAllMessages")
+		if len(msgs) != 1 || msgs[0].Content != content ||
+			len(msgs[0].ToolCalls) != 1 {
+			t.Errorf("%s messages = %+v, want %q with a tool call",
+				id, msgs, content)
+		}
+	}
+
+	stats, err = dst.MergeFrom(ctx, other.path)
+	requireNoError(t, err, "second MergeFrom")
+	if stats.Stored != 0 || stats.Unchanged != 3 {
+		t.Errorf("second stats = %+v, want nothing stored", stats)
+	}
+}
+
+func TestMergeFrom_Refused(t *testing.T) {
+	ctx := context.Background()
+	d := testDB(t)
+
+	if _, err := d.MergeFrom(ctx, d.path); err == nil {
+		t.Error("merging into itself: want error")
+	}
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	if _, err := d.MergeFrom(ctx, missing); err == nil {
+		t.Error("merging a missing file: want error")
+	}
+}
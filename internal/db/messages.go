@@ -3,27 +3,43 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"strings"
 	"time"
 )
 
 const (
 	selectMessageCols = `id, session_id, ordinal, role, content,
-		timestamp, has_thinking, has_tool_use, content_length`
+		timestamp, has_thinking, has_tool_use, content_length,
+		model, model_switch, kind, input_tokens, output_tokens,
+		cache_read_tokens, cache_creation_tokens,
+		reasoning_tokens, source_uuid, pasted_chars, language`
 
 	insertMessageCols = `session_id, ordinal, role, content,
-		timestamp, has_thinking, has_tool_use, content_length`
+		timestamp, has_thinking, has_tool_use, content_length,
+		model, model_switch, kind, input_tokens, output_tokens,
+		cache_read_tokens, cache_creation_tokens,
+		reasoning_tokens, source_uuid, pasted_chars, language`
 
 	// DefaultMessageLimit is the default number of messages returned.
 	DefaultMessageLimit = 100
 	// MaxMessageLimit is the maximum number of messages returned.
 	MaxMessageLimit = 1000
 
+	// DefaultContextRadius and MaxContextRadius bound how many
+	// messages either side of a hit GetMessageContext returns.
+	DefaultContextRadius = 5
+	MaxContextRadius     = 50
+
 	// Keep query parameter counts conservative so large sessions
 	// do not exceed SQLite variable limits when hydrating tool calls.
 	attachToolCallBatchSize = 500
+
+	// streamMessageBatch is how many messages StreamMessages reads
+	// per query.
+	streamMessageBatch = 500
 )
 
 // ToolCall represents a single tool invocation stored in
@@ -39,6 +55,17 @@ type ToolCall struct {
 	ResultContentLength int    `json:"result_content_length,omitempty"`
 	ResultContent       string `json:"result_content,omitempty"`
 	SubagentSessionID   string `json:"subagent_session_id,omitempty"`
+	FilePath            string `json:"file_path,omitempty"`
+	// Command is the normalized command line of a Bash call,
+	// such as "go test" or "npm run lint".
+	Command string `json:"command,omitempty"`
+	// ResultError is set when the tool reported a failure.
+	ResultError bool `json:"result_error,omitempty"`
+	// ResultTruncated is set when the agent cut the result to
+	// fit its size limits. ResultOriginalLength is the size in
+	// bytes before the cut, when the agent reported it.
+	ResultTruncated      bool `json:"result_truncated,omitempty"`
+	ResultOriginalLength int  `json:"result_original_length,omitempty"`
 }
 
 // ToolResult holds a tool_result content block for pairing.
@@ -46,6 +73,11 @@ type ToolResult struct {
 	ToolUseID     string
 	ContentLength int
 	ContentRaw    string // raw JSON of the content field; decode lazily
+	IsError       bool
+	// Truncated and OriginalLength carry the agent's truncation
+	// marker, see ToolCall.ResultTruncated.
+	Truncated      bool
+	OriginalLength int
 }
 
 // Message represents a row in the messages table.
@@ -59,8 +91,22 @@ type Message struct {
 	HasThinking   bool         `json:"has_thinking"`
 	HasToolUse    bool         `json:"has_tool_use"`
 	ContentLength int          `json:"content_length"`
+	Model         string       `json:"model,omitempty"`
+	ModelSwitch   string       `json:"model_switch,omitempty"`
+	Kind          string       `json:"kind,omitempty"` // command, command_output
+	SourceUUID    string       `json:"source_uuid,omitempty"`
+	PastedChars   int          `json:"pasted_chars,omitempty"` // user messages only
+	Language      string       `json:"language,omitempty"`     // typed user messages only
 	ToolCalls     []ToolCall   `json:"tool_calls,omitempty"`
 	ToolResults   []ToolResult `json:"-"` // transient, for pairing
+
+	// Token usage reported by the agent for this message.
+	// Zero when the agent does not record usage.
+	InputTokens         int `json:"input_tokens,omitempty"`
+	OutputTokens        int `json:"output_tokens,omitempty"`
+	CacheReadTokens     int `json:"cache_read_tokens,omitempty"`
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
+	ReasoningTokens     int `json:"reasoning_tokens,omitempty"`
 }
 
 // MinimapEntry is a lightweight message summary for minimap rendering.
@@ -115,6 +161,32 @@ func (db *DB) GetMessages(
 	return msgs, nil
 }
 
+// GetMessageContext returns the messages within radius
+// ordinals of ordinal, in ascending order with tool calls
+// attached.
+func (db *DB) GetMessageContext(
+	ctx context.Context, sessionID string, ordinal, radius int,
+) ([]Message, error) {
+	rows, err := db.getReader().QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM messages
+		WHERE session_id = ? AND ordinal BETWEEN ? AND ?
+		ORDER BY ordinal ASC`, selectMessageCols),
+		sessionID, ordinal-radius, ordinal+radius)
+	if err != nil {
+		return nil, fmt.Errorf("querying message context: %w", err)
+	}
+	defer rows.Close()
+	msgs, err := scanMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.attachToolCalls(ctx, msgs); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
 // GetAllMessages returns all messages for a session ordered by ordinal.
 func (db *DB) GetAllMessages(
 	ctx context.Context, sessionID string,
@@ -138,6 +210,36 @@ func (db *DB) GetAllMessages(
 	return msgs, nil
 }
 
+// StreamMessages calls fn with a session's messages from ordinal
+// from onwards, in ordinal order and in batches with tool calls
+// attached, so callers can write a large session out without
+// holding all of it. Each batch is a separate query; no read is
+// held open while fn runs. An error from fn stops the stream and
+// is returned.
+func (db *DB) StreamMessages(
+	ctx context.Context, sessionID string, from int,
+	fn func([]Message) error,
+) error {
+	for {
+		msgs, err := db.GetMessages(
+			ctx, sessionID, from, streamMessageBatch, true,
+		)
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			return nil
+		}
+		if err := fn(msgs); err != nil {
+			return err
+		}
+		if len(msgs) < streamMessageBatch {
+			return nil
+		}
+		from = msgs[len(msgs)-1].Ordinal + 1
+	}
+}
+
 // GetMinimap returns lightweight metadata for all messages in a session.
 func (db *DB) GetMinimap(
 	ctx context.Context, sessionID string,
@@ -204,18 +306,28 @@ func (db *DB) insertMessagesTx(
 ) ([]int64, error) {
 	stmt, err := tx.Prepare(fmt.Sprintf(`
 		INSERT INTO messages (%s)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, insertMessageCols))
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		insertMessageCols))
 	if err != nil {
 		return nil, fmt.Errorf("preparing insert: %w", err)
 	}
 	defer stmt.Close()
+	usageStmt, err := tx.Prepare(insertTokenUsage)
+	if err != nil {
+		return nil, fmt.Errorf("preparing token usage insert: %w", err)
+	}
+	defer usageStmt.Close()
 
 	ids := make([]int64, len(msgs))
 	for i, m := range msgs {
 		res, err := stmt.Exec(
 			m.SessionID, m.Ordinal, m.Role, m.Content,
 			m.Timestamp, m.HasThinking, m.HasToolUse,
-			m.ContentLength,
+			m.ContentLength, m.Model, m.ModelSwitch, m.Kind,
+			m.InputTokens, m.OutputTokens,
+			m.CacheReadTokens, m.CacheCreationTokens,
+			m.ReasoningTokens, m.SourceUUID, m.PastedChars,
+			m.Language,
 		)
 		if err != nil {
 			return nil, fmt.Errorf(
@@ -229,6 +341,19 @@ func (db *DB) insertMessagesTx(
 			)
 		}
 		ids[i] = id
+		if !m.HasTokenUsage() {
+			continue
+		}
+		if _, err := usageStmt.Exec(
+			id, m.SessionID, m.Model,
+			m.InputTokens, m.OutputTokens,
+			m.CacheReadTokens, m.CacheCreationTokens,
+			m.ReasoningTokens,
+		); err != nil {
+			return nil, fmt.Errorf(
+				"inserting token usage ord=%d: %w", m.Ordinal, err,
+			)
+		}
 	}
 	return ids, nil
 }
@@ -259,14 +384,24 @@ func insertToolCallsTx(
 		INSERT INTO tool_calls
 			(message_id, session_id, tool_name, category,
 			 tool_use_id, input_json, skill_name,
-			 result_content_length, result_content, subagent_session_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+			 result_content_length, result_content, subagent_session_id,
+			 file_path, result_error, result_truncated,
+			 result_original_length, command)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		return fmt.Errorf("preparing tool_calls insert: %w", err)
 	}
 	defer stmt.Close()
 
 	for _, tc := range calls {
+		filePath := tc.FilePath
+		if filePath == "" {
+			filePath = toolCallFilePath(tc.Category, tc.InputJSON)
+		}
+		command := tc.Command
+		if command == "" {
+			command = toolCallCommand(tc.Category, tc.InputJSON)
+		}
 		if _, err := stmt.Exec(
 			tc.MessageID, tc.SessionID,
 			tc.ToolName, tc.Category,
@@ -276,6 +411,11 @@ func insertToolCallsTx(
 			nilIfZero(tc.ResultContentLength),
 			nilIfEmpty(tc.ResultContent),
 			nilIfEmpty(tc.SubagentSessionID),
+			nilIfEmpty(filePath),
+			tc.ResultError,
+			tc.ResultTruncated,
+			nilIfZero(tc.ResultOriginalLength),
+			nilIfEmpty(command),
 		); err != nil {
 			return fmt.Errorf(
 				"inserting tool_call %q: %w", tc.ToolName, err,
@@ -295,32 +435,209 @@ func (db *DB) InsertMessages(msgs []Message) error {
 	t := time.Now()
 	defer func() {
 		if d := time.Since(t); d > slowOpThreshold {
-			log.Printf(
-				"db: InsertMessages (%d msgs): %s",
-				len(msgs), d.Round(time.Millisecond),
+			slog.Warn(
+				"db: slow InsertMessages",
+				"msgs", len(msgs),
+				"elapsed", d.Round(time.Millisecond),
 			)
 		}
 	}()
 
-	db.mu.Lock()
-	defer db.mu.Unlock()
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
 
-	tx, err := db.getWriter().Begin()
+		ids, err := db.insertMessagesTx(tx, msgs)
+		if err != nil {
+			return err
+		}
+
+		toolCalls := resolveToolCalls(msgs, ids)
+		if err := insertToolCallsTx(tx, toolCalls); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// AppendSessionMessages inserts messages appended to a session
+// and, in the same transaction, fills in results for stored
+// tool calls that were still waiting on one and updates the
+// token usage of stored messages. paired holds tool calls from
+// already-stored messages whose results are now known; only
+// stored calls without a result are updated. usage holds
+// already-stored messages, by ordinal, with the usage now
+// credited to them, which agents can report after the message
+// itself; only messages whose usage differs are rewritten.
+func (db *DB) AppendSessionMessages(
+	sessionID string, msgs []Message, paired []ToolCall,
+	usage []Message,
+) error {
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if len(msgs) > 0 {
+			ids, err := db.insertMessagesTx(tx, msgs)
+			if err != nil {
+				return err
+			}
+			toolCalls := resolveToolCalls(msgs, ids)
+			if err := insertToolCallsTx(tx, toolCalls); err != nil {
+				return err
+			}
+		}
+		if err := fillToolResultsTx(tx, sessionID, paired); err != nil {
+			return err
+		}
+		if err := updateTokenUsageTx(tx, sessionID, usage); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+// updateTokenUsageTx sets the token usage of a session's stored
+// messages to that of usage, matching by ordinal, and keeps
+// their token_usage rows in step.
+func updateTokenUsageTx(
+	tx *sql.Tx, sessionID string, usage []Message,
+) error {
+	if len(usage) == 0 {
+		return nil
+	}
+	stmt, err := tx.Prepare(`
+		UPDATE messages
+		SET input_tokens = ?, output_tokens = ?,
+			cache_read_tokens = ?, cache_creation_tokens = ?,
+			reasoning_tokens = ?
+		WHERE session_id = ? AND ordinal = ?
+			AND (input_tokens, output_tokens, cache_read_tokens,
+				cache_creation_tokens, reasoning_tokens)
+				!= (?, ?, ?, ?, ?)
+		RETURNING id, model`)
 	if err != nil {
-		return fmt.Errorf("beginning tx: %w", err)
+		return fmt.Errorf("preparing token usage update: %w", err)
 	}
-	defer func() { _ = tx.Rollback() }()
+	defer stmt.Close()
 
-	ids, err := db.insertMessagesTx(tx, msgs)
+	for _, m := range usage {
+		counts := []any{
+			m.InputTokens, m.OutputTokens, m.CacheReadTokens,
+			m.CacheCreationTokens, m.ReasoningTokens,
+		}
+		args := append(append(counts, sessionID, m.Ordinal), counts...)
+		var id int64
+		var model string
+		err := stmt.QueryRow(args...).Scan(&id, &model)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf(
+				"updating token usage ord=%d: %w", m.Ordinal, err,
+			)
+		}
+		if _, err := tx.Exec(
+			"DELETE FROM token_usage WHERE message_id = ?", id,
+		); err != nil {
+			return fmt.Errorf("clearing token usage: %w", err)
+		}
+		if !m.HasTokenUsage() {
+			continue
+		}
+		if _, err := tx.Exec(
+			insertTokenUsage, append([]any{id, sessionID, model},
+				counts...)...,
+		); err != nil {
+			return fmt.Errorf("storing token usage: %w", err)
+		}
+	}
+	return nil
+}
+
+// fillToolResultsTx sets result content on a session's stored
+// tool calls that have none yet, matching by tool_use_id, and
+// links those calls to the subagent session they started.
+func fillToolResultsTx(
+	tx *sql.Tx, sessionID string, paired []ToolCall,
+) error {
+	byID := make(map[string]ToolCall, len(paired))
+	for _, tc := range paired {
+		if tc.ToolUseID != "" &&
+			(tc.ResultContentLength > 0 || tc.ResultError ||
+				tc.SubagentSessionID != "") {
+			byID[tc.ToolUseID] = tc
+		}
+	}
+	if len(byID) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, tool_use_id FROM tool_calls
+		WHERE session_id = ? AND tool_use_id IS NOT NULL
+			AND result_content_length IS NULL`,
+		sessionID,
+	)
 	if err != nil {
-		return err
+		return fmt.Errorf("querying pending tool calls: %w", err)
+	}
+	type pending struct {
+		id int64
+		tc ToolCall
+	}
+	var updates []pending
+	for rows.Next() {
+		var id int64
+		var useID string
+		if err := rows.Scan(&id, &useID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning pending tool call: %w", err)
+		}
+		if tc, ok := byID[useID]; ok {
+			updates = append(updates, pending{id, tc})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading pending tool calls: %w", err)
 	}
 
-	toolCalls := resolveToolCalls(msgs, ids)
-	if err := insertToolCallsTx(tx, toolCalls); err != nil {
-		return err
+	for _, u := range updates {
+		if u.tc.SubagentSessionID != "" {
+			if _, err := tx.Exec(
+				"UPDATE tool_calls SET subagent_session_id = ?"+
+					" WHERE id = ?",
+				u.tc.SubagentSessionID, u.id,
+			); err != nil {
+				return fmt.Errorf("linking tool call subagent: %w", err)
+			}
+		}
+		if u.tc.ResultContentLength == 0 && !u.tc.ResultError {
+			continue
+		}
+		if _, err := tx.Exec(`
+			UPDATE tool_calls
+			SET result_content_length = ?, result_content = ?,
+				result_error = ?, result_truncated = ?,
+				result_original_length = ?
+			WHERE id = ?`,
+			u.tc.ResultContentLength,
+			nilIfEmpty(u.tc.ResultContent), u.tc.ResultError,
+			u.tc.ResultTruncated, nilIfZero(u.tc.ResultOriginalLength),
+			u.id,
+		); err != nil {
+			return fmt.Errorf("updating tool call result: %w", err)
+		}
 	}
-	return tx.Commit()
+	return nil
 }
 
 // MaxOrdinal returns the highest ordinal for a session,
@@ -346,29 +663,47 @@ func (db *DB) ReplaceSessionMessages(
 	t := time.Now()
 	defer func() {
 		if d := time.Since(t); d > slowOpThreshold {
-			log.Printf(
-				"db: ReplaceSessionMessages %s (%d msgs): %s",
-				sessionID, len(msgs),
-				d.Round(time.Millisecond),
+			slog.Warn(
+				"db: slow ReplaceSessionMessages",
+				"session", sessionID, "msgs", len(msgs),
+				"elapsed", d.Round(time.Millisecond),
 			)
 		}
 	}()
 
-	db.mu.Lock()
-	defer db.mu.Unlock()
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
 
-	tx, err := db.getWriter().Begin()
-	if err != nil {
-		return fmt.Errorf("beginning tx: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
+		if err := db.replaceSessionMessagesTx(
+			tx, sessionID, msgs,
+		); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
 
+// replaceSessionMessagesTx runs ReplaceSessionMessages within an
+// existing transaction.
+func (db *DB) replaceSessionMessagesTx(
+	tx *sql.Tx, sessionID string, msgs []Message,
+) error {
 	if _, err := tx.Exec(
 		"DELETE FROM tool_calls WHERE session_id = ?",
 		sessionID,
 	); err != nil {
 		return fmt.Errorf("deleting old tool_calls: %w", err)
 	}
+	if _, err := tx.Exec(
+		"DELETE FROM token_usage WHERE session_id = ?",
+		sessionID,
+	); err != nil {
+		return fmt.Errorf("deleting old token_usage: %w", err)
+	}
 
 	if _, err := tx.Exec(
 		"DELETE FROM messages WHERE session_id = ?", sessionID,
@@ -376,18 +711,14 @@ func (db *DB) ReplaceSessionMessages(
 		return fmt.Errorf("deleting old messages: %w", err)
 	}
 
-	if len(msgs) > 0 {
-		ids, err := db.insertMessagesTx(tx, msgs)
-		if err != nil {
-			return err
-		}
-		toolCalls := resolveToolCalls(msgs, ids)
-		if err := insertToolCallsTx(tx, toolCalls); err != nil {
-			return err
-		}
+	if len(msgs) == 0 {
+		return nil
 	}
-
-	return tx.Commit()
+	ids, err := db.insertMessagesTx(tx, msgs)
+	if err != nil {
+		return err
+	}
+	return insertToolCallsTx(tx, resolveToolCalls(msgs, ids))
 }
 
 // attachToolCalls loads tool_calls for the given messages
@@ -437,7 +768,9 @@ func (db *DB) attachToolCallsBatch(
 	query := fmt.Sprintf(`
 		SELECT message_id, session_id, tool_name, category,
 			tool_use_id, input_json, skill_name,
-			result_content_length, result_content, subagent_session_id
+			result_content_length, result_content, subagent_session_id,
+			file_path, result_error, result_truncated,
+			result_original_length, command
 		FROM tool_calls
 		WHERE message_id IN (%s)
 		ORDER BY id`,
@@ -453,12 +786,15 @@ func (db *DB) attachToolCallsBatch(
 		var tc ToolCall
 		var toolUseID, inputJSON, skillName sql.NullString
 		var subagentSessionID, resultContent sql.NullString
-		var resultLen sql.NullInt64
+		var filePath, command sql.NullString
+		var resultLen, originalLen sql.NullInt64
 		if err := rows.Scan(
 			&tc.MessageID, &tc.SessionID,
 			&tc.ToolName, &tc.Category,
 			&toolUseID, &inputJSON, &skillName,
 			&resultLen, &resultContent, &subagentSessionID,
+			&filePath, &tc.ResultError, &tc.ResultTruncated,
+			&originalLen, &command,
 		); err != nil {
 			return fmt.Errorf("scanning tool_call: %w", err)
 		}
@@ -477,9 +813,14 @@ func (db *DB) attachToolCallsBatch(
 		if resultContent.Valid {
 			tc.ResultContent = resultContent.String
 		}
+		tc.ResultOriginalLength = int(originalLen.Int64)
 		if subagentSessionID.Valid {
 			tc.SubagentSessionID = subagentSessionID.String
 		}
+		if filePath.Valid {
+			tc.FilePath = filePath.String
+		}
+		tc.Command = command.String
 
 		if idx, ok := idToIdx[tc.MessageID]; ok {
 			msgs[idx].ToolCalls = append(
@@ -490,15 +831,26 @@ func (db *DB) attachToolCallsBatch(
 	return rows.Err()
 }
 
+// scanMessageRow scans selectMessageCols into a Message.
+func scanMessageRow(rs rowScanner) (Message, error) {
+	var m Message
+	err := rs.Scan(
+		&m.ID, &m.SessionID, &m.Ordinal, &m.Role,
+		&m.Content, &m.Timestamp,
+		&m.HasThinking, &m.HasToolUse, &m.ContentLength,
+		&m.Model, &m.ModelSwitch, &m.Kind,
+		&m.InputTokens, &m.OutputTokens,
+		&m.CacheReadTokens, &m.CacheCreationTokens,
+		&m.ReasoningTokens, &m.SourceUUID, &m.PastedChars,
+		&m.Language,
+	)
+	return m, err
+}
+
 func scanMessages(rows *sql.Rows) ([]Message, error) {
 	var msgs []Message
 	for rows.Next() {
-		var m Message
-		err := rows.Scan(
-			&m.ID, &m.SessionID, &m.Ordinal, &m.Role,
-			&m.Content, &m.Timestamp,
-			&m.HasThinking, &m.HasToolUse, &m.ContentLength,
-		)
+		m, err := scanMessageRow(rows)
 		if err != nil {
 			return nil, fmt.Errorf("scanning message: %w", err)
 		}
@@ -527,12 +879,7 @@ func (db *DB) GetMessageByOrdinal(
 		WHERE session_id = ? AND ordinal = ?`, selectMessageCols),
 		sessionID, ordinal)
 
-	var m Message
-	err := row.Scan(
-		&m.ID, &m.SessionID, &m.Ordinal, &m.Role,
-		&m.Content, &m.Timestamp,
-		&m.HasThinking, &m.HasToolUse, &m.ContentLength,
-	)
+	m, err := scanMessageRow(row)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -542,6 +889,33 @@ func (db *DB) GetMessageByOrdinal(
 	return &m, nil
 }
 
+// GetMessageByUUID returns a single message by session ID and
+// source record UUID. Unlike ordinals, source UUIDs survive
+// re-parses that renumber a session's messages.
+func (db *DB) GetMessageByUUID(
+	ctx context.Context, sessionID, uuid string,
+) (*Message, error) {
+	if uuid == "" {
+		return nil, nil
+	}
+	row := db.getReader().QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM messages
+		WHERE session_id = ? AND source_uuid = ?
+		ORDER BY ordinal
+		LIMIT 1`, selectMessageCols),
+		sessionID, uuid)
+
+	m, err := scanMessageRow(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting message %s: %w", uuid, err)
+	}
+	return &m, nil
+}
+
 // resolveToolCalls builds ToolCall rows from messages using
 // the parallel IDs slice from insertMessagesTx. Panics if
 // len(ids) != len(msgs) since that indicates a caller bug.
@@ -558,16 +932,20 @@ func resolveToolCalls(
 	for i, m := range msgs {
 		for _, tc := range m.ToolCalls {
 			calls = append(calls, ToolCall{
-				MessageID:           ids[i],
-				SessionID:           m.SessionID,
-				ToolName:            tc.ToolName,
-				Category:            tc.Category,
-				ToolUseID:           tc.ToolUseID,
-				InputJSON:           tc.InputJSON,
-				SkillName:           tc.SkillName,
-				ResultContentLength: tc.ResultContentLength,
-				ResultContent:       tc.ResultContent,
-				SubagentSessionID:   tc.SubagentSessionID,
+				MessageID:            ids[i],
+				SessionID:            m.SessionID,
+				ToolName:             tc.ToolName,
+				Category:             tc.Category,
+				ToolUseID:            tc.ToolUseID,
+				InputJSON:            tc.InputJSON,
+				SkillName:            tc.SkillName,
+				ResultContentLength:  tc.ResultContentLength,
+				ResultContent:        tc.ResultContent,
+				SubagentSessionID:    tc.SubagentSessionID,
+				FilePath:             tc.FilePath,
+				ResultError:          tc.ResultError,
+				ResultTruncated:      tc.ResultTruncated,
+				ResultOriginalLength: tc.ResultOriginalLength,
 			})
 		}
 	}
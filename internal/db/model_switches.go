@@ -0,0 +1,280 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Reasons stored in messages.model_switch, matching the parser's
+// ModelSwitch constants.
+const (
+	ModelSwitchCommand  = "command"
+	ModelSwitchFallback = "fallback"
+)
+
+// maxModelTransitions caps the transitions listed by
+// GetAnalyticsModelSwitches.
+const maxModelTransitions = 50
+
+// ModelSegment is a run of consecutive messages produced by one
+// model within a session. Reason says how the session arrived
+// at this model and is empty for the first segment.
+type ModelSegment struct {
+	Model        string `json:"model"`
+	Reason       string `json:"reason,omitempty"`
+	FirstOrdinal int    `json:"first_ordinal"`
+	LastOrdinal  int    `json:"last_ordinal"`
+	Messages     int    `json:"messages"`
+	StartedAt    string `json:"started_at,omitempty"`
+}
+
+// GetSessionModelTimeline returns the models a session used in
+// order. Sessions whose agent does not record models return an
+// empty timeline.
+func (db *DB) GetSessionModelTimeline(
+	ctx context.Context, sessionID string,
+) ([]ModelSegment, error) {
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT ordinal, model, model_switch,
+			COALESCE(timestamp, '')
+		FROM messages
+		WHERE session_id = ? AND model != ''
+		ORDER BY ordinal`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("querying model timeline: %w", err)
+	}
+	defer rows.Close()
+
+	segments := []ModelSegment{}
+	for rows.Next() {
+		var ordinal int
+		var model, reason, ts string
+		if err := rows.Scan(&ordinal, &model, &reason, &ts); err != nil {
+			return nil, fmt.Errorf("scanning model timeline: %w", err)
+		}
+		if n := len(segments); n > 0 && segments[n-1].Model == model {
+			segments[n-1].LastOrdinal = ordinal
+			segments[n-1].Messages++
+			continue
+		}
+		segments = append(segments, ModelSegment{
+			Model:        model,
+			Reason:       reason,
+			FirstOrdinal: ordinal,
+			LastOrdinal:  ordinal,
+			Messages:     1,
+			StartedAt:    ts,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating model timeline: %w", err)
+	}
+	return segments, nil
+}
+
+// ModelSwitchStats counts model changes across sessions that
+// record models. FallbackRate is the percentage of those
+// sessions with at least one fallback.
+type ModelSwitchStats struct {
+	Sessions         int     `json:"sessions"`
+	SwitchedSessions int     `json:"switched_sessions"`
+	FallbackSessions int     `json:"fallback_sessions"`
+	Fallbacks        int     `json:"fallbacks"`
+	Commands         int     `json:"commands"`
+	FallbackRate     float64 `json:"fallback_rate"`
+}
+
+// AgentModelSwitches is ModelSwitchStats for one agent.
+type AgentModelSwitches struct {
+	Agent string `json:"agent"`
+	ModelSwitchStats
+}
+
+// ModelTransition counts switches from one model to another.
+type ModelTransition struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// ModelSwitchesResponse wraps model switch analytics.
+type ModelSwitchesResponse struct {
+	Overall     ModelSwitchStats     `json:"overall"`
+	ByAgent     []AgentModelSwitches `json:"by_agent"`
+	Transitions []ModelTransition    `json:"transitions"`
+}
+
+// modelSwitchAcc accumulates ModelSwitchStats one session at a
+// time.
+type modelSwitchAcc struct {
+	ModelSwitchStats
+}
+
+func (a *modelSwitchAcc) addSession(fallbacks, commands int) {
+	a.Sessions++
+	a.Fallbacks += fallbacks
+	a.Commands += commands
+	if fallbacks+commands > 0 {
+		a.SwitchedSessions++
+	}
+	if fallbacks > 0 {
+		a.FallbackSessions++
+	}
+}
+
+func (a *modelSwitchAcc) stats() ModelSwitchStats {
+	s := a.ModelSwitchStats
+	if s.Sessions > 0 {
+		s.FallbackRate = round1(
+			float64(s.FallbackSessions) / float64(s.Sessions) * 100,
+		)
+	}
+	return s
+}
+
+// GetAnalyticsModelSwitches reports how often sessions change
+// models mid-session, split into user-requested switches and
+// automatic fallbacks, with the most common transitions.
+func (db *DB) GetAnalyticsModelSwitches(
+	ctx context.Context, f AnalyticsFilter,
+) (ModelSwitchesResponse, error) {
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return ModelSwitchesResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, agent FROM sessions WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return ModelSwitchesResponse{},
+			fmt.Errorf("querying model switch sessions: %w", err)
+	}
+	defer rows.Close()
+
+	agents := map[string]string{}
+	var ids []string
+	for rows.Next() {
+		var id, ts, agent string
+		if err := rows.Scan(&id, &ts, &agent); err != nil {
+			return ModelSwitchesResponse{},
+				fmt.Errorf("scanning model switch session: %w", err)
+		}
+		if !inDateRange(localDate(ts, loc), f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		agents[id] = agent
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return ModelSwitchesResponse{},
+			fmt.Errorf("iterating model switch sessions: %w", err)
+	}
+
+	type sessionSwitches struct {
+		fallbacks, commands int
+	}
+	perSession := map[string]*sessionSwitches{}
+	transitions := map[ModelTransition]int{}
+	err = queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, model, model_switch
+			FROM messages
+			WHERE session_id IN `+ph+` AND model != ''
+			ORDER BY session_id, ordinal`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying message models: %w", err)
+		}
+		defer rows.Close()
+		var lastSession, lastModel string
+		for rows.Next() {
+			var sid, model, reason string
+			if err := rows.Scan(&sid, &model, &reason); err != nil {
+				return fmt.Errorf("scanning message model: %w", err)
+			}
+			if sid != lastSession {
+				lastSession, lastModel = sid, ""
+				perSession[sid] = &sessionSwitches{}
+			}
+			if reason != "" && lastModel != "" {
+				switch reason {
+				case ModelSwitchFallback:
+					perSession[sid].fallbacks++
+				case ModelSwitchCommand:
+					perSession[sid].commands++
+				}
+				transitions[ModelTransition{
+					From: lastModel, To: model, Reason: reason,
+				}]++
+			}
+			lastModel = model
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return ModelSwitchesResponse{}, err
+	}
+
+	var overall modelSwitchAcc
+	byAgent := map[string]*modelSwitchAcc{}
+	for sid, sw := range perSession {
+		agent := agents[sid]
+		if byAgent[agent] == nil {
+			byAgent[agent] = &modelSwitchAcc{}
+		}
+		overall.addSession(sw.fallbacks, sw.commands)
+		byAgent[agent].addSession(sw.fallbacks, sw.commands)
+	}
+
+	resp := ModelSwitchesResponse{
+		Overall:     overall.stats(),
+		ByAgent:     make([]AgentModelSwitches, 0, len(byAgent)),
+		Transitions: make([]ModelTransition, 0, len(transitions)),
+	}
+	for agent, acc := range byAgent {
+		resp.ByAgent = append(resp.ByAgent, AgentModelSwitches{
+			Agent: agent, ModelSwitchStats: acc.stats(),
+		})
+	}
+	sort.Slice(resp.ByAgent, func(i, j int) bool {
+		return resp.ByAgent[i].Agent < resp.ByAgent[j].Agent
+	})
+	for t, count := range transitions {
+		t.Count = count
+		resp.Transitions = append(resp.Transitions, t)
+	}
+	sort.Slice(resp.Transitions, func(i, j int) bool {
+		a, b := resp.Transitions[i], resp.Transitions[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Reason < b.Reason
+	})
+	if len(resp.Transitions) > maxModelTransitions {
+		resp.Transitions = resp.Transitions[:maxModelTransitions]
+	}
+	return resp, nil
+}
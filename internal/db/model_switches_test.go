@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+// modelMsg is an assistant message from model, optionally marked
+// as a switch.
+func modelMsg(sid string, ordinal int, model, reason string) Message {
+	m := asstMsg(sid, ordinal, "reply")
+	m.Model = model
+	m.ModelSwitch = reason
+	return m
+}
+
+func TestGetSessionModelTimeline(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+	insertMessages(t, d,
+		userMsg("s1", 0, "go"),
+		modelMsg("s1", 1, "opus", ""),
+		modelMsg("s1", 2, "opus", ""),
+		modelMsg("s1", 3, "sonnet", ModelSwitchFallback),
+		userMsg("s1", 4, "back to opus"),
+		modelMsg("s1", 5, "opus", ModelSwitchCommand),
+	)
+
+	got, err := d.GetSessionModelTimeline(ctx, "s1")
+	requireNoError(t, err, "GetSessionModelTimeline")
+	want := []ModelSegment{
+		{Model: "opus", FirstOrdinal: 1, LastOrdinal: 2, Messages: 2},
+		{Model: "sonnet", Reason: ModelSwitchFallback,
+			FirstOrdinal: 3, LastOrdinal: 3, Messages: 1},
+		{Model: "opus", Reason: ModelSwitchCommand,
+			FirstOrdinal: 5, LastOrdinal: 5, Messages: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("segments = %+v, want %d", got, len(want))
+	}
+	for i := range want {
+		got[i].StartedAt = ""
+		assertEq(t, "segment", got[i], want[i])
+	}
+
+	empty, err := d.GetSessionModelTimeline(ctx, "missing")
+	requireNoError(t, err, "GetSessionModelTimeline missing")
+	if empty == nil || len(empty) != 0 {
+		t.Errorf("missing session = %+v, want empty", empty)
+	}
+}
+
+func TestGetAnalyticsModelSwitches(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	started := func(s *Session) {
+		s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+		s.MessageCount = 3
+	}
+	for _, id := range []string{"fb", "cmd", "steady", "nomodel"} {
+		insertSession(t, d, id, "proj", started)
+	}
+	insertMessages(t, d,
+		modelMsg("fb", 0, "opus", ""),
+		modelMsg("fb", 1, "sonnet", ModelSwitchFallback),
+		modelMsg("fb", 2, "sonnet", ""),
+		modelMsg("cmd", 0, "sonnet", ""),
+		modelMsg("cmd", 1, "opus", ModelSwitchCommand),
+		modelMsg("steady", 0, "opus", ""),
+		asstMsg("nomodel", 0, "reply"),
+	)
+
+	resp, err := d.GetAnalyticsModelSwitches(ctx, AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	})
+	requireNoError(t, err, "GetAnalyticsModelSwitches")
+	assertEq(t, "Overall", resp.Overall, ModelSwitchStats{
+		Sessions:         3,
+		SwitchedSessions: 2,
+		FallbackSessions: 1,
+		Fallbacks:        1,
+		Commands:         1,
+		FallbackRate:     33.3,
+	})
+	if len(resp.ByAgent) != 1 || resp.ByAgent[0].Agent != defaultAgent {
+		t.Errorf("ByAgent = %+v", resp.ByAgent)
+	}
+	assertEq(t, "transitions", len(resp.Transitions), 2)
+	assertEq(t, "first transition", resp.Transitions[0], ModelTransition{
+		From: "opus", To: "sonnet", Reason: ModelSwitchFallback, Count: 1,
+	})
+}
@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/wesm/agentsview/internal/pricing"
+)
+
+// MonthPeriod totals sessions started between From and To,
+// inclusive local dates.
+type MonthPeriod struct {
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	Sessions      int     `json:"sessions"`
+	Cost          float64 `json:"cost"`
+	ActiveMinutes float64 `json:"active_minutes"`
+}
+
+// MonthChange is the percentage change of each total from the
+// previous month. A field is nil when last month's total was
+// zero.
+type MonthChange struct {
+	Sessions      *float64 `json:"sessions"`
+	Cost          *float64 `json:"cost"`
+	ActiveMinutes *float64 `json:"active_minutes"`
+}
+
+// MonthToDateResponse compares the month so far with the same
+// days of the previous month.
+type MonthToDateResponse struct {
+	Month       string `json:"month"` // YYYY-MM
+	DaysElapsed int    `json:"days_elapsed"`
+	DaysInMonth int    `json:"days_in_month"`
+	// Current runs from the first of the month through the
+	// filter's end date; Previous covers the first of last
+	// month through the same day, or its last day if shorter.
+	Current  MonthPeriod `json:"current"`
+	Previous MonthPeriod `json:"previous"`
+	Change   MonthChange `json:"change"`
+	// Projected extends Current's daily rate to the whole
+	// month.
+	Projected MonthPeriod `json:"projected"`
+	// Currency of the costs, chosen as in the forecast.
+	Currency string `json:"currency"`
+}
+
+// GetMonthToDate returns month-to-date sessions, estimated
+// cost, and active time for the month containing f.To, with
+// the same-day comparison against last month and a run-rate
+// projection. f.From is ignored.
+func (db *DB) GetMonthToDate(
+	ctx context.Context, f AnalyticsFilter,
+) (MonthToDateResponse, error) {
+	end, err := time.Parse("2006-01-02", f.To)
+	if err != nil {
+		return MonthToDateResponse{}, fmt.Errorf("invalid to: %w", err)
+	}
+	monthStart := time.Date(
+		end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC,
+	)
+	daysInMonth := monthStart.AddDate(0, 1, -1).Day()
+	prevStart := monthStart.AddDate(0, -1, 0)
+	prevEnd := prevStart.AddDate(
+		0, 0, min(end.Day(), monthStart.AddDate(0, 0, -1).Day())-1,
+	)
+
+	rate := CostRate{Currency: BaseCurrency, Multiplier: 1}
+	if f.Project != "" {
+		rate = f.costRate(f.Project)
+	}
+
+	cur, err := db.monthPeriod(ctx, f, monthStart, end, rate)
+	if err != nil {
+		return MonthToDateResponse{}, err
+	}
+	prev, err := db.monthPeriod(ctx, f, prevStart, prevEnd, rate)
+	if err != nil {
+		return MonthToDateResponse{}, err
+	}
+
+	scale := float64(daysInMonth) / float64(end.Day())
+	return MonthToDateResponse{
+		Month:       monthStart.Format("2006-01"),
+		DaysElapsed: end.Day(),
+		DaysInMonth: daysInMonth,
+		Current:     cur,
+		Previous:    prev,
+		Change: MonthChange{
+			Sessions: pctChange(
+				float64(prev.Sessions), float64(cur.Sessions),
+			),
+			Cost: pctChange(prev.Cost, cur.Cost),
+			ActiveMinutes: pctChange(
+				prev.ActiveMinutes, cur.ActiveMinutes,
+			),
+		},
+		Projected: MonthPeriod{
+			From: cur.From,
+			To: monthStart.AddDate(0, 1, -1).
+				Format("2006-01-02"),
+			Sessions: int(math.Round(
+				float64(cur.Sessions) * scale,
+			)),
+			Cost:          roundCost(cur.Cost * scale),
+			ActiveMinutes: round1(cur.ActiveMinutes * scale),
+		},
+		Currency: rate.Currency,
+	}, nil
+}
+
+// monthPeriod totals the sessions matching f that started
+// between from and to. Active time sums the gaps between a
+// session's messages, each capped like velocity's.
+func (db *DB) monthPeriod(
+	ctx context.Context, f AnalyticsFilter,
+	from, to time.Time, rate CostRate,
+) (MonthPeriod, error) {
+	f.From = from.Format("2006-01-02")
+	f.To = to.Format("2006-01-02")
+	p := MonthPeriod{From: f.From, To: f.To}
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return MonthPeriod{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+` FROM sessions WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return MonthPeriod{},
+			fmt.Errorf("querying month sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id, ts string
+		if err := rows.Scan(&id, &ts); err != nil {
+			return MonthPeriod{},
+				fmt.Errorf("scanning month session: %w", err)
+		}
+		if !inDateRange(localDate(ts, loc), f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return MonthPeriod{},
+			fmt.Errorf("iterating month sessions: %w", err)
+	}
+	p.Sessions = len(ids)
+
+	var mu sync.Mutex
+	var cost, activeSec float64
+	err = queryChunkedParallel(ctx, ids,
+		func(ctx context.Context, chunk []string) error {
+			ph, chunkArgs := inPlaceholders(chunk)
+			rows, err := db.getReader().QueryContext(ctx,
+				`SELECT session_id, timestamp, model,
+					input_tokens, output_tokens,
+					cache_read_tokens, cache_creation_tokens
+				FROM messages
+				WHERE session_id IN `+ph+`
+				ORDER BY session_id, ordinal`,
+				chunkArgs...,
+			)
+			if err != nil {
+				return fmt.Errorf("querying month messages: %w", err)
+			}
+			defer rows.Close()
+
+			var chunkCost, chunkSec float64
+			var lastSID string
+			var last time.Time
+			for rows.Next() {
+				var sid, ts, model string
+				var u pricing.Usage
+				if err := rows.Scan(
+					&sid, &ts, &model, &u.Input, &u.Output,
+					&u.CacheRead, &u.CacheWrite,
+				); err != nil {
+					return fmt.Errorf(
+						"scanning month message: %w", err,
+					)
+				}
+				if model != "" {
+					chunkCost += pricing.Cost(model, u)
+				}
+				t, ok := localTime(ts, loc)
+				if !ok {
+					last = time.Time{}
+					continue
+				}
+				if sid == lastSID && !last.IsZero() {
+					if gap := t.Sub(last).Seconds(); gap > 0 {
+						chunkSec += min(gap, maxActiveGapSec)
+					}
+				}
+				lastSID, last = sid, t
+			}
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			cost += chunkCost
+			activeSec += chunkSec
+			return nil
+		})
+	if err != nil {
+		return MonthPeriod{}, err
+	}
+	p.Cost = roundCost(cost * rate.Multiplier)
+	p.ActiveMinutes = round1(activeSec / 60)
+	return p, nil
+}
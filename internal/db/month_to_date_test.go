@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetMonthToDate(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	// seed adds a session with a user prompt at start and a
+	// priced assistant reply ($3) after gap.
+	seed := func(id, start, reply string) {
+		insertSession(t, d, id, "alpha", func(s *Session) {
+			s.StartedAt = Ptr(start)
+			s.MessageCount = 2
+		})
+		a := asstMsg(id, 1, "a")
+		a.Timestamp = reply
+		a.Model = "claude-sonnet-4-20250514"
+		a.InputTokens = 1_000_000
+		insertMessages(t, d, userMsgAt(id, 0, "q", start), a)
+	}
+	seed("m1", "2024-03-02T09:00:00Z", "2024-03-02T09:02:00Z")
+	// The 20-minute gap counts as 5 active minutes.
+	seed("m2", "2024-03-10T10:00:00Z", "2024-03-10T10:20:00Z")
+	seed("m3", "2024-03-11T10:00:00Z", "2024-03-11T10:01:00Z")
+	seed("f1", "2024-02-05T10:00:00Z", "2024-02-05T10:00:00Z")
+	seed("f2", "2024-02-12T10:00:00Z", "2024-02-12T10:01:00Z")
+
+	resp, err := d.GetMonthToDate(ctx, AnalyticsFilter{
+		To: "2024-03-10", Timezone: "UTC",
+	})
+	requireNoError(t, err, "GetMonthToDate")
+
+	assertEq(t, "Month", resp.Month, "2024-03")
+	assertEq(t, "DaysElapsed", resp.DaysElapsed, 10)
+	assertEq(t, "DaysInMonth", resp.DaysInMonth, 31)
+	assertEq(t, "Current", resp.Current, MonthPeriod{
+		From: "2024-03-01", To: "2024-03-10",
+		Sessions: 2, Cost: 6, ActiveMinutes: 7,
+	})
+	assertEq(t, "Previous", resp.Previous, MonthPeriod{
+		From: "2024-02-01", To: "2024-02-10",
+		Sessions: 1, Cost: 3,
+	})
+	if c := resp.Change.Cost; c == nil || *c != 100 {
+		t.Errorf("Change.Cost = %v, want 100", c)
+	}
+	if resp.Change.ActiveMinutes != nil {
+		t.Errorf("Change.ActiveMinutes = %v, want nil",
+			*resp.Change.ActiveMinutes)
+	}
+	assertEq(t, "Projected", resp.Projected, MonthPeriod{
+		From: "2024-03-01", To: "2024-03-31",
+		Sessions: 6, Cost: 18.6, ActiveMinutes: 21.7,
+	})
+	assertEq(t, "Currency", resp.Currency, "USD")
+
+	t.Run("previous month is shorter", func(t *testing.T) {
+		resp, err := d.GetMonthToDate(ctx, AnalyticsFilter{
+			To: "2024-03-31", Timezone: "UTC",
+		})
+		requireNoError(t, err, "GetMonthToDate")
+		assertEq(t, "Previous.To", resp.Previous.To, "2024-02-29")
+		assertEq(t, "Previous.Sessions", resp.Previous.Sessions, 2)
+	})
+
+	t.Run("project cost rate", func(t *testing.T) {
+		resp, err := d.GetMonthToDate(ctx, AnalyticsFilter{
+			To: "2024-03-10", Timezone: "UTC", Project: "alpha",
+			CostRates: map[string]CostRate{
+				"alpha": {Currency: "EUR", Multiplier: 0.5},
+			},
+		})
+		requireNoError(t, err, "GetMonthToDate")
+		assertEq(t, "Currency", resp.Currency, "EUR")
+		assertEq(t, "Current.Cost", resp.Current.Cost, 3.0)
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		if _, err := d.GetMonthToDate(ctx, AnalyticsFilter{
+			To: "march",
+		}); err == nil {
+			t.Error("expected error for invalid to")
+		}
+	})
+}
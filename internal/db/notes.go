@@ -0,0 +1,208 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxNoteLength bounds the Markdown body of one note.
+const MaxNoteLength = 64 << 10
+
+// SessionNote is a free-form Markdown note on a session, or on
+// the message at Ordinal when set.
+type SessionNote struct {
+	ID        int64  `json:"id"`
+	SessionID string `json:"session_id"`
+	Ordinal   *int   `json:"ordinal,omitempty"`
+	Body      string `json:"body"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ValidateNoteBody checks that a note body is non-blank and
+// within MaxNoteLength.
+func ValidateNoteBody(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return errors.New("note body is required")
+	}
+	if len(body) > MaxNoteLength {
+		return fmt.Errorf("note exceeds %d bytes", MaxNoteLength)
+	}
+	return nil
+}
+
+const noteCols = `id, session_id, ordinal, body, created_at, updated_at`
+
+func scanNote(rs rowScanner) (SessionNote, error) {
+	var n SessionNote
+	var ordinal sql.NullInt64
+	err := rs.Scan(
+		&n.ID, &n.SessionID, &ordinal, &n.Body,
+		&n.CreatedAt, &n.UpdatedAt,
+	)
+	if ordinal.Valid {
+		o := int(ordinal.Int64)
+		n.Ordinal = &o
+	}
+	return n, err
+}
+
+// ListSessionNotes returns a session's notes: whole-session
+// notes first, then message notes by ordinal, each oldest
+// first.
+func (db *DB) ListSessionNotes(
+	ctx context.Context, sessionID string,
+) ([]SessionNote, error) {
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT `+noteCols+` FROM session_notes
+		WHERE session_id = ?
+		ORDER BY ordinal IS NOT NULL, ordinal, id`, sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying session notes: %w", err)
+	}
+	defer rows.Close()
+
+	var notes []SessionNote
+	for rows.Next() {
+		n, err := scanNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning note: %w", err)
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// AddSessionNote attaches a note to a session, or to its
+// message at ordinal when ordinal is non-nil.
+func (db *DB) AddSessionNote(
+	ctx context.Context, sessionID string, ordinal *int, body string,
+) (SessionNote, error) {
+	if err := ValidateNoteBody(body); err != nil {
+		return SessionNote{}, err
+	}
+	var n SessionNote
+	err := db.write(func() error {
+		var err error
+		n, err = scanNote(db.getWriter().QueryRowContext(ctx,
+			`INSERT INTO session_notes (session_id, ordinal, body)
+			VALUES (?, ?, ?)
+			RETURNING `+noteCols,
+			sessionID, ordinal, body,
+		))
+		return err
+	})
+	if err != nil {
+		return SessionNote{}, fmt.Errorf("adding session note: %w", err)
+	}
+	return n, nil
+}
+
+// UpdateSessionNote replaces the body of a session's note.
+// Returns nil when the session has no note with that ID.
+func (db *DB) UpdateSessionNote(
+	ctx context.Context, sessionID string, id int64, body string,
+) (*SessionNote, error) {
+	if err := ValidateNoteBody(body); err != nil {
+		return nil, err
+	}
+	var n SessionNote
+	err := db.write(func() error {
+		var err error
+		n, err = scanNote(db.getWriter().QueryRowContext(ctx,
+			`UPDATE session_notes SET body = ?,
+				updated_at = strftime('%Y-%m-%dT%H:%M:%fZ','now')
+			WHERE id = ? AND session_id = ?
+			RETURNING `+noteCols,
+			body, id, sessionID,
+		))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("updating session note: %w", err)
+	}
+	return &n, nil
+}
+
+// DeleteSessionNote removes a session's note and reports
+// whether it existed.
+func (db *DB) DeleteSessionNote(
+	ctx context.Context, sessionID string, id int64,
+) (bool, error) {
+	var n int64
+	err := db.write(func() error {
+		res, err := db.getWriter().ExecContext(ctx,
+			`DELETE FROM session_notes WHERE id = ? AND session_id = ?`,
+			id, sessionID,
+		)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("deleting session note: %w", err)
+	}
+	return n > 0, nil
+}
+
+// NoteSearchResult is a note matching a full-text query.
+type NoteSearchResult struct {
+	SessionNote
+	Project string `json:"project"`
+	Snippet string `json:"snippet"`
+}
+
+// SearchNotes returns notes matching an FTS query, best match
+// first. Requires FTS.
+func (db *DB) SearchNotes(
+	ctx context.Context, query string, limit int,
+) ([]NoteSearchResult, error) {
+	if limit <= 0 || limit > MaxSearchLimit {
+		limit = DefaultSearchLimit
+	}
+	rows, err := db.getReader().QueryContext(ctx, fmt.Sprintf(`
+		SELECT n.id, n.session_id, n.ordinal, n.body,
+			n.created_at, n.updated_at,
+			COALESCE(s.project, ''),
+			snippet(session_notes_fts, 0, '<mark>', '</mark>',
+				'...', %d)
+		FROM session_notes_fts
+		JOIN session_notes n ON session_notes_fts.rowid = n.id
+		LEFT JOIN sessions s ON s.id = n.session_id
+		WHERE session_notes_fts MATCH ?
+		ORDER BY rank
+		LIMIT ?`, snippetTokenLength),
+		query, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("searching notes: %w", err)
+	}
+	defer rows.Close()
+
+	results := []NoteSearchResult{}
+	for rows.Next() {
+		var r NoteSearchResult
+		var ordinal sql.NullInt64
+		if err := rows.Scan(
+			&r.ID, &r.SessionID, &ordinal, &r.Body,
+			&r.CreatedAt, &r.UpdatedAt, &r.Project, &r.Snippet,
+		); err != nil {
+			return nil, fmt.Errorf("scanning note result: %w", err)
+		}
+		if ordinal.Valid {
+			o := int(ordinal.Int64)
+			r.Ordinal = &o
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
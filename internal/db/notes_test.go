@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSessionNotes(t *testing.T) {
+	d := testDB(t)
+	requireFTS(t, d)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+	insertSession(t, d, "s2", "proj")
+
+	msgNote, err := d.AddSessionNote(ctx, "s1", Ptr(3),
+		"Wrong fix: the **retry** loop hides the real error")
+	requireNoError(t, err, "AddSessionNote message")
+	_, err = d.AddSessionNote(ctx, "s1", nil, "Post-mortem: wasted an hour")
+	requireNoError(t, err, "AddSessionNote session")
+	_, err = d.AddSessionNote(ctx, "s2", nil, "Clean run")
+	requireNoError(t, err, "AddSessionNote s2")
+	if _, err := d.AddSessionNote(ctx, "s1", nil, "  "); err == nil {
+		t.Error("blank note accepted")
+	}
+
+	s, err := d.GetSessionFull(ctx, "s1")
+	requireNoError(t, err, "GetSessionFull")
+	if len(s.Notes) != 2 || s.Notes[0].Ordinal != nil ||
+		s.Notes[1].Ordinal == nil || *s.Notes[1].Ordinal != 3 {
+		t.Fatalf("notes = %+v, want session note then message 3", s.Notes)
+	}
+
+	updated, err := d.UpdateSessionNote(ctx, "s1", msgNote.ID,
+		"Wrong fix: the backoff loop hides the real error")
+	requireNoError(t, err, "UpdateSessionNote")
+	if updated == nil || updated.Ordinal == nil || *updated.Ordinal != 3 {
+		t.Errorf("updated = %+v", updated)
+	}
+	other, err := d.UpdateSessionNote(ctx, "s2", msgNote.ID, "x")
+	requireNoError(t, err, "UpdateSessionNote other session")
+	if other != nil {
+		t.Errorf("updated another session's note: %+v", other)
+	}
+
+	hits, err := d.SearchNotes(ctx, "backoff", 10)
+	requireNoError(t, err, "SearchNotes")
+	if len(hits) != 1 || hits[0].ID != msgNote.ID || hits[0].Project != "proj" {
+		t.Errorf("hits = %+v, want the updated note", hits)
+	}
+	hits, err = d.SearchNotes(ctx, "retry", 10)
+	requireNoError(t, err, "SearchNotes old text")
+	if len(hits) != 0 {
+		t.Errorf("old text still indexed: %+v", hits)
+	}
+
+	found, err := d.DeleteSessionNote(ctx, "s1", msgNote.ID)
+	requireNoError(t, err, "DeleteSessionNote")
+	if !found {
+		t.Error("DeleteSessionNote found = false")
+	}
+	notes, err := d.ListSessionNotes(ctx, "s1")
+	requireNoError(t, err, "ListSessionNotes")
+	if len(notes) != 1 {
+		t.Errorf("notes after delete = %+v", notes)
+	}
+	hits, err = d.SearchNotes(ctx, "backoff", 10)
+	requireNoError(t, err, "SearchNotes after delete")
+	if len(hits) != 0 {
+		t.Errorf("deleted note still indexed: %+v", hits)
+	}
+}
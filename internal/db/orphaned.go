@@ -3,12 +3,12 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 )
 
-// CopyOrphanedDataFrom copies sessions (and their messages
-// and tool_calls) that exist in the source database but not
+// CopyOrphanedDataFrom copies sessions (and their messages,
+// tool_calls, and hook_events) that exist in the source database but not
 // in this database. This preserves archived sessions whose
 // source files no longer exist on disk.
 //
@@ -24,9 +24,17 @@ import (
 func (d *DB) CopyOrphanedDataFrom(
 	sourcePath string,
 ) (int, error) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+	var count int
+	err := d.write(func() (err error) {
+		count, err = d.copyOrphanedDataLocked(sourcePath)
+		return err
+	})
+	return count, err
+}
 
+func (d *DB) copyOrphanedDataLocked(
+	sourcePath string,
+) (int, error) {
 	ctx := context.Background()
 	conn, err := d.getWriter().Conn(ctx)
 	if err != nil {
@@ -81,7 +89,7 @@ func (d *DB) CopyOrphanedDataFrom(
 
 	t := time.Now()
 
-	// Use a transaction so all three inserts are atomic.
+	// Use a transaction so all inserts are atomic.
 	// Partial orphan copies would leave dangling sessions
 	// without messages or tool_calls.
 	tx, err := conn.BeginTx(ctx, nil)
@@ -95,13 +103,17 @@ func (d *DB) CopyOrphanedDataFrom(
 		INSERT OR IGNORE INTO sessions
 			(id, project, machine, agent, first_message,
 			 started_at, ended_at, message_count,
-			 user_message_count, file_path, file_size,
+			 user_message_count, interrupt_count, headless,
+			 git_branch, worktree, entry_point, client,
+			 quality_score, language, file_path, file_size,
 			 file_mtime, file_hash, parent_session_id,
 			 relationship_type, created_at)
 		SELECT
 			id, project, machine, agent, first_message,
 			started_at, ended_at, message_count,
-			user_message_count, file_path, file_size,
+			user_message_count, interrupt_count, headless,
+			git_branch, worktree, entry_point, client,
+			quality_score, language, file_path, file_size,
 			file_mtime, file_hash, parent_session_id,
 			relationship_type, created_at
 		FROM old_db.sessions
@@ -119,11 +131,17 @@ func (d *DB) CopyOrphanedDataFrom(
 		INSERT INTO messages
 			(session_id, ordinal, role, content,
 			 timestamp, has_thinking, has_tool_use,
-			 content_length)
+			 content_length, model, model_switch, kind, input_tokens,
+			 output_tokens, cache_read_tokens,
+			 cache_creation_tokens, reasoning_tokens, source_uuid,
+			 pasted_chars, language)
 		SELECT
 			session_id, ordinal, role, content,
 			timestamp, has_thinking, has_tool_use,
-			content_length
+			content_length, model, model_switch, kind, input_tokens,
+			output_tokens, cache_read_tokens,
+			cache_creation_tokens, reasoning_tokens, source_uuid,
+			pasted_chars, language
 		FROM old_db.messages
 		WHERE session_id IN (
 			SELECT id FROM _orphaned_ids
@@ -140,12 +158,16 @@ func (d *DB) CopyOrphanedDataFrom(
 		INSERT INTO tool_calls
 			(message_id, session_id, tool_name, category,
 			 tool_use_id, input_json, skill_name,
-			 result_content_length, subagent_session_id)
+			 result_content_length, subagent_session_id,
+			 file_path, result_error, result_truncated,
+			 result_original_length, command)
 		SELECT
 			new_m.id, otc.session_id, otc.tool_name,
 			otc.category, otc.tool_use_id, otc.input_json,
 			otc.skill_name, otc.result_content_length,
-			otc.subagent_session_id
+			otc.subagent_session_id, otc.file_path,
+			otc.result_error, otc.result_truncated,
+			otc.result_original_length, otc.command
 		FROM old_db.tool_calls otc
 		JOIN old_db.messages old_m
 			ON old_m.id = otc.message_id
@@ -161,15 +183,132 @@ func (d *DB) CopyOrphanedDataFrom(
 		)
 	}
 
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO token_usage
+			(message_id, session_id, model, input_tokens,
+			 output_tokens, cache_read_tokens,
+			 cache_creation_tokens, reasoning_tokens)
+		SELECT
+			new_m.id, otu.session_id, otu.model,
+			otu.input_tokens, otu.output_tokens,
+			otu.cache_read_tokens, otu.cache_creation_tokens,
+			otu.reasoning_tokens
+		FROM old_db.token_usage otu
+		JOIN old_db.messages old_m
+			ON old_m.id = otu.message_id
+		JOIN main.messages new_m
+			ON new_m.session_id = old_m.session_id
+			AND new_m.ordinal = old_m.ordinal
+		WHERE otu.session_id IN (
+			SELECT id FROM _orphaned_ids
+		)`,
+	); err != nil {
+		return 0, fmt.Errorf(
+			"copying orphaned token_usage: %w", err,
+		)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO hook_events
+			(session_id, hook_event, hook_name, tool_name,
+			 command, decision, message, timestamp)
+		SELECT
+			session_id, hook_event, hook_name, tool_name,
+			command, decision, message, timestamp
+		FROM old_db.hook_events
+		WHERE session_id IN (
+			SELECT id FROM _orphaned_ids
+		)
+		ORDER BY id`,
+	); err != nil {
+		return 0, fmt.Errorf(
+			"copying orphaned hook_events: %w", err,
+		)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO session_commands
+			(session_id, name, timestamp)
+		SELECT session_id, name, timestamp
+		FROM old_db.session_commands
+		WHERE session_id IN (
+			SELECT id FROM _orphaned_ids
+		)
+		ORDER BY id`,
+	); err != nil {
+		return 0, fmt.Errorf(
+			"copying orphaned session_commands: %w", err,
+		)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO session_todos
+			(session_id, position, content, status,
+			 active_form, updated_at)
+		SELECT
+			session_id, position, content, status,
+			active_form, updated_at
+		FROM old_db.session_todos
+		WHERE session_id IN (
+			SELECT id FROM _orphaned_ids
+		)
+		ORDER BY id`,
+	); err != nil {
+		return 0, fmt.Errorf(
+			"copying orphaned session_todos: %w", err,
+		)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO unknown_records
+			(session_id, record_type, count, sample)
+		SELECT session_id, record_type, count, sample
+		FROM old_db.unknown_records
+		WHERE session_id IN (
+			SELECT id FROM _orphaned_ids
+		)`,
+	); err != nil {
+		return 0, fmt.Errorf(
+			"copying orphaned unknown_records: %w", err,
+		)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_message_origins (session_id, origin, count)
+		SELECT session_id, origin, count
+		FROM old_db.user_message_origins
+		WHERE session_id IN (
+			SELECT id FROM _orphaned_ids
+		)`,
+	); err != nil {
+		return 0, fmt.Errorf(
+			"copying orphaned user_message_origins: %w", err,
+		)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO session_env (session_id, key, value)
+		SELECT session_id, key, value
+		FROM old_db.session_env
+		WHERE session_id IN (
+			SELECT id FROM _orphaned_ids
+		)`,
+	); err != nil {
+		return 0, fmt.Errorf(
+			"copying orphaned session_env: %w", err,
+		)
+	}
+
 	if err := tx.Commit(); err != nil {
 		return 0, fmt.Errorf(
 			"committing orphaned data: %w", err,
 		)
 	}
 
-	log.Printf(
-		"resync: copied %d orphaned sessions in %s",
-		count, time.Since(t).Round(time.Millisecond),
+	slog.Info(
+		"resync: copied orphaned sessions",
+		"count", count,
+		"elapsed", time.Since(t).Round(time.Millisecond),
 	)
 
 	return count, nil
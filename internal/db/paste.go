@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// PasteTotals compares context the user pasted into prompts
+// with context the agent read itself through Read tools.
+type PasteTotals struct {
+	UserMessages  int `json:"user_messages"`
+	PasteMessages int `json:"paste_messages"` // with any pasted text
+	TypedChars    int `json:"typed_chars"`
+	PastedChars   int `json:"pasted_chars"`
+	ReadChars     int `json:"read_chars"` // Read tool results
+	// PasteShare is the percentage of pasted plus read context
+	// that was pasted.
+	PasteShare float64 `json:"paste_share"`
+}
+
+func (t *PasteTotals) add(o PasteTotals) {
+	t.UserMessages += o.UserMessages
+	t.PasteMessages += o.PasteMessages
+	t.TypedChars += o.TypedChars
+	t.PastedChars += o.PastedChars
+	t.ReadChars += o.ReadChars
+}
+
+func (t *PasteTotals) finish() {
+	if n := t.PastedChars + t.ReadChars; n > 0 {
+		t.PasteShare = round1(float64(t.PastedChars) / float64(n) * 100)
+	}
+}
+
+// PasteTrendEntry is one time bucket of paste totals.
+type PasteTrendEntry struct {
+	Date string `json:"date"`
+	PasteTotals
+}
+
+// ProjectPaste is the paste totals for one project.
+type ProjectPaste struct {
+	Project string `json:"project"`
+	PasteTotals
+}
+
+// PasteResponse wraps pasted-context analytics. Projects are
+// ordered by pasted characters.
+type PasteResponse struct {
+	Granularity string            `json:"granularity"`
+	Overall     PasteTotals       `json:"overall"`
+	Projects    []ProjectPaste    `json:"projects"`
+	Trend       []PasteTrendEntry `json:"trend"`
+}
+
+// GetAnalyticsPaste totals pasted and typed characters in user
+// messages against characters returned by Read tool calls, by
+// project and by session start date.
+func (db *DB) GetAnalyticsPaste(
+	ctx context.Context, f AnalyticsFilter, granularity string,
+) (PasteResponse, error) {
+	if granularity == "" {
+		granularity = "day"
+	}
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return PasteResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, project
+		FROM sessions WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return PasteResponse{},
+			fmt.Errorf("querying paste sessions: %w", err)
+	}
+	defer rows.Close()
+
+	type sessInfo struct{ bucket, project string }
+	sessions := map[string]sessInfo{}
+	var ids []string
+	for rows.Next() {
+		var id, ts, project string
+		if err := rows.Scan(&id, &ts, &project); err != nil {
+			return PasteResponse{},
+				fmt.Errorf("scanning paste session: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		sessions[id] = sessInfo{bucketDate(date, granularity), project}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return PasteResponse{},
+			fmt.Errorf("iterating paste sessions: %w", err)
+	}
+
+	perSession := make(map[string]*PasteTotals, len(ids))
+	totalsFor := func(id string) *PasteTotals {
+		t := perSession[id]
+		if t == nil {
+			t = &PasteTotals{}
+			perSession[id] = t
+		}
+		return t
+	}
+	err = queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		msgRows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, COUNT(*),
+				SUM(pasted_chars > 0),
+				SUM(MAX(content_length - pasted_chars, 0)),
+				SUM(pasted_chars)
+			FROM messages
+			WHERE session_id IN `+ph+`
+				AND role = 'user' AND kind = ''
+			GROUP BY session_id`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying paste messages: %w", err)
+		}
+		defer msgRows.Close()
+		for msgRows.Next() {
+			var id string
+			var t PasteTotals
+			if err := msgRows.Scan(
+				&id, &t.UserMessages, &t.PasteMessages,
+				&t.TypedChars, &t.PastedChars,
+			); err != nil {
+				return fmt.Errorf("scanning paste messages: %w", err)
+			}
+			totalsFor(id).add(t)
+		}
+		if err := msgRows.Err(); err != nil {
+			return err
+		}
+
+		readRows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id,
+				SUM(COALESCE(result_content_length, 0))
+			FROM tool_calls
+			WHERE session_id IN `+ph+` AND category = 'Read'
+			GROUP BY session_id`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying read results: %w", err)
+		}
+		defer readRows.Close()
+		for readRows.Next() {
+			var id string
+			var n int
+			if err := readRows.Scan(&id, &n); err != nil {
+				return fmt.Errorf("scanning read results: %w", err)
+			}
+			totalsFor(id).ReadChars += n
+		}
+		return readRows.Err()
+	})
+	if err != nil {
+		return PasteResponse{}, err
+	}
+
+	resp := PasteResponse{
+		Granularity: granularity,
+		Projects:    []ProjectPaste{},
+		Trend:       []PasteTrendEntry{},
+	}
+	projects := map[string]*ProjectPaste{}
+	buckets := map[string]*PasteTrendEntry{}
+	for id, t := range perSession {
+		info := sessions[id]
+		resp.Overall.add(*t)
+		p := projects[info.project]
+		if p == nil {
+			p = &ProjectPaste{Project: info.project}
+			projects[info.project] = p
+		}
+		p.add(*t)
+		b := buckets[info.bucket]
+		if b == nil {
+			b = &PasteTrendEntry{Date: info.bucket}
+			buckets[info.bucket] = b
+		}
+		b.add(*t)
+	}
+
+	resp.Overall.finish()
+	for _, p := range projects {
+		p.finish()
+		resp.Projects = append(resp.Projects, *p)
+	}
+	sort.Slice(resp.Projects, func(i, j int) bool {
+		a, b := resp.Projects[i], resp.Projects[j]
+		if a.PastedChars != b.PastedChars {
+			return a.PastedChars > b.PastedChars
+		}
+		return a.Project < b.Project
+	})
+	for _, b := range buckets {
+		b.finish()
+		resp.Trend = append(resp.Trend, *b)
+	}
+	sort.Slice(resp.Trend, func(i, j int) bool {
+		return resp.Trend[i].Date < resp.Trend[j].Date
+	})
+	return resp, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetAnalyticsPaste(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "alpha", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-03T09:00:00Z")
+	})
+	insertSession(t, d, "s2", "beta", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-04T09:00:00Z")
+	})
+
+	pasted := userMsg("s1", 0, "a prompt with a big paste")
+	pasted.ContentLength = 1000
+	pasted.PastedChars = 900
+	typed := userMsg("s1", 1, "typed")
+	command := userMsg("s1", 2, "/clear")
+	command.Kind = "command"
+	command.PastedChars = 50
+	read := toolMsg("s1", 3, "2024-06-03T09:01:00Z", ToolCall{
+		ToolName: "Read", Category: "Read", ToolUseID: "r1",
+		ResultContentLength: 300,
+	})
+	insertMessages(t, d, pasted, typed, command, read)
+	insertMessages(t, d, userMsg("s2", 0, "hello"))
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsPaste(ctx, f, "day")
+	requireNoError(t, err, "GetAnalyticsPaste")
+	assertEq(t, "Overall", resp.Overall, PasteTotals{
+		UserMessages: 3, PasteMessages: 1, TypedChars: 110,
+		PastedChars: 900, ReadChars: 300, PasteShare: 75,
+	})
+	if len(resp.Projects) != 2 || resp.Projects[0].Project != "alpha" {
+		t.Fatalf("Projects = %+v, want alpha first", resp.Projects)
+	}
+	assertEq(t, "beta", resp.Projects[1].PasteTotals, PasteTotals{
+		UserMessages: 1, TypedChars: 5,
+	})
+	if len(resp.Trend) != 2 || resp.Trend[0].Date != "2024-06-03" ||
+		resp.Trend[0].PastedChars != 900 {
+		t.Errorf("Trend = %+v, want 2024-06-03 with 900 pasted",
+			resp.Trend)
+	}
+}
@@ -0,0 +1,299 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultProgressSessions and MaxProgressSessions bound the
+// cohort size N for GetAnalyticsProgress.
+const (
+	DefaultProgressSessions = 10
+	MaxProgressSessions     = 100
+)
+
+// ProgressCohort aggregates one group of a project's sessions.
+// A task is a user message.
+type ProgressCohort struct {
+	Sessions             int                `json:"sessions"`
+	From                 string             `json:"from"` // local date of first session
+	To                   string             `json:"to"`   // local date of last session
+	MessagesPerSession   float64            `json:"messages_per_session"`
+	TasksPerSession      float64            `json:"tasks_per_session"`
+	MessagesPerTask      float64            `json:"messages_per_task"`
+	ToolCallsPerSession  float64            `json:"tool_calls_per_session"`
+	InterruptsPerSession float64            `json:"interrupts_per_session"`
+	InterruptedPct       float64            `json:"interrupted_pct"` // sessions with any interrupt
+	ToolMix              map[string]float64 `json:"tool_mix"`        // category -> % of tool calls
+}
+
+// ProgressChange is the percent change from the first cohort
+// to the recent one. Nil when the first cohort's value is zero.
+type ProgressChange struct {
+	MessagesPerTask      *float64 `json:"messages_per_task"`
+	ToolCallsPerSession  *float64 `json:"tool_calls_per_session"`
+	InterruptsPerSession *float64 `json:"interrupts_per_session"`
+}
+
+// ProjectProgress compares a project's first and most recent
+// sessions.
+type ProjectProgress struct {
+	Project       string         `json:"project"`
+	TotalSessions int            `json:"total_sessions"`
+	N             int            `json:"n"` // sessions per cohort
+	First         ProgressCohort `json:"first"`
+	Recent        ProgressCohort `json:"recent"`
+	Change        ProgressChange `json:"change"`
+}
+
+// ProgressResponse wraps the per-project comparison.
+type ProgressResponse struct {
+	N        int               `json:"n"`
+	Projects []ProjectProgress `json:"projects"`
+}
+
+// progressSession is the per-session data a cohort needs.
+type progressSession struct {
+	id         string
+	date       string
+	messages   int
+	tasks      int
+	interrupts int
+}
+
+// GetAnalyticsProgress compares each project's first n sessions
+// in the filter range with its most recent n, to show whether
+// the agent has become more effective on that codebase. When a
+// project has fewer than 2n sessions, both cohorts shrink to
+// half its sessions so they do not overlap. Projects with fewer
+// than two sessions are omitted.
+func (db *DB) GetAnalyticsProgress(
+	ctx context.Context, f AnalyticsFilter, n int,
+) (ProgressResponse, error) {
+	if n <= 0 {
+		n = DefaultProgressSessions
+	}
+	n = min(n, MaxProgressSessions)
+
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return ProgressResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, project, `+dateCol+`, message_count,
+			user_message_count, interrupt_count
+		FROM sessions WHERE `+where+`
+		ORDER BY `+dateCol+`, id`,
+		args...,
+	)
+	if err != nil {
+		return ProgressResponse{},
+			fmt.Errorf("querying progress sessions: %w", err)
+	}
+	defer rows.Close()
+
+	byProject := map[string][]progressSession{}
+	for rows.Next() {
+		var s progressSession
+		var project, ts string
+		if err := rows.Scan(
+			&s.id, &project, &ts, &s.messages,
+			&s.tasks, &s.interrupts,
+		); err != nil {
+			return ProgressResponse{},
+				fmt.Errorf("scanning progress session: %w", err)
+		}
+		s.date = localDate(ts, loc)
+		if !inDateRange(s.date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[s.id] {
+			continue
+		}
+		byProject[project] = append(byProject[project], s)
+	}
+	if err := rows.Err(); err != nil {
+		return ProgressResponse{},
+			fmt.Errorf("iterating progress sessions: %w", err)
+	}
+
+	type cohorts struct {
+		project       string
+		total, size   int
+		first, recent []progressSession
+	}
+	var groups []cohorts
+	var ids []string
+	for project, sessions := range byProject {
+		if len(sessions) < 2 {
+			continue
+		}
+		size := min(n, len(sessions)/2)
+		c := cohorts{
+			project: project,
+			total:   len(sessions),
+			size:    size,
+			first:   sessions[:size],
+			recent:  sessions[len(sessions)-size:],
+		}
+		for _, s := range c.first {
+			ids = append(ids, s.id)
+		}
+		for _, s := range c.recent {
+			ids = append(ids, s.id)
+		}
+		groups = append(groups, c)
+	}
+
+	tools, err := db.toolCategoryCounts(ctx, ids)
+	if err != nil {
+		return ProgressResponse{}, err
+	}
+
+	resp := ProgressResponse{
+		N:        n,
+		Projects: make([]ProjectProgress, 0, len(groups)),
+	}
+	for _, c := range groups {
+		p := ProjectProgress{
+			Project:       c.project,
+			TotalSessions: c.total,
+			N:             c.size,
+			First:         buildProgressCohort(c.first, tools),
+			Recent:        buildProgressCohort(c.recent, tools),
+		}
+		p.Change = ProgressChange{
+			MessagesPerTask: pctChange(
+				p.First.MessagesPerTask, p.Recent.MessagesPerTask,
+			),
+			ToolCallsPerSession: pctChange(
+				p.First.ToolCallsPerSession,
+				p.Recent.ToolCallsPerSession,
+			),
+			InterruptsPerSession: pctChange(
+				p.First.InterruptsPerSession,
+				p.Recent.InterruptsPerSession,
+			),
+		}
+		resp.Projects = append(resp.Projects, p)
+	}
+	sort.Slice(resp.Projects, func(i, j int) bool {
+		a, b := resp.Projects[i], resp.Projects[j]
+		if a.TotalSessions != b.TotalSessions {
+			return a.TotalSessions > b.TotalSessions
+		}
+		return a.Project < b.Project
+	})
+	return resp, nil
+}
+
+// toolCategoryCounts returns tool call counts by session, then
+// category.
+func (db *DB) toolCategoryCounts(
+	ctx context.Context, ids []string,
+) (map[string]map[string]int, error) {
+	out := map[string]map[string]int{}
+	err := queryChunked(ids, func(chunk []string) error {
+		ph, args := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, category, COUNT(*)
+			FROM tool_calls
+			WHERE session_id IN `+ph+`
+			GROUP BY session_id, category`,
+			args...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying tool categories: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sid, category string
+			var count int
+			if err := rows.Scan(&sid, &category, &count); err != nil {
+				return fmt.Errorf("scanning tool category: %w", err)
+			}
+			if out[sid] == nil {
+				out[sid] = map[string]int{}
+			}
+			out[sid][category] = count
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func buildProgressCohort(
+	sessions []progressSession,
+	tools map[string]map[string]int,
+) ProgressCohort {
+	c := ProgressCohort{
+		Sessions: len(sessions),
+		ToolMix:  map[string]float64{},
+	}
+	if len(sessions) == 0 {
+		return c
+	}
+	c.From = sessions[0].date
+	c.To = sessions[len(sessions)-1].date
+
+	var msgs, tasks, interrupts, interrupted, toolCalls int
+	mix := map[string]int{}
+	for _, s := range sessions {
+		msgs += s.messages
+		tasks += s.tasks
+		interrupts += s.interrupts
+		if s.interrupts > 0 {
+			interrupted++
+		}
+		for category, count := range tools[s.id] {
+			mix[category] += count
+			toolCalls += count
+		}
+	}
+
+	n := float64(len(sessions))
+	c.MessagesPerSession = round1(float64(msgs) / n)
+	c.TasksPerSession = round1(float64(tasks) / n)
+	if tasks > 0 {
+		c.MessagesPerTask = round1(float64(msgs) / float64(tasks))
+	}
+	c.ToolCallsPerSession = round1(float64(toolCalls) / n)
+	c.InterruptsPerSession = math.Round(
+		float64(interrupts)/n*100,
+	) / 100
+	c.InterruptedPct = round1(float64(interrupted) / n * 100)
+	for category, count := range mix {
+		c.ToolMix[category] = round1(
+			float64(count) / float64(toolCalls) * 100,
+		)
+	}
+	return c
+}
+
+// pctChange returns the percent change from before to after,
+// or nil when before is zero.
+func pctChange(before, after float64) *float64 {
+	if before == 0 {
+		return nil
+	}
+	v := round1((after - before) / before * 100)
+	return &v
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
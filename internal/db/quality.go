@@ -0,0 +1,269 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Quality score tuning. A session starts at 100 and loses
+// points for each signal, each penalty capped so no single
+// signal can sink a session on its own.
+const (
+	// LowQualityThreshold is the score below which analytics
+	// counts a session as worth reviewing.
+	LowQualityThreshold = 60
+
+	interruptPenalty    = 10 // per interrupt
+	maxInterruptPenalty = 30
+	maxFailurePenalty   = 30 // at a 100% tool failure rate
+	thrashPenalty       = 5  // per edit past the thrash threshold
+	maxThrashPenalty    = 20
+
+	// Sessions averaging more than paceMinutes per message lose
+	// pacePenalty points per extra minute.
+	paceMinutes    = 5
+	pacePenalty    = 2
+	maxPacePenalty = 20
+
+	// maxLowQualitySessions caps the sessions listed by
+	// GetAnalyticsQuality.
+	maxLowQualitySessions = 20
+)
+
+// QualitySignals are the per-session inputs to the quality
+// score.
+type QualitySignals struct {
+	Interrupts  int
+	ToolCalls   int
+	FailedTools int
+	// ThrashEdits counts Edit/Write calls past
+	// DefaultEditThrashThreshold, summed over files.
+	ThrashEdits int
+	Messages    int
+	DurationMin float64
+}
+
+// Score returns the 0-100 quality score for the signals. It is
+// a cheap heuristic for picking transcripts to review, not a
+// measure of outcome.
+func (q QualitySignals) Score() int {
+	score := 100.0
+	score -= min(
+		float64(q.Interrupts*interruptPenalty), maxInterruptPenalty,
+	)
+	if q.ToolCalls > 0 {
+		score -= maxFailurePenalty *
+			float64(q.FailedTools) / float64(q.ToolCalls)
+	}
+	score -= min(
+		float64(q.ThrashEdits*thrashPenalty), maxThrashPenalty,
+	)
+	if q.Messages > 0 {
+		pace := q.DurationMin / float64(q.Messages)
+		if pace > paceMinutes {
+			score -= min((pace-paceMinutes)*pacePenalty, maxPacePenalty)
+		}
+	}
+	return int(math.Round(max(score, 0)))
+}
+
+// UpdateSessionQuality recomputes a session's quality score
+// from its stored messages and tool calls. Sessions without
+// messages get no score.
+func (db *DB) UpdateSessionQuality(sessionID string) error {
+	return db.write(func() error {
+		return updateSessionQuality(db.getWriter(), sessionID)
+	})
+}
+
+// updateSessionQuality runs UpdateSessionQuality on x.
+func updateSessionQuality(x execer, sessionID string) error {
+	var q QualitySignals
+	var duration sql.NullFloat64
+	err := x.QueryRow(`
+		SELECT interrupt_count, message_count,
+			(julianday(ended_at) - julianday(started_at)) * 1440
+		FROM sessions WHERE id = ?`,
+		sessionID,
+	).Scan(&q.Interrupts, &q.Messages, &duration)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reading session quality: %w", err)
+	}
+	if q.Messages == 0 {
+		_, err := x.Exec(
+			"UPDATE sessions SET quality_score = NULL WHERE id = ?",
+			sessionID,
+		)
+		return err
+	}
+	q.DurationMin = max(duration.Float64, 0)
+
+	if err := x.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(result_error), 0)
+		FROM tool_calls WHERE session_id = ?`,
+		sessionID,
+	).Scan(&q.ToolCalls, &q.FailedTools); err != nil {
+		return fmt.Errorf("counting session tool calls: %w", err)
+	}
+	if err := x.QueryRow(`
+		SELECT COALESCE(SUM(n - ?), 0) FROM (
+			SELECT COUNT(*) AS n FROM tool_calls
+			WHERE session_id = ?
+			  AND category IN ('Edit', 'Write')
+			  AND file_path IS NOT NULL AND file_path != ''
+			GROUP BY file_path
+		) WHERE n > ?`,
+		DefaultEditThrashThreshold, sessionID,
+		DefaultEditThrashThreshold,
+	).Scan(&q.ThrashEdits); err != nil {
+		return fmt.Errorf("counting session thrash: %w", err)
+	}
+
+	if _, err := x.Exec(
+		"UPDATE sessions SET quality_score = ? WHERE id = ?",
+		q.Score(), sessionID,
+	); err != nil {
+		return fmt.Errorf("updating quality score: %w", err)
+	}
+	return nil
+}
+
+// QualityTrendEntry is one time bucket of quality scores.
+type QualityTrendEntry struct {
+	Date        string  `json:"date"`
+	Sessions    int     `json:"sessions"`
+	AvgScore    float64 `json:"avg_score"`
+	LowSessions int     `json:"low_sessions"`
+}
+
+// LowQualitySession is a scored session listed for review.
+type LowQualitySession struct {
+	SessionID    string  `json:"session_id"`
+	Project      string  `json:"project"`
+	Agent        string  `json:"agent"`
+	FirstMessage *string `json:"first_message"`
+	StartedAt    string  `json:"started_at"`
+	Score        int     `json:"score"`
+}
+
+// QualityResponse wraps quality score analytics. Only scored
+// sessions count; Lowest lists the lowest scores first.
+type QualityResponse struct {
+	Granularity string              `json:"granularity"`
+	Threshold   int                 `json:"threshold"`
+	Sessions    int                 `json:"sessions"`
+	AvgScore    float64             `json:"avg_score"`
+	LowSessions int                 `json:"low_sessions"`
+	Trend       []QualityTrendEntry `json:"trend"`
+	Lowest      []LowQualitySession `json:"lowest"`
+}
+
+// GetAnalyticsQuality trends session quality scores by session
+// start date and lists the lowest-scoring sessions.
+func (db *DB) GetAnalyticsQuality(
+	ctx context.Context, f AnalyticsFilter, granularity string,
+) (QualityResponse, error) {
+	if granularity == "" {
+		granularity = "day"
+	}
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return QualityResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, project, agent, first_message,
+			quality_score
+		FROM sessions
+		WHERE `+where+` AND quality_score IS NOT NULL`,
+		args...,
+	)
+	if err != nil {
+		return QualityResponse{},
+			fmt.Errorf("querying quality sessions: %w", err)
+	}
+	defer rows.Close()
+
+	resp := QualityResponse{
+		Granularity: granularity,
+		Threshold:   LowQualityThreshold,
+		Trend:       []QualityTrendEntry{},
+		Lowest:      []LowQualitySession{},
+	}
+	buckets := map[string]*QualityTrendEntry{}
+	sums := map[string]int{}
+	total := 0
+	for rows.Next() {
+		var s LowQualitySession
+		if err := rows.Scan(
+			&s.SessionID, &s.StartedAt, &s.Project, &s.Agent,
+			&s.FirstMessage, &s.Score,
+		); err != nil {
+			return QualityResponse{},
+				fmt.Errorf("scanning quality session: %w", err)
+		}
+		date := localDate(s.StartedAt, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[s.SessionID] {
+			continue
+		}
+		bucket := bucketDate(date, granularity)
+		e := buckets[bucket]
+		if e == nil {
+			e = &QualityTrendEntry{Date: bucket}
+			buckets[bucket] = e
+		}
+		e.Sessions++
+		sums[bucket] += s.Score
+		resp.Sessions++
+		total += s.Score
+		if s.Score < LowQualityThreshold {
+			e.LowSessions++
+			resp.LowSessions++
+		}
+		resp.Lowest = append(resp.Lowest, s)
+	}
+	if err := rows.Err(); err != nil {
+		return QualityResponse{},
+			fmt.Errorf("iterating quality sessions: %w", err)
+	}
+
+	for date, e := range buckets {
+		e.AvgScore = round1(float64(sums[date]) / float64(e.Sessions))
+		resp.Trend = append(resp.Trend, *e)
+	}
+	sort.Slice(resp.Trend, func(i, j int) bool {
+		return resp.Trend[i].Date < resp.Trend[j].Date
+	})
+	if resp.Sessions > 0 {
+		resp.AvgScore = round1(float64(total) / float64(resp.Sessions))
+	}
+
+	sort.Slice(resp.Lowest, func(i, j int) bool {
+		a, b := resp.Lowest[i], resp.Lowest[j]
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		return a.SessionID < b.SessionID
+	})
+	if len(resp.Lowest) > maxLowQualitySessions {
+		resp.Lowest = resp.Lowest[:maxLowQualitySessions]
+	}
+	return resp, nil
+}
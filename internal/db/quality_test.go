@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQualitySignalsScore(t *testing.T) {
+	tests := []struct {
+		name string
+		q    QualitySignals
+		want int
+	}{
+		{"clean", QualitySignals{Messages: 10, DurationMin: 20}, 100},
+		{"interrupts", QualitySignals{Interrupts: 2, Messages: 4}, 80},
+		{"interrupts capped", QualitySignals{Interrupts: 9, Messages: 4}, 70},
+		{"half failed", QualitySignals{
+			ToolCalls: 10, FailedTools: 5, Messages: 4,
+		}, 85},
+		{"thrash capped", QualitySignals{ThrashEdits: 10, Messages: 4}, 80},
+		{"slow pace", QualitySignals{Messages: 2, DurationMin: 20}, 90},
+		{"everything", QualitySignals{
+			Interrupts: 5, ToolCalls: 4, FailedTools: 4,
+			ThrashEdits: 6, Messages: 1, DurationMin: 600,
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertEq(t, "Score", tt.q.Score(), tt.want)
+		})
+	}
+}
+
+func TestUpdateSessionQuality(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "rough", "alpha", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+		s.EndedAt = Ptr("2024-06-01T09:10:00Z")
+		s.MessageCount = 7
+		s.InterruptCount = 2
+	})
+	insertSession(t, d, "empty", "alpha", func(s *Session) {
+		s.MessageCount = 0
+	})
+
+	// 7 edits to one file: 2 past the default threshold, and
+	// one failed.
+	msgs := editMsgs("rough", 0, 7, "/a.go")
+	msgs[3].ToolCalls[0].ResultError = true
+	insertMessages(t, d, msgs...)
+
+	requireNoError(t, d.UpdateSessionQuality("rough"), "rough")
+	requireNoError(t, d.UpdateSessionQuality("empty"), "empty")
+	requireNoError(t, d.UpdateSessionQuality("missing"), "missing")
+
+	// 100 - 20 (interrupts) - 30/7 (failures) - 10 (thrash).
+	s, err := d.GetSession(ctx, "rough")
+	requireNoError(t, err, "GetSession")
+	if s.QualityScore == nil || *s.QualityScore != 66 {
+		t.Errorf("QualityScore = %v, want 66", s.QualityScore)
+	}
+	full, err := d.GetSessionFull(ctx, "empty")
+	requireNoError(t, err, "GetSessionFull")
+	if full.QualityScore != nil {
+		t.Errorf("empty QualityScore = %d, want nil",
+			*full.QualityScore)
+	}
+
+	page, err := d.ListSessions(ctx, SessionFilter{QualityBelow: 66})
+	requireNoError(t, err, "ListSessions below 66")
+	assertEq(t, "below 66", len(page.Sessions), 0)
+	page, err = d.ListSessions(ctx, SessionFilter{QualityBelow: 67})
+	requireNoError(t, err, "ListSessions below 67")
+	if ids := collectIDs(page.Sessions); len(ids) != 1 || ids[0] != "rough" {
+		t.Errorf("below 67 = %v, want [rough]", ids)
+	}
+}
+
+func TestGetAnalyticsQuality(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	for _, s := range []struct {
+		id, started string
+		interrupts  int
+	}{
+		{"a", "2024-06-03T09:00:00Z", 0},
+		{"b", "2024-06-04T09:00:00Z", 5},
+		{"c", "2024-06-12T09:00:00Z", 1},
+	} {
+		insertSession(t, d, s.id, "alpha", func(sess *Session) {
+			sess.StartedAt = Ptr(s.started)
+			sess.InterruptCount = s.interrupts
+		})
+		requireNoError(t, d.UpdateSessionQuality(s.id), s.id)
+	}
+	// Unscored sessions are left out.
+	insertSession(t, d, "unscored", "alpha", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-03T09:00:00Z")
+	})
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsQuality(ctx, f, "week")
+	requireNoError(t, err, "GetAnalyticsQuality")
+	assertEq(t, "Sessions", resp.Sessions, 3)
+	assertEq(t, "AvgScore", resp.AvgScore, 86.7)
+	assertEq(t, "LowSessions", resp.LowSessions, 0)
+	if len(resp.Trend) != 2 {
+		t.Fatalf("Trend = %+v, want 2 weeks", resp.Trend)
+	}
+	assertEq(t, "week 1", resp.Trend[0], QualityTrendEntry{
+		Date: "2024-06-03", Sessions: 2, AvgScore: 85,
+	})
+	assertEq(t, "week 2", resp.Trend[1], QualityTrendEntry{
+		Date: "2024-06-10", Sessions: 1, AvgScore: 90,
+	})
+	if len(resp.Lowest) != 3 || resp.Lowest[0].SessionID != "b" ||
+		resp.Lowest[0].Score != 70 {
+		t.Errorf("Lowest = %+v, want b (70) first", resp.Lowest)
+	}
+}
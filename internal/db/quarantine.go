@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// QuarantinedFile is a session file whose last parse failed.
+// Sync retries it at NextRetryAt instead of on every pass.
+// Times are RFC 3339 UTC.
+type QuarantinedFile struct {
+	Path          string `json:"path"`
+	Agent         string `json:"agent"`
+	Error         string `json:"error"`
+	Attempts      int    `json:"attempts"`
+	FirstFailedAt string `json:"first_failed_at"`
+	LastFailedAt  string `json:"last_failed_at"`
+	NextRetryAt   string `json:"next_retry_at"`
+}
+
+const quarantineCols = `file_path, agent, error, attempts,
+	first_failed_at, last_failed_at, next_retry_at`
+
+// LoadQuarantine returns all quarantined files keyed by path.
+func (db *DB) LoadQuarantine() (map[string]QuarantinedFile, error) {
+	files, err := db.ListQuarantinedFiles(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]QuarantinedFile, len(files))
+	for _, f := range files {
+		result[f.Path] = f
+	}
+	return result, nil
+}
+
+// ListQuarantinedFiles returns quarantined files, most
+// recently failed first.
+func (db *DB) ListQuarantinedFiles(
+	ctx context.Context,
+) ([]QuarantinedFile, error) {
+	rows, err := db.getReader().QueryContext(ctx,
+		"SELECT "+quarantineCols+" FROM quarantined_files"+
+			" ORDER BY last_failed_at DESC, file_path",
+	)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"loading quarantined files: %w", err,
+		)
+	}
+	defer rows.Close()
+
+	files := []QuarantinedFile{}
+	for rows.Next() {
+		var f QuarantinedFile
+		if err := rows.Scan(
+			&f.Path, &f.Agent, &f.Error, &f.Attempts,
+			&f.FirstFailedAt, &f.LastFailedAt, &f.NextRetryAt,
+		); err != nil {
+			return nil, fmt.Errorf(
+				"scanning quarantined file: %w", err,
+			)
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// UpsertQuarantinedFile inserts or replaces the quarantine
+// record for f.Path.
+func (db *DB) UpsertQuarantinedFile(f QuarantinedFile) error {
+	return db.write(func() error {
+		_, err := db.getWriter().Exec(
+			"INSERT OR REPLACE INTO quarantined_files ("+
+				quarantineCols+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+			f.Path, f.Agent, f.Error, f.Attempts,
+			f.FirstFailedAt, f.LastFailedAt, f.NextRetryAt,
+		)
+		if err != nil {
+			return fmt.Errorf(
+				"upserting quarantined file %s: %w", f.Path, err,
+			)
+		}
+		return nil
+	})
+}
+
+// DeleteQuarantinedFile removes a file from quarantine.
+func (db *DB) DeleteQuarantinedFile(path string) error {
+	return db.write(func() error {
+		_, err := db.getWriter().Exec(
+			"DELETE FROM quarantined_files WHERE file_path = ?",
+			path,
+		)
+		return err
+	})
+}
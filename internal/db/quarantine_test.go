@@ -0,0 +1,60 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+)
+
+func TestQuarantinedFiles_RoundTrip(t *testing.T) {
+	d := dbtest.OpenTestDB(t)
+	ctx := context.Background()
+
+	older := db.QuarantinedFile{
+		Path:          "/a/old.json",
+		Agent:         "gemini",
+		Error:         "invalid JSON",
+		Attempts:      1,
+		FirstFailedAt: "2024-01-01T00:00:00Z",
+		LastFailedAt:  "2024-01-01T00:00:00Z",
+		NextRetryAt:   "2024-01-01T00:01:00Z",
+	}
+	newer := older
+	newer.Path = "/a/new.json"
+	newer.LastFailedAt = "2024-01-02T00:00:00Z"
+	for _, f := range []db.QuarantinedFile{older, newer} {
+		if err := d.UpsertQuarantinedFile(f); err != nil {
+			t.Fatalf("UpsertQuarantinedFile: %v", err)
+		}
+	}
+
+	// Upsert replaces the existing record.
+	older.Attempts = 2
+	if err := d.UpsertQuarantinedFile(older); err != nil {
+		t.Fatalf("UpsertQuarantinedFile: %v", err)
+	}
+
+	files, err := d.ListQuarantinedFiles(ctx)
+	if err != nil {
+		t.Fatalf("ListQuarantinedFiles: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	if files[0] != newer || files[1] != older {
+		t.Errorf("files = %+v, want newest first", files)
+	}
+
+	if err := d.DeleteQuarantinedFile(newer.Path); err != nil {
+		t.Fatalf("DeleteQuarantinedFile: %v", err)
+	}
+	loaded, err := d.LoadQuarantine()
+	if err != nil {
+		t.Fatalf("LoadQuarantine: %v", err)
+	}
+	if len(loaded) != 1 || loaded[older.Path] != older {
+		t.Errorf("loaded = %+v, want only %s", loaded, older.Path)
+	}
+}
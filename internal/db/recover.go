@@ -0,0 +1,305 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// recoverChunkRows is how many rowids salvage copies per
+// statement before falling back to row-by-row copying.
+const recoverChunkRows = 1000
+
+// RecoveryReport describes an automatic recovery of a corrupted
+// database: where the damaged file was moved and how many rows
+// of each table could be salvaged.
+type RecoveryReport struct {
+	CorruptPath string          `json:"corrupt_path"`
+	Tables      []TableRecovery `json:"tables"`
+	// Error is set when salvage stopped early, e.g. because
+	// the schema itself was unreadable.
+	Error string `json:"error,omitempty"`
+}
+
+// TableRecovery is the salvage result for one table. Lost
+// counts rows that existed but could not be read; Unreadable
+// means the table could not be scanned at all, so its losses
+// are unknown.
+type TableRecovery struct {
+	Table      string `json:"table"`
+	Recovered  int64  `json:"recovered"`
+	Lost       int64  `json:"lost"`
+	Unreadable bool   `json:"unreadable,omitempty"`
+}
+
+// Summary returns a one-line description of the recovery.
+func (r *RecoveryReport) Summary() string {
+	var recovered, lost int64
+	var unreadable []string
+	for _, t := range r.Tables {
+		recovered += t.Recovered
+		lost += t.Lost
+		if t.Unreadable {
+			unreadable = append(unreadable, t.Table)
+		}
+	}
+	s := fmt.Sprintf(
+		"recovered %d rows, %d unreadable rows lost",
+		recovered, lost,
+	)
+	if len(unreadable) > 0 {
+		s += "; unreadable tables: " + strings.Join(unreadable, ", ")
+	}
+	if r.Error != "" {
+		s += "; salvage stopped: " + r.Error
+	}
+	return s + "; damaged file kept at " + r.CorruptPath
+}
+
+// Recovery returns the report when Open had to recover the
+// database from corruption, or nil.
+func (db *DB) Recovery() *RecoveryReport {
+	return db.recovery
+}
+
+// isCorruptErr reports whether err is SQLite reporting a
+// malformed database or a file that is not a database.
+func isCorruptErr(err error) bool {
+	var se sqlite3.Error
+	if errors.As(err, &se) {
+		return se.Code == sqlite3.ErrCorrupt ||
+			se.Code == sqlite3.ErrNotADB
+	}
+	return false
+}
+
+// recoverDatabase moves the corrupted database at path aside
+// and rebuilds path from whatever rows can still be read. The
+// rebuilt file keeps user_version 0, so Open marks it stale and
+// the caller's full resync restores data from source files.
+func recoverDatabase(path string) (*RecoveryReport, error) {
+	aside := path + ".corrupt-" +
+		time.Now().UTC().Format("20060102T150405")
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(path+suffix, aside+suffix); err != nil &&
+			!os.IsNotExist(err) {
+			return nil, fmt.Errorf(
+				"moving corrupt database aside: %w", err,
+			)
+		}
+	}
+
+	tmp := path + ".recover"
+	if err := dropDatabase(tmp); err != nil {
+		return nil, err
+	}
+	d, err := openAndInit(tmp)
+	if err != nil {
+		return nil, fmt.Errorf("creating recovery database: %w", err)
+	}
+	rep := &RecoveryReport{CorruptPath: aside}
+	if err := d.salvageFrom(aside, rep); err != nil {
+		rep.Error = err.Error()
+	}
+	if err := d.Close(); err != nil {
+		return nil, fmt.Errorf("closing recovery database: %w", err)
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Rename(tmp+suffix, path+suffix); err != nil &&
+			!os.IsNotExist(err) {
+			return nil, fmt.Errorf(
+				"installing recovered database: %w", err,
+			)
+		}
+	}
+	return rep, nil
+}
+
+// salvageFrom copies every readable row of each regular table
+// from the database at sourcePath. Derived tables (stats, the
+// FTS indexes) are skipped; triggers rebuild them as rows land.
+func (db *DB) salvageFrom(
+	sourcePath string, rep *RecoveryReport,
+) error {
+	return db.write(func() error {
+		// ATTACH is connection-scoped; pin one connection.
+		ctx := context.Background()
+		conn, err := db.getWriter().Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("acquiring connection: %w", err)
+		}
+		defer conn.Close()
+
+		if _, err := conn.ExecContext(
+			ctx, "ATTACH DATABASE ? AS old_db", sourcePath,
+		); err != nil {
+			return fmt.Errorf("attaching corrupt db: %w", err)
+		}
+		defer func() {
+			_, _ = conn.ExecContext(ctx, "DETACH DATABASE old_db")
+		}()
+
+		tables, err := queryStrings(ctx, conn, `
+			SELECT name FROM main.sqlite_master
+			WHERE type = 'table'
+				AND name NOT LIKE 'sqlite_%'
+				AND name NOT LIKE 'messages_fts%'
+				AND name NOT LIKE 'session_notes_fts%'
+				AND name != 'stats'
+				AND sql NOT LIKE 'CREATE VIRTUAL%'
+			ORDER BY rowid`)
+		if err != nil {
+			return fmt.Errorf("listing tables: %w", err)
+		}
+		for _, table := range tables {
+			cols, err := sharedColumns(ctx, conn, table)
+			if err != nil {
+				return fmt.Errorf("reading %s columns: %w", table, err)
+			}
+			if len(cols) == 0 {
+				continue
+			}
+			t := salvageTable(ctx, conn, table, cols)
+			rep.Tables = append(rep.Tables, t)
+		}
+		return nil
+	})
+}
+
+// sharedColumns returns the columns table has in both the new
+// and the attached database, or nil when the old one lacks it.
+func sharedColumns(
+	ctx context.Context, conn *sql.Conn, table string,
+) ([]string, error) {
+	newCols, err := queryStrings(ctx, conn,
+		"SELECT name FROM pragma_table_info(?, 'main')", table)
+	if err != nil {
+		return nil, err
+	}
+	oldCols, err := queryStrings(ctx, conn,
+		"SELECT name FROM pragma_table_info(?, 'old_db')", table)
+	if err != nil {
+		return nil, err
+	}
+	old := make(map[string]bool, len(oldCols))
+	for _, c := range oldCols {
+		old[c] = true
+	}
+	var cols []string
+	for _, c := range newCols {
+		if old[c] {
+			cols = append(cols, c)
+		}
+	}
+	return cols, nil
+}
+
+// salvageTable copies a table in one statement, falling back to
+// rowid chunks and then single rows around damaged pages.
+func salvageTable(
+	ctx context.Context, conn *sql.Conn, table string, cols []string,
+) TableRecovery {
+	t := TableRecovery{Table: table}
+	colList := strings.Join(cols, ", ")
+	copyRows := func(where string, args ...any) (int64, error) {
+		res, err := conn.ExecContext(ctx,
+			"INSERT OR IGNORE INTO main."+table+" ("+colList+")"+
+				" SELECT "+colList+" FROM old_db."+table+
+				" WHERE "+where,
+			args...,
+		)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	if n, err := copyRows("1"); err == nil {
+		t.Recovered = n
+		return t
+	}
+
+	maxID, ok := maxRowid(ctx, conn, table)
+	if !ok {
+		t.Unreadable = true
+		return t
+	}
+	for lo := int64(0); lo < maxID; lo += recoverChunkRows {
+		hi := lo + recoverChunkRows
+		n, err := copyRows("rowid > ? AND rowid <= ?", lo, hi)
+		if err == nil {
+			t.Recovered += n
+			continue
+		}
+		for id := lo + 1; id <= hi; id++ {
+			n, err := copyRows("rowid = ?", id)
+			if err != nil {
+				t.Lost++
+				continue
+			}
+			t.Recovered += n
+		}
+	}
+	if t.Lost > 0 {
+		slog.Warn("recovery: rows lost",
+			"table", table, "lost", t.Lost)
+	}
+	return t
+}
+
+// maxRowid finds the table's largest rowid. When the table's
+// rightmost page is damaged, it reads the rowid from any intact
+// index instead.
+func maxRowid(
+	ctx context.Context, conn *sql.Conn, table string,
+) (int64, bool) {
+	var id sql.NullInt64
+	err := conn.QueryRowContext(ctx,
+		"SELECT max(rowid) FROM old_db."+table,
+	).Scan(&id)
+	if err == nil {
+		return id.Int64, true
+	}
+	indexes, err := queryStrings(ctx, conn, `
+		SELECT name FROM old_db.sqlite_master
+		WHERE type = 'index' AND tbl_name = ?`, table)
+	if err != nil {
+		return 0, false
+	}
+	for _, idx := range indexes {
+		err := conn.QueryRowContext(ctx,
+			"SELECT max(rowid) FROM old_db."+table+
+				" INDEXED BY "+idx,
+		).Scan(&id)
+		if err == nil {
+			return id.Int64, true
+		}
+	}
+	return 0, false
+}
+
+func queryStrings(
+	ctx context.Context, conn *sql.Conn, query string, args ...any,
+) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
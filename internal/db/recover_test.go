@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpen_RecoversUnreadableFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sessions.db")
+	garbage := []byte(strings.Repeat("not a database ", 512))
+	if err := os.WriteFile(path, garbage, 0o644); err != nil {
+		t.Fatalf("writing garbage: %v", err)
+	}
+
+	d, err := Open(path)
+	requireNoError(t, err, "Open")
+	defer d.Close()
+
+	rep := d.Recovery()
+	if rep == nil {
+		t.Fatal("expected a recovery report")
+	}
+	if rep.Error == "" {
+		t.Error("expected salvage error for an unreadable file")
+	}
+	if !d.NeedsResync() {
+		t.Error("recovered database should need a resync")
+	}
+	got, err := os.ReadFile(rep.CorruptPath)
+	requireNoError(t, err, "reading corrupt copy")
+	if string(got) != string(garbage) {
+		t.Error("corrupt file was not preserved")
+	}
+
+	// The rebuilt database is usable.
+	insertSession(t, d, "s1", "proj")
+	sess, err := d.GetSession(context.Background(), "s1")
+	requireNoError(t, err, "GetSession")
+	if sess == nil {
+		t.Error("session not found in rebuilt database")
+	}
+}
+
+func TestRecoverDatabase_SalvagesRows(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sessions.db")
+	d, err := Open(path)
+	requireNoError(t, err, "Open")
+	insertSession(t, d, "s1", "proj")
+	insertSession(t, d, "s2", "proj")
+	insertMessages(t, d,
+		userMsg("s1", 0, "hello"),
+		asstMsg("s1", 1, "hi"),
+	)
+	_, err = d.InsertInsight(Insight{
+		Type: "daily_activity", DateFrom: "2024-06-01",
+		DateTo: "2024-06-01", Agent: "claude", Content: "notes",
+	})
+	requireNoError(t, err, "InsertInsight")
+	requireNoError(t, d.Close(), "Close")
+
+	rep, err := recoverDatabase(path)
+	requireNoError(t, err, "recoverDatabase")
+	assertEq(t, "Error", rep.Error, "")
+
+	byTable := map[string]TableRecovery{}
+	for _, tr := range rep.Tables {
+		byTable[tr.Table] = tr
+	}
+	assertEq(t, "sessions", byTable["sessions"].Recovered, int64(2))
+	assertEq(t, "messages", byTable["messages"].Recovered, int64(2))
+	assertEq(t, "insights", byTable["insights"].Recovered, int64(1))
+	if _, ok := byTable["stats"]; ok {
+		t.Error("derived stats table should not be copied")
+	}
+
+	r, err := Open(path)
+	requireNoError(t, err, "reopen")
+	defer r.Close()
+	if !r.NeedsResync() {
+		t.Error("recovered database should need a resync")
+	}
+	// Triggers rebuilt the derived stats table.
+	var count int
+	requireNoError(t, r.getReader().QueryRow(
+		"SELECT value FROM stats WHERE key = 'message_count'",
+	).Scan(&count), "read stats")
+	assertEq(t, "message_count", count, 2)
+
+	page, err := r.Search(context.Background(),
+		SearchFilter{Query: "hello", Limit: 10})
+	requireNoError(t, err, "Search")
+	assertEq(t, "search results", len(page.Results), 1)
+}
@@ -0,0 +1,152 @@
+package db
+
+import "fmt"
+
+// Kinds of resync_checkpoint rows.
+const (
+	checkpointFile = "file"
+	checkpointSkip = "skip"
+	checkpointDir  = "dir"
+)
+
+// ResyncCheckpoint is the progress of a full resync recorded in
+// the database it is building.
+type ResyncCheckpoint struct {
+	// Files maps source files whose sessions were written to
+	// the mtime they were parsed at.
+	Files map[string]int64
+	// Skipped maps source files that produced no session to
+	// the mtime they were parsed at.
+	Skipped map[string]int64
+	// Dirs holds agent directories whose files are all done.
+	Dirs map[string]bool
+}
+
+// Empty reports whether nothing has been checkpointed.
+func (c ResyncCheckpoint) Empty() bool {
+	return len(c.Files) == 0 && len(c.Skipped) == 0 &&
+		len(c.Dirs) == 0
+}
+
+// LoadResyncCheckpoint returns the recorded resync progress.
+func (db *DB) LoadResyncCheckpoint() (ResyncCheckpoint, error) {
+	c := ResyncCheckpoint{
+		Files:   map[string]int64{},
+		Skipped: map[string]int64{},
+		Dirs:    map[string]bool{},
+	}
+	rows, err := db.getReader().Query(
+		"SELECT path, kind, file_mtime FROM resync_checkpoint",
+	)
+	if err != nil {
+		return c, fmt.Errorf("loading resync checkpoint: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var path, kind string
+		var mtime int64
+		if err := rows.Scan(&path, &kind, &mtime); err != nil {
+			return c, fmt.Errorf(
+				"scanning resync checkpoint: %w", err,
+			)
+		}
+		switch kind {
+		case checkpointFile:
+			c.Files[path] = mtime
+		case checkpointSkip:
+			c.Skipped[path] = mtime
+		case checkpointDir:
+			c.Dirs[path] = true
+		}
+	}
+	return c, rows.Err()
+}
+
+// SaveResyncCheckpoint adds files and directories finished
+// since the last save.
+func (db *DB) SaveResyncCheckpoint(c ResyncCheckpoint) error {
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		stmt, err := tx.Prepare(`
+			INSERT OR REPLACE INTO resync_checkpoint
+				(path, kind, file_mtime)
+			VALUES (?, ?, ?)`)
+		if err != nil {
+			return fmt.Errorf("prepare: %w", err)
+		}
+		defer stmt.Close()
+
+		for path, mtime := range c.Files {
+			if _, err := stmt.Exec(
+				path, checkpointFile, mtime,
+			); err != nil {
+				return fmt.Errorf("checkpointing file: %w", err)
+			}
+		}
+		for path, mtime := range c.Skipped {
+			if _, err := stmt.Exec(
+				path, checkpointSkip, mtime,
+			); err != nil {
+				return fmt.Errorf("checkpointing file: %w", err)
+			}
+		}
+		for dir := range c.Dirs {
+			if _, err := stmt.Exec(
+				dir, checkpointDir, 0,
+			); err != nil {
+				return fmt.Errorf("checkpointing dir: %w", err)
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// ClearResyncCheckpoint removes all recorded resync progress.
+func (db *DB) ClearResyncCheckpoint() error {
+	return db.write(func() error {
+		_, err := db.getWriter().Exec(
+			"DELETE FROM resync_checkpoint",
+		)
+		return err
+	})
+}
+
+// DeleteUncheckpointedSessions removes sessions whose source
+// file is not checkpointed: an interrupted resync may have
+// written them only in part. Resuming parses those files
+// again. Returns the number of sessions removed.
+func (db *DB) DeleteUncheckpointedSessions() (int, error) {
+	rows, err := db.getReader().Query(`
+		SELECT id FROM sessions
+		WHERE COALESCE(file_path, '') NOT IN (
+			SELECT path FROM resync_checkpoint
+			WHERE kind = 'file'
+		)`)
+	if err != nil {
+		return 0, fmt.Errorf(
+			"querying uncheckpointed sessions: %w", err,
+		)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf(
+				"scanning uncheckpointed session: %w", err,
+			)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	return db.DeleteSessions(ids)
+}
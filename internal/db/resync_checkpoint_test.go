@@ -0,0 +1,55 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResyncCheckpoint(t *testing.T) {
+	d := testDB(t)
+
+	cp, err := d.LoadResyncCheckpoint()
+	requireNoError(t, err, "LoadResyncCheckpoint")
+	if !cp.Empty() {
+		t.Fatalf("new db checkpoint = %+v, want empty", cp)
+	}
+
+	requireNoError(t, d.SaveResyncCheckpoint(ResyncCheckpoint{
+		Files:   map[string]int64{"/a.jsonl": 1},
+		Skipped: map[string]int64{"/b.jsonl": 2},
+	}), "SaveResyncCheckpoint")
+	requireNoError(t, d.SaveResyncCheckpoint(ResyncCheckpoint{
+		Files: map[string]int64{"/a.jsonl": 3},
+		Dirs:  map[string]bool{"/projects": true},
+	}), "SaveResyncCheckpoint again")
+
+	cp, err = d.LoadResyncCheckpoint()
+	requireNoError(t, err, "LoadResyncCheckpoint")
+	want := ResyncCheckpoint{
+		Files:   map[string]int64{"/a.jsonl": 3},
+		Skipped: map[string]int64{"/b.jsonl": 2},
+		Dirs:    map[string]bool{"/projects": true},
+	}
+	if !reflect.DeepEqual(cp, want) {
+		t.Errorf("checkpoint = %+v, want %+v", cp, want)
+	}
+
+	insertSession(t, d, "kept", "p", func(s *Session) {
+		s.FilePath = Ptr("/a.jsonl")
+	})
+	insertSession(t, d, "partial", "p", func(s *Session) {
+		s.FilePath = Ptr("/c.jsonl")
+	})
+	insertSession(t, d, "virtual", "p")
+	n, err := d.DeleteUncheckpointedSessions()
+	requireNoError(t, err, "DeleteUncheckpointedSessions")
+	assertEq(t, "deleted", n, 2)
+	requireSessionExists(t, d, "kept")
+
+	requireNoError(t, d.ClearResyncCheckpoint(), "ClearResyncCheckpoint")
+	cp, err = d.LoadResyncCheckpoint()
+	requireNoError(t, err, "LoadResyncCheckpoint after clear")
+	if !cp.Empty() {
+		t.Errorf("checkpoint after clear = %+v, want empty", cp)
+	}
+}
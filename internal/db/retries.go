@@ -0,0 +1,322 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/wesm/agentsview/internal/pricing"
+)
+
+// Bounds for GetAnalyticsRetries, in minutes.
+const (
+	DefaultRetryWindow = 10
+	MinRetryWindow     = 1
+	MaxRetryWindow     = 240
+
+	// maxRetrySessions caps the retries listed individually.
+	maxRetrySessions = 50
+)
+
+// RetrySession is a session that started soon after a failed
+// session in the same project ended.
+type RetrySession struct {
+	SessionID  string  `json:"session_id"`
+	PreviousID string  `json:"previous_id"`
+	Project    string  `json:"project"`
+	Agent      string  `json:"agent"`
+	StartedAt  string  `json:"started_at"`
+	GapMinutes float64 `json:"gap_minutes"`
+	// Reason the previous session counts as failed:
+	// "interrupts", "tool_errors", or both joined by "+".
+	Reason string `json:"reason"`
+}
+
+// RetryGroup counts retry sessions for one project and agent.
+// Cost is the estimated cost of the retry sessions, in the
+// project's billing currency.
+type RetryGroup struct {
+	Project        string  `json:"project"`
+	Agent          string  `json:"agent"`
+	Sessions       int     `json:"sessions"`
+	FailedSessions int     `json:"failed_sessions"`
+	RetrySessions  int     `json:"retry_sessions"`
+	RetryRate      float64 `json:"retry_rate"` // % of sessions
+	Cost           float64 `json:"cost"`
+	Currency       string  `json:"currency"`
+}
+
+// RetriesResponse wraps retry session analytics. Groups are
+// ordered by retry count; Recent lists the newest retries.
+type RetriesResponse struct {
+	WindowMinutes  int            `json:"window_minutes"`
+	Sessions       int            `json:"sessions"`
+	FailedSessions int            `json:"failed_sessions"`
+	RetrySessions  int            `json:"retry_sessions"`
+	Groups         []RetryGroup   `json:"groups"`
+	Recent         []RetrySession `json:"recent"`
+}
+
+type retryCandidate struct {
+	id, project, agent  string
+	start, end          time.Time
+	interrupts          int
+	toolErrors, inRange bool
+}
+
+func (c retryCandidate) failReason() string {
+	switch {
+	case c.interrupts > 0 && c.toolErrors:
+		return "interrupts+tool_errors"
+	case c.interrupts > 0:
+		return "interrupts"
+	case c.toolErrors:
+		return "tool_errors"
+	}
+	return ""
+}
+
+// GetAnalyticsRetries finds sessions that started within
+// window minutes of the end of a failed session in the same
+// project, a sign the agent keeps failing at the same task. A
+// session fails when the user interrupted it or a tool call
+// returned an error. A window of zero uses DefaultRetryWindow.
+func (db *DB) GetAnalyticsRetries(
+	ctx context.Context, f AnalyticsFilter, window int,
+) (RetriesResponse, error) {
+	if window <= 0 {
+		window = DefaultRetryWindow
+	}
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+
+	// Reach back a day so a retry early in the range can find
+	// the failed session it follows.
+	qf := f
+	if from, err := time.Parse("2006-01-02", f.From); err == nil {
+		qf.From = from.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	where, args := qf.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return RetriesResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, project, agent, started_at,
+			COALESCE(NULLIF(ended_at, ''), started_at),
+			interrupt_count
+		FROM sessions
+		WHERE `+where+`
+			AND started_at IS NOT NULL AND started_at != ''`,
+		args...,
+	)
+	if err != nil {
+		return RetriesResponse{},
+			fmt.Errorf("querying retry sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var cands []retryCandidate
+	for rows.Next() {
+		var c retryCandidate
+		var ts, started, ended string
+		if err := rows.Scan(
+			&c.id, &ts, &c.project, &c.agent, &started, &ended,
+			&c.interrupts,
+		); err != nil {
+			return RetriesResponse{},
+				fmt.Errorf("scanning retry session: %w", err)
+		}
+		if c.start, err = time.Parse(time.RFC3339Nano, started); err != nil {
+			continue
+		}
+		if c.end, err = time.Parse(time.RFC3339Nano, ended); err != nil {
+			c.end = c.start
+		}
+		c.inRange = inDateRange(localDate(ts, loc), f.From, f.To) &&
+			(timeIDs == nil || timeIDs[c.id])
+		cands = append(cands, c)
+	}
+	if err := rows.Err(); err != nil {
+		return RetriesResponse{},
+			fmt.Errorf("iterating retry sessions: %w", err)
+	}
+	ids := make([]string, len(cands))
+	for i, c := range cands {
+		ids[i] = c.id
+	}
+	toolErrors := map[string]bool{}
+	err = queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		errRows, err := db.getReader().QueryContext(ctx,
+			`SELECT DISTINCT session_id FROM tool_calls
+			WHERE session_id IN `+ph+` AND result_error = 1`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying tool errors: %w", err)
+		}
+		defer errRows.Close()
+		for errRows.Next() {
+			var id string
+			if err := errRows.Scan(&id); err != nil {
+				return fmt.Errorf("scanning tool error: %w", err)
+			}
+			toolErrors[id] = true
+		}
+		return errRows.Err()
+	})
+	if err != nil {
+		return RetriesResponse{}, err
+	}
+	for i := range cands {
+		cands[i].toolErrors = toolErrors[cands[i].id]
+	}
+
+	sort.Slice(cands, func(i, j int) bool {
+		a, b := cands[i], cands[j]
+		if a.project != b.project {
+			return a.project < b.project
+		}
+		if !a.start.Equal(b.start) {
+			return a.start.Before(b.start)
+		}
+		return a.id < b.id
+	})
+
+	resp := RetriesResponse{
+		WindowMinutes: window,
+		Groups:        []RetryGroup{},
+		Recent:        []RetrySession{},
+	}
+	type groupKey struct{ project, agent string }
+	groups := map[groupKey]*RetryGroup{}
+	var retryIDs []string
+	limit := time.Duration(window) * time.Minute
+	for i, c := range cands {
+		if !c.inRange {
+			continue
+		}
+		key := groupKey{c.project, c.agent}
+		g := groups[key]
+		if g == nil {
+			g = &RetryGroup{Project: c.project, Agent: c.agent}
+			groups[key] = g
+		}
+		g.Sessions++
+		resp.Sessions++
+		if c.failReason() != "" {
+			g.FailedSessions++
+			resp.FailedSessions++
+		}
+
+		if i == 0 || cands[i-1].project != c.project {
+			continue
+		}
+		prev := cands[i-1]
+		gap := c.start.Sub(prev.end)
+		if prev.failReason() == "" || gap < 0 || gap > limit {
+			continue
+		}
+		g.RetrySessions++
+		resp.RetrySessions++
+		retryIDs = append(retryIDs, c.id)
+		resp.Recent = append(resp.Recent, RetrySession{
+			SessionID:  c.id,
+			PreviousID: prev.id,
+			Project:    c.project,
+			Agent:      c.agent,
+			StartedAt:  c.start.UTC().Format(time.RFC3339),
+			GapMinutes: round1(gap.Minutes()),
+			Reason:     prev.failReason(),
+		})
+	}
+
+	costs, err := db.sessionCosts(ctx, retryIDs)
+	if err != nil {
+		return RetriesResponse{}, err
+	}
+	for _, r := range resp.Recent {
+		g := groups[groupKey{r.Project, r.Agent}]
+		g.Cost += costs[r.SessionID]
+	}
+
+	for _, g := range groups {
+		if g.RetrySessions == 0 {
+			continue
+		}
+		rate := f.costRate(g.Project)
+		g.Cost = roundCost(g.Cost * rate.Multiplier)
+		g.Currency = rate.Currency
+		g.RetryRate = round1(
+			float64(g.RetrySessions) / float64(g.Sessions) * 100,
+		)
+		resp.Groups = append(resp.Groups, *g)
+	}
+	sort.Slice(resp.Groups, func(i, j int) bool {
+		a, b := resp.Groups[i], resp.Groups[j]
+		if a.RetrySessions != b.RetrySessions {
+			return a.RetrySessions > b.RetrySessions
+		}
+		if a.Project != b.Project {
+			return a.Project < b.Project
+		}
+		return a.Agent < b.Agent
+	})
+
+	sort.Slice(resp.Recent, func(i, j int) bool {
+		a, b := resp.Recent[i], resp.Recent[j]
+		if a.StartedAt != b.StartedAt {
+			return a.StartedAt > b.StartedAt
+		}
+		return a.SessionID < b.SessionID
+	})
+	if len(resp.Recent) > maxRetrySessions {
+		resp.Recent = resp.Recent[:maxRetrySessions]
+	}
+	return resp, nil
+}
+
+// sessionCosts estimates each session's cost in BaseCurrency
+// from per-message token usage.
+func (db *DB) sessionCosts(
+	ctx context.Context, ids []string,
+) (map[string]float64, error) {
+	costs := map[string]float64{}
+	err := queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, model, input_tokens,
+				output_tokens, cache_read_tokens,
+				cache_creation_tokens
+			FROM messages
+			WHERE session_id IN `+ph+`
+				AND model != ''`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying session costs: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sid, model string
+			var u pricing.Usage
+			if err := rows.Scan(
+				&sid, &model, &u.Input, &u.Output,
+				&u.CacheRead, &u.CacheWrite,
+			); err != nil {
+				return fmt.Errorf("scanning session cost: %w", err)
+			}
+			costs[sid] += pricing.Cost(model, u)
+		}
+		return rows.Err()
+	})
+	return costs, err
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetAnalyticsRetries(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	for _, s := range []struct {
+		id, project, agent, start, end string
+		interrupts                     int
+	}{
+		// alpha: a fails, b retries 5 min later and fails
+		// too, c starts too late, d follows a clean session.
+		{"a", "alpha", "claude", "2024-06-03T09:00:00Z", "2024-06-03T09:30:00Z", 1},
+		{"b", "alpha", "claude", "2024-06-03T09:35:00Z", "2024-06-03T09:50:00Z", 0},
+		{"c", "alpha", "codex", "2024-06-03T10:30:00Z", "2024-06-03T10:40:00Z", 0},
+		{"d", "alpha", "claude", "2024-06-03T10:42:00Z", "2024-06-03T11:00:00Z", 0},
+		// beta: a retry of a session from the day before the
+		// range.
+		{"e", "beta", "codex", "2024-05-31T23:50:00Z", "2024-05-31T23:58:00Z", 2},
+		{"f", "beta", "codex", "2024-06-01T00:03:00Z", "2024-06-01T00:20:00Z", 0},
+	} {
+		insertSession(t, d, s.id, s.project, func(sess *Session) {
+			sess.Agent = s.agent
+			sess.StartedAt = Ptr(s.start)
+			sess.EndedAt = Ptr(s.end)
+			sess.InterruptCount = s.interrupts
+		})
+	}
+	failed := toolMsg("b", 0, "2024-06-03T09:40:00Z", ToolCall{
+		ToolName: "Bash", Category: "Bash", ResultError: true,
+	})
+	failed.Model = "claude-sonnet-4"
+	failed.OutputTokens = 1_000_000
+	insertMessages(t, d, failed)
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsRetries(ctx, f, 0)
+	requireNoError(t, err, "GetAnalyticsRetries")
+	assertEq(t, "WindowMinutes", resp.WindowMinutes, DefaultRetryWindow)
+	assertEq(t, "Sessions", resp.Sessions, 5)
+	assertEq(t, "FailedSessions", resp.FailedSessions, 2)
+	assertEq(t, "RetrySessions", resp.RetrySessions, 2)
+
+	if len(resp.Groups) != 2 {
+		t.Fatalf("Groups = %+v, want 2", resp.Groups)
+	}
+	assertEq(t, "alpha/claude", resp.Groups[0], RetryGroup{
+		Project: "alpha", Agent: "claude", Sessions: 3,
+		FailedSessions: 2, RetrySessions: 1, RetryRate: 33.3,
+		Cost: 15, Currency: BaseCurrency,
+	})
+	assertEq(t, "beta/codex", resp.Groups[1], RetryGroup{
+		Project: "beta", Agent: "codex", Sessions: 1,
+		RetrySessions: 1, RetryRate: 100, Currency: BaseCurrency,
+	})
+
+	if len(resp.Recent) != 2 {
+		t.Fatalf("Recent = %+v, want 2", resp.Recent)
+	}
+	assertEq(t, "newest", resp.Recent[0], RetrySession{
+		SessionID: "b", PreviousID: "a", Project: "alpha",
+		Agent: "claude", StartedAt: "2024-06-03T09:35:00Z",
+		GapMinutes: 5, Reason: "interrupts",
+	})
+	assertEq(t, "oldest reason", resp.Recent[1].Reason, "interrupts")
+	assertEq(t, "oldest gap", resp.Recent[1].GapMinutes, 5.0)
+
+	// A wider window picks up c, which followed failed b.
+	resp, err = d.GetAnalyticsRetries(ctx, f, 60)
+	requireNoError(t, err, "GetAnalyticsRetries 60")
+	assertEq(t, "RetrySessions 60", resp.RetrySessions, 3)
+	for _, r := range resp.Recent {
+		if r.SessionID == "c" && r.Reason != "tool_errors" {
+			t.Errorf("c reason = %q, want tool_errors", r.Reason)
+		}
+	}
+}
@@ -9,6 +9,14 @@ CREATE TABLE IF NOT EXISTS sessions (
     ended_at    TEXT,
     message_count INTEGER NOT NULL DEFAULT 0,
     user_message_count INTEGER NOT NULL DEFAULT 0,
+    interrupt_count INTEGER NOT NULL DEFAULT 0,
+    headless        INTEGER NOT NULL DEFAULT 0,
+    git_branch      TEXT NOT NULL DEFAULT '',
+    worktree        TEXT NOT NULL DEFAULT '',
+    entry_point     TEXT NOT NULL DEFAULT '',
+    client          TEXT NOT NULL DEFAULT '',
+    quality_score   INTEGER,
+    language        TEXT NOT NULL DEFAULT '',
     file_path   TEXT,
     file_size   INTEGER,
     file_mtime  INTEGER,
@@ -29,6 +37,17 @@ CREATE TABLE IF NOT EXISTS messages (
     has_thinking   INTEGER NOT NULL DEFAULT 0,
     has_tool_use   INTEGER NOT NULL DEFAULT 0,
     content_length INTEGER NOT NULL DEFAULT 0,
+    model          TEXT NOT NULL DEFAULT '',
+    model_switch   TEXT NOT NULL DEFAULT '',
+    kind           TEXT NOT NULL DEFAULT '',
+    input_tokens   INTEGER NOT NULL DEFAULT 0,
+    output_tokens  INTEGER NOT NULL DEFAULT 0,
+    cache_read_tokens     INTEGER NOT NULL DEFAULT 0,
+    cache_creation_tokens INTEGER NOT NULL DEFAULT 0,
+    reasoning_tokens      INTEGER NOT NULL DEFAULT 0,
+    source_uuid    TEXT NOT NULL DEFAULT '',
+    pasted_chars   INTEGER NOT NULL DEFAULT 0,
+    language       TEXT NOT NULL DEFAULT '',
     UNIQUE(session_id, ordinal)
 );
 
@@ -88,6 +107,53 @@ CREATE INDEX IF NOT EXISTS idx_sessions_user_message_count
 CREATE INDEX IF NOT EXISTS idx_sessions_agent
     ON sessions(agent);
 
+-- Session list indexes. The key expression must match the
+-- ORDER BY in ListSessions exactly so SQLite can walk the index
+-- in recency order instead of sorting the filtered rows. The
+-- partial predicate mirrors the fixed list filters.
+CREATE INDEX IF NOT EXISTS idx_sessions_list_recency
+    ON sessions(
+        COALESCE(NULLIF(ended_at, ''), NULLIF(started_at, ''), created_at) DESC,
+        id DESC
+    )
+    WHERE message_count > 0
+        AND relationship_type NOT IN ('subagent', 'fork');
+CREATE INDEX IF NOT EXISTS idx_sessions_list_project
+    ON sessions(
+        project,
+        COALESCE(NULLIF(ended_at, ''), NULLIF(started_at, ''), created_at) DESC,
+        id DESC
+    )
+    WHERE message_count > 0
+        AND relationship_type NOT IN ('subagent', 'fork');
+CREATE INDEX IF NOT EXISTS idx_sessions_list_agent
+    ON sessions(
+        agent,
+        COALESCE(NULLIF(ended_at, ''), NULLIF(started_at, ''), created_at) DESC,
+        id DESC
+    )
+    WHERE message_count > 0
+        AND relationship_type NOT IN ('subagent', 'fork');
+CREATE INDEX IF NOT EXISTS idx_sessions_list_machine
+    ON sessions(
+        machine,
+        COALESCE(NULLIF(ended_at, ''), NULLIF(started_at, ''), created_at) DESC,
+        id DESC
+    )
+    WHERE message_count > 0
+        AND relationship_type NOT IN ('subagent', 'fork');
+
+-- Covering index for the list filter columns so the total COUNT(*)
+-- in ListSessions is answered without touching table rows.
+CREATE INDEX IF NOT EXISTS idx_sessions_list_covering
+    ON sessions(
+        project, agent, machine, message_count,
+        user_message_count, relationship_type,
+        started_at, ended_at, created_at, id
+    )
+    WHERE message_count > 0
+        AND relationship_type NOT IN ('subagent', 'fork');
+
 -- Tool calls table
 CREATE TABLE IF NOT EXISTS tool_calls (
     id         INTEGER PRIMARY KEY,
@@ -102,7 +168,15 @@ CREATE TABLE IF NOT EXISTS tool_calls (
     skill_name  TEXT,
     result_content_length INTEGER,
     result_content        TEXT,
-    subagent_session_id TEXT
+    subagent_session_id TEXT,
+    file_path   TEXT,
+    result_error INTEGER NOT NULL DEFAULT 0,
+    -- The agent cut the result to fit its size limits; the
+    -- original length is set when the agent reported it.
+    result_truncated INTEGER NOT NULL DEFAULT 0,
+    result_original_length INTEGER,
+    -- Normalized command line of a Bash call, e.g. "go test".
+    command     TEXT
 );
 
 CREATE INDEX IF NOT EXISTS idx_tool_calls_session
@@ -113,6 +187,142 @@ CREATE INDEX IF NOT EXISTS idx_tool_calls_skill
     ON tool_calls(skill_name)
     WHERE skill_name IS NOT NULL;
 
+-- Token usage an agent reported for a message, from Claude and
+-- Codex usage blocks and other agents' per-message counts. Only
+-- messages with usage have a row; it mirrors their token columns
+-- and is what usage and cost analytics read.
+CREATE TABLE IF NOT EXISTS token_usage (
+    message_id INTEGER PRIMARY KEY
+        REFERENCES messages(id) ON DELETE CASCADE,
+    session_id TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    model      TEXT NOT NULL DEFAULT '',
+    input_tokens          INTEGER NOT NULL DEFAULT 0,
+    output_tokens         INTEGER NOT NULL DEFAULT 0,
+    cache_read_tokens     INTEGER NOT NULL DEFAULT 0,
+    cache_creation_tokens INTEGER NOT NULL DEFAULT 0,
+    reasoning_tokens      INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_token_usage_session
+    ON token_usage(session_id);
+
+-- Extra source files merged into one session, such as Codex
+-- rollouts split across restarts. The primary file stays in
+-- sessions.file_path; rows here let sync skip unchanged parts.
+CREATE TABLE IF NOT EXISTS session_file_parts (
+    file_path  TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    file_size  INTEGER,
+    file_mtime INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_file_parts_session
+    ON session_file_parts(session_id);
+
+-- Hook outcomes recorded in Claude Code sessions (PreToolUse,
+-- PostToolUse, Stop, ...). Rebuilt from source files on sync.
+CREATE TABLE IF NOT EXISTS hook_events (
+    id         INTEGER PRIMARY KEY,
+    session_id TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    hook_event TEXT NOT NULL,
+    hook_name  TEXT NOT NULL,
+    tool_name  TEXT NOT NULL DEFAULT '',
+    command    TEXT NOT NULL DEFAULT '',
+    decision   TEXT NOT NULL,
+    message    TEXT NOT NULL DEFAULT '',
+    timestamp  TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_hook_events_session
+    ON hook_events(session_id);
+
+-- Slash command invocations recorded in Claude Code sessions.
+-- Rebuilt from source files on sync.
+CREATE TABLE IF NOT EXISTS session_commands (
+    id         INTEGER PRIMARY KEY,
+    session_id TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    timestamp  TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_commands_session
+    ON session_commands(session_id);
+CREATE INDEX IF NOT EXISTS idx_session_commands_name
+    ON session_commands(name);
+
+-- Latest TodoWrite list per Claude Code session, one row per
+-- item in list order. Rebuilt from source files on sync.
+CREATE TABLE IF NOT EXISTS session_todos (
+    id          INTEGER PRIMARY KEY,
+    session_id  TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    position    INTEGER NOT NULL,
+    content     TEXT NOT NULL,
+    status      TEXT NOT NULL,
+    active_form TEXT NOT NULL DEFAULT '',
+    updated_at  TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_todos_session
+    ON session_todos(session_id);
+
+-- Top-level record types a parser did not recognize, counted
+-- per session with the first line seen as a sample. Surfaces
+-- upstream format additions in health. Rebuilt on sync.
+CREATE TABLE IF NOT EXISTS unknown_records (
+    session_id  TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    record_type TEXT NOT NULL,
+    count       INTEGER NOT NULL,
+    sample      TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (session_id, record_type)
+);
+
+-- User-role messages per session by origin: human prompts,
+-- tool results, hook output, and agent-injected notices,
+-- including injected entries left out of the transcript.
+-- Rebuilt on sync.
+CREATE TABLE IF NOT EXISTS user_message_origins (
+    session_id TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    origin     TEXT NOT NULL,
+    count      INTEGER NOT NULL,
+    PRIMARY KEY (session_id, origin)
+);
+
+-- Monthly per-project, per-agent totals for months before the
+-- compaction horizon, by session start month (UTC). Rebuilt by
+-- each compaction.
+CREATE TABLE IF NOT EXISTS monthly_usage (
+    month         TEXT NOT NULL,
+    project       TEXT NOT NULL,
+    agent         TEXT NOT NULL,
+    sessions      INTEGER NOT NULL,
+    messages      INTEGER NOT NULL,
+    user_messages INTEGER NOT NULL,
+    tool_calls    INTEGER NOT NULL,
+    input_tokens  INTEGER NOT NULL,
+    output_tokens INTEGER NOT NULL,
+    PRIMARY KEY (month, project, agent)
+);
+
+-- Allowlisted agent settings per session (client version,
+-- permission mode, thinking, beta flags). Rebuilt on sync.
+CREATE TABLE IF NOT EXISTS session_env (
+    session_id TEXT NOT NULL
+        REFERENCES sessions(id) ON DELETE CASCADE,
+    key        TEXT NOT NULL,
+    value      TEXT NOT NULL,
+    PRIMARY KEY (session_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_env_key_value
+    ON session_env(key, value);
+
 -- Insights table for AI-generated activity insights
 CREATE TABLE IF NOT EXISTS insights (
     id          INTEGER PRIMARY KEY,
@@ -141,3 +351,99 @@ CREATE TABLE IF NOT EXISTS skipped_files (
     file_path  TEXT PRIMARY KEY,
     file_mtime INTEGER NOT NULL
 );
+
+-- Progress of a full resync, kept in the database it builds so
+-- an interrupted resync resumes instead of starting over. kind
+-- is 'file' (sessions written), 'skip' (no session), or 'dir'
+-- (every file in the agent directory done). Cleared before the
+-- resynced database replaces the original.
+CREATE TABLE IF NOT EXISTS resync_checkpoint (
+    path       TEXT PRIMARY KEY,
+    kind       TEXT NOT NULL,
+    file_mtime INTEGER NOT NULL DEFAULT 0
+);
+
+-- Parse-failure quarantine: files whose last parse errored,
+-- retried with exponential backoff instead of on every sync.
+-- Times are RFC 3339 UTC.
+CREATE TABLE IF NOT EXISTS quarantined_files (
+    file_path       TEXT PRIMARY KEY,
+    agent           TEXT NOT NULL,
+    error           TEXT NOT NULL,
+    attempts        INTEGER NOT NULL,
+    first_failed_at TEXT NOT NULL,
+    last_failed_at  TEXT NOT NULL,
+    next_retry_at   TEXT NOT NULL
+);
+
+-- Machine labels. Rows exist only for machines that have been
+-- classified; is_bot marks CI runners and other automated
+-- hosts, which analytics exclude by default.
+CREATE TABLE IF NOT EXISTS machines (
+    name       TEXT PRIMARY KEY,
+    is_bot     INTEGER NOT NULL DEFAULT 0,
+    bot_reason TEXT NOT NULL DEFAULT ''
+);
+
+-- Machine renames and merges. Sessions written under name are
+-- stored under target instead, so a renamed machine stays
+-- renamed when its sessions are re-synced or re-uploaded.
+CREATE TABLE IF NOT EXISTS machine_aliases (
+    name   TEXT PRIMARY KEY,
+    target TEXT NOT NULL
+);
+
+-- User curation of sessions. Keyed by session ID rather than
+-- stored on sessions so labels survive resync, which rebuilds
+-- session rows from source files.
+CREATE TABLE IF NOT EXISTS session_tags (
+    session_id TEXT NOT NULL,
+    tag        TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+    PRIMARY KEY (session_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_tags_tag
+    ON session_tags(tag);
+
+-- Per-session flags. Muted sessions are excluded from
+-- analytics; archived sessions are hidden from the default
+-- session list; pinned sessions are listed first.
+CREATE TABLE IF NOT EXISTS session_flags (
+    session_id TEXT PRIMARY KEY,
+    muted      INTEGER NOT NULL DEFAULT 0,
+    archived   INTEGER NOT NULL DEFAULT 0,
+    pinned     INTEGER NOT NULL DEFAULT 0,
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
+
+-- Free-form Markdown notes on a session, or on one of its
+-- messages when ordinal is set. Keyed by session ID like the
+-- curation tables, so notes survive resync.
+CREATE TABLE IF NOT EXISTS session_notes (
+    id         INTEGER PRIMARY KEY,
+    session_id TEXT NOT NULL,
+    ordinal    INTEGER,
+    body       TEXT NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_notes_session
+    ON session_notes(session_id, ordinal);
+
+-- Frozen analytics reports. data holds each captured analytics
+-- response keyed by endpoint name; rows are never updated, so
+-- later resyncs or parser fixes do not change past numbers.
+CREATE TABLE IF NOT EXISTS analytics_snapshots (
+    id          INTEGER PRIMARY KEY,
+    name        TEXT NOT NULL,
+    filter      TEXT NOT NULL,
+    data        TEXT NOT NULL,
+    created_at  TEXT NOT NULL
+        DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+    imported_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_analytics_snapshots_created
+    ON analytics_snapshots(created_at DESC);
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -14,21 +15,93 @@ const (
 
 // SearchResult holds a message match with session context.
 type SearchResult struct {
-	SessionID string  `json:"session_id"`
-	Project   string  `json:"project"`
-	Ordinal   int     `json:"ordinal"`
-	Role      string  `json:"role"`
-	Timestamp string  `json:"timestamp"`
-	Snippet   string  `json:"snippet"`
-	Rank      float64 `json:"rank"`
+	SessionID  string  `json:"session_id"`
+	Project    string  `json:"project"`
+	Ordinal    int     `json:"ordinal"`
+	SourceUUID string  `json:"source_uuid,omitempty"`
+	Role       string  `json:"role"`
+	Timestamp  string  `json:"timestamp"`
+	Snippet    string  `json:"snippet"`
+	Rank       float64 `json:"rank"`
 }
 
 // SearchFilter specifies search parameters.
 type SearchFilter struct {
-	Query   string
-	Project string
-	Cursor  int // offset for pagination
-	Limit   int
+	Query      string
+	Project    string
+	Agent      string
+	Machine    string
+	Tag        string // sessions carrying this tag
+	Role       string // message role, "user" or "assistant"
+	HasToolUse bool   // only messages that call tools
+	Month      string // YYYY-MM of the message timestamp
+	DateFrom   string // YYYY-MM-DD of the message timestamp (inclusive)
+	DateTo     string // YYYY-MM-DD of the message timestamp (inclusive)
+	SessionID  string // scope to one session, ordered by ordinal
+	Cursor     int    // offset for pagination
+	Limit      int
+}
+
+// searchTimeExpr is the time a hit is faceted and filtered by:
+// the message's timestamp, else its session's start.
+const searchTimeExpr = `COALESCE(NULLIF(m.timestamp, ''),
+	s.started_at, '')`
+
+// searchMonthExpr and searchDateExpr are the YYYY-MM and
+// YYYY-MM-DD of searchTimeExpr.
+const (
+	searchMonthExpr = `substr(` + searchTimeExpr + `, 1, 7)`
+	searchDateExpr  = `substr(` + searchTimeExpr + `, 1, 10)`
+)
+
+// where returns the predicates shared by Search and
+// SearchFacets, over messages_fts joined to messages m and
+// sessions s.
+func (f SearchFilter) where() ([]string, []any) {
+	whereClauses := []string{"messages_fts MATCH ?"}
+	args := []any{f.Query}
+
+	if f.Project != "" {
+		whereClauses = append(whereClauses, "s.project = ?")
+		args = append(args, f.Project)
+	}
+	if f.Agent != "" {
+		whereClauses = append(whereClauses, "s.agent = ?")
+		args = append(args, f.Agent)
+	}
+	if f.Machine != "" {
+		whereClauses = append(whereClauses, "s.machine = ?")
+		args = append(args, f.Machine)
+	}
+	if f.Tag != "" {
+		whereClauses = append(whereClauses, `s.id IN
+			(SELECT session_id FROM session_tags WHERE tag = ?)`)
+		args = append(args, f.Tag)
+	}
+	if f.Role != "" {
+		whereClauses = append(whereClauses, "m.role = ?")
+		args = append(args, f.Role)
+	}
+	if f.HasToolUse {
+		whereClauses = append(whereClauses, "m.has_tool_use = 1")
+	}
+	if f.Month != "" {
+		whereClauses = append(whereClauses, searchMonthExpr+" = ?")
+		args = append(args, f.Month)
+	}
+	if f.DateFrom != "" {
+		whereClauses = append(whereClauses, searchDateExpr+" >= ?")
+		args = append(args, f.DateFrom)
+	}
+	if f.DateTo != "" {
+		whereClauses = append(whereClauses, searchDateExpr+" <= ?")
+		args = append(args, f.DateTo)
+	}
+	if f.SessionID != "" {
+		whereClauses = append(whereClauses, "m.session_id = ?")
+		args = append(args, f.SessionID)
+	}
+	return whereClauses, args
 }
 
 // SearchPage holds paginated search results.
@@ -38,6 +111,9 @@ type SearchPage struct {
 }
 
 // Search performs FTS5 full-text search across messages.
+// Results are ordered by rank, or by ordinal when scoped to a
+// single session so callers can step through matches in
+// transcript order.
 func (db *DB) Search(
 	ctx context.Context, f SearchFilter,
 ) (SearchPage, error) {
@@ -45,17 +121,15 @@ func (db *DB) Search(
 		f.Limit = DefaultSearchLimit
 	}
 
-	whereClauses := []string{"messages_fts MATCH ?"}
-	args := []any{f.Query}
-
-	if f.Project != "" {
-		whereClauses = append(whereClauses, "s.project = ?")
-		args = append(args, f.Project)
+	whereClauses, args := f.where()
+	orderBy := "rank"
+	if f.SessionID != "" {
+		orderBy = "m.ordinal"
 	}
 
 	query := fmt.Sprintf(`
-		SELECT m.session_id, s.project, m.ordinal, m.role,
-			m.timestamp,
+		SELECT m.session_id, s.project, m.ordinal, m.source_uuid,
+			m.role, m.timestamp,
 			snippet(messages_fts, 0, '<mark>', '</mark>',
 				'...', %d) as snippet,
 			rank
@@ -63,10 +137,11 @@ func (db *DB) Search(
 		JOIN messages m ON messages_fts.rowid = m.id
 		JOIN sessions s ON m.session_id = s.id
 		WHERE %s
-		ORDER BY rank
+		ORDER BY %s
 		LIMIT ? OFFSET ?`,
 		snippetTokenLength,
 		strings.Join(whereClauses, " AND "),
+		orderBy,
 	)
 	args = append(args, f.Limit+1, f.Cursor)
 
@@ -80,8 +155,8 @@ func (db *DB) Search(
 	for rows.Next() {
 		var r SearchResult
 		if err := rows.Scan(
-			&r.SessionID, &r.Project, &r.Ordinal, &r.Role,
-			&r.Timestamp, &r.Snippet, &r.Rank,
+			&r.SessionID, &r.Project, &r.Ordinal, &r.SourceUUID,
+			&r.Role, &r.Timestamp, &r.Snippet, &r.Rank,
 		); err != nil {
 			return SearchPage{},
 				fmt.Errorf("scanning result: %w", err)
@@ -99,3 +174,85 @@ func (db *DB) Search(
 	}
 	return page, nil
 }
+
+// SearchFacet is the hit count for one facet value.
+type SearchFacet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// SearchFacets groups a query's hits for drill-down. Projects
+// and agents are ordered by count, months (YYYY-MM) newest
+// first.
+type SearchFacets struct {
+	Total    int           `json:"total"`
+	Projects []SearchFacet `json:"projects"`
+	Agents   []SearchFacet `json:"agents"`
+	Months   []SearchFacet `json:"months"`
+}
+
+// SearchFacets counts the hits for f by project, agent, and
+// month in a single FTS pass. Cursor and Limit are ignored.
+func (db *DB) SearchFacets(
+	ctx context.Context, f SearchFilter,
+) (SearchFacets, error) {
+	whereClauses, args := f.where()
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT s.project, s.agent, `+searchMonthExpr+`, COUNT(*)
+		FROM messages_fts
+		JOIN messages m ON messages_fts.rowid = m.id
+		JOIN sessions s ON m.session_id = s.id
+		WHERE `+strings.Join(whereClauses, " AND ")+`
+		GROUP BY 1, 2, 3`,
+		args...,
+	)
+	if err != nil {
+		return SearchFacets{}, fmt.Errorf("searching facets: %w", err)
+	}
+	defer rows.Close()
+
+	projects := map[string]int{}
+	agents := map[string]int{}
+	months := map[string]int{}
+	var facets SearchFacets
+	for rows.Next() {
+		var project, agent, month string
+		var n int
+		if err := rows.Scan(&project, &agent, &month, &n); err != nil {
+			return SearchFacets{},
+				fmt.Errorf("scanning facet: %w", err)
+		}
+		facets.Total += n
+		projects[project] += n
+		agents[agent] += n
+		months[month] += n
+	}
+	if err := rows.Err(); err != nil {
+		return SearchFacets{}, err
+	}
+
+	facets.Projects = facetsByCount(projects)
+	facets.Agents = facetsByCount(agents)
+	facets.Months = make([]SearchFacet, 0, len(months))
+	for v, n := range months {
+		facets.Months = append(facets.Months, SearchFacet{v, n})
+	}
+	sort.Slice(facets.Months, func(i, j int) bool {
+		return facets.Months[i].Value > facets.Months[j].Value
+	})
+	return facets, nil
+}
+
+func facetsByCount(counts map[string]int) []SearchFacet {
+	out := make([]SearchFacet, 0, len(counts))
+	for v, n := range counts {
+		out = append(out, SearchFacet{v, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Value < out[j].Value
+	})
+	return out
+}
@@ -0,0 +1,279 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/wesm/agentsview/internal/secrets"
+)
+
+// SessionSecrets summarizes likely secrets found in one
+// session. It never carries the secret values themselves.
+type SessionSecrets struct {
+	SessionID string         `json:"session_id"`
+	Project   string         `json:"project"`
+	Agent     string         `json:"agent"`
+	Findings  int            `json:"findings"`
+	Rules     map[string]int `json:"rules"`
+	// Ordinals of messages whose content or tool calls
+	// contain a finding, ascending. Findings in hook events
+	// are counted but have no ordinal.
+	Ordinals []int `json:"ordinals"`
+}
+
+// secretScanQueries select (session_id, project, agent,
+// ordinal, text) for every scanned field: message content,
+// tool call inputs and results, and hook commands and
+// messages. Hook events have no message, so ordinal is -1.
+var secretScanQueries = []string{
+	`SELECT m.session_id, s.project, s.agent, m.ordinal,
+		m.content
+	FROM messages m JOIN sessions s ON s.id = m.session_id
+	WHERE s.project LIKE ? ESCAPE '\'`,
+	`SELECT t.session_id, s.project, s.agent, m.ordinal,
+		COALESCE(t.input_json, '')
+	FROM tool_calls t
+	JOIN messages m ON m.id = t.message_id
+	JOIN sessions s ON s.id = t.session_id
+	WHERE s.project LIKE ? ESCAPE '\'
+		AND t.input_json IS NOT NULL`,
+	`SELECT t.session_id, s.project, s.agent, m.ordinal,
+		COALESCE(t.result_content, '')
+	FROM tool_calls t
+	JOIN messages m ON m.id = t.message_id
+	JOIN sessions s ON s.id = t.session_id
+	WHERE s.project LIKE ? ESCAPE '\'
+		AND t.result_content IS NOT NULL`,
+	`SELECT h.session_id, s.project, s.agent, -1,
+		h.command || char(10) || h.message
+	FROM hook_events h JOIN sessions s ON s.id = h.session_id
+	WHERE s.project LIKE ? ESCAPE '\'`,
+}
+
+// ScanSecrets runs the secret detectors over stored message
+// content and tool call inputs and results. project, when
+// non-empty, limits the scan to projects containing it.
+// Sessions are returned with the most findings first.
+func (db *DB) ScanSecrets(
+	ctx context.Context, project string,
+) ([]SessionSecrets, error) {
+	pattern := "%" + escapeLike(project) + "%"
+	bySession := map[string]*SessionSecrets{}
+	ordinals := map[string]map[int]bool{}
+
+	for _, q := range secretScanQueries {
+		rows, err := db.getReader().QueryContext(ctx, q, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("querying secret scan: %w", err)
+		}
+		for rows.Next() {
+			var sid, proj, agent, text string
+			var ordinal int
+			if err := rows.Scan(
+				&sid, &proj, &agent, &ordinal, &text,
+			); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scanning secret scan: %w", err)
+			}
+			found := secrets.Find(text)
+			if len(found) == 0 {
+				continue
+			}
+			s := bySession[sid]
+			if s == nil {
+				s = &SessionSecrets{
+					SessionID: sid,
+					Project:   proj,
+					Agent:     agent,
+					Rules:     map[string]int{},
+				}
+				bySession[sid] = s
+				ordinals[sid] = map[int]bool{}
+			}
+			for _, f := range found {
+				s.Findings++
+				s.Rules[f.Rule]++
+			}
+			if ordinal >= 0 {
+				ordinals[sid][ordinal] = true
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("iterating secret scan: %w", err)
+		}
+	}
+
+	out := make([]SessionSecrets, 0, len(bySession))
+	for sid, s := range bySession {
+		for o := range ordinals[sid] {
+			s.Ordinals = append(s.Ordinals, o)
+		}
+		sort.Ints(s.Ordinals)
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Findings != out[j].Findings {
+			return out[i].Findings > out[j].Findings
+		}
+		return out[i].SessionID < out[j].SessionID
+	})
+	return out, nil
+}
+
+// RedactResult counts rows rewritten by RedactSecrets.
+type RedactResult struct {
+	Messages   int `json:"messages"`
+	ToolCalls  int `json:"tool_calls"`
+	HookEvents int `json:"hook_events"`
+	Findings   int `json:"findings"`
+}
+
+// RedactSecrets replaces detected secrets in the given
+// sessions' message content, first message, tool call inputs
+// and results, and hook events with [REDACTED:<rule>] markers. Only the
+// database is changed; a resync from unchanged source files
+// restores the original text.
+func (db *DB) RedactSecrets(
+	ctx context.Context, sessionIDs []string,
+) (RedactResult, error) {
+	var res RedactResult
+	if len(sessionIDs) == 0 {
+		return res, nil
+	}
+	err := db.write(func() error {
+		tx, err := db.getWriter().BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		err = queryChunked(sessionIDs, func(chunk []string) error {
+			ph, args := inPlaceholders(chunk)
+
+			n, found, err := redactColumn(ctx, tx,
+				`SELECT id, content FROM messages
+				WHERE session_id IN `+ph, args,
+				`UPDATE messages
+				SET content = ?, content_length = LENGTH(?)
+				WHERE id = ?`,
+				func(v string) []any { return []any{v, v} },
+			)
+			if err != nil {
+				return err
+			}
+			res.Messages += n
+			res.Findings += found
+
+			for _, col := range []string{
+				"input_json", "result_content",
+			} {
+				n, found, err = redactColumn(ctx, tx,
+					`SELECT id, `+col+` FROM tool_calls
+					WHERE session_id IN `+ph+`
+						AND `+col+` IS NOT NULL`, args,
+					`UPDATE tool_calls SET `+col+` = ?
+					WHERE id = ?`,
+					func(v string) []any { return []any{v} },
+				)
+				if err != nil {
+					return err
+				}
+				res.ToolCalls += n
+				res.Findings += found
+			}
+
+			for _, col := range []string{"command", "message"} {
+				n, found, err = redactColumn(ctx, tx,
+					`SELECT id, `+col+` FROM hook_events
+					WHERE session_id IN `+ph, args,
+					`UPDATE hook_events SET `+col+` = ?
+					WHERE id = ?`,
+					func(v string) []any { return []any{v} },
+				)
+				if err != nil {
+					return err
+				}
+				res.HookEvents += n
+				res.Findings += found
+			}
+
+			// first_message repeats the first user message and
+			// todo items repeat TodoWrite inputs, so their
+			// findings are not counted again.
+			_, _, err = redactColumn(ctx, tx,
+				`SELECT id, first_message FROM sessions
+				WHERE id IN `+ph+`
+					AND first_message IS NOT NULL`, args,
+				`UPDATE sessions SET first_message = ?
+				WHERE id = ?`,
+				func(v string) []any { return []any{v} },
+			)
+			if err != nil {
+				return err
+			}
+			_, _, err = redactColumn(ctx, tx,
+				`SELECT id, content FROM session_todos
+				WHERE session_id IN `+ph, args,
+				`UPDATE session_todos SET content = ?
+				WHERE id = ?`,
+				func(v string) []any { return []any{v} },
+			)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	return res, err
+}
+
+// redactColumn reads (id, text) rows with selectSQL, redacts
+// each text, and writes changed rows back with updateSQL,
+// whose parameters are setArgs(text) followed by the id.
+func redactColumn(
+	ctx context.Context, tx *sql.Tx,
+	selectSQL string, args []any,
+	updateSQL string, setArgs func(string) []any,
+) (rowsChanged, findings int, err error) {
+	type change struct {
+		id   string
+		text string
+	}
+	rows, err := tx.QueryContext(ctx, selectSQL, args...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("querying for redaction: %w", err)
+	}
+	var changes []change
+	for rows.Next() {
+		var id, text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("scanning for redaction: %w", err)
+		}
+		redacted, n := secrets.Redact(text)
+		if n == 0 {
+			continue
+		}
+		findings += n
+		changes = append(changes, change{id, redacted})
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, 0, fmt.Errorf("iterating for redaction: %w", err)
+	}
+
+	for _, c := range changes {
+		if _, err := tx.ExecContext(
+			ctx, updateSQL, append(setArgs(c.text), c.id)...,
+		); err != nil {
+			return 0, 0, fmt.Errorf("writing redaction: %w", err)
+		}
+	}
+	return len(changes), findings, nil
+}
@@ -0,0 +1,301 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Bounds for GetAnalyticsToolSequences.
+const (
+	DefaultSequenceLength = 3
+	MinSequenceLength     = 2
+	MaxSequenceLength     = 5
+	DefaultSequenceLimit  = 20
+	MaxSequenceLimit      = 100
+
+	// minSequenceSessions is the number of sessions a sequence
+	// must appear in to be reported; one-off runs are noise.
+	minSequenceSessions = 2
+)
+
+// ToolSequence is one tool-call n-gram and how sessions that
+// contain it fared. A session counts as successful when the
+// user never interrupted it.
+type ToolSequence struct {
+	Steps       []string `json:"steps"`
+	Count       int      `json:"count"`        // occurrences
+	Sessions    int      `json:"sessions"`     // sessions containing it
+	SuccessRate float64  `json:"success_rate"` // % of those sessions
+	Lift        float64  `json:"lift"`         // points vs agent baseline
+	Repeat      bool     `json:"repeat"`       // one step repeated
+}
+
+// AgentToolSequences holds the frequent sequences for one agent.
+// Spins lists repeated-step sequences (Grep→Grep→Grep), the
+// usual sign of an agent going in circles.
+type AgentToolSequences struct {
+	Agent               string         `json:"agent"`
+	Sessions            int            `json:"sessions"`
+	BaselineSuccessRate float64        `json:"baseline_success_rate"`
+	Sequences           []ToolSequence `json:"sequences"`
+	Spins               []ToolSequence `json:"spins"`
+}
+
+// ToolSequencesResponse wraps per-agent sequence mining.
+type ToolSequencesResponse struct {
+	Length int                  `json:"length"`
+	Agents []AgentToolSequences `json:"agents"`
+}
+
+// bashTestMarkers and bashBuildMarkers classify shell commands
+// so that running tests reads differently from other Bash use.
+var (
+	bashTestMarkers = []string{
+		"go test", "pytest", "npm test", "npm run test",
+		"yarn test", "pnpm test", "jest", "vitest",
+		"cargo test", "make test", "rspec", "mvn test",
+		"gradle test", "unittest",
+	}
+	bashBuildMarkers = []string{
+		"go build", "go vet", "npm run build", "yarn build",
+		"pnpm build", "cargo build", "cargo check", "tsc",
+		"make ", "mvn package", "gradle build",
+	}
+)
+
+// sequenceStep returns the n-gram token for a tool call: its
+// category, with Bash refined by what the command runs.
+func sequenceStep(category, command string) string {
+	if category != "Bash" || command == "" {
+		return category
+	}
+	cmd := strings.ToLower(strings.TrimSpace(command))
+	if strings.HasPrefix(cmd, "git ") {
+		return "Bash(git)"
+	}
+	for _, m := range bashTestMarkers {
+		if strings.Contains(cmd, m) {
+			return "Bash(test)"
+		}
+	}
+	for _, m := range bashBuildMarkers {
+		if strings.Contains(cmd, m) {
+			return "Bash(build)"
+		}
+	}
+	return "Bash"
+}
+
+// GetAnalyticsToolSequences mines the most common runs of n
+// consecutive tool calls per agent, ordered by how many sessions
+// they occur in, and reports whether sessions containing each
+// run succeeded more or less often than the agent's baseline.
+func (db *DB) GetAnalyticsToolSequences(
+	ctx context.Context, f AnalyticsFilter, n, limit int,
+) (ToolSequencesResponse, error) {
+	if n <= 0 {
+		n = DefaultSequenceLength
+	}
+	n = min(max(n, MinSequenceLength), MaxSequenceLength)
+	if limit <= 0 {
+		limit = DefaultSequenceLimit
+	}
+	limit = min(limit, MaxSequenceLimit)
+
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return ToolSequencesResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, agent, interrupt_count
+		FROM sessions WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return ToolSequencesResponse{},
+			fmt.Errorf("querying sequence sessions: %w", err)
+	}
+	defer rows.Close()
+
+	type sessInfo struct {
+		agent   string
+		success bool
+	}
+	sessions := map[string]sessInfo{}
+	var ids []string
+	for rows.Next() {
+		var id, ts, agent string
+		var interrupts int
+		if err := rows.Scan(&id, &ts, &agent, &interrupts); err != nil {
+			return ToolSequencesResponse{},
+				fmt.Errorf("scanning sequence session: %w", err)
+		}
+		if !inDateRange(localDate(ts, loc), f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		sessions[id] = sessInfo{agent: agent, success: interrupts == 0}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return ToolSequencesResponse{},
+			fmt.Errorf("iterating sequence sessions: %w", err)
+	}
+
+	steps := map[string][]string{}
+	err = queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT tc.session_id, tc.category, tc.command
+			FROM tool_calls tc
+			JOIN messages m ON m.id = tc.message_id
+			WHERE tc.session_id IN `+ph+`
+			ORDER BY tc.session_id, m.ordinal, tc.id`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying tool sequence: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sid, category string
+			var command sql.NullString
+			if err := rows.Scan(&sid, &category, &command); err != nil {
+				return fmt.Errorf("scanning tool sequence: %w", err)
+			}
+			steps[sid] = append(steps[sid],
+				sequenceStep(category, command.String))
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return ToolSequencesResponse{}, err
+	}
+
+	type gramStats struct {
+		steps     []string
+		count     int
+		sessions  int
+		successes int
+	}
+	type agentStats struct {
+		sessions  int
+		successes int
+		grams     map[string]*gramStats
+	}
+	byAgent := map[string]*agentStats{}
+	for _, id := range ids {
+		info := sessions[id]
+		a := byAgent[info.agent]
+		if a == nil {
+			a = &agentStats{grams: map[string]*gramStats{}}
+			byAgent[info.agent] = a
+		}
+		a.sessions++
+		if info.success {
+			a.successes++
+		}
+
+		seq := steps[id]
+		seen := map[string]bool{}
+		for i := 0; i+n <= len(seq); i++ {
+			gram := seq[i : i+n]
+			key := strings.Join(gram, "\x00")
+			g := a.grams[key]
+			if g == nil {
+				g = &gramStats{steps: gram}
+				a.grams[key] = g
+			}
+			g.count++
+			if !seen[key] {
+				seen[key] = true
+				g.sessions++
+				if info.success {
+					g.successes++
+				}
+			}
+		}
+	}
+
+	resp := ToolSequencesResponse{
+		Length: n,
+		Agents: make([]AgentToolSequences, 0, len(byAgent)),
+	}
+	for agent, a := range byAgent {
+		baseline := float64(a.successes) / float64(a.sessions) * 100
+		out := AgentToolSequences{
+			Agent:               agent,
+			Sessions:            a.sessions,
+			BaselineSuccessRate: round1(baseline),
+			Sequences:           []ToolSequence{},
+			Spins:               []ToolSequence{},
+		}
+		var all []ToolSequence
+		for _, g := range a.grams {
+			if g.sessions < minSequenceSessions {
+				continue
+			}
+			rate := float64(g.successes) / float64(g.sessions) * 100
+			all = append(all, ToolSequence{
+				Steps:       g.steps,
+				Count:       g.count,
+				Sessions:    g.sessions,
+				SuccessRate: round1(rate),
+				Lift:        round1(rate - baseline),
+				Repeat:      isRepeatSequence(g.steps),
+			})
+		}
+		sort.Slice(all, func(i, j int) bool {
+			x, y := all[i], all[j]
+			if x.Sessions != y.Sessions {
+				return x.Sessions > y.Sessions
+			}
+			if x.Count != y.Count {
+				return x.Count > y.Count
+			}
+			return strings.Join(x.Steps, ",") <
+				strings.Join(y.Steps, ",")
+		})
+		for _, s := range all {
+			if len(out.Sequences) < limit {
+				out.Sequences = append(out.Sequences, s)
+			}
+			if s.Repeat && len(out.Spins) < limit {
+				out.Spins = append(out.Spins, s)
+			}
+		}
+		resp.Agents = append(resp.Agents, out)
+	}
+	sort.Slice(resp.Agents, func(i, j int) bool {
+		a, b := resp.Agents[i], resp.Agents[j]
+		if a.Sessions != b.Sessions {
+			return a.Sessions > b.Sessions
+		}
+		return a.Agent < b.Agent
+	})
+	return resp, nil
+}
+
+// isRepeatSequence reports whether every step is the same.
+func isRepeatSequence(steps []string) bool {
+	for _, s := range steps[1:] {
+		if s != steps[0] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// EnvValue counts the sessions recorded with one environment
+// setting.
+type EnvValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Sessions int    `json:"sessions"`
+}
+
+// ReplaceSessionEnv replaces the environment snapshot for a
+// session. env holds only allowlisted settings; see the
+// parser's Env* keys.
+func (db *DB) ReplaceSessionEnv(
+	sessionID string, env map[string]string,
+) error {
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.Exec(
+			"DELETE FROM session_env WHERE session_id = ?",
+			sessionID,
+		); err != nil {
+			return fmt.Errorf("deleting old env: %w", err)
+		}
+
+		if len(env) > 0 {
+			stmt, err := tx.Prepare(`
+				INSERT INTO session_env (session_id, key, value)
+				VALUES (?, ?, ?)`)
+			if err != nil {
+				return fmt.Errorf("preparing env insert: %w", err)
+			}
+			defer stmt.Close()
+			for k, v := range env {
+				if _, err := stmt.Exec(sessionID, k, v); err != nil {
+					return fmt.Errorf("inserting env: %w", err)
+				}
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// GetSessionEnv returns a session's environment snapshot. The
+// map is empty when none was recorded.
+func (db *DB) GetSessionEnv(
+	ctx context.Context, sessionID string,
+) (map[string]string, error) {
+	rows, err := db.getReader().QueryContext(ctx,
+		"SELECT key, value FROM session_env WHERE session_id = ?",
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying session env: %w", err)
+	}
+	defer rows.Close()
+
+	env := map[string]string{}
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, fmt.Errorf("scanning session env: %w", err)
+		}
+		env[k] = v
+	}
+	return env, rows.Err()
+}
+
+// GetEnvValues lists every recorded environment setting with
+// the number of sessions that have it, ordered by key and then
+// most common value first. It backs the env filter's choices.
+func (db *DB) GetEnvValues(
+	ctx context.Context,
+) ([]EnvValue, error) {
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT key, value, COUNT(*) AS n
+		FROM session_env
+		GROUP BY key, value
+		ORDER BY key, n DESC, value`)
+	if err != nil {
+		return nil, fmt.Errorf("querying env values: %w", err)
+	}
+	defer rows.Close()
+
+	values := []EnvValue{}
+	for rows.Next() {
+		var v EnvValue
+		if err := rows.Scan(&v.Key, &v.Value, &v.Sessions); err != nil {
+			return nil, fmt.Errorf("scanning env value: %w", err)
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// envPredicate returns a SessionFilter predicate for one env
+// condition: "key=value" matches that setting, and a bare "key"
+// matches any session that recorded the key.
+func envPredicate(cond string) (string, []any) {
+	key, value, hasValue := strings.Cut(cond, "=")
+	if !hasValue {
+		return "id IN (SELECT session_id FROM session_env" +
+			" WHERE key = ?)", []any{key}
+	}
+	return "id IN (SELECT session_id FROM session_env" +
+		" WHERE key = ? AND value = ?)", []any{key, value}
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestReplaceSessionEnv(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+
+	requireNoError(t, d.ReplaceSessionEnv("s1", map[string]string{
+		"thinking": "disabled",
+	}), "ReplaceSessionEnv")
+	requireNoError(t, d.ReplaceSessionEnv("s1", map[string]string{
+		"thinking":       "enabled",
+		"client_version": "2.0.14",
+	}), "ReplaceSessionEnv again")
+
+	got, err := d.GetSessionEnv(ctx, "s1")
+	requireNoError(t, err, "GetSessionEnv")
+	want := map[string]string{
+		"thinking": "enabled", "client_version": "2.0.14",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("env = %v, want %v", got, want)
+	}
+
+	requireNoError(t, d.DeleteSession("s1"), "DeleteSession")
+	got, err = d.GetSessionEnv(ctx, "s1")
+	requireNoError(t, err, "GetSessionEnv after delete")
+	if len(got) != 0 {
+		t.Errorf("env after delete = %v, want empty", got)
+	}
+}
+
+func TestSessionEnvFilterAndValues(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	envs := map[string]map[string]string{
+		"a": {"thinking": "enabled", "beta.ctx-1m": "enabled"},
+		"b": {"thinking": "enabled"},
+		"c": {"thinking": "disabled"},
+		"d": nil,
+	}
+	for id, env := range envs {
+		insertSession(t, d, id, "proj")
+		requireNoError(t, d.ReplaceSessionEnv(id, env), "ReplaceSessionEnv")
+	}
+
+	tests := []struct {
+		env  []string
+		want []string
+	}{
+		{[]string{"thinking=enabled"}, []string{"a", "b"}},
+		{[]string{"thinking"}, []string{"a", "b", "c"}},
+		{[]string{"thinking=enabled", "beta.ctx-1m"}, []string{"a"}},
+		{[]string{"thinking=off"}, nil},
+	}
+	for _, tt := range tests {
+		page, err := d.ListSessions(ctx, SessionFilter{
+			Env: tt.env, Limit: 10,
+		})
+		requireNoError(t, err, "ListSessions")
+		got := collectIDs(page.Sessions)
+		sort.Strings(got)
+		if len(got) != len(tt.want) ||
+			(len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("env %v: got %v, want %v", tt.env, got, tt.want)
+		}
+	}
+
+	values, err := d.GetEnvValues(ctx)
+	requireNoError(t, err, "GetEnvValues")
+	wantValues := []EnvValue{
+		{Key: "beta.ctx-1m", Value: "enabled", Sessions: 1},
+		{Key: "thinking", Value: "enabled", Sessions: 2},
+		{Key: "thinking", Value: "disabled", Sessions: 1},
+	}
+	if !reflect.DeepEqual(values, wantValues) {
+		t.Errorf("values = %v, want %v", values, wantValues)
+	}
+}
@@ -2,13 +2,15 @@ package db
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -19,8 +21,21 @@ var ErrInvalidCursor = errors.New("invalid cursor")
 // (list, get). Keep in sync with scanSessionRow.
 const sessionBaseCols = `id, project, machine, agent,
 	first_message, started_at, ended_at,
-	message_count, user_message_count,
-	parent_session_id, relationship_type, created_at`
+	message_count, user_message_count, headless,
+	git_branch, worktree, entry_point, client, quality_score,
+	language, parent_session_id, relationship_type, created_at, ` + flagCols
+
+// flagCols selects whether a session is pinned and archived.
+const flagCols = `EXISTS (SELECT 1 FROM session_flags sf
+	WHERE sf.session_id = sessions.id AND sf.pinned = 1),
+	EXISTS (SELECT 1 FROM session_flags sf
+	WHERE sf.session_id = sessions.id AND sf.archived = 1)`
+
+// pinnedPred matches sessions flagged pinned.
+const pinnedPred = "id IN (SELECT session_id FROM session_flags WHERE pinned = 1)"
+
+// archivedPred matches sessions flagged archived.
+const archivedPred = "id IN (SELECT session_id FROM session_flags WHERE archived = 1)"
 
 // sessionPruneCols extends sessionBaseCols with file metadata
 // needed by FindPruneCandidates.
@@ -33,10 +48,11 @@ const sessionPruneCols = `id, project, machine, agent,
 // sessionFullCols includes all columns for a complete session record.
 const sessionFullCols = `id, project, machine, agent,
 	first_message, started_at, ended_at,
-	message_count, user_message_count,
-	parent_session_id, relationship_type,
+	message_count, user_message_count, interrupt_count,
+	headless, git_branch, worktree, entry_point, client,
+	quality_score, language, parent_session_id, relationship_type,
 	file_path, file_size, file_mtime,
-	file_hash, created_at`
+	file_hash, created_at, ` + flagCols
 
 const (
 	// DefaultSessionLimit is the default number of sessions returned.
@@ -51,15 +67,23 @@ type rowScanner interface {
 	Scan(dest ...any) error
 }
 
+// execer is satisfied by both *sql.DB and *sql.Tx, so a write
+// can run on its own or as part of a larger transaction.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 // scanSessionRow scans sessionBaseCols into a Session.
 func scanSessionRow(rs rowScanner) (Session, error) {
 	var s Session
 	err := rs.Scan(
 		&s.ID, &s.Project, &s.Machine, &s.Agent,
 		&s.FirstMessage, &s.StartedAt, &s.EndedAt,
-		&s.MessageCount, &s.UserMessageCount,
-		&s.ParentSessionID, &s.RelationshipType,
-		&s.CreatedAt,
+		&s.MessageCount, &s.UserMessageCount, &s.Headless,
+		&s.GitBranch, &s.Worktree, &s.EntryPoint, &s.Client,
+		&s.QualityScore, &s.Language, &s.ParentSessionID,
+		&s.RelationshipType, &s.CreatedAt, &s.Pinned, &s.Archived,
 	)
 	return s, err
 }
@@ -75,6 +99,17 @@ type Session struct {
 	EndedAt          *string `json:"ended_at"`
 	MessageCount     int     `json:"message_count"`
 	UserMessageCount int     `json:"user_message_count"`
+	InterruptCount   int     `json:"interrupt_count,omitempty"`
+	Headless         bool    `json:"headless,omitempty"`
+	GitBranch        string  `json:"git_branch,omitempty"`
+	Worktree         string  `json:"worktree,omitempty"`
+	EntryPoint       string  `json:"entry_point,omitempty"`
+	Client           string  `json:"client,omitempty"`
+	Language         string  `json:"language,omitempty"` // dominant prompt language, ISO 639-1
+	// QualityScore is the 0-100 review heuristic computed at
+	// sync; see QualitySignals. Nil for sessions without
+	// messages.
+	QualityScore     *int    `json:"quality_score,omitempty"`
 	ParentSessionID  *string `json:"parent_session_id,omitempty"`
 	RelationshipType string  `json:"relationship_type,omitempty"`
 	FilePath         *string `json:"file_path,omitempty"`
@@ -82,13 +117,24 @@ type Session struct {
 	FileMtime        *int64  `json:"file_mtime,omitempty"`
 	FileHash         *string `json:"file_hash,omitempty"`
 	CreatedAt        string  `json:"created_at"`
+	// Pinned is set on sessions the user pinned; ListSessions
+	// returns them ahead of the rest.
+	Pinned bool `json:"pinned,omitempty"`
+	// Archived is set on sessions the user archived; they are
+	// left out of listings and analytics unless asked for.
+	Archived bool `json:"archived,omitempty"`
+	// Notes are the user's annotations, filled by
+	// GetSessionFull only.
+	Notes []SessionNote `json:"notes,omitempty"`
 }
 
-// SessionCursor is the opaque pagination token.
+// SessionCursor is the opaque pagination token. Pinned is set
+// while the listing is still inside the pinned sessions.
 type SessionCursor struct {
 	EndedAt string `json:"e"`
 	ID      string `json:"i"`
 	Total   int    `json:"t,omitempty"`
+	Pinned  bool   `json:"p,omitempty"`
 }
 
 // EncodeCursor returns a base64-encoded cursor string.
@@ -97,20 +143,23 @@ func (db *DB) EncodeCursor(endedAt, id string, total ...int) string {
 	if len(total) > 0 {
 		t = total[0]
 	}
-	c := SessionCursor{EndedAt: endedAt, ID: id, Total: t}
-	data, _ := json.Marshal(c)
-
-	db.cursorMu.RLock()
-	mac := hmac.New(sha256.New, db.cursorSecret)
-	db.cursorMu.RUnlock()
-
-	mac.Write(data)
-	sig := mac.Sum(nil)
+	return db.encodeCursor(SessionCursor{
+		EndedAt: endedAt, ID: id, Total: t,
+	})
+}
 
+func (db *DB) encodeCursor(c SessionCursor) string {
+	data, _ := json.Marshal(c)
+	sig := db.sign(writeBytes(data))
 	return base64.RawURLEncoding.EncodeToString(data) + "." +
 		base64.RawURLEncoding.EncodeToString(sig)
 }
 
+// writeBytes returns a signing input function writing data.
+func writeBytes(data []byte) func(hash.Hash) {
+	return func(h hash.Hash) { h.Write(data) }
+}
+
 // DecodeCursor parses a base64-encoded cursor string.
 func (db *DB) DecodeCursor(s string) (SessionCursor, error) {
 	parts := strings.Split(s, ".")
@@ -143,14 +192,7 @@ func (db *DB) DecodeCursor(s string) (SessionCursor, error) {
 		return SessionCursor{}, fmt.Errorf("%w: invalid signature encoding: %v", ErrInvalidCursor, err)
 	}
 
-	db.cursorMu.RLock()
-	mac := hmac.New(sha256.New, db.cursorSecret)
-	db.cursorMu.RUnlock()
-
-	mac.Write(data)
-	expectedSig := mac.Sum(nil)
-
-	if !hmac.Equal(sig, expectedSig) {
+	if !db.verify(sig, writeBytes(data)) {
 		return SessionCursor{}, fmt.Errorf("%w: signature mismatch", ErrInvalidCursor)
 	}
 
@@ -167,14 +209,26 @@ type SessionFilter struct {
 	ExcludeProject  string // exclude sessions with this project name
 	Machine         string
 	Agent           string
-	Date            string // exact date YYYY-MM-DD
-	DateFrom        string // range start (inclusive)
-	DateTo          string // range end (inclusive)
-	ActiveSince     string // ISO-8601 timestamp; filters on most recent activity
-	MinMessages     int    // message_count >= N (0 = no filter)
-	MaxMessages     int    // message_count <= N (0 = no filter)
-	MinUserMessages int    // user_message_count >= N (0 = no filter)
-	Cursor          string // opaque cursor from previous page
+	Date            string   // exact date YYYY-MM-DD
+	DateFrom        string   // range start (inclusive)
+	DateTo          string   // range end (inclusive)
+	ActiveSince     string   // ISO-8601 timestamp; filters on most recent activity
+	StartedAfter    string   // started_at >= UTC RFC3339 timestamp
+	StartedBefore   string   // started_at < timestamp
+	EndedAfter      string   // ended_at >= timestamp
+	EndedBefore     string   // ended_at < timestamp
+	MinMessages     int      // message_count >= N (0 = no filter)
+	MaxMessages     int      // message_count <= N (0 = no filter)
+	MinUserMessages int      // user_message_count >= N (0 = no filter)
+	QualityBelow    int      // quality_score < N (0 = no filter)
+	Language        string   // dominant prompt language, ISO 639-1
+	Tag             string   // sessions carrying this tag
+	Query           string   // words that must all appear in first_message
+	Env             []string // env conditions, "key=value" or "key"; all must match
+	IncludeArchived bool     // include sessions flagged archived
+	Pinned          bool     // only sessions flagged pinned
+	Archived        bool     // only sessions flagged archived
+	Cursor          string   // opaque cursor from previous page
 	Limit           int
 }
 
@@ -230,6 +284,20 @@ func buildSessionFilter(f SessionFilter) (string, []any) {
 			"COALESCE(NULLIF(ended_at, ''), NULLIF(started_at, ''), created_at) >= ?")
 		args = append(args, f.ActiveSince)
 	}
+	// Compared with the bare columns so idx_sessions_started and
+	// idx_sessions_ended apply; sessions missing the column
+	// never match.
+	for _, b := range []struct{ pred, v string }{
+		{"started_at >= ?", f.StartedAfter},
+		{"started_at < ?", f.StartedBefore},
+		{"ended_at >= ?", f.EndedAfter},
+		{"ended_at < ?", f.EndedBefore},
+	} {
+		if b.v != "" {
+			preds = append(preds, b.pred)
+			args = append(args, b.v)
+		}
+	}
 	if f.MinMessages > 0 {
 		preds = append(preds, "message_count >= ?")
 		args = append(args, f.MinMessages)
@@ -242,6 +310,40 @@ func buildSessionFilter(f SessionFilter) (string, []any) {
 		preds = append(preds, "user_message_count >= ?")
 		args = append(args, f.MinUserMessages)
 	}
+	if f.QualityBelow > 0 {
+		preds = append(preds, "quality_score < ?")
+		args = append(args, f.QualityBelow)
+	}
+	if f.Language != "" {
+		preds = append(preds, "language = ?")
+		args = append(args, f.Language)
+	}
+	if f.Tag != "" {
+		preds = append(preds,
+			"id IN (SELECT session_id FROM session_tags WHERE tag = ?)")
+		args = append(args, f.Tag)
+	}
+	// Query is a quick filter over the session list, not a
+	// message search: each word is matched case-insensitively
+	// against first_message. A session title belongs here too
+	// once sessions have one.
+	for _, word := range strings.Fields(f.Query) {
+		preds = append(preds, `first_message LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(word)+"%")
+	}
+	for _, cond := range f.Env {
+		pred, predArgs := envPredicate(cond)
+		preds = append(preds, pred)
+		args = append(args, predArgs...)
+	}
+	if f.Pinned {
+		preds = append(preds, pinnedPred)
+	}
+	if f.Archived {
+		preds = append(preds, archivedPred)
+	} else if !f.IncludeArchived {
+		preds = append(preds, "NOT "+archivedPred)
+	}
 
 	return strings.Join(preds, " AND "), args
 }
@@ -279,7 +381,76 @@ func (db *DB) ListSessions(
 		}
 	}
 
-	// Paginated results
+	// Pinned sessions are listed ahead of the rest, each run in
+	// recency order. A cursor inside the pinned run continues it
+	// and then falls through to the unpinned sessions.
+	var sessions []Session
+	if f.Cursor == "" || cur.Pinned {
+		pinned, err := db.querySessionPage(
+			ctx, where+" AND "+pinnedPred, args, f, cur,
+		)
+		if err != nil {
+			return SessionPage{}, err
+		}
+		sessions = pinned
+	}
+	if len(sessions) <= f.Limit {
+		rest := f
+		rest.Limit = f.Limit - len(sessions)
+		if cur.Pinned {
+			rest.Cursor = ""
+		}
+		unpinned, err := db.querySessionPage(
+			ctx, where+" AND NOT "+pinnedPred, args, rest, cur,
+		)
+		if err != nil {
+			return SessionPage{}, err
+		}
+		sessions = append(sessions, unpinned...)
+	}
+
+	page := SessionPage{Sessions: sessions, Total: total}
+	if len(sessions) > f.Limit {
+		page.Sessions = sessions[:f.Limit]
+		last := page.Sessions[f.Limit-1]
+		ea := last.CreatedAt
+		if last.StartedAt != nil && *last.StartedAt != "" {
+			ea = *last.StartedAt
+		}
+		if last.EndedAt != nil && *last.EndedAt != "" {
+			ea = *last.EndedAt
+		}
+		page.NextCursor = db.encodeCursor(SessionCursor{
+			EndedAt: ea, ID: last.ID, Total: total,
+			Pinned: last.Pinned,
+		})
+	}
+
+	return page, nil
+}
+
+// querySessionPage runs one page query for ListSessions,
+// returning up to f.Limit+1 sessions.
+func (db *DB) querySessionPage(
+	ctx context.Context, where string, args []any,
+	f SessionFilter, cur SessionCursor,
+) ([]Session, error) {
+	query, cursorArgs := sessionPageQuery(where, args, f, cur)
+	rows, err := db.getReader().QueryContext(ctx, query, cursorArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("querying sessions: %w", err)
+	}
+	defer rows.Close()
+	return scanSessionRows(rows)
+}
+
+// sessionPageQuery builds the paginated list query for ListSessions.
+// The ORDER BY expression must stay in sync with the
+// idx_sessions_list_* indexes in schema.sql, otherwise SQLite falls
+// back to sorting every matching row.
+func sessionPageQuery(
+	where string, args []any, f SessionFilter, cur SessionCursor,
+) (string, []any) {
 	cursorArgs := append([]any{}, args...)
 	cursorWhere := where
 	if f.Cursor != "" {
@@ -298,34 +469,7 @@ func (db *DB) ListSessions(
 		) DESC, id DESC
 		LIMIT ?`
 	cursorArgs = append(cursorArgs, f.Limit+1)
-
-	rows, err := db.getReader().QueryContext(ctx, query, cursorArgs...)
-	if err != nil {
-		return SessionPage{},
-			fmt.Errorf("querying sessions: %w", err)
-	}
-	defer rows.Close()
-
-	sessions, err := scanSessionRows(rows)
-	if err != nil {
-		return SessionPage{}, err
-	}
-
-	page := SessionPage{Sessions: sessions, Total: total}
-	if len(sessions) > f.Limit {
-		page.Sessions = sessions[:f.Limit]
-		last := page.Sessions[f.Limit-1]
-		ea := last.CreatedAt
-		if last.StartedAt != nil && *last.StartedAt != "" {
-			ea = *last.StartedAt
-		}
-		if last.EndedAt != nil && *last.EndedAt != "" {
-			ea = *last.EndedAt
-		}
-		page.NextCursor = db.EncodeCursor(ea, last.ID, total)
-	}
-
-	return page, nil
+	return query, cursorArgs
 }
 
 // GetSession returns a single session by ID.
@@ -362,10 +506,13 @@ func (db *DB) GetSessionFull(
 	err := row.Scan(
 		&s.ID, &s.Project, &s.Machine, &s.Agent,
 		&s.FirstMessage, &s.StartedAt, &s.EndedAt,
-		&s.MessageCount, &s.UserMessageCount,
+		&s.MessageCount, &s.UserMessageCount, &s.InterruptCount,
+		&s.Headless, &s.GitBranch, &s.Worktree,
+		&s.EntryPoint, &s.Client, &s.QualityScore, &s.Language,
 		&s.ParentSessionID, &s.RelationshipType,
 		&s.FilePath, &s.FileSize,
-		&s.FileMtime, &s.FileHash, &s.CreatedAt,
+		&s.FileMtime, &s.FileHash, &s.CreatedAt, &s.Pinned,
+		&s.Archived,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -373,22 +520,38 @@ func (db *DB) GetSessionFull(
 	if err != nil {
 		return nil, fmt.Errorf("getting session full %s: %w", id, err)
 	}
+	if s.Notes, err = db.ListSessionNotes(ctx, id); err != nil {
+		return nil, err
+	}
 	return &s, nil
 }
 
-// UpsertSession inserts or updates a session.
+// UpsertSession inserts or updates a session. The machine name
+// is resolved through machine_aliases first.
 func (db *DB) UpsertSession(s Session) error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
+	return db.write(func() error {
+		return upsertSession(db.getWriter(), s)
+	})
+}
 
-	_, err := db.getWriter().Exec(`
+// upsertSession runs UpsertSession's statement on x.
+func upsertSession(x execer, s Session) error {
+	_, err := x.Exec(`
 		INSERT INTO sessions (
 			id, project, machine, agent, first_message,
 			started_at, ended_at, message_count,
-			user_message_count, parent_session_id,
-			relationship_type,
+			user_message_count, interrupt_count, headless,
+			git_branch, worktree, entry_point, client,
+			parent_session_id, relationship_type,
 			file_path, file_size, file_mtime, file_hash
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (
+			?, ?,
+			COALESCE(
+				(SELECT target FROM machine_aliases WHERE name = ?),
+				?
+			),
+			?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		)
 		ON CONFLICT(id) DO UPDATE SET
 			project = excluded.project,
 			machine = excluded.machine,
@@ -398,16 +561,23 @@ func (db *DB) UpsertSession(s Session) error {
 			ended_at = excluded.ended_at,
 			message_count = excluded.message_count,
 			user_message_count = excluded.user_message_count,
+			interrupt_count = excluded.interrupt_count,
+			headless = excluded.headless,
+			git_branch = excluded.git_branch,
+			worktree = excluded.worktree,
+			entry_point = excluded.entry_point,
+			client = excluded.client,
 			parent_session_id = excluded.parent_session_id,
 			relationship_type = excluded.relationship_type,
 			file_path = excluded.file_path,
 			file_size = excluded.file_size,
 			file_mtime = excluded.file_mtime,
 			file_hash = excluded.file_hash`,
-		s.ID, s.Project, s.Machine, s.Agent, s.FirstMessage,
-		s.StartedAt, s.EndedAt, s.MessageCount,
-		s.UserMessageCount, s.ParentSessionID,
-		s.RelationshipType,
+		s.ID, s.Project, s.Machine, s.Machine, s.Agent,
+		s.FirstMessage, s.StartedAt, s.EndedAt, s.MessageCount,
+		s.UserMessageCount, s.InterruptCount, s.Headless,
+		s.GitBranch, s.Worktree, s.EntryPoint, s.Client,
+		s.ParentSessionID, s.RelationshipType,
 		s.FilePath, s.FileSize, s.FileMtime, s.FileHash)
 	if err != nil {
 		return fmt.Errorf("upserting session %s: %w", s.ID, err)
@@ -450,6 +620,25 @@ func (db *DB) GetSessionFileInfo(
 	return s.Int64, m.Int64, true
 }
 
+// GetSessionFileHash returns the stored file_path, file_size,
+// and file_hash for a session. Used to tell whether a source
+// file was only appended to since it was last synced.
+func (db *DB) GetSessionFileHash(
+	id string,
+) (path string, size int64, hash string, ok bool) {
+	var p, h sql.NullString
+	var s sql.NullInt64
+	err := db.getReader().QueryRow(
+		"SELECT file_path, file_size, file_hash"+
+			" FROM sessions WHERE id = ?",
+		id,
+	).Scan(&p, &s, &h)
+	if err != nil {
+		return "", 0, "", false
+	}
+	return p.String, s.Int64, h.String, true
+}
+
 // GetFileInfoByPath returns file_size and file_mtime for a
 // session identified by file_path. Used for codex/gemini files
 // where the session ID requires parsing.
@@ -473,33 +662,39 @@ func (db *DB) GetFileInfoByPath(
 // the next sync to re-process all files regardless of whether
 // their size+mtime matches what was previously stored.
 func (db *DB) ResetAllMtimes() error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	_, err := db.getWriter().Exec(
-		"UPDATE sessions SET file_mtime = 0",
-	)
-	if err != nil {
-		return fmt.Errorf("resetting mtimes: %w", err)
-	}
-	return nil
+	return db.write(func() error {
+		_, err := db.getWriter().Exec(
+			"UPDATE sessions SET file_mtime = 0",
+		)
+		if err != nil {
+			return fmt.Errorf("resetting mtimes: %w", err)
+		}
+		return nil
+	})
 }
 
 // DeleteSession removes a session and its messages (cascading).
 func (db *DB) DeleteSession(id string) error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	_, err := db.getWriter().Exec(
-		"DELETE FROM sessions WHERE id = ?", id,
-	)
-	return err
+	return db.write(func() error {
+		_, err := db.getWriter().Exec(
+			"DELETE FROM sessions WHERE id = ?", id,
+		)
+		return err
+	})
 }
 
-// GetProjects returns project names with session counts.
+// sessionActivityCol is the last time a session was active.
+const sessionActivityCol = `COALESCE(NULLIF(ended_at, ''),
+	NULLIF(started_at, ''), created_at)`
+
+// GetProjects returns project names with session counts and
+// when each project last saw a session.
 func (db *DB) GetProjects(
 	ctx context.Context,
 ) ([]ProjectInfo, error) {
 	rows, err := db.getReader().QueryContext(ctx, `
-		SELECT project, COUNT(*) as session_count
+		SELECT project, COUNT(*) as session_count,
+			COALESCE(MAX(`+sessionActivityCol+`), '')
 		FROM sessions
 		WHERE message_count > 0
 		  AND relationship_type NOT IN ('subagent', 'fork')
@@ -513,7 +708,9 @@ func (db *DB) GetProjects(
 	var projects []ProjectInfo
 	for rows.Next() {
 		var p ProjectInfo
-		if err := rows.Scan(&p.Name, &p.SessionCount); err != nil {
+		if err := rows.Scan(
+			&p.Name, &p.SessionCount, &p.LastActiveAt,
+		); err != nil {
 			return nil, fmt.Errorf("scanning project: %w", err)
 		}
 		projects = append(projects, p)
@@ -525,6 +722,7 @@ func (db *DB) GetProjects(
 type ProjectInfo struct {
 	Name         string `json:"name"`
 	SessionCount int    `json:"session_count"`
+	LastActiveAt string `json:"last_active_at"`
 }
 
 // GetAgents returns distinct agent names with session counts.
@@ -600,17 +798,34 @@ func scanSessionRows(rows *sql.Rows) ([]Session, error) {
 // Filters combine with AND. At least one must be set.
 type PruneFilter struct {
 	Project      string // substring match (LIKE '%x%')
+	Agent        string // exact agent match
 	MaxMessages  *int   // user messages <= N (nil = no filter)
 	Before       string // ended_at < date (YYYY-MM-DD)
 	FirstMessage string // first_message LIKE 'prefix%'
+
+	// Orphaned selects local sessions whose source file no
+	// longer exists on disk. Sessions pushed from other
+	// machines are never orphaned: their files live there.
+	Orphaned bool
+	// LocalMachine is the machine label sync gives sessions
+	// found on this disk; empty means "local". A renamed or
+	// merged local machine is followed through its alias.
+	LocalMachine string
+
+	// IncludeChain treats sessions linked by parent_session_id
+	// as one unit: a chain is pruned only when every session in
+	// it matches. Without it, parents are never pruned.
+	IncludeChain bool
 }
 
 // HasFilters reports whether at least one filter is set.
 func (f PruneFilter) HasFilters() bool {
 	return f.Project != "" ||
+		f.Agent != "" ||
 		f.MaxMessages != nil ||
 		f.Before != "" ||
-		f.FirstMessage != ""
+		f.FirstMessage != "" ||
+		f.Orphaned
 }
 
 // escapeLike escapes SQL LIKE wildcard characters so user
@@ -638,6 +853,10 @@ func (db *DB) FindPruneCandidates(
 		where += ` AND project LIKE ? ESCAPE '\'`
 		args = append(args, "%"+escapeLike(f.Project)+"%")
 	}
+	if f.Agent != "" {
+		where += " AND agent = ?"
+		args = append(args, f.Agent)
+	}
 	if f.MaxMessages != nil {
 		where += ` AND (SELECT COUNT(*) FROM messages
 			WHERE messages.session_id = sessions.id
@@ -652,11 +871,26 @@ func (db *DB) FindPruneCandidates(
 		where += ` AND first_message LIKE ? ESCAPE '\'`
 		args = append(args, escapeLike(f.FirstMessage)+"%")
 	}
+	if f.Orphaned {
+		local := f.LocalMachine
+		if local == "" {
+			local = "local"
+		}
+		where += ` AND machine = COALESCE(
+				(SELECT target FROM machine_aliases WHERE name = ?),
+				?
+			)
+			AND file_path IS NOT NULL AND file_path != ''`
+		args = append(args, local, local)
+	}
 
-	// Exclude sessions that are parents of other sessions.
-	where += ` AND NOT EXISTS (
-		SELECT 1 FROM sessions AS child
-		WHERE child.parent_session_id = sessions.id)`
+	// Exclude sessions that are parents of other sessions,
+	// unless whole chains are being pruned.
+	if !f.IncludeChain {
+		where += ` AND NOT EXISTS (
+			SELECT 1 FROM sessions AS child
+			WHERE child.parent_session_id = sessions.id)`
+	}
 
 	query := "SELECT " + sessionPruneCols +
 		" FROM sessions WHERE " + where + `
@@ -685,22 +919,111 @@ func (db *DB) FindPruneCandidates(
 		if err != nil {
 			return nil, fmt.Errorf("scanning prune candidate: %w", err)
 		}
+		if f.Orphaned && sourceExists(*s.FilePath) {
+			continue
+		}
 		sessions = append(sessions, s)
 	}
-	return sessions, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if f.IncludeChain {
+		return db.wholeChains(sessions)
+	}
+	return sessions, nil
+}
+
+// sourceExists reports whether a session's source file is still
+// on disk, or might be: errors other than not-exist count as
+// present. Database-backed agents record "<db path>#<id>"; for
+// those the database is checked.
+func sourceExists(path string) bool {
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) && strings.Contains(path, "#") {
+		dbPath, _, _ := strings.Cut(path, "#")
+		_, err = os.Stat(dbPath)
+	}
+	return !os.IsNotExist(err)
+}
+
+// wholeChains drops matched sessions whose parent/child chain
+// has members that did not match, so chains are pruned whole
+// or not at all. Sessions outside any chain are kept.
+func (db *DB) wholeChains(matched []Session) ([]Session, error) {
+	rows, err := db.getReader().Query(`
+		SELECT child.id, child.parent_session_id
+		FROM sessions AS child
+		JOIN sessions AS parent
+			ON parent.id = child.parent_session_id`)
+	if err != nil {
+		return nil, fmt.Errorf("querying session chains: %w", err)
+	}
+	defer rows.Close()
+
+	root := map[string]string{}
+	var find func(id string) string
+	find = func(id string) string {
+		p, ok := root[id]
+		if !ok || p == id {
+			return id
+		}
+		r := find(p)
+		root[id] = r
+		return r
+	}
+	linked := map[string]bool{}
+	for rows.Next() {
+		var child, parent string
+		if err := rows.Scan(&child, &parent); err != nil {
+			return nil, fmt.Errorf("scanning session chain: %w", err)
+		}
+		linked[child], linked[parent] = true, true
+		if a, b := find(child), find(parent); a != b {
+			root[a] = b
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	size := map[string]int{}
+	for id := range linked {
+		size[find(id)]++
+	}
+	hits := map[string]int{}
+	for _, s := range matched {
+		if linked[s.ID] {
+			hits[find(s.ID)]++
+		}
+	}
+	kept := matched[:0]
+	for _, s := range matched {
+		if !linked[s.ID] || hits[find(s.ID)] == size[find(s.ID)] {
+			kept = append(kept, s)
+		}
+	}
+	return kept, nil
 }
 
 // DeleteSessions removes multiple sessions by ID in a single
-// transaction. Batches DELETEs in groups of 500 to stay under
-// SQLite variable limits. Returns count of deleted rows.
+// transaction, clearing the parent link of any session that
+// pointed at one of them. Batches DELETEs in groups of 500 to
+// stay under SQLite variable limits. Returns count of deleted
+// rows.
 func (db *DB) DeleteSessions(ids []string) (int, error) {
 	if len(ids) == 0 {
 		return 0, nil
 	}
 
-	db.mu.Lock()
-	defer db.mu.Unlock()
+	var total int
+	err := db.write(func() (err error) {
+		total, err = db.deleteSessionsLocked(ids)
+		return err
+	})
+	return total, err
+}
 
+func (db *DB) deleteSessionsLocked(ids []string) (int, error) {
 	tx, err := db.getWriter().Begin()
 	if err != nil {
 		return 0, fmt.Errorf("beginning transaction: %w", err)
@@ -728,6 +1051,16 @@ func (db *DB) DeleteSessions(ids []string) (int, error) {
 		}
 		n, _ := res.RowsAffected()
 		total += int(n)
+
+		// Sessions left behind must not point at a deleted
+		// parent.
+		if _, err := tx.Exec(
+			`UPDATE sessions SET parent_session_id = NULL
+			WHERE parent_session_id IN (`+placeholders+")",
+			args...,
+		); err != nil {
+			return 0, fmt.Errorf("clearing parent links: %w", err)
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -735,3 +1068,72 @@ func (db *DB) DeleteSessions(ids []string) (int, error) {
 	}
 	return total, nil
 }
+
+// LocalSessions returns the sessions synced on machine, resolved
+// through machine_aliases. Only their file paths refer to this
+// machine's disk; pushed and imported sessions keep the paths of
+// the machine they came from.
+func (db *DB) LocalSessions(
+	sessions []Session, machine string,
+) ([]Session, error) {
+	if machine == "" {
+		machine = "local"
+	}
+	var resolved string
+	err := db.getReader().QueryRow(`
+		SELECT COALESCE(
+			(SELECT target FROM machine_aliases WHERE name = ?),
+			?
+		)`, machine, machine,
+	).Scan(&resolved)
+	if err != nil {
+		return nil, fmt.Errorf("resolving machine %s: %w", machine, err)
+	}
+	var local []Session
+	for _, s := range sessions {
+		if s.Machine == resolved {
+			local = append(local, s)
+		}
+	}
+	return local, nil
+}
+
+// RemoveSessionFiles deletes the source files of sessions from
+// disk, along with their parent directory when it is left
+// empty. Missing files are skipped. Returns the number of files
+// removed and the bytes reclaimed.
+func RemoveSessionFiles(sessions []Session) (int, int64) {
+	removed := 0
+	var reclaimed int64
+
+	for _, s := range sessions {
+		if s.FilePath == nil {
+			continue
+		}
+		path := *s.FilePath
+
+		info, err := os.Stat(path)
+		size := int64(0)
+		if err == nil {
+			size = info.Size()
+		}
+
+		if err := os.Remove(path); err != nil {
+			if !os.IsNotExist(err) {
+				slog.Warn("removing session file",
+					"path", path, "err", err)
+			}
+			continue
+		}
+		removed++
+		reclaimed += size
+
+		// Remove parent directory if empty (session subdirs).
+		dir := filepath.Dir(path)
+		entries, err := os.ReadDir(dir)
+		if err == nil && len(entries) == 0 {
+			_ = os.Remove(dir)
+		}
+	}
+	return removed, reclaimed
+}
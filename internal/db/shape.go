@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// SessionShape summarizes what a parse stored for one session,
+// for comparing two parser versions without diffing content.
+type SessionShape struct {
+	ID               string `json:"id"`
+	FilePath         string `json:"file_path"`
+	Agent            string `json:"agent"`
+	MessageCount     int    `json:"message_count"`
+	UserMessageCount int    `json:"user_message_count"`
+	ToolCallCount    int    `json:"tool_call_count"`
+	// UnknownRecords counts source records the parser skipped.
+	UnknownRecords int `json:"unknown_records"`
+}
+
+// GetSessionShapes returns the shape of every session stored
+// from one of paths, keyed by session ID.
+func (db *DB) GetSessionShapes(
+	ctx context.Context, paths []string,
+) (map[string]SessionShape, error) {
+	shapes := map[string]SessionShape{}
+	err := queryChunked(paths, func(chunk []string) error {
+		ph, args := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT s.id, s.file_path, s.agent, s.message_count,
+				s.user_message_count,
+				(SELECT COUNT(*) FROM tool_calls t
+				 WHERE t.session_id = s.id),
+				(SELECT COALESCE(SUM(u.count), 0)
+				 FROM unknown_records u
+				 WHERE u.session_id = s.id)
+			FROM sessions s
+			WHERE s.file_path IN `+ph,
+			args...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying session shapes: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var s SessionShape
+			if err := rows.Scan(
+				&s.ID, &s.FilePath, &s.Agent, &s.MessageCount,
+				&s.UserMessageCount, &s.ToolCallCount,
+				&s.UnknownRecords,
+			); err != nil {
+				return fmt.Errorf("scanning session shape: %w", err)
+			}
+			shapes[s.ID] = s
+		}
+		return rows.Err()
+	})
+	return shapes, err
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidShareToken is returned for share tokens that are
+	// malformed or were not signed by this database.
+	ErrInvalidShareToken = errors.New("invalid share token")
+	// ErrShareExpired is returned for validly signed share
+	// tokens past their expiry.
+	ErrShareExpired = errors.New("share link expired")
+)
+
+// ShareLink is the payload of a session share token: read-only
+// access to one session until ExpiresAt (unix seconds).
+type ShareLink struct {
+	SessionID string `json:"s"`
+	ExpiresAt int64  `json:"x"`
+}
+
+// shareInput is the signing input for a share token. The
+// "share" prefix keeps share tokens from being accepted as
+// cursors or bulk tokens and vice versa.
+func shareInput(data []byte) func(hash.Hash) {
+	return func(h hash.Hash) {
+		h.Write([]byte("share\x00"))
+		h.Write(data)
+	}
+}
+
+// ShareToken returns a signed token granting read-only access
+// to sessionID until expires. Tokens are stateless: they stay
+// valid until expiry unless the cursor secret changes.
+func (db *DB) ShareToken(sessionID string, expires time.Time) string {
+	data, _ := json.Marshal(ShareLink{
+		SessionID: sessionID,
+		ExpiresAt: expires.Unix(),
+	})
+	return base64.RawURLEncoding.EncodeToString(data) + "." +
+		base64.RawURLEncoding.EncodeToString(db.sign(shareInput(data)))
+}
+
+// DecodeShareToken verifies a share token's signature and
+// expiry as of now.
+func (db *DB) DecodeShareToken(
+	token string, now time.Time,
+) (ShareLink, error) {
+	payload, sigStr, ok := strings.Cut(token, ".")
+	if !ok {
+		return ShareLink{}, fmt.Errorf("%w: invalid format", ErrInvalidShareToken)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ShareLink{}, fmt.Errorf("%w: invalid payload: %v", ErrInvalidShareToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigStr)
+	if err != nil {
+		return ShareLink{}, fmt.Errorf("%w: invalid signature encoding: %v", ErrInvalidShareToken, err)
+	}
+	if !db.verify(sig, shareInput(data)) {
+		return ShareLink{}, fmt.Errorf("%w: signature mismatch", ErrInvalidShareToken)
+	}
+
+	var link ShareLink
+	if err := json.Unmarshal(data, &link); err != nil {
+		return ShareLink{}, fmt.Errorf("%w: invalid json: %v", ErrInvalidShareToken, err)
+	}
+	if link.SessionID == "" {
+		return ShareLink{}, fmt.Errorf("%w: missing session", ErrInvalidShareToken)
+	}
+	if now.Unix() >= link.ExpiresAt {
+		return ShareLink{}, ErrShareExpired
+	}
+	return link, nil
+}
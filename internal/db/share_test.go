@@ -0,0 +1,46 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShareToken(t *testing.T) {
+	d := testDB(t)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	token := d.ShareToken("s1", now.Add(time.Hour))
+
+	link, err := d.DecodeShareToken(token, now)
+	requireNoError(t, err, "DecodeShareToken")
+	assertEq(t, "SessionID", link.SessionID, "s1")
+	assertEq(t, "ExpiresAt", link.ExpiresAt, now.Add(time.Hour).Unix())
+
+	_, err = d.DecodeShareToken(token, now.Add(time.Hour))
+	if !errors.Is(err, ErrShareExpired) {
+		t.Errorf("at expiry: err = %v, want ErrShareExpired", err)
+	}
+
+	payload, _, _ := strings.Cut(token, ".")
+	cursor := d.EncodeCursor("2024-06-01", "s1")
+	for name, bad := range map[string]string{
+		"Unsigned":    payload,
+		"Truncated":   token[:len(token)-2],
+		"OtherSecret": newTestDBToken(t, now),
+		"Cursor":      cursor,
+	} {
+		if _, err := d.DecodeShareToken(bad, now); !errors.Is(err, ErrInvalidShareToken) {
+			t.Errorf("%s: err = %v, want ErrInvalidShareToken", name, err)
+		}
+	}
+}
+
+// newTestDBToken signs a share token with a different database's
+// secret.
+func newTestDBToken(t *testing.T, now time.Time) string {
+	t.Helper()
+	other := testDB(t)
+	other.SetCursorSecret([]byte("another-secret"))
+	return other.ShareToken("s1", now.Add(time.Hour))
+}
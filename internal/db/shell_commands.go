@@ -0,0 +1,542 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Bounds for GetAnalyticsCommands.
+const (
+	DefaultCommandLimit = 20
+	MaxCommandLimit     = 100
+
+	// minFailureProneRuns is how often a command must have run
+	// to be ranked by failure rate; one failed run is noise.
+	minFailureProneRuns = 5
+	// projectTopCommands caps the commands listed per project.
+	projectTopCommands = 5
+)
+
+// commandPreludes only prepare the shell for the command that
+// follows them, so a chain starting with one is named by the
+// next.
+var commandPreludes = map[string]bool{
+	"cd": true, "pushd": true, "export": true, "unset": true,
+	"set": true, "source": true, ".": true,
+}
+
+// commandWrappers run the command after them, possibly with
+// flags or a duration of their own.
+var commandWrappers = map[string]bool{
+	"sudo": true, "env": true, "time": true, "nohup": true,
+	"exec": true, "command": true, "timeout": true, "nice": true,
+}
+
+// subcommandTools are named together with their subcommand,
+// since "git status" and "git push" are different things to run.
+var subcommandTools = map[string]bool{
+	"git": true, "go": true, "npm": true, "pnpm": true,
+	"yarn": true, "bun": true, "deno": true, "npx": true,
+	"bunx": true, "uv": true, "uvx": true, "pip": true,
+	"pip3": true, "poetry": true, "cargo": true, "make": true,
+	"just": true, "docker": true, "kubectl": true, "gh": true,
+	"mvn": true, "gradle": true, "dotnet": true, "bundle": true,
+	"rails": true, "terraform": true, "helm": true, "brew": true,
+}
+
+// scriptRunners name the script with "run", as in "npm run lint".
+var scriptRunners = map[string]bool{
+	"npm": true, "pnpm": true, "yarn": true, "bun": true,
+}
+
+// toolCallCommand returns the normalized command of a Bash
+// tool call, or "" for other calls.
+func toolCallCommand(category, inputJSON string) string {
+	if category != "Bash" || inputJSON == "" ||
+		!gjson.Valid(inputJSON) {
+		return ""
+	}
+	input := gjson.Parse(inputJSON)
+	cmd := input.Get("command")
+	if !cmd.Exists() {
+		cmd = input.Get("cmd")
+	}
+	if cmd.IsArray() {
+		// Codex passes argv, usually ["bash", "-lc", script].
+		argv := make([]string, 0, 3)
+		for _, a := range cmd.Array() {
+			argv = append(argv, a.String())
+		}
+		if len(argv) == 3 && (argv[1] == "-c" || argv[1] == "-lc") {
+			return normalizeCommand(argv[2])
+		}
+		return normalizeCommand(strings.Join(argv, " "))
+	}
+	return normalizeCommand(cmd.Str)
+}
+
+// normalizeCommand reduces a shell command line to what it
+// runs: the binary, plus the subcommand for tools such as git
+// or npm, with arguments, paths, and environment assignments
+// dropped. A chain is named by its first command that is not
+// just setup, so "cd web && npm run test -- --watch" is
+// "npm run test". It returns "" when nothing is run.
+func normalizeCommand(line string) string {
+	for _, words := range splitShellCommands(line) {
+		words = stripCommandPrefix(words)
+		if len(words) == 0 || commandPreludes[words[0]] {
+			continue
+		}
+		bin := words[0]
+		if i := strings.LastIndexByte(bin, '/'); i >= 0 {
+			bin = bin[i+1:]
+		}
+		if bin == "" || strings.HasPrefix(bin, "#") {
+			continue
+		}
+		parts := []string{bin}
+		args := words[1:]
+		switch {
+		case strings.HasPrefix(bin, "python") &&
+			len(args) >= 2 && args[0] == "-m":
+			parts = append(parts, "-m", args[1])
+		case subcommandTools[bin]:
+			sub := firstSubcommand(args)
+			if sub != "" {
+				parts = append(parts, sub)
+			}
+			if sub == "run" && scriptRunners[bin] {
+				if script := firstSubcommand(args[1:]); script != "" {
+					parts = append(parts, script)
+				}
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+	return ""
+}
+
+// firstSubcommand returns the first argument that reads as a
+// subcommand name, skipping flags and the value after -C or -f.
+func firstSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if strings.HasPrefix(a, "-") {
+			if a == "-C" || a == "-f" || a == "-c" {
+				i++
+			}
+			continue
+		}
+		if isSubcommandName(a) {
+			return a
+		}
+		return ""
+	}
+	return ""
+}
+
+func isSubcommandName(s string) bool {
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' ||
+			r == '-' || r == '_' || r == ':'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// stripCommandPrefix drops leading environment assignments and
+// wrapper commands, with the wrappers' own flags and durations.
+func stripCommandPrefix(words []string) []string {
+	wrapped := false
+	for len(words) > 0 {
+		w := words[0]
+		switch {
+		case isEnvAssignment(w):
+		case commandWrappers[w]:
+			wrapped = true
+		case wrapped && (strings.HasPrefix(w, "-") ||
+			w[0] >= '0' && w[0] <= '9'):
+		default:
+			return words
+		}
+		words = words[1:]
+	}
+	return nil
+}
+
+func isEnvAssignment(w string) bool {
+	name, _, ok := strings.Cut(w, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !(r >= 'A' && r <= 'Z') &&
+			!(r >= 'a' && r <= 'z') && !(i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// splitShellCommands splits a command line into the words of
+// each command it chains with newlines, ;, &&, ||, |, or &.
+// Quotes group words and are removed; a # starting a word
+// comments out the rest of the line.
+func splitShellCommands(line string) [][]string {
+	var (
+		cmds    [][]string
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		quote   rune
+		comment bool
+	)
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCmd := func() {
+		endWord()
+		if len(words) > 0 {
+			cmds = append(cmds, words)
+			words = nil
+		}
+	}
+	for _, r := range line {
+		switch {
+		case comment:
+			if r == '\n' {
+				comment = false
+				endCmd()
+			}
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '#' && !inWord:
+			comment = true
+		case r == '\n' || r == ';' || r == '&' || r == '|':
+			endCmd()
+		case r == ' ' || r == '\t' || r == '\\':
+			endWord()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endCmd()
+	return cmds
+}
+
+// backfillToolCallCommands sets command for Bash tool calls
+// stored before the column existed.
+func backfillToolCallCommands(w *sql.DB) error {
+	rows, err := w.Query(`
+		SELECT id, input_json FROM tool_calls
+		WHERE category = 'Bash' AND input_json IS NOT NULL`)
+	if err != nil {
+		return fmt.Errorf("querying bash tool calls: %w", err)
+	}
+	commands := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var input string
+		if err := rows.Scan(&id, &input); err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning bash tool call: %w", err)
+		}
+		if c := toolCallCommand("Bash", input); c != "" {
+			commands[id] = c
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterating bash tool calls: %w", err)
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+
+	tx, err := w.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(
+		"UPDATE tool_calls SET command = ? WHERE id = ?",
+	)
+	if err != nil {
+		return fmt.Errorf("preparing command backfill: %w", err)
+	}
+	defer stmt.Close()
+	for id, c := range commands {
+		if _, err := stmt.Exec(c, id); err != nil {
+			return fmt.Errorf("backfilling command: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// CommandStats is how often one normalized command ran and how
+// often its tool call reported an error.
+type CommandStats struct {
+	Command string `json:"command"`
+	// Kind is "test", "build", or "git" for commands that run
+	// a test suite, a build, or git; empty otherwise.
+	Kind        string  `json:"kind,omitempty"`
+	Runs        int     `json:"runs"`
+	Sessions    int     `json:"sessions"`
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failure_rate"` // % of runs
+}
+
+// CommandCount is one command's runs within a project.
+type CommandCount struct {
+	Command string `json:"command"`
+	Runs    int    `json:"runs"`
+}
+
+// ProjectCommandMix breaks a project's command runs down by
+// kind, with how many of its sessions ran the tests at all.
+type ProjectCommandMix struct {
+	Project      string         `json:"project"`
+	Sessions     int            `json:"sessions"`
+	TestSessions int            `json:"test_sessions"`
+	Runs         int            `json:"runs"`
+	TestRuns     int            `json:"test_runs"`
+	BuildRuns    int            `json:"build_runs"`
+	GitRuns      int            `json:"git_runs"`
+	Top          []CommandCount `json:"top"`
+}
+
+// CommandsAnalyticsResponse reports the shell commands agents
+// ran. Sessions counts every matching session and
+// TestSessions those that ran a test suite.
+type CommandsAnalyticsResponse struct {
+	Sessions     int                 `json:"sessions"`
+	TestSessions int                 `json:"test_sessions"`
+	Runs         int                 `json:"runs"`
+	Commands     []CommandStats      `json:"commands"`
+	FailureProne []CommandStats      `json:"failure_prone"`
+	Projects     []ProjectCommandMix `json:"projects"`
+}
+
+// commandKind classifies a normalized command the way tool
+// sequences classify Bash steps.
+func commandKind(command string) string {
+	step := sequenceStep("Bash", command)
+	if step == "Bash" {
+		return ""
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(step, "Bash("), ")")
+}
+
+// GetAnalyticsCommands reports the most-run commands, the
+// commands that fail most often, and each project's command
+// mix, from Bash tool calls of the matching sessions. Failures
+// count calls whose tool reported an error, which not every
+// agent records.
+func (db *DB) GetAnalyticsCommands(
+	ctx context.Context, f AnalyticsFilter, limit int,
+) (CommandsAnalyticsResponse, error) {
+	if limit <= 0 {
+		limit = DefaultCommandLimit
+	}
+	limit = min(limit, MaxCommandLimit)
+
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return CommandsAnalyticsResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, project
+		FROM sessions WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return CommandsAnalyticsResponse{},
+			fmt.Errorf("querying command sessions: %w", err)
+	}
+	defer rows.Close()
+
+	projectOf := map[string]string{}
+	projects := map[string]*ProjectCommandMix{}
+	var ids []string
+	for rows.Next() {
+		var id, ts, project string
+		if err := rows.Scan(&id, &ts, &project); err != nil {
+			return CommandsAnalyticsResponse{},
+				fmt.Errorf("scanning command session: %w", err)
+		}
+		if !inDateRange(localDate(ts, loc), f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		projectOf[id] = project
+		ids = append(ids, id)
+		p, ok := projects[project]
+		if !ok {
+			p = &ProjectCommandMix{Project: project}
+			projects[project] = p
+		}
+		p.Sessions++
+	}
+	if err := rows.Err(); err != nil {
+		return CommandsAnalyticsResponse{},
+			fmt.Errorf("iterating command sessions: %w", err)
+	}
+
+	resp := CommandsAnalyticsResponse{
+		Sessions:     len(ids),
+		Commands:     []CommandStats{},
+		FailureProne: []CommandStats{},
+		Projects:     []ProjectCommandMix{},
+	}
+	commands := map[string]*CommandStats{}
+	projectRuns := map[string]map[string]int{}
+	testSessions := map[string]bool{}
+	err = queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, command, COUNT(*), SUM(result_error)
+			FROM tool_calls
+			WHERE session_id IN `+ph+` AND command IS NOT NULL
+			GROUP BY session_id, command`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying commands: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sid, command string
+			var runs, failures int
+			if err := rows.Scan(
+				&sid, &command, &runs, &failures,
+			); err != nil {
+				return fmt.Errorf("scanning command: %w", err)
+			}
+			c, ok := commands[command]
+			if !ok {
+				c = &CommandStats{
+					Command: command, Kind: commandKind(command),
+				}
+				commands[command] = c
+			}
+			c.Runs += runs
+			c.Sessions++
+			c.Failures += failures
+
+			project := projectOf[sid]
+			p := projects[project]
+			p.Runs += runs
+			switch c.Kind {
+			case "test":
+				p.TestRuns += runs
+				testSessions[sid] = true
+			case "build":
+				p.BuildRuns += runs
+			case "git":
+				p.GitRuns += runs
+			}
+			if projectRuns[project] == nil {
+				projectRuns[project] = map[string]int{}
+			}
+			projectRuns[project][command] += runs
+			resp.Runs += runs
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return CommandsAnalyticsResponse{}, err
+	}
+
+	resp.TestSessions = len(testSessions)
+	for sid := range testSessions {
+		projects[projectOf[sid]].TestSessions++
+	}
+
+	for _, c := range commands {
+		c.FailureRate = round1(
+			float64(c.Failures) / float64(c.Runs) * 100,
+		)
+		resp.Commands = append(resp.Commands, *c)
+		if c.Failures > 0 && c.Runs >= minFailureProneRuns {
+			resp.FailureProne = append(resp.FailureProne, *c)
+		}
+	}
+	sort.Slice(resp.Commands, func(i, j int) bool {
+		a, b := resp.Commands[i], resp.Commands[j]
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.Command < b.Command
+	})
+	sort.Slice(resp.FailureProne, func(i, j int) bool {
+		a, b := resp.FailureProne[i], resp.FailureProne[j]
+		if a.FailureRate != b.FailureRate {
+			return a.FailureRate > b.FailureRate
+		}
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.Command < b.Command
+	})
+	resp.Commands = resp.Commands[:min(limit, len(resp.Commands))]
+	resp.FailureProne = resp.FailureProne[:min(limit, len(resp.FailureProne))]
+
+	for project, p := range projects {
+		if p.Runs == 0 {
+			continue
+		}
+		p.Top = []CommandCount{}
+		for command, runs := range projectRuns[project] {
+			p.Top = append(p.Top, CommandCount{command, runs})
+		}
+		sort.Slice(p.Top, func(i, j int) bool {
+			a, b := p.Top[i], p.Top[j]
+			if a.Runs != b.Runs {
+				return a.Runs > b.Runs
+			}
+			return a.Command < b.Command
+		})
+		p.Top = p.Top[:min(projectTopCommands, len(p.Top))]
+		resp.Projects = append(resp.Projects, *p)
+	}
+	sort.Slice(resp.Projects, func(i, j int) bool {
+		a, b := resp.Projects[i], resp.Projects[j]
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.Project < b.Project
+	})
+	return resp, nil
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestNormalizeCommand(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"go test ./internal/db -run TestFoo", "go test"},
+		{"cd web && npm run test -- --watch", "npm run test"},
+		{"git -C repo status --short", "git status"},
+		{"CGO_ENABLED=1 go build -tags fts5 ./...", "go build"},
+		{"sudo -E timeout 30 /usr/bin/make build", "make build"},
+		{"python3 -m pytest tests/ -x", "python3 -m pytest"},
+		{"ls -la | head", "ls"},
+		{"# list files\nls", "ls"},
+		{`echo "a && b"; rg foo`, "echo"},
+		{"./scripts/check.sh --fast", "check.sh"},
+		{"go ./...", "go"},
+		{"export FOO=1; cd /tmp", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assertEq(t, tt.line, normalizeCommand(tt.line), tt.want)
+	}
+}
+
+func TestToolCallCommand(t *testing.T) {
+	tests := []struct {
+		category, input, want string
+	}{
+		{"Bash", `{"command":"pytest -q"}`, "pytest"},
+		{
+			"Bash", `{"command":["bash","-lc","cd x && cargo test"]}`,
+			"cargo test",
+		},
+		{"Bash", `{"command":["git","diff"]}`, "git diff"},
+		{"Bash", `{"cmd":"npm install left-pad"}`, "npm install"},
+		{"Bash", `not json`, ""},
+		{"Read", `{"command":"ls"}`, ""},
+	}
+	for _, tt := range tests {
+		assertEq(t, tt.input,
+			toolCallCommand(tt.category, tt.input), tt.want)
+	}
+}
+
+// bashMsg returns an assistant message running each command,
+// with the calls at the given indexes failing.
+func bashMsg(
+	sid string, ordinal int, cmds []string, failed ...int,
+) Message {
+	m := asstMsg(sid, ordinal, "[Bash]")
+	m.HasToolUse = true
+	for i, c := range cmds {
+		m.ToolCalls = append(m.ToolCalls, ToolCall{
+			SessionID: sid,
+			ToolName:  "Bash",
+			Category:  "Bash",
+			ToolUseID: fmt.Sprintf("%s-%d-%d", sid, ordinal, i),
+			InputJSON: fmt.Sprintf(`{"command":%q}`, c),
+		})
+	}
+	for _, i := range failed {
+		m.ToolCalls[i].ResultError = true
+	}
+	return m
+}
+
+func TestGetAnalyticsCommands(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	for _, s := range []struct{ id, project string }{
+		{"a", "alpha"}, {"b", "alpha"}, {"c", "beta"},
+	} {
+		insertSession(t, d, s.id, s.project, func(sess *Session) {
+			sess.StartedAt = Ptr("2024-06-03T09:00:00Z")
+		})
+	}
+	insertMessages(t, d,
+		bashMsg("a", 0, []string{
+			"go test ./...", "go test ./db", "go test -run X ./db",
+			"git status", "go build ./...",
+		}, 0, 1),
+		bashMsg("a", 1, []string{"go test ./...", "ls"}, 0),
+		bashMsg("b", 0, []string{"git status", "ls"}),
+		bashMsg("c", 0, []string{"make build", "ls"}, 1),
+	)
+
+	msgs, err := d.GetAllMessages(ctx, "a")
+	requireNoError(t, err, "GetAllMessages")
+	assertEq(t, "stored command",
+		msgs[0].ToolCalls[2].Command, "go test")
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsCommands(ctx, f, 0)
+	requireNoError(t, err, "GetAnalyticsCommands")
+	assertEq(t, "sessions", resp.Sessions, 3)
+	assertEq(t, "test sessions", resp.TestSessions, 1)
+	assertEq(t, "runs", resp.Runs, 11)
+	if len(resp.Commands) != 5 {
+		t.Fatalf("Commands = %+v, want 5", resp.Commands)
+	}
+	assertEq(t, "top command", resp.Commands[0], CommandStats{
+		Command: "go test", Kind: "test", Runs: 4, Sessions: 1,
+		Failures: 3, FailureRate: 75,
+	})
+	assertEq(t, "second command", resp.Commands[1].Command, "ls")
+	if len(resp.FailureProne) != 0 {
+		t.Errorf("FailureProne = %+v, want none under %d runs",
+			resp.FailureProne, minFailureProneRuns)
+	}
+
+	if len(resp.Projects) != 2 {
+		t.Fatalf("Projects = %+v, want 2", resp.Projects)
+	}
+	alpha := resp.Projects[0]
+	assertEq(t, "alpha project", alpha.Project, "alpha")
+	assertEq(t, "alpha runs", alpha.Runs, 9)
+	assertEq(t, "alpha test runs", alpha.TestRuns, 4)
+	assertEq(t, "alpha build runs", alpha.BuildRuns, 1)
+	assertEq(t, "alpha git runs", alpha.GitRuns, 2)
+	assertEq(t, "alpha test sessions", alpha.TestSessions, 1)
+	assertEq(t, "alpha sessions", alpha.Sessions, 2)
+	assertEq(t, "beta build runs", resp.Projects[1].BuildRuns, 1)
+
+	insertMessages(t, d, bashMsg("b", 1, []string{
+		"go test ./...", "go test ./...",
+	}))
+	resp, err = d.GetAnalyticsCommands(ctx, f, 1)
+	requireNoError(t, err, "GetAnalyticsCommands limit")
+	if len(resp.Commands) != 1 || len(resp.FailureProne) != 1 {
+		t.Fatalf("limited = %+v", resp)
+	}
+	assertEq(t, "failure prone",
+		resp.FailureProne[0].FailureRate, 50.0)
+	assertEq(t, "test sessions after b", resp.TestSessions, 2)
+}
+
+func TestBackfillToolCallCommands(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "s", "alpha")
+	insertMessages(t, d, bashMsg("s", 0, []string{"cargo check"}))
+	_, err := d.getWriter().Exec("UPDATE tool_calls SET command = NULL")
+	requireNoError(t, err, "clear command")
+
+	requireNoError(t,
+		backfillToolCallCommands(d.getWriter()), "backfill")
+	var command string
+	err = d.getReader().QueryRow(
+		"SELECT command FROM tool_calls",
+	).Scan(&command)
+	requireNoError(t, err, "read command")
+	assertEq(t, "backfilled", command, "cargo check")
+}
@@ -0,0 +1,300 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Kinds of invocation GetSkillUsage reports on.
+const (
+	// UsageKindSkill counts Skill tool calls by skill name.
+	UsageKindSkill = "skill"
+	// UsageKindCommand counts slash commands by command name.
+	UsageKindCommand = "command"
+)
+
+// ErrInvalidUsageKind is returned for kinds other than
+// UsageKindSkill and UsageKindCommand.
+var ErrInvalidUsageKind = errors.New(
+	"kind must be skill or command",
+)
+
+// SkillUsageWeek is the invocation count for one ISO week.
+type SkillUsageWeek struct {
+	Week        string `json:"week"` // Monday, YYYY-MM-DD
+	Invocations int    `json:"invocations"`
+}
+
+// SkillUsage summarizes how one skill or slash command is used
+// across sessions in the filter range, so unused entries in a
+// skills or commands library can be found and retired.
+type SkillUsage struct {
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	Invocations int    `json:"invocations"`
+	Sessions    int    `json:"sessions"`
+	Machines    int    `json:"machines"`
+	Projects    int    `json:"projects"`
+	FirstUsed   string `json:"first_used,omitempty"`
+	LastUsed    string `json:"last_used,omitempty"`
+
+	// AvgMessagesAfter is the mean number of messages following
+	// the first invocation in each invoking session, and
+	// InterruptRate the percentage of invoking sessions the
+	// user interrupted.
+	AvgMessagesAfter float64 `json:"avg_messages_after"`
+	InterruptRate    float64 `json:"interrupt_rate"`
+
+	Agents map[string]int `json:"agents"`
+	// Trend has one entry per week of the range, zeros
+	// included, so a decline in use is visible.
+	Trend      []SkillUsageWeek      `json:"trend"`
+	SessionIDs *ContributingSessions `json:"session_ids,omitempty"`
+}
+
+// GetSkillUsage returns usage statistics for the skill or
+// slash command name, depending on kind.
+func (db *DB) GetSkillUsage(
+	ctx context.Context, f AnalyticsFilter, kind, name string,
+) (SkillUsage, error) {
+	var invQ string
+	switch kind {
+	case UsageKindSkill:
+		invQ = `SELECT tc.session_id, COALESCE(m.timestamp, '')
+			FROM tool_calls tc
+			JOIN messages m ON m.id = tc.message_id
+			WHERE tc.skill_name = ?`
+	case UsageKindCommand:
+		invQ = `SELECT session_id, COALESCE(timestamp, '')
+			FROM session_commands WHERE name = ?`
+	default:
+		return SkillUsage{}, ErrInvalidUsageKind
+	}
+
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return SkillUsage{}, err
+		}
+	}
+
+	type sessInfo struct {
+		date, machine, project, agent string
+		interrupted                   bool
+	}
+	sessions := make(map[string]sessInfo)
+
+	sessQ := `SELECT id, ` + dateCol + `, machine, project, agent,
+		interrupt_count
+		FROM sessions WHERE ` + where
+	rows, err := db.getReader().QueryContext(ctx, sessQ, args...)
+	if err != nil {
+		return SkillUsage{},
+			fmt.Errorf("querying skill usage sessions: %w", err)
+	}
+	for rows.Next() {
+		var id, ts string
+		var si sessInfo
+		var interrupts int
+		if err := rows.Scan(
+			&id, &ts, &si.machine, &si.project, &si.agent,
+			&interrupts,
+		); err != nil {
+			rows.Close()
+			return SkillUsage{},
+				fmt.Errorf("scanning skill usage session: %w", err)
+		}
+		si.date = localDate(ts, loc)
+		if !inDateRange(si.date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		si.interrupted = interrupts > 0
+		sessions[id] = si
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return SkillUsage{},
+			fmt.Errorf("iterating skill usage sessions: %w", err)
+	}
+
+	usage := SkillUsage{
+		Kind:       kind,
+		Name:       name,
+		Agents:     make(map[string]int),
+		Trend:      usageWeeks(f.From, f.To),
+		SessionIDs: f.contributors(),
+	}
+	weekIdx := make(map[string]int, len(usage.Trend))
+	for i, w := range usage.Trend {
+		weekIdx[w.Week] = i
+	}
+
+	rows, err = db.getReader().QueryContext(ctx, invQ, name)
+	if err != nil {
+		return SkillUsage{},
+			fmt.Errorf("querying %s invocations: %w", kind, err)
+	}
+	// firstAt is the earliest invocation timestamp per
+	// session; empty when the session's invocations carry none.
+	firstAt := make(map[string]string)
+	machines := make(map[string]bool)
+	projects := make(map[string]bool)
+	for rows.Next() {
+		var sid, ts string
+		if err := rows.Scan(&sid, &ts); err != nil {
+			rows.Close()
+			return SkillUsage{},
+				fmt.Errorf("scanning %s invocation: %w", kind, err)
+		}
+		si, ok := sessions[sid]
+		if !ok {
+			continue
+		}
+		date := si.date
+		if ts != "" {
+			date = localDate(ts, loc)
+		}
+		usage.Invocations++
+		if i, ok := weekIdx[bucketDate(date, "week")]; ok {
+			usage.Trend[i].Invocations++
+		}
+		if usage.FirstUsed == "" || date < usage.FirstUsed {
+			usage.FirstUsed = date
+		}
+		if date > usage.LastUsed {
+			usage.LastUsed = date
+		}
+
+		prev, seen := firstAt[sid]
+		if !seen {
+			usage.Agents[si.agent]++
+			machines[si.machine] = true
+			projects[si.project] = true
+			usage.SessionIDs.add(sid)
+		}
+		if !seen || (ts != "" && (prev == "" || ts < prev)) {
+			firstAt[sid] = ts
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return SkillUsage{},
+			fmt.Errorf("iterating %s invocations: %w", kind, err)
+	}
+
+	usage.Sessions = len(firstAt)
+	usage.Machines = len(machines)
+	usage.Projects = len(projects)
+	if usage.Sessions == 0 {
+		return usage, nil
+	}
+
+	interrupted := 0
+	for sid := range firstAt {
+		if sessions[sid].interrupted {
+			interrupted++
+		}
+	}
+	usage.InterruptRate = math.Round(
+		float64(interrupted)/float64(usage.Sessions)*1000,
+	) / 10
+
+	after, err := db.messagesAfter(ctx, firstAt)
+	if err != nil {
+		return SkillUsage{}, err
+	}
+	if len(after) > 0 {
+		total := 0
+		for _, n := range after {
+			total += n
+		}
+		usage.AvgMessagesAfter = math.Round(
+			float64(total)/float64(len(after))*10,
+		) / 10
+	}
+	return usage, nil
+}
+
+// messagesAfter counts, per session, the messages recorded
+// after the given timestamp. Sessions with an empty timestamp
+// are left out.
+func (db *DB) messagesAfter(
+	ctx context.Context, since map[string]string,
+) (map[string]int, error) {
+	ids := make([]string, 0, len(since))
+	for sid, ts := range since {
+		if ts != "" {
+			ids = append(ids, sid)
+		}
+	}
+
+	counts := make(map[string]int, len(ids))
+	// Two bind variables per session.
+	for i := 0; i < len(ids); i += maxSQLVars / 2 {
+		chunk := ids[i:min(i+maxSQLVars/2, len(ids))]
+		values := make([]string, len(chunk))
+		args := make([]any, 0, 2*len(chunk))
+		for j, sid := range chunk {
+			values[j] = "(?, ?)"
+			args = append(args, sid, since[sid])
+		}
+		q := `WITH since(sid, ts) AS (VALUES ` +
+			strings.Join(values, ", ") + `)
+			SELECT s.sid, COUNT(m.id)
+			FROM since s
+			LEFT JOIN messages m
+				ON m.session_id = s.sid AND m.timestamp > s.ts
+			GROUP BY s.sid`
+		rows, err := db.getReader().QueryContext(ctx, q, args...)
+		if err != nil {
+			return nil, fmt.Errorf("counting messages after: %w", err)
+		}
+		for rows.Next() {
+			var sid string
+			var n int
+			if err := rows.Scan(&sid, &n); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scanning messages after: %w", err)
+			}
+			counts[sid] = n
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("iterating messages after: %w", err)
+		}
+	}
+	return counts, nil
+}
+
+// usageWeeks returns a zeroed entry for every ISO week
+// touching [from, to].
+func usageWeeks(from, to string) []SkillUsageWeek {
+	weeks := []SkillUsageWeek{}
+	start, err := time.Parse("2006-01-02", bucketDate(from, "week"))
+	if err != nil {
+		return weeks
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return weeks
+	}
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 7) {
+		weeks = append(weeks, SkillUsageWeek{
+			Week: d.Format("2006-01-02"),
+		})
+	}
+	return weeks
+}
@@ -35,49 +35,48 @@ func (db *DB) LoadSkippedFiles() (map[string]int64, error) {
 func (db *DB) ReplaceSkippedFiles(
 	entries map[string]int64,
 ) error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-
-	tx, err := db.getWriter().Begin()
-	if err != nil {
-		return fmt.Errorf("begin: %w", err)
-	}
-	defer func() { _ = tx.Rollback() }()
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("begin: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
 
-	if _, err := tx.Exec(
-		"DELETE FROM skipped_files",
-	); err != nil {
-		return fmt.Errorf("clearing skipped files: %w", err)
-	}
+		if _, err := tx.Exec(
+			"DELETE FROM skipped_files",
+		); err != nil {
+			return fmt.Errorf("clearing skipped files: %w", err)
+		}
 
-	stmt, err := tx.Prepare(
-		"INSERT INTO skipped_files" +
-			" (file_path, file_mtime) VALUES (?, ?)",
-	)
-	if err != nil {
-		return fmt.Errorf("prepare: %w", err)
-	}
-	defer stmt.Close()
+		stmt, err := tx.Prepare(
+			"INSERT INTO skipped_files" +
+				" (file_path, file_mtime) VALUES (?, ?)",
+		)
+		if err != nil {
+			return fmt.Errorf("prepare: %w", err)
+		}
+		defer stmt.Close()
 
-	for path, mtime := range entries {
-		if _, err := stmt.Exec(path, mtime); err != nil {
-			return fmt.Errorf(
-				"inserting skipped file %s: %w",
-				path, err,
-			)
+		for path, mtime := range entries {
+			if _, err := stmt.Exec(path, mtime); err != nil {
+				return fmt.Errorf(
+					"inserting skipped file %s: %w",
+					path, err,
+				)
+			}
 		}
-	}
 
-	return tx.Commit()
+		return tx.Commit()
+	})
 }
 
 // DeleteSkippedFile removes a single skip cache entry.
 func (db *DB) DeleteSkippedFile(path string) error {
-	db.mu.Lock()
-	defer db.mu.Unlock()
-	_, err := db.getWriter().Exec(
-		"DELETE FROM skipped_files WHERE file_path = ?",
-		path,
-	)
-	return err
+	return db.write(func() error {
+		_, err := db.getWriter().Exec(
+			"DELETE FROM skipped_files WHERE file_path = ?",
+			path,
+		)
+		return err
+	})
 }
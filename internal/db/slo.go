@@ -0,0 +1,280 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/wesm/agentsview/internal/slo"
+)
+
+// SLO grouping dimensions.
+const (
+	SLOGroupByAgent = "agent"
+	SLOGroupByModel = "model"
+)
+
+// SLOTarget is a latency objective: the given percentile of
+// Metric should be at or below ThresholdSec.
+type SLOTarget struct {
+	Name         string
+	Metric       string
+	Percentile   float64 // 0-100, e.g. 90 for p90
+	ThresholdSec float64
+}
+
+// SLOPoint is SLO attainment over one set of samples.
+type SLOPoint struct {
+	Date      string  `json:"date,omitempty"`
+	Samples   int     `json:"samples"`
+	ValueSec  float64 `json:"value_sec"`  // observed percentile
+	WithinPct float64 `json:"within_pct"` // % of samples within threshold
+	Met       bool    `json:"met"`
+}
+
+// SLOGroup is SLO attainment for one agent or model.
+type SLOGroup struct {
+	Label   string     `json:"label"`
+	Overall SLOPoint   `json:"overall"`
+	Series  []SLOPoint `json:"series"`
+}
+
+// SLOReport is attainment for one SLO target.
+type SLOReport struct {
+	Name         string     `json:"name"`
+	Metric       string     `json:"metric"`
+	Percentile   float64    `json:"percentile"`
+	ThresholdSec float64    `json:"threshold_sec"`
+	Overall      SLOPoint   `json:"overall"`
+	Attainment   float64    `json:"attainment"` // % of buckets met
+	Series       []SLOPoint `json:"series"`
+	Groups       []SLOGroup `json:"groups"`
+}
+
+// SLOResponse wraps reports for all configured SLOs.
+type SLOResponse struct {
+	Granularity string      `json:"granularity"`
+	GroupBy     string      `json:"group_by"`
+	SLOs        []SLOReport `json:"slos"`
+}
+
+// sloSample is one latency observation.
+type sloSample struct {
+	bucket string
+	group  string
+	sec    float64
+}
+
+// GetAnalyticsSLO reports attainment of each target over time,
+// bucketed by session start date and broken down by agent or by
+// the model that produced the reply.
+func (db *DB) GetAnalyticsSLO(
+	ctx context.Context, f AnalyticsFilter,
+	targets []SLOTarget, granularity, groupBy string,
+) (SLOResponse, error) {
+	if granularity == "" {
+		granularity = "day"
+	}
+	if groupBy == "" {
+		groupBy = SLOGroupByAgent
+	}
+	resp := SLOResponse{
+		Granularity: granularity,
+		GroupBy:     groupBy,
+		SLOs:        []SLOReport{},
+	}
+	if len(targets) == 0 {
+		return resp, nil
+	}
+
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return SLOResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, agent
+		FROM sessions WHERE `+where, args...,
+	)
+	if err != nil {
+		return SLOResponse{},
+			fmt.Errorf("querying slo sessions: %w", err)
+	}
+	defer rows.Close()
+
+	type sessInfo struct {
+		bucket string
+		agent  string
+	}
+	sessionMap := make(map[string]sessInfo)
+	var sessionIDs []string
+	for rows.Next() {
+		var id, ts, agent string
+		if err := rows.Scan(&id, &ts, &agent); err != nil {
+			return SLOResponse{},
+				fmt.Errorf("scanning slo session: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		sessionMap[id] = sessInfo{
+			bucket: bucketDate(date, granularity),
+			agent:  agent,
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return SLOResponse{},
+			fmt.Errorf("iterating slo sessions: %w", err)
+	}
+
+	sessionMsgs := make(map[string][]velocityMsg)
+	err = queryChunked(sessionIDs, func(chunk []string) error {
+		return db.queryVelocityMsgs(ctx, chunk, loc, sessionMsgs)
+	})
+	if err != nil {
+		return SLOResponse{}, err
+	}
+
+	groupOf := func(info sessInfo, reply velocityMsg) string {
+		label := info.agent
+		if groupBy == SLOGroupByModel {
+			label = reply.model
+		}
+		if label == "" {
+			return "unknown"
+		}
+		return label
+	}
+
+	samples := map[string][]sloSample{}
+	for _, sid := range sessionIDs {
+		info := sessionMap[sid]
+		msgs := sessionMsgs[sid]
+		if sec, reply, ok := firstResponse(msgs); ok {
+			samples[slo.MetricFirstResponse] = append(
+				samples[slo.MetricFirstResponse], sloSample{
+					bucket: info.bucket,
+					group:  groupOf(info, reply),
+					sec:    sec,
+				})
+		}
+		eachTurnCycle(msgs, func(sec float64, reply velocityMsg) {
+			samples[slo.MetricTurnCycle] = append(
+				samples[slo.MetricTurnCycle], sloSample{
+					bucket: info.bucket,
+					group:  groupOf(info, reply),
+					sec:    sec,
+				})
+		})
+	}
+
+	for _, t := range targets {
+		resp.SLOs = append(resp.SLOs,
+			buildSLOReport(t, samples[t.Metric]))
+	}
+	return resp, nil
+}
+
+// buildSLOReport aggregates samples into overall, per-bucket,
+// and per-group attainment for one target.
+func buildSLOReport(t SLOTarget, samples []sloSample) SLOReport {
+	var all []float64
+	byBucket := map[string][]float64{}
+	byGroup := map[string][]float64{}
+	byGroupBucket := map[string]map[string][]float64{}
+	for _, s := range samples {
+		all = append(all, s.sec)
+		byBucket[s.bucket] = append(byBucket[s.bucket], s.sec)
+		byGroup[s.group] = append(byGroup[s.group], s.sec)
+		if byGroupBucket[s.group] == nil {
+			byGroupBucket[s.group] = map[string][]float64{}
+		}
+		byGroupBucket[s.group][s.bucket] = append(
+			byGroupBucket[s.group][s.bucket], s.sec,
+		)
+	}
+
+	r := SLOReport{
+		Name:         t.Name,
+		Metric:       t.Metric,
+		Percentile:   t.Percentile,
+		ThresholdSec: t.ThresholdSec,
+		Overall:      sloPoint(t, "", all),
+		Series:       sloSeries(t, byBucket),
+		Groups:       make([]SLOGroup, 0, len(byGroup)),
+	}
+
+	met := 0
+	for _, p := range r.Series {
+		if p.Met {
+			met++
+		}
+	}
+	if len(r.Series) > 0 {
+		r.Attainment = math.Round(
+			float64(met)/float64(len(r.Series))*1000) / 10
+	}
+
+	labels := make([]string, 0, len(byGroup))
+	for k := range byGroup {
+		labels = append(labels, k)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		r.Groups = append(r.Groups, SLOGroup{
+			Label:   label,
+			Overall: sloPoint(t, "", byGroup[label]),
+			Series:  sloSeries(t, byGroupBucket[label]),
+		})
+	}
+	return r
+}
+
+// sloSeries returns one point per bucket, ordered by date.
+func sloSeries(
+	t SLOTarget, byBucket map[string][]float64,
+) []SLOPoint {
+	dates := make([]string, 0, len(byBucket))
+	for d := range byBucket {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+	series := make([]SLOPoint, 0, len(dates))
+	for _, d := range dates {
+		series = append(series, sloPoint(t, d, byBucket[d]))
+	}
+	return series
+}
+
+// sloPoint computes attainment over vals, which it sorts in
+// place.
+func sloPoint(t SLOTarget, date string, vals []float64) SLOPoint {
+	p := SLOPoint{Date: date, Samples: len(vals)}
+	if len(vals) == 0 {
+		return p
+	}
+	sort.Float64s(vals)
+	value := percentileFloat(vals, t.Percentile/100)
+	within := sort.Search(len(vals), func(i int) bool {
+		return vals[i] > t.ThresholdSec
+	})
+	p.ValueSec = math.Round(value*10) / 10
+	p.WithinPct = math.Round(
+		float64(within)/float64(len(vals))*1000) / 10
+	p.Met = value <= t.ThresholdSec
+	return p
+}
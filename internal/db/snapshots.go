@@ -0,0 +1,254 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AnalyticsSnapshot is a frozen copy of the analytics responses
+// for one filter. Data maps endpoint names (as in
+// /api/v1/analytics/<name>) to their JSON responses and is
+// omitted from listings.
+type AnalyticsSnapshot struct {
+	ID         int64                      `json:"id"`
+	Name       string                     `json:"name"`
+	Filter     AnalyticsFilter            `json:"filter"`
+	Data       map[string]json.RawMessage `json:"data,omitempty"`
+	CreatedAt  string                     `json:"created_at"`
+	ImportedAt *string                    `json:"imported_at,omitempty"`
+}
+
+const maxSnapshotNameLen = 200
+
+// ValidateSnapshot checks a snapshot before it is stored.
+func ValidateSnapshot(s AnalyticsSnapshot) error {
+	name := strings.TrimSpace(s.Name)
+	if name == "" {
+		return errors.New("name is required")
+	}
+	if len(name) > maxSnapshotNameLen {
+		return fmt.Errorf(
+			"name must be at most %d characters", maxSnapshotNameLen,
+		)
+	}
+	if len(s.Data) == 0 {
+		return errors.New("snapshot has no data")
+	}
+	return nil
+}
+
+// CaptureAnalytics runs the standard analytics queries for f and
+// returns their responses keyed by endpoint name.
+func (db *DB) CaptureAnalytics(
+	ctx context.Context, f AnalyticsFilter,
+) (map[string]json.RawMessage, error) {
+	sections := []struct {
+		name string
+		get  func() (any, error)
+	}{
+		{"summary", func() (any, error) {
+			return db.GetAnalyticsSummary(ctx, f)
+		}},
+		{"activity", func() (any, error) {
+			return db.GetAnalyticsActivity(ctx, f, "day")
+		}},
+		{"heatmap", func() (any, error) {
+			return db.GetAnalyticsHeatmap(ctx, f, "messages")
+		}},
+		{"projects", func() (any, error) {
+			return db.GetAnalyticsProjects(ctx, f)
+		}},
+		{"hour-of-week", func() (any, error) {
+			return db.GetAnalyticsHourOfWeek(ctx, f)
+		}},
+		{"sessions", func() (any, error) {
+			return db.GetAnalyticsSessionShape(ctx, f)
+		}},
+		{"velocity", func() (any, error) {
+			return db.GetAnalyticsVelocity(ctx, f)
+		}},
+		{"tools", func() (any, error) {
+			return db.GetAnalyticsTools(ctx, f)
+		}},
+		{"hooks", func() (any, error) {
+			return db.GetAnalyticsHooks(ctx, f)
+		}},
+		{"top-sessions", func() (any, error) {
+			return db.GetAnalyticsTopSessions(ctx, f, "messages")
+		}},
+	}
+	data := make(map[string]json.RawMessage, len(sections))
+	for _, s := range sections {
+		v, err := s.get()
+		if err != nil {
+			return nil, fmt.Errorf("capturing %s: %w", s.name, err)
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("encoding %s: %w", s.name, err)
+		}
+		data[s.name] = b
+	}
+	return data, nil
+}
+
+// InsertAnalyticsSnapshot stores a snapshot and returns its ID.
+// A non-empty CreatedAt is kept, so imported snapshots retain
+// the time they were originally taken.
+func (db *DB) InsertAnalyticsSnapshot(
+	s AnalyticsSnapshot,
+) (int64, error) {
+	filter, err := json.Marshal(s.Filter)
+	if err != nil {
+		return 0, fmt.Errorf("encoding snapshot filter: %w", err)
+	}
+	data, err := json.Marshal(s.Data)
+	if err != nil {
+		return 0, fmt.Errorf("encoding snapshot data: %w", err)
+	}
+	createdAt := s.CreatedAt
+	if createdAt == "" {
+		createdAt = time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	}
+
+	var id int64
+	err = db.writePriority(func() error {
+		res, err := db.getWriter().Exec(`
+			INSERT INTO analytics_snapshots
+				(name, filter, data, created_at, imported_at)
+			VALUES (?, ?, ?, ?, ?)`,
+			strings.TrimSpace(s.Name), string(filter),
+			string(data), createdAt, s.ImportedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("inserting analytics snapshot: %w", err)
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+const maxAnalyticsSnapshots = 500
+
+// ListAnalyticsSnapshots returns snapshots without their data,
+// newest first, capped at 500 rows.
+func (db *DB) ListAnalyticsSnapshots(
+	ctx context.Context,
+) ([]AnalyticsSnapshot, error) {
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT id, name, filter, created_at, imported_at
+		FROM analytics_snapshots
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?`, maxAnalyticsSnapshots,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying analytics snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snaps := []AnalyticsSnapshot{}
+	for rows.Next() {
+		var s AnalyticsSnapshot
+		var filter string
+		if err := rows.Scan(
+			&s.ID, &s.Name, &filter, &s.CreatedAt, &s.ImportedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning analytics snapshot: %w", err)
+		}
+		if err := json.Unmarshal([]byte(filter), &s.Filter); err != nil {
+			return nil, fmt.Errorf(
+				"decoding snapshot %d filter: %w", s.ID, err,
+			)
+		}
+		snaps = append(snaps, s)
+	}
+	return snaps, rows.Err()
+}
+
+// GetAnalyticsSnapshot returns a snapshot with its data.
+// Returns nil, nil if not found.
+func (db *DB) GetAnalyticsSnapshot(
+	ctx context.Context, id int64,
+) (*AnalyticsSnapshot, error) {
+	var s AnalyticsSnapshot
+	var filter, data string
+	err := db.getReader().QueryRowContext(ctx, `
+		SELECT id, name, filter, data, created_at, imported_at
+		FROM analytics_snapshots WHERE id = ?`, id,
+	).Scan(
+		&s.ID, &s.Name, &filter, &data, &s.CreatedAt, &s.ImportedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"getting analytics snapshot %d: %w", id, err,
+		)
+	}
+	if err := json.Unmarshal([]byte(filter), &s.Filter); err != nil {
+		return nil, fmt.Errorf(
+			"decoding snapshot %d filter: %w", id, err,
+		)
+	}
+	if err := json.Unmarshal([]byte(data), &s.Data); err != nil {
+		return nil, fmt.Errorf(
+			"decoding snapshot %d data: %w", id, err,
+		)
+	}
+	return &s, nil
+}
+
+// DeleteAnalyticsSnapshot removes a snapshot by ID.
+func (db *DB) DeleteAnalyticsSnapshot(id int64) error {
+	return db.writePriority(func() error {
+		_, err := db.getWriter().Exec(
+			"DELETE FROM analytics_snapshots WHERE id = ?", id,
+		)
+		return err
+	})
+}
+
+// CopyAnalyticsSnapshotsFrom copies all snapshots from the
+// database at sourcePath into this database using
+// ATTACH/DETACH.
+func (db *DB) CopyAnalyticsSnapshotsFrom(sourcePath string) error {
+	return db.write(func() error {
+		// ATTACH is connection-scoped; pin one connection.
+		ctx := context.Background()
+		conn, err := db.getWriter().Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("acquiring connection: %w", err)
+		}
+		defer conn.Close()
+
+		if _, err := conn.ExecContext(
+			ctx, "ATTACH DATABASE ? AS old_db", sourcePath,
+		); err != nil {
+			return fmt.Errorf("attaching source db: %w", err)
+		}
+		defer func() {
+			_, _ = conn.ExecContext(
+				ctx, "DETACH DATABASE old_db",
+			)
+		}()
+
+		if _, err := conn.ExecContext(ctx, `
+			INSERT OR IGNORE INTO analytics_snapshots
+				(id, name, filter, data, created_at, imported_at)
+			SELECT id, name, filter, data, created_at, imported_at
+			FROM old_db.analytics_snapshots`); err != nil {
+			return fmt.Errorf("copying analytics snapshots: %w", err)
+		}
+		return nil
+	})
+}
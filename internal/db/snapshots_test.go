@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalyticsSnapshots(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "alpha", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-01T09:00:00Z")
+		s.MessageCount = 4
+	})
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	data, err := d.CaptureAnalytics(ctx, f)
+	requireNoError(t, err, "CaptureAnalytics")
+	for _, name := range []string{"summary", "activity", "tools"} {
+		if _, ok := data[name]; !ok {
+			t.Errorf("missing section %q", name)
+		}
+	}
+
+	id, err := d.InsertAnalyticsSnapshot(AnalyticsSnapshot{
+		Name: " Q2 close ", Filter: f, Data: data,
+	})
+	requireNoError(t, err, "InsertAnalyticsSnapshot")
+
+	// Later history changes do not touch the stored report.
+	insertSession(t, d, "s2", "alpha", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-02T09:00:00Z")
+	})
+
+	got, err := d.GetAnalyticsSnapshot(ctx, id)
+	requireNoError(t, err, "GetAnalyticsSnapshot")
+	if got == nil {
+		t.Fatal("snapshot not found")
+	}
+	assertEq(t, "Name", got.Name, "Q2 close")
+	assertEq(t, "Filter.From", got.Filter.From, "2024-06-01")
+	if got.CreatedAt == "" {
+		t.Error("CreatedAt not set")
+	}
+	var summary AnalyticsSummary
+	requireNoError(t,
+		json.Unmarshal(got.Data["summary"], &summary), "decode summary")
+	assertEq(t, "TotalSessions", summary.TotalSessions, 1)
+
+	// Imports keep their original creation time.
+	imported := "2024-07-01T00:00:00.000Z"
+	_, err = d.InsertAnalyticsSnapshot(AnalyticsSnapshot{
+		Name: "imported", Filter: f, Data: data,
+		CreatedAt: "2024-06-30T23:00:00.000Z", ImportedAt: &imported,
+	})
+	requireNoError(t, err, "InsertAnalyticsSnapshot imported")
+
+	list, err := d.ListAnalyticsSnapshots(ctx)
+	requireNoError(t, err, "ListAnalyticsSnapshots")
+	if len(list) != 2 {
+		t.Fatalf("len = %d, want 2", len(list))
+	}
+	assertEq(t, "newest first", list[0].ID, id)
+	assertEq(t, "imported created_at", list[1].CreatedAt,
+		"2024-06-30T23:00:00.000Z")
+	if list[0].Data != nil {
+		t.Error("listing should omit data")
+	}
+
+	requireNoError(t, d.DeleteAnalyticsSnapshot(id), "Delete")
+	got, err = d.GetAnalyticsSnapshot(ctx, id)
+	requireNoError(t, err, "GetAnalyticsSnapshot after delete")
+	if got != nil {
+		t.Error("snapshot still present after delete")
+	}
+}
+
+func TestValidateSnapshot(t *testing.T) {
+	data := map[string]json.RawMessage{"summary": json.RawMessage(`{}`)}
+	tests := []struct {
+		name    string
+		snap    AnalyticsSnapshot
+		wantErr bool
+	}{
+		{"ok", AnalyticsSnapshot{Name: "q", Data: data}, false},
+		{"blank name", AnalyticsSnapshot{Name: "  ", Data: data}, true},
+		{"no data", AnalyticsSnapshot{Name: "q"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSnapshot(tt.snap)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCopyAnalyticsSnapshotsFrom(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	srcPath := filepath.Join(dir, "src.db")
+	srcDB, err := Open(srcPath)
+	requireNoError(t, err, "Open src")
+	_, err = srcDB.InsertAnalyticsSnapshot(AnalyticsSnapshot{
+		Name: "keep",
+		Data: map[string]json.RawMessage{"summary": json.RawMessage(`{}`)},
+	})
+	requireNoError(t, err, "InsertAnalyticsSnapshot")
+	srcDB.Close()
+
+	dstDB, err := Open(filepath.Join(dir, "dst.db"))
+	requireNoError(t, err, "Open dst")
+	defer dstDB.Close()
+
+	requireNoError(t, dstDB.CopyAnalyticsSnapshotsFrom(srcPath),
+		"CopyAnalyticsSnapshotsFrom")
+	list, err := dstDB.ListAnalyticsSnapshots(ctx)
+	requireNoError(t, err, "ListAnalyticsSnapshots")
+	if len(list) != 1 || list[0].Name != "keep" {
+		t.Errorf("snapshots = %+v, want [keep]", list)
+	}
+}
@@ -0,0 +1,199 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// TodoCompleted is the status of a finished todo item; any
+// other status counts as open.
+const TodoCompleted = "completed"
+
+// Todo limits for ListOpenTodos.
+const (
+	DefaultTodoSessionLimit = 50
+	MaxTodoSessionLimit     = 500
+)
+
+// Todo is one item of a session's latest todo list.
+type Todo struct {
+	Position   int    `json:"position"`
+	Content    string `json:"content"`
+	Status     string `json:"status"`
+	ActiveForm string `json:"active_form,omitempty"`
+}
+
+// ReplaceSessionTodos replaces a session's todo list. updatedAt
+// is when the list was written.
+func (db *DB) ReplaceSessionTodos(
+	sessionID string, todos []Todo, updatedAt string,
+) error {
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.Exec(
+			"DELETE FROM session_todos WHERE session_id = ?",
+			sessionID,
+		); err != nil {
+			return fmt.Errorf("deleting old todos: %w", err)
+		}
+
+		if len(todos) > 0 {
+			stmt, err := tx.Prepare(`
+				INSERT INTO session_todos
+					(session_id, position, content, status,
+					 active_form, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?)`)
+			if err != nil {
+				return fmt.Errorf("preparing todo insert: %w", err)
+			}
+			defer stmt.Close()
+			for _, t := range todos {
+				if _, err := stmt.Exec(
+					sessionID, t.Position, t.Content, t.Status,
+					t.ActiveForm, nilIfEmpty(updatedAt),
+				); err != nil {
+					return fmt.Errorf("inserting todo: %w", err)
+				}
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// GetSessionTodos returns a session's latest todo list in list
+// order.
+func (db *DB) GetSessionTodos(
+	ctx context.Context, sessionID string,
+) ([]Todo, error) {
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT position, content, status, active_form
+		FROM session_todos
+		WHERE session_id = ?
+		ORDER BY position`, sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying todos: %w", err)
+	}
+	defer rows.Close()
+
+	todos := []Todo{}
+	for rows.Next() {
+		var t Todo
+		if err := rows.Scan(
+			&t.Position, &t.Content, &t.Status, &t.ActiveForm,
+		); err != nil {
+			return nil, fmt.Errorf("scanning todo: %w", err)
+		}
+		todos = append(todos, t)
+	}
+	return todos, rows.Err()
+}
+
+// TodoFilter selects sessions for ListOpenTodos.
+type TodoFilter struct {
+	Project string
+	Agent   string
+	Machine string
+	Since   string // updated_at >= Since (RFC3339 or YYYY-MM-DD)
+	Limit   int
+}
+
+// SessionTodos is the open part of one session's todo list.
+type SessionTodos struct {
+	SessionID    string  `json:"session_id"`
+	Project      string  `json:"project"`
+	Agent        string  `json:"agent"`
+	FirstMessage *string `json:"first_message"`
+	UpdatedAt    string  `json:"updated_at"`
+	Todos        []Todo  `json:"todos"`
+}
+
+// OpenTodosResponse lists open todos grouped by session, most
+// recently updated first. Total counts open items across all
+// matching sessions, including those past the limit.
+type OpenTodosResponse struct {
+	Sessions []SessionTodos `json:"sessions"`
+	Total    int            `json:"total"`
+}
+
+// ListOpenTodos returns unfinished todo items across sessions,
+// a task inbox built from the agents' own todo lists. Archived
+// sessions are excluded.
+func (db *DB) ListOpenTodos(
+	ctx context.Context, f TodoFilter,
+) (OpenTodosResponse, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultTodoSessionLimit
+	}
+	limit = min(limit, MaxTodoSessionLimit)
+
+	where := `t.status != ?
+		AND s.id NOT IN (
+			SELECT session_id FROM session_flags WHERE archived = 1)`
+	args := []any{TodoCompleted}
+	if f.Project != "" {
+		where += " AND s.project = ?"
+		args = append(args, f.Project)
+	}
+	if f.Agent != "" {
+		where += " AND s.agent = ?"
+		args = append(args, f.Agent)
+	}
+	if f.Machine != "" {
+		where += " AND s.machine = ?"
+		args = append(args, f.Machine)
+	}
+	if f.Since != "" {
+		where += " AND COALESCE(t.updated_at, '') >= ?"
+		args = append(args, f.Since)
+	}
+
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT t.session_id, s.project, s.agent, s.first_message,
+			COALESCE(t.updated_at, ''), t.position, t.content,
+			t.status, t.active_form
+		FROM session_todos t
+		JOIN sessions s ON s.id = t.session_id
+		WHERE `+where+`
+		ORDER BY COALESCE(t.updated_at, '') DESC, t.session_id,
+			t.position`,
+		args...,
+	)
+	if err != nil {
+		return OpenTodosResponse{},
+			fmt.Errorf("querying open todos: %w", err)
+	}
+	defer rows.Close()
+
+	resp := OpenTodosResponse{Sessions: []SessionTodos{}}
+	var cur *SessionTodos
+	for rows.Next() {
+		var st SessionTodos
+		var t Todo
+		if err := rows.Scan(
+			&st.SessionID, &st.Project, &st.Agent,
+			&st.FirstMessage, &st.UpdatedAt, &t.Position,
+			&t.Content, &t.Status, &t.ActiveForm,
+		); err != nil {
+			return OpenTodosResponse{},
+				fmt.Errorf("scanning open todo: %w", err)
+		}
+		resp.Total++
+		if cur == nil || cur.SessionID != st.SessionID {
+			if len(resp.Sessions) == limit {
+				cur = nil
+				continue
+			}
+			resp.Sessions = append(resp.Sessions, st)
+			cur = &resp.Sessions[len(resp.Sessions)-1]
+		}
+		cur.Todos = append(cur.Todos, t)
+	}
+	return resp, rows.Err()
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReplaceSessionTodos(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "proj")
+
+	requireNoError(t, d.ReplaceSessionTodos("s1", []Todo{
+		{Position: 0, Content: "old", Status: "pending"},
+	}, "2024-06-01T10:00:00Z"), "ReplaceSessionTodos")
+	requireNoError(t, d.ReplaceSessionTodos("s1", []Todo{
+		{Position: 0, Content: "write parser", Status: "completed"},
+		{Position: 1, Content: "add tests", Status: "in_progress", ActiveForm: "Adding tests"},
+	}, "2024-06-01T11:00:00Z"), "ReplaceSessionTodos again")
+
+	got, err := d.GetSessionTodos(ctx, "s1")
+	requireNoError(t, err, "GetSessionTodos")
+	if len(got) != 2 {
+		t.Fatalf("len = %d, want 2", len(got))
+	}
+	assertEq(t, "Content[1]", got[1].Content, "add tests")
+	assertEq(t, "ActiveForm[1]", got[1].ActiveForm, "Adding tests")
+
+	// Deleting the session removes its todos.
+	requireNoError(t, d.DeleteSession("s1"), "DeleteSession")
+	got, err = d.GetSessionTodos(ctx, "s1")
+	requireNoError(t, err, "GetSessionTodos after delete")
+	if len(got) != 0 {
+		t.Errorf("len after delete = %d, want 0", len(got))
+	}
+}
+
+func TestListOpenTodos(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	lists := []struct {
+		id, project, at string
+		todos           []Todo
+	}{
+		{"old", "alpha", "2024-05-01T10:00:00Z", []Todo{
+			{Content: "stale", Status: "pending"},
+		}},
+		{"a", "alpha", "2024-06-02T10:00:00Z", []Todo{
+			{Content: "done", Status: "completed"},
+			{Position: 1, Content: "next", Status: "pending"},
+		}},
+		{"b", "beta", "2024-06-03T10:00:00Z", []Todo{
+			{Content: "wip", Status: "in_progress"},
+			{Position: 1, Content: "later", Status: "pending"},
+		}},
+		{"finished", "beta", "2024-06-04T10:00:00Z", []Todo{
+			{Content: "all done", Status: "completed"},
+		}},
+	}
+	for _, l := range lists {
+		insertSession(t, d, l.id, l.project)
+		requireNoError(t,
+			d.ReplaceSessionTodos(l.id, l.todos, l.at),
+			"ReplaceSessionTodos "+l.id)
+	}
+
+	resp, err := d.ListOpenTodos(ctx, TodoFilter{Since: "2024-06-01"})
+	requireNoError(t, err, "ListOpenTodos")
+	assertEq(t, "Total", resp.Total, 3)
+	if len(resp.Sessions) != 2 {
+		t.Fatalf("sessions = %d, want 2", len(resp.Sessions))
+	}
+	assertEq(t, "first session", resp.Sessions[0].SessionID, "b")
+	assertEq(t, "b todos", len(resp.Sessions[0].Todos), 2)
+	assertEq(t, "a todo", resp.Sessions[1].Todos[0].Content, "next")
+
+	t.Run("Limit", func(t *testing.T) {
+		resp, err := d.ListOpenTodos(ctx, TodoFilter{Limit: 1})
+		requireNoError(t, err, "ListOpenTodos")
+		assertEq(t, "Total", resp.Total, 4)
+		assertEq(t, "sessions", len(resp.Sessions), 1)
+	})
+
+	t.Run("Project", func(t *testing.T) {
+		resp, err := d.ListOpenTodos(ctx, TodoFilter{Project: "alpha"})
+		requireNoError(t, err, "ListOpenTodos")
+		assertEq(t, "sessions", len(resp.Sessions), 2)
+	})
+
+	t.Run("Archived", func(t *testing.T) {
+		requireNoError(t, d.ApplyBulkAction(ctx,
+			BulkAction{Action: BulkActionArchive}, []string{"b"},
+		), "archive")
+		resp, err := d.ListOpenTodos(ctx, TodoFilter{Since: "2024-06-01"})
+		requireNoError(t, err, "ListOpenTodos")
+		assertEq(t, "Total", resp.Total, 1)
+	})
+}
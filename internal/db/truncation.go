@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// TruncationTotals counts tool results an agent truncated to
+// fit its size limits.
+type TruncationTotals struct {
+	Results   int `json:"results"` // tool calls with a result
+	Truncated int `json:"truncated"`
+	// Rate is the percentage of results that were truncated.
+	Rate float64 `json:"rate"`
+	// OriginalBytes sums the pre-truncation size of truncated
+	// results whose agent reported it.
+	OriginalBytes int64 `json:"original_bytes"`
+}
+
+func (t *TruncationTotals) add(o TruncationTotals) {
+	t.Results += o.Results
+	t.Truncated += o.Truncated
+	t.OriginalBytes += o.OriginalBytes
+}
+
+func (t *TruncationTotals) finish() {
+	if t.Results > 0 {
+		t.Rate = round1(float64(t.Truncated) / float64(t.Results) * 100)
+	}
+}
+
+// ToolTruncation is the truncation totals for one tool.
+type ToolTruncation struct {
+	Tool string `json:"tool"`
+	TruncationTotals
+}
+
+// ProjectTruncation is the truncation totals for one project.
+type ProjectTruncation struct {
+	Project string `json:"project"`
+	TruncationTotals
+}
+
+// TruncationResponse wraps tool output truncation analytics.
+// Tools and projects are ordered by truncated results.
+type TruncationResponse struct {
+	Overall  TruncationTotals    `json:"overall"`
+	Tools    []ToolTruncation    `json:"tools"`
+	Projects []ProjectTruncation `json:"projects"`
+}
+
+// GetAnalyticsTruncation reports how often tool results were
+// truncated by the agent, per tool and per project. Frequent
+// truncation means the agent often worked from incomplete
+// output. Tool calls removed by compaction are not counted.
+func (db *DB) GetAnalyticsTruncation(
+	ctx context.Context, f AnalyticsFilter,
+) (TruncationResponse, error) {
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return TruncationResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, project
+		FROM sessions WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return TruncationResponse{},
+			fmt.Errorf("querying truncation sessions: %w", err)
+	}
+	defer rows.Close()
+
+	projectOf := map[string]string{}
+	var ids []string
+	for rows.Next() {
+		var id, ts, project string
+		if err := rows.Scan(&id, &ts, &project); err != nil {
+			return TruncationResponse{},
+				fmt.Errorf("scanning truncation session: %w", err)
+		}
+		if !inDateRange(localDate(ts, loc), f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		projectOf[id] = project
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return TruncationResponse{},
+			fmt.Errorf("iterating truncation sessions: %w", err)
+	}
+
+	var overall TruncationTotals
+	tools := map[string]*ToolTruncation{}
+	projects := map[string]*ProjectTruncation{}
+	err = queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, tool_name, COUNT(*),
+				SUM(result_truncated),
+				SUM(CASE WHEN result_truncated = 1
+					THEN COALESCE(result_original_length, 0)
+					ELSE 0 END)
+			FROM tool_calls
+			WHERE session_id IN `+ph+`
+				AND (result_content_length IS NOT NULL
+					OR result_truncated = 1)
+			GROUP BY session_id, tool_name`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying truncated results: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sid, tool string
+			var t TruncationTotals
+			if err := rows.Scan(
+				&sid, &tool, &t.Results, &t.Truncated,
+				&t.OriginalBytes,
+			); err != nil {
+				return fmt.Errorf("scanning truncated results: %w", err)
+			}
+			overall.add(t)
+			tt := tools[tool]
+			if tt == nil {
+				tt = &ToolTruncation{Tool: tool}
+				tools[tool] = tt
+			}
+			tt.add(t)
+			project := projectOf[sid]
+			pt := projects[project]
+			if pt == nil {
+				pt = &ProjectTruncation{Project: project}
+				projects[project] = pt
+			}
+			pt.add(t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return TruncationResponse{}, err
+	}
+
+	resp := TruncationResponse{
+		Tools:    []ToolTruncation{},
+		Projects: []ProjectTruncation{},
+	}
+	overall.finish()
+	resp.Overall = overall
+	for _, t := range tools {
+		t.finish()
+		resp.Tools = append(resp.Tools, *t)
+	}
+	sort.Slice(resp.Tools, func(i, j int) bool {
+		a, b := resp.Tools[i], resp.Tools[j]
+		if a.Truncated != b.Truncated {
+			return a.Truncated > b.Truncated
+		}
+		return a.Tool < b.Tool
+	})
+	for _, p := range projects {
+		p.finish()
+		resp.Projects = append(resp.Projects, *p)
+	}
+	sort.Slice(resp.Projects, func(i, j int) bool {
+		a, b := resp.Projects[i], resp.Projects[j]
+		if a.Truncated != b.Truncated {
+			return a.Truncated > b.Truncated
+		}
+		return a.Project < b.Project
+	})
+	return resp, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetAnalyticsTruncation(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "alpha", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-03T09:00:00Z")
+	})
+	insertSession(t, d, "s2", "beta", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-04T09:00:00Z")
+	})
+
+	ts := "2024-06-03T09:01:00Z"
+	insertMessages(t, d,
+		toolMsg("s1", 0, ts,
+			ToolCall{
+				ToolName: "Bash", Category: "Bash", ToolUseID: "b1",
+				ResultContentLength: 2000, ResultTruncated: true,
+				ResultOriginalLength: 50000,
+			},
+			ToolCall{
+				ToolName: "Bash", Category: "Bash", ToolUseID: "b2",
+				ResultContentLength: 100,
+			},
+			// No result yet: not counted.
+			ToolCall{ToolName: "Read", Category: "Read", ToolUseID: "r0"},
+		),
+		toolMsg("s2", 0, ts,
+			ToolCall{
+				ToolName: "Read", Category: "Read", ToolUseID: "r1",
+				ResultContentLength: 900, ResultTruncated: true,
+			},
+		),
+	)
+
+	msgs, err := d.GetAllMessages(ctx, "s1")
+	requireNoError(t, err, "GetAllMessages")
+	tc := msgs[0].ToolCalls[0]
+	if !tc.ResultTruncated || tc.ResultOriginalLength != 50000 {
+		t.Errorf("stored tool call = %+v, want truncated from 50000", tc)
+	}
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsTruncation(ctx, f)
+	requireNoError(t, err, "GetAnalyticsTruncation")
+	assertEq(t, "Overall", resp.Overall, TruncationTotals{
+		Results: 3, Truncated: 2, Rate: 66.7, OriginalBytes: 50000,
+	})
+	if len(resp.Tools) != 2 || resp.Tools[0].Tool != "Bash" {
+		t.Fatalf("Tools = %+v, want Bash first", resp.Tools)
+	}
+	assertEq(t, "Read", resp.Tools[1].TruncationTotals, TruncationTotals{
+		Results: 1, Truncated: 1, Rate: 100,
+	})
+	if len(resp.Projects) != 2 || resp.Projects[0].Project != "alpha" ||
+		resp.Projects[0].Rate != 50 {
+		t.Errorf("Projects = %+v, want alpha at 50%%", resp.Projects)
+	}
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wesm/agentsview/internal/secrets"
+)
+
+// UnknownRecord is one unrecognized record type counted in a
+// session.
+type UnknownRecord struct {
+	Type   string
+	Count  int
+	Sample string
+}
+
+// UnknownRecordType aggregates an unrecognized record type
+// across all sessions of one agent.
+type UnknownRecordType struct {
+	Agent    string `json:"agent"`
+	Type     string `json:"type"`
+	Count    int    `json:"count"`
+	Sessions int    `json:"sessions"`
+	LastSeen string `json:"last_seen"`
+	// Sample is a line from the most recent session with this
+	// type, with detected secrets redacted.
+	Sample string `json:"sample"`
+}
+
+// ReplaceUnknownRecords replaces the unrecognized record counts
+// for a session. Samples are redacted before they are stored.
+func (db *DB) ReplaceUnknownRecords(
+	sessionID string, records []UnknownRecord,
+) error {
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.Exec(
+			"DELETE FROM unknown_records WHERE session_id = ?",
+			sessionID,
+		); err != nil {
+			return fmt.Errorf("deleting old unknown records: %w", err)
+		}
+
+		if len(records) > 0 {
+			stmt, err := tx.Prepare(`
+				INSERT INTO unknown_records
+					(session_id, record_type, count, sample)
+				VALUES (?, ?, ?, ?)
+				ON CONFLICT(session_id, record_type) DO UPDATE SET
+					count = count + excluded.count`)
+			if err != nil {
+				return fmt.Errorf("preparing unknown record insert: %w", err)
+			}
+			defer stmt.Close()
+			for _, r := range records {
+				sample, _ := secrets.Redact(r.Sample)
+				if _, err := stmt.Exec(
+					sessionID, r.Type, r.Count, sample,
+				); err != nil {
+					return fmt.Errorf("inserting unknown record: %w", err)
+				}
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// GetUnknownRecordTypes returns unrecognized record types per
+// agent, most recently seen first.
+func (db *DB) GetUnknownRecordTypes(
+	ctx context.Context,
+) ([]UnknownRecordType, error) {
+	// SQLite returns the bare sample column from the row that
+	// supplies MAX(), i.e. the most recent session.
+	rows, err := db.getReader().QueryContext(ctx, `
+		SELECT s.agent, u.record_type, SUM(u.count),
+			COUNT(*),
+			MAX(COALESCE(NULLIF(s.ended_at, ''),
+				NULLIF(s.started_at, ''), s.created_at)),
+			u.sample
+		FROM unknown_records u
+		JOIN sessions s ON s.id = u.session_id
+		GROUP BY s.agent, u.record_type
+		ORDER BY 5 DESC, s.agent, u.record_type`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying unknown records: %w", err)
+	}
+	defer rows.Close()
+
+	types := []UnknownRecordType{}
+	for rows.Next() {
+		var t UnknownRecordType
+		if err := rows.Scan(
+			&t.Agent, &t.Type, &t.Count, &t.Sessions,
+			&t.LastSeen, &t.Sample,
+		); err != nil {
+			return nil, fmt.Errorf("scanning unknown record: %w", err)
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
@@ -0,0 +1,289 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/wesm/agentsview/internal/pricing"
+)
+
+// insertTokenUsage stores the token usage of one message in
+// token_usage.
+const insertTokenUsage = `
+	INSERT INTO token_usage
+		(message_id, session_id, model, input_tokens,
+		 output_tokens, cache_read_tokens,
+		 cache_creation_tokens, reasoning_tokens)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+// backfillTokenUsage fills token_usage from the token columns of
+// messages stored before the table existed.
+const backfillTokenUsage = `
+	INSERT INTO token_usage
+		(message_id, session_id, model, input_tokens,
+		 output_tokens, cache_read_tokens,
+		 cache_creation_tokens, reasoning_tokens)
+	SELECT id, session_id, model, input_tokens, output_tokens,
+		cache_read_tokens, cache_creation_tokens,
+		reasoning_tokens
+	FROM messages
+	WHERE input_tokens + output_tokens + cache_read_tokens
+		+ cache_creation_tokens + reasoning_tokens > 0`
+
+// HasTokenUsage reports whether the agent reported any token
+// usage for m, which gives it a token_usage row.
+func (m Message) HasTokenUsage() bool {
+	return m.InputTokens+m.OutputTokens+m.CacheReadTokens+
+		m.CacheCreationTokens+m.ReasoningTokens > 0
+}
+
+// TokenTotals sums the token usage agents reported on messages,
+// as stored in token_usage, and its estimated cost. Input
+// excludes cache reads and writes, as in CacheStats.
+type TokenTotals struct {
+	InputTokens         int     `json:"input_tokens"`
+	OutputTokens        int     `json:"output_tokens"`
+	CacheReadTokens     int     `json:"cache_read_tokens"`
+	CacheCreationTokens int     `json:"cache_creation_tokens"`
+	ReasoningTokens     int     `json:"reasoning_tokens"`
+	Cost                float64 `json:"cost"`
+}
+
+func (t *TokenTotals) add(o TokenTotals) {
+	t.InputTokens += o.InputTokens
+	t.OutputTokens += o.OutputTokens
+	t.CacheReadTokens += o.CacheReadTokens
+	t.CacheCreationTokens += o.CacheCreationTokens
+	t.ReasoningTokens += o.ReasoningTokens
+	t.Cost += o.Cost
+}
+
+// finish converts the cost with multiplier and rounds it to
+// cents. Costs are summed unrounded until then.
+func (t *TokenTotals) finish(multiplier float64) {
+	t.Cost = roundCost(t.Cost * multiplier)
+}
+
+func (t TokenTotals) total() int {
+	return t.InputTokens + t.OutputTokens +
+		t.CacheReadTokens + t.CacheCreationTokens
+}
+
+// UsageTrendEntry is token usage for one date bucket.
+type UsageTrendEntry struct {
+	Date string `json:"date"`
+	TokenTotals
+}
+
+// UsageBreakdown is token usage for one agent, model, or
+// project.
+type UsageBreakdown struct {
+	Label string `json:"label"`
+	TokenTotals
+}
+
+// UsageAnalyticsResponse wraps token usage and cost analytics.
+type UsageAnalyticsResponse struct {
+	Granularity string            `json:"granularity"`
+	Total       TokenTotals       `json:"total"`
+	Trend       []UsageTrendEntry `json:"trend"`
+	ByAgent     []UsageBreakdown  `json:"by_agent"`
+	ByModel     []UsageBreakdown  `json:"by_model"`
+	ByProject   []UsageBreakdown  `json:"by_project"`
+	// Currency of the costs: the project's billing currency
+	// when the filter names a project with a cost rate,
+	// otherwise BaseCurrency.
+	Currency string `json:"currency"`
+}
+
+// GetAnalyticsUsage reports token usage and estimated cost over
+// time and per agent, model, and project. Cost is priced from
+// each message's model with built-in list prices; messages from
+// unknown models count tokens but no cost. Trend buckets are by
+// session start date.
+func (db *DB) GetAnalyticsUsage(
+	ctx context.Context, f AnalyticsFilter, granularity string,
+) (UsageAnalyticsResponse, error) {
+	if granularity == "" {
+		granularity = "day"
+	}
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return UsageAnalyticsResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, agent, project
+		FROM sessions WHERE `+where, args...,
+	)
+	if err != nil {
+		return UsageAnalyticsResponse{},
+			fmt.Errorf("querying usage sessions: %w", err)
+	}
+	defer rows.Close()
+
+	type sessInfo struct {
+		bucket  string
+		agent   string
+		project string
+	}
+	sessionMap := make(map[string]sessInfo)
+	var sessionIDs []string
+	for rows.Next() {
+		var id, ts, agent, project string
+		if err := rows.Scan(&id, &ts, &agent, &project); err != nil {
+			return UsageAnalyticsResponse{},
+				fmt.Errorf("scanning usage session: %w", err)
+		}
+		date := localDate(ts, loc)
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		sessionMap[id] = sessInfo{
+			bucket:  bucketDate(date, granularity),
+			agent:   agent,
+			project: project,
+		}
+		sessionIDs = append(sessionIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return UsageAnalyticsResponse{},
+			fmt.Errorf("iterating usage sessions: %w", err)
+	}
+
+	rate := CostRate{Currency: BaseCurrency, Multiplier: 1}
+	if f.Project != "" {
+		rate = f.costRate(f.Project)
+	}
+	resp := UsageAnalyticsResponse{
+		Granularity: granularity,
+		Currency:    rate.Currency,
+	}
+	trend := map[string]*TokenTotals{}
+	byAgent := map[string]*TokenTotals{}
+	byModel := map[string]*TokenTotals{}
+	byProject := map[string]*TokenTotals{}
+	bump := func(m map[string]*TokenTotals, key string, t TokenTotals) {
+		if m[key] == nil {
+			m[key] = &TokenTotals{}
+		}
+		m[key].add(t)
+	}
+
+	err = db.usageByModel(ctx, sessionIDs,
+		func(sid, model string, t TokenTotals) {
+			if model == "" {
+				model = "unknown"
+			}
+			info := sessionMap[sid]
+			resp.Total.add(t)
+			bump(trend, info.bucket, t)
+			bump(byAgent, info.agent, t)
+			bump(byModel, model, t)
+			bump(byProject, info.project, t)
+		})
+	if err != nil {
+		return UsageAnalyticsResponse{}, err
+	}
+
+	resp.Total.finish(rate.Multiplier)
+
+	dates := make([]string, 0, len(trend))
+	for d := range trend {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+	resp.Trend = make([]UsageTrendEntry, 0, len(dates))
+	for _, d := range dates {
+		trend[d].finish(rate.Multiplier)
+		resp.Trend = append(resp.Trend, UsageTrendEntry{
+			Date: d, TokenTotals: *trend[d],
+		})
+	}
+
+	resp.ByAgent = usageBreakdowns(byAgent, rate.Multiplier)
+	resp.ByModel = usageBreakdowns(byModel, rate.Multiplier)
+	resp.ByProject = usageBreakdowns(byProject, rate.Multiplier)
+	return resp, nil
+}
+
+// usageByModel calls fn with the summed token usage and
+// unrounded cost of each session and model among ids. Messages
+// without reported usage are skipped.
+func (db *DB) usageByModel(
+	ctx context.Context, ids []string,
+	fn func(sessionID, model string, t TokenTotals),
+) error {
+	return queryChunked(ids, func(chunk []string) error {
+		ph, chunkArgs := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx,
+			`SELECT session_id, model,
+				SUM(input_tokens), SUM(output_tokens),
+				SUM(cache_read_tokens),
+				SUM(cache_creation_tokens),
+				SUM(reasoning_tokens)
+			FROM token_usage
+			WHERE session_id IN `+ph+`
+			GROUP BY session_id, model`,
+			chunkArgs...,
+		)
+		if err != nil {
+			return fmt.Errorf("querying token usage: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sid, model string
+			var t TokenTotals
+			if err := rows.Scan(
+				&sid, &model, &t.InputTokens, &t.OutputTokens,
+				&t.CacheReadTokens, &t.CacheCreationTokens,
+				&t.ReasoningTokens,
+			); err != nil {
+				return fmt.Errorf("scanning token usage: %w", err)
+			}
+			t.Cost = pricing.Cost(model, pricing.Usage{
+				Input:      t.InputTokens,
+				Output:     t.OutputTokens,
+				CacheRead:  t.CacheReadTokens,
+				CacheWrite: t.CacheCreationTokens,
+			})
+			fn(sid, model, t)
+		}
+		return rows.Err()
+	})
+}
+
+// usageBreakdowns flattens m, ordered by cost and then total
+// tokens descending.
+func usageBreakdowns(
+	m map[string]*TokenTotals, multiplier float64,
+) []UsageBreakdown {
+	out := make([]UsageBreakdown, 0, len(m))
+	for label, t := range m {
+		t.finish(multiplier)
+		out = append(out, UsageBreakdown{Label: label, TokenTotals: *t})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		if a.total() != b.total() {
+			return a.total() > b.total()
+		}
+		return a.Label < b.Label
+	})
+	return out
+}
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// usageMsg returns an assistant reply from model reporting the
+// given input and output tokens.
+func usageMsg(
+	sid string, ordinal int, model string, in, out int,
+) Message {
+	m := asstMsg(sid, ordinal, "reply")
+	m.Model = model
+	m.InputTokens = in
+	m.OutputTokens = out
+	return m
+}
+
+func TestGetAnalyticsUsage(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	for _, s := range []struct{ id, agent, project, started string }{
+		{"a", "claude", "alpha", "2024-06-03T09:00:00Z"},
+		{"b", "codex", "beta", "2024-06-04T09:00:00Z"},
+		{"old", "claude", "alpha", "2024-05-01T09:00:00Z"},
+	} {
+		insertSession(t, d, s.id, s.project, func(sess *Session) {
+			sess.Agent = s.agent
+			sess.StartedAt = Ptr(s.started)
+		})
+	}
+	cached := usageMsg("a", 2, "claude-sonnet-4-20250514", 0, 0)
+	cached.CacheReadTokens = 1_000_000
+	cached.CacheCreationTokens = 1_000_000
+	insertMessages(t, d,
+		userMsg("a", 0, "go"),
+		usageMsg("a", 1, "claude-sonnet-4-20250514",
+			1_000_000, 100_000),
+		cached,
+		usageMsg("b", 0, "mystery-model", 500, 50),
+		usageMsg("old", 0, "claude-sonnet-4-20250514", 1_000_000, 0),
+	)
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsUsage(ctx, f, "")
+	requireNoError(t, err, "GetAnalyticsUsage")
+	assertEq(t, "granularity", resp.Granularity, "day")
+	assertEq(t, "currency", resp.Currency, BaseCurrency)
+	// $3 input + $1.50 output + $0.30 cache read + $3.75 cache
+	// write; the unknown model adds tokens but no cost.
+	assertEq(t, "total", resp.Total, TokenTotals{
+		InputTokens: 1_000_500, OutputTokens: 100_050,
+		CacheReadTokens: 1_000_000, CacheCreationTokens: 1_000_000,
+		Cost: 8.55,
+	})
+
+	if len(resp.Trend) != 2 {
+		t.Fatalf("Trend = %+v, want 2 days", resp.Trend)
+	}
+	assertEq(t, "first day", resp.Trend[0].Date, "2024-06-03")
+	assertEq(t, "first day cost", resp.Trend[0].Cost, 8.55)
+	assertEq(t, "second day", resp.Trend[1].Date, "2024-06-04")
+	assertEq(t, "second day tokens", resp.Trend[1].InputTokens, 500)
+
+	wantLabels := func(name string, got []UsageBreakdown, want ...string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s = %+v, want %v", name, got, want)
+		}
+		for i, w := range want {
+			assertEq(t, name, got[i].Label, w)
+		}
+	}
+	wantLabels("ByAgent", resp.ByAgent, "claude", "codex")
+	wantLabels("ByModel", resp.ByModel,
+		"claude-sonnet-4-20250514", "mystery-model")
+	wantLabels("ByProject", resp.ByProject, "alpha", "beta")
+	assertEq(t, "codex cost", resp.ByAgent[1].Cost, 0.0)
+
+	f.Project = "alpha"
+	f.CostRates = map[string]CostRate{
+		"alpha": {Currency: "EUR", Multiplier: 2},
+	}
+	resp, err = d.GetAnalyticsUsage(ctx, f, "month")
+	requireNoError(t, err, "GetAnalyticsUsage alpha")
+	assertEq(t, "alpha currency", resp.Currency, "EUR")
+	assertEq(t, "alpha cost", resp.Total.Cost, 17.1)
+	if len(resp.Trend) != 1 || resp.Trend[0].Date != "2024-06-01" {
+		t.Errorf("monthly Trend = %+v, want one 2024-06-01 bucket",
+			resp.Trend)
+	}
+}
+
+func TestUsageByModel(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s", "alpha", func(sess *Session) {
+		sess.StartedAt = Ptr("2024-06-03T09:00:00Z")
+	})
+	insertMessages(t, d,
+		userMsg("s", 0, "go"),
+		usageMsg("s", 1, "claude-sonnet-4-20250514", 100, 10),
+		usageMsg("s", 2, "claude-sonnet-4-20250514", 200, 20),
+		usageMsg("s", 3, "gpt-5", 300, 30),
+		usageMsg("s", 4, "gpt-5", 0, 0),
+	)
+
+	got := map[string]TokenTotals{}
+	err := d.usageByModel(ctx, []string{"s", "missing"},
+		func(sid, model string, tt TokenTotals) {
+			if sid != "s" {
+				t.Errorf("unexpected session %q", sid)
+			}
+			got[model] = tt
+		})
+	requireNoError(t, err, "usageByModel")
+	if len(got) != 2 {
+		t.Fatalf("models = %+v, want 2", got)
+	}
+	sonnet := got["claude-sonnet-4-20250514"]
+	assertEq(t, "sonnet input", sonnet.InputTokens, 300)
+	assertEq(t, "sonnet output", sonnet.OutputTokens, 30)
+	if sonnet.Cost <= 0 {
+		t.Errorf("sonnet cost = %v, want priced", sonnet.Cost)
+	}
+	assertEq(t, "gpt-5 input", got["gpt-5"].InputTokens, 300)
+
+	summary, err := d.GetAnalyticsSummary(ctx, AnalyticsFilter{
+		From: "2024-01-01", To: "2024-12-31", Timezone: "UTC",
+	})
+	requireNoError(t, err, "GetAnalyticsSummary")
+	assertEq(t, "summary input", summary.Tokens.InputTokens, 600)
+	assertEq(t, "summary agent input",
+		summary.Agents[defaultAgent].Tokens.InputTokens, 600)
+}
+
+func tokenUsageRows(t *testing.T, d *DB, sid string) int {
+	t.Helper()
+	var n int
+	err := d.getReader().QueryRow(
+		"SELECT count(*) FROM token_usage WHERE session_id = ?", sid,
+	).Scan(&n)
+	requireNoError(t, err, "counting token_usage")
+	return n
+}
+
+func TestTokenUsageTable(t *testing.T) {
+	d := testDB(t)
+	insertSession(t, d, "s", "alpha")
+	insertMessages(t, d,
+		userMsg("s", 0, "go"),
+		usageMsg("s", 1, "gpt-5", 100, 10),
+		usageMsg("s", 2, "gpt-5", 0, 0),
+	)
+	assertEq(t, "rows", tokenUsageRows(t, d, "s"), 1)
+
+	requireNoError(t, d.ReplaceSessionMessages("s", []Message{
+		usageMsg("s", 0, "gpt-5", 5, 1),
+		usageMsg("s", 1, "gpt-5", 7, 1),
+	}), "ReplaceSessionMessages")
+	assertEq(t, "rows after replace", tokenUsageRows(t, d, "s"), 2)
+
+	requireNoError(t, d.DeleteSession("s"), "DeleteSession")
+	assertEq(t, "rows after delete", tokenUsageRows(t, d, "s"), 0)
+}
+
+func TestMigration_TokenUsageBackfill(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	d, err := Open(path)
+	requireNoError(t, err, "initial open")
+	insertSession(t, d, "s", "alpha", func(sess *Session) {
+		sess.StartedAt = Ptr("2024-06-03T09:00:00Z")
+	})
+	insertMessages(t, d,
+		userMsg("s", 0, "go"),
+		usageMsg("s", 1, "gpt-5", 300, 30),
+	)
+	d.Close()
+
+	conn, err := sql.Open("sqlite3", path)
+	requireNoError(t, err, "raw open")
+	_, err = conn.Exec("DROP TABLE token_usage")
+	requireNoError(t, err, "drop token_usage")
+	conn.Close()
+
+	d2, err := Open(path)
+	requireNoError(t, err, "reopen after migration")
+	defer d2.Close()
+	resp, err := d2.GetAnalyticsUsage(context.Background(),
+		AnalyticsFilter{
+			From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+		}, "")
+	requireNoError(t, err, "GetAnalyticsUsage")
+	assertEq(t, "backfilled input", resp.Total.InputTokens, 300)
+}
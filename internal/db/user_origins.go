@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// UserOriginCounts splits user-role messages by origin. Only
+// Human counts prompts a person typed; the rest are tool
+// results, hook output, and notices the agent injected.
+type UserOriginCounts struct {
+	Human      int `json:"human"`
+	ToolResult int `json:"tool_result"`
+	Hook       int `json:"hook"`
+	System     int `json:"system"`
+}
+
+func (c *UserOriginCounts) add(origin string, n int) {
+	switch origin {
+	case "human":
+		c.Human += n
+	case "tool_result":
+		c.ToolResult += n
+	case "hook":
+		c.Hook += n
+	case "system":
+		c.System += n
+	}
+}
+
+func (c *UserOriginCounts) merge(o UserOriginCounts) {
+	c.Human += o.Human
+	c.ToolResult += o.ToolResult
+	c.Hook += o.Hook
+	c.System += o.System
+}
+
+// ReplaceUserOrigins replaces the per-origin user message
+// counts for a session.
+func (db *DB) ReplaceUserOrigins(
+	sessionID string, counts map[string]int,
+) error {
+	return db.write(func() error {
+		tx, err := db.getWriter().Begin()
+		if err != nil {
+			return fmt.Errorf("beginning tx: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.Exec(
+			"DELETE FROM user_message_origins WHERE session_id = ?",
+			sessionID,
+		); err != nil {
+			return fmt.Errorf("deleting old user origins: %w", err)
+		}
+		for origin, n := range counts {
+			if n == 0 {
+				continue
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO user_message_origins
+					(session_id, origin, count)
+				VALUES (?, ?, ?)`,
+				sessionID, origin, n,
+			); err != nil {
+				return fmt.Errorf("inserting user origin: %w", err)
+			}
+		}
+		return tx.Commit()
+	})
+}
+
+// userOriginsBySession returns the user message origin counts
+// of the given sessions. Sessions without counts are absent.
+func (db *DB) userOriginsBySession(
+	ctx context.Context, ids []string,
+) (map[string]UserOriginCounts, error) {
+	out := make(map[string]UserOriginCounts)
+	err := queryChunked(ids, func(chunk []string) error {
+		ph, args := inPlaceholders(chunk)
+		rows, err := db.getReader().QueryContext(ctx, `
+			SELECT session_id, origin, count
+			FROM user_message_origins
+			WHERE session_id IN `+ph, args...)
+		if err != nil {
+			return fmt.Errorf("querying user origins: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var sid, origin string
+			var n int
+			if err := rows.Scan(&sid, &origin, &n); err != nil {
+				return fmt.Errorf("scanning user origin: %w", err)
+			}
+			c := out[sid]
+			c.add(origin, n)
+			out[sid] = c
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
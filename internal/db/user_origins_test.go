@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUserOriginsInAnalytics(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+	insertSession(t, d, "s1", "alpha", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-03T09:00:00Z")
+	})
+	insertSession(t, d, "s2", "alpha", func(s *Session) {
+		s.StartedAt = Ptr("2024-06-04T09:00:00Z")
+		s.Agent = "codex"
+	})
+	requireNoError(t, d.ReplaceUserOrigins("s1", map[string]int{
+		"human": 3, "system": 5,
+	}), "ReplaceUserOrigins s1")
+	requireNoError(t, d.ReplaceUserOrigins("s2", map[string]int{
+		"human": 1, "system": 4,
+	}), "ReplaceUserOrigins s2")
+	// Replacing drops origins that are no longer present.
+	requireNoError(t, d.ReplaceUserOrigins("s1", map[string]int{
+		"human": 3, "tool_result": 10, "hook": 2, "system": 0,
+	}), "ReplaceUserOrigins s1 again")
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	summary, err := d.GetAnalyticsSummary(ctx, f)
+	requireNoError(t, err, "GetAnalyticsSummary")
+	assertEq(t, "UserMessages", summary.UserMessages, UserOriginCounts{
+		Human: 4, ToolResult: 10, Hook: 2, System: 4,
+	})
+	assertEq(t, "codex UserMessages",
+		summary.Agents["codex"].UserMessages,
+		UserOriginCounts{Human: 1, System: 4})
+
+	activity, err := d.GetAnalyticsActivity(ctx, f, "day")
+	requireNoError(t, err, "GetAnalyticsActivity")
+	if len(activity.Series) != 2 {
+		t.Fatalf("Series = %+v, want 2 days", activity.Series)
+	}
+	assertEq(t, "day 1", activity.Series[0].UserOrigins,
+		UserOriginCounts{Human: 3, ToolResult: 10, Hook: 2})
+	assertEq(t, "day 2", activity.Series[1].UserOrigins,
+		UserOriginCounts{Human: 1, System: 4})
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultWarmupMinUserMessages is the number of user messages
+// that makes a session substantive for warm-up analytics when
+// the caller does not choose one.
+const DefaultWarmupMinUserMessages = 5
+
+// WarmupDay is one local day's first agent interaction and
+// first substantive session. Times are minutes after local
+// midnight; the substantive fields are nil on days without a
+// substantive session.
+type WarmupDay struct {
+	Date               string   `json:"date"`
+	DayOfWeek          int      `json:"day_of_week"` // 0=Mon, 6=Sun
+	Sessions           int      `json:"sessions"`
+	FirstMinute        int      `json:"first_minute"`
+	FirstSessionID     string   `json:"first_session_id"`
+	SubstantiveMinute  *int     `json:"substantive_minute"`
+	SubstantiveSession string   `json:"substantive_session_id,omitempty"`
+	WarmupMinutes      *float64 `json:"warmup_minutes"`
+}
+
+// WarmupWeekday aggregates the days falling on one weekday.
+// Medians are nil when no day contributes.
+type WarmupWeekday struct {
+	DayOfWeek           int      `json:"day_of_week"` // 0=Mon, 6=Sun
+	Days                int      `json:"days"`
+	SubstantiveDays     int      `json:"substantive_days"`
+	MedianFirstMinute   *float64 `json:"median_first_minute"`
+	MedianWarmupMinutes *float64 `json:"median_warmup_minutes"`
+}
+
+// WarmupResponse wraps warm-up analytics.
+type WarmupResponse struct {
+	MinUserMessages     int             `json:"min_user_messages"`
+	Days                []WarmupDay     `json:"days"`
+	Weekdays            []WarmupWeekday `json:"weekdays"`
+	MedianFirstMinute   *float64        `json:"median_first_minute"`
+	MedianWarmupMinutes *float64        `json:"median_warmup_minutes"`
+}
+
+// GetAnalyticsWarmup reports, for each local day with sessions
+// matching f, when the first session started and how long after
+// that the first substantive session (one with at least
+// minUserMessages user messages) started, with medians per
+// weekday. minUserMessages <= 0 uses
+// DefaultWarmupMinUserMessages.
+func (db *DB) GetAnalyticsWarmup(
+	ctx context.Context, f AnalyticsFilter, minUserMessages int,
+) (WarmupResponse, error) {
+	if minUserMessages <= 0 {
+		minUserMessages = DefaultWarmupMinUserMessages
+	}
+	loc := f.location()
+	dateCol := "COALESCE(NULLIF(started_at, ''), created_at)"
+	where, args := f.buildWhere(dateCol)
+
+	var timeIDs map[string]bool
+	if f.HasTimeFilter() {
+		var err error
+		timeIDs, err = db.filteredSessionIDs(ctx, f)
+		if err != nil {
+			return WarmupResponse{}, err
+		}
+	}
+
+	rows, err := db.getReader().QueryContext(ctx,
+		`SELECT id, `+dateCol+`, user_message_count
+		FROM sessions WHERE `+where, args...,
+	)
+	if err != nil {
+		return WarmupResponse{},
+			fmt.Errorf("querying warmup sessions: %w", err)
+	}
+	defer rows.Close()
+
+	type dayState struct {
+		WarmupDay
+		first, substantive time.Time
+	}
+	days := map[string]*dayState{}
+	for rows.Next() {
+		var id, ts string
+		var userMsgs int
+		if err := rows.Scan(&id, &ts, &userMsgs); err != nil {
+			return WarmupResponse{},
+				fmt.Errorf("scanning warmup session: %w", err)
+		}
+		t, ok := localTime(ts, loc)
+		if !ok {
+			continue
+		}
+		date := t.Format("2006-01-02")
+		if !inDateRange(date, f.From, f.To) {
+			continue
+		}
+		if timeIDs != nil && !timeIDs[id] {
+			continue
+		}
+		d := days[date]
+		if d == nil {
+			d = &dayState{WarmupDay: WarmupDay{
+				Date:      date,
+				DayOfWeek: (int(t.Weekday()) + 6) % 7,
+			}}
+			days[date] = d
+		}
+		d.Sessions++
+		if d.first.IsZero() || t.Before(d.first) {
+			d.first = t
+			d.FirstSessionID = id
+		}
+		if userMsgs >= minUserMessages &&
+			(d.substantive.IsZero() || t.Before(d.substantive)) {
+			d.substantive = t
+			d.SubstantiveSession = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return WarmupResponse{},
+			fmt.Errorf("iterating warmup sessions: %w", err)
+	}
+
+	resp := WarmupResponse{
+		MinUserMessages: minUserMessages,
+		Days:            make([]WarmupDay, 0, len(days)),
+		Weekdays:        make([]WarmupWeekday, 7),
+	}
+	var firsts, warmups []float64
+	weekFirsts := make([][]float64, 7)
+	weekWarmups := make([][]float64, 7)
+	for _, d := range days {
+		d.FirstMinute = minuteOfDay(d.first)
+		first := float64(d.FirstMinute)
+		firsts = append(firsts, first)
+		weekFirsts[d.DayOfWeek] = append(weekFirsts[d.DayOfWeek], first)
+		resp.Weekdays[d.DayOfWeek].Days++
+		if !d.substantive.IsZero() {
+			m := minuteOfDay(d.substantive)
+			gap := round1(d.substantive.Sub(d.first).Minutes())
+			d.SubstantiveMinute = &m
+			d.WarmupMinutes = &gap
+			warmups = append(warmups, gap)
+			weekWarmups[d.DayOfWeek] = append(
+				weekWarmups[d.DayOfWeek], gap,
+			)
+			resp.Weekdays[d.DayOfWeek].SubstantiveDays++
+		}
+		resp.Days = append(resp.Days, d.WarmupDay)
+	}
+	sort.Slice(resp.Days, func(i, j int) bool {
+		return resp.Days[i].Date < resp.Days[j].Date
+	})
+	for i := range resp.Weekdays {
+		resp.Weekdays[i].DayOfWeek = i
+		resp.Weekdays[i].MedianFirstMinute = medianPtr(weekFirsts[i])
+		resp.Weekdays[i].MedianWarmupMinutes = medianPtr(weekWarmups[i])
+	}
+	resp.MedianFirstMinute = medianPtr(firsts)
+	resp.MedianWarmupMinutes = medianPtr(warmups)
+	return resp, nil
+}
+
+func minuteOfDay(t time.Time) int {
+	return t.Hour()*60 + t.Minute()
+}
+
+// medianPtr returns the median of vs rounded to one decimal,
+// or nil when vs is empty. vs is sorted in place.
+func medianPtr(vs []float64) *float64 {
+	n := len(vs)
+	if n == 0 {
+		return nil
+	}
+	sort.Float64s(vs)
+	m := vs[n/2]
+	if n%2 == 0 {
+		m = (vs[n/2-1] + vs[n/2]) / 2
+	}
+	m = round1(m)
+	return &m
+}
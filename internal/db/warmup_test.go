@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetAnalyticsWarmup(t *testing.T) {
+	d := testDB(t)
+	ctx := context.Background()
+
+	seed := func(id, start string, userMsgs int) {
+		insertSession(t, d, id, "alpha", func(s *Session) {
+			s.StartedAt = Ptr(start)
+			s.MessageCount = userMsgs * 2
+			s.UserMessageCount = userMsgs
+		})
+	}
+	// Monday: a quick question, then real work 20 minutes later.
+	seed("w1", "2024-06-03T08:50:00Z", 1)
+	seed("w2", "2024-06-03T09:10:00Z", 6)
+	seed("w3", "2024-06-03T10:00:00Z", 8)
+	// Tuesday: nothing substantive.
+	seed("w4", "2024-06-04T07:30:00Z", 2)
+	// The next Monday starts straight into work.
+	seed("w5", "2024-06-10T09:00:00Z", 5)
+
+	f := AnalyticsFilter{
+		From: "2024-06-01", To: "2024-06-30", Timezone: "UTC",
+	}
+	resp, err := d.GetAnalyticsWarmup(ctx, f, 0)
+	requireNoError(t, err, "GetAnalyticsWarmup")
+
+	assertEq(t, "MinUserMessages", resp.MinUserMessages,
+		DefaultWarmupMinUserMessages)
+	if len(resp.Days) != 3 {
+		t.Fatalf("len(Days) = %d, want 3", len(resp.Days))
+	}
+	mon := resp.Days[0]
+	assertEq(t, "Days[0].Date", mon.Date, "2024-06-03")
+	assertEq(t, "Days[0].Sessions", mon.Sessions, 3)
+	assertEq(t, "Days[0].FirstMinute", mon.FirstMinute, 530)
+	assertEq(t, "Days[0].FirstSessionID", mon.FirstSessionID, "w1")
+	assertEq(t, "Days[0].SubstantiveSession", mon.SubstantiveSession, "w2")
+	assertEq(t, "Days[0].WarmupMinutes", *mon.WarmupMinutes, 20.0)
+	tue := resp.Days[1]
+	assertEq(t, "Days[1].DayOfWeek", tue.DayOfWeek, 1)
+	if tue.SubstantiveMinute != nil || tue.WarmupMinutes != nil {
+		t.Errorf("Days[1] = %+v, want no substantive session", tue)
+	}
+
+	monday := resp.Weekdays[0]
+	assertEq(t, "Monday.Days", monday.Days, 2)
+	assertEq(t, "Monday.SubstantiveDays", monday.SubstantiveDays, 2)
+	assertEq(t, "Monday.MedianFirstMinute", *monday.MedianFirstMinute, 535.0)
+	assertEq(t, "Monday.MedianWarmupMinutes", *monday.MedianWarmupMinutes, 10.0)
+	if resp.Weekdays[2].MedianFirstMinute != nil {
+		t.Error("Wednesday median set without any days")
+	}
+	assertEq(t, "MedianFirstMinute", *resp.MedianFirstMinute, 530.0)
+	assertEq(t, "MedianWarmupMinutes", *resp.MedianWarmupMinutes, 10.0)
+
+	t.Run("Threshold", func(t *testing.T) {
+		resp, err := d.GetAnalyticsWarmup(ctx, f, 7)
+		requireNoError(t, err, "GetAnalyticsWarmup")
+		assertEq(t, "Days[0].WarmupMinutes", *resp.Days[0].WarmupMinutes, 70.0)
+		assertEq(t, "Weekdays[0].SubstantiveDays",
+			resp.Weekdays[0].SubstantiveDays, 1)
+	})
+
+	t.Run("Timezone", func(t *testing.T) {
+		tz := f
+		tz.Timezone = "America/New_York"
+		resp, err := d.GetAnalyticsWarmup(ctx, tz, 0)
+		requireNoError(t, err, "GetAnalyticsWarmup")
+		assertEq(t, "Days[0].FirstMinute", resp.Days[0].FirstMinute, 290)
+	})
+}
@@ -0,0 +1,245 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed is returned by writes submitted after Close.
+var ErrClosed = errors.New("database is closed")
+
+const (
+	// writeQueueSize bounds pending bulk writes. Submitters
+	// block once it is full, which throttles sync rather than
+	// letting it queue unbounded work.
+	writeQueueSize = 256
+	// priorityQueueSize bounds pending interactive writes.
+	priorityQueueSize = 64
+	// writeSampleSize is the number of recent writes kept for
+	// latency percentiles.
+	writeSampleSize = 512
+)
+
+// writeJob is one serialized write.
+type writeJob struct {
+	fn       func() error
+	enqueued time.Time
+	done     chan error
+	panicked any // recovered panic, re-raised by submit
+}
+
+// writeQueue runs every database write on a single goroutine.
+// Interactive writes (tags, labels, insights) use a priority
+// lane that is always drained first, so they wait for at most
+// the write in progress rather than a sync burst.
+type writeQueue struct {
+	priority chan *writeJob
+	bulk     chan *writeJob
+	stopped  chan struct{}
+
+	mu     sync.RWMutex // guards closed against concurrent submit
+	closed bool
+	once   sync.Once
+
+	writes atomic.Int64
+	errors atomic.Int64
+
+	sampleMu sync.Mutex
+	waits    []float64 // ms, ring buffer
+	execs    []float64 // ms, ring buffer
+	next     int
+}
+
+func newWriteQueue() *writeQueue {
+	q := &writeQueue{
+		priority: make(chan *writeJob, priorityQueueSize),
+		bulk:     make(chan *writeJob, writeQueueSize),
+		stopped:  make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func (q *writeQueue) run() {
+	defer close(q.stopped)
+	prio, bulk := q.priority, q.bulk
+	for prio != nil || bulk != nil {
+		if prio != nil {
+			select {
+			case j, ok := <-prio:
+				if !ok {
+					prio = nil
+				} else {
+					q.exec(j)
+				}
+				continue
+			default:
+			}
+		}
+		select {
+		case j, ok := <-prio:
+			if !ok {
+				prio = nil
+				continue
+			}
+			q.exec(j)
+		case j, ok := <-bulk:
+			if !ok {
+				bulk = nil
+				continue
+			}
+			q.exec(j)
+		}
+	}
+}
+
+func (q *writeQueue) exec(j *writeJob) {
+	start := time.Now()
+	err := j.call()
+	end := time.Now()
+
+	q.writes.Add(1)
+	if err != nil {
+		q.errors.Add(1)
+	}
+	q.record(
+		float64(start.Sub(j.enqueued))/float64(time.Millisecond),
+		float64(end.Sub(start))/float64(time.Millisecond),
+	)
+	j.done <- err
+}
+
+// call runs the job, capturing a panic so it surfaces in the
+// submitting goroutine instead of killing the writer.
+func (j *writeJob) call() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			j.panicked = r
+			err = fmt.Errorf("write panicked: %v", r)
+		}
+	}()
+	return j.fn()
+}
+
+func (q *writeQueue) record(waitMs, execMs float64) {
+	q.sampleMu.Lock()
+	defer q.sampleMu.Unlock()
+	if len(q.waits) < writeSampleSize {
+		q.waits = append(q.waits, waitMs)
+		q.execs = append(q.execs, execMs)
+		return
+	}
+	q.waits[q.next] = waitMs
+	q.execs[q.next] = execMs
+	q.next = (q.next + 1) % writeSampleSize
+}
+
+// submit enqueues fn and waits for it to run. It blocks while
+// the lane is full.
+func (q *writeQueue) submit(priority bool, fn func() error) error {
+	q.mu.RLock()
+	if q.closed {
+		q.mu.RUnlock()
+		return ErrClosed
+	}
+	j := &writeJob{
+		fn:       fn,
+		enqueued: time.Now(),
+		done:     make(chan error, 1),
+	}
+	if priority {
+		q.priority <- j
+	} else {
+		q.bulk <- j
+	}
+	q.mu.RUnlock()
+	err := <-j.done
+	if j.panicked != nil {
+		panic(j.panicked)
+	}
+	return err
+}
+
+// stop rejects new writes, runs those already queued, and
+// waits for the writer goroutine to exit.
+func (q *writeQueue) stop() {
+	q.once.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		close(q.priority)
+		close(q.bulk)
+		q.mu.Unlock()
+	})
+	<-q.stopped
+}
+
+// WriteQueueStats reports the state of the database writer.
+// Wait is time spent queued; exec is time spent writing.
+// Percentiles cover the most recent writes.
+type WriteQueueStats struct {
+	Depth         int     `json:"depth"`
+	PriorityDepth int     `json:"priority_depth"`
+	Capacity      int     `json:"capacity"`
+	Writes        int64   `json:"writes"`
+	Errors        int64   `json:"errors"`
+	WaitP50Ms     float64 `json:"wait_p50_ms"`
+	WaitP95Ms     float64 `json:"wait_p95_ms"`
+	WaitMaxMs     float64 `json:"wait_max_ms"`
+	ExecP50Ms     float64 `json:"exec_p50_ms"`
+	ExecP95Ms     float64 `json:"exec_p95_ms"`
+	ExecMaxMs     float64 `json:"exec_max_ms"`
+}
+
+func (q *writeQueue) stats() WriteQueueStats {
+	s := WriteQueueStats{
+		Depth:         len(q.bulk),
+		PriorityDepth: len(q.priority),
+		Capacity:      cap(q.bulk),
+		Writes:        q.writes.Load(),
+		Errors:        q.errors.Load(),
+	}
+	q.sampleMu.Lock()
+	waits := append([]float64(nil), q.waits...)
+	execs := append([]float64(nil), q.execs...)
+	q.sampleMu.Unlock()
+
+	summarize := func(v []float64) (p50, p95, hi float64) {
+		if len(v) == 0 {
+			return 0, 0, 0
+		}
+		sort.Float64s(v)
+		return roundMs(percentileFloat(v, 0.5)),
+			roundMs(percentileFloat(v, 0.95)),
+			roundMs(v[len(v)-1])
+	}
+	s.WaitP50Ms, s.WaitP95Ms, s.WaitMaxMs = summarize(waits)
+	s.ExecP50Ms, s.ExecP95Ms, s.ExecMaxMs = summarize(execs)
+	return s
+}
+
+func roundMs(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// WriteQueueStats returns writer queue depth and latency.
+func (db *DB) WriteQueueStats() WriteQueueStats {
+	return db.writes.stats()
+}
+
+// write runs fn on the writer goroutine via the bulk lane.
+// fn must not call other writing methods on db.
+func (db *DB) write(fn func() error) error {
+	return db.writes.submit(false, fn)
+}
+
+// writePriority runs fn on the writer goroutine ahead of
+// queued bulk writes. Use for user-initiated writes from HTTP
+// handlers.
+func (db *DB) writePriority(fn func() error) error {
+	return db.writes.submit(true, fn)
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteQueue_PriorityFirst(t *testing.T) {
+	q := newWriteQueue()
+	defer q.stop()
+
+	// Hold the writer so the following jobs queue up.
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		_ = q.submit(false, func() error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	var (
+		mu    sync.Mutex
+		order []string
+		wg    sync.WaitGroup
+	)
+	submit := func(priority bool, name string) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = q.submit(priority, func() error {
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				return nil
+			})
+		}()
+	}
+	submit(false, "bulk")
+	waitForDepth(t, q, 1, 0)
+	submit(true, "priority")
+	waitForDepth(t, q, 1, 1)
+
+	close(release)
+	wg.Wait()
+	if !slices.Equal(order, []string{"priority", "bulk"}) {
+		t.Errorf("order = %v, want [priority bulk]", order)
+	}
+}
+
+func waitForDepth(t *testing.T, q *writeQueue, bulk, prio int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s := q.stats()
+		if s.Depth == bulk && s.PriorityDepth == prio {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("queue depth did not reach bulk=%d priority=%d",
+		bulk, prio)
+}
+
+func TestWriteQueue_StatsAndClose(t *testing.T) {
+	q := newWriteQueue()
+	boom := errors.New("boom")
+
+	requireNoError(t, q.submit(false, func() error { return nil }), "ok write")
+	if err := q.submit(false, func() error { return boom }); !errors.Is(err, boom) {
+		t.Errorf("err = %v, want boom", err)
+	}
+
+	s := q.stats()
+	assertEq(t, "Writes", s.Writes, int64(2))
+	assertEq(t, "Errors", s.Errors, int64(1))
+	assertEq(t, "Capacity", s.Capacity, writeQueueSize)
+
+	q.stop()
+	q.stop() // idempotent
+	if err := q.submit(true, func() error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("after stop err = %v, want ErrClosed", err)
+	}
+}
+
+func TestWriteQueue_PanicSurfacesInCaller(t *testing.T) {
+	q := newWriteQueue()
+	defer q.stop()
+
+	func() {
+		defer func() {
+			if r := recover(); r != "bad write" {
+				t.Errorf("recovered %v, want bad write", r)
+			}
+		}()
+		_ = q.submit(false, func() error { panic("bad write") })
+	}()
+
+	// The writer goroutine survives.
+	requireNoError(t, q.submit(false, func() error { return nil }), "write after panic")
+}
@@ -0,0 +1,418 @@
+// Package demo generates a synthetic but realistic dataset of
+// agent sessions, so agentsview can be explored, documented,
+// and screenshotted without exposing real transcripts.
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+	"github.com/wesm/agentsview/internal/timeutil"
+)
+
+// Days is how many days of history Populate generates, ending
+// the day before now.
+const Days = 60
+
+// botMachine runs scheduled agent jobs and is labeled a bot, so
+// it is left out of analytics by default.
+const botMachine = "ci-runner"
+
+type task struct {
+	prompt string
+	tag    string // session tag, "" for none
+}
+
+type project struct {
+	name   string
+	agents []string
+	files  []string
+	greps  []string
+	test   string // shell command that runs the tests
+	tasks  []task
+}
+
+var projects = []project{
+	{
+		name:   "webshop",
+		agents: []string{"claude", "claude", "codex", "amp"},
+		files: []string{
+			"src/api/orders.ts", "src/api/cart.ts",
+			"src/db/schema.ts", "src/components/Checkout.tsx",
+			"tests/orders.test.ts",
+		},
+		greps: []string{"calculateTotal", "OrderStatus", "TODO"},
+		test:  "npm test",
+		tasks: []task{
+			{"Add cursor pagination to the orders API", ""},
+			{"Checkout fails when the cart has a discount code, fix it", "bugfix"},
+			{"Refactor the cart reducer into smaller functions", "refactor"},
+			{"Write tests for the refund flow", ""},
+		},
+	},
+	{
+		name:   "data-pipeline",
+		agents: []string{"claude", "gemini", "gemini"},
+		files: []string{
+			"pipeline/ingest.py", "pipeline/transform.py",
+			"pipeline/schema.py", "tests/test_transform.py",
+		},
+		greps: []string{"def normalize", "timestamp", "retry"},
+		test:  "pytest -q",
+		tasks: []task{
+			{"The nightly job drops rows with null timestamps, find out why", "bugfix"},
+			{"Add retries with backoff to the S3 reader", ""},
+			{"Split transform.py into per-source modules", "refactor"},
+		},
+	},
+	{
+		name:   "mobile-app",
+		agents: []string{"copilot", "claude"},
+		files: []string{
+			"app/screens/Login.kt", "app/screens/Settings.kt",
+			"app/data/UserRepository.kt", "app/build.gradle",
+		},
+		greps: []string{"viewModelScope", "onClick", "R.string"},
+		test:  "./gradlew test",
+		tasks: []task{
+			{"Add a dark mode toggle to settings", ""},
+			{"Login spinner never stops on a bad password", "bugfix"},
+			{"Migrate UserRepository to coroutines", "refactor"},
+		},
+	},
+	{
+		name:   "infra",
+		agents: []string{"codex", "codex", "claude"},
+		files: []string{
+			"terraform/main.tf", "terraform/variables.tf",
+			"k8s/deployment.yaml", ".github/workflows/deploy.yml",
+		},
+		greps: []string{"replicas", "instance_type", "secrets."},
+		test:  "terraform validate",
+		tasks: []task{
+			{"Bump the staging node pool to three replicas", ""},
+			{"Deploy workflow times out on the migrate step", "bugfix"},
+			{"Explain what this Terraform module provisions", ""},
+		},
+	},
+}
+
+var machines = []string{"laptop", "workstation"}
+
+// agentModels lists the models each agent's sessions use; the
+// first is the usual one.
+var agentModels = map[string][]string{
+	"claude":  {"claude-sonnet-4-20250514", "claude-opus-4-5-20251101"},
+	"codex":   {"gpt-5-codex", "gpt-5-mini"},
+	"gemini":  {"gemini-2.5-pro", "gemini-2.5-flash"},
+	"copilot": {"gpt-4.1"},
+	"amp":     {"claude-sonnet-4-20250514"},
+}
+
+// agentTools maps a tool category to the tool name each agent
+// reports for it; agents not listed use Claude's names.
+var agentTools = map[string]map[string]string{
+	"codex": {
+		"Read": "shell_command", "Edit": "apply_patch",
+		"Grep": "shell_command", "Bash": "shell_command",
+	},
+	"gemini": {
+		"Read": "read_file", "Edit": "edit_file",
+		"Grep": "search_files", "Bash": "run_command",
+	},
+}
+
+var followUps = []string{
+	"Looks good, now run the tests",
+	"That broke the build, can you check the error?",
+	"Can you also handle the empty case?",
+	"Please add a short comment explaining why",
+	"Great, commit this",
+	"Use the existing helper instead of a new one",
+}
+
+var replies = []string{
+	"I found the relevant code and made the change.",
+	"The tests pass now. Here is a summary of what changed.",
+	"The failure comes from an unchecked nil value; I added a guard.",
+	"I kept the public interface the same and moved the logic into a helper.",
+}
+
+// generator holds the random source and the sessions written so
+// far, for curation once generation is done.
+type generator struct {
+	rng    *rand.Rand
+	count  int
+	tagged map[string][]string // tag -> session IDs
+	ids    []string
+}
+
+// Populate writes the demo dataset into d, dated relative to
+// now, and returns the number of sessions written. The same
+// day always produces the same data.
+func Populate(
+	ctx context.Context, d *db.DB, now time.Time,
+) (int, error) {
+	g := &generator{
+		rng:    rand.New(rand.NewPCG(1, 2)),
+		tagged: map[string][]string{},
+	}
+	first := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -Days)
+	for day := range Days {
+		date := first.AddDate(0, 0, day)
+		n := 1 + g.rng.IntN(4)
+		if wd := date.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			n = g.rng.IntN(2)
+		}
+		for range n {
+			if err := g.session(ctx, d, date); err != nil {
+				return g.count, err
+			}
+		}
+	}
+	if err := g.curate(ctx, d); err != nil {
+		return g.count, err
+	}
+	return g.count, nil
+}
+
+// session writes one top-level session started on date, plus a
+// subagent session for some Claude sessions.
+func (g *generator) session(
+	ctx context.Context, d *db.DB, date time.Time,
+) error {
+	p := projects[g.rng.IntN(len(projects))]
+	agent := pick(g.rng, p.agents)
+	t := pick(g.rng, p.tasks)
+	machine := pick(g.rng, machines)
+	if agent == "codex" && g.rng.IntN(3) == 0 {
+		machine = botMachine
+	}
+	start := date.Add(
+		time.Duration(8*60+g.rng.IntN(11*60)) * time.Minute,
+	)
+
+	g.count++
+	id := fmt.Sprintf("demo-%04d", g.count)
+	var subagent string
+	if agent == "claude" && g.rng.IntN(4) == 0 {
+		subagent = id + "-explore"
+	}
+	msgs := g.transcript(p, agent, t.prompt, start, 2+g.rng.IntN(8), subagent)
+	sess := sessionFor(id, p.name, machine, agent, msgs)
+	sess.GitBranch = "main"
+	if t.tag != "" {
+		sess.GitBranch = t.tag + "/" + id
+	}
+	if err := ingest(ctx, d, sess, msgs); err != nil {
+		return err
+	}
+	g.ids = append(g.ids, id)
+	if t.tag != "" {
+		g.tagged[t.tag] = append(g.tagged[t.tag], id)
+	}
+
+	if subagent != "" {
+		sub := g.transcript(p, agent,
+			"Explore the codebase and list the files involved in: "+
+				t.prompt,
+			start.Add(time.Minute), 1+g.rng.IntN(2), "")
+		s := sessionFor(subagent, p.name, machine, agent, sub)
+		s.ParentSessionID = &id
+		s.RelationshipType = "subagent"
+		if err := ingest(ctx, d, s, sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transcript generates turns user/assistant exchanges starting
+// at start. A non-empty subagent adds a Task call spawning that
+// session to the first reply.
+func (g *generator) transcript(
+	p project, agent, prompt string, start time.Time,
+	turns int, subagent string,
+) []db.Message {
+	models := agentModels[agent]
+	model := models[0]
+	if len(models) > 1 && g.rng.IntN(4) == 0 {
+		model = models[1]
+	}
+
+	var msgs []db.Message
+	ts := start
+	add := func(m db.Message) {
+		m.Ordinal = len(msgs)
+		m.Timestamp = timeutil.Format(ts)
+		m.ContentLength = len(m.Content)
+		msgs = append(msgs, m)
+	}
+	for turn := range turns {
+		text := prompt
+		if turn > 0 {
+			text = pick(g.rng, followUps)
+		}
+		add(db.Message{Role: "user", Content: text})
+		ts = ts.Add(time.Duration(10+g.rng.IntN(90)) * time.Second)
+
+		var parts []string
+		reply := db.Message{Role: "assistant", Model: model}
+		if g.rng.IntN(3) == 0 {
+			reply.HasThinking = true
+			parts = append(parts, "[Thinking]\nLet me look at how "+
+				pick(g.rng, p.files)+" handles this first.\n[/Thinking]")
+		}
+		for range g.rng.IntN(5) {
+			tc, header := g.toolCall(p, agent)
+			reply.ToolCalls = append(reply.ToolCalls, tc)
+			parts = append(parts, header)
+		}
+		if turn == 0 && subagent != "" {
+			reply.ToolCalls = append(reply.ToolCalls, db.ToolCall{
+				ToolName:          "Task",
+				Category:          parser.NormalizeToolCategory("Task"),
+				InputJSON:         `{"description":"Explore the codebase"}`,
+				SubagentSessionID: subagent,
+			})
+			parts = append(parts, "[Task: Explore the codebase]")
+		}
+		reply.HasToolUse = len(reply.ToolCalls) > 0
+		parts = append(parts, pick(g.rng, replies))
+		reply.Content = strings.Join(parts, "\n")
+
+		reply.InputTokens = 1500 + g.rng.IntN(10000)
+		reply.OutputTokens = 150 + g.rng.IntN(2500)
+		if strings.HasPrefix(model, "claude") {
+			reply.CacheReadTokens = 8000 + g.rng.IntN(60000)
+			reply.CacheCreationTokens = g.rng.IntN(4000)
+		} else {
+			reply.CacheReadTokens = g.rng.IntN(20000)
+		}
+		if reply.HasThinking || strings.HasPrefix(model, "gpt-5") {
+			reply.ReasoningTokens = 200 + g.rng.IntN(3000)
+		}
+		add(reply)
+		ts = ts.Add(time.Duration(1+g.rng.IntN(6)) * time.Minute)
+	}
+	return msgs
+}
+
+// toolCall returns a random tool call against p's files and the
+// header line the transcript shows for it.
+func (g *generator) toolCall(
+	p project, agent string,
+) (db.ToolCall, string) {
+	category, detail, input := "Bash", p.test, map[string]string{
+		"command": p.test,
+	}
+	switch n := g.rng.IntN(7); {
+	case n < 3:
+		file := pick(g.rng, p.files)
+		category, detail = "Read", file
+		input = map[string]string{"file_path": file}
+	case n < 5:
+		file := pick(g.rng, p.files)
+		category, detail = "Edit", file
+		input = map[string]string{"file_path": file}
+	case n < 6:
+		pattern := pick(g.rng, p.greps)
+		category, detail = "Grep", pattern
+		input = map[string]string{"pattern": pattern}
+	}
+
+	name := category
+	if names, ok := agentTools[agent]; ok {
+		name = names[category]
+	}
+	inputJSON, _ := json.Marshal(input)
+	tc := db.ToolCall{
+		ToolName:  name,
+		Category:  parser.NormalizeToolCategory(name),
+		InputJSON: string(inputJSON),
+		FilePath:  input["file_path"],
+	}
+	if category == "Bash" {
+		tc.ResultError = g.rng.IntN(4) == 0
+	}
+	if category == "Bash" || category == "Read" {
+		tc.ResultContentLength = 200 + g.rng.IntN(4000)
+		if g.rng.IntN(10) == 0 {
+			tc.ResultTruncated = true
+			tc.ResultOriginalLength = tc.ResultContentLength * (5 + g.rng.IntN(20))
+		}
+	}
+	return tc, fmt.Sprintf("[%s: %s]", tc.Category, detail)
+}
+
+// curate pins, tags, and archives some sessions and labels the
+// CI machine a bot, so those features have data to show.
+func (g *generator) curate(ctx context.Context, d *db.DB) error {
+	if len(g.ids) == 0 {
+		return nil
+	}
+	type curation struct {
+		action db.BulkAction
+		ids    []string
+	}
+	actions := []curation{
+		{db.BulkAction{Action: db.BulkActionPin}, g.ids[len(g.ids)-1:]},
+		{db.BulkAction{Action: db.BulkActionArchive}, g.ids[:1]},
+	}
+	for tag, ids := range g.tagged {
+		actions = append(actions, curation{
+			db.BulkAction{Action: db.BulkActionTag, Tag: tag}, ids,
+		})
+	}
+	for _, a := range actions {
+		if err := d.ApplyBulkAction(ctx, a.action, a.ids); err != nil {
+			return fmt.Errorf("demo %s: %w", a.action.Action, err)
+		}
+	}
+	return d.MarkBotMachine(botMachine, "demo: scheduled agent runs")
+}
+
+// sessionFor builds the session row summarizing msgs.
+func sessionFor(
+	id, project, machine, agent string, msgs []db.Message,
+) db.Session {
+	s := db.Session{
+		ID:           id,
+		Project:      project,
+		Machine:      machine,
+		Agent:        agent,
+		MessageCount: len(msgs),
+	}
+	for _, m := range msgs {
+		if m.Role == "user" {
+			s.UserMessageCount++
+		}
+	}
+	if len(msgs) > 0 {
+		first, last := msgs[0], msgs[len(msgs)-1]
+		s.FirstMessage = &first.Content
+		s.StartedAt = &first.Timestamp
+		s.EndedAt = &last.Timestamp
+	}
+	return s
+}
+
+func ingest(
+	ctx context.Context, d *db.DB, s db.Session, msgs []db.Message,
+) error {
+	_, err := d.Ingest(ctx, db.IngestSession{Session: s, Messages: msgs})
+	if err != nil {
+		return fmt.Errorf("writing demo session %s: %w", s.ID, err)
+	}
+	return nil
+}
+
+func pick[T any](rng *rand.Rand, items []T) T {
+	return items[rng.IntN(len(items))]
+}
@@ -0,0 +1,65 @@
+package demo
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+)
+
+func TestPopulate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	d := dbtest.OpenTestDB(t)
+	n, err := Populate(ctx, d, now)
+	if err != nil {
+		t.Fatalf("Populate: %v", err)
+	}
+	if n < Days {
+		t.Fatalf("sessions = %d, want at least %d", n, Days)
+	}
+
+	summary, err := d.GetAnalyticsSummary(ctx, db.AnalyticsFilter{
+		From: "2024-12-01", To: "2025-03-01", IncludeBots: true,
+		IncludeArchived: true,
+	})
+	if err != nil {
+		t.Fatalf("GetAnalyticsSummary: %v", err)
+	}
+	if summary.TotalSessions != n {
+		t.Errorf("TotalSessions = %d, want %d", summary.TotalSessions, n)
+	}
+	if len(summary.Agents) < 4 || summary.ActiveProjects != len(projects) {
+		t.Errorf("agents = %d, projects = %d, want several of each",
+			len(summary.Agents), summary.ActiveProjects)
+	}
+	if summary.Tokens.Cost <= 0 {
+		t.Errorf("cost = %v, want priced usage", summary.Tokens.Cost)
+	}
+
+	bots, err := d.GetBotMachines(ctx)
+	if err != nil || !slices.Equal(bots, []string{botMachine}) {
+		t.Errorf("bot machines = %v, %v", bots, err)
+	}
+	page, err := d.ListSessions(ctx, db.SessionFilter{Tag: "bugfix"})
+	if err != nil || len(page.Sessions) == 0 {
+		t.Errorf("bugfix sessions = %v, %v", page.Sessions, err)
+	}
+
+	// The same day yields the same dataset.
+	again := dbtest.OpenTestDB(t)
+	if _, err := Populate(ctx, again, now); err != nil {
+		t.Fatalf("second Populate: %v", err)
+	}
+	for _, id := range []string{"demo-0001", "demo-0042"} {
+		a, _ := d.GetSession(ctx, id)
+		b, _ := again.GetSession(ctx, id)
+		if a == nil || b == nil || *a.FirstMessage != *b.FirstMessage ||
+			*a.StartedAt != *b.StartedAt {
+			t.Errorf("%s differs between runs: %+v vs %+v", id, a, b)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+const (
+	// baselineDays is the window yesterday is compared
+	// against when looking for unusual activity.
+	baselineDays = 7
+	// spikeFactor flags a day at this multiple of the
+	// baseline daily average.
+	spikeFactor = 2.0
+	// minSpikeSessions and minSpikeCost keep quiet days from
+	// being flagged over tiny absolute numbers.
+	minSpikeSessions = 5
+	minSpikeCost     = 1.0
+)
+
+// Digest holds one day's headline numbers.
+type Digest struct {
+	Date     string  `json:"date"` // YYYY-MM-DD
+	Sessions int     `json:"sessions"`
+	Messages int     `json:"messages"`
+	Projects int     `json:"projects"`
+	Cost     float64 `json:"cost"` // estimated, db.BaseCurrency
+	// Events are notable things about the day, such as a
+	// spike over the trailing average or files that failed
+	// to parse.
+	Events []string `json:"events"`
+}
+
+// Build computes the digest for date (YYYY-MM-DD) with days
+// bucketed in loc.
+func Build(
+	ctx context.Context, d *db.DB, date string, loc *time.Location,
+) (Digest, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return Digest{}, fmt.Errorf("invalid date: %w", err)
+	}
+	f := db.AnalyticsFilter{
+		From: date, To: date, Timezone: loc.String(),
+	}
+	summary, err := d.GetAnalyticsSummary(ctx, f)
+	if err != nil {
+		return Digest{}, fmt.Errorf("digest summary: %w", err)
+	}
+	cost, err := d.GetEstimatedCost(ctx, f)
+	if err != nil {
+		return Digest{}, fmt.Errorf("digest cost: %w", err)
+	}
+	dg := Digest{
+		Date:     date,
+		Sessions: summary.TotalSessions,
+		Messages: summary.TotalMessages,
+		Projects: summary.ActiveProjects,
+		Cost:     cost,
+		Events:   []string{},
+	}
+
+	base := f
+	base.From = day.AddDate(0, 0, -baselineDays).Format("2006-01-02")
+	base.To = day.AddDate(0, 0, -1).Format("2006-01-02")
+	baseSummary, err := d.GetAnalyticsSummary(ctx, base)
+	if err != nil {
+		return Digest{}, fmt.Errorf("digest baseline: %w", err)
+	}
+	baseCost, err := d.GetEstimatedCost(ctx, base)
+	if err != nil {
+		return Digest{}, fmt.Errorf("digest baseline cost: %w", err)
+	}
+	avgSessions := float64(baseSummary.TotalSessions) / baselineDays
+	if dg.Sessions >= minSpikeSessions &&
+		float64(dg.Sessions) >= spikeFactor*avgSessions {
+		dg.Events = append(dg.Events, fmt.Sprintf(
+			"%d sessions vs a %d-day average of %.1f",
+			dg.Sessions, baselineDays, avgSessions,
+		))
+	}
+	avgCost := baseCost / baselineDays
+	if dg.Cost >= minSpikeCost && dg.Cost >= spikeFactor*avgCost {
+		dg.Events = append(dg.Events, fmt.Sprintf(
+			"est. $%.2f spend vs a %d-day average of $%.2f",
+			dg.Cost, baselineDays, avgCost,
+		))
+	}
+
+	quarantined, err := d.ListQuarantinedFiles(ctx)
+	if err != nil {
+		return Digest{}, fmt.Errorf("digest quarantine: %w", err)
+	}
+	failed := 0
+	for _, q := range quarantined {
+		t, err := time.Parse(time.RFC3339, q.LastFailedAt)
+		if err == nil && t.In(loc).Format("2006-01-02") == date {
+			failed++
+		}
+	}
+	if failed > 0 {
+		dg.Events = append(dg.Events, fmt.Sprintf(
+			"%d session %s failed to parse",
+			failed, plural(failed, "file", "files"),
+		))
+	}
+	return dg, nil
+}
+
+// Title returns the notification title for the digest.
+func (dg Digest) Title() string {
+	day, err := time.Parse("2006-01-02", dg.Date)
+	if err != nil {
+		return "agentsview digest"
+	}
+	return "agentsview: " + day.Format("Mon Jan 2")
+}
+
+// Body returns the notification text: the headline numbers on
+// one line, then one line per event.
+func (dg Digest) Body() string {
+	headline := "No agent sessions."
+	if dg.Sessions > 0 {
+		headline = fmt.Sprintf(
+			"%d %s, %d messages, %d %s, est. $%.2f",
+			dg.Sessions, plural(dg.Sessions, "session", "sessions"),
+			dg.Messages,
+			dg.Projects, plural(dg.Projects, "project", "projects"),
+			dg.Cost,
+		)
+	}
+	lines := append([]string{headline}, dg.Events...)
+	return strings.Join(lines, "\n")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,192 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+)
+
+// seedDigestDB seeds six sessions on 2024-06-10, one priced at
+// $3, one session earlier in the week, and a file that failed
+// to parse on 06-10.
+func seedDigestDB(t *testing.T) *db.DB {
+	t.Helper()
+	d := dbtest.OpenTestDB(t)
+	for i := range 6 {
+		project := "alpha"
+		if i%2 == 1 {
+			project = "beta"
+		}
+		dbtest.SeedSession(t, d, fmt.Sprintf("s%d", i), project,
+			func(s *db.Session) {
+				s.StartedAt = dbtest.Ptr(fmt.Sprintf(
+					"2024-06-10T%02d:00:00Z", 9+i,
+				))
+				s.MessageCount = 4
+			})
+	}
+	m := dbtest.AsstMsg("s0", 0, "x")
+	m.Model = "claude-sonnet-4-20250514"
+	m.InputTokens = 1_000_000
+	dbtest.SeedMessages(t, d, m)
+	dbtest.SeedSession(t, d, "old", "alpha", func(s *db.Session) {
+		s.StartedAt = dbtest.Ptr("2024-06-05T09:00:00Z")
+	})
+	if err := d.UpsertQuarantinedFile(db.QuarantinedFile{
+		Path:          "/x.jsonl",
+		Agent:         "claude",
+		Error:         "bad",
+		Attempts:      1,
+		FirstFailedAt: "2024-06-10T12:00:00Z",
+		LastFailedAt:  "2024-06-10T12:00:00Z",
+		NextRetryAt:   "2024-06-10T12:01:00Z",
+	}); err != nil {
+		t.Fatalf("UpsertQuarantinedFile: %v", err)
+	}
+	return d
+}
+
+func TestBuild(t *testing.T) {
+	d := seedDigestDB(t)
+	dg, err := Build(context.Background(), d, "2024-06-10", time.UTC)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if dg.Sessions != 6 || dg.Messages != 24 || dg.Projects != 2 {
+		t.Errorf("digest = %+v, want 6 sessions, 24 messages, 2 projects", dg)
+	}
+	if dg.Cost != 3.0 {
+		t.Errorf("Cost = %v, want 3", dg.Cost)
+	}
+	if len(dg.Events) != 3 {
+		t.Fatalf("Events = %q, want session spike, cost spike, parse failure",
+			dg.Events)
+	}
+	if dg.Events[2] != "1 session file failed to parse" {
+		t.Errorf("Events[2] = %q", dg.Events[2])
+	}
+
+	if got := dg.Title(); got != "agentsview: Mon Jun 10" {
+		t.Errorf("Title = %q", got)
+	}
+	lines := strings.Split(dg.Body(), "\n")
+	if lines[0] != "6 sessions, 24 messages, 2 projects, est. $3.00" {
+		t.Errorf("headline = %q", lines[0])
+	}
+	if len(lines) != 4 {
+		t.Errorf("body = %q, want headline plus 3 events", lines)
+	}
+
+	// A quiet day has no events.
+	dg, err = Build(context.Background(), d, "2024-06-05", time.UTC)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if dg.Sessions != 1 || len(dg.Events) != 0 {
+		t.Errorf("quiet digest = %+v", dg)
+	}
+	if dg.Body() != "1 session, 1 messages, 1 project, est. $0.00" {
+		t.Errorf("quiet body = %q", dg.Body())
+	}
+}
+
+func TestNotifyCommand(t *testing.T) {
+	title, body := `Say "hi"`, "it's $(rm -rf)"
+	tests := []struct {
+		goos string
+		bin  string
+		args []string
+	}{
+		{"darwin", "osascript", []string{title, body}},
+		{"linux", "notify-send", []string{title, body}},
+		{"windows", "powershell", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			cmd, err := notifyCommand(tt.goos, title, body)
+			if err != nil {
+				t.Fatalf("notifyCommand: %v", err)
+			}
+			if filepath.Base(cmd.Args[0]) != tt.bin {
+				t.Errorf("command = %q, want %s", cmd.Args[0], tt.bin)
+			}
+			// Text is passed verbatim as trailing arguments.
+			if tt.args != nil &&
+				!slices.Equal(cmd.Args[len(cmd.Args)-2:], tt.args) {
+				t.Errorf("args = %q, want suffix %q", cmd.Args, tt.args)
+			}
+			if tt.goos == "windows" &&
+				!slices.Contains(cmd.Env, "AGENTSVIEW_BODY="+body) {
+				t.Error("body not passed in environment")
+			}
+		})
+	}
+	if _, err := notifyCommand("plan9", title, body); err == nil {
+		t.Error("expected error for unsupported platform")
+	}
+}
+
+type recordingNotifier struct {
+	titles chan string
+}
+
+func (n *recordingNotifier) Notify(title, _ string) error {
+	n.titles <- title
+	return nil
+}
+
+func TestSchedulerSendsOncePerDay(t *testing.T) {
+	d := seedDigestDB(t)
+	n := &recordingNotifier{titles: make(chan string, 4)}
+	now := time.Date(2024, 6, 11, 10, 0, 0, 0, time.UTC)
+	s := &Scheduler{
+		DB:        d,
+		Notifier:  n,
+		Minute:    9 * 60,
+		Location:  time.UTC,
+		StatePath: filepath.Join(t.TempDir(), "digest_sent"),
+		now:       func() time.Time { return now },
+	}
+
+	if got := s.dueAt(now); !got.Equal(
+		time.Date(2024, 6, 11, 9, 0, 0, 0, time.UTC),
+	) {
+		t.Errorf("dueAt = %v", got)
+	}
+
+	// Started after the fire time: catch up immediately.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	select {
+	case title := <-n.titles:
+		if title != "agentsview: Mon Jun 10" {
+			t.Errorf("title = %q", title)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("digest not sent")
+	}
+	cancel()
+	<-done
+
+	// Already sent today: a restart does not repeat it.
+	s.sendIfNew(context.Background(), now)
+	select {
+	case title := <-n.titles:
+		t.Errorf("digest sent twice: %q", title)
+	default:
+	}
+	if got := s.lastSent(); got != "2024-06-11" {
+		t.Errorf("lastSent = %q, want 2024-06-11", got)
+	}
+}
@@ -0,0 +1,73 @@
+package digest
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// Notifier shows a desktop notification.
+type Notifier interface {
+	Notify(title, body string) error
+}
+
+// DesktopNotifier shows notifications with the platform's
+// built-in tooling: osascript on macOS, notify-send on Linux
+// (libnotify), and a PowerShell tray balloon on Windows.
+type DesktopNotifier struct{}
+
+// Notify runs the platform notification command.
+func (DesktopNotifier) Notify(title, body string) error {
+	cmd, err := notifyCommand(runtime.GOOS, title, body)
+	if err != nil {
+		return err
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.Path, err, out)
+	}
+	return nil
+}
+
+// windowsBalloon shows a tray balloon tip. Title and body come
+// from the environment so they need no PowerShell quoting.
+const windowsBalloon = `Add-Type -AssemblyName System.Windows.Forms
+$n = New-Object System.Windows.Forms.NotifyIcon
+$n.Icon = [System.Drawing.SystemIcons]::Information
+$n.Visible = $true
+$n.ShowBalloonTip(10000, $env:AGENTSVIEW_TITLE, $env:AGENTSVIEW_BODY, 'Info')
+Start-Sleep -Seconds 10
+$n.Dispose()`
+
+// notifyCommand builds the notification command for goos.
+// Title and body are passed as arguments or environment
+// variables, never spliced into a script.
+func notifyCommand(goos, title, body string) (*exec.Cmd, error) {
+	switch goos {
+	case "darwin":
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv)"+
+				" with title (item 1 of argv)",
+			"-e", "end run",
+			title, body,
+		), nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return exec.Command("notify-send",
+			"--app-name=agentsview", title, body,
+		), nil
+	case "windows":
+		cmd := exec.Command("powershell",
+			"-NoProfile", "-NonInteractive",
+			"-Command", windowsBalloon,
+		)
+		cmd.Env = append(cmd.Environ(),
+			"AGENTSVIEW_TITLE="+title,
+			"AGENTSVIEW_BODY="+body,
+		)
+		return cmd, nil
+	default:
+		return nil, fmt.Errorf(
+			"desktop notifications not supported on %s", goos,
+		)
+	}
+}
@@ -0,0 +1,95 @@
+package digest
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// Scheduler sends the previous day's digest once a day at a
+// configured local time. If the server starts after that time
+// and today's digest has not gone out, it is sent right away.
+type Scheduler struct {
+	DB       *db.DB
+	Notifier Notifier
+	// Minute is the time of day to fire, in minutes after
+	// midnight in Location.
+	Minute   int
+	Location *time.Location
+	// StatePath records the date of the last digest sent, so
+	// restarts do not repeat it.
+	StatePath string
+
+	now func() time.Time
+}
+
+// Run sends digests until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	for {
+		now := s.clock().In(s.Location)
+		due := s.dueAt(now)
+		if !now.Before(due) {
+			s.sendIfNew(ctx, now)
+			due = s.dueAt(now.AddDate(0, 0, 1))
+		}
+		timer := time.NewTimer(due.Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// dueAt returns the fire time on the calendar day of t.
+func (s *Scheduler) dueAt(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(
+		y, m, d, s.Minute/60, s.Minute%60, 0, 0, s.Location,
+	)
+}
+
+// sendIfNew sends the digest for the day before now unless it
+// was already sent today.
+func (s *Scheduler) sendIfNew(ctx context.Context, now time.Time) {
+	today := now.Format("2006-01-02")
+	if s.lastSent() == today {
+		return
+	}
+	yesterday := now.AddDate(0, 0, -1).Format("2006-01-02")
+	dg, err := Build(ctx, s.DB, yesterday, s.Location)
+	if err != nil {
+		slog.Warn("daily digest", "err", err)
+		return
+	}
+	if err := s.Notifier.Notify(dg.Title(), dg.Body()); err != nil {
+		slog.Warn("daily digest notification", "err", err)
+		return
+	}
+	if err := os.WriteFile(
+		s.StatePath, []byte(today+"\n"), 0o600,
+	); err != nil {
+		slog.Warn("recording daily digest", "err", err)
+	}
+	slog.Info("daily digest sent", "date", yesterday)
+}
+
+func (s *Scheduler) lastSent() string {
+	data, err := os.ReadFile(s.StatePath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func (s *Scheduler) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,196 @@
+// Package locale holds number, currency, and date conventions
+// for the locales agentsview can be configured with, so the UI
+// and server-rendered text agree on how figures look.
+package locale
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format describes how to render figures for one locale.
+type Format struct {
+	// Locale is the BCP 47 tag, such as de-DE, suitable for
+	// Intl.NumberFormat in the browser.
+	Locale           string `json:"locale"`
+	DecimalSeparator string `json:"decimal_separator"`
+	GroupSeparator   string `json:"group_separator"`
+	// Currency is the ISO 4217 code amounts are shown in.
+	Currency       string `json:"currency"`
+	CurrencySymbol string `json:"currency_symbol"`
+	// CurrencyAfter places the symbol after the amount,
+	// separated by a space ("1.234,50 €").
+	CurrencyAfter bool `json:"currency_after"`
+	// WeekStart is the first day of the week, ISO numbered
+	// like the analytics day-of-week filter: 0=Mon, 6=Sun.
+	WeekStart int `json:"week_start"`
+	// DateFormat is the numeric date pattern using YYYY, MM,
+	// and DD, such as DD.MM.YYYY.
+	DateFormat string `json:"date_format"`
+}
+
+// Default is used when no locale is configured: US number
+// style with ISO weeks and dates, matching how analytics
+// buckets weeks.
+var Default = Format{
+	Locale:           "en-US",
+	DecimalSeparator: ".",
+	GroupSeparator:   ",",
+	Currency:         "USD",
+	CurrencySymbol:   "$",
+	WeekStart:        0,
+	DateFormat:       "YYYY-MM-DD",
+}
+
+// Week starts, ISO numbered.
+const (
+	Monday   = 0
+	Saturday = 5
+	Sunday   = 6
+)
+
+// nbsp is the narrow no-break space French and Nordic locales
+// group digits with.
+const nbsp = "\u202f"
+
+// formats lists the supported locales. Lookup falls back from
+// an unknown region to the first entry for the language.
+var formats = []Format{
+	{"en-US", ".", ",", "USD", "", false, Sunday, "MM/DD/YYYY"},
+	{"en-GB", ".", ",", "GBP", "", false, Monday, "DD/MM/YYYY"},
+	{"en-CA", ".", ",", "CAD", "", false, Sunday, "YYYY-MM-DD"},
+	{"en-AU", ".", ",", "AUD", "", false, Monday, "DD/MM/YYYY"},
+	{"en-IN", ".", ",", "INR", "", false, Sunday, "DD/MM/YYYY"},
+	{"en-IE", ".", ",", "EUR", "", false, Monday, "DD/MM/YYYY"},
+	{"de-DE", ",", ".", "EUR", "", true, Monday, "DD.MM.YYYY"},
+	{"de-AT", ",", nbsp, "EUR", "", true, Monday, "DD.MM.YYYY"},
+	{"de-CH", ".", "’", "CHF", "", false, Monday, "DD.MM.YYYY"},
+	{"fr-FR", ",", nbsp, "EUR", "", true, Monday, "DD/MM/YYYY"},
+	{"fr-CA", ",", nbsp, "CAD", "", true, Sunday, "YYYY-MM-DD"},
+	{"fr-CH", ",", nbsp, "CHF", "", true, Monday, "DD.MM.YYYY"},
+	{"es-ES", ",", ".", "EUR", "", true, Monday, "DD/MM/YYYY"},
+	{"es-MX", ".", ",", "MXN", "", false, Sunday, "DD/MM/YYYY"},
+	{"it-IT", ",", ".", "EUR", "", true, Monday, "DD/MM/YYYY"},
+	{"nl-NL", ",", ".", "EUR", "", false, Monday, "DD-MM-YYYY"},
+	{"pt-BR", ",", ".", "BRL", "", false, Sunday, "DD/MM/YYYY"},
+	{"pt-PT", ",", nbsp, "EUR", "", true, Monday, "DD/MM/YYYY"},
+	{"sv-SE", ",", nbsp, "SEK", "", true, Monday, "YYYY-MM-DD"},
+	{"da-DK", ",", ".", "DKK", "", true, Monday, "DD.MM.YYYY"},
+	{"nb-NO", ",", nbsp, "NOK", "", true, Monday, "DD.MM.YYYY"},
+	{"fi-FI", ",", nbsp, "EUR", "", true, Monday, "DD.MM.YYYY"},
+	{"pl-PL", ",", nbsp, "PLN", "", true, Monday, "DD.MM.YYYY"},
+	{"ru-RU", ",", nbsp, "RUB", "", true, Monday, "DD.MM.YYYY"},
+	{"ja-JP", ".", ",", "JPY", "", false, Sunday, "YYYY/MM/DD"},
+	{"zh-CN", ".", ",", "CNY", "", false, Monday, "YYYY/MM/DD"},
+	{"ko-KR", ".", ",", "KRW", "", false, Sunday, "YYYY.MM.DD"},
+}
+
+// currencySymbols maps ISO 4217 codes to display symbols.
+// Codes not listed display as the code itself.
+var currencySymbols = map[string]string{
+	"USD": "$", "EUR": "€", "GBP": "£", "JPY": "¥", "CNY": "¥",
+	"KRW": "₩", "INR": "₹", "BRL": "R$", "CAD": "CA$",
+	"AUD": "A$", "MXN": "MX$", "CHF": "CHF", "SEK": "kr",
+	"DKK": "kr.", "NOK": "kr", "PLN": "zł", "RUB": "₽",
+}
+
+// currencyDecimals lists currencies without minor units.
+var currencyDecimals = map[string]int{"JPY": 0, "KRW": 0}
+
+// Lookup returns the format for a BCP 47 tag such as de-DE or
+// de_de. A tag whose region is unknown falls back to the
+// language's first listed locale.
+func Lookup(tag string) (Format, bool) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	if tag == "" {
+		return Format{}, false
+	}
+	lang, _, _ := strings.Cut(tag, "-")
+	var fallback *Format
+	for i := range formats {
+		f := &formats[i]
+		if strings.EqualFold(f.Locale, tag) {
+			return f.WithCurrency(f.Currency), true
+		}
+		fl, _, _ := strings.Cut(f.Locale, "-")
+		if fallback == nil && strings.EqualFold(fl, lang) {
+			fallback = f
+		}
+	}
+	if fallback == nil {
+		return Format{}, false
+	}
+	return fallback.WithCurrency(fallback.Currency), true
+}
+
+// WithCurrency returns f showing amounts in code.
+func (f Format) WithCurrency(code string) Format {
+	f.Currency = code
+	f.CurrencySymbol = code
+	if sym, ok := currencySymbols[code]; ok {
+		f.CurrencySymbol = sym
+	}
+	return f
+}
+
+// Number formats v with the given number of decimals and
+// digit grouping.
+func (f Format) Number(v float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	var b strings.Builder
+	if v < 0 && strings.Trim(s, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(f.GroupSeparator)
+		}
+		b.WriteRune(r)
+	}
+	if frac != "" {
+		b.WriteString(f.DecimalSeparator)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// Money formats an amount in f's currency.
+func (f Format) Money(v float64) string {
+	decimals, ok := currencyDecimals[f.Currency]
+	if !ok {
+		decimals = 2
+	}
+	n := f.Number(v, decimals)
+	if f.CurrencyAfter {
+		return n + " " + f.CurrencySymbol
+	}
+	if neg, ok := strings.CutPrefix(n, "-"); ok {
+		return "-" + f.CurrencySymbol + neg
+	}
+	return f.CurrencySymbol + n
+}
+
+// Date formats t's calendar date with DateFormat.
+func (f Format) Date(t time.Time) string {
+	return strings.NewReplacer(
+		"YYYY", t.Format("2006"),
+		"MM", t.Format("01"),
+		"DD", t.Format("02"),
+	).Replace(f.DateFormat)
+}
+
+// ParseWeekStart parses monday, saturday, or sunday.
+func ParseWeekStart(name string) (int, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "monday":
+		return Monday, true
+	case "saturday":
+		return Saturday, true
+	case "sunday":
+		return Sunday, true
+	}
+	return 0, false
+}
@@ -0,0 +1,67 @@
+package locale
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		tag    string
+		want   string
+		wantOK bool
+	}{
+		{"de-DE", "de-DE", true},
+		{"de_de", "de-DE", true},
+		{"de-LU", "de-DE", true}, // unknown region, same language
+		{"pt", "pt-BR", true},
+		{"xx-YY", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		f, ok := Lookup(tt.tag)
+		if ok != tt.wantOK || f.Locale != tt.want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q, %v",
+				tt.tag, f.Locale, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	us, _ := Lookup("en-US")
+	de, _ := Lookup("de-DE")
+	fr, _ := Lookup("fr-FR")
+	ja, _ := Lookup("ja-JP")
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"us number", us.Number(1234567.891, 2), "1,234,567.89"},
+		{"de number", de.Number(1234567.891, 2), "1.234.567,89"},
+		{"small", de.Number(999, 0), "999"},
+		{"negative", us.Number(-1234.5, 1), "-1,234.5"},
+		{"negative zero", us.Number(-0.001, 2), "0.00"},
+		{"us money", us.Money(-1234.5), "-$1,234.50"},
+		{"de money", de.Money(1234.5), "1.234,50 €"},
+		{"fr money", fr.Money(1234.5), "1\u202f234,50 €"},
+		{"yen", ja.Money(1234.5), "¥1,234"},
+		{"override", de.WithCurrency("XYZ").Money(1), "1,00 XYZ"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	day := time.Date(2024, 6, 9, 0, 0, 0, 0, time.UTC)
+	if got := us.Date(day); got != "06/09/2024" {
+		t.Errorf("us date = %q", got)
+	}
+	if got := de.Date(day); got != "09.06.2024" {
+		t.Errorf("de date = %q", got)
+	}
+	if us.WeekStart != Sunday || de.WeekStart != Monday {
+		t.Errorf("week starts = %d, %d", us.WeekStart, de.WeekStart)
+	}
+}
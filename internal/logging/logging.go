@@ -0,0 +1,168 @@
+// Package logging configures structured JSON logging to the
+// debug log file and reads entries back for the log viewer API.
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// FileName is the name of the debug log inside the data dir.
+const FileName = "debug.log"
+
+const (
+	// DefaultLimit is the default number of entries returned
+	// by ReadEntries.
+	DefaultLimit = 200
+	// MaxLimit is the maximum number of entries returned by
+	// ReadEntries.
+	MaxLimit = 1000
+)
+
+// maxLineSize bounds a single log line when reading entries
+// back. Longer lines are skipped.
+const maxLineSize = 1024 * 1024
+
+// Setup installs a JSON slog handler writing to w as the
+// process-wide default logger. Output from the standard log
+// package is routed through the same handler at Info level.
+func Setup(w io.Writer, level slog.Level) {
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: level,
+	})
+	slog.SetDefault(slog.New(h))
+}
+
+// ParseLevel parses a level name (debug, info, warn, error),
+// case-insensitively. The empty string yields Info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q", s)
+}
+
+// Entry is a single decoded log record.
+type Entry struct {
+	Time  time.Time      `json:"time"`
+	Level string         `json:"level"`
+	Msg   string         `json:"msg"`
+	Attrs map[string]any `json:"attrs,omitempty"`
+}
+
+// Filter selects which entries ReadEntries returns.
+type Filter struct {
+	MinLevel slog.Level // entries below this level are dropped
+	Since    time.Time  // zero = no lower bound
+	Limit    int        // most recent N entries
+}
+
+// ReadEntries decodes the JSON log at path and returns the most
+// recent entries matching f, oldest first. Lines that are not
+// JSON records (e.g. output from before structured logging was
+// enabled) are skipped. A missing file yields no entries.
+func ReadEntries(path string, f Filter) ([]Entry, error) {
+	if f.Limit <= 0 || f.Limit > MaxLimit {
+		f.Limit = DefaultLimit
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening log: %w", err)
+	}
+	defer file.Close()
+
+	entries := []Entry{}
+	r := bufio.NewReader(file)
+	for {
+		line, err := readLine(r)
+		if len(line) > 0 {
+			if e, ok := parseEntry(line); ok && matches(e, f) {
+				entries = append(entries, e)
+				if len(entries) > f.Limit {
+					entries = entries[1:]
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading log: %w", err)
+		}
+	}
+	return entries, nil
+}
+
+// readLine reads one newline-terminated line. Lines longer than
+// maxLineSize are consumed and returned empty.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return line, err
+		}
+		if len(line)+len(chunk) <= maxLineSize {
+			line = append(line, chunk...)
+		} else {
+			line = line[:0]
+		}
+		if !isPrefix {
+			if len(line) == 0 {
+				return nil, nil
+			}
+			return line, nil
+		}
+	}
+}
+
+func parseEntry(line []byte) (Entry, bool) {
+	var raw map[string]any
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return Entry{}, false
+	}
+	ts, _ := raw[slog.TimeKey].(string)
+	level, _ := raw[slog.LevelKey].(string)
+	msg, _ := raw[slog.MessageKey].(string)
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil || level == "" {
+		return Entry{}, false
+	}
+	delete(raw, slog.TimeKey)
+	delete(raw, slog.LevelKey)
+	delete(raw, slog.MessageKey)
+	e := Entry{Time: t, Level: level, Msg: msg}
+	if len(raw) > 0 {
+		e.Attrs = raw
+	}
+	return e, true
+}
+
+func matches(e Entry, f Filter) bool {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(e.Level)); err != nil {
+		return false
+	}
+	if lvl < f.MinLevel {
+		return false
+	}
+	return f.Since.IsZero() || !e.Time.Before(f.Since)
+}
@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"", slog.LevelInfo, false},
+		{"info", slog.LevelInfo, false},
+		{"DEBUG", slog.LevelDebug, false},
+		{"warn", slog.LevelWarn, false},
+		{"warning", slog.LevelWarn, false},
+		{" error ", slog.LevelError, false},
+		{"verbose", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseLevel(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("level = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeLog(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+	data := strings.Join(lines, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadEntries(t *testing.T) {
+	path := writeLog(t,
+		`2024/01/01 00:00:00 legacy plain-text line`,
+		`{"time":"2024-06-01T10:00:00Z","level":"INFO","msg":"sync done","sessions":3}`,
+		`{"time":"2024-06-01T11:00:00Z","level":"WARN","msg":"parse warning","path":"/a.jsonl"}`,
+		`{"time":"2024-06-01T12:00:00Z","level":"ERROR","msg":"watcher error","err":"boom"}`,
+		`{"not":"a record"}`,
+		`{"time":"2024-06-01T13:00:00Z","level":"DEBUG","msg":"noisy"}`,
+	)
+
+	tests := []struct {
+		name string
+		f    Filter
+		want []string
+	}{
+		{
+			name: "default level includes debug",
+			f:    Filter{MinLevel: slog.LevelDebug},
+			want: []string{
+				"sync done", "parse warning",
+				"watcher error", "noisy",
+			},
+		},
+		{
+			name: "warn and above",
+			f:    Filter{MinLevel: slog.LevelWarn},
+			want: []string{"parse warning", "watcher error"},
+		},
+		{
+			name: "since",
+			f: Filter{
+				MinLevel: slog.LevelInfo,
+				Since: time.Date(
+					2024, 6, 1, 11, 30, 0, 0, time.UTC,
+				),
+			},
+			want: []string{"watcher error"},
+		},
+		{
+			name: "limit keeps most recent",
+			f:    Filter{MinLevel: slog.LevelInfo, Limit: 2},
+			want: []string{"parse warning", "watcher error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadEntries(path, tt.f)
+			if err != nil {
+				t.Fatalf("ReadEntries: %v", err)
+			}
+			var msgs []string
+			for _, e := range got {
+				msgs = append(msgs, e.Msg)
+			}
+			if strings.Join(msgs, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("msgs = %v, want %v", msgs, tt.want)
+			}
+		})
+	}
+
+	got, err := ReadEntries(path, Filter{MinLevel: slog.LevelError})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Attrs["err"] != "boom" {
+		t.Errorf("attrs = %+v, want err=boom", got)
+	}
+}
+
+func TestReadEntries_MissingFile(t *testing.T) {
+	got, err := ReadEntries(
+		filepath.Join(t.TempDir(), FileName), Filter{},
+	)
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("got %d entries, want 0", len(got))
+	}
+}
+
+func TestSetup_RoundTrip(t *testing.T) {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var buf bytes.Buffer
+	Setup(&buf, slog.LevelInfo)
+	slog.Debug("hidden")
+	slog.Warn("visible", "key", "v")
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadEntries(path, Filter{MinLevel: slog.LevelDebug})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Msg != "visible" ||
+		got[0].Level != "WARN" || got[0].Attrs["key"] != "v" {
+		t.Errorf("entries = %+v", got)
+	}
+}
@@ -0,0 +1,223 @@
+// Package mcp serves session data to coding agents over the
+// Model Context Protocol: JSON-RPC 2.0 messages, one per line,
+// on stdin and stdout. It implements the lifecycle and tools
+// methods, which is all an agent needs to call the tools.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// protocolVersions are the MCP revisions this server speaks,
+// newest first. The newest is offered when a client asks for
+// one it does not know.
+var protocolVersions = []string{
+	"2025-06-18", "2025-03-26", "2024-11-05",
+}
+
+// maxMessageSize bounds one JSON-RPC message on stdin.
+const maxMessageSize = 16 << 20
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server answers MCP requests using a set of tools.
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+}
+
+// Tool is one callable MCP tool. Call returns the text shown to
+// the agent; an error is reported as a tool failure rather than
+// a protocol error, so the agent can correct its arguments.
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+
+	Call func(ctx context.Context, args json.RawMessage) (string, error) `json:"-"`
+}
+
+// NewServer returns a server that reports name and version to
+// clients and exposes tools.
+func NewServer(name, version string, tools []Tool) *Server {
+	return &Server{name: name, version: version, tools: tools}
+}
+
+// Serve reads requests from r and writes responses to w until r
+// is exhausted or ctx is canceled. Requests are handled in
+// order; notifications get no response.
+func (s *Server) Serve(
+	ctx context.Context, r io.Reader, w io.Writer,
+) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	enc := json.NewEncoder(w)
+	for sc.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		resp, ok := s.handle(ctx, line)
+		if !ok {
+			continue
+		}
+		if err := enc.Encode(resp); err != nil {
+			return fmt.Errorf("writing response: %w", err)
+		}
+	}
+	if err := sc.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading requests: %w", err)
+	}
+	return nil
+}
+
+// handle processes one message. It returns false for
+// notifications, which must not be answered.
+func (s *Server) handle(
+	ctx context.Context, line []byte,
+) (response, bool) {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(nil, codeParseError,
+			"parse error: "+err.Error()), true
+	}
+	if len(req.ID) == 0 {
+		return response{}, false
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest,
+			"invalid request"), true
+	}
+
+	var (
+		result any
+		rerr   *rpcError
+	)
+	switch req.Method {
+	case "initialize":
+		result, rerr = s.initialize(req.Params)
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = map[string]any{"tools": s.tools}
+	case "tools/call":
+		result, rerr = s.callTool(ctx, req.Params)
+	default:
+		rerr = &rpcError{
+			Code:    codeMethodNotFound,
+			Message: "method not found: " + req.Method,
+		}
+	}
+	if rerr != nil {
+		return response{JSONRPC: "2.0", ID: req.ID, Error: rerr}, true
+	}
+	return response{JSONRPC: "2.0", ID: req.ID, Result: result}, true
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{codeInvalidParams, err.Error()}
+		}
+	}
+	version := protocolVersions[0]
+	if slices.Contains(protocolVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities": map[string]any{
+			"tools": map[string]any{},
+		},
+		"serverInfo": map[string]string{
+			"name":    s.name,
+			"version": s.version,
+		},
+	}, nil
+}
+
+func (s *Server) callTool(
+	ctx context.Context, params json.RawMessage,
+) (any, *rpcError) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{codeInvalidParams, err.Error()}
+	}
+	i := slices.IndexFunc(s.tools, func(t Tool) bool {
+		return t.Name == p.Name
+	})
+	if i < 0 {
+		return nil, &rpcError{
+			codeInvalidParams, "unknown tool: " + p.Name,
+		}
+	}
+	args := p.Arguments
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	text, err := s.tools[i].Call(ctx, args)
+	isError := err != nil
+	if isError {
+		text = err.Error()
+	}
+	return map[string]any{
+		"content": []map[string]string{
+			{"type": "text", "text": text},
+		},
+		"isError": isError,
+	}, nil
+}
+
+func errorResponse(
+	id json.RawMessage, code int, msg string,
+) response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &rpcError{Code: code, Message: msg},
+	}
+}
@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/dbtest"
+)
+
+type testResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type toolResult struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+// serve runs the server over the given request lines and
+// returns the decoded responses.
+func serve(t *testing.T, s *Server, lines ...string) []testResponse {
+	t.Helper()
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(lines, "\n") + "\n")
+	if err := s.Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	var resps []testResponse
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var r testResponse
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decoding response: %v", err)
+		}
+		resps = append(resps, r)
+	}
+	return resps
+}
+
+func callTool(t *testing.T, s *Server, name, args string) toolResult {
+	t.Helper()
+	resps := serve(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/call",`+
+		`"params":{"name":"`+name+`","arguments":`+args+`}}`)
+	if len(resps) != 1 || resps[0].Error != nil {
+		t.Fatalf("tools/call %s: %+v", name, resps)
+	}
+	var r toolResult
+	if err := json.Unmarshal(resps[0].Result, &r); err != nil {
+		t.Fatalf("decoding tool result: %v", err)
+	}
+	if len(r.Content) != 1 || r.Content[0].Type != "text" {
+		t.Fatalf("content = %+v, want one text block", r.Content)
+	}
+	return r
+}
+
+func TestServeLifecycle(t *testing.T) {
+	s := NewServer("agentsview", "1.2.3", SessionTools(nil))
+	resps := serve(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resources/list"}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":4,"method":"ping"}`,
+	)
+	if len(resps) != 5 {
+		t.Fatalf("got %d responses, want 5 (notification unanswered)", len(resps))
+	}
+
+	var init struct {
+		ProtocolVersion string            `json:"protocolVersion"`
+		ServerInfo      map[string]string `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(resps[0].Result, &init); err != nil {
+		t.Fatal(err)
+	}
+	if init.ProtocolVersion != "2024-11-05" ||
+		init.ServerInfo["version"] != "1.2.3" {
+		t.Errorf("initialize = %+v", init)
+	}
+
+	var list struct {
+		Tools []Tool `json:"tools"`
+	}
+	if err := json.Unmarshal(resps[1].Result, &list); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range list.Tools {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got !=
+		"search_sessions,get_session_transcript,get_analytics_summary" {
+		t.Errorf("tools = %s", got)
+	}
+	if string(resps[1].ID) != `"two"` {
+		t.Errorf("id = %s, want \"two\"", resps[1].ID)
+	}
+
+	if e := resps[2].Error; e == nil || e.Code != codeMethodNotFound {
+		t.Errorf("resources/list error = %+v", e)
+	}
+	if e := resps[3].Error; e == nil || e.Code != codeParseError ||
+		string(resps[3].ID) != "null" {
+		t.Errorf("parse error response = %+v", resps[3])
+	}
+	if resps[4].Error != nil || string(resps[4].Result) != "{}" {
+		t.Errorf("ping = %+v", resps[4])
+	}
+}
+
+func TestSessionTools(t *testing.T) {
+	d := dbtest.OpenTestDB(t)
+	today := time.Now().UTC().Format(time.RFC3339)
+	dbtest.SeedSession(t, d, "s1", "my-app", func(s *db.Session) {
+		s.StartedAt = &today
+		s.MessageCount = 3
+	})
+	dbtest.SeedMessages(t, d,
+		dbtest.UserMsg("s1", 0, "why does the flaky widget test fail"),
+		dbtest.AsstMsg("s1", 1, "The widget test races the timer."),
+		dbtest.UserMsg("s1", 2, "thanks"),
+	)
+	s := NewServer("agentsview", "dev", SessionTools(d))
+
+	r := callTool(t, s, "search_sessions", `{"query":"widget"}`)
+	if r.IsError || !strings.Contains(r.Content[0].Text, "session s1 (my-app)") ||
+		!strings.Contains(r.Content[0].Text, "**widget**") {
+		t.Errorf("search = %+v", r)
+	}
+
+	r = callTool(t, s, "get_session_transcript",
+		`{"session_id":"s1","from_ordinal":1,"max_messages":1}`)
+	text := r.Content[0].Text
+	if r.IsError || !strings.Contains(text, "## [1] assistant") ||
+		strings.Contains(text, "thanks") ||
+		!strings.Contains(text, "from_ordinal 2") {
+		t.Errorf("transcript = %s", text)
+	}
+
+	r = callTool(t, s, "get_session_transcript", `{"session_id":"nope"}`)
+	if !r.IsError || !strings.Contains(r.Content[0].Text, "not found") {
+		t.Errorf("missing session = %+v", r)
+	}
+
+	r = callTool(t, s, "get_analytics_summary", `{"project":"my-app"}`)
+	var summary struct {
+		TotalSessions int `json:"total_sessions"`
+	}
+	if r.IsError {
+		t.Fatalf("summary error: %s", r.Content[0].Text)
+	}
+	if err := json.Unmarshal([]byte(r.Content[0].Text), &summary); err != nil ||
+		summary.TotalSessions != 1 {
+		t.Errorf("summary = %s (%v)", r.Content[0].Text, err)
+	}
+
+	r = callTool(t, s, "get_analytics_summary", `{"from":"June 1"}`)
+	if !r.IsError {
+		t.Errorf("bad date accepted: %+v", r)
+	}
+}
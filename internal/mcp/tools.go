@@ -0,0 +1,240 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// Limits for tool arguments.
+const (
+	defaultSearchResults  = 20
+	defaultTranscriptMsgs = 200
+	maxTranscriptMsgs     = 500
+	defaultSummaryDays    = 30
+)
+
+// SessionTools returns the tools that query the session
+// database: full-text search, transcripts, and the analytics
+// summary.
+func SessionTools(d *db.DB) []Tool {
+	return []Tool{
+		{
+			Name: "search_sessions",
+			Description: "Full-text search across messages in past " +
+				"coding agent sessions. Returns matching snippets " +
+				"with their session IDs and message ordinals.",
+			InputSchema: objectSchema(map[string]any{
+				"query":   stringProp("FTS5 search query"),
+				"project": stringProp("Only sessions in this project"),
+				"agent":   stringProp("Only sessions from this agent, such as claude or codex"),
+				"limit":   intProp("Maximum results (default 20)"),
+			}, "query"),
+			Call: func(ctx context.Context, raw json.RawMessage) (string, error) {
+				return searchSessions(ctx, d, raw)
+			},
+		},
+		{
+			Name: "get_session_transcript",
+			Description: "Read the messages of one session in order, " +
+				"optionally starting at a message ordinal from " +
+				"search_sessions.",
+			InputSchema: objectSchema(map[string]any{
+				"session_id":   stringProp("Session ID"),
+				"from_ordinal": intProp("First message ordinal (default 0)"),
+				"max_messages": intProp("Maximum messages (default 200, max 500)"),
+			}, "session_id"),
+			Call: func(ctx context.Context, raw json.RawMessage) (string, error) {
+				return sessionTranscript(ctx, d, raw)
+			},
+		},
+		{
+			Name: "get_analytics_summary",
+			Description: "Session, message, and project totals for a " +
+				"date range, split by agent.",
+			InputSchema: objectSchema(map[string]any{
+				"from":    stringProp("Start date YYYY-MM-DD (default 30 days ago)"),
+				"to":      stringProp("End date YYYY-MM-DD (default today)"),
+				"project": stringProp("Only this project"),
+				"agent":   stringProp("Only this agent"),
+			}),
+			Call: func(ctx context.Context, raw json.RawMessage) (string, error) {
+				return analyticsSummary(ctx, d, raw)
+			},
+		},
+	}
+}
+
+func searchSessions(
+	ctx context.Context, d *db.DB, raw json.RawMessage,
+) (string, error) {
+	var args struct {
+		Query   string `json:"query"`
+		Project string `json:"project"`
+		Agent   string `json:"agent"`
+		Limit   int    `json:"limit"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(args.Query) == "" {
+		return "", errors.New("query is required")
+	}
+	if args.Limit <= 0 {
+		args.Limit = defaultSearchResults
+	}
+	page, err := d.Search(ctx, db.SearchFilter{
+		Query:   args.Query,
+		Project: args.Project,
+		Agent:   args.Agent,
+		Limit:   args.Limit,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(page.Results) == 0 {
+		return "No matches.", nil
+	}
+	var b strings.Builder
+	for _, r := range page.Results {
+		snippet := strings.NewReplacer(
+			"<mark>", "**", "</mark>", "**",
+		).Replace(r.Snippet)
+		fmt.Fprintf(&b, "- session %s (%s), message %d, %s %s\n  %s\n",
+			r.SessionID, r.Project, r.Ordinal, r.Role, r.Timestamp,
+			snippet)
+	}
+	return b.String(), nil
+}
+
+func sessionTranscript(
+	ctx context.Context, d *db.DB, raw json.RawMessage,
+) (string, error) {
+	var args struct {
+		SessionID   string `json:"session_id"`
+		FromOrdinal int    `json:"from_ordinal"`
+		MaxMessages int    `json:"max_messages"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.SessionID == "" {
+		return "", errors.New("session_id is required")
+	}
+	if args.MaxMessages <= 0 {
+		args.MaxMessages = defaultTranscriptMsgs
+	}
+	args.MaxMessages = min(args.MaxMessages, maxTranscriptMsgs)
+
+	sess, err := d.GetSession(ctx, args.SessionID)
+	if err != nil {
+		return "", err
+	}
+	if sess == nil {
+		return "", fmt.Errorf("session %q not found", args.SessionID)
+	}
+	// One extra message tells whether the transcript goes on.
+	msgs, err := d.GetMessages(
+		ctx, args.SessionID, args.FromOrdinal, args.MaxMessages+1, true,
+	)
+	if err != nil {
+		return "", err
+	}
+	more := len(msgs) > args.MaxMessages
+	if more {
+		msgs = msgs[:args.MaxMessages]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s (%s, %s)\n", sess.ID, sess.Project, sess.Agent)
+	if sess.StartedAt != nil {
+		fmt.Fprintf(&b, "Started: %s\n", *sess.StartedAt)
+	}
+	fmt.Fprintf(&b, "Messages: %d\n", sess.MessageCount)
+	for _, m := range msgs {
+		fmt.Fprintf(&b, "\n## [%d] %s", m.Ordinal, m.Role)
+		if m.Timestamp != "" {
+			fmt.Fprintf(&b, " %s", m.Timestamp)
+		}
+		b.WriteString("\n")
+		b.WriteString(m.Content)
+		b.WriteString("\n")
+	}
+	if more {
+		fmt.Fprintf(&b,
+			"\n(more messages follow; call again with from_ordinal %d)\n",
+			msgs[len(msgs)-1].Ordinal+1)
+	}
+	return b.String(), nil
+}
+
+func analyticsSummary(
+	ctx context.Context, d *db.DB, raw json.RawMessage,
+) (string, error) {
+	var args struct {
+		From    string `json:"from"`
+		To      string `json:"to"`
+		Project string `json:"project"`
+		Agent   string `json:"agent"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	now := time.Now().UTC()
+	if args.To == "" {
+		args.To = now.Format("2006-01-02")
+	}
+	if args.From == "" {
+		args.From = now.AddDate(0, 0, -defaultSummaryDays).
+			Format("2006-01-02")
+	}
+	for _, date := range []string{args.From, args.To} {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return "", fmt.Errorf(
+				"invalid date %q: use YYYY-MM-DD", date,
+			)
+		}
+	}
+	summary, err := d.GetAnalyticsSummary(ctx, db.AnalyticsFilter{
+		From:     args.From,
+		To:       args.To,
+		Project:  args.Project,
+		Agent:    args.Agent,
+		Timezone: "UTC",
+	})
+	if err != nil {
+		return "", err
+	}
+	out, err := json.MarshalIndent(struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		db.AnalyticsSummary
+	}{args.From, args.To, summary}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func objectSchema(
+	props map[string]any, required ...string,
+) map[string]any {
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func stringProp(desc string) map[string]any {
+	return map[string]any{"type": "string", "description": desc}
+}
+
+func intProp(desc string) map[string]any {
+	return map[string]any{"type": "integer", "description": desc}
+}
@@ -0,0 +1,302 @@
+// Package metrics collects process counters and histograms and
+// writes them in the Prometheus text exposition format, so
+// self-hosted instances can be scraped without pulling in a
+// client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds, suited
+// to HTTP requests and sync runs.
+var DefaultBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60,
+}
+
+// collector is a metric family the registry can write.
+type collector interface {
+	write(w io.Writer) error
+	metricName() string
+}
+
+// Registry holds metric families in registration order.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+	names      map[string]bool
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{names: map[string]bool{}}
+}
+
+// Default is the registry the package-level constructors
+// register with and the /metrics endpoint writes.
+var Default = NewRegistry()
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[c.metricName()] {
+		panic("metrics: duplicate metric " + c.metricName())
+	}
+	r.names[c.metricName()] = true
+	r.collectors = append(r.collectors, c)
+}
+
+// Write writes every registered family to w.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	cs := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+	for _, c := range cs {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Counter is a monotonically increasing value, optionally
+// split by label values.
+type Counter struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64 // keyed by joined label values
+}
+
+// NewCounter registers a counter with Default.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// NewCounter registers a counter with r.
+func (r *Registry) NewCounter(
+	name, help string, labels ...string,
+) *Counter {
+	c := &Counter{
+		name: name, help: help, labels: labels,
+		values: map[string]float64{},
+	}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the series for labelValues.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds n to the series for labelValues. Negative n is
+// ignored, since counters only go up.
+func (c *Counter) Add(n float64, labelValues ...string) {
+	if n < 0 {
+		return
+	}
+	key := seriesKey(c.labels, labelValues)
+	c.mu.Lock()
+	c.values[key] += n
+	c.mu.Unlock()
+}
+
+func (c *Counter) metricName() string { return c.name }
+
+func (c *Counter) write(w io.Writer) error {
+	c.mu.Lock()
+	keys := sortedKeys(c.values)
+	vals := make([]float64, len(keys))
+	for i, k := range keys {
+		vals[i] = c.values[k]
+	}
+	c.mu.Unlock()
+
+	var b strings.Builder
+	writeHeader(&b, c.name, c.help, "counter")
+	for i, k := range keys {
+		fmt.Fprintf(&b, "%s%s %s\n",
+			c.name, labelString(c.labels, k, ""), formatFloat(vals[i]))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Histogram counts observations into cumulative buckets,
+// optionally split by label values.
+type Histogram struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram registers a histogram with Default. Nil buckets
+// use DefaultBuckets.
+func NewHistogram(
+	name, help string, buckets []float64, labels ...string,
+) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+// NewHistogram registers a histogram with r. Nil buckets use
+// DefaultBuckets.
+func (r *Registry) NewHistogram(
+	name, help string, buckets []float64, labels ...string,
+) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &Histogram{
+		name: name, help: help, labels: labels, buckets: buckets,
+		series: map[string]*histogramSeries{},
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records v in the series for labelValues.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := seriesKey(h.labels, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[key]
+	if s == nil {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *Histogram) metricName() string { return h.name }
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mu.Lock()
+	keys := sortedKeys(h.series)
+	snap := make([]histogramSeries, len(keys))
+	for i, k := range keys {
+		s := h.series[k]
+		snap[i] = histogramSeries{
+			counts: append([]uint64(nil), s.counts...),
+			count:  s.count,
+			sum:    s.sum,
+		}
+	}
+	h.mu.Unlock()
+
+	var b strings.Builder
+	writeHeader(&b, h.name, h.help, "histogram")
+	for i, k := range keys {
+		s := snap[i]
+		var cum uint64
+		for j, le := range h.buckets {
+			cum += s.counts[j]
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", h.name,
+				labelString(h.labels, k, formatFloat(le)), cum)
+		}
+		fmt.Fprintf(&b, "%s_bucket%s %d\n", h.name,
+			labelString(h.labels, k, "+Inf"), s.count)
+		fmt.Fprintf(&b, "%s_sum%s %s\n", h.name,
+			labelString(h.labels, k, ""), formatFloat(s.sum))
+		fmt.Fprintf(&b, "%s_count%s %d\n", h.name,
+			labelString(h.labels, k, ""), s.count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteGauge writes a single unlabelled gauge sample, for
+// values such as database size that are read at scrape time
+// rather than tracked.
+func WriteGauge(w io.Writer, name, help string, v float64) error {
+	var b strings.Builder
+	writeHeader(&b, name, help, "gauge")
+	fmt.Fprintf(&b, "%s %s\n", name, formatFloat(v))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labelSep joins label values into a series key. It cannot
+// appear in a valid UTF-8 label value.
+const labelSep = "\xff"
+
+func seriesKey(labels, values []string) string {
+	if len(values) != len(labels) {
+		panic(fmt.Sprintf(
+			"metrics: got %d label values, want %d",
+			len(values), len(labels),
+		))
+	}
+	return strings.Join(values, labelSep)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeader(b *strings.Builder, name, help, typ string) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, typ)
+}
+
+// labelString renders {a="x",b="y"} for the series key, plus
+// an le label for histogram buckets when le is set.
+func labelString(labels []string, key, le string) string {
+	if len(labels) == 0 && le == "" {
+		return ""
+	}
+	var parts []string
+	if len(labels) > 0 {
+		for i, v := range strings.Split(key, labelSep) {
+			parts = append(parts,
+				labels[i]+`="`+escapeLabel(v)+`"`)
+		}
+	}
+	if le != "" {
+		parts = append(parts, `le="`+le+`"`)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	files := r.NewCounter(
+		"test_files_total", "Files parsed.", "agent",
+	)
+	files.Inc("codex")
+	files.Add(2, "claude")
+	files.Add(-1, "claude")
+	latency := r.NewHistogram(
+		"test_seconds", "Request latency.", []float64{1, 0.1},
+		"route",
+	)
+	latency.Observe(0.05, `GET /a "b"`)
+	latency.Observe(0.5, `GET /a "b"`)
+	latency.Observe(3, `GET /a "b"`)
+
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := WriteGauge(&b, "test_bytes", "Size.", 4096); err != nil {
+		t.Fatalf("WriteGauge: %v", err)
+	}
+	want := `# HELP test_files_total Files parsed.
+# TYPE test_files_total counter
+test_files_total{agent="claude"} 2
+test_files_total{agent="codex"} 1
+# HELP test_seconds Request latency.
+# TYPE test_seconds histogram
+test_seconds_bucket{route="GET /a \"b\"",le="0.1"} 1
+test_seconds_bucket{route="GET /a \"b\"",le="1"} 2
+test_seconds_bucket{route="GET /a \"b\"",le="+Inf"} 3
+test_seconds_sum{route="GET /a \"b\""} 3.55
+test_seconds_count{route="GET /a \"b\""} 3
+# HELP test_bytes Size.
+# TYPE test_bytes gauge
+test_bytes 4096
+`
+	if got := b.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRegistryDuplicate(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("dup_total", "")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate name")
+		}
+	}()
+	r.NewCounter("dup_total", "")
+}
@@ -0,0 +1,411 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AiderHistoryFileName is the chat log Aider appends to in the
+// directory it runs in.
+const AiderHistoryFileName = ".aider.chat.history.md"
+
+// aiderMaxDepth bounds how many directories below a configured
+// project root discovery descends looking for chat logs.
+const aiderMaxDepth = 6
+
+// aiderSkipDirs are directory names discovery never enters.
+var aiderSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"__pycache__":  true,
+}
+
+const (
+	aiderSessionHeader = "# aider chat started at "
+	aiderUserPrefix    = "#### "
+	aiderOutputPrefix  = "> "
+	aiderTimeLayout    = "2006-01-02 15:04:05"
+	aiderIDTimeLayout  = "20060102T150405"
+)
+
+var (
+	aiderModelRe = regexp.MustCompile(
+		`^(?:Main model|Model): (\S+)`,
+	)
+	aiderTokenRe = regexp.MustCompile(
+		`([\d.,]+)([kM]?) (sent|received|cache write|cache hit)`,
+	)
+)
+
+// IsAiderHistoryPath reports whether rel, a slash- or
+// OS-separated path relative to a project root, names an Aider
+// chat log that discovery would find.
+func IsAiderHistoryPath(rel string) bool {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) > aiderMaxDepth+1 ||
+		parts[len(parts)-1] != AiderHistoryFileName {
+		return false
+	}
+	for _, dir := range parts[:len(parts)-1] {
+		if aiderSkipDir(dir) {
+			return false
+		}
+	}
+	return true
+}
+
+func aiderSkipDir(name string) bool {
+	return strings.HasPrefix(name, ".") || aiderSkipDirs[name]
+}
+
+// DiscoverAiderSessions walks a project root for Aider chat
+// logs, skipping hidden and dependency directories.
+func DiscoverAiderSessions(root string) []DiscoveredFile {
+	if root == "" {
+		return nil
+	}
+	var files []DiscoveredFile
+	_ = filepath.WalkDir(root, func(
+		path string, d fs.DirEntry, err error,
+	) error {
+		if err != nil {
+			if d != nil && d.IsDir() && path != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (aiderSkipDir(d.Name()) ||
+				strings.Count(filepath.ToSlash(rel), "/") >=
+					aiderMaxDepth) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && IsAiderHistoryPath(rel) {
+			files = append(files, DiscoveredFile{
+				Path:  path,
+				Agent: AgentAider,
+			})
+		}
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// FindAiderSourceFile locates the chat log holding the session
+// with the given raw ID (without the "aider:" prefix) under a
+// project root.
+func FindAiderSourceFile(root, rawID string) string {
+	hash, _, ok := strings.Cut(rawID, "-")
+	if !ok || root == "" {
+		return ""
+	}
+	for _, f := range DiscoverAiderSessions(root) {
+		if aiderPathHash(f.Path) == hash {
+			return f.Path
+		}
+	}
+	return ""
+}
+
+// aiderPathHash identifies a chat log in session IDs. Every
+// project's log has the same name, so the ID carries a hash of
+// its path.
+func aiderPathHash(path string) string {
+	return geminiPathHash(filepath.Clean(path))[:12]
+}
+
+// ParseAiderHistory parses an Aider chat log. Aider appends
+// every run to the same file under a "# aider chat started at"
+// header, so one file yields one session per run. User input is
+// written as "#### " lines, Aider's own output as "> " quotes,
+// and the model's reply as plain markdown. The per-reply
+// "Tokens:" report supplies usage for the reply above it.
+func ParseAiderHistory(
+	path, machine string,
+) ([]ParseResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	project := ExtractProjectFromCwd(filepath.Dir(path))
+	if project == "" {
+		project = "aider"
+	}
+	p := aiderParser{
+		path:    path,
+		machine: machine,
+		project: project,
+		file: FileInfo{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: info.ModTime().UnixNano(),
+		},
+		ids: map[string]int{},
+	}
+
+	lr := newLineReader(f, maxLineSize)
+	for {
+		line, err := lr.readLine()
+		if err != nil {
+			if err != io.EOF {
+				return nil, fmt.Errorf("reading %s: %w", path, err)
+			}
+			break
+		}
+		p.line(line)
+	}
+	p.finishSession()
+
+	if n := len(p.results); n > 0 {
+		p.results[n-1].Session.EndedAt = info.ModTime()
+	}
+	return p.results, nil
+}
+
+// aiderParser accumulates sessions while reading a chat log.
+type aiderParser struct {
+	path    string
+	machine string
+	project string
+	file    FileInfo
+	ids     map[string]int // session ID -> runs seen at that second
+
+	results []ParseResult
+
+	// Current session state; started is zero before the first
+	// header.
+	started  time.Time
+	id       string
+	msgs     []ParsedMessage
+	models   modelTracker
+	model    string
+	userBuf  []string
+	asstBuf  []string
+	inUser   bool
+	realUser int
+	firstMsg string
+}
+
+func (p *aiderParser) line(line string) {
+	if ts, ok := strings.CutPrefix(line, aiderSessionHeader); ok {
+		p.finishSession()
+		p.startSession(strings.TrimSpace(ts))
+		return
+	}
+	if p.id == "" {
+		return
+	}
+	if text, ok := strings.CutPrefix(line, aiderUserPrefix); ok {
+		if !p.inUser {
+			p.flushAssistant()
+			p.inUser = true
+		}
+		p.userBuf = append(p.userBuf, text)
+		return
+	}
+	if p.inUser {
+		p.flushUser()
+	}
+	if out, ok := strings.CutPrefix(line, aiderOutputPrefix); ok ||
+		line == ">" {
+		p.output(out)
+		return
+	}
+	p.asstBuf = append(p.asstBuf, line)
+}
+
+// output handles one line of Aider's own output. Only the model
+// announcement and token report are kept; the rest (applied
+// edits, commits, confirmations) is tool chatter.
+func (p *aiderParser) output(out string) {
+	if m := aiderModelRe.FindStringSubmatch(out); m != nil {
+		p.model = m[1]
+		return
+	}
+	if strings.HasPrefix(out, "Tokens: ") {
+		p.flushAssistant()
+		if i := p.lastAssistant(); i >= 0 {
+			p.msgs[i].Usage = parseAiderTokens(out)
+		}
+	}
+}
+
+func (p *aiderParser) startSession(ts string) {
+	started, err := time.ParseInLocation(
+		aiderTimeLayout, ts, time.Local,
+	)
+	if err != nil {
+		return
+	}
+	id := "aider:" + aiderPathHash(p.path) + "-" +
+		started.Format(aiderIDTimeLayout)
+	p.ids[id]++
+	if n := p.ids[id]; n > 1 {
+		id += "-" + strconv.Itoa(n)
+	}
+	p.started = started
+	p.id = id
+	p.msgs = nil
+	p.models = modelTracker{}
+	p.model = ""
+	p.realUser = 0
+	p.firstMsg = ""
+}
+
+func (p *aiderParser) finishSession() {
+	if p.id == "" {
+		return
+	}
+	p.flushUser()
+	p.flushAssistant()
+	if len(p.msgs) > 0 {
+		p.results = append(p.results, ParseResult{
+			Session: ParsedSession{
+				ID:               p.id,
+				Project:          p.project,
+				Machine:          p.machine,
+				Agent:            AgentAider,
+				FirstMessage:     p.firstMsg,
+				StartedAt:        p.started,
+				EndedAt:          p.started,
+				MessageCount:     len(p.msgs),
+				UserMessageCount: p.realUser,
+				File:             p.file,
+			},
+			Messages: p.msgs,
+		})
+	}
+	p.id = ""
+	p.msgs = nil
+}
+
+func (p *aiderParser) flushUser() {
+	p.inUser = false
+	text := strings.TrimSpace(strings.Join(p.userBuf, "\n"))
+	p.userBuf = nil
+	if text == "" {
+		return
+	}
+	if strings.HasPrefix(text, "/") {
+		if strings.HasPrefix(text, "/model") {
+			p.models.request()
+		}
+	} else {
+		p.realUser++
+		if p.firstMsg == "" {
+			p.firstMsg = truncate(
+				strings.ReplaceAll(text, "\n", " "), 300,
+			)
+		}
+	}
+	p.msgs = append(p.msgs, ParsedMessage{
+		Ordinal:       len(p.msgs),
+		Role:          RoleUser,
+		Content:       text,
+		Timestamp:     p.timestamp(),
+		ContentLength: len(text),
+	})
+}
+
+func (p *aiderParser) flushAssistant() {
+	text := strings.TrimSpace(strings.Join(p.asstBuf, "\n"))
+	p.asstBuf = nil
+	if text == "" {
+		return
+	}
+	p.msgs = append(p.msgs, ParsedMessage{
+		Ordinal:       len(p.msgs),
+		Role:          RoleAssistant,
+		Content:       text,
+		Timestamp:     p.timestamp(),
+		ContentLength: len(text),
+		Model:         p.model,
+		ModelSwitch:   p.models.observe(p.model),
+	})
+}
+
+// timestamp is the session start for the first message; the log
+// records no other times.
+func (p *aiderParser) timestamp() time.Time {
+	if len(p.msgs) == 0 {
+		return p.started
+	}
+	return time.Time{}
+}
+
+// lastAssistant returns the index of the reply to the latest
+// user message, or -1 when it has none.
+func (p *aiderParser) lastAssistant() int {
+	for i := len(p.msgs) - 1; i >= 0; i-- {
+		switch p.msgs[i].Role {
+		case RoleAssistant:
+			return i
+		case RoleUser:
+			return -1
+		}
+	}
+	return -1
+}
+
+// parseAiderTokens reads a report such as "Tokens: 2.5k sent,
+// 1.1k cache write, 4.5k cache hit, 300 received." Aider's sent
+// count includes cached prompt tokens, which are split out to
+// match TokenUsage.
+func parseAiderTokens(report string) TokenUsage {
+	var u TokenUsage
+	var sent int
+	for _, m := range aiderTokenRe.FindAllStringSubmatch(report, -1) {
+		n := parseAiderCount(m[1], m[2])
+		switch m[3] {
+		case "sent":
+			sent = n
+		case "received":
+			u.OutputTokens = n
+		case "cache write":
+			u.CacheCreationTokens = n
+		case "cache hit":
+			u.CacheReadTokens = n
+		}
+	}
+	u.InputTokens = max(
+		sent-u.CacheCreationTokens-u.CacheReadTokens, 0,
+	)
+	return u
+}
+
+func parseAiderCount(num, suffix string) int {
+	v, err := strconv.ParseFloat(strings.ReplaceAll(num, ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	switch suffix {
+	case "k":
+		v *= 1e3
+	case "M":
+		v *= 1e6
+	}
+	return int(v + 0.5)
+}
@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const aiderHistory = `
+# aider chat started at 2024-05-01 10:00:00
+
+> /usr/local/bin/aider --model gpt-4o
+> Aider v0.50.0
+> Main model: gpt-4o with diff edit format
+> Git repo: .git with 10 files
+
+#### add a hello function
+#### to hello.py
+
+Sure, here is the change:
+
+hello.py
+` + "```" + `python
+def hello():
+    print("hi")
+` + "```" + `
+
+> Tokens: 2.5k sent, 1k cache hit, 300 received. Cost: $0.01 message, $0.01 session.
+> Applied edit to hello.py
+
+#### /model claude-3-5-sonnet-20241022
+
+> Main model: claude-3-5-sonnet-20241022 with diff edit format
+
+#### thanks
+
+You're welcome.
+
+> Tokens: 1,200 sent, 50 received.
+
+# aider chat started at 2024-05-02 09:30:00
+
+#### fix the tests
+
+Done.
+`
+
+func TestParseAiderHistory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "myproj")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	path := filepath.Join(dir, AiderHistoryFileName)
+	require.NoError(t, os.WriteFile(path, []byte(aiderHistory), 0o644))
+
+	results, err := ParseAiderHistory(path, "local")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	first := results[0]
+	hash := aiderPathHash(path)
+	assertSessionMeta(t, &first.Session,
+		"aider:"+hash+"-20240501T100000", "myproj", AgentAider,
+	)
+	assert.Equal(t, "add a hello function to hello.py",
+		first.Session.FirstMessage)
+	assert.Equal(t, 2, first.Session.UserMessageCount)
+	assert.True(t, first.Session.StartedAt.Equal(
+		time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local),
+	))
+
+	msgs := first.Messages
+	require.Len(t, msgs, 5)
+	assertMessage(t, msgs[0], RoleUser, "add a hello function\nto hello.py")
+	assertMessage(t, msgs[1], RoleAssistant, `print("hi")`)
+	assert.Equal(t, "gpt-4o", msgs[1].Model)
+	assert.Equal(t, TokenUsage{
+		InputTokens: 1500, CacheReadTokens: 1000, OutputTokens: 300,
+	}, msgs[1].Usage)
+	assertMessage(t, msgs[2], RoleUser, "/model")
+	assertMessage(t, msgs[4], RoleAssistant, "You're welcome.")
+	assert.Equal(t, "claude-3-5-sonnet-20241022", msgs[4].Model)
+	assert.Equal(t, ModelSwitchCommand, msgs[4].ModelSwitch)
+	assert.Equal(t, 1200, msgs[4].Usage.InputTokens)
+
+	second := results[1]
+	assert.Equal(t, "aider:"+hash+"-20240502T093000", second.Session.ID)
+	assert.Equal(t, 2, second.Session.MessageCount)
+	assert.Equal(t, "fix the tests", second.Session.FirstMessage)
+}
+
+func TestDiscoverAiderSessions(t *testing.T) {
+	root := t.TempDir()
+	for _, rel := range []string{
+		"a/" + AiderHistoryFileName,
+		"group/b/" + AiderHistoryFileName,
+		"a/node_modules/pkg/" + AiderHistoryFileName,
+		".cache/c/" + AiderHistoryFileName,
+		"a/notes.md",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, nil, 0o644))
+	}
+
+	files := DiscoverAiderSessions(root)
+	require.Len(t, files, 2)
+	want := filepath.Join(root, "group", "b", AiderHistoryFileName)
+	assert.Equal(t, want, files[1].Path)
+	assert.Equal(t, AgentAider, files[1].Agent)
+
+	assert.Equal(t, want, FindAiderSourceFile(
+		root, aiderPathHash(want)+"-20240501T100000",
+	))
+	assert.Empty(t, FindAiderSourceFile(root, "nope"))
+}
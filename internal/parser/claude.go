@@ -4,7 +4,7 @@ package parser
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -42,156 +42,284 @@ type dagEntry struct {
 func ParseClaudeSession(
 	path, project, machine string,
 ) ([]ParseResult, error) {
+	results, _, err := ParseClaudeSessionTail(path, project, machine)
+	return results, err
+}
+
+// ParseClaudeSessionTail is ParseClaudeSession that also returns
+// a ClaudeTail for parsing lines appended to the file later, or
+// nil when such lines could change what was already parsed.
+func ParseClaudeSessionTail(
+	path, project, machine string,
+) ([]ParseResult, *ClaudeTail, error) {
 	info, err := os.Stat(path)
 	if err != nil {
-		return nil, fmt.Errorf("stat %s: %w", path, err)
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
 	}
 
 	sessionID := strings.TrimSuffix(filepath.Base(path), ".jsonl")
 
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("open %s: %w", path, err)
+		return nil, nil, fmt.Errorf("open %s: %w", path, err)
 	}
 	defer f.Close()
 
-	// First pass: collect all valid lines with metadata.
-	var (
-		entries         []dagEntry
-		hasAnyUUID      bool
-		allHaveUUID     bool
-		parentSessionID string
-		foundParentSID  bool
-		lineIndex       int
-		subagentMap     = map[string]string{}
-		globalStart     time.Time
-		globalEnd       time.Time
-	)
-	allHaveUUID = true
-
-	lr := newLineReader(f, maxLineSize)
+	// First pass: collect all valid lines with metadata. Only
+	// the bytes present at the stat are read, so the recorded
+	// file size describes exactly what was parsed.
+	scan := claudeScan{sessionID: sessionID, allHaveUUID: true}
+	lr := newLineReader(io.LimitReader(f, info.Size()), maxLineSize)
 	for {
 		line, ok := lr.next()
 		if !ok {
 			break
 		}
-		if !gjson.Valid(line) {
-			continue
+		scan.add(line)
+	}
+
+	if err := lr.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	fileInfo := FileInfo{
+		Path:  path,
+		Size:  info.Size(),
+		Mtime: info.ModTime().UnixNano(),
+	}
+
+	// If all user/assistant entries have uuids, use DAG-aware
+	// processing; otherwise fall back to linear processing.
+	var (
+		results []ParseResult
+		main    claudeMain
+	)
+	if scan.hasAnyUUID && scan.allHaveUUID {
+		results, err = parseDAG(
+			scan.entries, sessionID, project, machine,
+			scan.parentSessionID, fileInfo, scan.subagentMap,
+			scan.globalStart, scan.globalEnd, &main,
+		)
+	} else {
+		results, err = parseLinear(
+			scan.entries, sessionID, project, machine,
+			scan.parentSessionID, fileInfo, scan.subagentMap,
+			scan.globalStart, scan.globalEnd, &main,
+		)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	worktree := WorktreeName(BridgePath(path, scan.cwd), scan.gitBranch)
+	entryPoint := ClassifyEntryPoint(scan.client)
+	for i := range results {
+		results[i].Session.Automated = scan.automated
+		results[i].Session.GitBranch = scan.gitBranch
+		results[i].Session.Worktree = worktree
+		results[i].Session.Client = scan.client
+		results[i].Session.EntryPoint = entryPoint
+		results[i].Session.Environment = scan.env
+		results[i].Session.Todos, results[i].Session.TodosAt =
+			latestTodos(results[i].Messages)
+		// SDK stream-json transcripts may carry no timestamps;
+		// date them by the file so they still sort and count.
+		if scan.automated && results[i].Session.StartedAt.IsZero() {
+			mtime := info.ModTime().UTC()
+			results[i].Session.StartedAt = mtime
+			results[i].Session.EndedAt = mtime
 		}
+	}
+	// Hook events, commands, and unknown records belong to the
+	// file's main session; fork results share its history.
+	if len(results) > 0 {
+		results[0].Session.HookEvents = scan.hooks
+		results[0].Session.Commands = scan.commands
+		results[0].Session.UnknownRecords = scan.unknown.records
+	}
+	return results, newClaudeTail(f, &scan, &main, results), nil
+}
 
-		entryType := gjson.Get(line, "type").Str
+// claudeScan is the state of the first pass over a Claude Code
+// transcript: its user/assistant entries plus what the other
+// record types say about the session.
+type claudeScan struct {
+	sessionID       string
+	entries         []dagEntry
+	hasAnyUUID      bool
+	allHaveUUID     bool
+	parentSessionID string
+	foundParentSID  bool
+	subagentMap     map[string]string
+	globalStart     time.Time
+	globalEnd       time.Time
+	automated       bool
+	hooks           []ParsedHookEvent
+	commands        []ParsedCommand
+	unknown         unknownRecords
+	cwd, gitBranch  string
+	client          string
+	env             envSnapshot
+
+	// mapped lists the tool_use ids added to subagentMap, in
+	// the order their records were read.
+	mapped []string
+}
 
-		// Track global timestamps from all lines for session
-		// bounds, including non-message events.
-		if ts := extractTimestamp(line); !ts.IsZero() {
-			if globalStart.IsZero() || ts.Before(globalStart) {
-				globalStart = ts
-			}
-			if ts.After(globalEnd) {
-				globalEnd = ts
-			}
+// add records one transcript line.
+func (s *claudeScan) add(line string) {
+	if !gjson.Valid(line) {
+		return
+	}
+
+	entryType := gjson.Get(line, "type").Str
+	if !claudeKnownTypes[entryType] {
+		s.unknown.observe(entryType, line)
+	}
+
+	if !s.automated {
+		s.automated = isClaudeHeadlessLine(entryType, line)
+	}
+	if s.client == "" {
+		s.client = gjson.Get(line, "entrypoint").Str
+	}
+	s.env.observeClaude(entryType, line)
+
+	// Track global timestamps from all lines for session
+	// bounds, including non-message events.
+	if ts := extractTimestamp(line); !ts.IsZero() {
+		if s.globalStart.IsZero() || ts.Before(s.globalStart) {
+			s.globalStart = ts
 		}
+		if ts.After(s.globalEnd) {
+			s.globalEnd = ts
+		}
+	}
 
-		// Collect queue-operation enqueue entries for subagent mapping.
-		if entryType == "queue-operation" {
-			if gjson.Get(line, "operation").Str == "enqueue" {
-				contentStr := gjson.Get(line, "content").Str
-				if contentStr != "" {
-					tuid := gjson.Get(contentStr, "tool_use_id").Str
-					taskID := gjson.Get(contentStr, "task_id").Str
-					if tuid == "" || taskID == "" {
-						// Fallback: extract from XML <task-id> and <tool-use-id> tags.
-						if m := xmlTaskIDRe.FindStringSubmatch(contentStr); m != nil {
-							taskID = m[1]
-						}
-						if m := xmlToolUseRe.FindStringSubmatch(contentStr); m != nil {
-							tuid = m[1]
-						}
+	// Collect queue-operation enqueue entries for subagent mapping.
+	if entryType == "queue-operation" {
+		if gjson.Get(line, "operation").Str == "enqueue" {
+			contentStr := gjson.Get(line, "content").Str
+			if contentStr != "" {
+				tuid := gjson.Get(contentStr, "tool_use_id").Str
+				taskID := gjson.Get(contentStr, "task_id").Str
+				if tuid == "" || taskID == "" {
+					// Fallback: extract from XML <task-id> and <tool-use-id> tags.
+					if m := xmlTaskIDRe.FindStringSubmatch(contentStr); m != nil {
+						taskID = m[1]
 					}
-					if tuid != "" && taskID != "" {
-						subagentMap[tuid] = "agent-" + taskID
+					if m := xmlToolUseRe.FindStringSubmatch(contentStr); m != nil {
+						tuid = m[1]
 					}
 				}
+				if tuid != "" && taskID != "" {
+					s.mapSubagent(tuid, "agent-"+taskID)
+				}
 			}
-			continue
 		}
+		return
+	}
 
-		// Collect agent_progress events for subagent mapping.
-		// Claude Code v2.1+ emits these instead of queue-operation for Agent tool calls.
-		if entryType == "progress" {
-			if gjson.Get(line, "data.type").Str == "agent_progress" {
-				tuid := gjson.Get(line, "parentToolUseID").Str
-				agentID := gjson.Get(line, "data.agentId").Str
-				if tuid != "" && agentID != "" {
-					subagentMap[tuid] = "agent-" + agentID
-				}
+	// Collect agent_progress events for subagent mapping.
+	// Claude Code v2.1+ emits these instead of queue-operation for Agent tool calls.
+	if entryType == "progress" {
+		if gjson.Get(line, "data.type").Str == "agent_progress" {
+			tuid := gjson.Get(line, "parentToolUseID").Str
+			agentID := gjson.Get(line, "data.agentId").Str
+			if tuid != "" && agentID != "" {
+				s.mapSubagent(tuid, "agent-"+agentID)
 			}
-			continue
 		}
+		return
+	}
 
-		if entryType != "user" && entryType != "assistant" {
-			continue
-		}
+	if hook, ok := parseClaudeHookLine(entryType, line); ok {
+		s.hooks = append(s.hooks, hook)
+	}
+	if cmd, ok := parseClaudeCommandLine(entryType, line); ok {
+		s.commands = append(s.commands, cmd)
+	}
 
-		// Check parentSessionID from first user/assistant entry.
-		if !foundParentSID {
-			if sid := gjson.Get(line, "sessionId").Str; sid != "" {
-				foundParentSID = true
-				if sid != sessionID {
-					parentSessionID = sid
-				}
+	if entryType != "user" && entryType != "assistant" {
+		return
+	}
+
+	// Check parentSessionID from first user/assistant entry.
+	if !s.foundParentSID {
+		sid := gjson.Get(line, "sessionId").Str
+		if sid == "" {
+			// Agent SDK stream-json records use snake_case.
+			sid = gjson.Get(line, "session_id").Str
+		}
+		if sid != "" {
+			s.foundParentSID = true
+			if sid != s.sessionID {
+				s.parentSessionID = sid
 			}
 		}
+	}
 
-		uuid := gjson.Get(line, "uuid").Str
-		parentUuid := gjson.Get(line, "parentUuid").Str
-
-		if uuid != "" {
-			hasAnyUUID = true
-		} else {
-			allHaveUUID = false
-		}
+	if s.cwd == "" {
+		s.cwd = gjson.Get(line, "cwd").Str
+	}
+	if s.gitBranch == "" {
+		s.gitBranch = gjson.Get(line, "gitBranch").Str
+	}
 
-		ts := extractTimestamp(line)
+	uuid := gjson.Get(line, "uuid").Str
+	parentUuid := gjson.Get(line, "parentUuid").Str
 
-		entries = append(entries, dagEntry{
-			uuid:       uuid,
-			parentUuid: parentUuid,
-			entryType:  entryType,
-			lineIndex:  lineIndex,
-			line:       line,
-			timestamp:  ts,
-		})
-		lineIndex++
+	if uuid != "" {
+		s.hasAnyUUID = true
+	} else {
+		s.allHaveUUID = false
 	}
 
-	if err := lr.Err(); err != nil {
-		return nil, fmt.Errorf("reading %s: %w", path, err)
-	}
+	s.entries = append(s.entries, dagEntry{
+		uuid:       uuid,
+		parentUuid: parentUuid,
+		entryType:  entryType,
+		lineIndex:  len(s.entries),
+		line:       line,
+		timestamp:  extractTimestamp(line),
+	})
+}
 
-	fileInfo := FileInfo{
-		Path:  path,
-		Size:  info.Size(),
-		Mtime: info.ModTime().UnixNano(),
+// mapSubagent links the Task or Agent tool call tuid to the
+// subagent session it started.
+func (s *claudeScan) mapSubagent(tuid, sessionID string) {
+	if s.subagentMap == nil {
+		s.subagentMap = map[string]string{}
 	}
+	s.subagentMap[tuid] = sessionID
+	s.mapped = append(s.mapped, tuid)
+}
 
-	// If all user/assistant entries have uuids, use DAG-aware processing.
-	if hasAnyUUID && allHaveUUID {
-		return parseDAG(
-			entries, sessionID, project, machine,
-			parentSessionID, fileInfo, subagentMap,
-			globalStart, globalEnd,
-		)
+// isClaudeHeadlessLine reports whether a transcript line marks
+// the session as a programmatic run: an SDK entrypoint, or the
+// system init and result records that Agent SDK stream-json
+// output carries in place of interactive TTY records.
+func isClaudeHeadlessLine(entryType, line string) bool {
+	switch entryType {
+	case "result":
+		return gjson.Get(line, "session_id").Exists()
+	case "system":
+		if gjson.Get(line, "subtype").Str == "init" &&
+			gjson.Get(line, "session_id").Exists() {
+			return true
+		}
 	}
-
-	// Fall back to linear processing.
-	return parseLinear(
-		entries, sessionID, project, machine,
-		parentSessionID, fileInfo, subagentMap,
-		globalStart, globalEnd,
+	return isClaudeHeadlessEntrypoint(
+		gjson.Get(line, "entrypoint").Str,
 	)
 }
 
+// isClaudeHeadlessEntrypoint reports whether a Claude Code
+// entrypoint value denotes a non-interactive run (claude -p or
+// the Agent SDK), e.g. "sdk-cli" or "sdk-ts".
+func isClaudeHeadlessEntrypoint(entrypoint string) bool {
+	return strings.HasPrefix(entrypoint, "sdk")
+}
+
 // parseLinear processes entries sequentially without DAG awareness.
 func parseLinear(
 	entries []dagEntry,
@@ -199,24 +327,17 @@ func parseLinear(
 	fileInfo FileInfo,
 	subagentMap map[string]string,
 	globalStart, globalEnd time.Time,
+	main *claudeMain,
 ) ([]ParseResult, error) {
-	messages, startedAt, endedAt := extractMessages(entries)
+	main.linear = true
+	main.leaf = len(entries) - 1
+	messages, startedAt, endedAt, interrupts, skipped :=
+		extractMessages(entries, &main.extract)
 	startedAt = earlierTime(globalStart, startedAt)
 	endedAt = laterTime(globalEnd, endedAt)
 	annotateSubagentSessions(messages, subagentMap)
 
-	userCount := 0
-	firstMsg := ""
-	for _, m := range messages {
-		if m.Role == RoleUser && m.Content != "" {
-			userCount++
-			if firstMsg == "" {
-				firstMsg = truncate(
-					strings.ReplaceAll(m.Content, "\n", " "), 300,
-				)
-			}
-		}
-	}
+	userCount, firstMsg := summarizeUserMessages(messages)
 
 	sess := ParsedSession{
 		ID:               sessionID,
@@ -229,7 +350,10 @@ func parseLinear(
 		EndedAt:          endedAt,
 		MessageCount:     len(messages),
 		UserMessageCount: userCount,
+		InterruptCount:   interrupts,
 		File:             fileInfo,
+
+		SkippedUserMessages: skipped,
 	}
 
 	return []ParseResult{{Session: sess, Messages: messages}}, nil
@@ -244,6 +368,7 @@ func parseDAG(
 	fileInfo FileInfo,
 	subagentMap map[string]string,
 	globalStart, globalEnd time.Time,
+	main *claudeMain,
 ) ([]ParseResult, error) {
 	// Build parent -> children ordered by line position and
 	// collect the set of all uuids for connectivity checks.
@@ -268,7 +393,7 @@ func parseDAG(
 		return parseLinear(
 			entries, sessionID, project, machine,
 			parentSessionID, fileInfo, subagentMap,
-			globalStart, globalEnd,
+			globalStart, globalEnd, main,
 		)
 	}
 	for _, e := range entries {
@@ -277,7 +402,7 @@ func parseDAG(
 				return parseLinear(
 					entries, sessionID, project, machine,
 					parentSessionID, fileInfo, subagentMap,
-					globalStart, globalEnd,
+					globalStart, globalEnd, main,
 				)
 			}
 		}
@@ -343,6 +468,7 @@ func parseDAG(
 	}
 
 	mainPath := walkBranch(roots[0], sessionID)
+	main.leaf = mainPath[len(mainPath)-1]
 	branches = append(
 		branches,
 		branch{indices: mainPath, parentID: parentSessionID},
@@ -358,7 +484,12 @@ func parseDAG(
 			branchEntries[j] = entries[idx]
 		}
 
-		messages, startedAt, endedAt := extractMessages(branchEntries)
+		st := &extractState{}
+		if i == 0 {
+			st = &main.extract
+		}
+		messages, startedAt, endedAt, interrupts, skipped :=
+			extractMessages(branchEntries, st)
 		// Main session uses global bounds to capture timestamps
 		// from non-message events (e.g. queue-operation).
 		if i == 0 {
@@ -367,18 +498,7 @@ func parseDAG(
 		}
 		annotateSubagentSessions(messages, subagentMap)
 
-		userCount := 0
-		firstMsg := ""
-		for _, m := range messages {
-			if m.Role == RoleUser && m.Content != "" {
-				userCount++
-				if firstMsg == "" {
-					firstMsg = truncate(
-						strings.ReplaceAll(m.Content, "\n", " "), 300,
-					)
-				}
-			}
-		}
+		userCount, firstMsg := summarizeUserMessages(messages)
 
 		sid := sessionID
 		pSID := b.parentID
@@ -404,7 +524,10 @@ func parseDAG(
 			EndedAt:          endedAt,
 			MessageCount:     len(messages),
 			UserMessageCount: userCount,
+			InterruptCount:   interrupts,
 			File:             fileInfo,
+
+			SkippedUserMessages: skipped,
 		}
 
 		results = append(results, ParseResult{
@@ -416,6 +539,36 @@ func parseDAG(
 	return results, nil
 }
 
+// summarizeUserMessages counts user turns and picks the first
+// message shown as the session's title. Slash commands count as
+// turns but only title a session with no typed prompt, so a
+// leading /clear does not name it; command output is neither.
+func summarizeUserMessages(
+	messages []ParsedMessage,
+) (count int, first string) {
+	var firstCommand string
+	for _, m := range messages {
+		if m.Role != RoleUser || m.Content == "" ||
+			m.Kind == MessageKindCommandOutput {
+			continue
+		}
+		count++
+		if m.Kind == MessageKindCommand {
+			if firstCommand == "" {
+				firstCommand = m.Content
+			}
+			continue
+		}
+		if first == "" {
+			first = m.Content
+		}
+	}
+	if first == "" {
+		first = firstCommand
+	}
+	return count, truncate(strings.ReplaceAll(first, "\n", " "), 300)
+}
+
 // countUserTurns counts the number of user entries reachable from
 // a starting index by following the first child at each node.
 func countUserTurns(
@@ -441,15 +594,22 @@ func countUserTurns(
 
 // extractMessages converts dagEntries into ParsedMessages, applying
 // the same filtering and content extraction as the original linear
-// parser.
-func extractMessages(entries []dagEntry) (
-	[]ParsedMessage, time.Time, time.Time,
+// parser. It also counts user interrupts, which are filtered out
+// as system messages, and the skipped injected user entries by
+// origin. Ordinals and model switches continue from st, which
+// is left where the entries end.
+func extractMessages(entries []dagEntry, st *extractState) (
+	[]ParsedMessage, time.Time, time.Time, int, map[string]int,
 ) {
 	var (
-		messages  []ParsedMessage
-		startedAt time.Time
-		endedAt   time.Time
-		ordinal   int
+		messages   []ParsedMessage
+		startedAt  time.Time
+		endedAt    time.Time
+		interrupts int
+		skipped    = map[string]int{}
+		// usageIdx is the message holding st.usageID's usage,
+		// or -1 when an earlier call credited it.
+		usageIdx = -1
 	)
 
 	for _, e := range entries {
@@ -464,6 +624,7 @@ func extractMessages(entries []dagEntry) (
 		if e.entryType == "user" {
 			if gjson.Get(e.line, "isMeta").Bool() ||
 				gjson.Get(e.line, "isCompactSummary").Bool() {
+				skipped[OriginSystem]++
 				continue
 			}
 		}
@@ -475,26 +636,116 @@ func extractMessages(entries []dagEntry) (
 			continue
 		}
 
-		// Tier 2: skip known system-injected patterns.
+		// Tier 2: skip known system-injected patterns, keeping
+		// slash commands and their output as typed messages.
+		var kind string
 		if e.entryType == "user" && isClaudeSystemMessage(text) {
-			continue
+			if isClaudeInterrupt(text) {
+				interrupts++
+			}
+			if isClaudeModelCommand(text) {
+				st.models.request()
+			}
+			origin := OriginSystem
+			if isClaudeHookFeedback(text) {
+				origin = OriginHook
+			}
+			var ok bool
+			if text, kind, ok = claudeCommandMessage(text); !ok {
+				skipped[origin]++
+				continue
+			}
+		}
+
+		var attached int
+		if e.entryType == "user" {
+			attached = attachedTextChars(content)
+		}
+
+		var model, modelSwitch string
+		if e.entryType == "assistant" {
+			model = gjson.Get(e.line, "message.model").Str
+			if model == claudeSyntheticModel {
+				model = ""
+			}
+			modelSwitch = st.models.observe(model)
 		}
 
 		messages = append(messages, ParsedMessage{
-			Ordinal:       ordinal,
+			Ordinal:       st.ordinal,
 			Role:          RoleType(e.entryType),
 			Content:       text,
 			Timestamp:     e.timestamp,
 			HasThinking:   hasThinking,
 			HasToolUse:    hasToolUse,
 			ContentLength: len(text),
+			Model:         model,
+			ModelSwitch:   modelSwitch,
+			Kind:          kind,
+			SourceUUID:    e.uuid,
+			AttachedChars: attached,
 			ToolCalls:     tcs,
 			ToolResults:   trs,
 		})
-		ordinal++
+		if e.entryType == "assistant" {
+			usageIdx = creditClaudeUsage(
+				messages, e.line, st, usageIdx,
+			)
+		}
+		st.ordinal++
+	}
+
+	return messages, startedAt, endedAt, interrupts, skipped
+}
+
+// creditClaudeUsage records the token usage of the assistant
+// entry just appended to messages. Claude Code writes each
+// content block of a response as its own entry, all repeating
+// the response's usage, so the usage is kept once, on the
+// response's latest message, whose output count is final. A
+// message from an earlier call that held the usage is added to
+// st.uncredited. It returns the index of the message now
+// holding st.usageID's usage.
+func creditClaudeUsage(
+	messages []ParsedMessage, line string,
+	st *extractState, usageIdx int,
+) int {
+	usage := claudeTokenUsage(gjson.Get(line, "message.usage"))
+	if usage == (TokenUsage{}) {
+		return usageIdx
 	}
+	last := len(messages) - 1
+	id := gjson.Get(line, "message.id").Str
+	switch {
+	case id == "":
+		messages[last].Usage = usage
+		return usageIdx
+	case id != st.usageID:
+		st.usageID = id
+	case usageIdx >= 0:
+		messages[usageIdx].Usage = TokenUsage{}
+	default:
+		// Credited to a message parsed before these entries.
+		st.uncredited = append(st.uncredited, st.usageOrdinal)
+	}
+	messages[last].Usage = usage
+	st.usageOrdinal = messages[last].Ordinal
+	return last
+}
 
-	return messages, startedAt, endedAt
+// claudeTokenUsage converts an Anthropic API usage object to
+// TokenUsage. Its input_tokens already exclude cache reads and
+// writes.
+func claudeTokenUsage(u gjson.Result) TokenUsage {
+	if !u.IsObject() {
+		return TokenUsage{}
+	}
+	return TokenUsage{
+		InputTokens:         int(u.Get("input_tokens").Int()),
+		OutputTokens:        int(u.Get("output_tokens").Int()),
+		CacheReadTokens:     int(u.Get("cache_read_input_tokens").Int()),
+		CacheCreationTokens: int(u.Get("cache_creation_input_tokens").Int()),
+	}
 }
 
 // annotateSubagentSessions sets SubagentSessionID on Task tool calls
@@ -598,7 +849,7 @@ func ExtractClaudeProjectHints(
 		}
 	}
 	if err := lr.Err(); err != nil {
-		log.Printf("reading hints from %s: %v", path, err)
+		logger().Warn("reading hints", "path", path, "err", err)
 	}
 	return cwd, gitBranch
 }
@@ -618,6 +869,22 @@ func truncate(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
+// isClaudeInterrupt reports whether a user message is the
+// marker Claude Code records when the user interrupts a turn.
+func isClaudeInterrupt(content string) bool {
+	return strings.HasPrefix(
+		strings.TrimSpace(content), "[Request interrupted",
+	)
+}
+
+// isClaudeModelCommand reports whether a user message records
+// the /model slash command.
+func isClaudeModelCommand(content string) bool {
+	return strings.Contains(
+		content, "<command-name>/model</command-name>",
+	)
+}
+
 // isClaudeSystemMessage returns true if the content matches
 // a known system-injected user message pattern.
 func isClaudeSystemMessage(content string) bool {
@@ -629,12 +896,12 @@ func isClaudeSystemMessage(content string) bool {
 		"<command-message>",
 		"<command-name>",
 		"<local-command-",
-		"Stop hook feedback:",
 	}
 	for _, p := range prefixes {
 		if strings.HasPrefix(trimmed, p) {
 			return true
 		}
 	}
-	return false
+	// Hook feedback is recorded as a hook event instead.
+	return isClaudeHookFeedback(trimmed)
 }
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// claudeCommandNameRe matches the tag Claude Code records when
+// the user runs a slash command, e.g.
+// <command-name>/review</command-name>.
+var claudeCommandNameRe = regexp.MustCompile(
+	`<command-name>/?([^<]+)</command-name>`,
+)
+
+// ParsedCommand is one slash command invocation in a Claude
+// Code session. Name has no leading slash.
+type ParsedCommand struct {
+	Name      string
+	Timestamp time.Time
+}
+
+// parseClaudeCommandLine extracts a slash command invocation
+// from a user transcript line. Command records are filtered
+// out of the message stream as system messages, so they are
+// collected separately.
+func parseClaudeCommandLine(
+	entryType, line string,
+) (ParsedCommand, bool) {
+	if entryType != "user" {
+		return ParsedCommand{}, false
+	}
+	content := gjson.Get(line, "message.content")
+	text := content.Str
+	if content.IsArray() {
+		content.ForEach(func(_, block gjson.Result) bool {
+			if block.Get("type").Str == "text" {
+				text = block.Get("text").Str
+				return false
+			}
+			return true
+		})
+	}
+	if !strings.Contains(text, "<command-name>") {
+		return ParsedCommand{}, false
+	}
+	m := claudeCommandNameRe.FindStringSubmatch(text)
+	if m == nil {
+		return ParsedCommand{}, false
+	}
+	name := strings.TrimSpace(m[1])
+	if name == "" {
+		return ParsedCommand{}, false
+	}
+	return ParsedCommand{
+		Name:      name,
+		Timestamp: extractTimestamp(line),
+	}, true
+}
+
+var (
+	claudeCommandArgsRe = regexp.MustCompile(
+		`(?s)<command-args>(.*?)</command-args>`,
+	)
+	claudeCommandOutputRe = regexp.MustCompile(
+		`(?s)<local-command-(?:stdout|stderr)>(.*?)` +
+			`</local-command-(?:stdout|stderr)>`,
+	)
+	// ansiEscapeRe matches the terminal color codes local
+	// command output is recorded with.
+	ansiEscapeRe = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+)
+
+// claudeCommandMessage rewrites a slash command record as
+// "/name args", or a local command's output as its plain text,
+// returning the MessageKind for it. Other system messages and
+// commands with no output report false.
+func claudeCommandMessage(text string) (string, string, bool) {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "<command-") &&
+		!strings.HasPrefix(trimmed, "<local-command-") {
+		return "", "", false
+	}
+	if m := claudeCommandNameRe.FindStringSubmatch(text); m != nil {
+		name := strings.TrimSpace(m[1])
+		if name == "" {
+			return "", "", false
+		}
+		cmd := "/" + name
+		if a := claudeCommandArgsRe.FindStringSubmatch(text); a != nil {
+			if args := strings.TrimSpace(a[1]); args != "" {
+				cmd += " " + args
+			}
+		}
+		return cmd, MessageKindCommand, true
+	}
+	var parts []string
+	for _, m := range claudeCommandOutputRe.FindAllStringSubmatch(text, -1) {
+		out := strings.TrimSpace(ansiEscapeRe.ReplaceAllString(m[1], ""))
+		if out != "" {
+			parts = append(parts, out)
+		}
+	}
+	if len(parts) == 0 {
+		return "", "", false
+	}
+	return strings.Join(parts, "\n"), MessageKindCommandOutput, true
+}
@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Hook decisions recorded for Claude Code hook events.
+const (
+	// HookDecisionAllow: the hook ran and let the agent continue.
+	HookDecisionAllow = "allow"
+	// HookDecisionBlock: the hook rejected the action and its
+	// message was fed back to the agent.
+	HookDecisionBlock = "block"
+	// HookDecisionModify: the hook injected extra context.
+	HookDecisionModify = "modify"
+	// HookDecisionStop: the hook halted the session.
+	HookDecisionStop = "stop"
+	// HookDecisionError: the hook failed without blocking.
+	HookDecisionError = "error"
+)
+
+// ParsedHookEvent is one hook outcome recorded in a Claude Code
+// session: PreToolUse/PostToolUse results, Stop hook feedback,
+// and similar.
+type ParsedHookEvent struct {
+	Event     string // PreToolUse, PostToolUse, Stop, ...
+	Name      string // event plus matcher, e.g. "PostToolUse:Edit"
+	ToolName  string
+	Command   string
+	Decision  string
+	Message   string
+	Timestamp time.Time
+}
+
+// claudeHookEvents are the hook event names Claude Code emits.
+var claudeHookEvents = map[string]bool{
+	"PreToolUse":       true,
+	"PostToolUse":      true,
+	"UserPromptSubmit": true,
+	"Notification":     true,
+	"Stop":             true,
+	"SubagentStop":     true,
+	"PreCompact":       true,
+	"SessionStart":     true,
+	"SessionEnd":       true,
+}
+
+var (
+	// "PostToolUse:Edit hook feedback:\n[cmd]: message"
+	hookFeedbackRe = regexp.MustCompile(
+		`^(\S+) hook feedback:\s*`,
+	)
+	// "PostToolUse:Edit [cmd] completed successfully: ..."
+	hookSystemRe = regexp.MustCompile(
+		`^(\S+) \[(.*?)\] (.*)$`,
+	)
+	// "[cmd]: message" at the start of a feedback body.
+	hookCommandRe = regexp.MustCompile(`^\[(.*?)\]:\s*`)
+)
+
+// splitHookName splits "PostToolUse:Edit" into event and tool.
+// ok is false when the event is not a Claude Code hook.
+func splitHookName(name string) (event, tool string, ok bool) {
+	event, tool, _ = strings.Cut(name, ":")
+	return event, tool, claudeHookEvents[event]
+}
+
+// isClaudeHookFeedback reports whether a user message is hook
+// feedback injected by Claude Code rather than typed text.
+func isClaudeHookFeedback(text string) bool {
+	m := hookFeedbackRe.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return false
+	}
+	_, _, ok := splitHookName(m[1])
+	return ok
+}
+
+// parseClaudeHookLine extracts a hook event from a Claude Code
+// JSONL line, if it records one. Three shapes are recognized:
+// user entries carrying "<hook> hook feedback:" text (a
+// blocking decision fed back to the model), system entries
+// summarizing a hook run, and attachment entries from newer
+// versions that describe the outcome explicitly.
+func parseClaudeHookLine(
+	entryType, line string,
+) (ParsedHookEvent, bool) {
+	switch entryType {
+	case "user":
+		return parseClaudeHookFeedback(line)
+	case "system":
+		return parseClaudeHookSystem(line)
+	case "attachment":
+		return parseClaudeHookAttachment(line)
+	}
+	return ParsedHookEvent{}, false
+}
+
+func parseClaudeHookFeedback(line string) (ParsedHookEvent, bool) {
+	if !strings.Contains(line, "hook feedback:") {
+		return ParsedHookEvent{}, false
+	}
+	text, _, _, _, _ := ExtractTextContent(
+		gjson.Get(line, "message.content"),
+	)
+	text = strings.TrimSpace(text)
+	m := hookFeedbackRe.FindStringSubmatch(text)
+	if m == nil {
+		return ParsedHookEvent{}, false
+	}
+	event, tool, ok := splitHookName(m[1])
+	if !ok {
+		return ParsedHookEvent{}, false
+	}
+	body := strings.TrimSpace(text[len(m[0]):])
+	var command string
+	if cm := hookCommandRe.FindStringSubmatch(body); cm != nil {
+		command = cm[1]
+		body = strings.TrimSpace(body[len(cm[0]):])
+	}
+	return ParsedHookEvent{
+		Event:     event,
+		Name:      m[1],
+		ToolName:  tool,
+		Command:   command,
+		Decision:  HookDecisionBlock,
+		Message:   body,
+		Timestamp: extractTimestamp(line),
+	}, true
+}
+
+func parseClaudeHookSystem(line string) (ParsedHookEvent, bool) {
+	content := strings.TrimSpace(gjson.Get(line, "content").Str)
+	m := hookSystemRe.FindStringSubmatch(content)
+	if m == nil {
+		return ParsedHookEvent{}, false
+	}
+	event, tool, ok := splitHookName(m[1])
+	if !ok {
+		return ParsedHookEvent{}, false
+	}
+	outcome, msg, _ := strings.Cut(m[3], ": ")
+	decision := HookDecisionError
+	switch {
+	case strings.HasPrefix(outcome, "completed successfully"):
+		decision = HookDecisionAllow
+	case strings.Contains(outcome, "non-blocking"):
+		decision = HookDecisionError
+	case strings.Contains(outcome, "blocking"),
+		strings.Contains(outcome, "denied"):
+		decision = HookDecisionBlock
+	case strings.Contains(outcome, "prevented continuation"):
+		decision = HookDecisionStop
+	}
+	return ParsedHookEvent{
+		Event:     event,
+		Name:      m[1],
+		ToolName:  tool,
+		Command:   m[2],
+		Decision:  decision,
+		Message:   strings.TrimSpace(msg),
+		Timestamp: extractTimestamp(line),
+	}, true
+}
+
+// claudeHookAttachmentDecisions maps attachment types to
+// decisions.
+var claudeHookAttachmentDecisions = map[string]string{
+	"hook_success":                HookDecisionAllow,
+	"hook_blocking_error":         HookDecisionBlock,
+	"hook_additional_context":     HookDecisionModify,
+	"hook_stopped_continuation":   HookDecisionStop,
+	"hook_non_blocking_error":     HookDecisionError,
+	"hook_error_during_execution": HookDecisionError,
+	"hook_cancelled":              HookDecisionError,
+}
+
+func parseClaudeHookAttachment(line string) (ParsedHookEvent, bool) {
+	att := gjson.Get(line, "attachment")
+	decision, ok := claudeHookAttachmentDecisions[att.Get("type").Str]
+	if !ok {
+		return ParsedHookEvent{}, false
+	}
+	name := att.Get("hookName").Str
+	event, tool, _ := strings.Cut(name, ":")
+	if e := att.Get("hookEvent").Str; e != "" {
+		event = e
+	}
+	if name == "" {
+		name = event
+	}
+
+	command := att.Get("command").Str
+	var msg string
+	switch {
+	case att.Get("blockingError").Exists():
+		be := att.Get("blockingError")
+		msg = be.Get("blockingError").Str
+		if command == "" {
+			command = be.Get("command").Str
+		}
+	case att.Get("content").IsArray():
+		var parts []string
+		for _, c := range att.Get("content").Array() {
+			parts = append(parts, c.String())
+		}
+		msg = strings.Join(parts, "\n")
+	case att.Get("content").Exists():
+		msg = att.Get("content").String()
+	case att.Get("message").Exists():
+		msg = att.Get("message").Str
+	case att.Get("stderr").Exists():
+		msg = att.Get("stderr").Str
+	}
+
+	return ParsedHookEvent{
+		Event:     event,
+		Name:      name,
+		ToolName:  tool,
+		Command:   command,
+		Decision:  decision,
+		Message:   strings.TrimSpace(msg),
+		Timestamp: extractTimestamp(line),
+	}, true
+}
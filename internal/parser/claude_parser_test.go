@@ -3,7 +3,9 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -45,6 +47,234 @@ func TestParseClaudeSession_HyphenatedFilename(t *testing.T) {
 	assert.Equal(t, "my-test-session", sess.ID)
 }
 
+func TestParseClaudeSession_Automated(t *testing.T) {
+	tests := []struct {
+		name       string
+		entrypoint string
+		want       bool
+	}{
+		{"interactive cli", "cli", false},
+		{"no entrypoint", "", false},
+		{"headless print mode", "sdk-cli", true},
+		{"typescript sdk", "sdk-ts", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := testjsonl.ClaudeUserJSON("run the checks", tsZero)
+			if tt.entrypoint != "" {
+				line = `{"entrypoint":"` + tt.entrypoint + `",` + line[1:]
+			}
+			sess, _ := runClaudeParserTest(t, "test.jsonl", line+"\n")
+			assert.Equal(t, tt.want, sess.Automated)
+		})
+	}
+}
+
+func TestParseClaudeSession_SDKStream(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		`{"type":"system","subtype":"init","session_id":"sdk-1","cwd":"/repo","model":"claude-sonnet-4-5","tools":["Bash"]}`,
+		`{"type":"user","message":{"role":"user","content":"summarize the diff"},"session_id":"sdk-1","parent_tool_use_id":null}`,
+		`{"type":"assistant","message":{"role":"assistant","model":"claude-sonnet-4-5","content":[{"type":"text","text":"The diff renames a flag."}]},"session_id":"sdk-1","parent_tool_use_id":null}`,
+		`{"type":"result","subtype":"success","is_error":false,"num_turns":1,"result":"The diff renames a flag.","session_id":"sdk-1"}`,
+	)
+	sess, msgs := runClaudeParserTest(t, "sdk-1.jsonl", content)
+
+	assert.True(t, sess.Automated)
+	assert.Empty(t, sess.ParentSessionID)
+	assertMessageCount(t, len(msgs), 2)
+	assert.Equal(t, "summarize the diff", sess.FirstMessage)
+	assert.Equal(t, 1, sess.UserMessageCount)
+	// No record carries a timestamp, so the file mtime dates it.
+	assert.False(t, sess.StartedAt.IsZero())
+	assert.Equal(t, sess.StartedAt, sess.EndedAt)
+}
+
+func TestParseClaudeSession_ModelSwitches(t *testing.T) {
+	asst := func(model, text string) string {
+		return `{"type":"assistant","timestamp":"` + tsEarlyS1 +
+			`","message":{"role":"assistant","model":"` + model +
+			`","content":[{"type":"text","text":"` + text + `"}]}}`
+	}
+	content := testjsonl.JoinJSONL(
+		testjsonl.ClaudeUserJSON("start", tsEarly),
+		asst("claude-opus-4-1", "one"),
+		asst("claude-sonnet-4-5", "two"),
+		asst("<synthetic>", "API Error: overloaded"),
+		testjsonl.ClaudeUserJSON(
+			"<command-name>/model</command-name>\n"+
+				"<command-args>opus</command-args>", tsEarlyS1),
+		testjsonl.ClaudeUserJSON("continue", tsEarlyS1),
+		asst("claude-opus-4-1", "three"),
+		asst("claude-opus-4-1", "four"),
+	)
+	_, msgs := runClaudeParserTest(t, "test.jsonl", content)
+	require.Len(t, msgs, 8)
+
+	var models, switches []string
+	for _, m := range msgs {
+		if m.Role == RoleAssistant {
+			models = append(models, m.Model)
+			switches = append(switches, m.ModelSwitch)
+		}
+	}
+	assert.Equal(t, []string{
+		"claude-opus-4-1", "claude-sonnet-4-5", "",
+		"claude-opus-4-1", "claude-opus-4-1",
+	}, models)
+	assert.Equal(t, []string{
+		"", ModelSwitchFallback, "", ModelSwitchCommand, "",
+	}, switches)
+}
+
+func TestParseClaudeSession_TokenUsage(t *testing.T) {
+	// One API response split over a text and a tool_use entry,
+	// repeating its usage with the final output count last.
+	asst := func(id, block string, output int) string {
+		return `{"type":"assistant","timestamp":"` + tsEarlyS1 +
+			`","message":{"id":"` + id + `","role":"assistant",` +
+			`"model":"claude-sonnet-4-5","content":[` + block + `],` +
+			`"usage":{"input_tokens":10,"output_tokens":` +
+			strconv.Itoa(output) + `,"cache_read_input_tokens":300,` +
+			`"cache_creation_input_tokens":40}}}`
+	}
+	text := `{"type":"text","text":"Looking."}`
+	tool := `{"type":"tool_use","id":"tu1","name":"Bash","input":{"command":"ls"}}`
+	content := testjsonl.JoinJSONL(
+		testjsonl.ClaudeUserJSON("list files", tsEarly),
+		asst("msg_1", text, 2),
+		asst("msg_1", tool, 25),
+		asst("msg_2", `{"type":"text","text":"Done."}`, 7),
+	)
+	_, msgs := runClaudeParserTest(t, "test.jsonl", content)
+	require.Len(t, msgs, 4)
+
+	assert.Equal(t, TokenUsage{}, msgs[0].Usage)
+	assert.Equal(t, TokenUsage{}, msgs[1].Usage)
+	assert.Equal(t, TokenUsage{
+		InputTokens: 10, OutputTokens: 25,
+		CacheReadTokens: 300, CacheCreationTokens: 40,
+	}, msgs[2].Usage)
+	assert.Equal(t, 7, msgs[3].Usage.OutputTokens)
+}
+
+func TestParseClaudeSession_GitBranch(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		`{"type":"user","timestamp":"`+tsEarly+`","cwd":"/nonexistent/app-fix-login","gitBranch":"fix-login","message":{"content":"fix it"}}`,
+		testjsonl.ClaudeAssistantJSON("done", tsEarlyS1),
+	)
+	sess, _ := runClaudeParserTest(t, "test.jsonl", content)
+	assert.Equal(t, "fix-login", sess.GitBranch)
+	assert.Equal(t, "app-fix-login", sess.Worktree)
+}
+
+func TestParseClaudeSession_EntryPoint(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		`{"type":"user","timestamp":"`+tsEarly+`","entrypoint":"claude-vscode","message":{"content":"hi"}}`,
+		testjsonl.ClaudeAssistantJSON("hello", tsEarlyS1),
+	)
+	sess, _ := runClaudeParserTest(t, "test.jsonl", content)
+	assert.Equal(t, "claude-vscode", sess.Client)
+	assert.Equal(t, EntryVSCode, sess.EntryPoint)
+}
+
+func TestParseClaudeSession_Environment(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		`{"type":"system","subtype":"init","session_id":"s1","claude_code_version":"2.0.14","permissionMode":"plan","betas":["context-1m-2025-08-07","bad beta"],"cwd":"/home/me/secret"}`,
+		`{"type":"user","timestamp":"`+tsEarly+`","version":"2.0.15","permissionMode":"default","thinkingMetadata":{"level":"high","disabled":false,"triggers":[]},"message":{"content":"hi"}}`,
+		`{"type":"user","timestamp":"`+tsEarlyS1+`","thinkingMetadata":{"level":"none","disabled":true},"message":{"content":"again"}}`,
+		testjsonl.ClaudeAssistantJSON("hello", tsEarlyS5),
+	)
+	sess, _ := runClaudeParserTest(t, "test.jsonl", content)
+	assert.Equal(t, map[string]string{
+		EnvClientVersion:                        "2.0.14",
+		EnvPermissionMode:                       "plan",
+		EnvBetaPrefix + "context-1m-2025-08-07": "enabled",
+		EnvThinking:                             "enabled",
+		EnvThinkingLevel:                        "high",
+	}, sess.Environment)
+}
+
+func TestParseClaudeSession_NoEnvironment(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		`{"type":"user","timestamp":"`+tsEarly+`","version":"$(rm -rf /)","message":{"content":"hi"}}`,
+		testjsonl.ClaudeAssistantJSON("hello", tsEarlyS1),
+	)
+	sess, _ := runClaudeParserTest(t, "test.jsonl", content)
+	assert.Nil(t, sess.Environment)
+}
+
+func TestParseClaudeSession_UnknownRecords(t *testing.T) {
+	widget := `{"type":"widget-render","timestamp":"` + tsEarly + `","id":1}`
+	content := testjsonl.JoinJSONL(
+		testjsonl.ClaudeUserJSON("hi", tsEarly),
+		widget,
+		`{"type":"file-history-snapshot","messageId":"m1"}`,
+		`{"type":"widget-render","id":2}`,
+		`{"noType":true}`,
+		testjsonl.ClaudeAssistantJSON("hello", tsEarlyS1),
+	)
+	sess, msgs := runClaudeParserTest(t, "test.jsonl", content)
+	assert.Len(t, msgs, 2)
+	assert.Equal(t, []UnknownRecord{
+		{Type: "widget-render", Count: 2, Sample: widget},
+	}, sess.UnknownRecords)
+}
+
+func TestParseClaudeSession_Commands(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		`{"type":"user","timestamp":"`+tsEarly+`","message":{"content":"<command-message>review is running</command-message>\n<command-name>/review</command-name>"}}`,
+		testjsonl.ClaudeAssistantJSON("reviewing", tsEarlyS1),
+		`{"type":"user","timestamp":"`+tsEarlyS5+`","message":{"content":[{"type":"text","text":"<command-name>deploy</command-name><command-args>prod</command-args>"}]}}`,
+		`{"type":"assistant","timestamp":"`+tsEarlyS5+`","message":{"content":[{"type":"text","text":"<command-name>/not-a-user-command</command-name>"}]}}`,
+	)
+	sess, _ := runClaudeParserTest(t, "test.jsonl", content)
+	require.Len(t, sess.Commands, 2)
+	assert.Equal(t, "review", sess.Commands[0].Name)
+	assert.Equal(t, "deploy", sess.Commands[1].Name)
+	assert.False(t, sess.Commands[0].Timestamp.IsZero())
+}
+
+func TestParseClaudeSession_CommandMessages(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		testjsonl.ClaudeUserJSON("<command-name>/clear</command-name>\n<command-message>clear</command-message>\n<command-args></command-args>", tsEarly),
+		testjsonl.ClaudeUserJSON("<local-command-stdout></local-command-stdout>", tsEarly),
+		testjsonl.ClaudeUserJSON("<command-message>review</command-message>\n<command-name>/review</command-name>\n<command-args>PR 12\n</command-args>", tsEarlyS1),
+		testjsonl.ClaudeAssistantJSON("reviewing", tsEarlyS1),
+		testjsonl.ClaudeUserJSON("<command-name>/cost</command-name>", tsEarlyS5),
+		testjsonl.ClaudeUserJSON("<local-command-stdout>\u001b[1mTotal cost:\u001b[22m $0.12</local-command-stdout>", tsEarlyS5),
+		testjsonl.ClaudeUserJSON("now fix it", tsEarlyS5),
+	)
+	sess, msgs := runClaudeParserTest(t, "test.jsonl", content)
+	require.Len(t, msgs, 6)
+
+	type entry struct{ kind, content string }
+	var got []entry
+	for _, m := range msgs {
+		got = append(got, entry{m.Kind, m.Content})
+	}
+	assert.Equal(t, []entry{
+		{MessageKindCommand, "/clear"},
+		{MessageKindCommand, "/review PR 12"},
+		{"", "reviewing"},
+		{MessageKindCommand, "/cost"},
+		{MessageKindCommandOutput, "Total cost: $0.12"},
+		{"", "now fix it"},
+	}, got)
+	assert.Equal(t, RoleUser, msgs[0].Role)
+	assert.Equal(t, 5, msgs[5].Ordinal)
+	assert.Equal(t, 4, sess.UserMessageCount)
+	assert.Equal(t, "now fix it", sess.FirstMessage)
+}
+
+func TestParseClaudeSession_CommandOnlyTitle(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		testjsonl.ClaudeUserJSON("<command-name>/review</command-name><command-args>PR 12</command-args>", tsEarly),
+		testjsonl.ClaudeAssistantJSON("reviewing", tsEarlyS1),
+	)
+	sess, _ := runClaudeParserTest(t, "test.jsonl", content)
+	assert.Equal(t, "/review PR 12", sess.FirstMessage)
+}
+
 func TestParseClaudeSession_EdgeCases(t *testing.T) {
 	t.Run("empty file", func(t *testing.T) {
 		sess, msgs := runClaudeParserTest(t, "test.jsonl", "")
@@ -117,6 +347,7 @@ func TestParseClaudeSession_SkippedMessages(t *testing.T) {
 		sess, _ := runClaudeParserTest(t, "test.jsonl", content)
 		assert.Equal(t, 1, sess.MessageCount)
 		assert.Equal(t, "real question", sess.FirstMessage)
+		assert.Equal(t, map[string]int{OriginSystem: 1}, sess.SkippedUserMessages)
 	})
 
 	t.Run("skips isCompactSummary user messages", func(t *testing.T) {
@@ -135,7 +366,7 @@ func TestParseClaudeSession_SkippedMessages(t *testing.T) {
 			testjsonl.ClaudeUserJSON("[Request interrupted by user]", tsZeroS1),
 			testjsonl.ClaudeUserJSON("<task-notification>data</task-notification>", tsZeroS2),
 			testjsonl.ClaudeUserJSON("<command-message>x</command-message>", "2024-01-01T00:00:03Z"),
-			testjsonl.ClaudeUserJSON("<command-name>commit</command-name>", "2024-01-01T00:00:04Z"),
+			testjsonl.ClaudeUserJSON("<local-command-stdout></local-command-stdout>", "2024-01-01T00:00:04Z"),
 			testjsonl.ClaudeUserJSON("<local-command-result>ok</local-command-result>", "2024-01-01T00:00:05Z"),
 			testjsonl.ClaudeUserJSON("Stop hook feedback: rejected", "2024-01-01T00:00:06Z"),
 			testjsonl.ClaudeUserJSON("real user message", "2024-01-01T00:00:07Z"),
@@ -144,6 +375,10 @@ func TestParseClaudeSession_SkippedMessages(t *testing.T) {
 		assert.Equal(t, 1, sess.MessageCount)
 		assert.Equal(t, "real user message", msgs[0].Content)
 		assert.Equal(t, "real user message", sess.FirstMessage)
+		assert.Equal(t, 1, sess.InterruptCount)
+		assert.Equal(t,
+			map[string]int{OriginSystem: 6, OriginHook: 1},
+			sess.SkippedUserMessages)
 	})
 
 	t.Run("assistant with system-like content not filtered", func(t *testing.T) {
@@ -169,6 +404,85 @@ func TestParseClaudeSession_SkippedMessages(t *testing.T) {
 	})
 }
 
+func TestParseClaudeSession_Todos(t *testing.T) {
+	todoWrite := func(todos ...map[string]string) []map[string]any {
+		return []map[string]any{{
+			"type": "tool_use", "id": "toolu_todo", "name": "TodoWrite",
+			"input": map[string]any{"todos": todos},
+		}}
+	}
+	content := testjsonl.JoinJSONL(
+		testjsonl.ClaudeUserJSON("plan the work", tsZero),
+		testjsonl.ClaudeAssistantJSON(todoWrite(
+			map[string]string{"content": "Write parser", "status": "in_progress", "activeForm": "Writing parser"},
+			map[string]string{"content": "Add tests", "status": "pending"},
+		), tsZeroS1),
+		testjsonl.ClaudeAssistantJSON(todoWrite(
+			map[string]string{"content": "Write parser", "status": "completed"},
+			map[string]string{"content": "Add tests", "status": "in_progress", "activeForm": "Adding tests"},
+			map[string]string{"content": "", "status": "pending"},
+		), tsZeroS2),
+	)
+	sess, _ := runClaudeParserTest(t, "test.jsonl", content)
+
+	assert.Equal(t, []ParsedTodo{
+		{Content: "Write parser", Status: TodoCompleted},
+		{Content: "Add tests", Status: TodoInProgress, ActiveForm: "Adding tests"},
+	}, sess.Todos)
+	assert.Equal(t, tsZeroS2, sess.TodosAt.UTC().Format(time.RFC3339))
+
+	t.Run("NoTodoWrite", func(t *testing.T) {
+		sess, _ := runClaudeParserTest(t, "test.jsonl",
+			testjsonl.ClaudeUserJSON("hello", tsZero))
+		assert.Nil(t, sess.Todos)
+	})
+}
+
+func TestParseClaudeSession_HookEvents(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		testjsonl.ClaudeUserJSON("fix the tests", tsZero),
+		`{"type":"system","content":"PostToolUse:Edit [gofmt -l .] completed successfully","timestamp":"2024-01-01T00:00:01Z"}`,
+		`{"type":"system","content":"PreToolUse:Bash [./guard.sh] failed with non-blocking status code 1: boom","timestamp":"2024-01-01T00:00:02Z"}`,
+		`{"type":"system","content":"Compacting conversation [auto]","timestamp":"2024-01-01T00:00:03Z"}`,
+		testjsonl.ClaudeUserJSON("PostToolUse:Edit hook feedback:\n[./lint.sh]: line too long", "2024-01-01T00:00:04Z"),
+		`{"type":"attachment","attachment":{"type":"hook_additional_context","hookName":"UserPromptSubmit","hookEvent":"UserPromptSubmit","content":["branch is main"]},"timestamp":"2024-01-01T00:00:05Z"}`,
+		`{"type":"attachment","attachment":{"type":"hook_blocking_error","hookName":"PreToolUse:Write","hookEvent":"PreToolUse","blockingError":{"blockingError":"protected path","command":"./protect.sh"}},"timestamp":"2024-01-01T00:00:06Z"}`,
+		testjsonl.ClaudeUserJSON("Stop hook feedback:\n[./check.sh]: tests failing", "2024-01-01T00:00:07Z"),
+	)
+	sess, msgs := runClaudeParserTest(t, "test.jsonl", content)
+
+	// Hook feedback is not shown as user messages.
+	assert.Equal(t, 1, len(msgs))
+
+	got := sess.HookEvents
+	require.Len(t, got, 6)
+	assert.Equal(t, ParsedHookEvent{
+		Event: "PostToolUse", Name: "PostToolUse:Edit", ToolName: "Edit",
+		Command: "gofmt -l .", Decision: HookDecisionAllow,
+		Timestamp: got[0].Timestamp,
+	}, got[0])
+	assert.Equal(t, HookDecisionError, got[1].Decision)
+	assert.Equal(t, "boom", got[1].Message)
+	assert.Equal(t, "Bash", got[1].ToolName)
+
+	assert.Equal(t, HookDecisionBlock, got[2].Decision)
+	assert.Equal(t, "./lint.sh", got[2].Command)
+	assert.Equal(t, "line too long", got[2].Message)
+
+	assert.Equal(t, HookDecisionModify, got[3].Decision)
+	assert.Equal(t, "UserPromptSubmit", got[3].Event)
+	assert.Equal(t, "branch is main", got[3].Message)
+
+	assert.Equal(t, HookDecisionBlock, got[4].Decision)
+	assert.Equal(t, "Write", got[4].ToolName)
+	assert.Equal(t, "./protect.sh", got[4].Command)
+	assert.Equal(t, "protected path", got[4].Message)
+
+	assert.Equal(t, "Stop", got[5].Event)
+	assert.Equal(t, HookDecisionBlock, got[5].Decision)
+	assert.Equal(t, "tests failing", got[5].Message)
+}
+
 func TestParseClaudeSession_ParentSessionID(t *testing.T) {
 	t.Run("sessionId != fileId sets ParentSessionID", func(t *testing.T) {
 		content := testjsonl.JoinJSONL(
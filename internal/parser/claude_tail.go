@@ -0,0 +1,428 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// How many bytes at the start of a file and before a
+// ClaudeTail's offset are kept to recognize a file that was
+// rewritten rather than grown. Claude Code only appends to its
+// transcripts, so this stands in for hashing the whole prefix.
+const (
+	tailHeadSize = 4096
+	tailEdgeSize = 256
+)
+
+// ClaudeTail is where a parse of a Claude Code session file
+// stopped, kept so that lines later appended to the file can be
+// parsed on their own (see Append) instead of reading a long
+// transcript from the start again.
+type ClaudeTail struct {
+	// Offset is the number of bytes parsed. It always ends a
+	// line.
+	Offset int64
+
+	sess    ParsedSession // main session as of Offset
+	scan    claudeScan    // first-pass state, without entries
+	extract extractState  // where the main session's messages end
+	// leafUUID is the uuid of the main session's last entry
+	// when the file is parsed as a DAG, "" when linearly.
+	leafUUID  string
+	hasPrompt bool // the session is titled by a typed prompt
+	// pending holds the main session's tool calls still waiting
+	// for a result, by tool_use id, without their input.
+	pending map[string]ParsedToolCall
+	head    []byte // the file's first bytes
+	edge    []byte // the bytes just before Offset
+}
+
+// ClaudeAppend is the outcome of parsing lines appended to a
+// Claude Code session file.
+type ClaudeAppend struct {
+	// Result holds the file's main session updated for the new
+	// lines, and only the messages they added. Ordinals continue
+	// from the messages parsed before.
+	Result ParseResult
+	// Carried are tool calls from earlier messages that the new
+	// lines gave a result or linked to a subagent session.
+	Carried []ParsedToolCall
+	// Uncredited are the ordinals of earlier messages whose
+	// token usage moved to a new message, because the response
+	// they belong to continued in the new lines.
+	Uncredited []int
+	// Tail continues from the end of the new lines.
+	Tail *ClaudeTail
+}
+
+// claudeMain records how ParseClaudeSessionTail built a file's
+// main session, so that appended entries can continue it.
+type claudeMain struct {
+	extract extractState
+	linear  bool
+	leaf    int // index of the main path's last entry
+}
+
+// extractState is where extractMessages stopped: the next
+// message ordinal and the model in use.
+type extractState struct {
+	ordinal int
+	models  modelTracker
+	// usageID is the message.id of the last response whose
+	// token usage was credited, and usageOrdinal the ordinal of
+	// the message holding it.
+	usageID      string
+	usageOrdinal int
+	// uncredited collects the ordinals of messages from an
+	// earlier call that gave up their usage to a later message.
+	uncredited []int
+}
+
+// newClaudeTail returns the tail of a parsed Claude file, or nil
+// when appended lines could change what was parsed: when the
+// file does not end with a complete line, holds no messages yet
+// (so its parse mode is open), is a headless run, or its main
+// session does not end at the last entry, where new entries
+// would extend a fork.
+func newClaudeTail(
+	f *os.File, scan *claudeScan, main *claudeMain,
+	results []ParseResult,
+) *ClaudeTail {
+	if len(results) == 0 || len(scan.entries) == 0 ||
+		scan.automated {
+		return nil
+	}
+	if !main.linear && main.leaf != len(scan.entries)-1 {
+		return nil
+	}
+	sess := results[0].Session
+	edge, err := readEdge(f, sess.File.Size)
+	if err != nil || len(edge) == 0 || edge[len(edge)-1] != '\n' {
+		return nil
+	}
+	head, err := readHead(f, sess.File.Size)
+	if err != nil {
+		return nil
+	}
+
+	t := &ClaudeTail{
+		Offset:    sess.File.Size,
+		sess:      sess,
+		scan:      scan.resume(),
+		extract:   main.extract,
+		hasPrompt: hasTypedPrompt(results[0].Messages),
+		pending:   map[string]ParsedToolCall{},
+		head:      head,
+		edge:      edge,
+	}
+	if !main.linear {
+		t.leafUUID = scan.entries[main.leaf].uuid
+	}
+	for _, m := range results[0].Messages {
+		for _, tc := range m.ToolCalls {
+			if tc.ToolUseID != "" {
+				t.pending[tc.ToolUseID] = pendingCall(tc)
+			}
+		}
+		for _, tr := range m.ToolResults {
+			delete(t.pending, tr.ToolUseID)
+		}
+	}
+	return t
+}
+
+// Append parses the complete lines added to the file at path
+// since t was taken. It reports false, and the file must be
+// parsed again in full, when the file was not only appended to
+// or the new lines would change what was already parsed: a fork
+// or a new root in the DAG, a late session or branch hint, a
+// headless marker, or a subagent link for a call whose result
+// is already stored. t itself is left unchanged.
+func (t *ClaudeTail) Append(path string) (ClaudeAppend, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ClaudeAppend{}, false, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return ClaudeAppend{}, false, fmt.Errorf("stat %s: %w", path, err)
+	}
+	if info.Size() < t.Offset {
+		return ClaudeAppend{}, false, nil
+	}
+	head, err := readHead(f, t.Offset)
+	if err != nil || !bytes.Equal(head, t.head) {
+		return ClaudeAppend{}, false, nil
+	}
+	edge, err := readEdge(f, t.Offset)
+	if err != nil || !bytes.Equal(edge, t.edge) {
+		return ClaudeAppend{}, false, nil
+	}
+	end, err := lastLineEnd(f, t.Offset, info.Size())
+	if err != nil {
+		return ClaudeAppend{}, false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if _, err := f.Seek(t.Offset, io.SeekStart); err != nil {
+		return ClaudeAppend{}, false, fmt.Errorf("seek %s: %w", path, err)
+	}
+
+	scan := t.scan.resume()
+	lr := newLineReader(io.LimitReader(f, end-t.Offset), maxLineSize)
+	for {
+		line, ok := lr.next()
+		if !ok {
+			break
+		}
+		scan.add(line)
+	}
+	if err := lr.Err(); err != nil {
+		return ClaudeAppend{}, false, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if !t.continuedBy(&scan) {
+		return ClaudeAppend{}, false, nil
+	}
+
+	st := t.extract
+	msgs, _, endedAt, interrupts, skipped :=
+		extractMessages(scan.entries, &st)
+	uncredited := st.uncredited
+	st.uncredited = nil
+	annotateSubagentSessions(msgs, scan.subagentMap)
+	pending, carried, ok := t.carry(&scan, msgs)
+	if !ok {
+		return ClaudeAppend{}, false, nil
+	}
+
+	sess := t.sess
+	sess.StartedAt = earlierTime(scan.globalStart, sess.StartedAt)
+	sess.EndedAt = laterTime(scan.globalEnd,
+		laterTime(sess.EndedAt, endedAt))
+	sess.MessageCount += len(msgs)
+	userCount, first := summarizeUserMessages(msgs)
+	sess.UserMessageCount += userCount
+	hasPrompt := t.hasPrompt
+	if !hasPrompt && hasTypedPrompt(msgs) {
+		sess.FirstMessage = first
+		hasPrompt = true
+	} else if sess.FirstMessage == "" {
+		sess.FirstMessage = first
+	}
+	sess.InterruptCount += interrupts
+	sess.SkippedUserMessages = maps.Clone(sess.SkippedUserMessages)
+	if sess.SkippedUserMessages == nil {
+		sess.SkippedUserMessages = map[string]int{}
+	}
+	for origin, n := range skipped {
+		sess.SkippedUserMessages[origin] += n
+	}
+	if sess.Client == "" && scan.client != "" {
+		sess.Client = scan.client
+		sess.EntryPoint = ClassifyEntryPoint(scan.client)
+	}
+	sess.Environment = scan.env
+	sess.HookEvents = scan.hooks
+	sess.Commands = scan.commands
+	sess.UnknownRecords = scan.unknown.records
+	if todos, at := latestTodos(msgs); todos != nil {
+		sess.Todos, sess.TodosAt = todos, at
+	}
+	sess.File = FileInfo{
+		Path:  path,
+		Size:  end,
+		Mtime: info.ModTime().UnixNano(),
+	}
+
+	next := &ClaudeTail{
+		Offset:    end,
+		sess:      sess,
+		extract:   st,
+		leafUUID:  t.leafUUID,
+		hasPrompt: hasPrompt,
+		pending:   pending,
+		head:      t.head,
+		edge:      t.edge,
+	}
+	if n := len(scan.entries); n > 0 && t.leafUUID != "" {
+		next.leafUUID = scan.entries[n-1].uuid
+	}
+	if end > t.Offset {
+		if next.edge, err = readEdge(f, end); err == nil {
+			next.head, err = readHead(f, end)
+		}
+		if err != nil {
+			return ClaudeAppend{}, false,
+				fmt.Errorf("reading %s: %w", path, err)
+		}
+	}
+	scan.entries, scan.mapped = nil, nil
+	next.scan = scan
+
+	return ClaudeAppend{
+		Result:     ParseResult{Session: sess, Messages: msgs},
+		Carried:    carried,
+		Uncredited: uncredited,
+		Tail:       next,
+	}, true, nil
+}
+
+// continuedBy reports whether the entries scan read after t only
+// extend the main session: each must follow the previous one
+// without forking, and the session's origin, parent and branch
+// hints must be as they were.
+func (t *ClaudeTail) continuedBy(scan *claudeScan) bool {
+	if scan.automated ||
+		scan.parentSessionID != t.scan.parentSessionID ||
+		scan.cwd != t.scan.cwd ||
+		scan.gitBranch != t.scan.gitBranch {
+		return false
+	}
+	if t.leafUUID == "" {
+		return true
+	}
+	leaf := t.leafUUID
+	for _, e := range scan.entries {
+		if e.uuid == "" || e.parentUuid != leaf {
+			return false
+		}
+		leaf = e.uuid
+	}
+	return true
+}
+
+// carry updates t's pending tool calls for the appended
+// messages msgs and returns them along with the earlier calls
+// that msgs resolved or scan linked to a subagent. It reports
+// false when scan links a call that is no longer pending.
+func (t *ClaudeTail) carry(
+	scan *claudeScan, msgs []ParsedMessage,
+) (map[string]ParsedToolCall, []ParsedToolCall, bool) {
+	pending := maps.Clone(t.pending)
+	added := map[string]bool{}
+	for _, m := range msgs {
+		for _, tc := range m.ToolCalls {
+			if tc.ToolUseID != "" {
+				added[tc.ToolUseID] = true
+				pending[tc.ToolUseID] = pendingCall(tc)
+			}
+		}
+	}
+
+	var carried []ParsedToolCall
+	index := map[string]int{}
+	carry := func(tc ParsedToolCall) {
+		if i, ok := index[tc.ToolUseID]; ok {
+			carried[i] = tc
+			return
+		}
+		index[tc.ToolUseID] = len(carried)
+		carried = append(carried, tc)
+	}
+	for _, id := range scan.mapped {
+		if added[id] {
+			continue
+		}
+		tc, ok := t.pending[id]
+		if !ok {
+			return nil, nil, false
+		}
+		if tc.ToolName == "Task" || tc.ToolName == "Agent" {
+			tc.SubagentSessionID = scan.subagentMap[id]
+			pending[id] = tc
+			carry(tc)
+		}
+	}
+	for _, m := range msgs {
+		for _, tr := range m.ToolResults {
+			tc, ok := pending[tr.ToolUseID]
+			if !ok {
+				continue
+			}
+			delete(pending, tr.ToolUseID)
+			if !added[tr.ToolUseID] {
+				carry(tc)
+			}
+		}
+	}
+	return pending, carried, true
+}
+
+// resume returns a copy of s for reading further lines. Entries
+// already read are dropped, and the rest is copied so that s is
+// unchanged.
+func (s *claudeScan) resume() claudeScan {
+	c := *s
+	c.entries = nil
+	c.mapped = nil
+	c.subagentMap = maps.Clone(s.subagentMap)
+	c.hooks = slices.Clone(s.hooks)
+	c.commands = slices.Clone(s.commands)
+	c.unknown = unknownRecords{
+		records: slices.Clone(s.unknown.records),
+		index:   maps.Clone(s.unknown.index),
+	}
+	c.env = maps.Clone(s.env)
+	return c
+}
+
+// pendingCall is tc without its input, as kept in a ClaudeTail.
+func pendingCall(tc ParsedToolCall) ParsedToolCall {
+	tc.InputJSON = ""
+	return tc
+}
+
+// hasTypedPrompt reports whether msgs include a user prompt
+// other than a slash command, which titles the session.
+func hasTypedPrompt(msgs []ParsedMessage) bool {
+	for _, m := range msgs {
+		if m.Role == RoleUser && m.Content != "" &&
+			m.Kind != MessageKindCommand &&
+			m.Kind != MessageKindCommandOutput {
+			return true
+		}
+	}
+	return false
+}
+
+// readHead returns up to tailHeadSize bytes from the start of f,
+// within its first end bytes.
+func readHead(f *os.File, end int64) ([]byte, error) {
+	return readRange(f, 0, min(end, tailHeadSize))
+}
+
+// readEdge returns up to tailEdgeSize bytes of f ending at end.
+func readEdge(f *os.File, end int64) ([]byte, error) {
+	n := min(end, tailEdgeSize)
+	return readRange(f, end-n, n)
+}
+
+// readRange returns the n bytes of f at off.
+func readRange(f *os.File, off, n int64) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := f.ReadAt(buf, off); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// lastLineEnd returns the offset just past the last newline in
+// f between from and to, or from when there is none.
+func lastLineEnd(f *os.File, from, to int64) (int64, error) {
+	buf := make([]byte, initialScanBufSize)
+	for to > from {
+		n := min(to-from, int64(len(buf)))
+		chunk := buf[:n]
+		if _, err := f.ReadAt(chunk, to-n); err != nil {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			return to - n + int64(i) + 1, nil
+		}
+		to -= n
+	}
+	return from, nil
+}
@@ -0,0 +1,258 @@
+package parser
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+func appendToFile(t *testing.T, path, content string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func parseTail(t *testing.T, path string) ([]ParseResult, *ClaudeTail) {
+	t.Helper()
+	results, tail, err := ParseClaudeSessionTail(path, "proj", "local")
+	require.NoError(t, err)
+	return results, tail
+}
+
+func TestClaudeTailAppend_MatchesFullParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		initial string
+		added   string
+	}{
+		{
+			name: "dag",
+			initial: testjsonl.JoinJSONL(
+				testjsonl.ClaudeEntryJSON("user", "run the tests", "2024-01-01T10:00:00Z", "a", ""),
+				`{"type":"assistant","uuid":"b","parentUuid":"a","timestamp":"2024-01-01T10:00:01Z","message":{"model":"claude-sonnet-4","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+			),
+			added: testjsonl.JoinJSONL(
+				`{"type":"user","uuid":"c","parentUuid":"b","timestamp":"2024-01-01T10:00:02Z","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}}`,
+				`{"type":"assistant","uuid":"d","parentUuid":"c","timestamp":"2024-01-01T10:00:03Z","message":{"model":"claude-opus-4","content":[{"type":"text","text":"tests pass"}]}}`,
+				testjsonl.ClaudeEntryJSON("user", "thanks", "2024-01-01T10:00:04Z", "e", "d"),
+			),
+		},
+		{
+			name: "linear",
+			initial: testjsonl.NewSessionBuilder().
+				AddClaudeUser("2024-01-01T10:00:00Z", "/clear").
+				AddClaudeAssistant("2024-01-01T10:00:01Z", "cleared").
+				String(),
+			added: testjsonl.NewSessionBuilder().
+				AddClaudeUser("2024-01-01T10:00:02Z", "fix the bug").
+				AddClaudeAssistant("2024-01-01T10:00:03Z", "fixed").
+				String(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createTestFile(t, "tail.jsonl", tt.initial)
+			first, tail := parseTail(t, path)
+			require.NotNil(t, tail)
+			appendToFile(t, path, tt.added)
+
+			app, ok, err := tail.Append(path)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			full, fullTail := parseTail(t, path)
+			require.Len(t, full, 1)
+			want := full[0]
+			assert.Equal(t, want.Session, app.Result.Session)
+			assert.Equal(t,
+				want.Messages[len(first[0].Messages):],
+				app.Result.Messages)
+			require.NotNil(t, fullTail)
+			assert.Equal(t, fullTail.Offset, app.Tail.Offset)
+		})
+	}
+}
+
+func TestClaudeTailAppend_CarriesResults(t *testing.T) {
+	path := createTestFile(t, "tail.jsonl", testjsonl.JoinJSONL(
+		testjsonl.ClaudeEntryJSON("user", "delegate", "2024-01-01T10:00:00Z", "a", ""),
+		`{"type":"assistant","uuid":"b","parentUuid":"a","timestamp":"2024-01-01T10:00:01Z","message":{"content":[{"type":"tool_use","id":"t1","name":"Task","input":{"prompt":"look around"}}]}}`,
+	))
+	_, tail := parseTail(t, path)
+	require.NotNil(t, tail)
+
+	appendToFile(t, path, testjsonl.JoinJSONL(
+		`{"type":"progress","parentToolUseID":"t1","timestamp":"2024-01-01T10:00:02Z","data":{"type":"agent_progress","agentId":"x1"}}`,
+	))
+	app, ok, err := tail.Append(path)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Empty(t, app.Result.Messages)
+	require.Len(t, app.Carried, 1)
+	assert.Equal(t, "agent-x1", app.Carried[0].SubagentSessionID)
+
+	appendToFile(t, path, testjsonl.JoinJSONL(
+		`{"type":"user","uuid":"c","parentUuid":"b","timestamp":"2024-01-01T10:00:03Z","message":{"content":[{"type":"tool_result","tool_use_id":"t1","content":"found it"}]}}`,
+	))
+	app, ok, err = app.Tail.Append(path)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, app.Carried, 1)
+	assert.Equal(t, "t1", app.Carried[0].ToolUseID)
+	assert.Empty(t, app.Carried[0].InputJSON)
+	require.Len(t, app.Result.Messages, 1)
+	assert.Equal(t, 2, app.Result.Messages[0].Ordinal)
+}
+
+func TestClaudeTailAppend_UsageAcrossAppend(t *testing.T) {
+	// One API response whose text and tool_use entries are
+	// parsed by different calls, repeating its usage with the
+	// final output count last.
+	asst := func(uuid, parent, id, block string, output int) string {
+		return `{"type":"assistant","uuid":"` + uuid +
+			`","parentUuid":"` + parent +
+			`","timestamp":"2024-01-01T10:00:01Z","message":{"id":"` +
+			id + `","model":"claude-sonnet-4-5","content":[` + block +
+			`],"usage":{"input_tokens":10,"output_tokens":` +
+			strconv.Itoa(output) + `}}}`
+	}
+	path := createTestFile(t, "tail.jsonl", testjsonl.JoinJSONL(
+		testjsonl.ClaudeEntryJSON("user", "list files", "2024-01-01T10:00:00Z", "a", ""),
+		asst("b", "a", "msg_1", `{"type":"text","text":"Looking."}`, 2),
+	))
+	first, tail := parseTail(t, path)
+	require.NotNil(t, tail)
+	appendToFile(t, path, testjsonl.JoinJSONL(
+		asst("c", "b", "msg_1", `{"type":"tool_use","id":"tu1","name":"Bash","input":{"command":"ls"}}`, 25),
+		asst("d", "c", "msg_2", `{"type":"text","text":"Done."}`, 7),
+	))
+	app, ok, err := tail.Append(path)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []int{1}, app.Uncredited)
+
+	got := append([]ParsedMessage{}, first[0].Messages...)
+	for _, ord := range app.Uncredited {
+		got[ord].Usage = TokenUsage{}
+	}
+	got = append(got, app.Result.Messages...)
+	full, _ := parseTail(t, path)
+	require.Len(t, got, len(full[0].Messages))
+	for i, m := range full[0].Messages {
+		assert.Equal(t, m.Usage, got[i].Usage, "message %d", i)
+	}
+
+	// The next append starts with nothing left to uncredit.
+	appendToFile(t, path, testjsonl.JoinJSONL(
+		testjsonl.ClaudeEntryJSON("user", "thanks", "2024-01-01T10:00:04Z", "e", "d"),
+	))
+	app, ok, err = app.Tail.Append(path)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Empty(t, app.Uncredited)
+}
+
+func TestClaudeTailAppend_PartialLine(t *testing.T) {
+	path := createTestFile(t, "tail.jsonl", testjsonl.JoinJSONL(
+		testjsonl.ClaudeEntryJSON("user", "hello", "2024-01-01T10:00:00Z", "a", ""),
+	))
+	_, tail := parseTail(t, path)
+	require.NotNil(t, tail)
+
+	line := testjsonl.ClaudeEntryJSON("user", "more", "2024-01-01T10:00:01Z", "b", "a")
+	appendToFile(t, path, line[:10])
+	app, ok, err := tail.Append(path)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Empty(t, app.Result.Messages)
+	assert.Equal(t, tail.Offset, app.Tail.Offset)
+
+	appendToFile(t, path, line[10:]+"\n")
+	app, ok, err = app.Tail.Append(path)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, app.Result.Messages, 1)
+	assert.Equal(t, "more", app.Result.Messages[0].Content)
+	assert.Equal(t, 2, app.Result.Session.MessageCount)
+}
+
+func TestClaudeTailAppend_Fallback(t *testing.T) {
+	initial := testjsonl.JoinJSONL(
+		testjsonl.ClaudeEntryJSON("user", "hello", "2024-01-01T10:00:00Z", "a", ""),
+		testjsonl.ClaudeEntryJSON("assistant", "hi", "2024-01-01T10:00:01Z", "b", "a"),
+	)
+	tests := []struct {
+		name  string
+		write func(t *testing.T, path string)
+	}{
+		{"fork", func(t *testing.T, path string) {
+			appendToFile(t, path, testjsonl.JoinJSONL(
+				testjsonl.ClaudeEntryJSON("user", "retry", "2024-01-01T10:00:02Z", "c", "a"),
+			))
+		}},
+		{"new root", func(t *testing.T, path string) {
+			appendToFile(t, path, testjsonl.JoinJSONL(
+				testjsonl.ClaudeEntryJSON("user", "again", "2024-01-01T10:00:02Z", "c", ""),
+			))
+		}},
+		{"missing uuid", func(t *testing.T, path string) {
+			appendToFile(t, path, testjsonl.JoinJSONL(
+				testjsonl.ClaudeUserJSON("plain", "2024-01-01T10:00:02Z"),
+			))
+		}},
+		{"rewritten", func(t *testing.T, path string) {
+			require.NoError(t, os.WriteFile(path, []byte(testjsonl.JoinJSONL(
+				testjsonl.ClaudeEntryJSON("user", "HELLO", "2024-01-01T10:00:00Z", "a", ""),
+				testjsonl.ClaudeEntryJSON("assistant", "HI", "2024-01-01T10:00:01Z", "b", "a"),
+				testjsonl.ClaudeEntryJSON("user", "more", "2024-01-01T10:00:02Z", "c", "b"),
+			)), 0o644))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createTestFile(t, "tail.jsonl", initial)
+			_, tail := parseTail(t, path)
+			require.NotNil(t, tail)
+			tt.write(t, path)
+			_, ok, err := tail.Append(path)
+			require.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestParseClaudeSessionTail_NoTail(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no trailing newline", testjsonl.NewSessionBuilder().
+			AddClaudeUser("2024-01-01T10:00:00Z", "hello").
+			StringNoTrailingNewline()},
+		{"no messages", testjsonl.JoinJSONL(
+			`{"type":"summary","summary":"Earlier work"}`,
+		)},
+		{"fork leaf", testjsonl.NewSessionBuilder().
+			AddClaudeUserWithUUID("2024-01-01T10:00:00Z", "hello", "a", "").
+			AddClaudeAssistantWithUUID("2024-01-01T10:00:01Z", "first", "b", "a").
+			AddClaudeAssistantWithUUID("2024-01-01T10:00:02Z", "retry", "c", "a").
+			AddClaudeUserWithUUID("2024-01-01T10:00:03Z", "go on", "d", "c").
+			AddClaudeUserWithUUID("2024-01-01T10:00:04Z", "old", "e", "b").
+			String()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := createTestFile(t, "tail.jsonl", tt.content)
+			_, tail := parseTail(t, path)
+			assert.Nil(t, tail)
+		})
+	}
+}
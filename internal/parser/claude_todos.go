@@ -0,0 +1,57 @@
+package parser
+
+import (
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// Todo statuses written by Claude Code's TodoWrite tool.
+const (
+	TodoPending    = "pending"
+	TodoInProgress = "in_progress"
+	TodoCompleted  = "completed"
+)
+
+// ParsedTodo is one item of a Claude Code TodoWrite list.
+type ParsedTodo struct {
+	Content    string
+	Status     string
+	ActiveForm string // present-tense label shown while in progress
+}
+
+// latestTodos returns the list written by the last TodoWrite
+// call in msgs and the time it was written. Each call replaces
+// the whole list, so the last one is the session's current
+// state; an explicit empty list clears it.
+func latestTodos(msgs []ParsedMessage) ([]ParsedTodo, time.Time) {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		calls := msgs[i].ToolCalls
+		for j := len(calls) - 1; j >= 0; j-- {
+			if calls[j].ToolName != "TodoWrite" {
+				continue
+			}
+			todos := []ParsedTodo{}
+			gjson.Get(calls[j].InputJSON, "todos").ForEach(
+				func(_, t gjson.Result) bool {
+					content := t.Get("content").Str
+					if content == "" {
+						return true
+					}
+					status := t.Get("status").Str
+					if status == "" {
+						status = TodoPending
+					}
+					todos = append(todos, ParsedTodo{
+						Content:    content,
+						Status:     status,
+						ActiveForm: t.Get("activeForm").Str,
+					})
+					return true
+				},
+			)
+			return todos, msgs[i].Timestamp
+		}
+	}
+	return nil, time.Time{}
+}
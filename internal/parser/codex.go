@@ -2,8 +2,10 @@ package parser
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -15,9 +17,20 @@ import (
 const (
 	codexTypeSessionMeta  = "session_meta"
 	codexTypeResponseItem = "response_item"
+	codexTypeTurnContext  = "turn_context"
+	codexTypeEventMsg     = "event_msg"
 	codexOriginatorExec   = "codex_exec"
 )
 
+// maxCodexThinkingLen caps the reasoning text kept per
+// reasoning item.
+const maxCodexThinkingLen = 32 << 10 // 32KB
+
+// maxCodexStringLen caps string values in Codex records longer
+// than maxLineSize (giant tool outputs). Such records are kept
+// with the value truncated instead of being dropped.
+const maxCodexStringLen = 1 << 20 // 1MB
+
 // codexSessionBuilder accumulates state while scanning a Codex
 // JSONL session file line by line.
 type codexSessionBuilder struct {
@@ -29,6 +42,28 @@ type codexSessionBuilder struct {
 	project      string
 	ordinal      int
 	includeExec  bool
+	automated    bool
+	model        string
+	models       modelTracker
+	gitBranch    string
+	worktree     string
+	client       string
+	env          envSnapshot
+	unknown      unknownRecords
+	skipped      map[string]int // injected user entries by origin
+	source       string         // transcript path, for BridgePath
+
+	// thinking holds reasoning blocks waiting to be attached
+	// to the next assistant message.
+	thinking   []string
+	thinkingTS time.Time
+
+	// tokenTotal is the raw total_token_usage of the last
+	// token_count event, to skip repeats of it.
+	tokenTotal string
+	// unclaimed holds usage reported before any assistant
+	// message, for the next one.
+	unclaimed TokenUsage
 }
 
 func newCodexSessionBuilder(
@@ -37,6 +72,7 @@ func newCodexSessionBuilder(
 	return &codexSessionBuilder{
 		project:     "unknown",
 		includeExec: includeExec,
+		skipped:     map[string]int{},
 	}
 }
 
@@ -61,11 +97,22 @@ func (b *codexSessionBuilder) processLine(
 
 	payload := gjson.Get(line, "payload")
 
-	switch gjson.Get(line, "type").Str {
+	typ := gjson.Get(line, "type").Str
+	if !codexKnownTypes[typ] {
+		b.unknown.observe(typ, line)
+	}
+
+	switch typ {
 	case codexTypeSessionMeta:
 		return b.handleSessionMeta(payload)
 	case codexTypeResponseItem:
 		b.handleResponseItem(payload, ts)
+	case codexTypeTurnContext:
+		b.handleTurnContext(payload)
+	case codexTypeEventMsg:
+		if payload.Get("type").Str == "token_count" {
+			b.handleTokenCount(payload.Get("info"))
+		}
 	}
 	return false
 }
@@ -77,26 +124,97 @@ func (b *codexSessionBuilder) handleSessionMeta(
 
 	if cwd := payload.Get("cwd").Str; cwd != "" {
 		branch := payload.Get("git.branch").Str
-		if proj := ExtractProjectFromCwdWithBranch(cwd, branch); proj != "" {
+		b.gitBranch = branch
+		hostCwd := BridgePath(b.source, cwd)
+		b.worktree = WorktreeName(hostCwd, branch)
+		if proj := ExtractProjectFromCwdWithBranch(hostCwd, branch); proj != "" {
 			b.project = proj
 		} else {
 			b.project = "unknown"
 		}
 	}
 
-	if !b.includeExec &&
-		payload.Get("originator").Str == codexOriginatorExec {
-		return true
+	b.env.observeCodexMeta(payload)
+	b.client = firstNonEmpty(
+		payload.Get("originator").Str, payload.Get("source").Str,
+	)
+	if payload.Get("originator").Str == codexOriginatorExec {
+		if !b.includeExec {
+			return true
+		}
+		b.automated = true
 	}
 	return false
 }
 
+// handleTurnContext tracks the model used for the following
+// turn. Codex has no automatic fallback: the model only changes
+// between turns when the user picks another one (/model).
+func (b *codexSessionBuilder) handleTurnContext(
+	payload gjson.Result,
+) {
+	b.env.observeCodexTurn(payload)
+	model := payload.Get("model").Str
+	if model == "" {
+		return
+	}
+	if b.model != "" && model != b.model {
+		b.models.request()
+	}
+	b.model = model
+}
+
+// handleTokenCount credits the usage of the model request that
+// just finished to the latest assistant message. Codex repeats
+// the event, e.g. when rate limits update, so an event whose
+// running total has not changed is skipped. Codex counts cached
+// tokens inside input and reasoning inside output.
+func (b *codexSessionBuilder) handleTokenCount(info gjson.Result) {
+	total := info.Get("total_token_usage").Raw
+	last := info.Get("last_token_usage")
+	if total == "" || total == b.tokenTotal || !last.IsObject() {
+		return
+	}
+	b.tokenTotal = total
+
+	cached := int(last.Get("cached_input_tokens").Int())
+	input := int(last.Get("input_tokens").Int()) - cached
+	u := TokenUsage{
+		InputTokens:     max(input, 0),
+		OutputTokens:    int(last.Get("output_tokens").Int()),
+		CacheReadTokens: cached,
+		ReasoningTokens: int(last.Get("reasoning_output_tokens").Int()),
+	}
+	for i := len(b.messages) - 1; i >= 0; i-- {
+		if m := &b.messages[i]; m.Role == RoleAssistant {
+			m.Usage.add(b.unclaimed)
+			m.Usage.add(u)
+			b.unclaimed = TokenUsage{}
+			return
+		}
+	}
+	b.unclaimed.add(u)
+}
+
+// assistantModel returns the current model and its switch
+// reason for the next assistant message.
+func (b *codexSessionBuilder) assistantModel() (string, string) {
+	return b.model, b.models.observe(b.model)
+}
+
 func (b *codexSessionBuilder) handleResponseItem(
 	payload gjson.Result, ts time.Time,
 ) {
-	if payload.Get("type").Str == "function_call" {
+	switch payload.Get("type").Str {
+	case "function_call":
 		b.handleFunctionCall(payload, ts)
 		return
+	case "reasoning":
+		b.handleReasoning(payload, ts)
+		return
+	case "function_call_output":
+		b.handleFunctionCallOutput(payload)
+		return
 	}
 
 	role := payload.Get("role").Str
@@ -110,8 +228,12 @@ func (b *codexSessionBuilder) handleResponseItem(
 	}
 
 	if role == "user" && isCodexSystemMessage(content) {
+		b.skipped[OriginSystem]++
 		return
 	}
+	if role == "user" {
+		b.flushThinking()
+	}
 
 	if role == "user" && b.firstMessage == "" {
 		b.firstMessage = truncate(
@@ -119,13 +241,89 @@ func (b *codexSessionBuilder) handleResponseItem(
 		)
 	}
 
-	b.messages = append(b.messages, ParsedMessage{
+	msg := ParsedMessage{
 		Ordinal:       b.ordinal,
 		Role:          RoleType(role),
 		Content:       content,
 		Timestamp:     ts,
 		ContentLength: len(content),
-	})
+		SourceUUID:    payload.Get("id").Str,
+	}
+	if msg.Role == RoleAssistant {
+		msg.Model, msg.ModelSwitch = b.assistantModel()
+		b.attachThinking(&msg)
+	}
+	b.messages = append(b.messages, msg)
+	b.ordinal++
+}
+
+// handleReasoning buffers a reasoning item's text for the
+// assistant message that follows it. Codex writes the summary
+// and, for some models, the raw reasoning; reasoning that is
+// only stored encrypted has no text and is skipped.
+func (b *codexSessionBuilder) handleReasoning(
+	payload gjson.Result, ts time.Time,
+) {
+	var texts []string
+	for _, key := range []string{"summary", "content"} {
+		payload.Get(key).ForEach(func(_, block gjson.Result) bool {
+			switch block.Get("type").Str {
+			case "summary_text", "reasoning_text", "text":
+				if t := strings.TrimSpace(block.Get("text").Str); t != "" {
+					texts = append(texts, t)
+				}
+			}
+			return true
+		})
+		if len(texts) > 0 {
+			break
+		}
+	}
+	if len(texts) == 0 {
+		return
+	}
+	if len(b.thinking) == 0 {
+		b.thinkingTS = ts
+	}
+	b.thinking = append(b.thinking, truncate(
+		strings.Join(texts, "\n\n"), maxCodexThinkingLen,
+	))
+}
+
+// attachThinking prepends buffered reasoning to an assistant
+// message and marks it as having thinking.
+func (b *codexSessionBuilder) attachThinking(msg *ParsedMessage) {
+	if len(b.thinking) == 0 {
+		return
+	}
+	parts := make([]string, 0, len(b.thinking)+1)
+	for _, t := range b.thinking {
+		parts = append(parts, "[Thinking]\n"+t+"\n[/Thinking]")
+	}
+	if msg.Content != "" {
+		parts = append(parts, msg.Content)
+	}
+	msg.Content = strings.Join(parts, "\n")
+	msg.ContentLength = len(msg.Content)
+	msg.HasThinking = true
+	b.thinking = nil
+}
+
+// flushThinking emits reasoning that no assistant message
+// followed, such as when the user interrupted the turn, as an
+// assistant message of its own.
+func (b *codexSessionBuilder) flushThinking() {
+	if len(b.thinking) == 0 {
+		return
+	}
+	msg := ParsedMessage{
+		Ordinal:   b.ordinal,
+		Role:      RoleAssistant,
+		Timestamp: b.thinkingTS,
+	}
+	msg.Model, msg.ModelSwitch = b.assistantModel()
+	b.attachThinking(&msg)
+	b.messages = append(b.messages, msg)
 	b.ordinal++
 }
 
@@ -139,23 +337,61 @@ func (b *codexSessionBuilder) handleFunctionCall(
 
 	content := formatCodexFunctionCall(name, payload)
 	inputJSON := extractCodexInputJSON(payload)
+	model, modelSwitch := b.assistantModel()
 
-	b.messages = append(b.messages, ParsedMessage{
+	msg := ParsedMessage{
 		Ordinal:       b.ordinal,
 		Role:          RoleAssistant,
 		Content:       content,
 		Timestamp:     ts,
 		HasToolUse:    true,
 		ContentLength: len(content),
+		Model:         model,
+		ModelSwitch:   modelSwitch,
+		SourceUUID:    payload.Get("id").Str,
 		ToolCalls: []ParsedToolCall{{
+			ToolUseID: payload.Get("call_id").Str,
 			ToolName:  name,
 			Category:  NormalizeToolCategory(name),
 			InputJSON: inputJSON,
 		}},
-	})
+	}
+	b.attachThinking(&msg)
+	b.messages = append(b.messages, msg)
 	b.ordinal++
 }
 
+// handleFunctionCallOutput attaches a function call's output to
+// the message that made the call, for pairing by call_id.
+func (b *codexSessionBuilder) handleFunctionCallOutput(
+	payload gjson.Result,
+) {
+	callID := payload.Get("call_id").Str
+	if callID == "" {
+		return
+	}
+	output := payload.Get("output")
+	length := toolResultContentLength(output)
+	truncated, orig := detectTruncation(output.Raw, length)
+	result := ParsedToolResult{
+		ToolUseID:      callID,
+		ContentLength:  length,
+		ContentRaw:     output.Raw,
+		Truncated:      truncated,
+		OriginalLength: orig,
+	}
+	for i := len(b.messages) - 1; i >= 0; i-- {
+		for _, tc := range b.messages[i].ToolCalls {
+			if tc.ToolUseID == callID {
+				b.messages[i].ToolResults = append(
+					b.messages[i].ToolResults, result,
+				)
+				return
+			}
+		}
+	}
+}
+
 func formatCodexFunctionCall(
 	name string, payload gjson.Result,
 ) string {
@@ -545,7 +781,9 @@ func ParseCodexSession(
 	defer f.Close()
 
 	lr := newLineReader(f, maxLineSize)
+	lr.maxString = maxCodexStringLen
 	b := newCodexSessionBuilder(includeExec)
+	b.source = path
 
 	for {
 		line, ok := lr.next()
@@ -564,6 +802,7 @@ func ParseCodexSession(
 		return nil, nil,
 			fmt.Errorf("reading codex %s: %w", path, err)
 	}
+	b.flushThinking()
 
 	sessionID := b.sessionID
 	if sessionID == "" {
@@ -595,11 +834,82 @@ func ParseCodexSession(
 			Size:  info.Size(),
 			Mtime: info.ModTime().UnixNano(),
 		},
+		Automated:      b.automated,
+		GitBranch:      b.gitBranch,
+		Worktree:       b.worktree,
+		Client:         b.client,
+		EntryPoint:     ClassifyEntryPoint(b.client),
+		Environment:    b.env,
+		UnknownRecords: b.unknown.records,
+
+		SkippedUserMessages: b.skipped,
 	}
 
 	return sess, b.messages, nil
 }
 
+// MergeCodexRollouts combines rollout files that Codex split a
+// single session across (e.g. after a restart). All parts must
+// share the same session_meta id. Parts are ordered by start
+// time and their messages renumbered into one ordinal sequence;
+// the most recently ended part becomes the session's file.
+func MergeCodexRollouts(parts []ParseResult) ParseResult {
+	if len(parts) == 0 {
+		return ParseResult{}
+	}
+	sorted := slices.Clone(parts)
+	slices.SortStableFunc(sorted, func(a, b ParseResult) int {
+		return a.Session.StartedAt.Compare(b.Session.StartedAt)
+	})
+
+	merged := sorted[0].Session
+	merged.Environment = maps.Clone(merged.Environment)
+	merged.SkippedUserMessages = maps.Clone(merged.SkippedUserMessages)
+	var msgs []ParsedMessage
+	for i, p := range sorted {
+		s := p.Session
+		if i > 0 {
+			merged.MessageCount += s.MessageCount
+			merged.UserMessageCount += s.UserMessageCount
+			if merged.Project == "unknown" {
+				merged.Project = s.Project
+			}
+			if merged.FirstMessage == "" {
+				merged.FirstMessage = s.FirstMessage
+			}
+			if merged.StartedAt.IsZero() {
+				merged.StartedAt = s.StartedAt
+			}
+			if !s.EndedAt.Before(merged.EndedAt) {
+				merged.EndedAt = s.EndedAt
+				merged.File = s.File
+			}
+			merged.UnknownRecords = mergeUnknownRecords(
+				merged.UnknownRecords, s.UnknownRecords,
+			)
+			for origin, n := range s.SkippedUserMessages {
+				if merged.SkippedUserMessages == nil {
+					merged.SkippedUserMessages = map[string]int{}
+				}
+				merged.SkippedUserMessages[origin] += n
+			}
+			for k, v := range s.Environment {
+				if _, ok := merged.Environment[k]; !ok {
+					if merged.Environment == nil {
+						merged.Environment = map[string]string{}
+					}
+					merged.Environment[k] = v
+				}
+			}
+		}
+		for _, m := range p.Messages {
+			m.Ordinal = len(msgs)
+			msgs = append(msgs, m)
+		}
+	}
+	return ParseResult{Session: merged, Messages: msgs}
+}
+
 func isCodexSystemMessage(content string) bool {
 	return strings.HasPrefix(content, "# AGENTS.md") ||
 		strings.HasPrefix(content, "<environment_context>") ||
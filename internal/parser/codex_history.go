@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// CodexHistoryFileName is the prompt log Codex keeps next to its
+// sessions directory. Each line records one user prompt:
+//
+//	{"session_id":"<uuid>","ts":<unix seconds>,"text":"..."}
+//
+// It outlives rollout files Codex prunes, so it is the only
+// record of older sessions.
+const CodexHistoryFileName = "history.jsonl"
+
+// CodexHistoryPath returns the history.jsonl that belongs to a
+// Codex sessions directory (<codex home>/sessions), or "" for
+// other directories such as archived_sessions.
+func CodexHistoryPath(sessionsDir string) string {
+	if filepath.Base(sessionsDir) != "sessions" {
+		return ""
+	}
+	return filepath.Join(
+		filepath.Dir(sessionsDir), CodexHistoryFileName,
+	)
+}
+
+// IsCodexHistoryPath reports whether path is a Codex
+// history.jsonl rather than a rollout file.
+func IsCodexHistoryPath(path string) bool {
+	return filepath.Base(path) == CodexHistoryFileName
+}
+
+// ParseCodexHistory reads a Codex history.jsonl into one session
+// per session_id, holding only the user prompts in the order
+// they were logged. Sessions whose rollout file still exists
+// should be taken from it instead; the caller filters those.
+func ParseCodexHistory(
+	path, machine string,
+) ([]ParseResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	file := FileInfo{
+		Path:  path,
+		Size:  info.Size(),
+		Mtime: info.ModTime().UnixNano(),
+	}
+	index := map[string]int{}
+	var results []ParseResult
+
+	lr := newLineReader(f, maxLineSize)
+	for {
+		line, err := lr.readLine()
+		if err != nil {
+			if err != io.EOF {
+				return nil, fmt.Errorf("reading %s: %w", path, err)
+			}
+			break
+		}
+		if !gjson.Valid(line) {
+			continue
+		}
+		rec := gjson.Parse(line)
+		sid := rec.Get("session_id").Str
+		text := rec.Get("text").Str
+		if !IsValidSessionID(sid) || strings.TrimSpace(text) == "" {
+			continue
+		}
+		ts := time.Unix(rec.Get("ts").Int(), 0)
+
+		i, ok := index[sid]
+		if !ok {
+			i = len(results)
+			index[sid] = i
+			results = append(results, ParseResult{
+				Session: ParsedSession{
+					ID:      "codex:" + sid,
+					Project: "unknown",
+					Machine: machine,
+					Agent:   AgentCodex,
+					FirstMessage: truncate(
+						strings.ReplaceAll(text, "\n", " "), 300,
+					),
+					StartedAt: ts,
+					File:      file,
+				},
+			})
+		}
+		r := &results[i]
+		r.Messages = append(r.Messages, ParsedMessage{
+			Ordinal:       len(r.Messages),
+			Role:          RoleUser,
+			Content:       text,
+			Timestamp:     ts,
+			ContentLength: len(text),
+		})
+		r.Session.EndedAt = ts
+		r.Session.MessageCount = len(r.Messages)
+		r.Session.UserMessageCount = len(r.Messages)
+	}
+	return results, nil
+}
@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -45,6 +48,17 @@ func TestParseCodexSession_ExecOriginator(t *testing.T) {
 		require.NotNil(t, sess)
 		assert.Equal(t, "codex:abc", sess.ID)
 		assert.Equal(t, 1, len(msgs))
+		assert.True(t, sess.Automated)
+	})
+
+	t.Run("interactive originator is not automated", func(t *testing.T) {
+		content := testjsonl.JoinJSONL(
+			testjsonl.CodexSessionMetaJSON("abc", "/tmp", "codex_cli_rs", tsEarly),
+			testjsonl.CodexMsgJSON("user", "test", tsEarlyS1),
+		)
+		sess, _ := runCodexParserTest(t, "test.jsonl", content, true)
+		require.NotNil(t, sess)
+		assert.False(t, sess.Automated)
 	})
 }
 
@@ -327,3 +341,240 @@ func TestParseCodexSession_EdgeCases(t *testing.T) {
 		assert.Equal(t, "unknown", sess.Project)
 	})
 }
+
+func TestParseCodexSession_Environment(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		`{"type":"session_meta","timestamp":"`+tsEarly+`","payload":{"id":"env","cwd":"/tmp","originator":"codex_cli_rs","cli_version":"0.46.0"}}`,
+		`{"type":"turn_context","timestamp":"`+tsEarlyS1+`","payload":{"cwd":"/tmp","approval_policy":"on-request","sandbox_policy":{"mode":"workspace-write","writable_roots":["/tmp"]},"model":"gpt-5-codex","effort":"high","summary":"auto"}}`,
+		testjsonl.CodexMsgJSON("user", "hi", tsEarlyS1),
+		`{"type":"turn_context","timestamp":"`+tsEarlyS5+`","payload":{"cwd":"/tmp","approval_policy":"never","model":"gpt-5-codex","effort":"low"}}`,
+		testjsonl.CodexMsgJSON("assistant", "hello", tsEarlyS5),
+	)
+	sess, _ := runCodexParserTest(t, "test.jsonl", content, false)
+	require.NotNil(t, sess)
+	assert.Equal(t, map[string]string{
+		EnvClientVersion:    "0.46.0",
+		EnvApprovalPolicy:   "on-request",
+		EnvSandbox:          "workspace-write",
+		EnvReasoningEffort:  "high",
+		EnvReasoningSummary: "auto",
+	}, sess.Environment)
+}
+
+func TestParseCodexSession_ModelSwitches(t *testing.T) {
+	turn := func(model string) string {
+		return `{"type":"turn_context","timestamp":"` + tsEarlyS1 +
+			`","payload":{"cwd":"/tmp","model":"` + model + `"}}`
+	}
+	content := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("ms", "/tmp", "user", tsEarly),
+		turn("gpt-5-codex"),
+		testjsonl.CodexMsgJSON("user", "hi", tsEarlyS1),
+		testjsonl.CodexMsgJSON("assistant", "hello", tsEarlyS1),
+		turn("gpt-5-codex"),
+		testjsonl.CodexMsgJSON("user", "again", tsEarlyS1),
+		testjsonl.CodexMsgJSON("assistant", "same model", tsEarlyS1),
+		turn("gpt-5"),
+		testjsonl.CodexMsgJSON("user", "switch", tsEarlyS5),
+		testjsonl.CodexFunctionCallJSON("shell", "ls", tsEarlyS5),
+	)
+	_, msgs := runCodexParserTest(t, "test.jsonl", content, false)
+	require.Len(t, msgs, 6)
+
+	assert.Empty(t, msgs[0].Model, "user messages carry no model")
+	assert.Equal(t, "gpt-5-codex", msgs[1].Model)
+	assert.Empty(t, msgs[1].ModelSwitch)
+	assert.Empty(t, msgs[3].ModelSwitch)
+	assert.Equal(t, "gpt-5", msgs[5].Model)
+	assert.Equal(t, ModelSwitchCommand, msgs[5].ModelSwitch)
+}
+
+func TestParseCodexSession_SourceUUID(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("u", "/tmp", "user", tsEarly),
+		testjsonl.CodexMsgJSON("user", "fix it", tsEarlyS1),
+		`{"type":"response_item","timestamp":"`+tsEarlyS1+`","payload":{"type":"function_call","id":"fc_1","name":"shell","arguments":"{\"command\":[\"ls\"]}"}}`,
+		`{"type":"response_item","timestamp":"`+tsEarlyS5+`","payload":{"type":"message","id":"msg_2","role":"assistant","content":[{"type":"output_text","text":"done"}]}}`,
+	)
+	_, msgs := runCodexParserTest(t, "test.jsonl", content, false)
+	require.Len(t, msgs, 3)
+	assert.Empty(t, msgs[0].SourceUUID)
+	assert.Equal(t, "fc_1", msgs[1].SourceUUID)
+	assert.Equal(t, "msg_2", msgs[2].SourceUUID)
+}
+
+func TestParseCodexSession_Reasoning(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("r", "/tmp", "user", tsEarly),
+		testjsonl.CodexMsgJSON("user", "fix it", tsEarlyS1),
+		testjsonl.CodexReasoningJSON(tsEarlyS1, "**Planning**", "Look at tests"),
+		testjsonl.CodexFunctionCallJSON("shell", "go test", tsEarlyS1),
+		testjsonl.CodexReasoningJSON(tsEarlyS1), // encrypted only
+		testjsonl.CodexMsgJSON("assistant", "done", tsEarlyS5),
+		testjsonl.CodexReasoningJSON(tsEarlyS5, "Next step"),
+		testjsonl.CodexMsgJSON("user", "stop", tsEarlyS5),
+		testjsonl.CodexReasoningJSON(tsEarlyS5, "Trailing"),
+	)
+	_, msgs := runCodexParserTest(t, "test.jsonl", content, false)
+	require.Len(t, msgs, 6)
+
+	assert.True(t, msgs[1].HasThinking)
+	assert.True(t, msgs[1].HasToolUse)
+	assert.Equal(t,
+		"[Thinking]\n**Planning**\n\nLook at tests\n[/Thinking]\n[Bash: go test]",
+		msgs[1].Content)
+	assert.Equal(t, len(msgs[1].Content), msgs[1].ContentLength)
+
+	assert.False(t, msgs[2].HasThinking, "encrypted reasoning has no text")
+	assert.Equal(t, "done", msgs[2].Content)
+
+	// Reasoning cut off by the user becomes its own message.
+	assert.Equal(t, RoleAssistant, msgs[3].Role)
+	assert.True(t, msgs[3].HasThinking)
+	assert.Equal(t, "[Thinking]\nNext step\n[/Thinking]", msgs[3].Content)
+	assert.Equal(t, RoleUser, msgs[4].Role)
+	assert.Equal(t, 4, msgs[4].Ordinal)
+
+	// So does reasoning at the end of the file.
+	assert.True(t, msgs[5].HasThinking)
+	assert.Equal(t, 5, msgs[5].Ordinal)
+}
+
+func TestParseCodexSession_EntryPoint(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("ep", "/tmp", "codex_vscode", tsEarly),
+		testjsonl.CodexMsgJSON("user", "hi", tsEarlyS1),
+	)
+	sess, _ := runCodexParserTest(t, "test.jsonl", content, false)
+	require.NotNil(t, sess)
+	assert.Equal(t, "codex_vscode", sess.Client)
+	assert.Equal(t, EntryVSCode, sess.EntryPoint)
+}
+
+func TestParseCodexSession_UnknownRecords(t *testing.T) {
+	long := `{"type":"telemetry","payload":"` +
+		strings.Repeat("é", maxUnknownSampleLen) + `"}`
+	content := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("u", "/tmp", "user", tsEarly),
+		`{"type":"event_msg","timestamp":"`+tsEarly+`","payload":{"type":"token_count"}}`,
+		long,
+		testjsonl.CodexMsgJSON("user", "hi", tsEarlyS1),
+	)
+	sess, _ := runCodexParserTest(t, "test.jsonl", content, false)
+	require.NotNil(t, sess)
+	require.Len(t, sess.UnknownRecords, 1)
+	rec := sess.UnknownRecords[0]
+	assert.Equal(t, "telemetry", rec.Type)
+	assert.Equal(t, 1, rec.Count)
+	assert.LessOrEqual(t, len(rec.Sample), maxUnknownSampleLen)
+	assert.True(t, utf8.ValidString(rec.Sample))
+	assert.True(t, strings.HasPrefix(long, rec.Sample))
+}
+
+func TestParseCodexSession_TokenUsage(t *testing.T) {
+	tokenCount := func(total, input, cached, output, reasoning int) string {
+		return fmt.Sprintf(`{"type":"event_msg","timestamp":"%s",`+
+			`"payload":{"type":"token_count","info":{`+
+			`"total_token_usage":{"total_tokens":%d},`+
+			`"last_token_usage":{"input_tokens":%d,`+
+			`"cached_input_tokens":%d,"output_tokens":%d,`+
+			`"reasoning_output_tokens":%d}}}}`,
+			tsEarlyS5, total, input, cached, output, reasoning)
+	}
+	content := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("tok", "/tmp", "user", tsEarly),
+		tokenCount(50, 40, 0, 10, 0),
+		testjsonl.CodexMsgJSON("user", "run the tests", tsEarlyS1),
+		testjsonl.CodexFunctionCallJSON("shell", "go test", tsEarlyS1),
+		tokenCount(1200, 1000, 800, 150, 100),
+		tokenCount(1200, 1000, 800, 150, 100),
+		testjsonl.CodexMsgJSON("assistant", "all pass", tsEarlyS5),
+		tokenCount(1500, 250, 200, 50, 0),
+		`{"type":"event_msg","timestamp":"`+tsEarlyS5+`","payload":{"type":"token_count","info":null}}`,
+	)
+	_, msgs := runCodexParserTest(t, "test.jsonl", content, false)
+	require.Len(t, msgs, 3)
+
+	assert.Equal(t, TokenUsage{}, msgs[0].Usage)
+	assert.Equal(t, TokenUsage{
+		InputTokens: 240, OutputTokens: 160,
+		CacheReadTokens: 800, ReasoningTokens: 100,
+	}, msgs[1].Usage)
+	assert.Equal(t, TokenUsage{
+		InputTokens: 50, OutputTokens: 50, CacheReadTokens: 200,
+	}, msgs[2].Usage)
+}
+
+func TestMergeCodexRollouts(t *testing.T) {
+	first := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("split", "/home/user/code/api", "user", tsEarly),
+		testjsonl.CodexMsgJSON("user", "start work", tsEarlyS1),
+		testjsonl.CodexMsgJSON("assistant", "on it", tsEarlyS5),
+		`{"type":"telemetry","part":1}`,
+	)
+	second := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("split", "/home/user/code/api", "user", tsLate),
+		testjsonl.CodexMsgJSON("user", "keep going", tsLate),
+		testjsonl.CodexMsgJSON("assistant", "done", tsLateS5),
+		`{"type":"telemetry","part":2}`,
+	)
+	s1, m1 := runCodexParserTest(t, "rollout-a.jsonl", first, false)
+	s2, m2 := runCodexParserTest(t, "rollout-b.jsonl", second, false)
+
+	// Input order must not matter.
+	merged := MergeCodexRollouts([]ParseResult{
+		{Session: *s2, Messages: m2},
+		{Session: *s1, Messages: m1},
+	})
+
+	sess := merged.Session
+	assert.Equal(t, "codex:split", sess.ID)
+	assert.Equal(t, "start work", sess.FirstMessage)
+	assert.Equal(t, s1.StartedAt, sess.StartedAt)
+	assert.Equal(t, s2.EndedAt, sess.EndedAt)
+	assert.Equal(t, s2.File.Path, sess.File.Path)
+	assert.Equal(t, 4, sess.MessageCount)
+	assert.Equal(t, 2, sess.UserMessageCount)
+	assert.Equal(t, []UnknownRecord{
+		{Type: "telemetry", Count: 2, Sample: `{"type":"telemetry","part":1}`},
+	}, sess.UnknownRecords)
+
+	require.Len(t, merged.Messages, 4)
+	for i, want := range []string{
+		"start work", "on it", "keep going", "done",
+	} {
+		assert.Equal(t, i, merged.Messages[i].Ordinal)
+		assert.Equal(t, want, merged.Messages[i].Content)
+	}
+}
+
+func TestParseCodexHistory(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		`{"session_id":"aaa-1","ts":1700000000,"text":"fix the\nbuild"}`,
+		`{"session_id":"bbb-2","ts":1700000100,"text":"write docs"}`,
+		`not json`,
+		`{"session_id":"aaa-1","ts":1700000200,"text":"now test it"}`,
+		`{"session_id":"../bad","ts":1700000300,"text":"ignored"}`,
+		`{"session_id":"bbb-2","ts":1700000400,"text":"  "}`,
+	)
+	path := createTestFile(t, CodexHistoryFileName, content)
+
+	results, err := ParseCodexHistory(path, "local")
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	a := results[0]
+	assert.Equal(t, "codex:aaa-1", a.Session.ID)
+	assert.Equal(t, AgentCodex, a.Session.Agent)
+	assert.Equal(t, "fix the build", a.Session.FirstMessage)
+	assert.Equal(t, int64(1700000000), a.Session.StartedAt.Unix())
+	assert.Equal(t, int64(1700000200), a.Session.EndedAt.Unix())
+	assert.Equal(t, 2, a.Session.UserMessageCount)
+	require.Len(t, a.Messages, 2)
+	assert.Equal(t, "now test it", a.Messages[1].Content)
+	assert.Equal(t, 1, a.Messages[1].Ordinal)
+	assert.Equal(t, RoleUser, a.Messages[1].Role)
+
+	assert.Equal(t, "codex:bbb-2", results[1].Session.ID)
+	assert.Len(t, results[1].Messages, 1)
+}
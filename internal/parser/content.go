@@ -69,10 +69,14 @@ func ExtractTextContent(
 			if tuid != "" {
 				rc := block.Get("content")
 				cl := toolResultContentLength(rc)
+				truncated, orig := detectTruncation(rc.Raw, cl)
 				toolResults = append(toolResults, ParsedToolResult{
-					ToolUseID:     tuid,
-					ContentLength: cl,
-					ContentRaw:    rc.Raw,
+					ToolUseID:      tuid,
+					ContentLength:  cl,
+					ContentRaw:     rc.Raw,
+					IsError:        block.Get("is_error").Bool(),
+					Truncated:      truncated,
+					OriginalLength: orig,
 				})
 			}
 		}
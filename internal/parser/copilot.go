@@ -29,6 +29,9 @@ type copilotSessionBuilder struct {
 	sessionID    string
 	project      string
 	ordinal      int
+	gitBranch    string
+	worktree     string
+	source       string // transcript path, for BridgePath
 }
 
 func newCopilotSessionBuilder() *copilotSessionBuilder {
@@ -73,8 +76,11 @@ func (b *copilotSessionBuilder) handleSessionStart(
 	cwd := data.Get("context.cwd").Str
 	branch := data.Get("context.branch").Str
 	if cwd != "" {
+		b.gitBranch = branch
+		hostCwd := BridgePath(b.source, cwd)
+		b.worktree = WorktreeName(hostCwd, branch)
 		if p := ExtractProjectFromCwdWithBranch(
-			cwd, branch,
+			hostCwd, branch,
 		); p != "" {
 			b.project = p
 		}
@@ -231,6 +237,7 @@ func ParseCopilotSession(
 
 	lr := newLineReader(f, maxLineSize)
 	b := newCopilotSessionBuilder()
+	b.source = path
 
 	for {
 		line, ok := lr.next()
@@ -283,6 +290,8 @@ func ParseCopilotSession(
 		EndedAt:          b.endedAt,
 		MessageCount:     len(b.messages),
 		UserMessageCount: userCount,
+		GitBranch:        b.gitBranch,
+		Worktree:         b.worktree,
 		File: FileInfo{
 			Path:  path,
 			Size:  info.Size(),
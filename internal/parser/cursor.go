@@ -98,6 +98,7 @@ func ParseCursorSession(
 		StartedAt:    mtime,
 		EndedAt:      mtime,
 		MessageCount: len(messages),
+		EntryPoint:   EntryCursor,
 		File: FileInfo{
 			Path:  path,
 			Size:  info.Size(),
@@ -123,10 +123,26 @@ func DiscoverClaudeProjects(projectsDir string) []DiscoveredFile {
 }
 
 // DiscoverCodexSessions finds all JSONL files under the Codex
-// sessions dir (year/month/day structure).
+// sessions dir (year/month/day structure), the rollout files in
+// an archived_sessions dir, and the history.jsonl next to a
+// sessions dir.
 func DiscoverCodexSessions(sessionsDir string) []DiscoveredFile {
 	var files []DiscoveredFile
 
+	for _, name := range flatCodexRollouts(sessionsDir) {
+		files = append(files, DiscoveredFile{
+			Path:  filepath.Join(sessionsDir, name),
+			Agent: AgentCodex,
+		})
+	}
+	if h := CodexHistoryPath(sessionsDir); h != "" &&
+		IsRegularFile(h) {
+		files = append(files, DiscoveredFile{
+			Path:  h,
+			Agent: AgentCodex,
+		})
+	}
+
 	walkCodexDayDirs(sessionsDir, func(dayPath string) bool {
 		entries, err := os.ReadDir(dayPath)
 		if err != nil {
@@ -213,12 +229,18 @@ func FindClaudeSourceFile(
 }
 
 // FindCodexSourceFile finds a Codex session file by UUID.
-// Searches the year/month/day directory structure for files matching
+// Searches the year/month/day directory structure, or an
+// archived_sessions dir, for files matching
 // rollout-{timestamp}-{uuid}.jsonl.
 func FindCodexSourceFile(sessionsDir, sessionID string) string {
 	if !IsValidSessionID(sessionID) {
 		return ""
 	}
+	for _, name := range flatCodexRollouts(sessionsDir) {
+		if extractUUIDFromRollout(name) == sessionID {
+			return filepath.Join(sessionsDir, name)
+		}
+	}
 
 	var result string
 	walkCodexDayDirs(sessionsDir, func(dayPath string) bool {
@@ -248,6 +270,49 @@ func FindCodexSourceFile(sessionsDir, sessionID string) string {
 	return result
 }
 
+// IsCodexArchiveDir reports whether dir is a Codex
+// archived_sessions directory, where archived rollout files are
+// kept flat instead of under year/month/day.
+func IsCodexArchiveDir(dir string) bool {
+	return filepath.Base(dir) == "archived_sessions"
+}
+
+// flatCodexRollouts lists the rollout files directly in an
+// archived_sessions dir.
+func flatCodexRollouts(dir string) []string {
+	if !IsCodexArchiveDir(dir) {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if IsCodexRolloutName(e.Name()) && e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
+// CodexRolloutUUID returns the session UUID in a rollout file's
+// name, or "" if path is not a rollout file.
+func CodexRolloutUUID(path string) string {
+	name := filepath.Base(path)
+	if !IsCodexRolloutName(name) {
+		return ""
+	}
+	return extractUUIDFromRollout(name)
+}
+
+// IsCodexRolloutName reports whether name looks like a Codex
+// rollout file, rollout-{timestamp}-{uuid}.jsonl.
+func IsCodexRolloutName(name string) bool {
+	return strings.HasPrefix(name, "rollout-") &&
+		strings.HasSuffix(name, ".jsonl")
+}
+
 // walkCodexDayDirs traverses a Codex sessions directory with
 // year/month/day structure, calling fn for each valid day directory.
 // fn returns false to stop traversal.
@@ -167,6 +167,23 @@ func TestDiscoverCodexSessions(t *testing.T) {
 			assertDiscoveredFiles(t, files, tt.wantFiles, AgentCodex)
 		})
 	}
+
+	t.Run("ArchivedAndHistory", func(t *testing.T) {
+		home := t.TempDir()
+		setupFileSystem(t, home, map[string]string{
+			filepath.Join("sessions", "2024", "01", "15", file1): "{}",
+			filepath.Join("sessions", file2):                     "{}",
+			filepath.Join("archived_sessions", file2):            "{}",
+			filepath.Join("archived_sessions", "notes.jsonl"):    "{}",
+			CodexHistoryFileName:                                 "{}",
+		})
+		assertDiscoveredFiles(t,
+			DiscoverCodexSessions(filepath.Join(home, "sessions")),
+			[]string{file1, CodexHistoryFileName}, AgentCodex)
+		assertDiscoveredFiles(t,
+			DiscoverCodexSessions(filepath.Join(home, "archived_sessions")),
+			[]string{file2}, AgentCodex)
+	})
 }
 
 func TestDiscoverAmpSessions(t *testing.T) {
@@ -0,0 +1,55 @@
+package parser
+
+import "strings"
+
+// Entry points a session can be started from, as classified
+// from the client identifier an agent records.
+const (
+	EntryTerminal  = "terminal"
+	EntrySSH       = "ssh"
+	EntryTmux      = "tmux"
+	EntryVSCode    = "vscode"
+	EntryJetBrains = "jetbrains"
+	EntryCursor    = "cursor"
+	EntryDesktop   = "desktop"
+	EntrySDK       = "sdk"
+	EntryExec      = "exec"
+	EntryOther     = "other"
+)
+
+// entryPointMarkers maps substrings of a client identifier to
+// entry points, checked in order: more specific contexts (a
+// terminal inside SSH or tmux, an IDE extension) come before
+// the generic CLI marker they may also contain.
+var entryPointMarkers = []struct {
+	marker, entry string
+}{
+	{"ssh", EntrySSH},
+	{"tmux", EntryTmux},
+	{"vscode", EntryVSCode},
+	{"jetbrains", EntryJetBrains},
+	{"intellij", EntryJetBrains},
+	{"cursor", EntryCursor},
+	{"desktop", EntryDesktop},
+	{"sdk", EntrySDK},
+	{"exec", EntryExec},
+	{"cli", EntryTerminal},
+}
+
+// ClassifyEntryPoint normalizes a client identifier such as
+// Claude Code's entrypoint ("cli", "claude-vscode", "sdk-ts")
+// or Codex's originator ("codex_cli_rs", "codex_vscode") to an
+// entry point. Unrecognized identifiers are EntryOther; an
+// empty identifier stays empty (not recorded).
+func ClassifyEntryPoint(client string) string {
+	c := strings.ToLower(client)
+	if c == "" {
+		return ""
+	}
+	for _, m := range entryPointMarkers {
+		if strings.Contains(c, m.marker) {
+			return m.entry
+		}
+	}
+	return EntryOther
+}
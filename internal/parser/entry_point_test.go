@@ -0,0 +1,28 @@
+package parser
+
+import "testing"
+
+func TestClassifyEntryPoint(t *testing.T) {
+	tests := []struct {
+		client, want string
+	}{
+		{"", ""},
+		{"cli", EntryTerminal},
+		{"claude-vscode", EntryVSCode},
+		{"claude-desktop", EntryDesktop},
+		{"sdk-ts", EntrySDK},
+		{"sdk-cli", EntrySDK},
+		{"codex_cli_rs", EntryTerminal},
+		{"codex_vscode", EntryVSCode},
+		{"codex_exec", EntryExec},
+		{"Claude-JetBrains", EntryJetBrains},
+		{"cli-ssh", EntrySSH},
+		{"web", EntryOther},
+	}
+	for _, tt := range tests {
+		if got := ClassifyEntryPoint(tt.client); got != tt.want {
+			t.Errorf("ClassifyEntryPoint(%q) = %q, want %q",
+				tt.client, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Environment keys recorded per session. Only these settings,
+// read from known fields of the transcript, are kept: process
+// environment variables, paths, and free-form text never are,
+// so the snapshot is safe to store and share.
+const (
+	EnvClientVersion    = "client_version"
+	EnvPermissionMode   = "permission_mode"
+	EnvThinking         = "thinking" // "enabled" or "disabled"
+	EnvThinkingLevel    = "thinking_level"
+	EnvApprovalPolicy   = "approval_policy"
+	EnvSandbox          = "sandbox"
+	EnvReasoningEffort  = "reasoning_effort"
+	EnvReasoningSummary = "reasoning_summary"
+
+	// EnvBetaPrefix prefixes one key per beta feature flag,
+	// e.g. "beta.context-1m-2025-08-07", with value "enabled".
+	EnvBetaPrefix = "beta."
+)
+
+// maxEnvValueLen caps stored environment values; longer values
+// are dropped rather than truncated.
+const maxEnvValueLen = 64
+
+// envValueRe matches values safe to store: version strings,
+// mode names, and flag identifiers.
+var envValueRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:+-]*$`)
+
+// envSnapshot collects a session's environment. The first value
+// seen for a key wins, so the snapshot reflects how the session
+// started.
+type envSnapshot map[string]string
+
+func (e *envSnapshot) set(key, value string) {
+	if len(value) > maxEnvValueLen || !envValueRe.MatchString(value) {
+		return
+	}
+	if *e == nil {
+		*e = envSnapshot{}
+	}
+	if _, ok := (*e)[key]; !ok {
+		(*e)[key] = value
+	}
+}
+
+// observeClaude records the settings Claude Code writes on
+// transcript lines and on the Agent SDK system init record.
+func (e *envSnapshot) observeClaude(entryType, line string) {
+	if entryType == "system" &&
+		gjson.Get(line, "subtype").Str == "init" {
+		e.set(EnvClientVersion,
+			gjson.Get(line, "claude_code_version").Str)
+		e.set(EnvPermissionMode,
+			gjson.Get(line, "permissionMode").Str)
+		for _, b := range gjson.Get(line, "betas").Array() {
+			if name := b.Str; envValueRe.MatchString(name) {
+				e.set(EnvBetaPrefix+strings.ToLower(name), "enabled")
+			}
+		}
+		return
+	}
+	if entryType != "user" && entryType != "assistant" {
+		return
+	}
+	e.set(EnvClientVersion, gjson.Get(line, "version").Str)
+	e.set(EnvPermissionMode, gjson.Get(line, "permissionMode").Str)
+	if tm := gjson.Get(line, "thinkingMetadata"); tm.IsObject() {
+		if tm.Get("disabled").Bool() {
+			e.set(EnvThinking, "disabled")
+		} else {
+			e.set(EnvThinking, "enabled")
+		}
+		e.set(EnvThinkingLevel, tm.Get("level").Str)
+	}
+}
+
+// observeCodexMeta records the settings in a Codex session_meta
+// payload.
+func (e *envSnapshot) observeCodexMeta(payload gjson.Result) {
+	e.set(EnvClientVersion, payload.Get("cli_version").Str)
+}
+
+// observeCodexTurn records the settings in a Codex turn_context
+// payload.
+func (e *envSnapshot) observeCodexTurn(payload gjson.Result) {
+	e.set(EnvApprovalPolicy, payload.Get("approval_policy").Str)
+	sandbox := payload.Get("sandbox_policy")
+	if sandbox.IsObject() {
+		e.set(EnvSandbox, firstNonEmpty(
+			sandbox.Get("mode").Str, sandbox.Get("type").Str,
+		))
+	} else {
+		e.set(EnvSandbox, sandbox.Str)
+	}
+	e.set(EnvReasoningEffort, payload.Get("effort").Str)
+	e.set(EnvReasoningSummary, payload.Get("summary").Str)
+}
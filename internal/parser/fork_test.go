@@ -40,6 +40,11 @@ func TestForkDetection_LinearSession(t *testing.T) {
 
 	assertSessionMeta(t, &results[0].Session, "linear", "proj", AgentClaude)
 	assertMessageCount(t, len(results[0].Messages), 4)
+	for i, want := range []string{"a", "b", "c", "d"} {
+		if got := results[0].Messages[i].SourceUUID; got != want {
+			t.Errorf("msg %d SourceUUID = %q, want %q", i, got, want)
+		}
+	}
 }
 
 func TestForkDetection_LargeGapFork(t *testing.T) {
@@ -81,6 +81,8 @@ func ParseGeminiSession(
 				HasThinking:   hasThinking,
 				HasToolUse:    hasToolUse,
 				ContentLength: len(content),
+				Model:         msg.Get("model").Str,
+				Usage:         geminiTokenUsage(msg.Get("tokens")),
 				ToolCalls:     tcs,
 			})
 			ordinal++
@@ -188,6 +190,25 @@ func extractGeminiContent(
 		hasThinking, hasToolUse, parsed
 }
 
+// geminiTokenUsage converts a Gemini CLI "tokens" object
+// ({input, output, cached, thoughts, tool, total}) to
+// TokenUsage. Gemini counts cached tokens inside input, so
+// they are subtracted to keep InputTokens cache-exclusive.
+func geminiTokenUsage(tokens gjson.Result) TokenUsage {
+	if !tokens.IsObject() {
+		return TokenUsage{}
+	}
+	cached := int(tokens.Get("cached").Int())
+	input := int(tokens.Get("input").Int()) +
+		int(tokens.Get("tool").Int()) - cached
+	return TokenUsage{
+		InputTokens:     max(input, 0),
+		OutputTokens:    int(tokens.Get("output").Int()),
+		CacheReadTokens: cached,
+		ReasoningTokens: int(tokens.Get("thoughts").Int()),
+	}
+}
+
 func formatGeminiToolCall(tc gjson.Result) string {
 	name := tc.Get("name").Str
 	displayName := tc.Get("displayName").Str
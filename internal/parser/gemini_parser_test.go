@@ -173,3 +173,33 @@ func TestParseGeminiSession_EdgeCases(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestParseGeminiSession_ModelAndTokens(t *testing.T) {
+	content := testjsonl.GeminiSessionJSON("sess-uuid-tokens", "hash", tsEarly, tsEarlyS5, []map[string]any{
+		testjsonl.GeminiUserMsg("u1", tsEarly, "hello"),
+		testjsonl.GeminiAssistantMsg("a1", tsEarlyS5, "Hi there.", &testjsonl.GeminiMsgOpts{
+			Model: "gemini-2.5-pro",
+			Tokens: &testjsonl.GeminiTokens{
+				Input: 1200, Output: 80, Cached: 1000,
+				Thoughts: 40, Tool: 10,
+			},
+		}),
+		testjsonl.GeminiAssistantMsg("a2", tsEarlyS5, "No usage.", nil),
+	})
+	_, msgs := runGeminiParserTest(t, content)
+	require.Equal(t, 3, len(msgs))
+
+	assert.Empty(t, msgs[0].Model)
+	assert.Equal(t, TokenUsage{}, msgs[0].Usage)
+
+	assert.Equal(t, "gemini-2.5-pro", msgs[1].Model)
+	assert.Equal(t, TokenUsage{
+		InputTokens:     210,
+		OutputTokens:    80,
+		CacheReadTokens: 1000,
+		ReasoningTokens: 40,
+	}, msgs[1].Usage)
+
+	assert.Empty(t, msgs[2].Model)
+	assert.Equal(t, TokenUsage{}, msgs[2].Usage)
+}
@@ -0,0 +1,314 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// gooseFallbackProject names sessions whose metadata records no
+// working directory.
+const gooseFallbackProject = "goose"
+
+// IsGooseSessionFileName reports whether name is a Goose
+// session file (<name>.jsonl) whose name is usable as a
+// session ID.
+func IsGooseSessionFileName(name string) bool {
+	stem, ok := strings.CutSuffix(name, ".jsonl")
+	return ok && IsValidSessionID(stem)
+}
+
+// DiscoverGooseSessions finds the session files directly in a
+// Goose sessions directory (~/.local/share/goose/sessions).
+func DiscoverGooseSessions(sessionsDir string) []DiscoveredFile {
+	if sessionsDir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(sessionsDir)
+	if err != nil {
+		return nil
+	}
+
+	var files []DiscoveredFile
+	for _, entry := range entries {
+		if entry.IsDir() || !IsGooseSessionFileName(entry.Name()) {
+			continue
+		}
+		files = append(files, DiscoveredFile{
+			Path:  filepath.Join(sessionsDir, entry.Name()),
+			Agent: AgentGoose,
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// FindGooseSourceFile locates a Goose session file by its
+// session name (the ID without the "goose:" prefix).
+func FindGooseSourceFile(sessionsDir, name string) string {
+	if sessionsDir == "" || !IsValidSessionID(name) {
+		return ""
+	}
+	candidate := filepath.Join(sessionsDir, name+".jsonl")
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate
+	}
+	return ""
+}
+
+// ParseGooseSession parses a Goose session file. The first
+// line holds session metadata (working directory, description,
+// token totals); every later line is one message whose content
+// is a list of text, thinking, toolRequest, and toolResponse
+// items. Tool results arrive in user messages, paired to their
+// request by ID.
+func ParseGooseSession(
+	path, machine string,
+) (*ParsedSession, []ParsedMessage, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	lr := newLineReader(f, maxLineSize)
+	var (
+		messages           []ParsedMessage
+		meta               gjson.Result
+		startedAt, endedAt time.Time
+		firstMsg           string
+		userCount          int
+	)
+	for {
+		line, ok := lr.next()
+		if !ok {
+			break
+		}
+		if !gjson.Valid(line) {
+			continue
+		}
+		entry := gjson.Parse(line)
+		role := entry.Get("role").Str
+		if role == "" {
+			if !meta.Exists() {
+				meta = entry
+			}
+			continue
+		}
+		if role != "user" && role != "assistant" {
+			continue
+		}
+
+		var ts time.Time
+		if secs := entry.Get("created").Int(); secs > 0 {
+			ts = time.Unix(secs, 0).UTC()
+			if startedAt.IsZero() || ts.Before(startedAt) {
+				startedAt = ts
+			}
+			if ts.After(endedAt) {
+				endedAt = ts
+			}
+		}
+
+		text, hasThinking, tcs, trs := extractGooseContent(
+			entry.Get("content"),
+		)
+		text = strings.TrimSpace(text)
+		if text == "" && len(tcs) == 0 && len(trs) == 0 {
+			continue
+		}
+		m := ParsedMessage{
+			Ordinal:       len(messages),
+			Role:          RoleUser,
+			Content:       text,
+			Timestamp:     ts,
+			HasThinking:   hasThinking,
+			HasToolUse:    len(tcs) > 0,
+			ContentLength: len(text),
+			SourceUUID:    entry.Get("id").Str,
+			ToolCalls:     tcs,
+			ToolResults:   trs,
+		}
+		if role == "assistant" {
+			m.Role = RoleAssistant
+		} else if text != "" {
+			userCount++
+			if firstMsg == "" {
+				firstMsg = truncate(
+					strings.ReplaceAll(text, "\n", " "), 300,
+				)
+			}
+		}
+		messages = append(messages, m)
+	}
+	if err := lr.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	if len(messages) == 0 {
+		return nil, nil, nil
+	}
+
+	// Goose keeps token counts for the session as a whole, so
+	// they are credited to its last reply.
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == RoleAssistant {
+			messages[i].Usage = gooseTokenUsage(meta)
+			break
+		}
+	}
+
+	if desc := meta.Get("description").Str; desc != "" {
+		firstMsg = desc
+	}
+	project := gooseFallbackProject
+	if cwd := meta.Get("working_dir").Str; cwd != "" {
+		if p := ExtractProjectFromCwd(cwd); p != "" {
+			project = p
+		}
+	}
+
+	stem := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+	sess := &ParsedSession{
+		ID:               "goose:" + stem,
+		Project:          project,
+		Machine:          machine,
+		Agent:            AgentGoose,
+		FirstMessage:     firstMsg,
+		StartedAt:        startedAt,
+		EndedAt:          endedAt,
+		MessageCount:     len(messages),
+		UserMessageCount: userCount,
+		Automated:        meta.Get("schedule_id").Str != "",
+		File: FileInfo{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: info.ModTime().UnixNano(),
+		},
+	}
+	return sess, messages, nil
+}
+
+// gooseTokenUsage reads the session's token totals, preferring
+// the accumulated counts that survive context summarization.
+func gooseTokenUsage(meta gjson.Result) TokenUsage {
+	count := func(key string) int {
+		if n := meta.Get("accumulated_" + key).Int(); n > 0 {
+			return int(n)
+		}
+		return int(meta.Get(key).Int())
+	}
+	return TokenUsage{
+		InputTokens:  count("input_tokens"),
+		OutputTokens: count("output_tokens"),
+	}
+}
+
+// extractGooseContent flattens a Goose message's content items
+// into display text, tool calls, and tool results.
+func extractGooseContent(
+	content gjson.Result,
+) (string, bool, []ParsedToolCall, []ParsedToolResult) {
+	var (
+		parts       []string
+		toolCalls   []ParsedToolCall
+		toolResults []ParsedToolResult
+		hasThinking bool
+	)
+	content.ForEach(func(_, item gjson.Result) bool {
+		switch item.Get("type").Str {
+		case "text":
+			if t := item.Get("text").Str; t != "" {
+				parts = append(parts, t)
+			}
+		case "thinking":
+			if t := item.Get("thinking").Str; t != "" {
+				hasThinking = true
+				parts = append(parts,
+					"[Thinking]\n"+t+"\n[/Thinking]")
+			}
+		case "redactedThinking":
+			hasThinking = true
+		case "toolRequest", "frontendToolRequest":
+			call := item.Get("toolCall.value")
+			name := call.Get("name").Str
+			if name == "" {
+				return true
+			}
+			args := call.Get("arguments")
+			toolCalls = append(toolCalls, ParsedToolCall{
+				ToolUseID: item.Get("id").Str,
+				ToolName:  name,
+				Category:  gooseToolCategory(name, args),
+				InputJSON: args.Raw,
+			})
+			parts = append(parts, formatGooseToolCall(name, args))
+		case "toolResponse":
+			id := item.Get("id").Str
+			if id == "" {
+				return true
+			}
+			res := item.Get("toolResult")
+			out := res.Get("value")
+			isErr := res.Get("status").Str == "error"
+			if isErr {
+				out = res.Get("error")
+			}
+			cl := toolResultContentLength(out)
+			truncated, orig := detectTruncation(out.Raw, cl)
+			toolResults = append(toolResults, ParsedToolResult{
+				ToolUseID:      id,
+				ContentLength:  cl,
+				ContentRaw:     out.Raw,
+				IsError:        isErr,
+				Truncated:      truncated,
+				OriginalLength: orig,
+			})
+		}
+		return true
+	})
+	return strings.Join(parts, "\n"), hasThinking,
+		toolCalls, toolResults
+}
+
+// gooseToolCategory categorizes a Goose tool call. The
+// developer extension's text_editor tool views, writes, or
+// edits files depending on its command, so it is split by
+// that; other tools go by name.
+func gooseToolCategory(name string, args gjson.Result) string {
+	if name != "developer__text_editor" {
+		return NormalizeToolCategory(name)
+	}
+	switch args.Get("command").Str {
+	case "view":
+		return "Read"
+	case "write":
+		return "Write"
+	default:
+		return "Edit"
+	}
+}
+
+// formatGooseToolCall renders a tool call as display text in
+// the bracketed style of the other agents.
+func formatGooseToolCall(name string, args gjson.Result) string {
+	switch name {
+	case "developer__shell":
+		return fmt.Sprintf("[Bash]\n$ %s", args.Get("command").Str)
+	case "developer__text_editor":
+		return fmt.Sprintf("[%s: %s]",
+			gooseToolCategory(name, args), args.Get("path").Str)
+	default:
+		return fmt.Sprintf("[Tool: %s]", name)
+	}
+}
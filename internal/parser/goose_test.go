@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gooseSession = `{"working_dir":"/home/user/webapp","description":"Fix the failing build","schedule_id":null,"message_count":4,"input_tokens":900,"output_tokens":80,"accumulated_input_tokens":1500,"accumulated_output_tokens":120}
+{"id":"m1","role":"user","created":1735725600,"content":[{"type":"text","text":"The build fails, please fix it."}]}
+{"id":"m2","role":"assistant","created":1735725610,"content":[{"type":"thinking","thinking":"Check the config first.","signature":"x"},{"type":"text","text":"Let me look."},{"type":"toolRequest","id":"call_1","toolCall":{"status":"success","value":{"name":"developer__text_editor","arguments":{"command":"view","path":"/home/user/webapp/main.go"}}}},{"type":"toolRequest","id":"call_2","toolCall":{"status":"success","value":{"name":"developer__shell","arguments":{"command":"go build ./..."}}}}]}
+{"id":"m3","role":"user","created":1735725620,"content":[{"type":"toolResponse","id":"call_1","toolResult":{"status":"success","value":[{"type":"text","text":"package main"}]}},{"type":"toolResponse","id":"call_2","toolResult":{"status":"error","error":"exit status 1"}}]}
+not json
+{"id":"m4","role":"assistant","created":1735725650,"content":[{"type":"toolRequest","id":"call_3","toolCall":{"status":"success","value":{"name":"developer__text_editor","arguments":{"command":"str_replace","path":"/home/user/webapp/main.go","old_str":"a","new_str":"b"}}}},{"type":"text","text":"Fixed the import."}]}
+`
+
+func TestParseGooseSession(t *testing.T) {
+	path := createTestFile(t, "20250101_100000.jsonl", gooseSession)
+	sess, msgs, err := ParseGooseSession(path, "local")
+	require.NoError(t, err)
+	require.NotNil(t, sess)
+
+	assertSessionMeta(t, sess, "goose:20250101_100000", "webapp", AgentGoose)
+	assert.Equal(t, "Fix the failing build", sess.FirstMessage)
+	assert.False(t, sess.Automated)
+	assertMessageCount(t, sess.MessageCount, 4)
+	assert.Equal(t, 1, sess.UserMessageCount)
+	assertTimestamp(t, sess.StartedAt, time.Unix(1735725600, 0))
+	assertTimestamp(t, sess.EndedAt, time.Unix(1735725650, 0))
+
+	require.Len(t, msgs, 4)
+	assertMessage(t, msgs[0], RoleUser, "The build fails")
+	assertMessage(t, msgs[1], RoleAssistant, "[Read: /home/user/webapp/main.go]")
+	assert.Contains(t, msgs[1].Content, "[Bash]\n$ go build ./...")
+	assert.True(t, msgs[1].HasThinking)
+	assert.True(t, msgs[1].HasToolUse)
+	require.Len(t, msgs[1].ToolCalls, 2)
+	assert.Equal(t, ParsedToolCall{
+		ToolUseID: "call_1",
+		ToolName:  "developer__text_editor",
+		Category:  "Read",
+		InputJSON: `{"command":"view","path":"/home/user/webapp/main.go"}`,
+	}, msgs[1].ToolCalls[0])
+	assert.Equal(t, "Bash", msgs[1].ToolCalls[1].Category)
+
+	assert.Equal(t, RoleUser, msgs[2].Role)
+	assert.Empty(t, msgs[2].Content)
+	require.Len(t, msgs[2].ToolResults, 2)
+	assert.Equal(t, len("package main"), msgs[2].ToolResults[0].ContentLength)
+	assert.False(t, msgs[2].ToolResults[0].IsError)
+	assert.True(t, msgs[2].ToolResults[1].IsError)
+	assert.Equal(t, `"exit status 1"`, msgs[2].ToolResults[1].ContentRaw)
+
+	assert.Equal(t, "Edit", msgs[3].ToolCalls[0].Category)
+	assert.Equal(t, TokenUsage{InputTokens: 1500, OutputTokens: 120}, msgs[3].Usage)
+	assert.Zero(t, msgs[1].Usage)
+}
+
+func TestParseGooseSession_ScheduledAndEmpty(t *testing.T) {
+	path := createTestFile(t, "nightly.jsonl",
+		`{"working_dir":"","description":"","schedule_id":"nightly-report"}
+{"role":"user","created":1735725600,"content":[{"type":"text","text":"Summarize yesterday's commits"}]}
+`)
+	sess, _, err := ParseGooseSession(path, "local")
+	require.NoError(t, err)
+	require.NotNil(t, sess)
+	assert.Equal(t, "goose", sess.Project)
+	assert.Equal(t, "Summarize yesterday's commits", sess.FirstMessage)
+	assert.True(t, sess.Automated)
+
+	path = createTestFile(t, "empty.jsonl", `{"working_dir":"/tmp"}`+"\n")
+	sess, msgs, err := ParseGooseSession(path, "local")
+	require.NoError(t, err)
+	assert.Nil(t, sess)
+	assert.Nil(t, msgs)
+}
+
+func TestDiscoverGooseSessions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"20250101_100000.jsonl", "my-session.jsonl",
+		"notes.txt", "bad name.jsonl",
+	} {
+		require.NoError(t, os.WriteFile(
+			filepath.Join(dir, name), []byte("{}\n"), 0o644,
+		))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub.jsonl"), 0o755))
+
+	files := DiscoverGooseSessions(dir)
+	require.Len(t, files, 2)
+	assert.Equal(t, filepath.Join(dir, "20250101_100000.jsonl"), files[0].Path)
+	assert.Equal(t, AgentGoose, files[1].Agent)
+
+	assert.Equal(t, files[1].Path, FindGooseSourceFile(dir, "my-session"))
+	assert.Empty(t, FindGooseSourceFile(dir, "missing"))
+	assert.Empty(t, FindGooseSourceFile(dir, "../my-session"))
+}
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFileName is the per-directory ignore file honored
+// inside agent roots. Each line is a path.Match glob
+// relative to the directory holding the file; blank lines and
+// lines starting with # are skipped. A pattern without a slash
+// matches a file or directory name at any depth below that
+// directory; a pattern with a slash matches the relative path.
+// Matching a directory ignores everything beneath it.
+const IgnoreFileName = ".agentsviewignore"
+
+// IgnoreMatcher reports whether paths under one agent root are
+// excluded by ignore files in the root or its subdirectories.
+// Ignore files are read lazily and cached, so a matcher should
+// live for one scan; a new one picks up edited files.
+type IgnoreMatcher struct {
+	root  string
+	cache map[string][]string // dir -> patterns, nil if none
+}
+
+// NewIgnoreMatcher returns a matcher for paths under root.
+func NewIgnoreMatcher(root string) *IgnoreMatcher {
+	return &IgnoreMatcher{
+		root:  filepath.Clean(root),
+		cache: map[string][]string{},
+	}
+}
+
+// Ignored reports whether p, a file or directory under the
+// matcher's root, is excluded. Paths outside the root are never
+// ignored.
+func (m *IgnoreMatcher) Ignored(p string) bool {
+	rel, err := filepath.Rel(m.root, filepath.Clean(p))
+	if err != nil || rel == "." || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	// Check the ignore file in the root and in each directory
+	// above p against p relative to that directory.
+	dir := m.root
+	for i := range parts {
+		if matchIgnore(m.patterns(dir), parts[i:]) {
+			return true
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return false
+}
+
+func (m *IgnoreMatcher) patterns(dir string) []string {
+	if p, ok := m.cache[dir]; ok {
+		return p
+	}
+	p := readIgnoreFile(filepath.Join(dir, IgnoreFileName))
+	m.cache[dir] = p
+	return p
+}
+
+func readIgnoreFile(file string) []string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var patterns []string
+	for line := range strings.SplitSeq(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSuffix(line, "/")
+		if line == "" {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// matchIgnore reports whether any pattern matches the relative
+// path parts or one of its ancestors.
+func matchIgnore(patterns, parts []string) bool {
+	for _, pat := range patterns {
+		anchored := strings.Contains(pat, "/")
+		pat = strings.TrimPrefix(pat, "/")
+		for i := range parts {
+			var ok bool
+			if anchored {
+				ok, _ = path.Match(
+					pat, strings.Join(parts[:i+1], "/"),
+				)
+			} else {
+				ok, _ = path.Match(pat, parts[i])
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	root := t.TempDir()
+	setupFileSystem(t, root, map[string]string{
+		IgnoreFileName: "# client work\n-Users-me-client-*\n\n" +
+			"2025/01/\n",
+		filepath.Join("-Users-me-app", IgnoreFileName): "scratch-*.jsonl\n" +
+			"/sub/agent-*.jsonl\n",
+	})
+	m := NewIgnoreMatcher(root)
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"-Users-me-client-acme", true},
+		{"-Users-me-client-acme/s1.jsonl", true},
+		{"-Users-me-app/s1.jsonl", false},
+		{"-Users-me-app/scratch-1.jsonl", true},
+		{"-Users-me-app/deep/scratch-2.jsonl", true},
+		{"-Users-me-app/sub/agent-1.jsonl", true},
+		{"-Users-me-app/other/sub/agent-1.jsonl", false},
+		{"2025/01/05/rollout-x.jsonl", true},
+		{"2025/02/05/rollout-x.jsonl", false},
+		{"-Users-me-other/scratch-1.jsonl", false},
+	}
+	for _, tt := range tests {
+		got := m.Ignored(filepath.Join(root, filepath.FromSlash(tt.rel)))
+		if got != tt.want {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+	if m.Ignored(root) || m.Ignored(filepath.Dir(root)) {
+		t.Error("root and paths outside it must not be ignored")
+	}
+}
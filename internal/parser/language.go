@@ -0,0 +1,154 @@
+package parser
+
+import (
+	"strings"
+	"unicode"
+)
+
+// languageSampleRunes caps how much of a message DetectLanguage
+// reads; a prompt's language shows in its opening lines, and
+// pasted logs can run to megabytes.
+const languageSampleRunes = 2000
+
+// minUnmarkedLatin is how many Latin letters a message without
+// any stopword needs before it is taken as English, so that
+// replies like "y" or "ok" stay undetermined.
+const minUnmarkedLatin = 20
+
+// cjkWeight scales counts of Han, kana, and Hangul characters,
+// each of which carries about as much text as a short Latin
+// word, against Latin letters.
+const cjkWeight = 3
+
+// scriptLanguages maps writing systems used by one language
+// (as far as prompts go) to its ISO 639-1 code.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// latinStopwords tells apart languages written in Latin script
+// by their most frequent short words, checked in order so that
+// English wins ties.
+var latinStopwords = []struct {
+	lang  string
+	words map[string]bool
+}{
+	{"en", wordSet("the and is are to of in it this that with for please can you what how why not")},
+	{"es", wordSet("el la los las que y es por para una con del como pero esto está")},
+	{"fr", wordSet("le la les des est et une pour que dans pas avec du je vous ce cette")},
+	{"de", wordSet("der die das und ist nicht ein eine mit ich zu auf den bitte es wie")},
+	{"pt", wordSet("o os as que e não uma com para do da em você por isso está")},
+	{"it", wordSet("il lo gli che e non una con per del della è questo come ma")},
+}
+
+func wordSet(words string) map[string]bool {
+	m := make(map[string]bool)
+	for w := range strings.FieldsSeq(words) {
+		m[w] = true
+	}
+	return m
+}
+
+// DetectLanguage guesses the language of a typed prompt from
+// the scripts its letters are written in and, for Latin script,
+// from common stopwords. It returns an ISO 639-1 code, or ""
+// when the text is too short or too ambiguous to tell. Code
+// fences are skipped: their contents say nothing about the
+// language the user writes in.
+func DetectLanguage(text string) string {
+	var (
+		kana, han, latin int
+		scripts          = make([]int, len(scriptLanguages))
+		words            []string
+		inFence          bool
+		seen             int
+	)
+	for line := range strings.SplitSeq(text, "\n") {
+		if seen >= languageSampleRunes {
+			break
+		}
+		if rs := []rune(line); len(rs) > languageSampleRunes-seen {
+			line = string(rs[:languageSampleRunes-seen])
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		for _, r := range line {
+			seen++
+			switch {
+			case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+				kana++
+			case unicode.Is(unicode.Han, r):
+				han++
+			case unicode.Is(unicode.Latin, r):
+				latin++
+			case unicode.IsLetter(r):
+				for i, s := range scriptLanguages {
+					if unicode.Is(s.script, r) {
+						scripts[i]++
+						break
+					}
+				}
+			}
+		}
+		words = append(words, strings.FieldsFunc(
+			strings.ToLower(line),
+			func(r rune) bool { return !unicode.Is(unicode.Latin, r) },
+		)...)
+	}
+
+	best, bestScore := "", 0
+	if n := (kana + han) * cjkWeight; n > bestScore {
+		best, bestScore = "ja", n
+		if kana == 0 {
+			best = "zh"
+		}
+	}
+	for i, s := range scriptLanguages {
+		n := scripts[i]
+		if s.script == unicode.Hangul {
+			n *= cjkWeight
+		}
+		if n > bestScore {
+			best, bestScore = s.lang, n
+		}
+	}
+	if latin > bestScore {
+		return latinLanguage(words, latin)
+	}
+	return best
+}
+
+// latinLanguage picks the Latin-script language whose stopwords
+// occur most often in words.
+func latinLanguage(words []string, letters int) string {
+	best, bestHits := "", 0
+	for _, sw := range latinStopwords {
+		hits := 0
+		for _, w := range words {
+			if sw.words[w] {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = sw.lang, hits
+		}
+	}
+	if best == "" && letters >= minUnmarkedLatin {
+		return "en"
+	}
+	return best
+}
@@ -0,0 +1,50 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "Can you fix the failing test in the parser?", "en"},
+		{"english without stopwords", "refactor parser module imports today", "en"},
+		{"japanese", "このテストが失敗する原因を調べてください", "ja"},
+		{"japanese with identifiers", "parseConfigのbugを直してください", "ja"},
+		{"chinese", "请帮我修复这个测试", "zh"},
+		{"korean", "이 테스트를 고쳐 주세요", "ko"},
+		{"russian", "Почини этот тест, пожалуйста", "ru"},
+		{"spanish", "Por favor, arregla la prueba que falla en el parser", "es"},
+		{"german", "Bitte repariere den Test, der nicht funktioniert", "de"},
+		{"french", "Peux-tu corriger le test qui échoue dans le parser", "fr"},
+		{"short reply", "ok", ""},
+		{"no letters", "42 / 7", ""},
+		{
+			"fenced code skipped",
+			"このエラーを見て\n```\nfunc main() { panic(\"the end is not near\") }\n```",
+			"ja",
+		},
+		{
+			"long english paste outweighs prompt",
+			"テストを直して\n" + strings.Repeat("the end ", languageSampleRunes),
+			"en",
+		},
+		{
+			"language past the sample ignored",
+			strings.Repeat("the end ", languageSampleRunes) + "\nテストを直して",
+			"en",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q",
+					tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,10 @@ package parser
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
+	"strings"
 )
 
 // lineReader reads JSONL files line by line, skipping lines that
@@ -14,6 +17,14 @@ type lineReader struct {
 	maxLen int
 	buf    []byte
 	err    error
+
+	// maxString, when positive, keeps oversized JSON lines
+	// instead of skipping them: the rest of the line is
+	// streamed through truncateJSONStrings, which cuts every
+	// string value to maxString bytes. The record's small
+	// fields survive even when one value (a giant tool
+	// output) does not fit in memory.
+	maxString int
 }
 
 func newLineReader(r io.Reader, maxLen int) *lineReader {
@@ -73,6 +84,9 @@ func (lr *lineReader) readLine() (string, error) {
 		lr.buf = append(lr.buf, chunk...)
 
 		if len(lr.buf) > lr.maxLen {
+			if lr.maxString > 0 {
+				return lr.truncateOversized(!isPrefix)
+			}
 			oversized = true
 			lr.buf = lr.buf[:0]
 			if !isPrefix {
@@ -88,3 +102,142 @@ func (lr *lineReader) readLine() (string, error) {
 
 	return string(lr.buf), nil
 }
+
+// truncateOversized streams the oversized line whose head is
+// in lr.buf through truncateJSONStrings. Lines that are still
+// over maxLen after truncation, or are not JSON, are skipped.
+func (lr *lineReader) truncateOversized(complete bool) (string, error) {
+	rest := &lineRemainder{r: lr.r, done: complete}
+	src := bufio.NewReader(io.MultiReader(
+		bytes.NewReader(lr.buf), rest,
+	))
+	line, ok := truncateJSONStrings(src, lr.maxString, lr.maxLen)
+	lr.buf = lr.buf[:0]
+	if rest.err != nil {
+		return "", rest.err
+	}
+	if !ok {
+		return "", nil
+	}
+	return line, nil
+}
+
+// lineRemainder reads the unread rest of the current line from
+// a bufio.Reader, returning io.EOF at the line end.
+type lineRemainder struct {
+	r       *bufio.Reader
+	pending []byte
+	done    bool
+	err     error
+}
+
+func (l *lineRemainder) Read(p []byte) (int, error) {
+	for len(l.pending) == 0 {
+		if l.done {
+			return 0, io.EOF
+		}
+		chunk, isPrefix, err := l.r.ReadLine()
+		if err != nil {
+			l.done = true
+			if err != io.EOF {
+				l.err = err
+			}
+			return 0, io.EOF
+		}
+		// chunk is only valid until the next ReadLine, which
+		// happens after pending has been fully copied out.
+		l.pending = chunk
+		l.done = !isPrefix
+	}
+	n := copy(p, l.pending)
+	l.pending = l.pending[n:]
+	return n, nil
+}
+
+// truncateJSONStrings copies one JSON value from r, cutting
+// every string longer than maxString bytes and noting how much
+// was dropped. Cuts never split an escape sequence or a UTF-8
+// sequence, so the output stays valid JSON. It reads r to EOF
+// and reports false if the input ends inside a string or the
+// output would exceed maxOut bytes.
+func truncateJSONStrings(
+	r io.ByteReader, maxString, maxOut int,
+) (string, bool) {
+	var out strings.Builder
+	var (
+		inString   bool
+		truncating bool
+		escaped    bool
+		hexLeft    int // \uXXXX digits still to come
+		strLen     int
+		dropped    int
+		overflow   bool
+	)
+	emit := func(b ...byte) {
+		if overflow {
+			return
+		}
+		if out.Len()+len(b) > maxOut {
+			overflow = true
+			return
+		}
+		out.Write(b)
+	}
+
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			break
+		}
+		if !inString {
+			if c == '"' {
+				inString = true
+				strLen, dropped = 0, 0
+			}
+			emit(c)
+			continue
+		}
+
+		if !truncating && strLen >= maxString &&
+			!escaped && hexLeft == 0 && c != '"' &&
+			c&0xC0 != 0x80 {
+			truncating = true
+		}
+		if truncating {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				emit(fmt.Appendf(nil,
+					"... [%d bytes truncated]\"", dropped)...)
+				inString, truncating = false, false
+				continue
+			}
+			dropped++
+			continue
+		}
+
+		switch {
+		case escaped:
+			escaped = false
+			if c == 'u' {
+				hexLeft = 4
+			}
+		case hexLeft > 0:
+			hexLeft--
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inString = false
+		}
+		emit(c)
+		strLen++
+	}
+
+	if overflow || inString {
+		return "", false
+	}
+	return out.String(), true
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"slices"
 	"strings"
@@ -117,3 +118,100 @@ func TestLineReaderIOError(t *testing.T) {
 		t.Fatalf("Err() = %v, want %v", lr.Err(), ioErr)
 	}
 }
+
+func TestLineReaderTruncatesOversizedJSON(t *testing.T) {
+	big := strings.Repeat("x", 200)
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			"truncates long string value",
+			`{"type":"response_item","payload":{"output":"` +
+				big + `","call_id":"c1"}}` + "\nafter\n",
+			[]string{
+				`{"type":"response_item","payload":{"output":"` +
+					strings.Repeat("x", 16) +
+					`... [184 bytes truncated]","call_id":"c1"}}`,
+				"after",
+			},
+		},
+		{
+			"does not split escape sequences",
+			`{"o":"` + strings.Repeat("a", 15) + `\u00e9` +
+				big + `"}` + "\n",
+			[]string{
+				`{"o":"` + strings.Repeat("a", 15) + `\u00e9` +
+					`... [200 bytes truncated]"}`,
+			},
+		},
+		{
+			"does not split UTF-8 sequences",
+			`{"o":"` + strings.Repeat("a", 15) + "é" + big +
+				`"}` + "\n",
+			[]string{
+				`{"o":"` + strings.Repeat("a", 15) + "é" +
+					`... [200 bytes truncated]"}`,
+			},
+		},
+		{
+			"skips line still too long",
+			`[` + strings.Repeat(`1,`, 100) + `1]` + "\nafter\n",
+			[]string{"after"},
+		},
+		{
+			"skips unterminated string",
+			`{"o":"` + big,
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lr := newLineReader(strings.NewReader(tt.input), 150)
+			lr.maxString = 16
+			var got []string
+			for {
+				line, ok := lr.next()
+				if !ok {
+					break
+				}
+				got = append(got, line)
+			}
+			if err := lr.Err(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineReaderTruncatesAcrossBufferChunks(t *testing.T) {
+	// Longer than several initialScanBufSize reads.
+	n := 5 * initialScanBufSize
+	input := `{"type":"response_item","payload":{"output":"` +
+		strings.Repeat("x", n) + `"}}` + "\n" + `{"type":"after"}` + "\n"
+
+	lr := newLineReader(strings.NewReader(input), initialScanBufSize)
+	lr.maxString = 16
+	var got []string
+	for {
+		line, ok := lr.next()
+		if !ok {
+			break
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		`{"type":"response_item","payload":{"output":"` +
+			strings.Repeat("x", 16) +
+			fmt.Sprintf(`... [%d bytes truncated]"}}`, n-16),
+		`{"type":"after"}`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
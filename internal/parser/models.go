@@ -0,0 +1,50 @@
+package parser
+
+// Reasons recorded in ParsedMessage.ModelSwitch on the first
+// message produced by a different model than the one before.
+const (
+	// ModelSwitchCommand means the user asked for the change,
+	// e.g. with /model.
+	ModelSwitchCommand = "command"
+	// ModelSwitchFallback means the agent changed models on its
+	// own, e.g. falling back after an overload or usage limit.
+	ModelSwitchFallback = "fallback"
+)
+
+// claudeSyntheticModel is the placeholder model Claude Code
+// records on locally generated assistant messages such as API
+// error notices. It is not a real model and never a switch.
+const claudeSyntheticModel = "<synthetic>"
+
+// modelTracker follows the model across a session's messages
+// and reports where it changes.
+type modelTracker struct {
+	current   string
+	requested bool
+}
+
+// request notes that the user asked to change models, so the
+// next change is attributed to them.
+func (t *modelTracker) request() {
+	t.requested = true
+}
+
+// observe records the model of the next assistant message and
+// returns the switch reason when it differs from the previous
+// model, or "" when it does not.
+func (t *modelTracker) observe(model string) string {
+	if model == "" {
+		return ""
+	}
+	prev, requested := t.current, t.requested
+	t.current = model
+	t.requested = false
+	switch {
+	case prev == "" || prev == model:
+		return ""
+	case requested:
+		return ModelSwitchCommand
+	default:
+		return ModelSwitchFallback
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"sort"
 	"strings"
@@ -107,8 +106,8 @@ func ParseOpenCodeDB(
 			db, s, worktree, dbPath, machine,
 		)
 		if err != nil {
-			log.Printf(
-				"opencode session %s: %v", s.id, err,
+			logger().Warn(
+				"opencode session", "session", s.id, "err", err,
 			)
 			continue
 		}
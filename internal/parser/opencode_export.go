@@ -0,0 +1,168 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// openCodeExport is the JSON document produced by
+// `opencode export` and served by OpenCode share links: the
+// session info plus every message with its parts inlined.
+type openCodeExport struct {
+	Info struct {
+		ID        string `json:"id"`
+		ParentID  string `json:"parentID"`
+		Title     string `json:"title"`
+		Directory string `json:"directory"`
+		Time      struct {
+			Created int64 `json:"created"`
+			Updated int64 `json:"updated"`
+		} `json:"time"`
+	} `json:"info"`
+	Messages []struct {
+		Info struct {
+			ID      string `json:"id"`
+			Role    string `json:"role"`
+			ModelID string `json:"modelID"`
+			Time    struct {
+				Created int64 `json:"created"`
+			} `json:"time"`
+			Tokens struct {
+				Input     int `json:"input"`
+				Output    int `json:"output"`
+				Reasoning int `json:"reasoning"`
+				Cache     struct {
+					Read  int `json:"read"`
+					Write int `json:"write"`
+				} `json:"cache"`
+			} `json:"tokens"`
+		} `json:"info"`
+		Parts []json.RawMessage `json:"parts"`
+	} `json:"messages"`
+}
+
+// ParseOpenCodeExport parses an exported or shared OpenCode
+// thread (JSON) into a session. Sessions use the same
+// "opencode:" ID namespace as the local database, so importing
+// a thread that also exists locally updates one session rather
+// than creating a duplicate. Returns nil if the thread has no
+// user or assistant content.
+func ParseOpenCodeExport(
+	path, machine string,
+) (*ParsedSession, []ParsedMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	var exp openCodeExport
+	if err := json.Unmarshal(data, &exp); err != nil {
+		return nil, nil, fmt.Errorf(
+			"decoding opencode export %s: %w", path, err,
+		)
+	}
+	if exp.Info.ID == "" {
+		return nil, nil, fmt.Errorf(
+			"opencode export %s: missing session id", path,
+		)
+	}
+
+	var (
+		parsed   []ParsedMessage
+		firstMsg string
+		ordinal  int
+	)
+	for _, m := range exp.Messages {
+		role := normalizeOpenCodeRole(m.Info.Role)
+		if role == "" {
+			continue
+		}
+
+		// Reuse the database part decoder; parts in an export
+		// carry the same JSON as the part.data column.
+		parts := make([]openCodePartRow, len(m.Parts))
+		for i, raw := range m.Parts {
+			parts[i] = openCodePartRow{data: string(raw)}
+		}
+		pm := buildOpenCodeMessage(
+			ordinal, role, m.Info.Time.Created, parts,
+		)
+		if strings.TrimSpace(pm.Content) == "" &&
+			!pm.HasToolUse {
+			continue
+		}
+		if role == RoleAssistant {
+			pm.Model = m.Info.ModelID
+			pm.Usage = TokenUsage{
+				InputTokens:         m.Info.Tokens.Input,
+				OutputTokens:        m.Info.Tokens.Output,
+				CacheReadTokens:     m.Info.Tokens.Cache.Read,
+				CacheCreationTokens: m.Info.Tokens.Cache.Write,
+				ReasoningTokens:     m.Info.Tokens.Reasoning,
+			}
+		}
+
+		if role == RoleUser && firstMsg == "" {
+			firstMsg = truncate(
+				strings.ReplaceAll(pm.Content, "\n", " "),
+				300,
+			)
+		}
+		parsed = append(parsed, pm)
+		ordinal++
+	}
+	if len(parsed) == 0 {
+		return nil, nil, nil
+	}
+
+	project := ExtractProjectFromCwd(exp.Info.Directory)
+	if project == "" {
+		project = "unknown"
+	}
+
+	parentID := ""
+	if exp.Info.ParentID != "" {
+		parentID = "opencode:" + exp.Info.ParentID
+	}
+
+	userCount := 0
+	for _, m := range parsed {
+		if m.Role == RoleUser && m.Content != "" {
+			userCount++
+		}
+	}
+
+	startedAt := millisToTime(exp.Info.Time.Created)
+	if startedAt.IsZero() {
+		startedAt = parsed[0].Timestamp
+	}
+	endedAt := millisToTime(exp.Info.Time.Updated)
+	if endedAt.IsZero() {
+		endedAt = parsed[len(parsed)-1].Timestamp
+	}
+
+	sess := &ParsedSession{
+		ID:               "opencode:" + exp.Info.ID,
+		Project:          project,
+		Machine:          machine,
+		Agent:            AgentOpenCode,
+		ParentSessionID:  parentID,
+		FirstMessage:     firstMsg,
+		StartedAt:        startedAt,
+		EndedAt:          endedAt,
+		MessageCount:     len(parsed),
+		UserMessageCount: userCount,
+		File: FileInfo{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: info.ModTime().UnixNano(),
+		},
+	}
+	return sess, parsed, nil
+}
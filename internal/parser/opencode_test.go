@@ -377,3 +377,104 @@ func TestListOpenCodeSessionMeta_NonexistentDB(t *testing.T) {
 	}
 	assertEq(t, "metas len", len(metas), 0)
 }
+
+const openCodeExportJSON = `{
+  "info": {
+    "id": "ses_shared1",
+    "title": "Fix login",
+    "directory": "/home/alice/code/web.app",
+    "time": {"created": 1717232400000, "updated": 1717232460000}
+  },
+  "messages": [
+    {
+      "info": {"id": "msg_1", "role": "user", "time": {"created": 1717232400000}},
+      "parts": [{"type": "text", "text": "Fix the login bug"}]
+    },
+    {
+      "info": {
+        "id": "msg_2", "role": "assistant", "modelID": "claude-sonnet-4",
+        "time": {"created": 1717232430000},
+        "tokens": {"input": 10, "output": 20, "reasoning": 0,
+                   "cache": {"read": 300, "write": 40}}
+      },
+      "parts": [
+        {"type": "step-start"},
+        {"type": "reasoning", "text": "look at auth"},
+        {"type": "text", "text": "Looking now."},
+        {"type": "tool", "tool": "bash", "callID": "c1",
+         "state": {"input": {"command": "go test ./..."}}}
+      ]
+    },
+    {
+      "info": {"id": "msg_3", "role": "system", "time": {"created": 1717232440000}},
+      "parts": [{"type": "text", "text": "ignored"}]
+    }
+  ]
+}`
+
+func TestParseOpenCodeExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "share.json")
+	if err := os.WriteFile(path, []byte(openCodeExportJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sess, msgs, err := ParseOpenCodeExport(path, "laptop")
+	if err != nil {
+		t.Fatalf("ParseOpenCodeExport: %v", err)
+	}
+	if sess == nil {
+		t.Fatal("expected session")
+	}
+
+	assertEq(t, "ID", sess.ID, "opencode:ses_shared1")
+	assertEq(t, "Project", sess.Project, "web.app")
+	assertEq(t, "Machine", sess.Machine, "laptop")
+	assertEq(t, "Agent", sess.Agent, AgentOpenCode)
+	assertEq(t, "FirstMessage", sess.FirstMessage, "Fix the login bug")
+	assertEq(t, "MessageCount", sess.MessageCount, 2)
+	assertEq(t, "UserMessageCount", sess.UserMessageCount, 1)
+	assertEq(t, "File.Path", sess.File.Path, path)
+	assertEq(t, "StartedAt", sess.StartedAt.UnixMilli(), int64(1717232400000))
+
+	if len(msgs) != 2 {
+		t.Fatalf("len(msgs) = %d, want 2", len(msgs))
+	}
+	a := msgs[1]
+	assertEq(t, "Role", a.Role, RoleAssistant)
+	assertEq(t, "HasThinking", a.HasThinking, true)
+	assertEq(t, "HasToolUse", a.HasToolUse, true)
+	assertEq(t, "Model", a.Model, "claude-sonnet-4")
+	assertEq(t, "CacheReadTokens", a.Usage.CacheReadTokens, 300)
+	assertEq(t, "CacheCreationTokens", a.Usage.CacheCreationTokens, 40)
+	if len(a.ToolCalls) != 1 || a.ToolCalls[0].ToolName != "bash" {
+		t.Errorf("ToolCalls = %+v, want one bash call", a.ToolCalls)
+	}
+}
+
+func TestParseOpenCodeExport_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"NotJSON", "not json", true},
+		{"MissingID", `{"info": {}, "messages": []}`, true},
+		{"NoMessages", `{"info": {"id": "ses_x"}, "messages": []}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			sess, _, err := ParseOpenCodeExport(path, "m")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if sess != nil {
+				t.Errorf("sess = %+v, want nil", sess)
+			}
+		})
+	}
+}
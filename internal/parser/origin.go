@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"maps"
+	"strings"
+)
+
+// Origins of user-role messages. Agents record tool output,
+// hook output, and their own notices as user turns; only
+// OriginHuman is something a person typed.
+const (
+	OriginHuman      = "human"
+	OriginToolResult = "tool_result"
+	OriginHook       = "hook"
+	OriginSystem     = "system"
+)
+
+// UserOrigin classifies a user message kept in the transcript:
+// messages carrying only tool results are tool_result, output of
+// a locally run command is system, and the rest are human.
+func UserOrigin(m ParsedMessage) string {
+	switch {
+	case len(m.ToolResults) > 0 && strings.TrimSpace(m.Content) == "":
+		return OriginToolResult
+	case m.Kind == MessageKindCommandOutput:
+		return OriginSystem
+	}
+	return OriginHuman
+}
+
+// CountUserOrigins tallies a session's user messages by origin,
+// including the injected entries the parser skipped.
+func CountUserOrigins(
+	sess ParsedSession, msgs []ParsedMessage,
+) map[string]int {
+	counts := maps.Clone(sess.SkippedUserMessages)
+	if counts == nil {
+		counts = map[string]int{}
+	}
+	for _, m := range msgs {
+		if m.Role == RoleUser {
+			counts[UserOrigin(m)]++
+		}
+	}
+	return counts
+}
@@ -0,0 +1,34 @@
+package parser
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestCountUserOrigins(t *testing.T) {
+	sess := ParsedSession{
+		SkippedUserMessages: map[string]int{OriginHook: 2},
+	}
+	msgs := []ParsedMessage{
+		{Role: RoleUser, Content: "fix the build"},
+		{Role: RoleAssistant, Content: "running tests"},
+		{Role: RoleUser, ToolResults: []ParsedToolResult{{ToolUseID: "t1"}}},
+		{Role: RoleUser, Content: "/cost", Kind: MessageKindCommand},
+		{Role: RoleUser, Content: "Total cost: $0.10", Kind: MessageKindCommandOutput},
+		{Role: RoleUser, Content: "stop, wrong file",
+			ToolResults: []ParsedToolResult{{ToolUseID: "t2"}}},
+	}
+	got := CountUserOrigins(sess, msgs)
+	want := map[string]int{
+		OriginHuman:      3,
+		OriginToolResult: 1,
+		OriginSystem:     1,
+		OriginHook:       2,
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("CountUserOrigins = %v, want %v", got, want)
+	}
+	if sess.SkippedUserMessages[OriginHook] != 2 {
+		t.Error("CountUserOrigins modified the session's counts")
+	}
+}
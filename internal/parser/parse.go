@@ -0,0 +1,109 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+var pkgLogger atomic.Pointer[slog.Logger]
+
+// SetLogger directs the package's warnings, such as unreadable
+// timestamps, to l. A nil l restores slog.Default.
+func SetLogger(l *slog.Logger) {
+	pkgLogger.Store(l)
+}
+
+// logger returns the logger set by SetLogger, or slog.Default.
+func logger() *slog.Logger {
+	if l := pkgLogger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
+
+// Discover returns the session files of a file-based agent
+// under dir, minus those excluded by ignore files in dir.
+// Agents that do not store sessions in files return nil.
+func Discover(agent AgentType, dir string) []DiscoveredFile {
+	def, ok := AgentByType(agent)
+	if !ok || !def.FileBased || def.DiscoverFunc == nil {
+		return nil
+	}
+	found := def.DiscoverFunc(dir)
+	m := NewIgnoreMatcher(dir)
+	kept := found[:0]
+	for _, f := range found {
+		if !m.Ignored(f.Path) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// ParseFile parses a discovered file with its agent's parser.
+// machine labels the sessions. A file can hold several
+// sessions, or none when it is not an interactive session.
+// Unlike a sync, nothing is cached: every call reads the file.
+func ParseFile(
+	ctx context.Context, file DiscoveredFile, machine string,
+) ([]ParseResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var (
+		sess *ParsedSession
+		msgs []ParsedMessage
+		err  error
+	)
+	switch file.Agent {
+	case AgentClaude:
+		cwd, branch := ExtractClaudeProjectHints(file.Path)
+		project := DecodeClaudeProject(
+			file.Path, file.Project, cwd, branch,
+		)
+		results, err := ParseClaudeSession(file.Path, project, machine)
+		if err != nil {
+			return nil, err
+		}
+		InferRelationshipTypes(results)
+		return results, nil
+	case AgentCodex:
+		if IsCodexHistoryPath(file.Path) {
+			return ParseCodexHistory(file.Path, machine)
+		}
+		sess, msgs, err = ParseCodexSession(file.Path, machine, false)
+	case AgentCopilot:
+		sess, msgs, err = ParseCopilotSession(file.Path, machine)
+	case AgentGemini:
+		sess, msgs, err = ParseGeminiSession(
+			file.Path, file.Project, machine,
+		)
+	case AgentCursor:
+		sess, msgs, err = ParseCursorSession(
+			file.Path, file.Project, machine,
+		)
+	case AgentAmp:
+		sess, msgs, err = ParseAmpSession(file.Path, machine)
+	case AgentVSCodeCopilot:
+		sess, msgs, err = ParseVSCodeCopilotSession(
+			file.Path, file.Project, machine,
+		)
+	case AgentOpenClaw:
+		sess, msgs, err = ParseOpenClawSession(
+			file.Path, file.Project, machine,
+		)
+	case AgentAider:
+		return ParseAiderHistory(file.Path, machine)
+	case AgentGoose:
+		sess, msgs, err = ParseGooseSession(file.Path, machine)
+	default:
+		return nil, fmt.Errorf("unknown agent type: %s", file.Agent)
+	}
+	if err != nil || sess == nil {
+		return nil, err
+	}
+	return []ParseResult{{Session: *sess, Messages: msgs}}, nil
+}
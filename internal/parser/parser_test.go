@@ -911,7 +911,7 @@ func TestCodexSessionTimestampSemantics(t *testing.T) {
 	})
 }
 
-func TestParseCodexSessionOversizedLineSkipped(t *testing.T) {
+func TestParseCodexSessionOversizedLineTruncated(t *testing.T) {
 	if testing.Short() {
 		t.Skip("allocates large buffer")
 	}
@@ -941,10 +941,17 @@ func TestParseCodexSessionOversizedLineSkipped(t *testing.T) {
 	if sess == nil {
 		t.Fatal("session is nil")
 	}
-	if len(msgs) != 2 {
-		t.Fatalf("got %d messages, want 2 (oversized skipped)",
+	if len(msgs) != 3 {
+		t.Fatalf("got %d messages, want 3 (oversized kept)",
 			len(msgs))
 	}
+	got := msgs[1].Content
+	if !strings.HasPrefix(got, strings.Repeat("x", maxCodexStringLen)) ||
+		!strings.Contains(got, "bytes truncated]") ||
+		len(got) > maxCodexStringLen+100 {
+		t.Errorf("oversized content not truncated: len %d, tail %q",
+			len(got), got[max(0, len(got)-60):])
+	}
 }
 
 func TestExtractCwdFromSession(t *testing.T) {
@@ -1282,3 +1289,27 @@ func TestParseClaudeToolResults(t *testing.T) {
 			userMsg.ToolResults[0].ContentLength)
 	}
 }
+
+func TestParseClaudeToolResults_IsError(t *testing.T) {
+	lines := []string{
+		`{"type":"assistant","timestamp":"2024-01-01T00:00:00Z","message":{"content":[{"type":"tool_use","id":"toolu_ok","name":"Read","input":{"file_path":"a.go"}},{"type":"tool_use","id":"toolu_bad","name":"Read","input":{"file_path":"b.go"}}]}}`,
+		`{"type":"user","timestamp":"2024-01-01T00:00:01Z","message":{"content":[{"type":"tool_result","tool_use_id":"toolu_ok","content":"package a"},{"type":"tool_result","tool_use_id":"toolu_bad","content":"File does not exist.","is_error":true}]}}`,
+	}
+	content := strings.Join(lines, "\n") + "\n"
+	path := createTestFile(t, "tool-errors.jsonl", content)
+
+	results, err := ParseClaudeSession(path, "test-project", "local")
+	if err != nil {
+		t.Fatalf("ParseClaudeSession: %v", err)
+	}
+	msgs := results[0].Messages
+	if len(msgs) != 2 || len(msgs[1].ToolResults) != 2 {
+		t.Fatalf("messages = %+v, want 2 tool results", msgs)
+	}
+	if msgs[1].ToolResults[0].IsError {
+		t.Error("toolu_ok IsError = true, want false")
+	}
+	if !msgs[1].ToolResults[1].IsError {
+		t.Error("toolu_bad IsError = false, want true")
+	}
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Thresholds for PastedChars. Typed prompts are short and
+// broken into paragraphs; pasted logs, diffs, and files arrive
+// as long unbroken runs of lines or single very long lines.
+const (
+	pasteMinLines     = 8
+	pasteMinLineChars = 400
+)
+
+// PastedChars estimates how many characters of a user message
+// were pasted rather than typed: the bodies of code fences,
+// runs of at least pasteMinLines consecutive non-blank lines,
+// and lines of at least pasteMinLineChars.
+func PastedChars(text string) int {
+	var (
+		total, run, runChars, runLong int
+		inFence                       bool
+	)
+	flush := func() {
+		if run >= pasteMinLines {
+			total += runChars
+		} else {
+			total += runLong
+		}
+		run, runChars, runLong = 0, 0, 0
+	}
+	for line := range strings.SplitSeq(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			flush()
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			total += len(line) + 1
+			continue
+		}
+		if trimmed == "" {
+			flush()
+			continue
+		}
+		run++
+		runChars += len(line) + 1
+		if len(line) >= pasteMinLineChars {
+			runLong += len(line) + 1
+		}
+	}
+	flush()
+	return min(total, len(text))
+}
+
+// attachedTextChars sums the text of document blocks in Claude
+// message content: pasted text the client sent as an
+// attachment rather than inline, which ExtractTextContent does
+// not include in the message body.
+func attachedTextChars(content gjson.Result) int {
+	if !content.IsArray() {
+		return 0
+	}
+	n := 0
+	content.ForEach(func(_, block gjson.Result) bool {
+		if block.Get("type").Str == "document" &&
+			block.Get("source.type").Str == "text" {
+			n += len(block.Get("source.data").Str)
+		}
+		return true
+	})
+	return n
+}
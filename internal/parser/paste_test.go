@@ -0,0 +1,51 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+func TestPastedChars(t *testing.T) {
+	block := strings.Repeat("line of a pasted log\n", 10)
+	long := strings.Repeat("x", pasteMinLineChars)
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"typed", "fix the login bug please", 0},
+		{"short paragraphs", "one\ntwo\n\nthree\nfour", 0},
+		{"fence", "see:\n```go\nfunc f() {}\n```\nthanks", len("func f() {}\n")},
+		{"unclosed fence", "```\nabc\nde", len("abc\nde\n")},
+		{"long run", "why does this fail?\n\n" + block, len(block)},
+		{"long line", "parse this: \n" + long + "\nok", len(long) + 1},
+		{"short run with long line", "a\n" + long, len(long) + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PastedChars(tt.text); got != tt.want {
+				t.Errorf("PastedChars = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseClaudeSession_AttachedText(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		`{"type":"user","timestamp":"2024-01-01T00:00:00Z","message":{"role":"user","content":[{"type":"text","text":"summarize this"},{"type":"document","source":{"type":"text","media_type":"text/plain","data":"0123456789"}}]}}`,
+		testjsonl.ClaudeAssistantJSON("ok", "2024-01-01T00:00:01Z"),
+	)
+	_, msgs := runClaudeParserTest(t, "test.jsonl", content)
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2", len(msgs))
+	}
+	if msgs[0].AttachedChars != 10 {
+		t.Errorf("AttachedChars = %d, want 10", msgs[0].AttachedChars)
+	}
+	if msgs[1].AttachedChars != 0 {
+		t.Errorf("assistant AttachedChars = %d, want 0",
+			msgs[1].AttachedChars)
+	}
+}
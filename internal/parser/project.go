@@ -3,8 +3,10 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 var projectMarkers = []string{
@@ -58,6 +60,135 @@ func GetProjectName(dirName string) string {
 	return NormalizeName(dirName)
 }
 
+// EncodeClaudeProjectPath encodes a path the way Claude Code
+// names its project directories: every character other than an
+// ASCII letter or digit becomes a dash, so /Users/alice/my.app
+// becomes -Users-alice-my-app.
+func EncodeClaudeProjectPath(path string) string {
+	out := make([]byte, 0, len(path))
+	for _, r := range path {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) ||
+			unicode.IsDigit(r)) {
+			out = append(out, byte(r))
+		} else {
+			out = append(out, '-')
+		}
+	}
+	return string(out)
+}
+
+// DecodeClaudeProject derives the project name for a Claude
+// project directory. The directory encoding is lossy (dots,
+// spaces, and non-ASCII characters all become dashes), so the
+// session cwd is preferred when known. Without a cwd, the
+// encoded path is resolved against the filesystem, falling back
+// to GetProjectName when the directory no longer exists.
+// source is the transcript the cwd was read from; see
+// BridgePath.
+func DecodeClaudeProject(
+	source, dirName, cwd, gitBranch string,
+) string {
+	if cwd != "" {
+		// A cwd below the launch directory (after cd) should
+		// still name the launch directory's project.
+		if dir := claudeLaunchDir(dirName, cwd); dir != "" {
+			cwd = dir
+		}
+		if p := ExtractProjectFromCwdWithBranch(
+			BridgePath(source, cwd), gitBranch,
+		); p != "" {
+			return p
+		}
+	}
+	dir := ResolveClaudeProjectDir(dirName)
+	if root := WSLRoot(source); root != "" &&
+		strings.HasPrefix(dirName, "-") {
+		// Directories of a WSL distribution encode its Linux
+		// paths.
+		dir = resolveEncodedPath(root+`\`, dirName[1:])
+	}
+	if dir != "" {
+		if p := ExtractProjectFromCwdWithBranch(
+			dir, gitBranch,
+		); p != "" {
+			return p
+		}
+	}
+	return GetProjectName(dirName)
+}
+
+// claudeLaunchDir returns the ancestor of cwd (or cwd itself)
+// whose encoding is dirName, or "" if none matches.
+func claudeLaunchDir(dirName, cwd string) string {
+	if dirName == "" {
+		return ""
+	}
+	dir := filepath.Clean(cwd)
+	for {
+		if EncodeClaudeProjectPath(dir) == dirName {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ResolveClaudeProjectDir recovers the absolute path encoded in
+// a Claude project directory name by matching each component
+// against directories that exist on disk. Returns "" when the
+// path cannot be resolved.
+func ResolveClaudeProjectDir(dirName string) string {
+	if !strings.HasPrefix(dirName, "-") ||
+		filepath.Separator != '/' {
+		return ""
+	}
+	return resolveEncodedPath("/", dirName[1:])
+}
+
+func resolveEncodedPath(dir, rest string) string {
+	if rest == "" {
+		return dir
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	// Try longer names first so "my-app" wins over "my" when
+	// both exist.
+	type candidate struct{ name, enc string }
+	var cands []candidate
+	for _, e := range entries {
+		enc := EncodeClaudeProjectPath(e.Name())
+		if enc == rest || strings.HasPrefix(rest, enc+"-") {
+			cands = append(cands, candidate{e.Name(), enc})
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		return len(cands[i].enc) > len(cands[j].enc)
+	})
+
+	for _, c := range cands {
+		path := filepath.Join(dir, c.name)
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if c.enc == rest {
+			return path
+		}
+		if got := resolveEncodedPath(
+			path, rest[len(c.enc)+1:],
+		); got != "" {
+			return got
+		}
+	}
+	return ""
+}
+
 // ExtractProjectFromCwd extracts a project name from a working
 // directory path. If cwd is inside a git repository (including
 // linked worktrees), this returns the repository root directory
@@ -96,6 +227,46 @@ func ExtractProjectFromCwdWithBranch(
 	return NormalizeName(name)
 }
 
+// WorktreeName returns the directory name of the linked git
+// worktree containing cwd, or "" when cwd is in a main checkout
+// or outside a repository. When the worktree no longer exists
+// on disk, a directory named after gitBranch (my-app-feature-x)
+// is still recognized as one.
+func WorktreeName(cwd, gitBranch string) string {
+	if cwd == "" {
+		return ""
+	}
+	dir := filepath.Clean(cwd)
+	if _, err := os.Stat(dir); err != nil {
+		name := filepath.Base(dir)
+		if isInvalidPathBase(name) ||
+			trimBranchSuffix(name, gitBranch) == name {
+			return ""
+		}
+		return name
+	}
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			if !info.Mode().IsRegular() {
+				return ""
+			}
+			// Submodules also use a .git file; only linked
+			// worktrees point into <repo>/.git/worktrees/.
+			gitDir := filepath.ToSlash(readGitDirFromFile(gitPath))
+			if strings.Contains(gitDir, "/worktrees/") {
+				return filepath.Base(dir)
+			}
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
 func isInvalidPathBase(name string) bool {
 	if name == "." || name == ".." || name == "/" || name == string(filepath.Separator) {
 		return true
@@ -119,6 +119,126 @@ func TestExtractProjectFromCwdWithBranch(t *testing.T) {
 	}
 }
 
+func TestWorktreeName(t *testing.T) {
+	root := t.TempDir()
+	mainRepo := filepath.Join(root, "app")
+	worktree := filepath.Join(root, "app-login")
+	worktreeGitDir := filepath.Join(mainRepo, ".git", "worktrees", "login")
+	submodule := filepath.Join(mainRepo, "vendor", "lib")
+
+	mustMkdirAll(t, filepath.Join(mainRepo, ".git", "modules", "lib"))
+	mustMkdirAll(t, worktreeGitDir)
+	mustMkdirAll(t, filepath.Join(worktree, "src"))
+	mustMkdirAll(t, submodule)
+	mustWriteFile(t, filepath.Join(worktree, ".git"),
+		"gitdir: "+worktreeGitDir+"\n")
+	mustWriteFile(t, filepath.Join(submodule, ".git"),
+		"gitdir: ../../.git/modules/lib\n")
+
+	tests := []struct {
+		name, cwd, branch, want string
+	}{
+		{"MainCheckout", mainRepo, "main", ""},
+		{"LinkedWorktree", filepath.Join(worktree, "src"), "login", "app-login"},
+		{"Submodule", submodule, "main", ""},
+		{"NotARepo", root, "", ""},
+		{"DeletedWorktreeNamedForBranch",
+			filepath.Join(root, "gone", "app-feature-x"),
+			"feature/x", "app-feature-x"},
+		{"DeletedPlainDir",
+			filepath.Join(root, "gone", "app"), "feature/x", ""},
+		{"Empty", "", "main", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WorktreeName(tt.cwd, tt.branch); got != tt.want {
+				t.Errorf("WorktreeName(%q, %q) = %q, want %q",
+					tt.cwd, tt.branch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeClaudeProjectPath(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/Users/alice/code/my-app", "-Users-alice-code-my-app"},
+		{"/Users/alice/code/my.app", "-Users-alice-code-my-app"},
+		{"/home/bob/My Projects/site", "-home-bob-My-Projects-site"},
+		{"/home/bob/café", "-home-bob-caf-"},
+		{"/home/bob/.config", "-home-bob--config"},
+	}
+	for _, tt := range tests {
+		if got := EncodeClaudeProjectPath(tt.in); got != tt.want {
+			t.Errorf("EncodeClaudeProjectPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeClaudeProject(t *testing.T) {
+	if filepath.Separator != '/' {
+		t.Skip("claude dir resolution is POSIX-only")
+	}
+	root := t.TempDir()
+	dotted := filepath.Join(root, "my.app")
+	spaced := filepath.Join(root, "My Projects", "my site")
+	dashed := filepath.Join(root, "my-app")
+	mustMkdirAll(t, filepath.Join(dotted, "sub"))
+	mustMkdirAll(t, spaced)
+
+	tests := []struct {
+		name string
+		dir  string
+		cwd  string
+		want string
+	}{
+		{
+			name: "ResolvesDotsFromDisk",
+			dir:  EncodeClaudeProjectPath(dotted),
+			want: "my.app",
+		},
+		{
+			name: "ResolvesSpacesFromDisk",
+			dir:  EncodeClaudeProjectPath(spaced),
+			want: "my site",
+		},
+		{
+			name: "CwdBelowLaunchDir",
+			dir:  EncodeClaudeProjectPath(dotted),
+			cwd:  filepath.Join(dotted, "sub"),
+			want: "my.app",
+		},
+		{
+			name: "CwdOutsideLaunchDir",
+			dir:  EncodeClaudeProjectPath(dotted),
+			cwd:  filepath.FromSlash("/Users/alice/other.repo"),
+			want: "other.repo",
+		},
+		{
+			name: "MissingDirFallsBack",
+			dir:  "-Users-alice-code-gone-app",
+			want: "gone_app",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DecodeClaudeProject("", tt.dir, tt.cwd, "")
+			if got != tt.want {
+				t.Fatalf("DecodeClaudeProject(%q, %q) = %q, want %q", tt.dir, tt.cwd, got, tt.want)
+			}
+		})
+	}
+
+	// With both my.app and my-app on disk the encodings collide;
+	// the cwd disambiguates.
+	mustMkdirAll(t, dashed)
+	got := DecodeClaudeProject(
+		"", EncodeClaudeProjectPath(dashed), dotted, "",
+	)
+	if got != "my.app" {
+		t.Errorf("ambiguous dir with cwd = %q, want my.app", got)
+	}
+}
+
 func mustMkdirAll(t *testing.T, path string) {
 	t.Helper()
 	if err := os.MkdirAll(path, 0o755); err != nil {
@@ -0,0 +1,263 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// specStoryDir is the directory SpecStory saves its history in,
+// inside the project it was recording.
+const specStoryDir = ".specstory"
+
+var (
+	// specStoryTitleRe matches the "# Title (time)" heading.
+	specStoryTitleRe = regexp.MustCompile(`^# .*\(([^()]+)\)\s*$`)
+	// specStorySpeakerRe matches a speaker line such as
+	// "_**User**_", "_**Assistant**_", or "_**Agent (detail)**_".
+	specStorySpeakerRe = regexp.MustCompile(
+		`^_\*\*(User|Assistant|Agent)(?: \(([^)]*)\))?\*\*_\s*$`,
+	)
+	specStoryThinkingRe = regexp.MustCompile(
+		`(?s)(?:<think>)?\s*<details>\s*<summary>Thought Process</summary>` +
+			`(.*?)</details>\s*(?:</think>)?`,
+	)
+	specStoryToolRe = regexp.MustCompile(
+		`(?s)<details>\s*<summary>Tool use: \*\*([^*]+)\*\*[^<]*` +
+			`</summary>.*?</details>`,
+	)
+)
+
+// specStoryTimeLayouts are the timestamp forms SpecStory has
+// written in titles and speaker lines. Times without a zone are
+// taken as UTC.
+var specStoryTimeLayouts = []string{
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC3339,
+}
+
+func parseSpecStoryTime(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range specStoryTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// ParseSpecStoryExport parses a SpecStory Markdown transcript
+// (as saved under .specstory/history) into a session. The format
+// records roles reliably but times only sometimes, so timestamps
+// are best effort: a speaker line's time when present, else the
+// title's time for the first message. Thinking and tool-use
+// blocks are kept in the same form other agents' messages use;
+// tool output is dropped. Returns nil if the file has no
+// messages.
+func ParseSpecStoryExport(
+	path, machine string,
+) (*ParsedSession, []ParsedMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("stat %s: %w", path, err)
+	}
+
+	var (
+		parsed  []ParsedMessage
+		started time.Time
+		role    RoleType
+		detail  string
+		body    []string
+		inFence bool
+	)
+	flush := func() {
+		if role != "" {
+			pm := buildSpecStoryMessage(len(parsed), role, detail, body)
+			if pm.Content != "" || pm.HasToolUse {
+				parsed = append(parsed, pm)
+			}
+		}
+		body = nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence {
+			if m := specStorySpeakerRe.FindStringSubmatch(line); m != nil {
+				flush()
+				role = RoleAssistant
+				if m[1] == "User" {
+					role = RoleUser
+				}
+				detail = m[2]
+				continue
+			}
+			if role == "" {
+				if m := specStoryTitleRe.FindStringSubmatch(line); m != nil {
+					started, _ = parseSpecStoryTime(m[1])
+				}
+				continue
+			}
+			if strings.TrimSpace(line) == "---" {
+				// Separates messages, and parts of one agent turn.
+				body = append(body, "")
+				continue
+			}
+		}
+		body = append(body, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	flush()
+	if len(parsed) == 0 {
+		return nil, nil, nil
+	}
+
+	if started.IsZero() {
+		started = parsed[0].Timestamp
+	}
+	if started.IsZero() {
+		started = info.ModTime()
+	}
+	if parsed[0].Timestamp.IsZero() {
+		parsed[0].Timestamp = started
+	}
+	ended := started
+	firstMsg := ""
+	userCount := 0
+	for _, m := range parsed {
+		if m.Timestamp.After(ended) {
+			ended = m.Timestamp
+		}
+		if m.Role == RoleUser {
+			userCount++
+			if firstMsg == "" {
+				firstMsg = truncate(
+					strings.ReplaceAll(m.Content, "\n", " "), 300,
+				)
+			}
+		}
+	}
+
+	stem := strings.TrimSuffix(
+		filepath.Base(path), filepath.Ext(path),
+	)
+	sess := &ParsedSession{
+		ID:               "specstory:" + stem,
+		Project:          specStoryProject(path),
+		Machine:          machine,
+		Agent:            specStoryAgent(data),
+		FirstMessage:     firstMsg,
+		StartedAt:        started,
+		EndedAt:          ended,
+		MessageCount:     len(parsed),
+		UserMessageCount: userCount,
+		File: FileInfo{
+			Path:  path,
+			Size:  info.Size(),
+			Mtime: info.ModTime().UnixNano(),
+		},
+	}
+	return sess, parsed, nil
+}
+
+// buildSpecStoryMessage turns one speaker's lines into a
+// message. detail is the speaker line's parenthetical: a time,
+// a mode ("mode Agent"), or a model name.
+func buildSpecStoryMessage(
+	ordinal int, role RoleType, detail string, body []string,
+) ParsedMessage {
+	pm := ParsedMessage{Ordinal: ordinal, Role: role}
+	if t, ok := parseSpecStoryTime(detail); ok {
+		pm.Timestamp = t
+	} else if role == RoleAssistant && detail != "" &&
+		!strings.HasPrefix(detail, "mode ") &&
+		!strings.Contains(detail, " ") {
+		pm.Model = detail
+	}
+
+	text := strings.Join(body, "\n")
+	text = specStoryThinkingRe.ReplaceAllStringFunc(text, func(m string) string {
+		pm.HasThinking = true
+		inner := specStoryThinkingRe.FindStringSubmatch(m)[1]
+		return "[Thinking]\n" + strings.TrimSpace(inner) + "\n[/Thinking]"
+	})
+	text = specStoryToolRe.ReplaceAllStringFunc(text, func(m string) string {
+		name := specStoryToolRe.FindStringSubmatch(m)[1]
+		pm.HasToolUse = true
+		pm.ToolCalls = append(pm.ToolCalls, ParsedToolCall{
+			ToolName: name,
+			Category: NormalizeToolCategory(name),
+		})
+		return "[Tool: " + name + "]"
+	})
+	pm.Content = collapseBlankLines(strings.TrimSpace(text))
+	pm.ContentLength = len(pm.Content)
+	return pm
+}
+
+// collapseBlankLines squeezes runs of blank lines, left behind
+// by separators and removed blocks, to one.
+func collapseBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	out := lines[:0]
+	blank := false
+	for _, l := range lines {
+		isBlank := strings.TrimSpace(l) == ""
+		if isBlank && blank {
+			continue
+		}
+		blank = isBlank
+		out = append(out, l)
+	}
+	return strings.Join(out, "\n")
+}
+
+// specStoryProject is the project holding the .specstory
+// directory the file was saved in, or "unknown" for a file
+// copied elsewhere.
+func specStoryProject(path string) string {
+	dir := filepath.Dir(path)
+	for d := dir; ; d = filepath.Dir(d) {
+		if filepath.Base(d) == specStoryDir {
+			if p := ExtractProjectFromCwd(filepath.Dir(d)); p != "" {
+				return p
+			}
+			break
+		}
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+	return "unknown"
+}
+
+// specStoryAgent reads the agent from the session comment newer
+// SpecStory versions write ("<!-- Claude Code Session ... -->").
+// SpecStory began as a Cursor extension, so files without one
+// are Cursor chats.
+func specStoryAgent(data []byte) AgentType {
+	head := data[:min(len(data), 512)]
+	if bytes.Contains(head, []byte("<!-- Claude Code Session")) {
+		return AgentClaude
+	}
+	return AgentCursor
+}
@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const specStoryLegacy = `<!-- Generated by SpecStory -->
+
+# Fix login bug (2024-12-01 10:00:00)
+
+_**User**_
+
+the login form rejects valid passwords
+
+---
+
+_**Assistant**_
+
+Let me check the handler.
+
+` + "```" + `go
+_**User**_ is not a speaker inside code
+` + "```" + `
+
+---
+
+_**User**_
+
+thanks
+`
+
+const specStoryAgentFormat = `<!-- Generated by SpecStory -->
+
+<!-- Claude Code Session 9f1c (2025-06-10 10:00Z) -->
+
+# Refactor the parser (2025-06-10 10:00Z)
+
+_**User (2025-06-10 10:00Z)**_
+
+split the parser into files
+
+---
+
+_**Agent (claude-sonnet-4)**_
+
+<think><details><summary>Thought Process</summary>
+Start with the lexer.
+</details></think>
+
+---
+
+<details><summary>Tool use: **read_file**</summary>
+
+Read file: parser.go
+
+</details>
+
+---
+
+Done.
+
+---
+`
+
+func writeSpecStory(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestParseSpecStoryExport_Legacy(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "webapp", ".specstory", "history")
+	path := writeSpecStory(t, dir,
+		"2024-12-01_10-00-00Z-fix-login-bug.md", specStoryLegacy)
+
+	sess, msgs, err := ParseSpecStoryExport(path, "laptop")
+	require.NoError(t, err)
+	assertSessionMeta(t, sess,
+		"specstory:2024-12-01_10-00-00Z-fix-login-bug",
+		"webapp", AgentCursor,
+	)
+	assert.Equal(t, "laptop", sess.Machine)
+	assert.Equal(t, "the login form rejects valid passwords",
+		sess.FirstMessage)
+	assert.Equal(t, 2, sess.UserMessageCount)
+	assert.True(t, sess.StartedAt.Equal(
+		time.Date(2024, 12, 1, 10, 0, 0, 0, time.UTC),
+	))
+
+	assertMessageCount(t, len(msgs), 3)
+	assertMessage(t, msgs[1], RoleAssistant, "is not a speaker")
+	assert.True(t, msgs[0].Timestamp.Equal(sess.StartedAt))
+	assertMessage(t, msgs[2], RoleUser, "thanks")
+}
+
+func TestParseSpecStoryExport_AgentFormat(t *testing.T) {
+	path := writeSpecStory(t, t.TempDir(), "chat.md", specStoryAgentFormat)
+
+	sess, msgs, err := ParseSpecStoryExport(path, "laptop")
+	require.NoError(t, err)
+	assertSessionMeta(t, sess, "specstory:chat", "unknown", AgentClaude)
+
+	assertMessageCount(t, len(msgs), 2)
+	asst := msgs[1]
+	assert.Equal(t, "claude-sonnet-4", asst.Model)
+	assert.True(t, asst.HasThinking)
+	assert.True(t, asst.HasToolUse)
+	assert.Equal(t, "[Thinking]\nStart with the lexer.\n[/Thinking]\n\n"+
+		"[Tool: read_file]\n\nDone.", asst.Content)
+	require.Len(t, asst.ToolCalls, 1)
+	assert.Equal(t, "Read", asst.ToolCalls[0].Category)
+	assert.True(t, msgs[0].Timestamp.Equal(
+		time.Date(2025, 6, 10, 10, 0, 0, 0, time.UTC),
+	))
+}
+
+func TestParseSpecStoryExport_Empty(t *testing.T) {
+	path := writeSpecStory(t, t.TempDir(), "empty.md",
+		"<!-- Generated by SpecStory -->\n\n# Nothing (2024-12-01 10:00)\n")
+	sess, msgs, err := ParseSpecStoryExport(path, "laptop")
+	require.NoError(t, err)
+	assert.Nil(t, sess)
+	assert.Nil(t, msgs)
+}
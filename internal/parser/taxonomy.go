@@ -102,6 +102,24 @@ func NormalizeToolCategory(rawName string) string {
 	case "subagents", "agents_list", "session_status":
 		return "Task"
 
+	// Goose tools, named <extension>__<tool>. The parser
+	// splits developer__text_editor by its command.
+	case "developer__shell", "computercontroller__automation_script":
+		return "Bash"
+	case "developer__text_editor":
+		return "Edit"
+	case "developer__list_windows", "developer__screen_capture",
+		"developer__image_processor":
+		return "Tool"
+	case "computercontroller__web_search",
+		"computercontroller__web_scrape":
+		return "Tool"
+	case "platform__read_resource", "platform__list_resources":
+		return "Read"
+	case "platform__search_available_extensions",
+		"platform__manage_extensions":
+		return "Tool"
+
 	default:
 		return "Other"
 	}
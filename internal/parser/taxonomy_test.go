@@ -56,6 +56,14 @@ func TestNormalizeToolCategory(t *testing.T) {
 		{"view", "Read"},
 		{"report_intent", "Tool"},
 
+		// Goose tools
+		{"developer__shell", "Bash"},
+		{"developer__text_editor", "Edit"},
+		{"developer__screen_capture", "Tool"},
+		{"computercontroller__automation_script", "Bash"},
+		{"computercontroller__web_search", "Tool"},
+		{"platform__read_resource", "Read"},
+
 		// Unknown
 		{"view_image", "Other"},
 		{"update_plan", "Other"},
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"log"
 	"time"
 )
 
@@ -36,7 +35,7 @@ func logParseError(ts string) {
 	if len(ts) > maxLen {
 		ts = ts[:maxLen] + "..."
 	}
-	log.Printf(
-		"unparseable timestamp %q: no matching layout", ts,
+	logger().Warn(
+		"unparseable timestamp: no matching layout", "ts", ts,
 	)
 }
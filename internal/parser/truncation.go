@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Markers agents leave in a tool result when they cut it to
+// fit their size limits. They are matched against the raw JSON
+// of the result, so "…" may appear escaped as ….
+var (
+	// Claude Code, when it saves a large output to a file and
+	// keeps a preview: "Output too large (45.2KB)".
+	truncTooLarge = regexp.MustCompile(
+		`Output too large \((\d+(?:\.\d+)?)\s*(B|KB|MB)\)`)
+	// Codex: "…1234 chars truncated…".
+	truncChars = regexp.MustCompile(
+		`(?:…|\\u2026)(\d+) chars truncated(?:…|\\u2026)`)
+	// Markers that give no size in bytes: Claude Code's
+	// "... [12 lines truncated] ..." and "<response clipped>",
+	// Codex's "[... omitted 12 of 340 lines ...]" and
+	// "…1234 tokens truncated…".
+	truncOther = regexp.MustCompile(
+		`\.\.\. \[\d+ lines truncated\] \.\.\.` +
+			`|<response clipped>` +
+			`|\[\.\.\. omitted \d+ of \d+ lines \.\.\.\]` +
+			`|(?:…|\\u2026)\d+ tokens truncated(?:…|\\u2026)`)
+)
+
+var sizeUnits = map[string]float64{"B": 1, "KB": 1 << 10, "MB": 1 << 20}
+
+// detectTruncation reports whether a tool result's raw content
+// carries an agent's truncation marker and, when the marker
+// says, the result's size in bytes before truncation. length
+// is the size of the content as kept.
+func detectTruncation(raw string, length int) (bool, int) {
+	if !strings.Contains(raw, "trunc") &&
+		!strings.Contains(raw, "omitted") &&
+		!strings.Contains(raw, "clipped") &&
+		!strings.Contains(raw, "too large") {
+		return false, 0
+	}
+	if m := truncTooLarge.FindStringSubmatch(raw); m != nil {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		return true, int(n * sizeUnits[m[2]])
+	}
+	if m := truncChars.FindStringSubmatch(raw); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return true, length + n
+	}
+	return truncOther.MatchString(raw), 0
+}
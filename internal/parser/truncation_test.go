@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/wesm/agentsview/internal/testjsonl"
+)
+
+func TestDetectTruncation(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       string
+		length    int
+		truncated bool
+		orig      int
+	}{
+		{"plain", `"ok\nall tests passed"`, 20, false, 0},
+		{"mentions truncate", `"func truncate(s string) string"`, 30, false, 0},
+		{"too large", `"Output too large (45.5KB). Full output saved to: /tmp/o.txt"`, 60, true, 46592},
+		{"too large bytes", `"Output too large (900 B)"`, 24, true, 900},
+		{"lines truncated", `"head\n\n... [120 lines truncated] ...\n\ntail"`, 40, true, 0},
+		{"clipped", `"abc<response clipped><NOTE>use grep</NOTE>"`, 40, true, 0},
+		{"codex omitted", `"Total output lines: 400\n\nhead\n[... omitted 300 of 400 lines ...]\ntail"`, 60, true, 0},
+		{"codex chars", `"head…5000 chars truncated…tail"`, 30, true, 5030},
+		{"codex chars escaped", `"head\u20265000 chars truncated\u2026tail"`, 30, true, 5030},
+		{"codex tokens", `"head…800 tokens truncated…tail"`, 30, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			truncated, orig := detectTruncation(tt.raw, tt.length)
+			assert.Equal(t, tt.truncated, truncated)
+			assert.Equal(t, tt.orig, orig)
+		})
+	}
+}
+
+func TestParseClaudeSession_TruncatedToolResult(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		testjsonl.ClaudeUserJSON("run the tests", "2024-01-01T00:00:00Z"),
+		`{"type":"assistant","timestamp":"2024-01-01T00:00:01Z","message":{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"go test ./..."}}]}}`,
+		`{"type":"user","timestamp":"2024-01-01T00:00:02Z","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok a\n... [812 lines truncated] ...\nok z"}]}}`,
+	)
+	_, msgs := runClaudeParserTest(t, "test.jsonl", content)
+	var results []ParsedToolResult
+	for _, m := range msgs {
+		results = append(results, m.ToolResults...)
+	}
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Truncated)
+	assert.Zero(t, results[0].OriginalLength)
+}
+
+func TestParseCodexSession_FunctionCallOutput(t *testing.T) {
+	content := testjsonl.JoinJSONL(
+		testjsonl.CodexSessionMetaJSON("fc-out", "/tmp", "user", tsEarly),
+		testjsonl.CodexMsgJSON("user", "list files", tsEarlyS1),
+		testjsonl.CodexFunctionCallArgsJSON("exec_command", map[string]any{
+			"cmd": "ls -R",
+		}, tsEarlyS5),
+		`{"type":"response_item","timestamp":"`+tsEarlyS5+`","payload":{"type":"function_call_output","call_id":"call_test","output":"a\n…2000 chars truncated…\nz"}}`,
+	)
+	_, msgs := runCodexParserTest(t, "test.jsonl", content, false)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "call_test", msgs[1].ToolCalls[0].ToolUseID)
+	require.Len(t, msgs[1].ToolResults, 1)
+	tr := msgs[1].ToolResults[0]
+	assert.Equal(t, "call_test", tr.ToolUseID)
+	assert.True(t, tr.Truncated)
+	assert.Equal(t, tr.ContentLength+2000, tr.OriginalLength)
+}
@@ -18,6 +18,8 @@ const (
 	AgentAmp           AgentType = "amp"
 	AgentVSCodeCopilot AgentType = "vscode-copilot"
 	AgentOpenClaw      AgentType = "openclaw"
+	AgentAider         AgentType = "aider"
+	AgentGoose         AgentType = "goose"
 )
 
 // AgentDef describes a supported coding agent's filesystem
@@ -61,7 +63,7 @@ var Registry = []AgentDef{
 		DisplayName:    "Codex",
 		EnvVar:         "CODEX_SESSIONS_DIR",
 		ConfigKey:      "codex_sessions_dirs",
-		DefaultDirs:    []string{".codex/sessions"},
+		DefaultDirs:    []string{".codex/sessions", ".codex/archived_sessions"},
 		IDPrefix:       "codex:",
 		FileBased:      true,
 		DiscoverFunc:   DiscoverCodexSessions,
@@ -104,6 +106,7 @@ var Registry = []AgentDef{
 		Type:           AgentCursor,
 		DisplayName:    "Cursor",
 		EnvVar:         "CURSOR_PROJECTS_DIR",
+		ConfigKey:      "cursor_dirs",
 		DefaultDirs:    []string{".cursor/projects"},
 		IDPrefix:       "cursor:",
 		FileBased:      true,
@@ -114,6 +117,7 @@ var Registry = []AgentDef{
 		Type:           AgentAmp,
 		DisplayName:    "Amp",
 		EnvVar:         "AMP_DIR",
+		ConfigKey:      "amp_dirs",
 		DefaultDirs:    []string{".local/share/amp/threads"},
 		IDPrefix:       "amp:",
 		FileBased:      true,
@@ -149,16 +153,40 @@ var Registry = []AgentDef{
 		FindSourceFunc: FindVSCodeCopilotSourceFile,
 	},
 	{
-		Type:        AgentOpenClaw,
-		DisplayName: "OpenClaw",
-		EnvVar:      "OPENCLAW_DIR",
-		ConfigKey:   "openclaw_dirs",
-		DefaultDirs: []string{".openclaw/agents"},
-		IDPrefix:    "openclaw:",
-		FileBased:   true,
+		Type:           AgentOpenClaw,
+		DisplayName:    "OpenClaw",
+		EnvVar:         "OPENCLAW_DIR",
+		ConfigKey:      "openclaw_dirs",
+		DefaultDirs:    []string{".openclaw/agents"},
+		IDPrefix:       "openclaw:",
+		FileBased:      true,
 		DiscoverFunc:   DiscoverOpenClawSessions,
 		FindSourceFunc: FindOpenClawSourceFile,
 	},
+	{
+		// Aider writes its chat log into each project it runs
+		// in, so its dirs are project roots to search and there
+		// is no default.
+		Type:           AgentAider,
+		DisplayName:    "Aider",
+		EnvVar:         "AIDER_DIR",
+		ConfigKey:      "aider_dirs",
+		IDPrefix:       "aider:",
+		FileBased:      true,
+		DiscoverFunc:   DiscoverAiderSessions,
+		FindSourceFunc: FindAiderSourceFile,
+	},
+	{
+		Type:           AgentGoose,
+		DisplayName:    "Goose",
+		EnvVar:         "GOOSE_DIR",
+		ConfigKey:      "goose_dirs",
+		DefaultDirs:    []string{".local/share/goose/sessions"},
+		IDPrefix:       "goose:",
+		FileBased:      true,
+		DiscoverFunc:   DiscoverGooseSessions,
+		FindSourceFunc: FindGooseSourceFile,
+	},
 }
 
 // AgentByType returns the AgentDef for the given type.
@@ -210,6 +238,17 @@ const (
 	RoleAssistant RoleType = "assistant"
 )
 
+// Kinds recorded in ParsedMessage.Kind for messages that are
+// not plain prompts or replies.
+const (
+	// MessageKindCommand is a slash command the user ran,
+	// with Content holding the command and its arguments.
+	MessageKindCommand = "command"
+	// MessageKindCommandOutput is the output of a command the
+	// agent ran locally, such as /cost, rather than a prompt.
+	MessageKindCommandOutput = "command_output"
+)
+
 // FileInfo holds file system metadata for a session source file.
 type FileInfo struct {
 	Path  string
@@ -231,7 +270,57 @@ type ParsedSession struct {
 	EndedAt          time.Time
 	MessageCount     int
 	UserMessageCount int
-	File             FileInfo
+	// InterruptCount is the number of turns the user
+	// interrupted (Claude Code only).
+	InterruptCount int
+	File           FileInfo
+
+	// Automated is set when the source records a
+	// non-interactive origin, such as a Codex exec run or a
+	// headless Claude Code / Agent SDK invocation. It is
+	// stored as the session's headless flag.
+	Automated bool
+
+	// GitBranch is the branch checked out when the session
+	// started, and Worktree the linked worktree directory it
+	// ran in, if any. Project stays the canonical repository.
+	GitBranch string
+	Worktree  string
+
+	// Client is the raw client identifier the agent recorded
+	// (Claude Code entrypoint, Codex originator), and
+	// EntryPoint its normalized category, e.g. "terminal" or
+	// "vscode". See ClassifyEntryPoint.
+	Client     string
+	EntryPoint string
+
+	// Environment is an allowlisted snapshot of the agent's
+	// settings, keyed by the Env* constants. Nil when the
+	// transcript records none.
+	Environment map[string]string
+
+	// HookEvents are hook outcomes recorded in the session
+	// (Claude Code only).
+	HookEvents []ParsedHookEvent
+
+	// Commands are slash command invocations recorded in the
+	// session (Claude Code only).
+	Commands []ParsedCommand
+
+	// Todos is the session's latest TodoWrite list, written at
+	// TodosAt (Claude Code only). Nil when the session never
+	// wrote one; empty when the list was cleared.
+	Todos   []ParsedTodo
+	TodosAt time.Time
+
+	// UnknownRecords counts top-level record types the parser
+	// did not recognize (Claude Code and Codex only).
+	UnknownRecords []UnknownRecord
+
+	// SkippedUserMessages counts user entries left out of the
+	// transcript because the agent or a hook injected them,
+	// keyed by Origin* (Claude Code and Codex only).
+	SkippedUserMessages map[string]int
 }
 
 // ParsedToolCall holds a single tool invocation extracted from
@@ -251,6 +340,32 @@ type ParsedToolResult struct {
 	ToolUseID     string
 	ContentLength int
 	ContentRaw    string // raw JSON of the content field; decode with DecodeContent
+	IsError       bool   // the tool reported a failure (Claude Code only)
+	// Truncated is set when the agent cut the result to fit its
+	// size limits; OriginalLength is the size in bytes before
+	// the cut when the agent reported it, else 0.
+	Truncated      bool
+	OriginalLength int
+}
+
+// TokenUsage holds token counts reported by the agent for a
+// single message. InputTokens excludes cache reads, matching
+// the Anthropic API convention. Zero values mean the agent did
+// not report usage.
+type TokenUsage struct {
+	InputTokens         int
+	OutputTokens        int
+	CacheReadTokens     int
+	CacheCreationTokens int
+	ReasoningTokens     int
+}
+
+func (u *TokenUsage) add(o TokenUsage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheReadTokens += o.CacheReadTokens
+	u.CacheCreationTokens += o.CacheCreationTokens
+	u.ReasoningTokens += o.ReasoningTokens
 }
 
 // ParsedMessage holds a single extracted message.
@@ -262,6 +377,12 @@ type ParsedMessage struct {
 	HasThinking   bool
 	HasToolUse    bool
 	ContentLength int
+	Model         string // model that produced the message, if known
+	ModelSwitch   string // ModelSwitch* reason if Model changed here
+	Kind          string // MessageKind*, empty for ordinary messages
+	SourceUUID    string // id of the source record, if the agent writes one
+	AttachedChars int    // pasted text attached outside Content
+	Usage         TokenUsage
 	ToolCalls     []ParsedToolCall
 	ToolResults   []ParsedToolResult
 }
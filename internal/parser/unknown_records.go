@@ -0,0 +1,100 @@
+package parser
+
+import "unicode/utf8"
+
+// maxUnknownSampleLen caps the sample line kept for an
+// unrecognized record type, in bytes.
+const maxUnknownSampleLen = 2000
+
+// UnknownRecord counts transcript records whose top-level type
+// the parser does not handle. Sample is the first such line,
+// truncated, so a new upstream record kind can be inspected
+// without digging through session files.
+type UnknownRecord struct {
+	Type   string
+	Count  int
+	Sample string
+}
+
+// claudeKnownTypes are the top-level record types Claude Code
+// writes that the parser either consumes or deliberately skips.
+var claudeKnownTypes = map[string]bool{
+	"user":                  true,
+	"assistant":             true,
+	"system":                true,
+	"summary":               true,
+	"result":                true,
+	"attachment":            true,
+	"progress":              true,
+	"queue-operation":       true,
+	"file-history-snapshot": true,
+	"custom-title":          true,
+}
+
+// codexKnownTypes are the top-level record types Codex writes
+// that the parser either consumes or deliberately skips.
+var codexKnownTypes = map[string]bool{
+	codexTypeSessionMeta:  true,
+	codexTypeResponseItem: true,
+	codexTypeTurnContext:  true,
+	codexTypeEventMsg:     true,
+	"compacted":           true,
+}
+
+// unknownRecords collects unrecognized record types in the
+// order they were first seen.
+type unknownRecords struct {
+	records []UnknownRecord
+	index   map[string]int
+}
+
+// observe counts line under typ. Lines without a type are not
+// records of an unknown kind and are ignored.
+func (u *unknownRecords) observe(typ, line string) {
+	if typ == "" {
+		return
+	}
+	if i, ok := u.index[typ]; ok {
+		u.records[i].Count++
+		return
+	}
+	if u.index == nil {
+		u.index = map[string]int{}
+	}
+	u.index[typ] = len(u.records)
+	u.records = append(u.records, UnknownRecord{
+		Type:   typ,
+		Count:  1,
+		Sample: truncateSample(line),
+	})
+}
+
+// truncateSample shortens line to maxUnknownSampleLen bytes
+// without splitting a UTF-8 sequence.
+func truncateSample(line string) string {
+	if len(line) <= maxUnknownSampleLen {
+		return line
+	}
+	cut := maxUnknownSampleLen
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut]
+}
+
+// mergeUnknownRecords sums per-type counts across parts,
+// keeping the first sample seen for each type.
+func mergeUnknownRecords(parts ...[]UnknownRecord) []UnknownRecord {
+	var u unknownRecords
+	for _, recs := range parts {
+		for _, r := range recs {
+			if i, ok := u.index[r.Type]; ok {
+				u.records[i].Count += r.Count
+				continue
+			}
+			u.observe(r.Type, r.Sample)
+			u.records[len(u.records)-1].Count = r.Count
+		}
+	}
+	return u.records
+}
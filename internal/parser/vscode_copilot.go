@@ -240,6 +240,7 @@ func ParseVSCodeCopilotSession(
 		EndedAt:          endedAt,
 		MessageCount:     len(messages),
 		UserMessageCount: userCount,
+		EntryPoint:       EntryVSCode,
 		File: FileInfo{
 			Path:  path,
 			Size:  info.Size(),
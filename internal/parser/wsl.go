@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"runtime"
+	"strings"
+)
+
+// Windows and WSL can read each other's agent directories: a
+// Windows process through \\wsl$\<distro> (or
+// \\wsl.localhost\<distro>), and a WSL process through
+// /mnt/<drive>. Transcripts record paths as the agent saw them,
+// so paths read from a transcript are bridged before they are
+// looked up on disk.
+
+// wslSharePrefixes are the UNC prefixes of WSL distribution
+// shares, lowercased with backslash separators.
+var wslSharePrefixes = []string{`\\wsl$\`, `\\wsl.localhost\`}
+
+// WSLRoot returns the distribution share containing path, such
+// as \\wsl$\Ubuntu, or "" when path is not inside one. Either
+// slash direction is accepted.
+func WSLRoot(path string) string {
+	norm := strings.ToLower(strings.ReplaceAll(path, "/", `\`))
+	for _, prefix := range wslSharePrefixes {
+		rest, ok := strings.CutPrefix(norm, prefix)
+		if !ok {
+			continue
+		}
+		distro, _, _ := strings.Cut(rest, `\`)
+		if distro == "" {
+			return ""
+		}
+		return path[:len(prefix)+len(distro)]
+	}
+	return ""
+}
+
+// BridgePath returns p, a path recorded in the transcript at
+// source, as this process can open it. Paths that need no
+// translation are returned unchanged.
+func BridgePath(source, p string) string {
+	return bridgePath(source, p, runtime.GOOS == "windows")
+}
+
+func bridgePath(source, p string, windowsHost bool) string {
+	if !windowsHost {
+		// A Windows path seen from WSL: C:\x → /mnt/c/x.
+		if drive, rest, ok := cutWindowsDrive(p); ok {
+			rest = strings.ReplaceAll(rest, `\`, "/")
+			return "/mnt/" + strings.ToLower(drive) +
+				"/" + strings.TrimLeft(rest, "/")
+		}
+		return p
+	}
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") {
+		return p
+	}
+	// A WSL path seen from Windows: /mnt/c/x → C:\x, and
+	// anything else lives in the source's distribution.
+	if drive, rest, ok := cutWSLMount(p); ok {
+		return strings.ToUpper(drive) + `:\` +
+			strings.ReplaceAll(rest, "/", `\`)
+	}
+	root := WSLRoot(source)
+	if root == "" {
+		return p
+	}
+	return root + strings.ReplaceAll(p, "/", `\`)
+}
+
+// cutWindowsDrive splits an absolute Windows path like C:\x
+// into its drive letter and the rest.
+func cutWindowsDrive(p string) (drive, rest string, ok bool) {
+	if len(p) < 3 || p[1] != ':' || (p[2] != '\\' && p[2] != '/') {
+		return "", "", false
+	}
+	c := p[0] | 0x20
+	if c < 'a' || c > 'z' {
+		return "", "", false
+	}
+	return p[:1], p[3:], true
+}
+
+// cutWSLMount splits a WSL drive mount path like /mnt/c/x into
+// its drive letter and the rest.
+func cutWSLMount(p string) (drive, rest string, ok bool) {
+	after, found := strings.CutPrefix(p, "/mnt/")
+	if !found || len(after) == 0 {
+		return "", "", false
+	}
+	c := after[0] | 0x20
+	if c < 'a' || c > 'z' || (len(after) > 1 && after[1] != '/') {
+		return "", "", false
+	}
+	return after[:1], strings.TrimPrefix(after[1:], "/"), true
+}
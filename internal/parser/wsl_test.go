@@ -0,0 +1,96 @@
+package parser
+
+import "testing"
+
+func TestWSLRoot(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{`\\wsl$\Ubuntu\home\me\.claude\projects`, `\\wsl$\Ubuntu`},
+		{`\\wsl.localhost\Debian\home\me`, `\\wsl.localhost\Debian`},
+		{`//wsl$/Ubuntu/home/me`, `//wsl$/Ubuntu`},
+		{`\\WSL$\Ubuntu`, `\\WSL$\Ubuntu`},
+		{`\\wsl$\`, ""},
+		{`\\server\share\x`, ""},
+		{`C:\Users\me\.claude`, ""},
+		{"/home/me/.claude", ""},
+	}
+	for _, tt := range tests {
+		if got := WSLRoot(tt.path); got != tt.want {
+			t.Errorf("WSLRoot(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestBridgePath(t *testing.T) {
+	const src = `\\wsl$\Ubuntu\home\me\.claude\projects\-home-me-app\s.jsonl`
+	tests := []struct {
+		name        string
+		source, p   string
+		windowsHost bool
+		want        string
+	}{
+		{
+			"wsl path on windows", src, "/home/me/code/app", true,
+			`\\wsl$\Ubuntu\home\me\code\app`,
+		},
+		{
+			"drive mount on windows", src, "/mnt/c/Users/me/app", true,
+			`C:\Users\me\app`,
+		},
+		{
+			"drive root on windows", src, "/mnt/d", true, `D:\`,
+		},
+		{
+			"mnt dir that is not a drive", src, "/mnt/data/app", true,
+			`\\wsl$\Ubuntu\mnt\data\app`,
+		},
+		{
+			"windows source keeps linux path",
+			`C:\Users\me\.codex\s.jsonl`, "/home/me/app", true,
+			"/home/me/app",
+		},
+		{
+			"windows path on windows", src, `C:\Users\me\app`, true,
+			`C:\Users\me\app`,
+		},
+		{
+			"windows path on wsl", "/mnt/c/Users/me/.copilot/e.jsonl",
+			`C:\Users\me\code\app`, false, "/mnt/c/Users/me/code/app",
+		},
+		{
+			"forward slashes on wsl", "", "D:/work/app", false,
+			"/mnt/d/work/app",
+		},
+		{
+			"linux path on linux", "", "/home/me/app", false,
+			"/home/me/app",
+		},
+		{"relative", src, "app", true, "app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bridgePath(tt.source, tt.p, tt.windowsHost)
+			if got != tt.want {
+				t.Errorf("bridgePath(%q) = %q, want %q", tt.p, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCopilotSession_WindowsCwd(t *testing.T) {
+	if BridgePath("", `C:\x`) == `C:\x` {
+		t.Skip("bridges Windows paths only on non-Windows hosts")
+	}
+	content := `{"type":"session.start","data":{"sessionId":"s1","context":{"cwd":"C:\\Users\\me\\code\\my-app"}},"timestamp":"2025-01-01T00:00:00Z"}` + "\n" +
+		`{"type":"user.message","data":{"content":"hi"},"timestamp":"2025-01-01T00:00:01Z"}` + "\n"
+	path := createTestFile(t, "events.jsonl", content)
+
+	sess, _, err := ParseCopilotSession(path, "local")
+	if err != nil {
+		t.Fatalf("ParseCopilotSession: %v", err)
+	}
+	if sess == nil || sess.Project != "my_app" {
+		t.Fatalf("project = %+v, want my_app", sess)
+	}
+}
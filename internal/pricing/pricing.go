@@ -0,0 +1,185 @@
+// Package pricing estimates API cost from token usage using
+// built-in per-model list prices, which the pricing section of
+// config.json can override or extend.
+package pricing
+
+import (
+	"errors"
+	"maps"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Rate is the price of a model in USD per million tokens.
+type Rate struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheRead  float64 `json:"cache_read"`
+	CacheWrite float64 `json:"cache_write"`
+}
+
+// Usage is the token usage to price. Input excludes cache
+// reads and writes, matching how the Anthropic API reports it.
+type Usage struct {
+	Input      int
+	Output     int
+	CacheRead  int
+	CacheWrite int
+}
+
+// builtinRates maps model name prefixes to list prices. Lookup
+// uses the longest matching prefix, so dated or suffixed model
+// IDs (claude-sonnet-4-20250514) resolve to their family.
+var builtinRates = map[string]Rate{
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheRead: 0.5, CacheWrite: 6.25},
+	"claude-opus-4":     {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-3-opus":     {Input: 15, Output: 75, CacheRead: 1.5, CacheWrite: 18.75},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-3-5-sonnet": {Input: 3, Output: 15, CacheRead: 0.3, CacheWrite: 3.75},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheRead: 0.1, CacheWrite: 1.25},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+	"gpt-5":             {Input: 1.25, Output: 10, CacheRead: 0.125},
+	"gpt-5-mini":        {Input: 0.25, Output: 2, CacheRead: 0.025},
+	"gpt-4.1":           {Input: 2, Output: 8, CacheRead: 0.5},
+	"gpt-4o":            {Input: 2.5, Output: 10, CacheRead: 1.25},
+	"o3":                {Input: 2, Output: 8, CacheRead: 0.5},
+	"o4-mini":           {Input: 1.1, Output: 4.4, CacheRead: 0.275},
+	"gemini-2.5-pro":    {Input: 1.25, Output: 10, CacheRead: 0.31},
+	"gemini-2.5-flash":  {Input: 0.3, Output: 2.5, CacheRead: 0.075},
+}
+
+// Sources of a rate in the table.
+const (
+	SourceBuiltin = "builtin"
+	SourceConfig  = "config"
+)
+
+// Entry is one model prefix in the pricing table.
+type Entry struct {
+	Model  string `json:"model"`
+	Source string `json:"source"`
+	Rate
+}
+
+// table is the active pricing: the built-in rates with any
+// overrides applied, and its keys longest first.
+type table struct {
+	rates     map[string]Rate
+	overrides map[string]bool
+	prefixes  []string
+}
+
+var active atomic.Pointer[table]
+
+func init() {
+	active.Store(newTable(nil))
+}
+
+func newTable(overrides map[string]Rate) *table {
+	t := &table{
+		rates:     maps.Clone(builtinRates),
+		overrides: map[string]bool{},
+	}
+	for model, r := range overrides {
+		model = normalizeModel(model)
+		t.rates[model] = r
+		t.overrides[model] = true
+	}
+	t.prefixes = make([]string, 0, len(t.rates))
+	for k := range t.rates {
+		t.prefixes = append(t.prefixes, k)
+	}
+	sort.Slice(t.prefixes, func(i, j int) bool {
+		a, b := t.prefixes[i], t.prefixes[j]
+		if len(a) != len(b) {
+			return len(a) > len(b)
+		}
+		return a < b
+	})
+	return t
+}
+
+// Validate reports whether overrides can be applied: model
+// names must be non-empty and rates non-negative.
+func Validate(overrides map[string]Rate) error {
+	for model, r := range overrides {
+		if normalizeModel(model) == "" {
+			return errors.New("model name is required")
+		}
+		if r.Input < 0 || r.Output < 0 ||
+			r.CacheRead < 0 || r.CacheWrite < 0 {
+			return errors.New(
+				"rates for " + model + " must not be negative",
+			)
+		}
+	}
+	return nil
+}
+
+// SetOverrides replaces the configured rates. Each key is a
+// model name prefix matched like the built-in ones: a key equal
+// to a built-in prefix replaces its rate, and any other key
+// adds a model. The longest matching prefix wins, so an
+// override for "claude" does not reprice "claude-sonnet-4".
+func SetOverrides(overrides map[string]Rate) {
+	active.Store(newTable(overrides))
+}
+
+// Table returns the active pricing table sorted by model.
+func Table() []Entry {
+	t := active.Load()
+	out := make([]Entry, 0, len(t.rates))
+	for model, r := range t.rates {
+		src := SourceBuiltin
+		if t.overrides[model] {
+			src = SourceConfig
+		}
+		out = append(out, Entry{Model: model, Source: src, Rate: r})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// normalizeModel lower-cases model and drops provider prefixes
+// such as "anthropic/".
+func normalizeModel(model string) string {
+	m := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	return m
+}
+
+// Lookup returns the rate for model. Provider prefixes such as
+// "anthropic/" are ignored. The second result is false for
+// unknown models.
+func Lookup(model string) (Rate, bool) {
+	m := normalizeModel(model)
+	if m == "" {
+		return Rate{}, false
+	}
+	t := active.Load()
+	for _, p := range t.prefixes {
+		if strings.HasPrefix(m, p) {
+			return t.rates[p], true
+		}
+	}
+	return Rate{}, false
+}
+
+// Cost returns the estimated USD cost of u on model, or 0 for
+// unknown models.
+func Cost(model string, u Usage) float64 {
+	r, ok := Lookup(model)
+	if !ok {
+		return 0
+	}
+	return (float64(u.Input)*r.Input +
+		float64(u.Output)*r.Output +
+		float64(u.CacheRead)*r.CacheRead +
+		float64(u.CacheWrite)*r.CacheWrite) / 1e6
+}
@@ -0,0 +1,95 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		model  string
+		want   float64 // input rate
+		wantOK bool
+	}{
+		{"claude-sonnet-4-20250514", 3, true},
+		{"claude-opus-4-5-20251101", 5, true},
+		{"claude-opus-4-1-20250805", 15, true},
+		{"anthropic/claude-3-5-haiku-latest", 0.8, true},
+		{"GPT-5-mini", 0.25, true},
+		{"gpt-5-codex", 1.25, true},
+		{"some-local-model", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			r, ok := Lookup(tt.model)
+			if ok != tt.wantOK || r.Input != tt.want {
+				t.Errorf("Lookup(%q) = %v, %v; want input %v, %v",
+					tt.model, r, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	got := Cost("claude-sonnet-4", Usage{
+		Input:      1_000_000,
+		Output:     100_000,
+		CacheRead:  2_000_000,
+		CacheWrite: 100_000,
+	})
+	// 3 + 1.5 + 0.6 + 0.375
+	if want := 5.475; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Cost = %v, want %v", got, want)
+	}
+	if got := Cost("unknown", Usage{Input: 1000}); got != 0 {
+		t.Errorf("unknown model cost = %v, want 0", got)
+	}
+}
+
+func TestSetOverrides(t *testing.T) {
+	t.Cleanup(func() { SetOverrides(nil) })
+	SetOverrides(map[string]Rate{
+		"Claude-Sonnet-4": {Input: 2, Output: 10},
+		"my-local-model":  {Input: 0.5},
+		"claude":          {Input: 100},
+	})
+
+	if r, _ := Lookup("claude-sonnet-4-20250514"); r.Input != 2 {
+		t.Errorf("overridden rate = %v, want input 2", r)
+	}
+	// The longer built-in prefix beats the short override.
+	if r, _ := Lookup("claude-opus-4-1"); r.Input != 15 {
+		t.Errorf("claude-opus-4-1 = %v, want built-in input 15", r)
+	}
+	if r, ok := Lookup("ollama/my-local-model:7b"); !ok || r.Input != 0.5 {
+		t.Errorf("added model = %v, %v", r, ok)
+	}
+
+	sources := map[string]string{}
+	for _, e := range Table() {
+		sources[e.Model] = e.Source
+	}
+	if sources["claude-sonnet-4"] != SourceConfig ||
+		sources["gpt-4o"] != SourceBuiltin ||
+		sources["my-local-model"] != SourceConfig {
+		t.Errorf("table sources = %v", sources)
+	}
+
+	SetOverrides(nil)
+	if r, _ := Lookup("claude-sonnet-4"); r.Input != 3 {
+		t.Errorf("after reset = %v, want built-in input 3", r)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(map[string]Rate{"m": {Input: 1}}); err != nil {
+		t.Errorf("valid overrides: %v", err)
+	}
+	if err := Validate(map[string]Rate{"m": {CacheRead: -1}}); err == nil {
+		t.Error("negative rate accepted")
+	}
+	if err := Validate(map[string]Rate{" ": {}}); err == nil {
+		t.Error("blank model accepted")
+	}
+}
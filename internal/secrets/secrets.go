@@ -0,0 +1,153 @@
+// Package secrets detects likely credentials (API keys, tokens,
+// private keys) in free text so they can be reported or
+// redacted without being displayed.
+package secrets
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Finding is one likely secret in a string. Start and End are
+// byte offsets of the secret value itself.
+type Finding struct {
+	Rule  string
+	Start int
+	End   int
+}
+
+// rule is a named detector. When group is non-zero only that
+// submatch is the secret (the rest is context such as a key
+// name). valid, when set, rejects matches that look like
+// placeholders.
+type rule struct {
+	name  string
+	re    *regexp.Regexp
+	group int
+	valid func(string) bool
+}
+
+// rules are checked in order; earlier rules win when matches
+// overlap, so specific formats come before generic ones.
+var rules = []rule{
+	{name: "private_key", re: regexp.MustCompile(
+		`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----(?:.*?-----END [A-Z ]*PRIVATE KEY-----)?`,
+	)},
+	{name: "aws_access_key", re: regexp.MustCompile(
+		`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	)},
+	{name: "github_token", re: regexp.MustCompile(
+		`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`,
+	)},
+	{name: "anthropic_api_key", re: regexp.MustCompile(
+		`\bsk-ant-[A-Za-z0-9_-]{20,}`,
+	)},
+	{name: "openai_api_key", re: regexp.MustCompile(
+		`\bsk-(?:proj-)?[A-Za-z0-9_-]{32,}`,
+	)},
+	{name: "slack_token", re: regexp.MustCompile(
+		`\bxox[abprs]-[A-Za-z0-9-]{10,}`,
+	)},
+	{name: "stripe_key", re: regexp.MustCompile(
+		`\b[rs]k_live_[A-Za-z0-9]{20,}`,
+	)},
+	{name: "google_api_key", re: regexp.MustCompile(
+		`\bAIza[0-9A-Za-z_-]{35}`,
+	)},
+	{name: "jwt", re: regexp.MustCompile(
+		`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`,
+	)},
+	{
+		name: "password_assignment",
+		re: regexp.MustCompile(
+			`(?i)\b(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)["']?\s*[:=]\s*["']?([^\s"'` + "`" + `,;]{8,})`,
+		),
+		group: 1,
+		valid: looksRandom,
+	},
+}
+
+// Rules returns the detector names in priority order.
+func Rules() []string {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.name
+	}
+	return names
+}
+
+// Find returns the likely secrets in s, ordered by position,
+// with overlapping matches removed.
+func Find(s string) []Finding {
+	var found []Finding
+	for _, r := range rules {
+		for _, m := range r.re.FindAllStringSubmatchIndex(s, -1) {
+			start, end := m[2*r.group], m[2*r.group+1]
+			if start < 0 {
+				continue
+			}
+			if r.valid != nil && !r.valid(s[start:end]) {
+				continue
+			}
+			if overlaps(found, start, end) {
+				continue
+			}
+			found = append(found, Finding{
+				Rule: r.name, Start: start, End: end,
+			})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Start < found[j].Start
+	})
+	return found
+}
+
+func overlaps(found []Finding, start, end int) bool {
+	for _, f := range found {
+		if start < f.End && f.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact replaces every finding in s with "[REDACTED:<rule>]"
+// and returns the result and the number of replacements.
+func Redact(s string) (string, int) {
+	found := Find(s)
+	if len(found) == 0 {
+		return s, 0
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	prev := 0
+	for _, f := range found {
+		b.WriteString(s[prev:f.Start])
+		b.WriteString("[REDACTED:" + f.Rule + "]")
+		prev = f.End
+	}
+	b.WriteString(s[prev:])
+	return b.String(), len(found)
+}
+
+// looksRandom rejects values that read like placeholders or
+// code (${VAR}, <token>, process.env.X, xxxxxxxx) rather than
+// literal credentials: the value must mix letters and digits.
+func looksRandom(v string) bool {
+	if strings.ContainsAny(v, "$<>{}()[]") ||
+		strings.Contains(v, "REDACTED") {
+		return false
+	}
+	var letters, digits bool
+	for _, c := range v {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			letters = true
+		}
+	}
+	return letters && digits
+}
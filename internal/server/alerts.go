@@ -0,0 +1,19 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/wesm/agentsview/internal/alert"
+)
+
+// handleListAlerts reports each configured alert rule's state
+// as of its last scheduled check.
+func (s *Server) handleListAlerts(
+	w http.ResponseWriter, _ *http.Request,
+) {
+	states := []alert.State{}
+	if s.alerts != nil {
+		states = s.alerts.States()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"alerts": states})
+}
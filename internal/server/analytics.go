@@ -1,11 +1,14 @@
 package server
 
 import (
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/wesm/agentsview/internal/config"
 	"github.com/wesm/agentsview/internal/db"
 )
 
@@ -25,10 +28,22 @@ func isValidTimestamp(s string) bool {
 	return err == nil
 }
 
+// defaultRangeDays is the date range length used when neither
+// the request nor the config sets one.
+const defaultRangeDays = 30
+
 // defaultDateRange returns (from, to) defaulting to the last
 // 30 days if not provided.
 func defaultDateRange(
 	from, to string,
+) (string, string) {
+	return defaultDateRangeDays(from, to, defaultRangeDays)
+}
+
+// defaultDateRangeDays is defaultDateRange with a configurable
+// range length.
+func defaultDateRangeDays(
+	from, to string, days int,
 ) (string, string) {
 	now := time.Now().UTC()
 	if to == "" {
@@ -39,17 +54,58 @@ func defaultDateRange(
 		if err != nil {
 			t = now
 		}
-		from = t.AddDate(0, 0, -30).Format("2006-01-02")
+		from = t.AddDate(0, 0, -days).Format("2006-01-02")
 	}
 	return from, to
 }
 
+// analyticsDefaults returns the defaults to apply to a request:
+// the configured analytics_defaults, overlaid with the saved
+// filter named by the filter param. defaults=false skips the
+// configured defaults (the dashboard sends it once it has
+// seeded its own state) but still honors an explicit filter.
+func (s *Server) analyticsDefaults(
+	w http.ResponseWriter, r *http.Request,
+) (config.AnalyticsDefaults, bool) {
+	q := r.URL.Query()
+	var d config.AnalyticsDefaults
+	useDefaults := true
+	if v := q.Get("defaults"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest,
+				"defaults must be true or false")
+			return d, false
+		}
+		useDefaults = b
+	}
+	if useDefaults {
+		d = s.cfg.AnalyticsDefaults
+	}
+	if name := q.Get("filter"); name != "" {
+		sf, ok := s.cfg.SavedFilter(name)
+		if !ok {
+			writeError(w, http.StatusBadRequest,
+				"unknown saved filter: "+name)
+			return d, false
+		}
+		d = d.Merge(sf.AnalyticsDefaults)
+	}
+	return d, true
+}
+
 // parseAnalyticsFilter extracts the common analytics filter
-// params from a request.
-func parseAnalyticsFilter(
+// params from a request. Parameters the request omits take
+// their value from analyticsDefaults; an explicit empty
+// project= clears a default project.
+func (s *Server) parseAnalyticsFilter(
 	w http.ResponseWriter, r *http.Request,
 ) (db.AnalyticsFilter, bool) {
 	q := r.URL.Query()
+	defaults, ok := s.analyticsDefaults(w, r)
+	if !ok {
+		return db.AnalyticsFilter{}, false
+	}
 	tz := q.Get("timezone")
 	if tz == "" {
 		tz = "UTC"
@@ -60,7 +116,13 @@ func parseAnalyticsFilter(
 		return db.AnalyticsFilter{}, false
 	}
 
-	from, to := defaultDateRange(q.Get("from"), q.Get("to"))
+	rangeDays := defaultRangeDays
+	if defaults.RangeDays > 0 {
+		rangeDays = defaults.RangeDays
+	}
+	from, to := defaultDateRangeDays(
+		q.Get("from"), q.Get("to"), rangeDays,
+	)
 
 	if !isValidDate(from) || !isValidDate(to) {
 		writeError(w, http.StatusBadRequest,
@@ -95,9 +157,24 @@ func parseAnalyticsFilter(
 		hour = &v
 	}
 
-	minUserMsgs, ok := parseIntParam(w, r, "min_user_messages")
-	if !ok {
-		return db.AnalyticsFilter{}, false
+	minUserMsgs := defaults.MinUserMessages
+	if q.Has("min_user_messages") {
+		if minUserMsgs, ok = parseIntParam(
+			w, r, "min_user_messages",
+		); !ok {
+			return db.AnalyticsFilter{}, false
+		}
+	}
+
+	project := defaults.Project
+	if q.Has("project") {
+		project = q.Get("project")
+	}
+	// A project with its own timezone reports in it, so a
+	// client's days line up with the client's calendar.
+	if ps, ok := s.cfg.ProjectSettings(project); ok &&
+		ps.Timezone != "" {
+		tz = ps.Timezone
 	}
 
 	activeSince := q.Get("active_since")
@@ -107,60 +184,179 @@ func parseAnalyticsFilter(
 		return db.AnalyticsFilter{}, false
 	}
 
+	includeBots := false
+	if s := q.Get("include_bots"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest,
+				"include_bots must be true or false")
+			return db.AnalyticsFilter{}, false
+		}
+		includeBots = v
+	}
+
+	includeArchived, ok := parseBoolParam(w, r, "include_archived")
+	if !ok {
+		return db.AnalyticsFilter{}, false
+	}
+
+	activeProjectsSince, ok := s.activeProjectsSince(w, r)
+	if !ok {
+		return db.AnalyticsFilter{}, false
+	}
+
+	includeSessionIDs := false
+	if s := q.Get("include_session_ids"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest,
+				"include_session_ids must be true or false")
+			return db.AnalyticsFilter{}, false
+		}
+		includeSessionIDs = v
+	}
+
 	return db.AnalyticsFilter{
-		From:            from,
-		To:              to,
-		Machine:         q.Get("machine"),
-		Project:         q.Get("project"),
-		Agent:           q.Get("agent"),
-		Timezone:        tz,
-		DayOfWeek:       dow,
-		Hour:            hour,
-		MinUserMessages: minUserMsgs,
-		ActiveSince:     activeSince,
+		From:                from,
+		To:                  to,
+		Machine:             q.Get("machine"),
+		Project:             project,
+		Agent:               q.Get("agent"),
+		Tag:                 q.Get("tag"),
+		Language:            q.Get("language"),
+		Timezone:            tz,
+		DayOfWeek:           dow,
+		Hour:                hour,
+		MinUserMessages:     minUserMsgs,
+		ActiveSince:         activeSince,
+		IncludeBots:         includeBots,
+		IncludeArchived:     includeArchived,
+		IncludeSessionIDs:   includeSessionIDs,
+		ActiveProjectsSince: activeProjectsSince,
 	}, true
 }
 
-func (s *Server) handleAnalyticsSummary(
+// activeProjectsSince returns the cutoff that hides inactive
+// projects, or "" when archival is off or the request sets
+// include_inactive. Writes a 400 and returns false when
+// include_inactive is not a boolean.
+func (s *Server) activeProjectsSince(
+	w http.ResponseWriter, r *http.Request,
+) (string, bool) {
+	if v := r.URL.Query().Get("include_inactive"); v != "" {
+		include, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest,
+				"include_inactive must be true or false")
+			return "", false
+		}
+		if include {
+			return "", true
+		}
+	}
+	return s.cfg.InactiveProjectCutoff(time.Now()), true
+}
+
+// analyticsDefaultsResponse reports the configured analytics
+// defaults so the dashboard can seed its filters. Zero fields
+// are not configured.
+type analyticsDefaultsResponse struct {
+	RangeDays       int                  `json:"range_days"`
+	Project         string               `json:"project"`
+	MinUserMessages int                  `json:"min_user_messages"`
+	SavedFilters    []config.SavedFilter `json:"saved_filters"`
+}
+
+func (s *Server) handleAnalyticsDefaults(
 	w http.ResponseWriter, r *http.Request,
 ) {
-	f, ok := parseAnalyticsFilter(w, r)
+	d, ok := s.analyticsDefaults(w, r)
 	if !ok {
 		return
 	}
+	resp := analyticsDefaultsResponse{
+		RangeDays:       d.RangeDays,
+		Project:         d.Project,
+		MinUserMessages: d.MinUserMessages,
+		SavedFilters:    s.cfg.SavedFilters,
+	}
+	if resp.SavedFilters == nil {
+		resp.SavedFilters = []config.SavedFilter{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
 
-	result, err := s.db.GetAnalyticsSummary(r.Context(), f)
+// handleAnalyticsMonthly returns the monthly totals kept for
+// months before the compaction horizon, optionally for one
+// project and agent.
+func (s *Server) handleAnalyticsMonthly(
+	w http.ResponseWriter, r *http.Request,
+) {
+	q := r.URL.Query()
+	months, err := s.db.GetMonthlyUsage(
+		r.Context(), q.Get("project"), q.Get("agent"),
+	)
 	if err != nil {
 		if handleContextError(w, err) {
 			return
 		}
-		log.Printf("analytics error: %v", err)
-		writeError(w, http.StatusInternalServerError,
-			"internal server error")
+		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-
-	writeJSON(w, http.StatusOK, result)
+	writeJSON(w, http.StatusOK, map[string]any{"months": months})
 }
 
-func (s *Server) handleAnalyticsActivity(
+func (s *Server) handleAnalyticsSummary(
 	w http.ResponseWriter, r *http.Request,
 ) {
-	f, ok := parseAnalyticsFilter(w, r)
+	f, ok := s.parseAnalyticsFilter(w, r)
 	if !ok {
 		return
 	}
 
+	result, err := s.db.GetAnalyticsSummary(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// parseGranularity reads the granularity query param,
+// defaulting to day. Writes a 400 and returns false when
+// invalid.
+func parseGranularity(
+	w http.ResponseWriter, r *http.Request,
+) (string, bool) {
 	granularity := r.URL.Query().Get("granularity")
 	if granularity == "" {
 		granularity = "day"
 	}
 	switch granularity {
 	case "day", "week", "month":
-		// valid
-	default:
-		writeError(w, http.StatusBadRequest,
-			"invalid granularity: must be day, week, or month")
+		return granularity, true
+	}
+	writeError(w, http.StatusBadRequest,
+		"invalid granularity: must be day, week, or month")
+	return "", false
+}
+
+func (s *Server) handleAnalyticsActivity(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	granularity, ok := parseGranularity(w, r)
+	if !ok {
 		return
 	}
 
@@ -171,19 +367,19 @@ func (s *Server) handleAnalyticsActivity(
 		if handleContextError(w, err) {
 			return
 		}
-		log.Printf("analytics error: %v", err)
+		slog.Error("analytics error", "err", err)
 		writeError(w, http.StatusInternalServerError,
 			"internal server error")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	s.writeAnalytics(w, r, f, result)
 }
 
 func (s *Server) handleAnalyticsHeatmap(
 	w http.ResponseWriter, r *http.Request,
 ) {
-	f, ok := parseAnalyticsFilter(w, r)
+	f, ok := s.parseAnalyticsFilter(w, r)
 	if !ok {
 		return
 	}
@@ -201,6 +397,7 @@ func (s *Server) handleAnalyticsHeatmap(
 		return
 	}
 
+	f.WorkingHours = s.workingWindows()
 	result, err := s.db.GetAnalyticsHeatmap(
 		r.Context(), f, metric,
 	)
@@ -208,19 +405,56 @@ func (s *Server) handleAnalyticsHeatmap(
 		if handleContextError(w, err) {
 			return
 		}
-		log.Printf("analytics error: %v", err)
+		slog.Error("analytics error", "err", err)
 		writeError(w, http.StatusInternalServerError,
 			"internal server error")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	s.writeAnalytics(w, r, f, result)
+}
+
+// workingWindows converts the configured working hours for the
+// heatmap and hour-of-week queries.
+func (s *Server) workingWindows() []db.WorkingWindow {
+	if len(s.cfg.WorkingHours) == 0 {
+		return nil
+	}
+	windows := make([]db.WorkingWindow, len(s.cfg.WorkingHours))
+	for i, wh := range s.cfg.WorkingHours {
+		windows[i] = db.WorkingWindow{
+			Days:        wh.Days,
+			StartMinute: wh.StartMinute(),
+			EndMinute:   wh.EndMinute(),
+			Timezone:    wh.Timezone,
+		}
+	}
+	return windows
+}
+
+// costRates converts the configured project currencies for
+// cost figures.
+func (s *Server) costRates() map[string]db.CostRate {
+	var rates map[string]db.CostRate
+	for name, ps := range s.cfg.Projects {
+		if ps.Currency == "" && ps.RateMultiplier == 0 {
+			continue
+		}
+		if rates == nil {
+			rates = make(map[string]db.CostRate)
+		}
+		rates[name] = db.CostRate{
+			Currency:   ps.Currency,
+			Multiplier: ps.RateMultiplier,
+		}
+	}
+	return rates
 }
 
 func (s *Server) handleAnalyticsProjects(
 	w http.ResponseWriter, r *http.Request,
 ) {
-	f, ok := parseAnalyticsFilter(w, r)
+	f, ok := s.parseAnalyticsFilter(w, r)
 	if !ok {
 		return
 	}
@@ -230,23 +464,24 @@ func (s *Server) handleAnalyticsProjects(
 		if handleContextError(w, err) {
 			return
 		}
-		log.Printf("analytics error: %v", err)
+		slog.Error("analytics error", "err", err)
 		writeError(w, http.StatusInternalServerError,
 			"internal server error")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	s.writeAnalytics(w, r, f, result)
 }
 
 func (s *Server) handleAnalyticsHourOfWeek(
 	w http.ResponseWriter, r *http.Request,
 ) {
-	f, ok := parseAnalyticsFilter(w, r)
+	f, ok := s.parseAnalyticsFilter(w, r)
 	if !ok {
 		return
 	}
 
+	f.WorkingHours = s.workingWindows()
 	result, err := s.db.GetAnalyticsHourOfWeek(
 		r.Context(), f,
 	)
@@ -254,19 +489,19 @@ func (s *Server) handleAnalyticsHourOfWeek(
 		if handleContextError(w, err) {
 			return
 		}
-		log.Printf("analytics error: %v", err)
+		slog.Error("analytics error", "err", err)
 		writeError(w, http.StatusInternalServerError,
 			"internal server error")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	s.writeAnalytics(w, r, f, result)
 }
 
 func (s *Server) handleAnalyticsSessionShape(
 	w http.ResponseWriter, r *http.Request,
 ) {
-	f, ok := parseAnalyticsFilter(w, r)
+	f, ok := s.parseAnalyticsFilter(w, r)
 	if !ok {
 		return
 	}
@@ -278,19 +513,19 @@ func (s *Server) handleAnalyticsSessionShape(
 		if handleContextError(w, err) {
 			return
 		}
-		log.Printf("analytics error: %v", err)
+		slog.Error("analytics error", "err", err)
 		writeError(w, http.StatusInternalServerError,
 			"internal server error")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	s.writeAnalytics(w, r, f, result)
 }
 
 func (s *Server) handleAnalyticsTools(
 	w http.ResponseWriter, r *http.Request,
 ) {
-	f, ok := parseAnalyticsFilter(w, r)
+	f, ok := s.parseAnalyticsFilter(w, r)
 	if !ok {
 		return
 	}
@@ -300,19 +535,19 @@ func (s *Server) handleAnalyticsTools(
 		if handleContextError(w, err) {
 			return
 		}
-		log.Printf("analytics error: %v", err)
+		slog.Error("analytics error", "err", err)
 		writeError(w, http.StatusInternalServerError,
 			"internal server error")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	s.writeAnalytics(w, r, f, result)
 }
 
 func (s *Server) handleAnalyticsVelocity(
 	w http.ResponseWriter, r *http.Request,
 ) {
-	f, ok := parseAnalyticsFilter(w, r)
+	f, ok := s.parseAnalyticsFilter(w, r)
 	if !ok {
 		return
 	}
@@ -324,19 +559,19 @@ func (s *Server) handleAnalyticsVelocity(
 		if handleContextError(w, err) {
 			return
 		}
-		log.Printf("analytics error: %v", err)
+		slog.Error("analytics error", "err", err)
 		writeError(w, http.StatusInternalServerError,
 			"internal server error")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	s.writeAnalytics(w, r, f, result)
 }
 
 func (s *Server) handleAnalyticsTopSessions(
 	w http.ResponseWriter, r *http.Request,
 ) {
-	f, ok := parseAnalyticsFilter(w, r)
+	f, ok := s.parseAnalyticsFilter(w, r)
 	if !ok {
 		return
 	}
@@ -361,11 +596,648 @@ func (s *Server) handleAnalyticsTopSessions(
 		if handleContextError(w, err) {
 			return
 		}
-		log.Printf("analytics error: %v", err)
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+func (s *Server) handleAnalyticsSLO(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	granularity, ok := parseGranularity(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+
+	groupBy := q.Get("group_by")
+	if groupBy == "" {
+		groupBy = db.SLOGroupByAgent
+	}
+	switch groupBy {
+	case db.SLOGroupByAgent, db.SLOGroupByModel:
+		// valid
+	default:
+		writeError(w, http.StatusBadRequest,
+			"invalid group_by: must be agent or model")
+		return
+	}
+
+	targets := make([]db.SLOTarget, len(s.cfg.SLOs))
+	for i, slo := range s.cfg.SLOs {
+		targets[i] = db.SLOTarget{
+			Name:         slo.Name,
+			Metric:       slo.Metric,
+			Percentile:   slo.Percentile,
+			ThresholdSec: slo.ThresholdSec,
+		}
+	}
+
+	result, err := s.db.GetAnalyticsSLO(
+		r.Context(), f, targets, granularity, groupBy,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+func (s *Server) handleAnalyticsCache(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	granularity, ok := parseGranularity(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsCache(
+		r.Context(), f, granularity,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsUsage reports token usage and estimated cost
+// per date bucket, agent, model, and project.
+func (s *Server) handleAnalyticsUsage(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	granularity, ok := parseGranularity(w, r)
+	if !ok {
+		return
+	}
+
+	f.CostRates = s.costRates()
+	result, err := s.db.GetAnalyticsUsage(
+		r.Context(), f, granularity,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+func (s *Server) handleAnalyticsCostPhases(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	f.CostRates = s.costRates()
+	result, err := s.db.GetAnalyticsCostPhases(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+func (s *Server) handleAnalyticsForecast(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	f.CostRates = s.costRates()
+	result, err := s.db.GetAnalyticsForecast(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsMonthToDate reports the month containing the
+// filter's end date against the same days of last month.
+func (s *Server) handleAnalyticsMonthToDate(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	f.CostRates = s.costRates()
+	result, err := s.db.GetMonthToDate(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsWarmup reports each day's first session and
+// how long it took to reach a substantive one.
+func (s *Server) handleAnalyticsWarmup(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+	minUser, ok := parseIntParam(w, r, "min_user_messages")
+	if !ok {
+		return
+	}
+	if minUser < 0 {
+		writeError(w, http.StatusBadRequest,
+			"min_user_messages must not be negative")
+		return
+	}
+
+	result, err := s.db.GetAnalyticsWarmup(r.Context(), f, minUser)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+func (s *Server) handleAnalyticsProgress(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+	n, ok := parseIntParam(w, r, "n")
+	if !ok {
+		return
+	}
+	if n < 0 || n > db.MaxProgressSessions {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("n must be 1-%d", db.MaxProgressSessions))
+		return
+	}
+
+	result, err := s.db.GetAnalyticsProgress(r.Context(), f, n)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+func (s *Server) handleAnalyticsToolSequences(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+	n, ok := parseIntParam(w, r, "n")
+	if !ok {
+		return
+	}
+	if n != 0 && (n < db.MinSequenceLength || n > db.MaxSequenceLength) {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("n must be %d-%d",
+				db.MinSequenceLength, db.MaxSequenceLength))
+		return
+	}
+	limit, ok := parseIntParam(w, r, "limit")
+	if !ok {
+		return
+	}
+	if limit < 0 || limit > db.MaxSequenceLimit {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("limit must be 1-%d", db.MaxSequenceLimit))
+		return
+	}
+
+	result, err := s.db.GetAnalyticsToolSequences(
+		r.Context(), f, n, limit,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+func (s *Server) handleAnalyticsCommands(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+	limit, ok := parseIntParam(w, r, "limit")
+	if !ok {
+		return
+	}
+	if limit < 0 || limit > db.MaxCommandLimit {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("limit must be 1-%d", db.MaxCommandLimit))
+		return
+	}
+
+	result, err := s.db.GetAnalyticsCommands(r.Context(), f, limit)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+func (s *Server) handleAnalyticsHooks(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsHooks(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+func (s *Server) handleAnalyticsModelSwitches(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsModelSwitches(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsEditThrash flags sessions that edited the same
+// file more than threshold times.
+func (s *Server) handleAnalyticsEditThrash(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+	threshold, ok := parseIntParam(w, r, "threshold")
+	if !ok {
+		return
+	}
+	if threshold != 0 && (threshold < db.MinEditThrashThreshold ||
+		threshold > db.MaxEditThrashThreshold) {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("threshold must be %d-%d",
+				db.MinEditThrashThreshold, db.MaxEditThrashThreshold))
+		return
+	}
+
+	result, err := s.db.GetAnalyticsEditThrash(
+		r.Context(), f, threshold,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsRetries counts sessions started within window
+// minutes of a failed session in the same project.
+func (s *Server) handleAnalyticsRetries(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+	window, ok := parseIntParam(w, r, "window")
+	if !ok {
+		return
+	}
+	if window != 0 && (window < db.MinRetryWindow ||
+		window > db.MaxRetryWindow) {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("window must be %d-%d",
+				db.MinRetryWindow, db.MaxRetryWindow))
+		return
+	}
+
+	result, err := s.db.GetAnalyticsRetries(r.Context(), f, window)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsPaste compares context pasted into prompts
+// with context the agent read through tools.
+func (s *Server) handleAnalyticsPaste(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	granularity, ok := parseGranularity(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsPaste(r.Context(), f, granularity)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsTruncation reports how often tool results were
+// truncated, per tool and project.
+func (s *Server) handleAnalyticsTruncation(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsTruncation(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsQuality trends session quality scores and
+// lists the sessions most worth reviewing.
+func (s *Server) handleAnalyticsQuality(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	granularity, ok := parseGranularity(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsQuality(
+		r.Context(), f, granularity,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsBranches breaks project effort down by git
+// branch; pass project to drill into one project.
+func (s *Server) handleAnalyticsBranches(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsBranches(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsEntryPoints breaks sessions down by entry
+// point (IDE extension, terminal, SDK, ...).
+func (s *Server) handleAnalyticsEntryPoints(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsEntryPoints(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsLanguages breaks sessions down by the
+// dominant language of their prompts.
+func (s *Server) handleAnalyticsLanguages(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetAnalyticsLanguages(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+
+	s.writeAnalytics(w, r, f, result)
+}
+
+// handleAnalyticsSkillUsage returns usage statistics for one
+// skill (kind=skill, the default) or slash command
+// (kind=command) named by the name parameter.
+func (s *Server) handleAnalyticsSkillUsage(
+	w http.ResponseWriter, r *http.Request,
+) {
+	q := r.URL.Query()
+	name := strings.TrimSpace(q.Get("name"))
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name is required")
+		return
+	}
+	kind := q.Get("kind")
+	if kind == "" {
+		kind = db.UsageKindSkill
+	}
+	if kind != db.UsageKindSkill && kind != db.UsageKindCommand {
+		writeError(w, http.StatusBadRequest,
+			db.ErrInvalidUsageKind.Error())
+		return
+	}
+
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := s.db.GetSkillUsage(r.Context(), f, kind, name)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics error", "err", err)
 		writeError(w, http.StatusInternalServerError,
 			"internal server error")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, result)
+	s.writeAnalytics(w, r, f, result)
 }
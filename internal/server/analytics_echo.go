@@ -0,0 +1,122 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// analyticsParams are the query parameters analytics endpoints
+// read. Anything else in a request is reported as ignored.
+var analyticsParams = map[string]bool{
+	"from": true, "to": true, "timezone": true, "machine": true,
+	"project": true, "agent": true, "tag": true, "dow": true,
+	"hour": true, "min_user_messages": true, "active_since": true,
+	"include_bots": true, "include_archived": true,
+	"include_inactive": true, "include_session_ids": true,
+	"filter": true, "defaults": true,
+	"granularity": true, "metric": true, "group_by": true,
+	"limit": true, "n": true, "threshold": true, "window": true,
+	"kind": true, "name": true,
+}
+
+// analyticsEcho is added to every analytics response: the
+// filter the numbers were computed with, after defaults, and
+// warnings about parts of the request that may not have done
+// what the client meant.
+type analyticsEcho struct {
+	Filter   db.AnalyticsFilter `json:"filter"`
+	Warnings []string           `json:"warnings"`
+}
+
+// analyticsWarnings lists ignored parameters, a timezone
+// overridden by the project's, and filter values no session
+// has.
+func (s *Server) analyticsWarnings(
+	r *http.Request, f db.AnalyticsFilter,
+) []string {
+	warnings := []string{}
+	q := r.URL.Query()
+
+	var unknown []string
+	for name := range q {
+		if !analyticsParams[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		warnings = append(warnings,
+			fmt.Sprintf("unknown parameter %q ignored", name))
+	}
+
+	if tz := q.Get("timezone"); tz != "" && tz != f.Timezone {
+		warnings = append(warnings, fmt.Sprintf(
+			"timezone %q replaced by project %q timezone %q",
+			tz, f.Project, f.Timezone,
+		))
+	}
+
+	fields, err := s.db.UnmatchedFilterFields(r.Context(), f)
+	if err != nil {
+		slog.Warn("checking analytics filter", "err", err)
+	}
+	for _, field := range fields {
+		var value string
+		switch field {
+		case "project":
+			value = f.Project
+		case "machine":
+			value = f.Machine
+		case "agent":
+			value = f.Agent
+		case "tag":
+			value = f.Tag
+		}
+		warnings = append(warnings,
+			fmt.Sprintf("no sessions have %s %q", field, value))
+	}
+	return warnings
+}
+
+// writeAnalytics writes an analytics result with the effective
+// filter and any warnings added as top-level filter and
+// warnings fields.
+func (s *Server) writeAnalytics(
+	w http.ResponseWriter, r *http.Request,
+	f db.AnalyticsFilter, result any,
+) {
+	body, err := json.Marshal(result)
+	if err != nil || len(body) < 2 || body[0] != '{' {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+	// Server configuration, not part of the request.
+	f.WorkingHours, f.CostRates = nil, nil
+	echo, err := json.Marshal(analyticsEcho{
+		Filter:   f,
+		Warnings: s.analyticsWarnings(r, f),
+	})
+	if err != nil {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.Write(echo[:len(echo)-1])
+	if !bytes.Equal(body, []byte("{}")) {
+		buf.WriteByte(',')
+		buf.Write(body[1:])
+	} else {
+		buf.WriteByte('}')
+	}
+	buf.WriteByte('\n')
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
@@ -1,14 +1,21 @@
 package server_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/wesm/agentsview/internal/config"
 	"github.com/wesm/agentsview/internal/db"
 	"github.com/wesm/agentsview/internal/dbtest"
+	"github.com/wesm/agentsview/internal/slo"
+	"github.com/wesm/agentsview/internal/testjsonl"
 )
 
 const basePath = "/api/v1/analytics/"
@@ -153,6 +160,113 @@ func TestAnalyticsSummary(t *testing.T) {
 	})
 }
 
+func TestAnalyticsFilterEcho(t *testing.T) {
+	te := setup(t, func(c *config.Config) {
+		c.Projects = map[string]config.ProjectSettings{
+			"tokyo": {Timezone: "Asia/Tokyo"},
+		}
+	})
+	seedAnalyticsEnv(t, te)
+
+	type echo struct {
+		Filter   db.AnalyticsFilter `json:"filter"`
+		Warnings []string           `json:"warnings"`
+	}
+
+	t.Run("clean request", func(t *testing.T) {
+		w := te.get(t, buildURL("summary", map[string]string{
+			"to": "2024-06-30", "project": "alpha",
+		}))
+		assertStatus(t, w, http.StatusOK)
+		got := decode[echo](t, w)
+		want := db.AnalyticsFilter{
+			From: "2024-05-31", To: "2024-06-30",
+			Project: "alpha", Timezone: "UTC",
+		}
+		if !reflect.DeepEqual(got.Filter, want) {
+			t.Errorf("filter = %+v, want %+v", got.Filter, want)
+		}
+		if len(got.Warnings) != 0 {
+			t.Errorf("warnings = %v, want none", got.Warnings)
+		}
+		// The result itself is unchanged.
+		if resp := decode[db.AnalyticsSummary](t, w); resp.TotalSessions != 2 {
+			t.Errorf("TotalSessions = %d, want 2", resp.TotalSessions)
+		}
+	})
+
+	t.Run("warnings", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("activity", map[string]string{
+			"projct":   "alpha",
+			"project":  "tokyo",
+			"machine":  "nowhere",
+			"tag":      "wasted",
+			"timezone": "Europe/Paris",
+		}))
+		assertStatus(t, w, http.StatusOK)
+		got := decode[echo](t, w)
+		if got.Filter.Timezone != "Asia/Tokyo" {
+			t.Errorf("timezone = %q, want Asia/Tokyo", got.Filter.Timezone)
+		}
+		want := []string{
+			`unknown parameter "projct" ignored`,
+			`timezone "Europe/Paris" replaced by project "tokyo" timezone "Asia/Tokyo"`,
+			`no sessions have project "tokyo"`,
+			`no sessions have machine "nowhere"`,
+			`no sessions have tag "wasted"`,
+		}
+		if !slices.Equal(got.Warnings, want) {
+			t.Errorf("warnings = %q, want %q", got.Warnings, want)
+		}
+	})
+}
+
+func TestAnalyticsSummary_BotMachines(t *testing.T) {
+	te := setup(t)
+	stats := seedAnalyticsEnv(t, te)
+
+	content := testjsonl.NewSessionBuilder().
+		AddClaudeUser("2024-06-02T12:00:00Z", "run the release checks").
+		AddClaudeAssistant("2024-06-02T12:00:05Z", "Done.").
+		String()
+	w := te.upload(t, "ci-run.jsonl", content,
+		"project=alpha&machine=runner-9")
+	assertStatus(t, w, http.StatusOK)
+
+	w = te.get(t, "/api/v1/machines")
+	assertStatus(t, w, http.StatusOK)
+	machines := decode[struct {
+		Machines []string `json:"machines"`
+		Bots     []string `json:"bots"`
+	}](t, w)
+	if len(machines.Bots) != 1 || machines.Bots[0] != "runner-9" {
+		t.Errorf("bots = %v, want [runner-9]", machines.Bots)
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   int
+	}{
+		{"excluded by default", map[string]string{}, stats.TotalSessions},
+		{"include_bots", map[string]string{"include_bots": "true"}, stats.TotalSessions + 1},
+		{"machine filter", map[string]string{"machine": "runner-9"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := te.get(t, buildURLWithRange("summary", tt.params))
+			assertStatus(t, w, http.StatusOK)
+			resp := decode[db.AnalyticsSummary](t, w)
+			if resp.TotalSessions != tt.want {
+				t.Errorf("TotalSessions = %d, want %d", resp.TotalSessions, tt.want)
+			}
+		})
+	}
+
+	w = te.get(t, buildURLWithRange("summary", map[string]string{"include_bots": "maybe"}))
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
 func TestAnalyticsSummary_DateValidation(t *testing.T) {
 	te := setup(t)
 
@@ -282,6 +396,28 @@ func TestSessionsDateValidation(t *testing.T) {
 	}
 }
 
+func TestAnalyticsMonthly(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 2)
+	te.seedSession(t, "s2", "other", 2)
+	_, err := te.db.Compact(context.Background(), db.CompactOptions{
+		Before: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := te.get(t, "/api/v1/analytics/monthly?project=my-app")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		Months []db.MonthlyUsage `json:"months"`
+	}](t, w)
+	if len(resp.Months) != 1 || resp.Months[0].Month != "2025-01" ||
+		resp.Months[0].Sessions != 1 {
+		t.Errorf("months = %+v, want one 2025-01 entry", resp.Months)
+	}
+}
+
 func TestActiveSinceValidation(t *testing.T) {
 	te := setup(t)
 
@@ -596,6 +732,372 @@ func TestAnalyticsVelocity(t *testing.T) {
 	})
 }
 
+type analyticsDefaults struct {
+	RangeDays       int                  `json:"range_days"`
+	Project         string               `json:"project"`
+	MinUserMessages int                  `json:"min_user_messages"`
+	SavedFilters    []config.SavedFilter `json:"saved_filters"`
+}
+
+func TestAnalyticsDefaults(t *testing.T) {
+	te := setup(t, func(c *config.Config) {
+		c.AnalyticsDefaults = config.AnalyticsDefaults{
+			Project: "alpha",
+		}
+		c.SavedFilters = []config.SavedFilter{{
+			Name: "beta-busy",
+			AnalyticsDefaults: config.AnalyticsDefaults{
+				RangeDays: 7,
+				Project:   "beta",
+			},
+		}}
+	})
+	seedAnalyticsEnv(t, te)
+
+	summary := func(t *testing.T, params map[string]string) db.AnalyticsSummary {
+		t.Helper()
+		w := te.get(t, buildURLWithRange("summary", params))
+		assertStatus(t, w, http.StatusOK)
+		return decode[db.AnalyticsSummary](t, w)
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   int
+	}{
+		{"DefaultProject", nil, 2},
+		{"ExplicitProject", map[string]string{"project": "beta"}, 1},
+		{"ExplicitEmptyClears", map[string]string{"project": ""}, 3},
+		{"DefaultsDisabled", map[string]string{"defaults": "false"}, 3},
+		{"SavedFilter", map[string]string{"filter": "beta-busy"}, 1},
+		{"SavedFilterOverridden", map[string]string{
+			"filter": "beta-busy", "project": "alpha",
+		}, 2},
+		{"ExplicitMinUserMessages", map[string]string{
+			"filter": "beta-busy", "min_user_messages": "1000",
+		}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := summary(t, tt.params)
+			if resp.TotalSessions != tt.want {
+				t.Errorf("TotalSessions = %d, want %d",
+					resp.TotalSessions, tt.want)
+			}
+		})
+	}
+
+	t.Run("UnknownFilter", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("summary", map[string]string{
+			"filter": "nope",
+		}))
+		assertStatus(t, w, http.StatusBadRequest)
+	})
+
+	t.Run("Endpoint", func(t *testing.T) {
+		w := te.get(t, buildURL("defaults", nil))
+		assertStatus(t, w, http.StatusOK)
+		resp := decode[analyticsDefaults](t, w)
+		if resp.RangeDays != 0 || resp.Project != "alpha" {
+			t.Errorf("defaults = %+v, want project alpha only", resp)
+		}
+		if len(resp.SavedFilters) != 1 {
+			t.Errorf("len(SavedFilters) = %d, want 1",
+				len(resp.SavedFilters))
+		}
+
+		w = te.get(t, buildURL("defaults", map[string]string{
+			"filter": "beta-busy",
+		}))
+		assertStatus(t, w, http.StatusOK)
+		resp = decode[analyticsDefaults](t, w)
+		if resp.RangeDays != 7 || resp.Project != "beta" {
+			t.Errorf("saved filter defaults = %+v", resp)
+		}
+	})
+}
+
+func TestAnalyticsSLO(t *testing.T) {
+	te := setup(t, func(c *config.Config) {
+		c.SLOs = []config.SLO{{
+			Name:         "fast replies",
+			Metric:       slo.MetricFirstResponse,
+			Percentile:   90,
+			ThresholdSec: 30,
+		}}
+	})
+	seedAnalyticsEnv(t, te)
+
+	t.Run("OK", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("slo", map[string]string{
+			"timezone": "UTC", "group_by": "model",
+		}))
+		assertStatus(t, w, http.StatusOK)
+
+		resp := decode[db.SLOResponse](t, w)
+		if resp.GroupBy != "model" {
+			t.Errorf("GroupBy = %q, want model", resp.GroupBy)
+		}
+		if len(resp.SLOs) != 1 || resp.SLOs[0].Name != "fast replies" {
+			t.Fatalf("SLOs = %+v, want one named target", resp.SLOs)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		params map[string]string
+	}{
+		{"InvalidGranularity", map[string]string{"granularity": "hour"}},
+		{"InvalidGroupBy", map[string]string{"group_by": "project"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := te.get(t, buildURLWithRange("slo", tt.params))
+			assertStatus(t, w, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestAnalyticsCache(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+
+	t.Run("OK", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("cache", map[string]string{
+			"timezone": "UTC", "granularity": "week",
+		}))
+		assertStatus(t, w, http.StatusOK)
+
+		resp := decode[db.CacheAnalyticsResponse](t, w)
+		if resp.Granularity != "week" {
+			t.Errorf("Granularity = %q, want week", resp.Granularity)
+		}
+		if resp.Trend == nil || resp.ByModel == nil {
+			t.Error("expected non-nil Trend and ByModel")
+		}
+	})
+
+	t.Run("InvalidGranularity", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("cache", map[string]string{
+			"granularity": "hour",
+		}))
+		assertStatus(t, w, http.StatusBadRequest)
+	})
+}
+
+func TestAnalyticsUsage(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+	te.seedSession(t, "u1", "alpha", 2, func(sess *db.Session) {
+		sess.StartedAt = dbtest.Ptr("2024-06-03T09:00:00Z")
+	})
+	te.seedMessages(t, "u1", 2, func(i int, m *db.Message) {
+		if m.Role == "assistant" {
+			m.Model = "claude-sonnet-4-20250514"
+			m.InputTokens = 1_000_000
+			m.OutputTokens = 100_000
+		}
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("usage", map[string]string{
+			"timezone": "UTC", "granularity": "week",
+		}))
+		assertStatus(t, w, http.StatusOK)
+
+		resp := decode[db.UsageAnalyticsResponse](t, w)
+		if resp.Granularity != "week" {
+			t.Errorf("Granularity = %q, want week", resp.Granularity)
+		}
+		if resp.Total.InputTokens != 1_000_000 || resp.Total.Cost != 4.5 {
+			t.Errorf("Total = %+v, want 1M input costing $4.50",
+				resp.Total)
+		}
+		if len(resp.ByModel) != 1 || len(resp.ByProject) != 1 ||
+			resp.ByProject[0].Label != "alpha" {
+			t.Errorf("breakdowns = %+v / %+v",
+				resp.ByModel, resp.ByProject)
+		}
+	})
+
+	t.Run("InvalidGranularity", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("usage", map[string]string{
+			"granularity": "hour",
+		}))
+		assertStatus(t, w, http.StatusBadRequest)
+	})
+}
+
+func TestAnalyticsCostPhases(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+
+	w := te.get(t, buildURLWithRange("cost-phases", map[string]string{
+		"timezone": "UTC",
+	}))
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[db.CostPhasesResponse](t, w)
+	if resp.Currency != db.BaseCurrency {
+		t.Errorf("Currency = %q, want %q", resp.Currency, db.BaseCurrency)
+	}
+	if len(resp.Phases) != 5 || resp.Projects == nil {
+		t.Errorf("resp = %+v, want 5 phases and non-nil Projects", resp)
+	}
+}
+
+func TestAnalyticsForecast(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+
+	w := te.get(t, buildURLWithRange("forecast", map[string]string{
+		"timezone": "UTC",
+	}))
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[db.ForecastResponse](t, w)
+	if resp.Projects == nil {
+		t.Error("expected non-nil Projects")
+	}
+	if resp.Month.Month == "" {
+		t.Error("expected month projection")
+	}
+	if resp.Overall.HistoryMessages == 0 {
+		t.Error("expected history messages in overall forecast")
+	}
+}
+
+func TestAnalyticsMonthToDate(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+
+	w := te.get(t, buildURL("month-to-date", map[string]string{
+		"to": "2024-06-02", "timezone": "UTC",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.MonthToDateResponse](t, w)
+	if resp.Month != "2024-06" || resp.Current.Sessions != 3 {
+		t.Errorf("resp = %+v, want 3 sessions in 2024-06", resp)
+	}
+	if resp.Previous.From != "2024-05-01" || resp.Previous.Sessions != 0 {
+		t.Errorf("Previous = %+v", resp.Previous)
+	}
+	if resp.Change.Sessions != nil {
+		t.Errorf("Change.Sessions = %v, want nil", *resp.Change.Sessions)
+	}
+
+	w = te.get(t, buildURL("month-to-date", map[string]string{
+		"to": "June",
+	}))
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
+func TestAnalyticsProgress(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+
+	t.Run("OK", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("progress", map[string]string{
+			"timezone": "UTC", "n": "5",
+		}))
+		assertStatus(t, w, http.StatusOK)
+
+		resp := decode[db.ProgressResponse](t, w)
+		if resp.N != 5 {
+			t.Errorf("N = %d, want 5", resp.N)
+		}
+		// Only alpha has two sessions to compare.
+		if len(resp.Projects) != 1 || resp.Projects[0].Project != "alpha" {
+			t.Fatalf("Projects = %+v, want alpha only", resp.Projects)
+		}
+		if resp.Projects[0].N != 1 {
+			t.Errorf("alpha N = %d, want 1", resp.Projects[0].N)
+		}
+	})
+
+	for _, n := range []string{"abc", "-1", "1000"} {
+		t.Run("InvalidN_"+n, func(t *testing.T) {
+			w := te.get(t, buildURLWithRange("progress", map[string]string{
+				"n": n,
+			}))
+			assertStatus(t, w, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestAnalyticsToolSequences(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+
+	t.Run("OK", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("tool-sequences", map[string]string{
+			"timezone": "UTC", "n": "2",
+		}))
+		assertStatus(t, w, http.StatusOK)
+
+		resp := decode[db.ToolSequencesResponse](t, w)
+		if resp.Length != 2 {
+			t.Errorf("Length = %d, want 2", resp.Length)
+		}
+		if resp.Agents == nil {
+			t.Error("Agents should not be nil")
+		}
+	})
+
+	for _, q := range []map[string]string{
+		{"n": "1"}, {"n": "9"}, {"n": "x"},
+		{"limit": "-1"}, {"limit": "1000"},
+	} {
+		t.Run(fmt.Sprintf("Invalid_%v", q), func(t *testing.T) {
+			w := te.get(t, buildURLWithRange("tool-sequences", q))
+			assertStatus(t, w, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestAnalyticsCommands(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+
+	t.Run("OK", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("commands", map[string]string{
+			"timezone": "UTC", "limit": "5",
+		}))
+		assertStatus(t, w, http.StatusOK)
+
+		resp := decode[db.CommandsAnalyticsResponse](t, w)
+		if resp.Commands == nil || resp.FailureProne == nil ||
+			resp.Projects == nil {
+			t.Errorf("lists should not be nil: %+v", resp)
+		}
+	})
+
+	for _, limit := range []string{"x", "-1", "1000"} {
+		t.Run("InvalidLimit_"+limit, func(t *testing.T) {
+			w := te.get(t, buildURLWithRange("commands", map[string]string{
+				"limit": limit,
+			}))
+			assertStatus(t, w, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestAnalyticsHooks(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+
+	w := te.get(t, buildURLWithRange("hooks", map[string]string{
+		"timezone": "UTC",
+	}))
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[db.HooksAnalyticsResponse](t, w)
+	if resp.ByEvent == nil || resp.ByHook == nil {
+		t.Error("expected non-nil ByEvent and ByHook")
+	}
+}
+
 func TestAnalyticsTools(t *testing.T) {
 	te := setup(t)
 	stats := seedAnalyticsEnv(t, te)
@@ -632,6 +1134,76 @@ func TestAnalyticsTools(t *testing.T) {
 	})
 }
 
+func TestAnalyticsIncludeSessionIDs(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+	withIDs := map[string]string{
+		"timezone": "UTC", "include_session_ids": "true",
+	}
+
+	t.Run("OmittedByDefault", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("summary", nil))
+		assertStatus(t, w, http.StatusOK)
+		if resp := decode[db.AnalyticsSummary](t, w); resp.SessionIDs != nil {
+			t.Errorf("SessionIDs = %+v, want omitted", resp.SessionIDs)
+		}
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("summary", withIDs))
+		assertStatus(t, w, http.StatusOK)
+		resp := decode[db.AnalyticsSummary](t, w)
+		if resp.SessionIDs == nil || resp.SessionIDs.Total != 3 {
+			t.Fatalf("SessionIDs = %+v, want 3 sessions", resp.SessionIDs)
+		}
+		codex := resp.Agents["codex"].SessionIDs
+		if codex == nil || len(codex.IDs) != 1 || codex.IDs[0] != "a2" {
+			t.Errorf("codex SessionIDs = %+v, want [a2]", codex)
+		}
+	})
+
+	t.Run("Tools", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("tools", withIDs))
+		assertStatus(t, w, http.StatusOK)
+		resp := decode[db.ToolsAnalyticsResponse](t, w)
+		if len(resp.ByCategory) == 0 {
+			t.Fatal("expected non-empty ByCategory")
+		}
+		// Sessions with several Read calls are listed once.
+		read := resp.ByCategory[0].SessionIDs
+		if read == nil || read.Total != 3 || len(read.IDs) != 3 {
+			t.Errorf("Read SessionIDs = %+v, want 3 sessions", read)
+		}
+		for _, e := range resp.Trend {
+			if e.SessionIDs["Read"] == nil {
+				t.Errorf("trend %s missing Read sessions", e.Date)
+			}
+		}
+	})
+
+	t.Run("Velocity", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("velocity", withIDs))
+		assertStatus(t, w, http.StatusOK)
+		resp := decode[db.VelocityResponse](t, w)
+		if resp.SessionIDs == nil || resp.SessionIDs.Total != 3 {
+			t.Errorf("SessionIDs = %+v, want 3 sessions", resp.SessionIDs)
+		}
+		for _, b := range resp.ByComplexity {
+			if b.SessionIDs == nil || b.SessionIDs.Total != b.Sessions {
+				t.Errorf("%s SessionIDs = %+v, want %d",
+					b.Label, b.SessionIDs, b.Sessions)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("summary", map[string]string{
+			"include_session_ids": "maybe",
+		}))
+		assertStatus(t, w, http.StatusBadRequest)
+	})
+}
+
 func TestAnalyticsTopSessions(t *testing.T) {
 	te := setup(t)
 	stats := seedAnalyticsEnv(t, te)
@@ -822,3 +1394,289 @@ func TestSessionCountConsistency(t *testing.T) {
 		)
 	}
 }
+
+func TestAnalyticsModelSwitches(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "alpha", 4)
+	te.seedMessages(t, "s1", 4, func(i int, m *db.Message) {
+		switch i {
+		case 1:
+			m.Model = "opus"
+		case 3:
+			m.Model = "sonnet"
+			m.ModelSwitch = db.ModelSwitchFallback
+		}
+	})
+
+	w := te.get(t, "/api/v1/sessions/s1/models")
+	assertStatus(t, w, http.StatusOK)
+	segments := decode[[]db.ModelSegment](t, w)
+	if len(segments) != 2 || segments[1].Reason != db.ModelSwitchFallback {
+		t.Errorf("segments = %+v", segments)
+	}
+
+	w = te.get(t, buildURL("model-switches", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.ModelSwitchesResponse](t, w)
+	if resp.Overall.Fallbacks != 1 || resp.Overall.FallbackRate != 100 {
+		t.Errorf("Overall = %+v", resp.Overall)
+	}
+	if len(resp.Transitions) != 1 || resp.Transitions[0].From != "opus" {
+		t.Errorf("Transitions = %+v", resp.Transitions)
+	}
+}
+
+func TestAnalyticsEditThrash(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "alpha", 8)
+	te.seedMessages(t, "s1", 8, func(i int, m *db.Message) {
+		m.HasToolUse = true
+		m.ToolCalls = []db.ToolCall{{
+			ToolName: "Edit", Category: "Edit",
+			InputJSON: `{"file_path":"/src/main.go"}`,
+		}}
+	})
+
+	w := te.get(t, buildURL("edit-thrash", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.EditThrashResponse](t, w)
+	if len(resp.Sessions) != 1 || resp.Sessions[0].Score != 3 {
+		t.Fatalf("Sessions = %+v, want s1 with score 3", resp.Sessions)
+	}
+	if len(resp.Projects) != 1 || resp.Projects[0].ThrashRate != 100 {
+		t.Errorf("Projects = %+v", resp.Projects)
+	}
+
+	w = te.get(t, buildURL("edit-thrash", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31", "threshold": "10",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp = decode[db.EditThrashResponse](t, w)
+	if len(resp.Sessions) != 0 {
+		t.Errorf("Sessions = %+v, want none at threshold 10", resp.Sessions)
+	}
+
+	w = te.get(t, buildURL("edit-thrash", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31", "threshold": "0x",
+	}))
+	assertStatus(t, w, http.StatusBadRequest)
+	w = te.get(t, buildURL("edit-thrash", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31", "threshold": "101",
+	}))
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
+func TestAnalyticsRetries(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "alpha", 2, func(s *db.Session) {
+		s.InterruptCount = 1
+	})
+	te.seedSession(t, "s2", "alpha", 2, func(s *db.Session) {
+		s.StartedAt = dbtest.Ptr("2025-01-15T11:05:00Z")
+		s.EndedAt = dbtest.Ptr("2025-01-15T11:30:00Z")
+	})
+
+	w := te.get(t, buildURL("retries", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.RetriesResponse](t, w)
+	if resp.RetrySessions != 1 || len(resp.Recent) != 1 ||
+		resp.Recent[0].PreviousID != "s1" {
+		t.Fatalf("resp = %+v, want s2 retrying s1", resp)
+	}
+
+	w = te.get(t, buildURL("retries", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31", "window": "2",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp = decode[db.RetriesResponse](t, w)
+	if resp.RetrySessions != 0 {
+		t.Errorf("RetrySessions = %d, want 0 in 2 min", resp.RetrySessions)
+	}
+
+	for _, window := range []string{"x", "-1", "241"} {
+		w = te.get(t, buildURL("retries", map[string]string{
+			"from": "2025-01-01", "to": "2025-01-31", "window": window,
+		}))
+		assertStatus(t, w, http.StatusBadRequest)
+	}
+}
+
+func TestAnalyticsBranches(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "alpha", 4, func(s *db.Session) {
+		s.GitBranch = "feature"
+		s.Worktree = "alpha-feature"
+	})
+	te.seedSession(t, "s2", "alpha", 2)
+
+	w := te.get(t, buildURL("branches", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31", "project": "alpha",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.BranchesAnalyticsResponse](t, w)
+	if len(resp.Branches) != 2 {
+		t.Fatalf("branches = %+v, want 2", resp.Branches)
+	}
+	if b := resp.Branches[0]; b.Branch != "feature" || b.Messages != 4 {
+		t.Errorf("first branch = %+v", b)
+	}
+}
+
+func TestAnalyticsEntryPoints(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "alpha", 4, func(s *db.Session) {
+		s.EntryPoint = "vscode"
+		s.Client = "claude-vscode"
+	})
+	te.seedSession(t, "s2", "alpha", 2)
+
+	w := te.get(t, buildURL("entry-points", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.EntryPointsAnalyticsResponse](t, w)
+	if len(resp.EntryPoints) != 2 {
+		t.Fatalf("entry points = %+v, want 2", resp.EntryPoints)
+	}
+	for _, e := range resp.EntryPoints {
+		if e.EntryPoint == "vscode" && e.Sessions != 1 {
+			t.Errorf("vscode = %+v", e)
+		}
+	}
+}
+
+func TestAnalyticsLanguages(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "alpha", 4)
+	te.seedSession(t, "s2", "alpha", 2)
+	if err := te.db.InsertMessages([]db.Message{{
+		SessionID: "s1", Role: "user", Content: "テストを直して",
+		Language: "ja",
+	}}); err != nil {
+		t.Fatalf("InsertMessages: %v", err)
+	}
+	if err := te.db.UpdateSessionLanguage("s1"); err != nil {
+		t.Fatalf("UpdateSessionLanguage: %v", err)
+	}
+
+	w := te.get(t, buildURL("languages", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.LanguagesAnalyticsResponse](t, w)
+	if len(resp.Languages) != 2 {
+		t.Fatalf("languages = %+v, want 2", resp.Languages)
+	}
+
+	w = te.get(t, buildURL("languages", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31", "language": "ja",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp = decode[db.LanguagesAnalyticsResponse](t, w)
+	if len(resp.Languages) != 1 || resp.Languages[0].Language != "ja" ||
+		resp.Languages[0].Sessions != 1 {
+		t.Errorf("ja languages = %+v, want s1 only", resp.Languages)
+	}
+
+	w = te.get(t, "/api/v1/sessions?language=ja")
+	assertStatus(t, w, http.StatusOK)
+	list := decode[sessionListResponse](t, w)
+	if len(list.Sessions) != 1 || list.Sessions[0].ID != "s1" {
+		t.Errorf("sessions = %+v, want only s1", list.Sessions)
+	}
+}
+
+func TestAnalyticsSkillUsage(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "alpha", 2)
+	if err := te.db.ReplaceSessionCommands("s1", []db.Command{
+		{Name: "review"}, {Name: "review"},
+	}); err != nil {
+		t.Fatalf("ReplaceSessionCommands: %v", err)
+	}
+
+	w := te.get(t, buildURL("skill-usage", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31",
+		"kind": "command", "name": "review",
+	}))
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.SkillUsage](t, w)
+	if resp.Invocations != 2 || resp.Sessions != 1 {
+		t.Errorf("usage = %+v, want 2 invocations in 1 session", resp)
+	}
+
+	w = te.get(t, buildURL("skill-usage", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31",
+	}))
+	assertStatus(t, w, http.StatusBadRequest)
+
+	w = te.get(t, buildURL("skill-usage", map[string]string{
+		"from": "2025-01-01", "to": "2025-01-31",
+		"kind": "plugin", "name": "review",
+	}))
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
+func TestAnalyticsProjectSettings(t *testing.T) {
+	te := setup(t, func(c *config.Config) {
+		c.Projects = map[string]config.ProjectSettings{
+			"alpha": {Currency: "EUR", RateMultiplier: 0.9},
+			"tokyo": {Timezone: "Asia/Tokyo"},
+		}
+	})
+	seedAnalyticsEnv(t, te)
+	// 2024-06-02 09:00 in Tokyo.
+	started := "2024-06-02T00:00:00Z"
+	te.seedSession(t, "t1", "tokyo", 2, func(s *db.Session) {
+		s.StartedAt = &started
+	})
+
+	t.Run("project timezone", func(t *testing.T) {
+		// In the request's timezone the session starts on
+		// 2024-06-01; in the project's it starts on 06-02.
+		w := te.get(t, buildURL("summary", map[string]string{
+			"from": "2024-06-02", "to": "2024-06-02",
+			"timezone": "America/New_York", "project": "tokyo",
+		}))
+		assertStatus(t, w, http.StatusOK)
+		if got := decode[db.AnalyticsSummary](t, w).TotalSessions; got != 1 {
+			t.Errorf("TotalSessions = %d, want 1", got)
+		}
+	})
+
+	t.Run("forecast currencies", func(t *testing.T) {
+		w := te.get(t, buildURLWithRange("forecast", map[string]string{
+			"timezone": "UTC",
+		}))
+		assertStatus(t, w, http.StatusOK)
+		resp := decode[db.ForecastResponse](t, w)
+		if resp.Currency != "USD" || resp.Overall.Currency != "USD" {
+			t.Errorf("overall currency = %q/%q, want USD",
+				resp.Currency, resp.Overall.Currency)
+		}
+		for _, p := range resp.Projects {
+			want := "USD"
+			if p.Project == "alpha" {
+				want = "EUR"
+			}
+			if p.Currency != want {
+				t.Errorf("%s currency = %q, want %q",
+					p.Project, p.Currency, want)
+			}
+		}
+
+		w = te.get(t, buildURLWithRange("forecast", map[string]string{
+			"timezone": "UTC", "project": "alpha",
+		}))
+		assertStatus(t, w, http.StatusOK)
+		if got := decode[db.ForecastResponse](t, w).Currency; got != "EUR" {
+			t.Errorf("scoped currency = %q, want EUR", got)
+		}
+	})
+}
@@ -0,0 +1,312 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// bulkFilter selects sessions for a bulk action. Fields mirror
+// the session list query parameters; Query additionally
+// restricts to sessions with a matching message.
+type bulkFilter struct {
+	Project         string `json:"project"`
+	ExcludeProject  string `json:"exclude_project"`
+	Machine         string `json:"machine"`
+	Agent           string `json:"agent"`
+	Date            string `json:"date"`
+	DateFrom        string `json:"date_from"`
+	DateTo          string `json:"date_to"`
+	ActiveSince     string `json:"active_since"`
+	StartedAfter    string `json:"started_after"`
+	StartedBefore   string `json:"started_before"`
+	EndedAfter      string `json:"ended_after"`
+	EndedBefore     string `json:"ended_before"`
+	MinMessages     int    `json:"min_messages"`
+	MaxMessages     int    `json:"max_messages"`
+	MinUserMessages int    `json:"min_user_messages"`
+	Tag             string `json:"tag"`
+	IncludeArchived bool   `json:"include_archived"`
+	Pinned          bool   `json:"pinned"`
+	Archived        bool   `json:"archived"`
+	Query           string `json:"q"`
+}
+
+type bulkRequest struct {
+	Action string     `json:"action"`
+	Tag    string     `json:"tag"`
+	Filter bulkFilter `json:"filter"`
+	// Token confirms a previewed selection. Omit it to get a
+	// count preview and a token to send back.
+	Token string `json:"token"`
+}
+
+type bulkResponse struct {
+	Action  string `json:"action"`
+	Tag     string `json:"tag,omitempty"`
+	Count   int    `json:"count"`
+	Applied bool   `json:"applied"`
+	Token   string `json:"token,omitempty"`
+}
+
+// handleBulkSessions applies a curation action to every
+// session matching a filter. It is a two-step operation: a
+// request without a token returns the match count and a
+// confirmation token; resending with that token applies the
+// action, provided the selection has not changed since.
+func (s *Server) handleBulkSessions(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var req bulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	action := db.BulkAction{
+		Action: req.Action,
+		Tag:    strings.TrimSpace(req.Tag),
+	}
+	if err := action.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	f := req.Filter
+	for _, d := range []string{f.Date, f.DateFrom, f.DateTo} {
+		if d != "" && !isValidDate(d) {
+			writeError(w, http.StatusBadRequest,
+				"invalid date format: use YYYY-MM-DD")
+			return
+		}
+	}
+	if f.ActiveSince != "" && !isValidTimestamp(f.ActiveSince) {
+		writeError(w, http.StatusBadRequest,
+			"invalid active_since: use RFC3339 timestamp")
+		return
+	}
+	for _, b := range []struct {
+		name string
+		v    *string
+	}{
+		{"started_after", &f.StartedAfter},
+		{"started_before", &f.StartedBefore},
+		{"ended_after", &f.EndedAfter},
+		{"ended_before", &f.EndedBefore},
+	} {
+		v, ok := normalizeTimestamp(*b.v)
+		if !ok {
+			writeError(w, http.StatusBadRequest,
+				"invalid "+b.name+": use RFC3339 timestamp")
+			return
+		}
+		*b.v = v
+	}
+
+	query := strings.TrimSpace(f.Query)
+	if query != "" {
+		if !s.db.HasFTS() {
+			writeError(w, http.StatusNotImplemented,
+				"search not available")
+			return
+		}
+		query = prepareFTSQuery(query)
+	}
+
+	filter := db.SessionFilter{
+		Project:         f.Project,
+		ExcludeProject:  f.ExcludeProject,
+		Machine:         f.Machine,
+		Agent:           f.Agent,
+		Date:            f.Date,
+		DateFrom:        f.DateFrom,
+		DateTo:          f.DateTo,
+		ActiveSince:     f.ActiveSince,
+		StartedAfter:    f.StartedAfter,
+		StartedBefore:   f.StartedBefore,
+		EndedAfter:      f.EndedAfter,
+		EndedBefore:     f.EndedBefore,
+		MinMessages:     f.MinMessages,
+		MaxMessages:     f.MaxMessages,
+		MinUserMessages: f.MinUserMessages,
+		Tag:             f.Tag,
+		// Archived sessions are hidden by default, so
+		// unarchiving must be able to see them.
+		IncludeArchived: f.IncludeArchived ||
+			action.Action == db.BulkActionUnarchive,
+		Pinned:   f.Pinned,
+		Archived: f.Archived,
+	}
+
+	ids, err := s.db.MatchSessionIDs(r.Context(), filter, query)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		if errors.Is(err, db.ErrBulkTooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge,
+				err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := bulkResponse{
+		Action: action.Action,
+		Tag:    action.Tag,
+		Count:  len(ids),
+	}
+	if req.Token == "" {
+		resp.Token = s.db.BulkToken(action, ids)
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	if err := s.db.CheckBulkToken(action, ids, req.Token); err != nil {
+		writeError(w, http.StatusConflict,
+			"selection changed since preview; request a new token")
+		return
+	}
+	if err := s.db.ApplyBulkAction(r.Context(), action, ids); err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Applied = true
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSessionFlag returns a handler that applies set to a
+// session on PUT and clear on DELETE, reporting the flag under
+// name. Pinned sessions are listed first; archived ones are
+// hidden from listings and analytics.
+func (s *Server) handleSessionFlag(
+	name, set, clear string,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.setSessionFlag(w, r, name, set, clear)
+	}
+}
+
+func (s *Server) setSessionFlag(
+	w http.ResponseWriter, r *http.Request, name, set, clear string,
+) {
+	id := r.PathValue("id")
+	session, err := s.db.GetSession(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+
+	on := r.Method == http.MethodPut
+	action := db.BulkAction{Action: clear}
+	if on {
+		action.Action = set
+	}
+	if err := s.db.ApplyBulkAction(
+		r.Context(), action, []string{id},
+	); err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id": id, name: on,
+	})
+}
+
+// handleSessionTags lists a session's tags on GET, adds the
+// body's tag on POST, and removes the tag query parameter's tag
+// on DELETE. Every method responds with the resulting tags.
+func (s *Server) handleSessionTags(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id := r.PathValue("id")
+	session, err := s.db.GetSession(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+
+	var action db.BulkAction
+	switch r.Method {
+	case http.MethodPost:
+		var req struct {
+			Tag string `json:"tag"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+		action = db.BulkAction{Action: db.BulkActionTag, Tag: req.Tag}
+	case http.MethodDelete:
+		action = db.BulkAction{
+			Action: db.BulkActionUntag, Tag: r.URL.Query().Get("tag"),
+		}
+	}
+	if action.Action != "" {
+		if err := action.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := s.db.ApplyBulkAction(
+			r.Context(), action, []string{id},
+		); err != nil {
+			if handleContextError(w, err) {
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	tags, err := s.db.GetSessionTags(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id": id, "tags": tags,
+	})
+}
+
+// handleListTags returns every tag in use with its session
+// count, for tag pickers and dashboard filters.
+func (s *Server) handleListTags(
+	w http.ResponseWriter, r *http.Request,
+) {
+	tags, err := s.db.ListTags(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"br, gzip, deflate", true},
+		{"gzip;q=0.5", true},
+		{"gzip;q=0", false},
+		{"*", true},
+		{"deflate, br", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsGzip(tt.header); got != tt.want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v",
+				tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompressMiddleware(t *testing.T) {
+	t.Parallel()
+
+	large := `{"text":"` + strings.Repeat("abc", minCompressSize) + `"}`
+	handler := compressMiddleware(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/large":
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Content-Length", "999")
+				io.WriteString(w, large)
+			case "/small":
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{"ok":true}`)
+			case "/binary":
+				w.Header().Set("Content-Type", "application/zip")
+				io.WriteString(w, large)
+			case "/stream":
+				stream, err := NewSSEStream(w)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				stream.Send("done", large)
+			}
+		},
+	))
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/large", "gzip, br")
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := w.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length = %q, want none", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary = %q, want Accept-Encoding", got)
+	}
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	body, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("decompressing: %v", err)
+	}
+	if string(body) != large {
+		t.Errorf("decompressed body differs (%d bytes)", len(body))
+	}
+
+	for _, tt := range []struct{ name, path, enc string }{
+		{"not accepted", "/large", ""},
+		{"refused", "/large", "gzip;q=0"},
+		{"small", "/small", "gzip"},
+		{"binary", "/binary", "gzip"},
+		{"event stream", "/stream", "gzip"},
+	} {
+		w := get(tt.path, tt.enc)
+		if got := w.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("%s: Content-Encoding = %q, want none",
+				tt.name, got)
+		}
+		if w.Body.Len() == 0 {
+			t.Errorf("%s: empty body", tt.name)
+		}
+	}
+}
+
+func TestServerSpeaksH2C(t *testing.T) {
+	s := testServer(t, 5*time.Second)
+	srv := s.newHTTPServer()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{
+		Transport: &http.Transport{Protocols: &protocols},
+	}
+	resp, err := client.Get("http://" + ln.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("h2c request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Errorf("protocol = %s, want HTTP/2", resp.Proto)
+	}
+}
@@ -22,6 +22,7 @@ func TestMiddleware_Timeout(t *testing.T) {
 		{"GetSession", http.MethodGet, "/api/v1/sessions/s1"},
 		{"GetMessages", http.MethodGet, "/api/v1/sessions/s1/messages"},
 		{"GetMinimap", http.MethodGet, "/api/v1/sessions/s1/minimap"},
+		{"GetMessageContext", http.MethodGet, "/api/v1/sessions/s1/context?ordinal=5"},
 		{"GetStats", http.MethodGet, "/api/v1/stats"},
 		{"ListProjects", http.MethodGet, "/api/v1/projects"},
 		{"ListMachines", http.MethodGet, "/api/v1/machines"},
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"net/http"
+)
+
+// handleGetSessionEnv returns the session's allowlisted
+// environment snapshot as a key/value object.
+func (s *Server) handleGetSessionEnv(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id := r.PathValue("id")
+	env, err := s.db.GetSessionEnv(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, env)
+}
+
+// handleListEnvValues lists the recorded environment settings
+// with session counts, the choices for the sessions env filter.
+func (s *Server) handleListEnvValues(
+	w http.ResponseWriter, r *http.Request,
+) {
+	values, err := s.db.GetEnvValues(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"values": values,
+	})
+}
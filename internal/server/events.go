@@ -3,7 +3,7 @@ package server
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"syscall"
@@ -86,7 +86,7 @@ func (s *Server) checkAndSync(
 	}
 	*lastMtime = info.ModTime().UnixNano()
 	if err := s.engine.SyncSingleSession(sessionID); err != nil {
-		log.Printf("watch sync error: %v", err)
+		slog.Warn("watch sync error", "session", sessionID, "err", err)
 		return false
 	}
 	return true
@@ -118,7 +118,7 @@ func (s *Server) syncIfModified(
 	}
 	*lastMtime = mtime
 	if err := s.engine.SyncSingleSession(sessionID); err != nil {
-		log.Printf("watch sync error: %v", err)
+		slog.Warn("watch sync error", "session", sessionID, "err", err)
 		return false
 	}
 	return true
@@ -225,6 +225,10 @@ func (s *Server) handleGetStats(
 func (s *Server) handleListProjects(
 	w http.ResponseWriter, r *http.Request,
 ) {
+	since, ok := s.activeProjectsSince(w, r)
+	if !ok {
+		return
+	}
 	projects, err := s.db.GetProjects(r.Context())
 	if err != nil {
 		if handleContextError(w, err) {
@@ -233,24 +237,21 @@ func (s *Server) handleListProjects(
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{
-		"projects": projects,
-	})
-}
-
-func (s *Server) handleListMachines(
-	w http.ResponseWriter, r *http.Request,
-) {
-	machines, err := s.db.GetMachines(r.Context())
-	if err != nil {
-		if handleContextError(w, err) {
-			return
+	inactive := 0
+	if since != "" {
+		active := projects[:0]
+		for _, p := range projects {
+			if p.LastActiveAt >= since {
+				active = append(active, p)
+			} else {
+				inactive++
+			}
 		}
-		writeError(w, http.StatusInternalServerError, err.Error())
-		return
+		projects = active
 	}
 	writeJSON(w, http.StatusOK, map[string]any{
-		"machines": machines,
+		"projects":       projects,
+		"inactive_count": inactive,
 	})
 }
 
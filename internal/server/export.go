@@ -7,6 +7,7 @@ import (
 	"html"
 	"html/template"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
@@ -42,25 +43,80 @@ func (s *Server) getSessionWithMessages(
 	return session, msgs, true
 }
 
+// parseExportFormat reads the format query parameter, which
+// defaults to HTML.
+func parseExportFormat(
+	w http.ResponseWriter, r *http.Request,
+) (string, bool) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		return ExportHTML, true
+	}
+	if !ValidExportFormat(format) {
+		writeError(w, http.StatusBadRequest,
+			"format must be one of: "+strings.Join(ExportFormats, ", "))
+		return "", false
+	}
+	return format, true
+}
+
 func (s *Server) handleExportSession(
 	w http.ResponseWriter, r *http.Request,
 ) {
+	format, ok := parseExportFormat(w, r)
+	if !ok {
+		return
+	}
 	session, msgs, ok := s.getSessionWithMessages(w, r)
 	if !ok {
 		return
 	}
 
-	htmlContent := generateExportHTML(session, msgs)
-	filename := sanitizeFilename(
-		session.Project + "-" + formatDateShort(session.StartedAt) + ".html",
+	w.Header().Set("Content-Type", exportContentType(format))
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="%s"`,
+			ExportFilename(session, format)),
+	)
+	_ = WriteExport(w, format, session, msgs)
+}
+
+// handleExportProject streams a zip archive of every session in
+// a project.
+func (s *Server) handleExportProject(
+	w http.ResponseWriter, r *http.Request,
+) {
+	format, ok := parseExportFormat(w, r)
+	if !ok {
+		return
+	}
+	project := r.PathValue("project")
+	ids, err := s.db.MatchSessionIDs(
+		r.Context(), db.SessionFilter{Project: project}, "",
 	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(ids) == 0 {
+		writeError(w, http.StatusNotFound, "no sessions in project")
+		return
+	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Type", "application/zip")
 	w.Header().Set(
 		"Content-Disposition",
-		fmt.Sprintf(`attachment; filename="%s"`, filename),
+		fmt.Sprintf(`attachment; filename="%s"`,
+			sanitizeFilename(project+"-"+format+".zip")),
 	)
-	_, _ = io.WriteString(w, htmlContent)
+	// Headers are sent with the first file, so a failure
+	// part-way can only truncate the archive.
+	if _, err := WriteProjectExport(
+		r.Context(), s.db, w, project, format,
+	); err != nil {
+		slog.Error("project export failed",
+			"project", project, "err", err)
+	}
 }
 
 func (s *Server) handlePublishSession(
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// Session export formats.
+const (
+	ExportHTML     = "html"
+	ExportMarkdown = "md"
+	ExportJSON     = "json"
+)
+
+// ExportFormats lists the accepted export formats.
+var ExportFormats = []string{ExportHTML, ExportMarkdown, ExportJSON}
+
+// ValidExportFormat reports whether format is an export format.
+func ValidExportFormat(format string) bool {
+	switch format {
+	case ExportHTML, ExportMarkdown, ExportJSON:
+		return true
+	}
+	return false
+}
+
+// exportContentType is the Content-Type served for format.
+func exportContentType(format string) string {
+	switch format {
+	case ExportMarkdown:
+		return "text/markdown; charset=utf-8"
+	case ExportJSON:
+		return "application/json"
+	default:
+		return "text/html; charset=utf-8"
+	}
+}
+
+// ExportFilename is the file name for a session exported in
+// format: its project and start date.
+func ExportFilename(session *db.Session, format string) string {
+	return sanitizeFilename(
+		session.Project + "-" + formatDateShort(session.StartedAt) +
+			"." + format,
+	)
+}
+
+// sessionExport is the document written by the JSON format.
+type sessionExport struct {
+	Session  *db.Session  `json:"session"`
+	Messages []db.Message `json:"messages"`
+}
+
+// WriteExport renders a session transcript, with its tool calls
+// and thinking blocks, in format.
+func WriteExport(
+	w io.Writer, format string,
+	session *db.Session, msgs []db.Message,
+) error {
+	switch format {
+	case ExportHTML:
+		_, err := io.WriteString(w, generateExportHTML(session, msgs))
+		return err
+	case ExportMarkdown:
+		_, err := io.WriteString(w, generateExportMarkdown(session, msgs))
+		return err
+	case ExportJSON:
+		if msgs == nil {
+			msgs = []db.Message{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sessionExport{Session: session, Messages: msgs})
+	}
+	return fmt.Errorf("unsupported export format %q", format)
+}
+
+// WriteProjectExport writes a zip archive to w holding every
+// session in project, one file per session in format, and
+// returns the number of sessions written.
+func WriteProjectExport(
+	ctx context.Context, d *db.DB, w io.Writer,
+	project, format string,
+) (int, error) {
+	ids, err := d.MatchSessionIDs(
+		ctx, db.SessionFilter{Project: project}, "",
+	)
+	if err != nil {
+		return 0, err
+	}
+	zw := zip.NewWriter(w)
+	n := 0
+	for _, id := range ids {
+		session, err := d.GetSession(ctx, id)
+		if err != nil {
+			return n, err
+		}
+		if session == nil {
+			continue
+		}
+		msgs, err := d.GetAllMessages(ctx, id)
+		if err != nil {
+			return n, err
+		}
+		f, err := zw.Create(ProjectExportFilename(session, format))
+		if err != nil {
+			return n, err
+		}
+		if err := WriteExport(f, format, session, msgs); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, zw.Close()
+}
+
+// ProjectExportFilename names a session within a project export.
+// Unlike ExportFilename it includes the session ID, since one
+// project can have several sessions on a day.
+func ProjectExportFilename(session *db.Session, format string) string {
+	return sanitizeFilename(
+		formatDateShort(session.StartedAt) + "-" + session.ID +
+			"." + format,
+	)
+}
+
+func generateExportMarkdown(
+	session *db.Session, msgs []db.Message,
+) string {
+	agentDisplay := string(session.Agent)
+	if def, ok := parser.AgentByType(
+		parser.AgentType(session.Agent),
+	); ok {
+		agentDisplay = def.DisplayName
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", session.Project)
+	fmt.Fprintf(&b, "- Agent: %s\n", agentDisplay)
+	fmt.Fprintf(&b, "- Messages: %d\n", session.MessageCount)
+	if session.StartedAt != nil {
+		fmt.Fprintf(&b, "- Started: %s\n",
+			formatTimestamp(*session.StartedAt))
+	}
+	fmt.Fprintf(&b, "- Session: `%s`\n", session.ID)
+
+	for _, m := range msgs {
+		role := m.Role
+		if role != "" {
+			role = strings.ToUpper(role[:1]) + role[1:]
+		}
+		fmt.Fprintf(&b, "\n## %s", role)
+		if ts := formatTimestamp(m.Timestamp); ts != "" {
+			fmt.Fprintf(&b, " · %s", ts)
+		}
+		b.WriteString("\n\n")
+		b.WriteString(strings.TrimSpace(
+			formatContentForMarkdown(m.Content),
+		))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// thinkingMarkdown folds thinking blocks, which are long and
+// rarely the point of a shared transcript.
+const thinkingMarkdown = "\n<details><summary>Thinking</summary>\n\n" +
+	"$1\n\n</details>\n"
+
+// formatContentForMarkdown keeps message text as written, since
+// agents already answer in markdown, and folds thinking blocks.
+// Tool blocks stay inline as "[Tool ...]" lines.
+func formatContentForMarkdown(text string) string {
+	s := thinkingMarkedRe.ReplaceAllString(text, thinkingMarkdown)
+	return thinkingLegacyRe.ReplaceAllStringFunc(s, func(m string) string {
+		// Keep the terminator the legacy pattern consumes, which
+		// may open the next tool block.
+		tail := ""
+		for _, t := range []string{"\n[", "\n\n"} {
+			if strings.HasSuffix(m, t) {
+				tail = t
+				break
+			}
+		}
+		body := thinkingLegacyRe.FindStringSubmatch(m)[1]
+		return strings.Replace(thinkingMarkdown, "$1", body, 1) + tail
+	})
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+type recentFilesResponse struct {
+	Files []db.RecentFile `json:"files"`
+}
+
+func (s *Server) handleRecentFiles(
+	w http.ResponseWriter, r *http.Request,
+) {
+	q := r.URL.Query()
+
+	limit, ok := parseIntParam(w, r, "limit")
+	if !ok {
+		return
+	}
+	limit = clampLimit(
+		limit, db.DefaultRecentFilesLimit, db.MaxRecentFilesLimit,
+	)
+	touches, ok := parseIntParam(w, r, "touches")
+	if !ok {
+		return
+	}
+	touches = clampLimit(
+		touches, db.DefaultFileTouchLimit, db.MaxFileTouchLimit,
+	)
+
+	files, err := s.db.GetRecentFiles(r.Context(), db.RecentFilesFilter{
+		Project:    q.Get("project"),
+		Path:       q.Get("path"),
+		Limit:      limit,
+		TouchLimit: touches,
+	})
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, recentFilesResponse{Files: files})
+}
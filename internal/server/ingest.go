@@ -0,0 +1,151 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+const (
+	// ingestPathPrefix covers the routes other agentsview
+	// instances push sessions to. They authenticate with a
+	// bearer token instead of the browser origin checks.
+	ingestPathPrefix = "/api/v1/ingest"
+	// maxIngestBytes bounds one push request body.
+	maxIngestBytes = 256 << 20
+)
+
+// IngestCheckRequest lists the sources of sessions a client
+// could push.
+type IngestCheckRequest struct {
+	Sessions []db.SessionSource `json:"sessions"`
+}
+
+// IngestCheckResponse names the sessions the server would
+// store, so clients only send those.
+type IngestCheckResponse struct {
+	Needed []string `json:"needed"`
+}
+
+// IngestRequest is a batch of pushed sessions.
+type IngestRequest struct {
+	Sessions []db.IngestSession `json:"sessions"`
+}
+
+// IngestResult is the outcome for one pushed session: one of
+// db.IngestStored, db.IngestUnchanged, or db.IngestStale.
+type IngestResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// IngestResponse reports each pushed session's outcome.
+type IngestResponse struct {
+	Results []IngestResult `json:"results"`
+}
+
+// authorizeIngest checks the request's bearer token against
+// the configured ingest token, writing an error when ingest is
+// disabled or the token is wrong.
+func (s *Server) authorizeIngest(
+	w http.ResponseWriter, r *http.Request,
+) bool {
+	if s.cfg.IngestToken == "" {
+		writeError(w, http.StatusForbidden,
+			"ingest is disabled; set ingest_token in config")
+		return false
+	}
+	token, ok := strings.CutPrefix(
+		r.Header.Get("Authorization"), "Bearer ",
+	)
+	if !ok || subtle.ConstantTimeCompare(
+		[]byte(token), []byte(s.cfg.IngestToken),
+	) != 1 {
+		writeError(w, http.StatusUnauthorized, "invalid ingest token")
+		return false
+	}
+	return true
+}
+
+// decodeIngestBody authorizes the request and decodes its
+// size-limited JSON body into v.
+func (s *Server) decodeIngestBody(
+	w http.ResponseWriter, r *http.Request, v any,
+) bool {
+	if !s.authorizeIngest(w, r) {
+		return false
+	}
+	body := http.MaxBytesReader(w, r.Body, maxIngestBytes)
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleIngestCheck(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var req IngestCheckRequest
+	if !s.decodeIngestBody(w, r, &req) {
+		return
+	}
+	resp := IngestCheckResponse{Needed: []string{}}
+	for _, src := range req.Sessions {
+		status, err := s.db.IngestDecision(r.Context(), src)
+		if err != nil {
+			if handleContextError(w, err) {
+				return
+			}
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if status == db.IngestStored {
+			resp.Needed = append(resp.Needed, src.ID)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleIngest stores sessions pushed from another machine.
+// A session whose stored copy has the same file hash is left
+// alone, as is one stored from a newer version of its file.
+func (s *Server) handleIngest(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var req IngestRequest
+	if !s.decodeIngestBody(w, r, &req) {
+		return
+	}
+	for _, in := range req.Sessions {
+		if in.Session.ID == "" {
+			writeError(w, http.StatusBadRequest, "session id required")
+			return
+		}
+	}
+
+	resp := IngestResponse{Results: make([]IngestResult, 0, len(req.Sessions))}
+	for _, in := range req.Sessions {
+		if in.Session.Machine == "" {
+			in.Session.Machine = "remote"
+		}
+		status, err := s.db.Ingest(r.Context(), in)
+		if err != nil {
+			if handleContextError(w, err) {
+				return
+			}
+			slog.Error("ingesting session",
+				"session", in.Session.ID, "err", err)
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		resp.Results = append(resp.Results, IngestResult{
+			ID: in.Session.ID, Status: status,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
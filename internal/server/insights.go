@@ -4,7 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -204,7 +204,7 @@ func (s *Server) handleGenerateInsight(
 		},
 	)
 	if err != nil {
-		log.Printf("insight prompt error: %v", err)
+		slog.Error("insight prompt error", "err", err)
 		sendJSON("error", map[string]string{
 			"message": "failed to build prompt",
 		})
@@ -277,9 +277,9 @@ func (s *Server) handleGenerateInsight(
 		case <-logDone:
 			return dropped, true, true, false
 		case <-time.After(logDrainTimeout):
-			log.Printf(
-				"insight log stream drain timed out after %s",
-				logDrainTimeout,
+			slog.Warn(
+				"insight log stream drain timed out",
+				"timeout", logDrainTimeout,
 			)
 			// Count remaining buffered events as dropped since they will
 			// not be delivered once we abort the stream.
@@ -289,9 +289,9 @@ func (s *Server) handleGenerateInsight(
 			case <-logDone:
 				return dropped, false, true, true
 			case <-time.After(logStopWaitTimeout):
-				log.Printf(
-					"insight log sender stop timed out after %s",
-					logStopWaitTimeout,
+				slog.Warn(
+					"insight log sender stop timed out",
+					"timeout", logStopWaitTimeout,
 				)
 				// Try to force-unblock any in-flight writer and wait one
 				// more bounded interval for sender shutdown.
@@ -300,7 +300,7 @@ func (s *Server) handleGenerateInsight(
 				case <-logDone:
 					return dropped, false, true, true
 				case <-time.After(logStopWaitTimeout):
-					log.Printf(
+					slog.Warn(
 						"insight log sender did not stop after forced deadline",
 					)
 					return dropped, false, false, true
@@ -316,7 +316,7 @@ func (s *Server) handleGenerateInsight(
 	dropped, drained, senderStopped, timedOut := finishLogStream()
 	if !senderStopped {
 		stream.ForceWriteDeadlineNow()
-		log.Printf("insight log stream sender did not stop; aborting terminal SSE events")
+		slog.Warn("insight log stream sender did not stop; aborting terminal SSE events")
 		return
 	}
 	if dropped > 0 {
@@ -332,14 +332,14 @@ func (s *Server) handleGenerateInsight(
 		})
 	}
 	if timedOut || !drained {
-		log.Printf("insight log stream did not fully drain before completion")
+		slog.Warn("insight log stream did not fully drain before completion")
 		sendJSON("error", map[string]string{
 			"message": "insight log stream timed out before completion",
 		})
 		return
 	}
 	if err != nil {
-		log.Printf("insight generate error: %v", err)
+		slog.Error("insight generate error", "err", err)
 		sendJSON("error", map[string]string{
 			"message": insightGenerateClientMessage(req.Agent),
 		})
@@ -377,7 +377,7 @@ func (s *Server) handleGenerateInsight(
 		Content:  result.Content,
 	})
 	if err != nil {
-		log.Printf("insight insert error: %v", err)
+		slog.Error("insight insert error", "err", err)
 		sendJSON("error", map[string]string{
 			"message": "failed to save insight",
 		})
@@ -386,8 +386,7 @@ func (s *Server) handleGenerateInsight(
 
 	saved, err := s.db.GetInsight(r.Context(), id)
 	if err != nil || saved == nil {
-		log.Printf("insight get error: id=%d err=%v",
-			id, err)
+		slog.Error("insight get error", "id", id, "err", err)
 		sendJSON("error", map[string]string{
 			"message": "failed to retrieve saved insight",
 		})
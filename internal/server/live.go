@@ -0,0 +1,163 @@
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// activeWindow is how recently a session must have had a
+// message to be listed as active.
+const activeWindow = 10 * time.Minute
+
+// errStreamClosed stops a message scan once the client is gone.
+var errStreamClosed = errors.New("stream closed")
+
+// liveMessages is the payload of a "messages" stream event.
+type liveMessages struct {
+	SessionID string       `json:"session_id"`
+	Messages  []db.Message `json:"messages"`
+}
+
+// handleActiveSessions lists sessions with activity within the
+// window query parameter (a duration, default 10m), most
+// recently active first.
+func (s *Server) handleActiveSessions(
+	w http.ResponseWriter, r *http.Request,
+) {
+	window := activeWindow
+	if v := r.URL.Query().Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest,
+				"invalid window: use a positive duration such as 15m")
+			return
+		}
+		window = d
+	}
+	since := time.Now().Add(-window).UTC().Format(time.RFC3339)
+
+	page, err := s.db.ListSessions(r.Context(), db.SessionFilter{
+		ActiveSince:     since,
+		IncludeArchived: true,
+		Limit:           db.MaxSessionLimit,
+	})
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sessions": page.Sessions,
+		"since":    since,
+		"count":    len(page.Sessions),
+	})
+}
+
+// handleStreamSession tails a session over SSE. It sends a
+// "session" event with the session's metadata, then "messages"
+// events carrying messages from the from ordinal onwards
+// (default: only messages added after connecting), repeating
+// both each time the file watcher syncs new content. If the
+// session shrinks below what was sent, a "reset" event tells
+// the client to reload it and streaming restarts at ordinal 0.
+func (s *Server) handleStreamSession(
+	w http.ResponseWriter, r *http.Request,
+) {
+	ctx := r.Context()
+	sessionID := r.PathValue("id")
+	session, err := s.db.GetSession(ctx, sessionID)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	next := session.MessageCount
+	if r.URL.Query().Get("from") != "" {
+		from, ok := parseIntParam(w, r, "from")
+		if !ok {
+			return
+		}
+		next = max(from, 0)
+	}
+
+	stream, err := NewSSEStream(w)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError,
+			"streaming not supported")
+		return
+	}
+
+	// sendNew writes the session and any messages from next
+	// onwards, advancing next. Returns false once the client
+	// is gone or the session cannot be read.
+	sendNew := func(session *db.Session) bool {
+		if session.MessageCount < next {
+			if !stream.Send("reset", sessionID) {
+				return false
+			}
+			next = 0
+		}
+		if !stream.SendJSON("session", session) {
+			return false
+		}
+		err := s.db.StreamMessages(ctx, sessionID, next,
+			func(msgs []db.Message) error {
+				if !stream.SendJSON("messages", liveMessages{
+					SessionID: sessionID, Messages: msgs,
+				}) {
+					return errStreamClosed
+				}
+				next = msgs[len(msgs)-1].Ordinal + 1
+				return nil
+			})
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, errStreamClosed) {
+				slog.Warn("live stream read failed",
+					"session", sessionID, "err", err)
+			}
+			return false
+		}
+		return true
+	}
+	if !sendNew(session) {
+		return
+	}
+
+	updates := s.sessionMonitor(ctx, sessionID)
+	heartbeat := time.NewTicker(pollInterval * heartbeatTicks)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-updates:
+			if !ok {
+				return
+			}
+			session, err := s.db.GetSession(ctx, sessionID)
+			if err != nil || session == nil {
+				// Removed or unreadable; the client can reload.
+				stream.Send("reset", sessionID)
+				return
+			}
+			if !sendNew(session) {
+				return
+			}
+		case <-heartbeat.C:
+			stream.Send("heartbeat", time.Now().Format(time.RFC3339))
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/wesm/agentsview/internal/locale"
+)
+
+// localeResponse reports the formatting conventions the UI and
+// exports should use. Configured is false when the defaults
+// apply, so the UI may keep the browser's own locale.
+type localeResponse struct {
+	Configured bool `json:"configured"`
+	locale.Format
+}
+
+func (s *Server) handleGetLocale(
+	w http.ResponseWriter, _ *http.Request,
+) {
+	writeJSON(w, http.StatusOK, localeResponse{
+		Configured: s.cfg.Locale.Tag != "",
+		Format:     s.cfg.LocaleFormat(),
+	})
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/wesm/agentsview/internal/logging"
+)
+
+type logsResponse struct {
+	Entries []logging.Entry `json:"entries"`
+}
+
+// handleLogs returns recent structured records from the debug
+// log. The since parameter accepts an RFC 3339 timestamp or a
+// duration relative to now (e.g. "15m").
+func (s *Server) handleLogs(
+	w http.ResponseWriter, r *http.Request,
+) {
+	q := r.URL.Query()
+
+	level := slog.LevelWarn
+	if v := q.Get("level"); v != "" {
+		var err error
+		if level, err = logging.ParseLevel(v); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			d, derr := time.ParseDuration(v)
+			if derr != nil || d < 0 {
+				writeError(w, http.StatusBadRequest,
+					"invalid since: use RFC 3339 or a duration")
+				return
+			}
+			t = time.Now().Add(-d)
+		}
+		since = t
+	}
+
+	limit, ok := parseIntParam(w, r, "limit")
+	if !ok {
+		return
+	}
+	limit = clampLimit(limit, logging.DefaultLimit, logging.MaxLimit)
+
+	entries, err := logging.ReadEntries(
+		filepath.Join(s.cfg.DataDir, logging.FileName),
+		logging.Filter{MinLevel: level, Since: since, Limit: limit},
+	)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, logsResponse{Entries: entries})
+}
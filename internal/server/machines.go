@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// handleListMachines lists machine names for filters, the
+// machines labeled as bots, and per-machine details (session
+// counts and former names) for machine management.
+func (s *Server) handleListMachines(
+	w http.ResponseWriter, r *http.Request,
+) {
+	machines, err := s.db.GetMachines(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	bots, err := s.db.GetBotMachines(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	details, err := s.db.GetMachineInfos(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"machines": machines,
+		"bots":     bots,
+		"details":  details,
+	})
+}
+
+type renameMachineRequest struct {
+	To string `json:"to"`
+}
+
+type mergeMachineRequest struct {
+	Into string `json:"into"`
+}
+
+// handleRenameMachine relabels a machine under a new, unused
+// name. Sessions synced or uploaded later under the old name
+// are stored under the new one.
+func (s *Server) handleRenameMachine(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var req renameMachineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	s.relabelMachine(w, r, strings.TrimSpace(req.To), s.db.RenameMachine)
+}
+
+// handleMergeMachine folds a machine into another existing
+// machine, e.g. after a hostname change.
+func (s *Server) handleMergeMachine(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var req mergeMachineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	s.relabelMachine(w, r, strings.TrimSpace(req.Into), s.db.MergeMachines)
+}
+
+func (s *Server) relabelMachine(
+	w http.ResponseWriter, r *http.Request, to string,
+	apply func(ctx context.Context, from, to string) error,
+) {
+	from := r.PathValue("name")
+	if to == "" {
+		writeError(w, http.StatusBadRequest, "target machine is required")
+		return
+	}
+	if to == from {
+		writeError(w, http.StatusBadRequest,
+			"target machine must differ from source")
+		return
+	}
+
+	if err := apply(r.Context(), from, to); err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, db.ErrMachineNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, db.ErrMachineExists):
+			writeError(w, http.StatusConflict, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"machine": to,
+	})
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"encoding/json"
+	"log/slog"
 	"math"
 	"net/http"
 
@@ -10,8 +12,63 @@ import (
 func (s *Server) handleGetMessages(
 	w http.ResponseWriter, r *http.Request,
 ) {
+	s.writeMessagesPage(w, r, r.PathValue("id"))
+}
+
+// handleStreamMessages writes a session's messages from the
+// optional from ordinal onwards as JSON lines, one message per
+// line, flushing after each batch read so clients can render a
+// long transcript as it arrives.
+func (s *Server) handleStreamMessages(
+	w http.ResponseWriter, r *http.Request,
+) {
+	from, ok := parseIntParam(w, r, "from")
+	if !ok {
+		return
+	}
 	sessionID := r.PathValue("id")
+	session, err := s.db.GetSession(r.Context(), sessionID)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
 
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+	err = s.db.StreamMessages(r.Context(), sessionID, from,
+		func(msgs []dbpkg.Message) error {
+			for i := range msgs {
+				if err := enc.Encode(&msgs[i]); err != nil {
+					return err
+				}
+			}
+			_ = rc.Flush()
+			return nil
+		})
+	if err != nil && r.Context().Err() == nil {
+		// Headers are sent; the client sees a truncated stream.
+		slog.Warn("streaming messages failed",
+			"session", sessionID, "err", err)
+	}
+}
+
+// writeMessagesPage writes one page of a session's messages,
+// paged by the from, limit, and direction query parameters. A
+// uuid parameter starts the page at the message with that
+// source UUID instead of at an ordinal.
+func (s *Server) writeMessagesPage(
+	w http.ResponseWriter, r *http.Request, sessionID string,
+) {
 	limit, ok := parseIntParam(w, r, "limit")
 	if !ok {
 		return
@@ -21,7 +78,13 @@ func (s *Server) handleGetMessages(
 	asc := r.URL.Query().Get("direction") != "desc"
 
 	from := 0
-	if r.URL.Query().Get("from") != "" {
+	if r.URL.Query().Get("uuid") != "" {
+		var ok bool
+		from, ok = s.resolveMessageUUID(w, r, sessionID)
+		if !ok {
+			return
+		}
+	} else if r.URL.Query().Get("from") != "" {
 		var ok bool
 		from, ok = parseIntParam(w, r, "from")
 		if !ok {
@@ -48,6 +111,84 @@ func (s *Server) handleGetMessages(
 	})
 }
 
+// resolveMessageUUID returns the ordinal of the message whose
+// source UUID is the uuid query parameter, writing a 404 when
+// the session has no such message.
+func (s *Server) resolveMessageUUID(
+	w http.ResponseWriter, r *http.Request, sessionID string,
+) (int, bool) {
+	msg, err := s.db.GetMessageByUUID(
+		r.Context(), sessionID, r.URL.Query().Get("uuid"),
+	)
+	if err != nil {
+		if !handleContextError(w, err) {
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return 0, false
+	}
+	if msg == nil {
+		writeError(w, http.StatusNotFound, "message not found")
+		return 0, false
+	}
+	return msg.Ordinal, true
+}
+
+// handleGetMessageContext returns the messages within radius
+// ordinals of a search hit, for previews that should not load
+// the whole session. The hit is given by ordinal or by source
+// uuid.
+func (s *Server) handleGetMessageContext(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var (
+		ordinal int
+		ok      bool
+	)
+	switch {
+	case r.URL.Query().Get("uuid") != "":
+		ordinal, ok = s.resolveMessageUUID(w, r, r.PathValue("id"))
+	case r.URL.Query().Get("ordinal") != "":
+		ordinal, ok = parseIntParam(w, r, "ordinal")
+	default:
+		writeError(w, http.StatusBadRequest, "ordinal or uuid required")
+		return
+	}
+	if !ok {
+		return
+	}
+	radius := dbpkg.DefaultContextRadius
+	if r.URL.Query().Get("radius") != "" {
+		radius, ok = parseIntParam(w, r, "radius")
+		if !ok {
+			return
+		}
+		if radius < 0 {
+			writeError(w, http.StatusBadRequest,
+				"invalid radius parameter")
+			return
+		}
+		radius = min(radius, dbpkg.MaxContextRadius)
+	}
+
+	msgs, err := s.db.GetMessageContext(
+		r.Context(), r.PathValue("id"), ordinal, radius,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"messages": msgs,
+		"count":    len(msgs),
+		"ordinal":  ordinal,
+		"radius":   radius,
+	})
+}
+
 func (s *Server) handleGetMinimap(
 	w http.ResponseWriter, r *http.Request,
 ) {
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/wesm/agentsview/internal/metrics"
+)
+
+var httpDuration = metrics.NewHistogram(
+	"agentsview_http_request_duration_seconds",
+	"HTTP request latency by route pattern and status code.",
+	nil, "method", "route", "code",
+)
+
+// handleMetrics serves process metrics in the Prometheus text
+// format. Counters and histograms come from metrics.Default;
+// database gauges are read at scrape time.
+func (s *Server) handleMetrics(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var buf bytes.Buffer
+	if err := metrics.Default.Write(&buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	gauge := func(name, help string, v float64) {
+		_ = metrics.WriteGauge(&buf, name, help, v)
+	}
+	if path := s.db.Path(); path != "" {
+		for _, f := range []struct{ suffix, name, help string }{
+			{"", "agentsview_db_size_bytes",
+				"Size of the SQLite database file."},
+			{"-wal", "agentsview_db_wal_size_bytes",
+				"Size of the SQLite write-ahead log."},
+		} {
+			if info, err := os.Stat(path + f.suffix); err == nil {
+				gauge(f.name, f.help, float64(info.Size()))
+			}
+		}
+	}
+	stats, err := s.db.GetStats(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("metrics stats", "err", err)
+	} else {
+		gauge("agentsview_sessions", "Sessions in the database.",
+			float64(stats.SessionCount))
+		gauge("agentsview_messages", "Messages in the database.",
+			float64(stats.MessageCount))
+	}
+	queue := s.db.WriteQueueStats()
+	gauge("agentsview_write_queue_depth",
+		"Pending database writes.", float64(queue.Depth))
+	if s.engine != nil {
+		if last := s.engine.LastSync(); !last.IsZero() {
+			gauge("agentsview_last_sync_timestamp_seconds",
+				"Unix time of the last completed sync.",
+				float64(last.UnixNano())/1e9)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// metricsMiddleware records request latency labelled with the
+// mux route pattern, so path parameters such as session IDs do
+// not create a series per request.
+func metricsMiddleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		if route == "" {
+			route = "unmatched"
+		}
+		sw := &statusWriter{ResponseWriter: w}
+		// Only advertise Flush when the client connection
+		// supports it, so handlers can still fall back to
+		// buffered responses.
+		var ww http.ResponseWriter = sw
+		if _, ok := w.(http.Flusher); ok {
+			ww = flushStatusWriter{sw}
+		}
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+		code := sw.status
+		if code == 0 {
+			code = http.StatusOK
+		}
+		httpDuration.Observe(time.Since(start).Seconds(),
+			r.Method, route, strconv.Itoa(code))
+	})
+}
+
+// statusWriter records the response status code.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap exposes the underlying writer to
+// http.NewResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// flushStatusWriter is a statusWriter over a writer that
+// supports streaming.
+type flushStatusWriter struct{ *statusWriter }
+
+func (w flushStatusWriter) Flush() {
+	w.ResponseWriter.(http.Flusher).Flush()
+}
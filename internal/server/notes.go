@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+type noteRequest struct {
+	Body string `json:"body"`
+	// Ordinal attaches the note to one message; omit it for a
+	// note on the whole session. Ignored on update.
+	Ordinal *int `json:"ordinal"`
+}
+
+// sessionForNotes loads the session named in the path, writing
+// a 404 or 500 and returning nil when it cannot.
+func (s *Server) sessionForNotes(
+	w http.ResponseWriter, r *http.Request,
+) *db.Session {
+	session, err := s.db.GetSession(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if handleContextError(w, err) {
+			return nil
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+	}
+	return session
+}
+
+// decodeNote reads a note request body, writing a 400 when it
+// is not valid JSON or the note body is unusable.
+func decodeNote(w http.ResponseWriter, r *http.Request) (noteRequest, bool) {
+	var req noteRequest
+	body := http.MaxBytesReader(w, r.Body, 2*db.MaxNoteLength)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return req, false
+	}
+	if err := db.ValidateNoteBody(req.Body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return req, false
+	}
+	return req, true
+}
+
+func (s *Server) handleListSessionNotes(
+	w http.ResponseWriter, r *http.Request,
+) {
+	session := s.sessionForNotes(w, r)
+	if session == nil {
+		return
+	}
+	notes, err := s.db.ListSessionNotes(r.Context(), session.ID)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if notes == nil {
+		notes = []db.SessionNote{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"notes": notes})
+}
+
+// handleAddSessionNote attaches a Markdown note to a session
+// or, with an ordinal, to one of its messages.
+func (s *Server) handleAddSessionNote(
+	w http.ResponseWriter, r *http.Request,
+) {
+	session := s.sessionForNotes(w, r)
+	if session == nil {
+		return
+	}
+	req, ok := decodeNote(w, r)
+	if !ok {
+		return
+	}
+	if o := req.Ordinal; o != nil && (*o < 0 || *o >= session.MessageCount) {
+		writeError(w, http.StatusBadRequest,
+			"ordinal is not a message in this session")
+		return
+	}
+	note, err := s.db.AddSessionNote(
+		r.Context(), session.ID, req.Ordinal, req.Body,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, note)
+}
+
+func (s *Server) handleUpdateSessionNote(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id, err := strconv.ParseInt(r.PathValue("note"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid note id")
+		return
+	}
+	req, ok := decodeNote(w, r)
+	if !ok {
+		return
+	}
+	note, err := s.db.UpdateSessionNote(
+		r.Context(), r.PathValue("id"), id, req.Body,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if note == nil {
+		writeError(w, http.StatusNotFound, "note not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, note)
+}
+
+func (s *Server) handleDeleteSessionNote(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id, err := strconv.ParseInt(r.PathValue("note"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid note id")
+		return
+	}
+	found, err := s.db.DeleteSessionNote(r.Context(), r.PathValue("id"), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "note not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSearchNotes full-text searches note bodies across all
+// sessions.
+func (s *Server) handleSearchNotes(
+	w http.ResponseWriter, r *http.Request,
+) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "query required")
+		return
+	}
+	limit, ok := parseIntParam(w, r, "limit")
+	if !ok {
+		return
+	}
+	if !s.db.HasFTS() {
+		writeError(w, http.StatusNotImplemented, "search not available")
+		return
+	}
+
+	results, err := s.db.SearchNotes(
+		r.Context(), prepareFTSQuery(query), limit,
+	)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"query": query, "results": results, "count": len(results),
+	})
+}
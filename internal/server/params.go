@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
+
+	"github.com/wesm/agentsview/internal/timeutil"
 )
 
 // parseIntParam reads an integer query parameter from r.
@@ -26,6 +29,55 @@ func parseIntParam(
 	return v, true
 }
 
+// parseBoolParam reads a boolean query parameter from r. It
+// writes a 400 error and returns false if the value is not a
+// boolean. An absent parameter returns (false, true).
+func parseBoolParam(
+	w http.ResponseWriter, r *http.Request, name string,
+) (bool, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return false, true
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		writeError(w, http.StatusBadRequest,
+			fmt.Sprintf("%s must be true or false", name))
+		return false, false
+	}
+	return v, true
+}
+
+// parseTimeParam reads an RFC3339 timestamp query parameter
+// and returns it in the UTC form timestamps are stored in, so it
+// compares correctly with stored values. It writes a 400 error
+// and returns false if the value is not a timestamp. An absent
+// parameter returns ("", true).
+func parseTimeParam(
+	w http.ResponseWriter, r *http.Request, name string,
+) (string, bool) {
+	v, ok := normalizeTimestamp(r.URL.Query().Get(name))
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf(
+			"invalid %s: use RFC3339 timestamp", name,
+		))
+	}
+	return v, ok
+}
+
+// normalizeTimestamp converts an RFC3339 timestamp to the UTC
+// form timestamps are stored in. Empty stays empty.
+func normalizeTimestamp(raw string) (string, bool) {
+	if raw == "" {
+		return "", true
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return "", false
+	}
+	return timeutil.Format(t), true
+}
+
 // clampLimit applies a default and upper bound to a limit value.
 func clampLimit(limit, defaultLimit, maxLimit int) int {
 	if limit <= 0 {
@@ -102,3 +102,10 @@ func TestClampLimit(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultDateRangeDays(t *testing.T) {
+	from, to := defaultDateRangeDays("", "2024-06-30", 7)
+	if from != "2024-06-23" || to != "2024-06-30" {
+		t.Errorf("range = %s..%s, want 2024-06-23..2024-06-30", from, to)
+	}
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/pricing"
+)
+
+// pricingResponse is the model pricing table cost estimates
+// use, in Currency per million tokens.
+type pricingResponse struct {
+	Currency string          `json:"currency"`
+	Models   []pricing.Entry `json:"models"`
+}
+
+// handlePricing returns the built-in model rates merged with
+// the pricing overrides from config.json.
+func (s *Server) handlePricing(
+	w http.ResponseWriter, _ *http.Request,
+) {
+	writeJSON(w, http.StatusOK, pricingResponse{
+		Currency: db.BaseCurrency,
+		Models:   pricing.Table(),
+	})
+}
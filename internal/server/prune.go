@@ -0,0 +1,223 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+const (
+	defaultPruneLimit = 50
+	maxPruneLimit     = 500
+)
+
+// pruneAction scopes prune confirmation tokens so they cannot
+// be replayed as bulk curation tokens.
+var pruneAction = db.BulkAction{Action: "prune"}
+
+// pruneRequest carries the same filters as the prune CLI
+// command. Limit and Offset page the preview listing; Token
+// confirms an execute request, and DeleteFiles opts in to
+// removing the source files of sessions synced on this machine.
+type pruneRequest struct {
+	Project      string `json:"project"`
+	Agent        string `json:"agent"`
+	MaxMessages  *int   `json:"max_messages"`
+	Before       string `json:"before"`
+	FirstMessage string `json:"first_message"`
+	Orphaned     bool   `json:"orphaned"`
+	IncludeChain bool   `json:"include_chain"`
+	Limit        int    `json:"limit"`
+	Offset       int    `json:"offset"`
+	Token        string `json:"token"`
+	DeleteFiles  bool   `json:"delete_files"`
+}
+
+type pruneProject struct {
+	Project  string `json:"project"`
+	Sessions int    `json:"sessions"`
+	Bytes    int64  `json:"bytes"`
+}
+
+type prunePreviewResponse struct {
+	Total      int            `json:"total"`
+	TotalBytes int64          `json:"total_bytes"`
+	ByProject  []pruneProject `json:"by_project"`
+	Sessions   []db.Session   `json:"sessions"`
+	Limit      int            `json:"limit"`
+	Offset     int            `json:"offset"`
+	Token      string         `json:"token"`
+}
+
+type pruneExecuteResponse struct {
+	Deleted        int   `json:"deleted"`
+	FilesRemoved   int   `json:"files_removed"`
+	BytesReclaimed int64 `json:"bytes_reclaimed"`
+}
+
+// decodePruneRequest parses and validates a prune request
+// body, writing a 400 and returning false on failure.
+func decodePruneRequest(
+	w http.ResponseWriter, r *http.Request,
+) (pruneRequest, db.PruneFilter, bool) {
+	var req pruneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return req, db.PruneFilter{}, false
+	}
+	if req.MaxMessages != nil && *req.MaxMessages < 0 {
+		writeError(w, http.StatusBadRequest,
+			"max_messages must be >= 0")
+		return req, db.PruneFilter{}, false
+	}
+	if req.Before != "" && !isValidDate(req.Before) {
+		writeError(w, http.StatusBadRequest,
+			"invalid before: use YYYY-MM-DD")
+		return req, db.PruneFilter{}, false
+	}
+	if req.Offset < 0 {
+		writeError(w, http.StatusBadRequest,
+			"offset must be >= 0")
+		return req, db.PruneFilter{}, false
+	}
+	f := db.PruneFilter{
+		Project:      req.Project,
+		Agent:        req.Agent,
+		MaxMessages:  req.MaxMessages,
+		Before:       req.Before,
+		FirstMessage: req.FirstMessage,
+		Orphaned:     req.Orphaned,
+		IncludeChain: req.IncludeChain,
+	}
+	if !f.HasFilters() {
+		writeError(w, http.StatusBadRequest,
+			"at least one filter is required")
+		return req, db.PruneFilter{}, false
+	}
+	return req, f, true
+}
+
+// localMachine returns the machine name sync gives sessions on
+// this host, or "" (meaning "local") when there is no engine.
+func (s *Server) localMachine() string {
+	if s.engine == nil {
+		return ""
+	}
+	return s.engine.Machine()
+}
+
+// findPruneCandidates returns the sessions matching f and their
+// IDs sorted, the form expected by BulkToken.
+func (s *Server) findPruneCandidates(
+	f db.PruneFilter,
+) ([]db.Session, []string, error) {
+	f.LocalMachine = s.localMachine()
+	candidates, err := s.db.FindPruneCandidates(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	ids := make([]string, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.ID
+	}
+	sort.Strings(ids)
+	return candidates, ids, nil
+}
+
+// handlePrunePreview lists the sessions a prune would delete,
+// one page at a time, with totals over the whole selection and
+// a token that handlePruneExecute requires.
+func (s *Server) handlePrunePreview(
+	w http.ResponseWriter, r *http.Request,
+) {
+	req, f, ok := decodePruneRequest(w, r)
+	if !ok {
+		return
+	}
+	candidates, ids, err := s.findPruneCandidates(f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := prunePreviewResponse{
+		Total:    len(candidates),
+		Limit:    clampLimit(req.Limit, defaultPruneLimit, maxPruneLimit),
+		Offset:   req.Offset,
+		Sessions: []db.Session{},
+		Token:    s.db.BulkToken(pruneAction, ids),
+	}
+	byProject := map[string]*pruneProject{}
+	for _, c := range candidates {
+		p := byProject[c.Project]
+		if p == nil {
+			p = &pruneProject{Project: c.Project}
+			byProject[c.Project] = p
+		}
+		p.Sessions++
+		if c.FileSize != nil {
+			p.Bytes += *c.FileSize
+			resp.TotalBytes += *c.FileSize
+		}
+	}
+	resp.ByProject = make([]pruneProject, 0, len(byProject))
+	for _, p := range byProject {
+		resp.ByProject = append(resp.ByProject, *p)
+	}
+	sort.Slice(resp.ByProject, func(i, j int) bool {
+		return resp.ByProject[i].Project < resp.ByProject[j].Project
+	})
+	if resp.Offset < len(candidates) {
+		end := min(resp.Offset+resp.Limit, len(candidates))
+		resp.Sessions = candidates[resp.Offset:end]
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handlePruneExecute deletes the sessions matching the filters,
+// provided the selection still matches the token returned by
+// the preview. Source files are left alone unless the request
+// sets delete_files, and even then only files of sessions
+// synced on this machine are removed.
+func (s *Server) handlePruneExecute(
+	w http.ResponseWriter, r *http.Request,
+) {
+	req, f, ok := decodePruneRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Token == "" {
+		writeError(w, http.StatusBadRequest,
+			"token is required; request a preview first")
+		return
+	}
+	candidates, ids, err := s.findPruneCandidates(f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := s.db.CheckBulkToken(pruneAction, ids, req.Token); err != nil {
+		writeError(w, http.StatusConflict,
+			"selection changed since preview; request a new token")
+		return
+	}
+
+	deleted, err := s.db.DeleteSessions(ids)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp := pruneExecuteResponse{Deleted: deleted}
+	if req.DeleteFiles {
+		local, err := s.db.LocalSessions(candidates, s.localMachine())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		resp.FilesRemoved, resp.BytesReclaimed =
+			db.RemoveSessionFiles(local)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
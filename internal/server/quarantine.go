@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// handleListQuarantine lists session files that failed to
+// parse and are waiting to be retried.
+func (s *Server) handleListQuarantine(
+	w http.ResponseWriter, r *http.Request,
+) {
+	files, err := s.db.ListQuarantinedFiles(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"files": files})
+}
+
+type retryQuarantineRequest struct {
+	// Path limits the retry to one file; empty retries all.
+	Path string `json:"path"`
+}
+
+// handleRetryQuarantine re-parses quarantined files now
+// instead of waiting for their backoff, then returns the
+// files still quarantined.
+func (s *Server) handleRetryQuarantine(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var req retryQuarantineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil &&
+		!errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	var paths []string
+	if req.Path != "" {
+		paths = []string{req.Path}
+	}
+	retried := s.engine.RetryQuarantined(paths)
+	if req.Path != "" && retried == 0 {
+		writeError(w, http.StatusNotFound, "file not quarantined")
+		return
+	}
+
+	files, err := s.db.ListQuarantinedFiles(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"retried": retried,
+		"files":   files,
+	})
+}
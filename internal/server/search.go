@@ -2,7 +2,9 @@ package server
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/wesm/agentsview/internal/db"
 )
@@ -14,6 +16,11 @@ type searchResponse struct {
 	Next    int               `json:"next"`
 }
 
+type searchFacetsResponse struct {
+	Query string `json:"query"`
+	db.SearchFacets
+}
+
 // prepareFTSQuery wraps multi-word queries in quotes so
 // SQLite FTS matches the exact phrase rather than individual
 // terms.
@@ -47,17 +54,17 @@ func (s *Server) handleSearch(
 		return
 	}
 
-	if !s.db.HasFTS() {
-		writeError(w, http.StatusNotImplemented, "search not available")
+	filter, ok := parseSearchFilter(w, r, query)
+	if !ok {
 		return
 	}
 
-	filter := db.SearchFilter{
-		Query:   prepareFTSQuery(query),
-		Project: q.Get("project"),
-		Cursor:  cursor,
-		Limit:   limit,
+	if !s.db.HasFTS() {
+		writeError(w, http.StatusNotImplemented, "search not available")
+		return
 	}
+	filter.Cursor = cursor
+	filter.Limit = limit
 
 	page, err := s.db.Search(r.Context(), filter)
 	if err != nil {
@@ -79,3 +86,102 @@ func (s *Server) handleSearch(
 		Next:    page.NextCursor,
 	})
 }
+
+// parseSearchFilter reads the filters shared by search and its
+// facets: project, agent, machine, tag, role, has_tool_use,
+// month (YYYY-MM), date_from and date_to (YYYY-MM-DD), and
+// session_id.
+func parseSearchFilter(
+	w http.ResponseWriter, r *http.Request, query string,
+) (db.SearchFilter, bool) {
+	q := r.URL.Query()
+	month := q.Get("month")
+	if month != "" {
+		if _, err := time.Parse("2006-01", month); err != nil {
+			writeError(w, http.StatusBadRequest,
+				"invalid month: use YYYY-MM")
+			return db.SearchFilter{}, false
+		}
+	}
+
+	dateFrom := q.Get("date_from")
+	dateTo := q.Get("date_to")
+	for _, d := range []string{dateFrom, dateTo} {
+		if d != "" && !isValidDate(d) {
+			writeError(w, http.StatusBadRequest,
+				"invalid date format: use YYYY-MM-DD")
+			return db.SearchFilter{}, false
+		}
+	}
+	if dateFrom != "" && dateTo != "" && dateFrom > dateTo {
+		writeError(w, http.StatusBadRequest,
+			"date_from must not be after date_to")
+		return db.SearchFilter{}, false
+	}
+
+	role := q.Get("role")
+	if role != "" && role != "user" && role != "assistant" {
+		writeError(w, http.StatusBadRequest,
+			"role must be user or assistant")
+		return db.SearchFilter{}, false
+	}
+
+	hasToolUse := false
+	if v := q.Get("has_tool_use"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest,
+				"has_tool_use must be true or false")
+			return db.SearchFilter{}, false
+		}
+		hasToolUse = b
+	}
+
+	return db.SearchFilter{
+		Query:      prepareFTSQuery(query),
+		Project:    q.Get("project"),
+		Agent:      q.Get("agent"),
+		Machine:    q.Get("machine"),
+		Tag:        q.Get("tag"),
+		Role:       role,
+		HasToolUse: hasToolUse,
+		Month:      month,
+		DateFrom:   dateFrom,
+		DateTo:     dateTo,
+		SessionID:  q.Get("session_id"),
+	}, true
+}
+
+// handleSearchFacets returns hit counts by project, agent, and
+// month for a query, so the UI can drill into large result
+// sets.
+func (s *Server) handleSearchFacets(
+	w http.ResponseWriter, r *http.Request,
+) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "query required")
+		return
+	}
+	if !s.db.HasFTS() {
+		writeError(w, http.StatusNotImplemented, "search not available")
+		return
+	}
+	filter, ok := parseSearchFilter(w, r, query)
+	if !ok {
+		return
+	}
+
+	facets, err := s.db.SearchFacets(r.Context(), filter)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, searchFacetsResponse{
+		Query:        query,
+		SearchFacets: facets,
+	})
+}
@@ -2,9 +2,9 @@ package server
 
 import (
 	"context"
-	"fmt"
 	"io/fs"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
@@ -13,6 +13,7 @@ import (
 	gosync "sync"
 	"time"
 
+	"github.com/wesm/agentsview/internal/alert"
 	"github.com/wesm/agentsview/internal/config"
 	"github.com/wesm/agentsview/internal/db"
 	"github.com/wesm/agentsview/internal/insight"
@@ -36,8 +37,10 @@ type Server struct {
 	mux     *http.ServeMux
 	httpSrv *http.Server
 	version VersionInfo
+	alerts  *alert.Evaluator
 
 	generateStreamFunc insight.GenerateStreamFunc
+	revealFunc         RevealFunc
 	spaFS              fs.FS
 	spaHandler         http.Handler
 
@@ -63,6 +66,7 @@ func New(
 		engine:             engine,
 		mux:                http.NewServeMux(),
 		generateStreamFunc: insight.GenerateStream,
+		revealFunc:         revealInFileManager,
 		spaFS:              dist,
 		spaHandler:         http.FileServerFS(dist),
 	}
@@ -81,6 +85,16 @@ func WithVersion(v VersionInfo) Option {
 	return func(s *Server) { s.version = v }
 }
 
+// WithAlerts sets the evaluator whose rule states the alerts
+// endpoint reports. Nil is ignored.
+func WithAlerts(e *alert.Evaluator) Option {
+	return func(s *Server) {
+		if e != nil {
+			s.alerts = e
+		}
+	}
+}
+
 // WithGenerateFunc overrides the insight generation function,
 // allowing tests to substitute a stub. Nil is ignored.
 func WithGenerateFunc(f insight.GenerateFunc) Option {
@@ -96,6 +110,17 @@ func WithGenerateFunc(f insight.GenerateFunc) Option {
 	}
 }
 
+// WithRevealFunc overrides how source directories are opened
+// in the file manager, allowing tests to substitute a stub.
+// Nil is ignored.
+func WithRevealFunc(f RevealFunc) Option {
+	return func(s *Server) {
+		if f != nil {
+			s.revealFunc = f
+		}
+	}
+}
+
 // WithGenerateStreamFunc overrides the streaming insight
 // generation function used by the SSE handler. Nil is ignored.
 func WithGenerateStreamFunc(f insight.GenerateStreamFunc) Option {
@@ -110,6 +135,9 @@ func (s *Server) routes() {
 	// API v1 routes
 	s.mux.Handle("GET /api/v1/sessions", s.withTimeout(s.handleListSessions))
 	s.mux.Handle("GET /api/v1/sessions/{id}", s.withTimeout(s.handleGetSession))
+	s.mux.Handle(
+		"GET /api/v1/sessions/active", s.withTimeout(s.handleActiveSessions),
+	)
 	s.mux.Handle(
 		"GET /api/v1/sessions/{id}/messages", s.withTimeout(s.handleGetMessages),
 	)
@@ -119,44 +147,173 @@ func (s *Server) routes() {
 	s.mux.Handle(
 		"GET /api/v1/sessions/{id}/minimap", s.withTimeout(s.handleGetMinimap),
 	)
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/context", s.withTimeout(s.handleGetMessageContext),
+	)
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/source", s.withTimeout(s.handleGetSessionSource),
+	)
+	s.mux.Handle(
+		"POST /api/v1/sessions/{id}/reveal", s.withTimeout(s.handleRevealSessionSource),
+	)
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/hooks", s.withTimeout(s.handleGetSessionHooks),
+	)
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/models", s.withTimeout(s.handleGetSessionModels),
+	)
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/todos", s.withTimeout(s.handleGetSessionTodos),
+	)
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/env", s.withTimeout(s.handleGetSessionEnv),
+	)
+	pin := s.handleSessionFlag("pinned", db.BulkActionPin, db.BulkActionUnpin)
+	s.mux.Handle("PUT /api/v1/sessions/{id}/pin", s.withTimeout(pin))
+	s.mux.Handle("DELETE /api/v1/sessions/{id}/pin", s.withTimeout(pin))
+	archive := s.handleSessionFlag(
+		"archived", db.BulkActionArchive, db.BulkActionUnarchive,
+	)
+	s.mux.Handle("PUT /api/v1/sessions/{id}/archive", s.withTimeout(archive))
+	s.mux.Handle("DELETE /api/v1/sessions/{id}/archive", s.withTimeout(archive))
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/notes",
+		s.withTimeout(s.handleListSessionNotes),
+	)
+	s.mux.Handle(
+		"POST /api/v1/sessions/{id}/notes",
+		s.withTimeout(s.handleAddSessionNote),
+	)
+	s.mux.Handle(
+		"PUT /api/v1/sessions/{id}/notes/{note}",
+		s.withTimeout(s.handleUpdateSessionNote),
+	)
+	s.mux.Handle(
+		"DELETE /api/v1/sessions/{id}/notes/{note}",
+		s.withTimeout(s.handleDeleteSessionNote),
+	)
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/tags", s.withTimeout(s.handleSessionTags),
+	)
+	s.mux.Handle(
+		"POST /api/v1/sessions/{id}/tags", s.withTimeout(s.handleSessionTags),
+	)
+	s.mux.Handle(
+		"DELETE /api/v1/sessions/{id}/tags", s.withTimeout(s.handleSessionTags),
+	)
 	// SSE: Do not use timeout, as this is a long-lived connection.
 	s.mux.HandleFunc(
 		"GET /api/v1/sessions/{id}/watch", s.handleWatchSession,
 	)
+	s.mux.HandleFunc(
+		"GET /api/v1/sessions/{id}/stream", s.handleStreamSession,
+	)
+	// Streamed transcript: no timeout, so long sessions can finish.
+	s.mux.Handle(
+		"GET /api/v1/sessions/{id}/messages/stream",
+		http.HandlerFunc(s.handleStreamMessages),
+	)
 	// Export: Do not use timeout handler to support large downloads and avoid buffering.
 	s.mux.Handle(
 		"GET /api/v1/sessions/{id}/export", http.HandlerFunc(s.handleExportSession),
 	)
+	s.mux.Handle(
+		"GET /api/v1/projects/{project}/export", http.HandlerFunc(s.handleExportProject),
+	)
 	s.mux.Handle(
 		"POST /api/v1/sessions/{id}/publish", s.withTimeout(s.handlePublishSession),
 	)
+	s.mux.Handle(
+		"POST /api/v1/sessions/{id}/share", s.withTimeout(s.handleCreateShareLink),
+	)
+	s.mux.Handle(
+		"GET /api/v1/shared/{token}", s.withTimeout(s.handleGetSharedSession),
+	)
+	s.mux.Handle(
+		"GET /api/v1/shared/{token}/messages",
+		s.withTimeout(s.handleGetSharedMessages),
+	)
 	s.mux.Handle(
 		"POST /api/v1/sessions/upload", s.withTimeout(s.handleUploadSession),
 	)
+	s.mux.Handle(
+		"POST /api/v1/ingest/check", s.withTimeout(s.handleIngestCheck),
+	)
+	s.mux.Handle("POST /api/v1/ingest", s.withTimeout(s.handleIngest))
+	s.mux.Handle(
+		"POST /api/v1/sessions/bulk", s.withTimeout(s.handleBulkSessions),
+	)
+	s.mux.Handle("POST /api/v1/prune/preview", s.withTimeout(s.handlePrunePreview))
+	s.mux.Handle("POST /api/v1/prune/execute", s.withTimeout(s.handlePruneExecute))
+	s.mux.Handle("GET /api/v1/analytics/defaults", s.withTimeout(s.handleAnalyticsDefaults))
 	s.mux.Handle("GET /api/v1/analytics/summary", s.withTimeout(s.handleAnalyticsSummary))
 	s.mux.Handle("GET /api/v1/analytics/activity", s.withTimeout(s.handleAnalyticsActivity))
 	s.mux.Handle("GET /api/v1/analytics/heatmap", s.withTimeout(s.handleAnalyticsHeatmap))
 	s.mux.Handle("GET /api/v1/analytics/projects", s.withTimeout(s.handleAnalyticsProjects))
+	s.mux.Handle("GET /api/v1/analytics/branches", s.withTimeout(s.handleAnalyticsBranches))
+	s.mux.Handle("GET /api/v1/analytics/entry-points", s.withTimeout(s.handleAnalyticsEntryPoints))
+	s.mux.Handle("GET /api/v1/analytics/languages", s.withTimeout(s.handleAnalyticsLanguages))
+	s.mux.Handle("GET /api/v1/analytics/skill-usage", s.withTimeout(s.handleAnalyticsSkillUsage))
 	s.mux.Handle("GET /api/v1/analytics/hour-of-week", s.withTimeout(s.handleAnalyticsHourOfWeek))
 	s.mux.Handle("GET /api/v1/analytics/sessions", s.withTimeout(s.handleAnalyticsSessionShape))
 	s.mux.Handle("GET /api/v1/analytics/velocity", s.withTimeout(s.handleAnalyticsVelocity))
+	s.mux.Handle("GET /api/v1/analytics/slo", s.withTimeout(s.handleAnalyticsSLO))
+	s.mux.Handle("GET /api/v1/analytics/cache", s.withTimeout(s.handleAnalyticsCache))
+	s.mux.Handle("GET /api/v1/pricing", s.withTimeout(s.handlePricing))
+	s.mux.Handle("GET /api/v1/analytics/usage", s.withTimeout(s.handleAnalyticsUsage))
+	s.mux.Handle("GET /api/v1/analytics/cost-phases", s.withTimeout(s.handleAnalyticsCostPhases))
+	s.mux.Handle("GET /api/v1/analytics/forecast", s.withTimeout(s.handleAnalyticsForecast))
+	s.mux.Handle("GET /api/v1/analytics/month-to-date", s.withTimeout(s.handleAnalyticsMonthToDate))
+	s.mux.Handle("GET /api/v1/analytics/monthly", s.withTimeout(s.handleAnalyticsMonthly))
+	s.mux.Handle("GET /api/v1/analytics/warmup", s.withTimeout(s.handleAnalyticsWarmup))
+	s.mux.Handle("GET /api/v1/analytics/progress", s.withTimeout(s.handleAnalyticsProgress))
+	s.mux.Handle("GET /api/v1/analytics/hooks", s.withTimeout(s.handleAnalyticsHooks))
 	s.mux.Handle("GET /api/v1/analytics/tools", s.withTimeout(s.handleAnalyticsTools))
+	s.mux.Handle("GET /api/v1/analytics/tool-sequences", s.withTimeout(s.handleAnalyticsToolSequences))
+	s.mux.Handle("GET /api/v1/analytics/commands", s.withTimeout(s.handleAnalyticsCommands))
+	s.mux.Handle("GET /api/v1/analytics/model-switches", s.withTimeout(s.handleAnalyticsModelSwitches))
+	s.mux.Handle("GET /api/v1/analytics/edit-thrash", s.withTimeout(s.handleAnalyticsEditThrash))
+	s.mux.Handle("GET /api/v1/analytics/quality", s.withTimeout(s.handleAnalyticsQuality))
+	s.mux.Handle("GET /api/v1/analytics/retries", s.withTimeout(s.handleAnalyticsRetries))
+	s.mux.Handle("GET /api/v1/analytics/paste", s.withTimeout(s.handleAnalyticsPaste))
+	s.mux.Handle("GET /api/v1/analytics/truncation", s.withTimeout(s.handleAnalyticsTruncation))
 	s.mux.Handle("GET /api/v1/analytics/top-sessions", s.withTimeout(s.handleAnalyticsTopSessions))
 
+	s.mux.Handle("GET /api/v1/analytics/snapshots", s.withTimeout(s.handleListAnalyticsSnapshots))
+	s.mux.Handle("POST /api/v1/analytics/snapshots", s.withTimeout(s.handleCreateAnalyticsSnapshot))
+	s.mux.Handle("POST /api/v1/analytics/snapshots/import", s.withTimeout(s.handleImportAnalyticsSnapshot))
+	s.mux.Handle("GET /api/v1/analytics/snapshots/{id}", s.withTimeout(s.handleGetAnalyticsSnapshot))
+	s.mux.Handle("DELETE /api/v1/analytics/snapshots/{id}", s.withTimeout(s.handleDeleteAnalyticsSnapshot))
+
 	s.mux.Handle("GET /api/v1/insights", s.withTimeout(s.handleListInsights))
 	s.mux.Handle("GET /api/v1/insights/{id}", s.withTimeout(s.handleGetInsight))
 	s.mux.Handle("DELETE /api/v1/insights/{id}", s.withTimeout(s.handleDeleteInsight))
 	s.mux.HandleFunc("POST /api/v1/insights/generate", s.handleGenerateInsight)
 
 	s.mux.Handle("GET /api/v1/search", s.withTimeout(s.handleSearch))
+	s.mux.Handle("GET /api/v1/search/facets", s.withTimeout(s.handleSearchFacets))
+	s.mux.Handle("GET /api/v1/search/notes", s.withTimeout(s.handleSearchNotes))
+	s.mux.Handle("GET /api/v1/todos", s.withTimeout(s.handleListOpenTodos))
+	s.mux.Handle("GET /api/v1/files/recent", s.withTimeout(s.handleRecentFiles))
+	s.mux.Handle("GET /api/v1/logs", s.withTimeout(s.handleLogs))
 	s.mux.Handle("GET /api/v1/projects", s.withTimeout(s.handleListProjects))
 	s.mux.Handle("GET /api/v1/machines", s.withTimeout(s.handleListMachines))
+	s.mux.Handle("GET /api/v1/tags", s.withTimeout(s.handleListTags))
+	s.mux.Handle("POST /api/v1/machines/{name}/rename", s.withTimeout(s.handleRenameMachine))
+	s.mux.Handle("POST /api/v1/machines/{name}/merge", s.withTimeout(s.handleMergeMachine))
 	s.mux.Handle("GET /api/v1/agents", s.withTimeout(s.handleListAgents))
+	s.mux.Handle("GET /api/v1/env", s.withTimeout(s.handleListEnvValues))
 	s.mux.Handle("GET /api/v1/stats", s.withTimeout(s.handleGetStats))
 	s.mux.Handle("GET /api/v1/version", s.withTimeout(s.handleGetVersion))
+	s.mux.Handle("GET /api/v1/health", s.withTimeout(s.handleHealth))
+	s.mux.Handle("GET /api/v1/alerts", s.withTimeout(s.handleListAlerts))
+	s.mux.Handle("GET /metrics", s.withTimeout(s.handleMetrics))
 	s.mux.HandleFunc("POST /api/v1/sync", s.handleTriggerSync)
 	s.mux.HandleFunc("POST /api/v1/resync", s.handleTriggerResync)
 	s.mux.Handle("GET /api/v1/sync/status", s.withTimeout(s.handleSyncStatus))
+	s.mux.Handle("GET /api/v1/sync/quarantine", s.withTimeout(s.handleListQuarantine))
+	s.mux.HandleFunc("POST /api/v1/sync/quarantine/retry", s.handleRetryQuarantine)
+	s.mux.Handle("GET /api/v1/config/locale", s.withTimeout(s.handleGetLocale))
 	s.mux.Handle("GET /api/v1/config/github", s.withTimeout(s.handleGetGithubConfig))
 	s.mux.Handle(
 		"POST /api/v1/config/github", s.withTimeout(s.handleSetGithubConfig),
@@ -173,6 +330,34 @@ func (s *Server) handleGetVersion(
 	writeJSON(w, http.StatusOK, s.version)
 }
 
+// handleHealth reports liveness plus database writer queue
+// depth and latency. Status is "busy" while the write queue is
+// full and sync is being throttled. Unknown record types list
+// transcript records the parsers skipped, so upstream format
+// additions show up before messages go missing.
+func (s *Server) handleHealth(
+	w http.ResponseWriter, r *http.Request,
+) {
+	queue := s.db.WriteQueueStats()
+	status := "ok"
+	if queue.Depth >= queue.Capacity {
+		status = "busy"
+	}
+	unknown, err := s.db.GetUnknownRecordTypes(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":               status,
+		"write_queue":          queue,
+		"unknown_record_types": unknown,
+	})
+}
+
 func (s *Server) handleSPA(w http.ResponseWriter, r *http.Request) {
 	// Try to serve the exact file
 	path := strings.TrimPrefix(r.URL.Path, "/")
@@ -222,12 +407,26 @@ func (s *Server) Handler() http.Handler {
 	if bindAll {
 		bindAllIPs = localInterfaceIPs()
 	}
-	return hostCheckMiddleware(
+	app := logMiddleware(metricsMiddleware(
+		s.mux, compressMiddleware(s.mux),
+	))
+	browser := hostCheckMiddleware(
 		allowedHosts, bindAll, s.cfg.Port, bindAllIPs,
 		corsMiddleware(
-			allowedOrigins, bindAll, s.cfg.Port, bindAllIPs, logMiddleware(s.mux),
+			allowedOrigins, bindAll, s.cfg.Port, bindAllIPs, app,
 		),
 	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Ingest is called by other machines, under whatever
+		// host name reaches this one, and requires a bearer
+		// token that browsers never send on their own, so the
+		// rebinding and CSRF defenses do not apply.
+		if strings.HasPrefix(r.URL.Path, ingestPathPrefix) {
+			app.ServeHTTP(w, r)
+			return
+		}
+		browser.ServeHTTP(w, r)
+	})
 }
 
 // buildAllowedHosts returns the set of Host header values that
@@ -239,9 +438,9 @@ func buildAllowedHosts(host string, port int) map[string]bool {
 	hosts := make(map[string]bool)
 	add := func(h string) {
 		hosts[net.JoinHostPort(h, strconv.Itoa(port))] = true
-		// Browsers may omit port 80 from the Host header.
-		// IPv6 literals need brackets (e.g., [::1]).
-		if port == 80 {
+		// Browsers may omit port 80 or 443 from the Host
+		// header. IPv6 literals need brackets (e.g., [::1]).
+		if defaultPortScheme(port) != "" {
 			if strings.Contains(h, ":") {
 				hosts["["+h+"]"] = true
 			} else {
@@ -299,9 +498,11 @@ func hostCheckMiddleware(
 // header for default ports (80 for HTTP), so for port 80 both
 // forms are returned.
 func httpOrigin(host string, port int) []string {
+	// Both schemes are allowed: the server may serve HTTPS, and
+	// either way the port identifies this server.
 	hp := net.JoinHostPort(host, strconv.Itoa(port))
-	origin := "http://" + hp
-	if port == 80 {
+	origins := []string{"http://" + hp, "https://" + hp}
+	if scheme := defaultPortScheme(port); scheme != "" {
 		// net.JoinHostPort brackets IPv6, so use it for the
 		// portless form too: JoinHostPort("::1","") is not
 		// valid, so bracket manually when needed.
@@ -309,9 +510,21 @@ func httpOrigin(host string, port int) []string {
 		if strings.Contains(host, ":") {
 			bare = "[" + host + "]"
 		}
-		return []string{origin, "http://" + bare}
+		origins = append(origins, scheme+"://"+bare)
 	}
-	return []string{origin}
+	return origins
+}
+
+// defaultPortScheme returns the scheme whose default port is
+// port, so Host and Origin values may omit it, or "" if none.
+func defaultPortScheme(port int) string {
+	switch port {
+	case 80:
+		return "http"
+	case 443:
+		return "https"
+	}
+	return ""
 }
 
 // buildAllowedOrigins returns the set of origins that should be
@@ -380,8 +593,9 @@ func parseHostHeader(hostHeader string, port int) (string, bool) {
 	if err == nil {
 		return host, gotPort == strconv.Itoa(port)
 	}
-	// Browsers may omit :80 from Host for default HTTP port.
-	if port != 80 {
+	// Browsers may omit :80 or :443 from Host for the
+	// scheme's default port.
+	if defaultPortScheme(port) == "" {
 		return "", false
 	}
 	host = hostHeader
@@ -430,22 +644,40 @@ func localInterfaceIPs() map[string]bool {
 	return ips
 }
 
-// ListenAndServe starts the HTTP server.
+// ListenAndServe starts the HTTP server, serving HTTPS when a
+// certificate is configured.
 func (s *Server) ListenAndServe() error {
-	addr := fmt.Sprintf("%s:%d", s.cfg.Host, s.cfg.Port)
-	srv := &http.Server{
-		Addr:        addr,
-		Handler:     s.Handler(),
-		ReadTimeout: 10 * time.Second,
-		IdleTimeout: 120 * time.Second,
-	}
+	srv := s.newHTTPServer()
 	s.mu.Lock()
 	s.httpSrv = srv
 	s.mu.Unlock()
-	log.Printf("Starting server at http://%s", addr)
+	if s.cfg.TLSEnabled() {
+		slog.Info("starting server", "addr", "https://"+srv.Addr)
+		return srv.ListenAndServeTLS(s.cfg.TLSCert, s.cfg.TLSKey)
+	}
+	slog.Info("starting server", "addr", "http://"+srv.Addr)
 	return srv.ListenAndServe()
 }
 
+// newHTTPServer builds the http.Server for the configured
+// address. HTTP/2 is offered over TLS and, for clients and
+// proxies that speak it with prior knowledge, over cleartext
+// (h2c). Only headers are read under a deadline, so slow links
+// can still upload large sessions.
+func (s *Server) newHTTPServer() *http.Server {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Server{
+		Addr:              net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port)),
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		Protocols:         &protocols,
+	}
+}
+
 // Shutdown gracefully shuts down the HTTP server.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.mu.RLock()
@@ -540,13 +772,14 @@ func corsMiddleware(
 }
 
 // isAllowedBindAllOrigin returns true when Origin is an http://
-// local-interface IP-literal origin using the configured server port.
+// or https:// local-interface IP-literal origin using the
+// configured server port.
 func isAllowedBindAllOrigin(origin string, port int, allowedIPs map[string]bool) bool {
 	u, err := url.Parse(origin)
 	if err != nil || u == nil {
 		return false
 	}
-	if u.Scheme != "http" || u.Host == "" {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
 		return false
 	}
 	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
@@ -558,8 +791,8 @@ func isAllowedBindAllOrigin(origin string, port int, allowedIPs map[string]bool)
 	}
 	gotPort := u.Port()
 	portOK := false
-	if port == 80 {
-		portOK = gotPort == "" || gotPort == "80"
+	if defaultPortScheme(port) == u.Scheme {
+		portOK = gotPort == "" || gotPort == strconv.Itoa(port)
 	} else {
 		portOK = gotPort == strconv.Itoa(port)
 	}
@@ -604,7 +837,9 @@ func ensureVaryHeader(h http.Header, token string) {
 func logMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasPrefix(r.URL.Path, "/api/") {
-			log.Printf("%s %s", r.Method, r.URL.Path)
+			slog.Debug(
+				"request", "method", r.Method, "path", r.URL.Path,
+			)
 		}
 		next.ServeHTTP(w, r)
 	})
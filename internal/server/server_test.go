@@ -1,6 +1,7 @@
 package server_test
 
 import (
+	"archive/zip"
 	"bufio"
 	"bytes"
 	"context"
@@ -13,15 +14,19 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	stdlibsync "sync"
 	"testing"
 	"time"
 
+	"github.com/wesm/agentsview/internal/alert"
 	"github.com/wesm/agentsview/internal/config"
 	"github.com/wesm/agentsview/internal/db"
 	"github.com/wesm/agentsview/internal/dbtest"
+	"github.com/wesm/agentsview/internal/locale"
 	"github.com/wesm/agentsview/internal/parser"
+	"github.com/wesm/agentsview/internal/pricing"
 	"github.com/wesm/agentsview/internal/server"
 	"github.com/wesm/agentsview/internal/sync"
 	"github.com/wesm/agentsview/internal/testjsonl"
@@ -559,6 +564,11 @@ type searchResponse struct {
 	Count   int               `json:"count"`
 }
 
+type searchFacetsResponse struct {
+	Query string `json:"query"`
+	db.SearchFacets
+}
+
 type projectListResponse struct {
 	Projects []db.ProjectInfo `json:"projects"`
 }
@@ -644,6 +654,48 @@ func TestListSessions_ProjectFilter(t *testing.T) {
 	}
 }
 
+func TestListSessions_QueryFilter(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 5, func(s *db.Session) {
+		s.FirstMessage = dbtest.Ptr("Fix the login redirect")
+	})
+	te.seedSession(t, "s2", "my-app", 3, func(s *db.Session) {
+		s.FirstMessage = dbtest.Ptr("Add a login page")
+	})
+
+	w := te.get(t, "/api/v1/sessions?q=login+redirect")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[sessionListResponse](t, w)
+	if len(resp.Sessions) != 1 || resp.Sessions[0].ID != "s1" {
+		t.Errorf("sessions = %+v, want only s1", resp.Sessions)
+	}
+	if resp.Total != 1 {
+		t.Errorf("total = %d, want 1", resp.Total)
+	}
+}
+
+func TestListSessions_StartedEndedFilters(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 5)
+	te.seedSession(t, "s2", "my-app", 5, func(s *db.Session) {
+		s.EndedAt = dbtest.Ptr("2025-01-20T09:00:00Z")
+	})
+
+	// Offsets are normalized to UTC before comparing:
+	// 12:00+02:00 is 10:00Z, the seeded start.
+	w := te.get(t, "/api/v1/sessions?"+
+		"started_after=2025-01-15T12:00:00%2B02:00&"+
+		"ended_after=2025-01-16T00:00:00Z")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[sessionListResponse](t, w)
+	if len(resp.Sessions) != 1 || resp.Sessions[0].ID != "s2" {
+		t.Errorf("sessions = %+v, want only s2", resp.Sessions)
+	}
+
+	w = te.get(t, "/api/v1/sessions?ended_before=yesterday")
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
 func TestListSessions_ExcludeProjectFilter(t *testing.T) {
 	te := setup(t)
 	te.seedSession(t, "s1", "my-app", 5)
@@ -801,6 +853,36 @@ func TestGetMessages_DescWithFrom(t *testing.T) {
 	}
 }
 
+func TestStreamMessages(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 20)
+	te.seedMessages(t, "s1", 20)
+
+	w := te.get(t, "/api/v1/sessions/s1/messages/stream?from=5")
+	assertStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	var ordinals []int
+	sc := bufio.NewScanner(w.Body)
+	for sc.Scan() {
+		var m db.Message
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("decoding line %q: %v", sc.Text(), err)
+		}
+		ordinals = append(ordinals, m.Ordinal)
+	}
+	if len(ordinals) != 15 || ordinals[0] != 5 || ordinals[14] != 19 {
+		t.Errorf("ordinals = %v, want 5..19", ordinals)
+	}
+
+	w = te.get(t, "/api/v1/sessions/missing/messages/stream")
+	assertStatus(t, w, http.StatusNotFound)
+	w = te.get(t, "/api/v1/sessions/s1/messages/stream?from=x")
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
 func TestGetMessages_Pagination(t *testing.T) {
 	te := setup(t)
 	te.seedSession(t, "s1", "my-app", 20)
@@ -855,6 +937,85 @@ func TestGetMessages_InvalidParams(t *testing.T) {
 	}
 }
 
+func TestGetMessageContext(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 20)
+	te.seedMessages(t, "s1", 20, func(i int, m *db.Message) {
+		if i == 9 {
+			m.HasToolUse = true
+			m.ToolCalls = []db.ToolCall{{
+				ToolName: "Read", Category: "Read",
+				ToolUseID: "t1",
+			}}
+		}
+	})
+
+	w := te.get(t, "/api/v1/sessions/s1/context?ordinal=10&radius=2")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[messageListResponse](t, w)
+	if len(resp.Messages) != 5 {
+		t.Fatalf("expected 5 messages, got %d", len(resp.Messages))
+	}
+	if resp.Messages[0].Ordinal != 8 || resp.Messages[4].Ordinal != 12 {
+		t.Errorf("ordinals = %d..%d, want 8..12",
+			resp.Messages[0].Ordinal, resp.Messages[4].Ordinal)
+	}
+	if tc := resp.Messages[1].ToolCalls; len(tc) != 1 ||
+		tc[0].ToolName != "Read" {
+		t.Errorf("tool calls = %+v, want Read", tc)
+	}
+
+	// Default radius, clipped at the start of the session.
+	w = te.get(t, "/api/v1/sessions/s1/context?ordinal=1")
+	assertStatus(t, w, http.StatusOK)
+	resp = decode[messageListResponse](t, w)
+	if len(resp.Messages) != 7 || resp.Messages[0].Ordinal != 0 {
+		t.Errorf("expected ordinals 0..6, got %d messages",
+			len(resp.Messages))
+	}
+
+	for _, path := range []string{
+		"/api/v1/sessions/s1/context",
+		"/api/v1/sessions/s1/context?ordinal=x",
+		"/api/v1/sessions/s1/context?ordinal=1&radius=-1",
+	} {
+		w = te.get(t, path)
+		assertStatus(t, w, http.StatusBadRequest)
+	}
+}
+
+func TestGetMessages_BySourceUUID(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 10)
+	te.seedMessages(t, "s1", 10, func(i int, m *db.Message) {
+		m.SourceUUID = fmt.Sprintf("uuid-%d", i)
+	})
+
+	w := te.get(t, "/api/v1/sessions/s1/messages?uuid=uuid-4&limit=3")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[messageListResponse](t, w)
+	if len(resp.Messages) != 3 || resp.Messages[0].Ordinal != 4 ||
+		resp.Messages[0].SourceUUID != "uuid-4" {
+		t.Errorf("messages = %+v, want 3 from ordinal 4", resp.Messages)
+	}
+
+	w = te.get(t, "/api/v1/sessions/s1/context?uuid=uuid-6&radius=1")
+	assertStatus(t, w, http.StatusOK)
+	resp = decode[messageListResponse](t, w)
+	if len(resp.Messages) != 3 || resp.Messages[1].Ordinal != 6 {
+		t.Errorf("context = %+v, want ordinals 5..7", resp.Messages)
+	}
+
+	for _, path := range []string{
+		"/api/v1/sessions/s1/messages?uuid=missing",
+		"/api/v1/sessions/s1/context?uuid=missing",
+		"/api/v1/sessions/s2/messages?uuid=uuid-4",
+	} {
+		w = te.get(t, path)
+		assertStatus(t, w, http.StatusNotFound)
+	}
+}
+
 func TestListSessions_InvalidLimit(t *testing.T) {
 	te := setup(t)
 
@@ -879,6 +1040,11 @@ func TestSearch_InvalidParams(t *testing.T) {
 		{"InvalidLimit", "/api/v1/search?q=test&limit=nope"},
 		{"InvalidCursor", "/api/v1/search?q=test&cursor=bad"},
 		{"EmptyQuery", "/api/v1/search"},
+		{"InvalidRole", "/api/v1/search?q=test&role=tool"},
+		{"InvalidHasToolUse", "/api/v1/search?q=test&has_tool_use=maybe"},
+		{"InvalidDate", "/api/v1/search?q=test&date_from=June"},
+		{"ReversedDates",
+			"/api/v1/search?q=test&date_from=2024-06-02&date_to=2024-06-01"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1020,6 +1186,530 @@ func TestSearch_WithResults(t *testing.T) {
 	}
 }
 
+func TestSearch_SessionScope(t *testing.T) {
+	te := setup(t)
+	if !te.db.HasFTS() {
+		t.Skip("skipping search test: no FTS support")
+	}
+	for _, id := range []string{"s1", "s2"} {
+		te.seedSession(t, id, "my-app", 4)
+		te.seedMessages(t, id, 4, func(i int, m *db.Message) {
+			if i%2 == 1 {
+				m.Content = "deploy the widget"
+				m.ContentLength = len(m.Content)
+			}
+		})
+	}
+
+	w := te.get(t, "/api/v1/search?q=widget&session_id=s2")
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[searchResponse](t, w)
+	if resp.Count != 2 {
+		t.Fatalf("expected 2 results, got %d", resp.Count)
+	}
+	for i, want := range []int{1, 3} {
+		r := resp.Results[i]
+		if r.SessionID != "s2" || r.Ordinal != want {
+			t.Errorf("result %d = %s#%d, want s2#%d",
+				i, r.SessionID, r.Ordinal, want)
+		}
+	}
+}
+
+func TestBulkSessions(t *testing.T) {
+	te := setup(t)
+	if !te.db.HasFTS() {
+		t.Skip("skipping bulk search test: no FTS support")
+	}
+	for _, id := range []string{"s1", "s2", "s3"} {
+		te.seedSession(t, id, "my-app", 2)
+		te.seedMessages(t, id, 2, func(i int, m *db.Message) {
+			if id != "s3" && i == 0 {
+				m.Content = "flaky widget test"
+				m.ContentLength = len(m.Content)
+			}
+		})
+	}
+
+	type bulkResp struct {
+		Count   int    `json:"count"`
+		Applied bool   `json:"applied"`
+		Token   string `json:"token"`
+	}
+	body := `{"action":"tag","tag":"flaky","filter":{"q":"widget"}}`
+
+	w := te.post(t, "/api/v1/sessions/bulk", body)
+	assertStatus(t, w, http.StatusOK)
+	preview := decode[bulkResp](t, w)
+	if preview.Count != 2 || preview.Applied || preview.Token == "" {
+		t.Fatalf("preview = %+v, want count 2 with token", preview)
+	}
+
+	t.Run("StaleToken", func(t *testing.T) {
+		w := te.post(t, "/api/v1/sessions/bulk",
+			`{"action":"tag","tag":"other","filter":{"q":"widget"},"token":"`+
+				preview.Token+`"}`)
+		assertStatus(t, w, http.StatusConflict)
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		w := te.post(t, "/api/v1/sessions/bulk",
+			`{"action":"tag","tag":"flaky","filter":{"q":"widget"},"token":"`+
+				preview.Token+`"}`)
+		assertStatus(t, w, http.StatusOK)
+		if got := decode[bulkResp](t, w); !got.Applied || got.Count != 2 {
+			t.Fatalf("confirm = %+v, want applied count 2", got)
+		}
+
+		w = te.get(t, "/api/v1/sessions?tag=flaky")
+		assertStatus(t, w, http.StatusOK)
+		if page := decode[db.SessionPage](t, w); page.Total != 2 {
+			t.Errorf("tagged total = %d, want 2", page.Total)
+		}
+	})
+
+	t.Run("Archive", func(t *testing.T) {
+		w := te.post(t, "/api/v1/sessions/bulk",
+			`{"action":"archive","filter":{"tag":"flaky"}}`)
+		preview := decode[bulkResp](t, w)
+		w = te.post(t, "/api/v1/sessions/bulk",
+			`{"action":"archive","filter":{"tag":"flaky"},"token":"`+
+				preview.Token+`"}`)
+		assertStatus(t, w, http.StatusOK)
+
+		w = te.get(t, "/api/v1/sessions")
+		if page := decode[db.SessionPage](t, w); page.Total != 1 {
+			t.Errorf("default total = %d, want 1", page.Total)
+		}
+		w = te.get(t, "/api/v1/sessions?include_archived=true")
+		if page := decode[db.SessionPage](t, w); page.Total != 3 {
+			t.Errorf("include_archived total = %d, want 3", page.Total)
+		}
+	})
+
+	bad := []struct {
+		name string
+		body string
+	}{
+		{"UnknownAction", `{"action":"delete"}`},
+		{"MissingTag", `{"action":"tag"}`},
+		{"BadDate", `{"action":"mute","filter":{"date_from":"yesterday"}}`},
+		{"BadJSON", `{`},
+	}
+	for _, tt := range bad {
+		t.Run(tt.name, func(t *testing.T) {
+			w := te.post(t, "/api/v1/sessions/bulk", tt.body)
+			assertStatus(t, w, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestPinSession(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "old", "my-app", 2, func(s *db.Session) {
+		s.EndedAt = dbtest.Ptr("2024-06-01T00:00:00Z")
+	})
+	te.seedSession(t, "new", "my-app", 2, func(s *db.Session) {
+		s.EndedAt = dbtest.Ptr("2024-06-02T00:00:00Z")
+	})
+
+	pin := func(method, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method,
+			"/api/v1/sessions/"+id+"/pin", nil)
+		w := httptest.NewRecorder()
+		te.handler.ServeHTTP(w, req)
+		return w
+	}
+	listIDs := func() []string {
+		w := te.get(t, "/api/v1/sessions")
+		assertStatus(t, w, http.StatusOK)
+		var ids []string
+		for _, s := range decode[db.SessionPage](t, w).Sessions {
+			ids = append(ids, s.ID)
+		}
+		return ids
+	}
+
+	assertStatus(t, pin(http.MethodPut, "old"), http.StatusOK)
+	if got := listIDs(); !slices.Equal(got, []string{"old", "new"}) {
+		t.Errorf("pinned list = %v, want [old new]", got)
+	}
+	w := te.get(t, "/api/v1/sessions/old")
+	if !decode[db.Session](t, w).Pinned {
+		t.Error("session detail not marked pinned")
+	}
+
+	assertStatus(t, pin(http.MethodDelete, "old"), http.StatusOK)
+	if got := listIDs(); !slices.Equal(got, []string{"new", "old"}) {
+		t.Errorf("unpinned list = %v, want [new old]", got)
+	}
+	assertStatus(t, pin(http.MethodPut, "missing"), http.StatusNotFound)
+}
+
+func TestArchiveSession(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 2)
+	te.seedSession(t, "s2", "my-app", 2)
+
+	archive := func(method, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method,
+			"/api/v1/sessions/"+id+"/archive", nil)
+		w := httptest.NewRecorder()
+		te.handler.ServeHTTP(w, req)
+		return w
+	}
+	listIDs := func(query string) []string {
+		w := te.get(t, "/api/v1/sessions"+query)
+		assertStatus(t, w, http.StatusOK)
+		var ids []string
+		for _, s := range decode[db.SessionPage](t, w).Sessions {
+			ids = append(ids, s.ID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+
+	w := archive(http.MethodPut, "s1")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[map[string]any](t, w); got["archived"] != true {
+		t.Errorf("archive response = %v", got)
+	}
+	if got := listIDs(""); !slices.Equal(got, []string{"s2"}) {
+		t.Errorf("default list = %v, want [s2]", got)
+	}
+	if got := listIDs("?archived=true"); !slices.Equal(got, []string{"s1"}) {
+		t.Errorf("archived list = %v, want [s1]", got)
+	}
+	if !decode[db.Session](t, te.get(t, "/api/v1/sessions/s1")).Archived {
+		t.Error("session detail not marked archived")
+	}
+
+	assertStatus(t, archive(http.MethodDelete, "s1"), http.StatusOK)
+	if got := listIDs(""); !slices.Equal(got, []string{"s1", "s2"}) {
+		t.Errorf("unarchived list = %v, want [s1 s2]", got)
+	}
+	assertStatus(t, te.get(t, "/api/v1/sessions?pinned=maybe"),
+		http.StatusBadRequest)
+	assertStatus(t, archive(http.MethodPut, "missing"), http.StatusNotFound)
+}
+
+func TestSessionTags(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 2)
+	te.seedSession(t, "s2", "my-app", 2)
+
+	tags := func(w *httptest.ResponseRecorder) []string {
+		t.Helper()
+		assertStatus(t, w, http.StatusOK)
+		return decode[struct {
+			Tags []string `json:"tags"`
+		}](t, w).Tags
+	}
+	del := func(id, tag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete,
+			"/api/v1/sessions/"+id+"/tags?tag="+tag, nil)
+		w := httptest.NewRecorder()
+		te.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	te.post(t, "/api/v1/sessions/s1/tags", `{"tag":"refactor"}`)
+	got := tags(te.post(t, "/api/v1/sessions/s1/tags", `{"tag":"wasted"}`))
+	if !slices.Equal(got, []string{"refactor", "wasted"}) {
+		t.Errorf("tags after add = %v, want [refactor wasted]", got)
+	}
+
+	w := te.get(t, "/api/v1/sessions?tag=wasted")
+	assertStatus(t, w, http.StatusOK)
+	page := decode[db.SessionPage](t, w)
+	if len(page.Sessions) != 1 || page.Sessions[0].ID != "s1" {
+		t.Errorf("tag filter = %+v, want only s1", page.Sessions)
+	}
+
+	w = te.get(t, "/api/v1/tags")
+	assertStatus(t, w, http.StatusOK)
+	counts := decode[struct {
+		Tags []db.TagCount `json:"tags"`
+	}](t, w).Tags
+	if len(counts) != 2 || counts[0] != (db.TagCount{Tag: "refactor", Count: 1}) {
+		t.Errorf("tag counts = %+v", counts)
+	}
+
+	if got := tags(del("s1", "wasted")); !slices.Equal(got, []string{"refactor"}) {
+		t.Errorf("tags after delete = %v, want [refactor]", got)
+	}
+	if got := tags(te.get(t, "/api/v1/sessions/s1/tags")); !slices.Equal(got, []string{"refactor"}) {
+		t.Errorf("GET tags = %v, want [refactor]", got)
+	}
+
+	assertStatus(t, te.post(t, "/api/v1/sessions/s1/tags", `{"tag":" "}`),
+		http.StatusBadRequest)
+	assertStatus(t, del("s1", ""), http.StatusBadRequest)
+	assertStatus(t, te.post(t, "/api/v1/sessions/missing/tags", `{"tag":"x"}`),
+		http.StatusNotFound)
+}
+
+func TestSessionNotes(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 4)
+
+	w := te.post(t, "/api/v1/sessions/s1/notes",
+		`{"body":"Agent ignored the *failing* test","ordinal":2}`)
+	assertStatus(t, w, http.StatusCreated)
+	note := decode[db.SessionNote](t, w)
+	if note.Ordinal == nil || *note.Ordinal != 2 {
+		t.Errorf("created note = %+v", note)
+	}
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		te.handler.ServeHTTP(w, req)
+		return w
+	}
+	notePath := fmt.Sprintf("/api/v1/sessions/s1/notes/%d", note.ID)
+	w = do(http.MethodPut, notePath, `{"body":"Agent skipped the flaky test"}`)
+	assertStatus(t, w, http.StatusOK)
+
+	w = te.get(t, "/api/v1/sessions/s1")
+	assertStatus(t, w, http.StatusOK)
+	notes := decode[db.Session](t, w).Notes
+	if len(notes) != 1 || notes[0].Body != "Agent skipped the flaky test" {
+		t.Errorf("session notes = %+v", notes)
+	}
+
+	if te.db.HasFTS() {
+		w = te.get(t, "/api/v1/search/notes?q=flaky")
+		assertStatus(t, w, http.StatusOK)
+		res := decode[struct {
+			Results []db.NoteSearchResult `json:"results"`
+		}](t, w).Results
+		if len(res) != 1 || res[0].SessionID != "s1" {
+			t.Errorf("note search = %+v", res)
+		}
+	}
+
+	assertStatus(t, do(http.MethodDelete, notePath, ""), http.StatusNoContent)
+	assertStatus(t, do(http.MethodDelete, notePath, ""), http.StatusNotFound)
+
+	for name, tc := range map[string]struct {
+		path, body string
+		want       int
+	}{
+		"blank body":      {"/api/v1/sessions/s1/notes", `{"body":" "}`, http.StatusBadRequest},
+		"bad ordinal":     {"/api/v1/sessions/s1/notes", `{"body":"x","ordinal":9}`, http.StatusBadRequest},
+		"invalid json":    {"/api/v1/sessions/s1/notes", `{`, http.StatusBadRequest},
+		"unknown session": {"/api/v1/sessions/nope/notes", `{"body":"x"}`, http.StatusNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assertStatus(t, te.post(t, tc.path, tc.body), tc.want)
+		})
+	}
+}
+
+func TestPruneSessions(t *testing.T) {
+	te := setup(t)
+	dir := t.TempDir()
+	for _, id := range []string{"s1", "s2", "s3"} {
+		path := filepath.Join(dir, id+".jsonl")
+		if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		proj := "scratch"
+		if id == "s3" {
+			proj = "keep"
+		}
+		te.seedSession(t, id, proj, 2, func(s *db.Session) {
+			s.FilePath = dbtest.Ptr(path)
+			s.FileSize = dbtest.Ptr(int64(10))
+		})
+	}
+
+	type previewResp struct {
+		Total      int          `json:"total"`
+		TotalBytes int64        `json:"total_bytes"`
+		Sessions   []db.Session `json:"sessions"`
+		Token      string       `json:"token"`
+	}
+	type executeResp struct {
+		Deleted        int   `json:"deleted"`
+		FilesRemoved   int   `json:"files_removed"`
+		BytesReclaimed int64 `json:"bytes_reclaimed"`
+	}
+	body := `{"project":"scratch","limit":1}`
+
+	w := te.post(t, "/api/v1/prune/preview", body)
+	assertStatus(t, w, http.StatusOK)
+	preview := decode[previewResp](t, w)
+	if preview.Total != 2 || preview.TotalBytes != 20 ||
+		len(preview.Sessions) != 1 || preview.Token == "" {
+		t.Fatalf("preview = %+v, want 2 sessions, 20 bytes, page of 1",
+			preview)
+	}
+
+	t.Run("MissingToken", func(t *testing.T) {
+		w := te.post(t, "/api/v1/prune/execute", body)
+		assertStatus(t, w, http.StatusBadRequest)
+	})
+
+	t.Run("StaleToken", func(t *testing.T) {
+		w := te.post(t, "/api/v1/prune/execute",
+			`{"project":"keep","token":"`+preview.Token+`"}`)
+		assertStatus(t, w, http.StatusConflict)
+	})
+
+	t.Run("Execute", func(t *testing.T) {
+		w := te.post(t, "/api/v1/prune/execute",
+			`{"project":"scratch","token":"`+preview.Token+`"}`)
+		assertStatus(t, w, http.StatusOK)
+		got := decode[executeResp](t, w)
+		if want := (executeResp{Deleted: 2}); got != want {
+			t.Fatalf("execute = %+v, want %+v", got, want)
+		}
+
+		w = te.get(t, "/api/v1/sessions")
+		if page := decode[db.SessionPage](t, w); page.Total != 1 {
+			t.Errorf("remaining sessions = %d, want 1", page.Total)
+		}
+		// Files stay on disk without delete_files.
+		for _, id := range []string{"s1", "s2", "s3"} {
+			if _, err := os.Stat(filepath.Join(dir, id+".jsonl")); err != nil {
+				t.Errorf("session file %s: %v", id, err)
+			}
+		}
+	})
+
+	t.Run("DeleteFiles", func(t *testing.T) {
+		for _, id := range []string{"local1", "pushed1"} {
+			path := filepath.Join(dir, id+".jsonl")
+			if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			te.seedSession(t, id, "trash", 2, func(s *db.Session) {
+				s.FilePath = dbtest.Ptr(path)
+				s.FileSize = dbtest.Ptr(int64(10))
+				if id == "pushed1" {
+					s.Machine = "laptop"
+				}
+			})
+		}
+		w := te.post(t, "/api/v1/prune/preview", `{"project":"trash"}`)
+		token := decode[previewResp](t, w).Token
+
+		w = te.post(t, "/api/v1/prune/execute",
+			`{"project":"trash","delete_files":true,"token":"`+token+`"}`)
+		assertStatus(t, w, http.StatusOK)
+		got := decode[executeResp](t, w)
+		want := executeResp{
+			Deleted: 2, FilesRemoved: 1, BytesReclaimed: 10,
+		}
+		if got != want {
+			t.Fatalf("execute = %+v, want %+v", got, want)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "local1.jsonl")); !os.IsNotExist(err) {
+			t.Errorf("local session file not removed: %v", err)
+		}
+		// A pushed session's path belongs to another machine.
+		if _, err := os.Stat(filepath.Join(dir, "pushed1.jsonl")); err != nil {
+			t.Errorf("pushed session file: %v", err)
+		}
+	})
+
+	t.Run("IncludeChain", func(t *testing.T) {
+		te.seedSession(t, "p1", "chain", 2)
+		te.seedSession(t, "c1", "chain", 2, func(s *db.Session) {
+			s.ParentSessionID = dbtest.Ptr("p1")
+		})
+		w := te.post(t, "/api/v1/prune/preview", `{"project":"chain"}`)
+		if got := decode[previewResp](t, w); got.Total != 1 {
+			t.Errorf("default total = %d, want 1 (parent kept)", got.Total)
+		}
+		w = te.post(t, "/api/v1/prune/preview",
+			`{"project":"chain","include_chain":true}`)
+		if got := decode[previewResp](t, w); got.Total != 2 {
+			t.Errorf("include_chain total = %d, want 2", got.Total)
+		}
+	})
+
+	bad := []struct {
+		name string
+		body string
+	}{
+		{"NoFilters", `{}`},
+		{"NegativeMaxMessages", `{"max_messages":-1}`},
+		{"BadBefore", `{"before":"yesterday"}`},
+		{"BadJSON", `{`},
+	}
+	for _, tt := range bad {
+		t.Run(tt.name, func(t *testing.T) {
+			w := te.post(t, "/api/v1/prune/preview", tt.body)
+			assertStatus(t, w, http.StatusBadRequest)
+		})
+	}
+}
+func TestSearchFacets(t *testing.T) {
+	te := setup(t)
+	if !te.db.HasFTS() {
+		t.Skip("skipping search test: no FTS support")
+	}
+	te.seedSession(t, "s1", "my-app", 3, func(s *db.Session) {
+		s.Agent = "claude"
+	})
+	te.seedMessages(t, "s1", 3, func(i int, m *db.Message) {
+		m.Content = "deploy the widget"
+		if i == 2 {
+			m.Timestamp = "2025-02-03T10:00:00Z"
+		}
+	})
+	te.seedSession(t, "s2", "other", 2, func(s *db.Session) {
+		s.Agent = "codex"
+	})
+	te.seedMessages(t, "s2", 2, func(i int, m *db.Message) {
+		if i == 0 {
+			m.Content = "widget again"
+		}
+	})
+
+	w := te.get(t, "/api/v1/search/facets?q=widget")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[searchFacetsResponse](t, w)
+	if resp.Query != "widget" || resp.Total != 4 {
+		t.Fatalf("resp = %+v, want 4 hits for widget", resp)
+	}
+	if got := fmt.Sprint(resp.Projects); got != "[{my-app 3} {other 1}]" {
+		t.Errorf("Projects = %s, want [{my-app 3} {other 1}]", got)
+	}
+	if got := fmt.Sprint(resp.Agents); got != "[{claude 3} {codex 1}]" {
+		t.Errorf("Agents = %s, want [{claude 3} {codex 1}]", got)
+	}
+	if got := fmt.Sprint(resp.Months); got != "[{2025-02 1} {2025-01 3}]" {
+		t.Errorf("Months = %s, want [{2025-02 1} {2025-01 3}]", got)
+	}
+
+	// Facet values drill into both facets and results.
+	w = te.get(t, "/api/v1/search/facets?q=widget&agent=claude&month=2025-01")
+	assertStatus(t, w, http.StatusOK)
+	if resp := decode[searchFacetsResponse](t, w); resp.Total != 2 {
+		t.Errorf("drill-down total = %d, want 2", resp.Total)
+	}
+	w = te.get(t, "/api/v1/search?q=widget&month=2025-02")
+	assertStatus(t, w, http.StatusOK)
+	if resp := decode[searchResponse](t, w); resp.Count != 1 ||
+		resp.Results[0].Ordinal != 2 {
+		t.Errorf("month results = %+v, want s1#2", resp.Results)
+	}
+
+	for _, path := range []string{
+		"/api/v1/search/facets",
+		"/api/v1/search/facets?q=widget&month=2025-13",
+		"/api/v1/search?q=widget&month=feb",
+	} {
+		w = te.get(t, path)
+		assertStatus(t, w, http.StatusBadRequest)
+	}
+}
+
 func TestSearch_Limits(t *testing.T) {
 	te := setup(t)
 	if !te.db.HasFTS() {
@@ -1178,6 +1868,63 @@ func TestListProjects(t *testing.T) {
 	}
 }
 
+func TestListProjects_HidesInactive(t *testing.T) {
+	te := setup(t, func(c *config.Config) {
+		c.InactiveProjectDays = 30
+	})
+	recent := time.Now().UTC().Add(-24 * time.Hour).Format(time.RFC3339)
+	te.seedSession(t, "s1", "live-app", 2, func(s *db.Session) {
+		s.StartedAt = dbtest.Ptr(recent)
+		s.EndedAt = dbtest.Ptr(recent)
+	})
+	te.seedSession(t, "s2", "dead-app", 2)
+
+	w := te.get(t, "/api/v1/projects")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		Projects      []db.ProjectInfo `json:"projects"`
+		InactiveCount int              `json:"inactive_count"`
+	}](t, w)
+	if len(resp.Projects) != 1 || resp.Projects[0].Name != "live-app" {
+		t.Errorf("projects = %+v, want live-app only", resp.Projects)
+	}
+	if resp.InactiveCount != 1 {
+		t.Errorf("inactive_count = %d, want 1", resp.InactiveCount)
+	}
+
+	w = te.get(t, "/api/v1/projects?include_inactive=true")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[projectListResponse](t, w); len(got.Projects) != 2 {
+		t.Errorf("include_inactive projects = %+v, want 2", got.Projects)
+	}
+
+	from := "2025-01-01"
+	to := time.Now().UTC().Format("2006-01-02")
+	summary := func(params map[string]string) db.AnalyticsSummary {
+		t.Helper()
+		params["from"], params["to"] = from, to
+		w := te.get(t, buildURL("summary", params))
+		assertStatus(t, w, http.StatusOK)
+		return decode[db.AnalyticsSummary](t, w)
+	}
+	if got := summary(map[string]string{}); got.TotalSessions != 1 {
+		t.Errorf("summary sessions = %d, want 1", got.TotalSessions)
+	}
+	if got := summary(map[string]string{
+		"include_inactive": "true",
+	}); got.TotalSessions != 2 {
+		t.Errorf("include_inactive sessions = %d, want 2", got.TotalSessions)
+	}
+	if got := summary(map[string]string{
+		"project": "dead-app",
+	}); got.TotalSessions != 1 {
+		t.Errorf("explicit project sessions = %d, want 1", got.TotalSessions)
+	}
+
+	w = te.get(t, "/api/v1/projects?include_inactive=maybe")
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
 func TestSyncStatus(t *testing.T) {
 	te := setup(t)
 
@@ -1634,9 +2381,10 @@ func TestCORSVaryAlwaysSet(t *testing.T) {
 	te.handler.ServeHTTP(w, req)
 	assertStatus(t, w, http.StatusOK)
 
+	// Compression adds Accept-Encoding alongside it.
 	vary := w.Header().Get("Vary")
-	if vary != "Origin" {
-		t.Fatalf("expected Vary: Origin, got %q", vary)
+	if vary != "Origin, Accept-Encoding" {
+		t.Fatalf("expected Vary: Origin, Accept-Encoding, got %q", vary)
 	}
 }
 
@@ -1689,6 +2437,35 @@ func TestGetGithubConfig(t *testing.T) {
 	}
 }
 
+func TestGetLocale(t *testing.T) {
+	type localeResp struct {
+		Configured bool `json:"configured"`
+		locale.Format
+	}
+
+	te := setup(t)
+	w := te.get(t, "/api/v1/config/locale")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[localeResp](t, w)
+	if resp.Configured || resp.Format != locale.Default {
+		t.Errorf("unconfigured locale = %+v", resp)
+	}
+
+	te = setup(t, func(c *config.Config) {
+		c.Locale = config.LocaleSettings{
+			Tag: "de-DE", WeekStart: "sunday",
+		}
+	})
+	w = te.get(t, "/api/v1/config/locale")
+	assertStatus(t, w, http.StatusOK)
+	resp = decode[localeResp](t, w)
+	if !resp.Configured || resp.Locale != "de-DE" ||
+		resp.DecimalSeparator != "," || resp.Currency != "EUR" ||
+		resp.WeekStart != locale.Sunday {
+		t.Errorf("de-DE locale = %+v", resp)
+	}
+}
+
 func TestExportSession(t *testing.T) {
 	te := setup(t)
 	te.seedSession(t, "s1", "my-app", 3)
@@ -1708,6 +2485,74 @@ func TestExportSession(t *testing.T) {
 	assertBodyContains(t, w, "my-app")
 }
 
+func TestExportSession_Formats(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 2)
+	te.seedMessages(t, "s1", 2, func(i int, m *db.Message) {
+		if i == 1 {
+			m.Content = "[Thinking]\nweigh it\n[/Thinking]\nDone."
+		}
+	})
+
+	w := te.get(t, "/api/v1/sessions/s1/export?format=md")
+	assertStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); !strings.Contains(ct, "markdown") {
+		t.Errorf("Content-Type = %q, want markdown", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, ".md") {
+		t.Errorf("Content-Disposition = %q, want .md file", cd)
+	}
+	assertBodyContains(t, w, "# my-app")
+	assertBodyContains(t, w, "## Assistant")
+	assertBodyContains(t, w, "<summary>Thinking</summary>")
+
+	w = te.get(t, "/api/v1/sessions/s1/export?format=json")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		Session  db.Session   `json:"session"`
+		Messages []db.Message `json:"messages"`
+	}](t, w)
+	if resp.Session.ID != "s1" || len(resp.Messages) != 2 {
+		t.Errorf("json export = %+v", resp)
+	}
+
+	w = te.get(t, "/api/v1/sessions/s1/export?format=pdf")
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
+func TestExportProject(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 2)
+	te.seedMessages(t, "s1", 2)
+	te.seedSession(t, "s2", "my-app", 2)
+	te.seedMessages(t, "s2", 2)
+	te.seedSession(t, "s3", "other", 2)
+	te.seedMessages(t, "s3", 2)
+
+	w := te.get(t, "/api/v1/projects/my-app/export?format=json")
+	assertStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Fatalf("Content-Type = %q, want application/zip", ct)
+	}
+	zr, err := zip.NewReader(
+		bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()),
+	)
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if len(names) != 2 || !strings.HasSuffix(names[0], "-s1.json") ||
+		!strings.HasSuffix(names[1], "-s2.json") {
+		t.Errorf("files = %v, want s1 and s2", names)
+	}
+
+	w = te.get(t, "/api/v1/projects/missing/export")
+	assertStatus(t, w, http.StatusNotFound)
+}
+
 func TestExportSession_NotFound(t *testing.T) {
 	te := setup(t)
 
@@ -1782,7 +2627,10 @@ func TestUploadSession(t *testing.T) {
 
 	content := testjsonl.NewSessionBuilder().
 		AddClaudeUser(tsEarly, "Hello upload").
-		AddClaudeAssistant(tsEarlyS5, "Hi!").
+		AddRaw(`{"type":"assistant","timestamp":"` + tsEarlyS5 +
+			`","message":{"id":"msg_1","role":"assistant",` +
+			`"content":[{"type":"text","text":"Hi!"}],` +
+			`"usage":{"input_tokens":12,"output_tokens":3}}}`).
 		String()
 
 	w := te.upload(t, "upload-test.jsonl", content,
@@ -1813,6 +2661,16 @@ func TestUploadSession(t *testing.T) {
 	if sess.Project != "myproj" {
 		t.Errorf("stored project = %q", sess.Project)
 	}
+	msgs, err := te.db.GetAllMessages(
+		context.Background(), "upload-test",
+	)
+	if err != nil {
+		t.Fatalf("GetAllMessages: %v", err)
+	}
+	if len(msgs) != 2 || msgs[1].InputTokens != 12 ||
+		msgs[1].OutputTokens != 3 {
+		t.Errorf("messages = %+v, want reply with 12/3 tokens", msgs)
+	}
 }
 
 func TestUploadSession_InfersRelationshipType(t *testing.T) {
@@ -1885,54 +2743,416 @@ func TestUploadSession_Errors(t *testing.T) {
 			"test.jsonl", "{}", "project=foo/bar",
 		},
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			te := setup(t)
-			w := te.upload(t,
-				tt.filename, tt.content, tt.query)
-			assertStatus(t, w, http.StatusBadRequest)
-		})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			te := setup(t)
+			w := te.upload(t,
+				tt.filename, tt.content, tt.query)
+			assertStatus(t, w, http.StatusBadRequest)
+		})
+	}
+}
+
+func TestUploadSession_EmptyFile(t *testing.T) {
+	te := setup(t)
+
+	w := te.upload(t, "empty.jsonl", "",
+		"project=myproj")
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[uploadResponse](t, w)
+	if resp.Messages != 0 {
+		t.Errorf("messages = %v, want 0", resp.Messages)
+	}
+}
+
+func TestIngest(t *testing.T) {
+	te := setup(t, func(c *config.Config) { c.IngestToken = "s3cret" })
+
+	// Pushes come from other machines: no Origin, and the
+	// server's host name rather than its bind address.
+	push := func(path, token string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(
+			http.MethodPost, path, bytes.NewReader(data),
+		)
+		req.Host = "central.example:8080"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		te.handler.ServeHTTP(w, req)
+		return w
+	}
+	session := db.IngestSession{
+		Session: db.Session{
+			ID: "remote-1", Project: "proj", Machine: "laptop",
+			Agent: "claude", MessageCount: 1,
+			FileHash: dbtest.Ptr("h1"),
+		},
+		Messages: []db.Message{{Ordinal: 0, Role: "user", Content: "hi"}},
+	}
+	check := server.IngestCheckRequest{Sessions: []db.SessionSource{
+		{ID: "remote-1", FileHash: "h1"},
+	}}
+
+	w := push("/api/v1/ingest", "", server.IngestRequest{})
+	assertStatus(t, w, http.StatusUnauthorized)
+	w = push("/api/v1/ingest", "wrong", server.IngestRequest{})
+	assertStatus(t, w, http.StatusUnauthorized)
+
+	w = push("/api/v1/ingest/check", "s3cret", check)
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[server.IngestCheckResponse](t, w).Needed; !slices.Equal(got, []string{"remote-1"}) {
+		t.Errorf("needed = %v, want [remote-1]", got)
+	}
+
+	body := server.IngestRequest{Sessions: []db.IngestSession{session}}
+	w = push("/api/v1/ingest", "s3cret", body)
+	assertStatus(t, w, http.StatusOK)
+	want := []server.IngestResult{{ID: "remote-1", Status: db.IngestStored}}
+	if got := decode[server.IngestResponse](t, w).Results; !slices.Equal(got, want) {
+		t.Errorf("results = %+v, want %+v", got, want)
+	}
+	s, err := te.db.GetSession(context.Background(), "remote-1")
+	if err != nil || s == nil || s.Machine != "laptop" {
+		t.Fatalf("stored session = %+v, %v", s, err)
+	}
+
+	w = push("/api/v1/ingest/check", "s3cret", check)
+	if got := decode[server.IngestCheckResponse](t, w).Needed; len(got) != 0 {
+		t.Errorf("needed after push = %v, want none", got)
+	}
+	w = push("/api/v1/ingest", "s3cret", body)
+	want[0].Status = db.IngestUnchanged
+	if got := decode[server.IngestResponse](t, w).Results; !slices.Equal(got, want) {
+		t.Errorf("repeat results = %+v, want %+v", got, want)
+	}
+}
+
+func TestIngest_Disabled(t *testing.T) {
+	te := setup(t)
+	req := httptest.NewRequest(
+		http.MethodPost, "/api/v1/ingest", strings.NewReader("{}"),
+	)
+	req.Header.Set("Authorization", "Bearer anything")
+	w := httptest.NewRecorder()
+	te.handler.ServeHTTP(w, req)
+	assertStatus(t, w, http.StatusForbidden)
+}
+
+// noFlushWriter wraps an http.ResponseWriter without Flusher.
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+func TestTriggerSync_NonStreaming(t *testing.T) {
+	te := setup(t)
+
+	// Seed a session file so we expect at least one session in the sync result.
+	te.writeSessionFile(t, "test-proj", "sync-test.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddClaudeUser(tsZero, "msg"),
+	)
+
+	rec := httptest.NewRecorder()
+	nf := &noFlushWriter{rec}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sync", nil)
+	req.Header.Set("Content-Type", "application/json")
+	te.handler.ServeHTTP(nf, req)
+	assertStatus(t, rec, http.StatusOK)
+
+	resp := decode[syncResultResponse](t, rec)
+	if resp.TotalSessions != 1 {
+		t.Fatalf("expected 1 total_session, got %d", resp.TotalSessions)
+	}
+}
+
+func TestGetSessionSource(t *testing.T) {
+	te := setup(t)
+	path := te.writeSessionFile(t, "src-proj", "src-sess.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddClaudeUser(tsZero, "msg"),
+	)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sync", nil)
+	te.handler.ServeHTTP(&noFlushWriter{rec}, req)
+	assertStatus(t, rec, http.StatusOK)
+
+	type sourceResponse struct {
+		Path       string  `json:"path"`
+		Root       string  `json:"root"`
+		Exists     bool    `json:"exists"`
+		Size       *int64  `json:"size"`
+		StoredPath *string `json:"stored_path"`
+		StoredHash *string `json:"stored_hash"`
+		Changed    bool    `json:"changed"`
+	}
+
+	w := te.get(t, "/api/v1/sessions/src-sess/source")
+	assertStatus(t, w, http.StatusOK)
+	got := decode[sourceResponse](t, w)
+	if got.Path != path {
+		t.Errorf("path = %q, want %q", got.Path, path)
+	}
+	if got.Root != te.claudeDir {
+		t.Errorf("root = %q, want %q", got.Root, te.claudeDir)
+	}
+	if !got.Exists || got.Size == nil || *got.Size == 0 {
+		t.Errorf("expected existing file with size, got %+v", got)
+	}
+	if got.StoredPath == nil || *got.StoredPath != path {
+		t.Errorf("stored_path = %v, want %q", got.StoredPath, path)
+	}
+	if got.StoredHash == nil || *got.StoredHash == "" {
+		t.Error("expected stored_hash")
+	}
+	if got.Changed {
+		t.Error("unmodified file reported as changed")
+	}
+
+	if err := os.WriteFile(path, []byte("{}\n{}\n{}\n"), 0o644); err != nil {
+		t.Fatalf("rewriting session file: %v", err)
+	}
+	w = te.get(t, "/api/v1/sessions/src-sess/source")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[sourceResponse](t, w); !got.Changed {
+		t.Error("expected changed after rewrite")
+	}
+
+	w = te.get(t, "/api/v1/sessions/missing/source")
+	assertStatus(t, w, http.StatusNotFound)
+}
+
+func TestRevealSessionSource(t *testing.T) {
+	var opened []string
+	te := setupWithServerOpts(t, []server.Option{
+		server.WithRevealFunc(func(dir string) error {
+			opened = append(opened, dir)
+			return nil
+		}),
+	})
+	path := te.writeSessionFile(t, "rev-proj", "rev-sess.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddClaudeUser(tsZero, "msg"),
+	)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sync", nil)
+	te.handler.ServeHTTP(&noFlushWriter{rec}, req)
+	assertStatus(t, rec, http.StatusOK)
+
+	reveal := func(id, body, remote string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost,
+			"/api/v1/sessions/"+id+"/reveal", strings.NewReader(body))
+		req.RemoteAddr = remote
+		w := httptest.NewRecorder()
+		te.handler.ServeHTTP(w, req)
+		return w
+	}
+
+	const local = "127.0.0.1:50000"
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		remote string
+		want   int
+	}{
+		{"remote client", "rev-sess", `{"confirm":true}`, "192.0.2.1:1234", http.StatusForbidden},
+		{"unconfirmed", "rev-sess", `{}`, local, http.StatusBadRequest},
+		{"bad body", "rev-sess", `nope`, local, http.StatusBadRequest},
+		{"missing session", "missing", `{"confirm":true}`, local, http.StatusNotFound},
+		{"confirmed", "rev-sess", `{"confirm":true}`, local, http.StatusOK},
+		{"ipv6 loopback", "rev-sess", `{"confirm":true}`, "[::1]:50000", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertStatus(t, reveal(tt.id, tt.body, tt.remote), tt.want)
+		})
+	}
+
+	want := filepath.Dir(path)
+	if len(opened) != 2 || opened[0] != want {
+		t.Errorf("opened = %v, want [%s %s]", opened, want, want)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("removing session file: %v", err)
+	}
+	assertStatus(t, reveal("rev-sess", `{"confirm":true}`, local),
+		http.StatusNotFound)
+}
+
+func TestGetSessionHooks(t *testing.T) {
+	te := setup(t)
+	te.writeSessionFile(t, "hook-proj", "hook-sess.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddClaudeUser(tsZero, "msg").
+			AddRaw(`{"type":"system","content":"Stop [./check.sh] completed successfully","timestamp":"2024-01-01T00:00:01Z"}`),
+	)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sync", nil)
+	te.handler.ServeHTTP(&noFlushWriter{rec}, req)
+	assertStatus(t, rec, http.StatusOK)
+
+	w := te.get(t, "/api/v1/sessions/hook-sess/hooks")
+	assertStatus(t, w, http.StatusOK)
+	events := decode[[]db.HookEvent](t, w)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 hook event, got %d", len(events))
+	}
+	if events[0].HookName != "Stop" || events[0].Decision != "allow" {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+}
+
+func TestSessionEnv(t *testing.T) {
+	te := setup(t)
+	te.writeSessionFile(t, "env-proj", "env-sess.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddRaw(`{"type":"user","timestamp":"`+tsZero+
+				`","version":"2.0.14","thinkingMetadata":{"level":"high","disabled":false},"message":{"content":"think hard"}}`).
+			AddClaudeAssistant(tsZeroS5, "done"),
+	)
+	te.writeSessionFile(t, "env-proj", "plain-sess.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddClaudeUser(tsZero, "hi").
+			AddClaudeAssistant(tsZeroS5, "hello"),
+	)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sync", nil)
+	te.handler.ServeHTTP(&noFlushWriter{rec}, req)
+	assertStatus(t, rec, http.StatusOK)
+
+	w := te.get(t, "/api/v1/sessions/env-sess/env")
+	assertStatus(t, w, http.StatusOK)
+	env := decode[map[string]string](t, w)
+	if env["thinking"] != "enabled" || env["client_version"] != "2.0.14" {
+		t.Errorf("unexpected env %v", env)
+	}
+
+	w = te.get(t, "/api/v1/sessions?env=thinking%3Denabled")
+	assertStatus(t, w, http.StatusOK)
+	page := decode[db.SessionPage](t, w)
+	if page.Total != 1 || page.Sessions[0].ID != "env-sess" {
+		t.Errorf("env filter = %+v, want env-sess only", page.Sessions)
+	}
+
+	w = te.get(t, "/api/v1/env")
+	assertStatus(t, w, http.StatusOK)
+	values := decode[struct {
+		Values []db.EnvValue `json:"values"`
+	}](t, w)
+	if len(values.Values) != 3 {
+		t.Errorf("env values = %+v, want 3", values.Values)
 	}
+
+	w = te.get(t, "/api/v1/sessions?env=%3Dx")
+	assertStatus(t, w, http.StatusBadRequest)
 }
 
-func TestUploadSession_EmptyFile(t *testing.T) {
+func TestSessionQuality(t *testing.T) {
 	te := setup(t)
+	te.writeSessionFile(t, "q-proj", "q-sess.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddClaudeUser(tsZero, "run the tests").
+			AddRaw(testjsonl.ClaudeAssistantJSON([]map[string]any{{
+				"type": "tool_use", "id": "toolu_1", "name": "Bash",
+				"input": map[string]any{"command": "make test"},
+			}}, tsZeroS5)).
+			AddRaw(`{"type":"user","timestamp":"`+tsZeroS5+
+				`","message":{"content":[{"type":"tool_result","tool_use_id":"toolu_1","content":"make: *** No rule","is_error":true}]}}`).
+			AddClaudeAssistant(tsZeroS5, "the build is broken"),
+	)
 
-	w := te.upload(t, "empty.jsonl", "",
-		"project=myproj")
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sync", nil)
+	te.handler.ServeHTTP(&noFlushWriter{rec}, req)
+	assertStatus(t, rec, http.StatusOK)
+
+	// One tool call, and it failed.
+	w := te.get(t, "/api/v1/sessions/q-sess")
 	assertStatus(t, w, http.StatusOK)
+	sess := decode[db.Session](t, w)
+	if sess.QualityScore == nil || *sess.QualityScore != 70 {
+		t.Fatalf("quality_score = %v, want 70", sess.QualityScore)
+	}
 
-	resp := decode[uploadResponse](t, w)
-	if resp.Messages != 0 {
-		t.Errorf("messages = %v, want 0", resp.Messages)
+	w = te.get(t, "/api/v1/sessions?quality_below=70")
+	assertStatus(t, w, http.StatusOK)
+	if page := decode[db.SessionPage](t, w); page.Total != 0 {
+		t.Errorf("quality_below=70 = %+v, want none", page.Sessions)
 	}
-}
+	w = te.get(t, "/api/v1/sessions?quality_below=71")
+	assertStatus(t, w, http.StatusOK)
+	if page := decode[db.SessionPage](t, w); page.Total != 1 {
+		t.Errorf("quality_below=71 = %+v, want q-sess", page.Sessions)
+	}
+	w = te.get(t, "/api/v1/sessions?quality_below=x")
+	assertStatus(t, w, http.StatusBadRequest)
 
-// noFlushWriter wraps an http.ResponseWriter without Flusher.
-type noFlushWriter struct {
-	http.ResponseWriter
+	w = te.get(t, "/api/v1/analytics/quality"+
+		"?from=2024-01-01&to=2024-01-31&timezone=UTC&granularity=month")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.QualityResponse](t, w)
+	if resp.Sessions != 1 || len(resp.Trend) != 1 ||
+		resp.Trend[0].Date != "2024-01-01" ||
+		len(resp.Lowest) != 1 || resp.Lowest[0].Score != 70 {
+		t.Errorf("quality analytics = %+v", resp)
+	}
 }
 
-func TestTriggerSync_NonStreaming(t *testing.T) {
+func TestSessionTodos(t *testing.T) {
 	te := setup(t)
-
-	// Seed a session file so we expect at least one session in the sync result.
-	te.writeSessionFile(t, "test-proj", "sync-test.jsonl",
+	te.writeSessionFile(t, "todo-proj", "todo-sess.jsonl",
 		testjsonl.NewSessionBuilder().
-			AddClaudeUser(tsZero, "msg"),
+			AddClaudeUser(tsZero, "plan it").
+			AddRaw(testjsonl.ClaudeAssistantJSON([]map[string]any{{
+				"type": "tool_use", "id": "toolu_1", "name": "TodoWrite",
+				"input": map[string]any{"todos": []map[string]string{
+					{"content": "Write parser", "status": "completed"},
+					{"content": "Add tests", "status": "pending"},
+				}},
+			}}, tsZeroS5)),
 	)
 
 	rec := httptest.NewRecorder()
-	nf := &noFlushWriter{rec}
-
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/sync", nil)
-	req.Header.Set("Content-Type", "application/json")
-	te.handler.ServeHTTP(nf, req)
+	te.handler.ServeHTTP(&noFlushWriter{rec}, req)
 	assertStatus(t, rec, http.StatusOK)
 
-	resp := decode[syncResultResponse](t, rec)
-	if resp.TotalSessions != 1 {
-		t.Fatalf("expected 1 total_session, got %d", resp.TotalSessions)
+	w := te.get(t, "/api/v1/sessions/todo-sess/todos")
+	assertStatus(t, w, http.StatusOK)
+	if todos := decode[[]db.Todo](t, w); len(todos) != 2 {
+		t.Fatalf("expected 2 todos, got %d", len(todos))
+	}
+
+	w = te.get(t, "/api/v1/todos?since=2024-01-01")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[db.OpenTodosResponse](t, w)
+	if resp.Total != 1 || len(resp.Sessions) != 1 ||
+		resp.Sessions[0].Todos[0].Content != "Add tests" {
+		t.Errorf("unexpected open todos %+v", resp)
+	}
+
+	// The default window excludes lists older than two weeks.
+	w = te.get(t, "/api/v1/todos")
+	assertStatus(t, w, http.StatusOK)
+	if resp := decode[db.OpenTodosResponse](t, w); resp.Total != 0 {
+		t.Errorf("default window total = %d, want 0", resp.Total)
+	}
+
+	for _, q := range []string{"since=last-week", "days=-1", "limit=x"} {
+		w = te.get(t, "/api/v1/todos?"+q)
+		assertStatus(t, w, http.StatusBadRequest)
 	}
 }
 
@@ -2081,6 +3301,105 @@ func TestWatchSession_FileDisappearAndResolve(t *testing.T) {
 	<-done
 }
 
+func TestStreamSession_Messages(t *testing.T) {
+	te := setup(t)
+
+	b := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsZero, "initial")
+	content := b.String()
+	sessionPath := te.writeSessionFile(t, "live-proj", "live-sess.jsonl", b)
+
+	engine := sync.NewEngine(te.db, sync.EngineConfig{
+		AgentDirs: map[parser.AgentType][]string{
+			parser.AgentClaude: {te.claudeDir},
+		},
+		Machine: "test",
+	})
+	engine.SyncAll(nil)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second,
+	)
+	defer cancel()
+
+	req := httptest.NewRequest(
+		http.MethodGet, "/api/v1/sessions/live-sess/stream", nil,
+	).WithContext(ctx)
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	done := make(chan struct{})
+	go func() {
+		te.handler.ServeHTTP(w, req)
+		close(done)
+	}()
+
+	te.waitForSSEEvent(t, w, "session", 2*time.Second)
+
+	updated := content + testjsonl.NewSessionBuilder().
+		AddClaudeAssistant(tsZeroS5, "live response").
+		String()
+	if err := os.WriteFile(
+		sessionPath, []byte(updated), 0o644,
+	); err != nil {
+		t.Fatalf("writing updated session file: %v", err)
+	}
+
+	te.waitForSSEEvent(t, w, "messages", 5*time.Second)
+	cancel()
+	<-done
+
+	var got []string
+	for _, e := range parseSSE(w.BodyString()) {
+		if e.Event != "messages" {
+			continue
+		}
+		var batch struct {
+			Messages []db.Message `json:"messages"`
+		}
+		if err := json.Unmarshal([]byte(e.Data), &batch); err != nil {
+			t.Fatalf("decoding messages event: %v", err)
+		}
+		for _, m := range batch.Messages {
+			got = append(got, m.Content)
+		}
+	}
+	// Only the message added after connecting is streamed.
+	if len(got) != 1 || got[0] != "live response" {
+		t.Errorf("streamed messages = %q", got)
+	}
+}
+
+func TestStreamSession_NotFound(t *testing.T) {
+	te := setup(t)
+	w := te.get(t, "/api/v1/sessions/nope/stream")
+	assertStatus(t, w, http.StatusNotFound)
+}
+
+func TestActiveSessions(t *testing.T) {
+	te := setup(t)
+	recent := time.Now().Add(-2 * time.Minute).UTC().Format(time.RFC3339)
+	te.seedSession(t, "live", "my-app", 4, func(s *db.Session) {
+		s.EndedAt = &recent
+	})
+	te.seedSession(t, "old", "my-app", 4)
+
+	w := te.get(t, "/api/v1/sessions/active")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[sessionListResponse](t, w)
+	if len(resp.Sessions) != 1 || resp.Sessions[0].ID != "live" {
+		t.Errorf("active sessions = %+v", resp.Sessions)
+	}
+
+	w = te.get(t, "/api/v1/sessions/active?window=1m")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[sessionListResponse](t, w).Sessions; len(got) != 0 {
+		t.Errorf("1m window sessions = %+v", got)
+	}
+
+	w = te.get(t, "/api/v1/sessions/active?window=soon")
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
 func TestTriggerSync_SSEEvents(t *testing.T) {
 	te := setup(t)
 
@@ -2476,6 +3795,63 @@ func TestGetVersion(t *testing.T) {
 	}
 }
 
+func TestHealth(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 1)
+	if err := te.db.ReplaceUnknownRecords("s1", []db.UnknownRecord{
+		{Type: "widget", Count: 3, Sample: `{"type":"widget"}`},
+	}); err != nil {
+		t.Fatalf("ReplaceUnknownRecords: %v", err)
+	}
+
+	w := te.get(t, "/api/v1/health")
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[struct {
+		Status      string                 `json:"status"`
+		WriteQueue  db.WriteQueueStats     `json:"write_queue"`
+		UnknownRecs []db.UnknownRecordType `json:"unknown_record_types"`
+	}](t, w)
+	if len(resp.UnknownRecs) != 1 ||
+		resp.UnknownRecs[0].Type != "widget" ||
+		resp.UnknownRecs[0].Count != 3 {
+		t.Errorf("unknown_record_types = %+v", resp.UnknownRecs)
+	}
+	if resp.Status != "ok" {
+		t.Errorf("status = %q, want ok", resp.Status)
+	}
+	if resp.WriteQueue.Capacity == 0 {
+		t.Error("expected write queue capacity")
+	}
+	if resp.WriteQueue.Writes == 0 {
+		t.Error("expected seeded writes to be counted")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 3)
+	assertStatus(t, te.get(t, "/api/v1/sessions/s1"), http.StatusOK)
+
+	w := te.get(t, "/metrics")
+	assertStatus(t, w, http.StatusOK)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE agentsview_files_parsed_total counter\n",
+		"# TYPE agentsview_sync_duration_seconds histogram\n",
+		"agentsview_sessions 1\n",
+		"agentsview_db_size_bytes ",
+		`agentsview_http_request_duration_seconds_count{method="GET",route="GET /api/v1/sessions/{id}",code="200"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
 func TestGetVersion_Default(t *testing.T) {
 	te := setup(t)
 
@@ -2517,3 +3893,309 @@ func TestFindAvailablePortSkipsOccupied(t *testing.T) {
 	}
 	ln2.Close()
 }
+
+func TestRecentFiles(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "s1", "my-app", 2)
+	te.seedMessages(t, "s1", 2, func(i int, m *db.Message) {
+		if m.Role != "assistant" {
+			return
+		}
+		m.HasToolUse = true
+		m.ToolCalls = []db.ToolCall{{
+			SessionID: "s1",
+			ToolName:  "Edit",
+			Category:  "Edit",
+			InputJSON: `{"file_path":"/src/auth.ts"}`,
+		}}
+	})
+
+	w := te.get(t, "/api/v1/files/recent?project=my-app")
+	assertStatus(t, w, http.StatusOK)
+
+	resp := decode[struct {
+		Files []db.RecentFile `json:"files"`
+	}](t, w)
+	if len(resp.Files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(resp.Files))
+	}
+	f := resp.Files[0]
+	if f.Path != "/src/auth.ts" || f.EditCount != 1 {
+		t.Errorf("file = %+v", f)
+	}
+	if len(f.Touches) != 1 || f.Touches[0].Ordinal != 1 {
+		t.Errorf("touches = %+v", f.Touches)
+	}
+
+	w = te.get(t, "/api/v1/files/recent?project=none")
+	assertStatus(t, w, http.StatusOK)
+	assertBodyContains(t, w, `"files":[]`)
+
+	w = te.get(t, "/api/v1/files/recent?limit=abc")
+	assertStatus(t, w, http.StatusBadRequest)
+}
+
+func TestLogs(t *testing.T) {
+	te := setup(t)
+	lines := strings.Join([]string{
+		`plain-text line from before structured logging`,
+		`{"time":"2024-06-01T10:00:00Z","level":"INFO","msg":"sync done"}`,
+		`{"time":"2024-06-01T11:00:00Z","level":"WARN","msg":"unparseable timestamp","ts":"garbage"}`,
+		`{"time":"2024-06-01T12:00:00Z","level":"ERROR","msg":"watcher error","err":"boom"}`,
+	}, "\n") + "\n"
+	logPath := filepath.Join(te.dataDir, "debug.log")
+	if err := os.WriteFile(logPath, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	type logsResp struct {
+		Entries []struct {
+			Level string         `json:"level"`
+			Msg   string         `json:"msg"`
+			Attrs map[string]any `json:"attrs"`
+		} `json:"entries"`
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"default warn", "", []string{"unparseable timestamp", "watcher error"}},
+		{"info", "?level=info", []string{"sync done", "unparseable timestamp", "watcher error"}},
+		{"error", "?level=error", []string{"watcher error"}},
+		{"since", "?level=info&since=2024-06-01T10:30:00Z", []string{"unparseable timestamp", "watcher error"}},
+		{"limit", "?level=info&limit=1", []string{"watcher error"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := te.get(t, "/api/v1/logs"+tt.query)
+			assertStatus(t, w, http.StatusOK)
+			resp := decode[logsResp](t, w)
+			var got []string
+			for _, e := range resp.Entries {
+				got = append(got, e.Msg)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("msgs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	w := te.get(t, "/api/v1/logs?level=error")
+	resp := decode[logsResp](t, w)
+	if len(resp.Entries) != 1 || resp.Entries[0].Attrs["err"] != "boom" {
+		t.Errorf("entries = %+v", resp.Entries)
+	}
+
+	for _, q := range []string{
+		"?level=loud", "?since=yesterday", "?limit=abc",
+	} {
+		w := te.get(t, "/api/v1/logs"+q)
+		assertStatus(t, w, http.StatusBadRequest)
+	}
+}
+
+func TestMachineManagement(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "m1", "proj", 2, func(s *db.Session) {
+		s.Machine = "old-host"
+	})
+	te.seedSession(t, "m2", "proj", 2, func(s *db.Session) {
+		s.Machine = "laptop"
+	})
+
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"RenameOntoExisting", "/api/v1/machines/old-host/rename",
+			`{"to":"laptop"}`, http.StatusConflict},
+		{"RenameMissing", "/api/v1/machines/nope/rename",
+			`{"to":"x"}`, http.StatusNotFound},
+		{"RenameEmptyTarget", "/api/v1/machines/old-host/rename",
+			`{"to":" "}`, http.StatusBadRequest},
+		{"MergeIntoMissing", "/api/v1/machines/old-host/merge",
+			`{"into":"desktop"}`, http.StatusNotFound},
+		{"Rename", "/api/v1/machines/old-host/rename",
+			`{"to":"new-host"}`, http.StatusOK},
+		{"Merge", "/api/v1/machines/new-host/merge",
+			`{"into":"laptop"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := te.post(t, tt.path, tt.body)
+		if w.Code != tt.want {
+			t.Fatalf("%s: status = %d, want %d: %s",
+				tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+
+	w := te.get(t, "/api/v1/machines")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		Machines []string         `json:"machines"`
+		Details  []db.MachineInfo `json:"details"`
+	}](t, w)
+	if len(resp.Machines) != 1 || resp.Machines[0] != "laptop" {
+		t.Errorf("machines = %v, want [laptop]", resp.Machines)
+	}
+	if len(resp.Details) != 1 || resp.Details[0].SessionCount != 2 ||
+		len(resp.Details[0].Aliases) != 2 {
+		t.Errorf("unexpected details %+v", resp.Details)
+	}
+}
+
+func TestSessionShareLinks(t *testing.T) {
+	te := setup(t)
+	te.seedSession(t, "shared", "proj", 3)
+	te.seedMessages(t, "shared", 3)
+	te.seedSession(t, "private", "proj", 1)
+
+	w := te.post(t, "/api/v1/sessions/missing/share", "")
+	assertStatus(t, w, http.StatusNotFound)
+	w = te.post(t, "/api/v1/sessions/shared/share",
+		`{"expires_in_hours":100000}`)
+	assertStatus(t, w, http.StatusBadRequest)
+
+	w = te.post(t, "/api/v1/sessions/shared/share",
+		`{"expires_in_hours":2}`)
+	assertStatus(t, w, http.StatusCreated)
+	link := decode[struct {
+		Token     string `json:"token"`
+		Path      string `json:"path"`
+		ExpiresAt string `json:"expires_at"`
+	}](t, w)
+	if link.Token == "" || link.Path != "/api/v1/shared/"+link.Token {
+		t.Fatalf("unexpected link: %+v", link)
+	}
+
+	w = te.get(t, link.Path)
+	assertStatus(t, w, http.StatusOK)
+	got := decode[struct {
+		Session   db.Session `json:"session"`
+		ExpiresAt string     `json:"expires_at"`
+	}](t, w)
+	if got.Session.ID != "shared" || got.ExpiresAt != link.ExpiresAt {
+		t.Errorf("shared session = %s (expires %s), want shared (%s)",
+			got.Session.ID, got.ExpiresAt, link.ExpiresAt)
+	}
+
+	w = te.get(t, link.Path+"/messages?limit=2")
+	assertStatus(t, w, http.StatusOK)
+	msgs := decode[struct {
+		Count int `json:"count"`
+	}](t, w)
+	if msgs.Count != 2 {
+		t.Errorf("shared messages count = %d, want 2", msgs.Count)
+	}
+
+	// Another session's payload under this link's signature
+	// must not verify.
+	other := te.db.ShareToken("private", time.Now().Add(time.Hour))
+	otherPayload, _, _ := strings.Cut(other, ".")
+	_, sig, _ := strings.Cut(link.Token, ".")
+	forged := otherPayload + "." + sig
+	w = te.get(t, "/api/v1/shared/"+forged)
+	assertStatus(t, w, http.StatusNotFound)
+
+	expired := te.db.ShareToken("shared", time.Now().Add(-time.Minute))
+	w = te.get(t, "/api/v1/shared/"+expired+"/messages")
+	assertStatus(t, w, http.StatusGone)
+}
+
+func TestSyncQuarantine(t *testing.T) {
+	te := setup(t)
+
+	w := te.get(t, "/api/v1/sync/quarantine")
+	assertStatus(t, w, http.StatusOK)
+	type quarantineResponse struct {
+		Retried int                  `json:"retried"`
+		Files   []db.QuarantinedFile `json:"files"`
+	}
+	if resp := decode[quarantineResponse](t, w); len(resp.Files) != 0 {
+		t.Fatalf("files = %+v, want empty", resp.Files)
+	}
+
+	f := db.QuarantinedFile{
+		Path:          "/claude/proj/broken.jsonl",
+		Agent:         "claude",
+		Error:         "line too long",
+		Attempts:      3,
+		FirstFailedAt: "2024-01-01T00:00:00Z",
+		LastFailedAt:  "2024-01-01T00:04:00Z",
+		NextRetryAt:   "2024-01-01T00:08:00Z",
+	}
+	if err := te.db.UpsertQuarantinedFile(f); err != nil {
+		t.Fatalf("UpsertQuarantinedFile: %v", err)
+	}
+
+	w = te.get(t, "/api/v1/sync/quarantine")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[quarantineResponse](t, w)
+	if len(resp.Files) != 1 || resp.Files[0] != f {
+		t.Errorf("files = %+v, want [%+v]", resp.Files, f)
+	}
+
+	w = te.post(t, "/api/v1/sync/quarantine/retry",
+		`{"path":"/not/quarantined.jsonl"}`)
+	assertStatus(t, w, http.StatusNotFound)
+
+	w = te.post(t, "/api/v1/sync/quarantine/retry", `{"path":`)
+	assertStatus(t, w, http.StatusBadRequest)
+
+	w = te.post(t, "/api/v1/sync/quarantine/retry", "")
+	assertStatus(t, w, http.StatusOK)
+	resp = decode[quarantineResponse](t, w)
+	if len(resp.Files) != 1 {
+		t.Errorf("files after retry = %+v, want 1", resp.Files)
+	}
+}
+
+func TestListAlerts(t *testing.T) {
+	type alertsResponse struct {
+		Alerts []alert.State `json:"alerts"`
+	}
+	te := setup(t)
+	w := te.get(t, "/api/v1/alerts")
+	assertStatus(t, w, http.StatusOK)
+	if resp := decode[alertsResponse](t, w); len(resp.Alerts) != 0 {
+		t.Fatalf("alerts = %+v, want empty", resp.Alerts)
+	}
+
+	d := dbtest.OpenTestDB(t)
+	e := alert.New(d, config.Alerts{Rules: []config.AlertRule{{
+		Name: "ci", Kind: config.AlertMachineSilent,
+		Machine: "ci-box", Threshold: 1,
+	}}})
+	e.Evaluate(context.Background())
+	te = setupWithServerOpts(t, []server.Option{server.WithAlerts(e)})
+	w = te.get(t, "/api/v1/alerts")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[alertsResponse](t, w)
+	if len(resp.Alerts) != 1 || resp.Alerts[0].Rule != "ci" ||
+		!resp.Alerts[0].Firing {
+		t.Errorf("alerts = %+v, want ci firing", resp.Alerts)
+	}
+}
+
+func TestPricing(t *testing.T) {
+	te := setup(t)
+	w := te.get(t, "/api/v1/pricing")
+	assertStatus(t, w, http.StatusOK)
+	resp := decode[struct {
+		Currency string          `json:"currency"`
+		Models   []pricing.Entry `json:"models"`
+	}](t, w)
+	if resp.Currency != "USD" {
+		t.Errorf("currency = %q, want USD", resp.Currency)
+	}
+	i := slices.IndexFunc(resp.Models, func(e pricing.Entry) bool {
+		return e.Model == "claude-sonnet-4"
+	})
+	if i < 0 || resp.Models[i].Output != 15 ||
+		resp.Models[i].Source != pricing.SourceBuiltin {
+		t.Errorf("models = %+v", resp.Models)
+	}
+}
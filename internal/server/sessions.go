@@ -3,6 +3,7 @@ package server
 import (
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/wesm/agentsview/internal/db"
 )
@@ -30,6 +31,10 @@ func (s *Server) handleListSessions(
 	if !ok {
 		return
 	}
+	qualityBelow, ok := parseIntParam(w, r, "quality_below")
+	if !ok {
+		return
+	}
 
 	date := q.Get("date")
 	dateFrom := q.Get("date_from")
@@ -55,6 +60,45 @@ func (s *Server) handleListSessions(
 		return
 	}
 
+	startedAfter, ok := parseTimeParam(w, r, "started_after")
+	if !ok {
+		return
+	}
+	startedBefore, ok := parseTimeParam(w, r, "started_before")
+	if !ok {
+		return
+	}
+	endedAfter, ok := parseTimeParam(w, r, "ended_after")
+	if !ok {
+		return
+	}
+	endedBefore, ok := parseTimeParam(w, r, "ended_before")
+	if !ok {
+		return
+	}
+
+	includeArchived, ok := parseBoolParam(w, r, "include_archived")
+	if !ok {
+		return
+	}
+	pinned, ok := parseBoolParam(w, r, "pinned")
+	if !ok {
+		return
+	}
+	archived, ok := parseBoolParam(w, r, "archived")
+	if !ok {
+		return
+	}
+
+	env := q["env"]
+	for _, cond := range env {
+		if key, _, _ := strings.Cut(cond, "="); key == "" {
+			writeError(w, http.StatusBadRequest,
+				"env must be key or key=value")
+			return
+		}
+	}
+
 	filter := db.SessionFilter{
 		Project:         q.Get("project"),
 		ExcludeProject:  q.Get("exclude_project"),
@@ -64,9 +108,21 @@ func (s *Server) handleListSessions(
 		DateFrom:        dateFrom,
 		DateTo:          dateTo,
 		ActiveSince:     activeSince,
+		StartedAfter:    startedAfter,
+		StartedBefore:   startedBefore,
+		EndedAfter:      endedAfter,
+		EndedBefore:     endedBefore,
 		MinMessages:     minMsgs,
 		MaxMessages:     maxMsgs,
 		MinUserMessages: minUserMsgs,
+		QualityBelow:    qualityBelow,
+		Language:        q.Get("language"),
+		Tag:             q.Get("tag"),
+		Query:           q.Get("q"),
+		Env:             env,
+		IncludeArchived: includeArchived,
+		Pinned:          pinned,
+		Archived:        archived,
 		Cursor:          q.Get("cursor"),
 		Limit:           limit,
 	}
@@ -91,7 +147,7 @@ func (s *Server) handleGetSession(
 	w http.ResponseWriter, r *http.Request,
 ) {
 	id := r.PathValue("id")
-	session, err := s.db.GetSession(r.Context(), id)
+	session, err := s.db.GetSessionFull(r.Context(), id)
 	if err != nil {
 		if handleContextError(w, err) {
 			return
@@ -106,6 +162,38 @@ func (s *Server) handleGetSession(
 	writeJSON(w, http.StatusOK, session)
 }
 
+func (s *Server) handleGetSessionHooks(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id := r.PathValue("id")
+	events, err := s.db.GetSessionHookEvents(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// handleGetSessionModels returns the session's model timeline:
+// one segment per run of messages from the same model.
+func (s *Server) handleGetSessionModels(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id := r.PathValue("id")
+	segments, err := s.db.GetSessionModelTimeline(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, segments)
+}
+
 func (s *Server) handleGetChildSessions(
 	w http.ResponseWriter, r *http.Request,
 ) {
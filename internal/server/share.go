@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+const (
+	defaultShareHours = 7 * 24
+	maxShareHours     = 90 * 24
+)
+
+type createShareRequest struct {
+	ExpiresInHours int `json:"expires_in_hours"`
+}
+
+// handleCreateShareLink issues a signed, expiring link to one
+// session. The shared endpoints only serve the session named
+// in the token, so a team deployment can expose them (e.g.
+// /api/v1/shared/ behind a reverse proxy) without exposing the
+// rest of the archive.
+func (s *Server) handleCreateShareLink(
+	w http.ResponseWriter, r *http.Request,
+) {
+	var req createShareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil &&
+		!errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	hours := req.ExpiresInHours
+	if hours == 0 {
+		hours = defaultShareHours
+	}
+	if hours < 0 || hours > maxShareHours {
+		writeError(w, http.StatusBadRequest,
+			"expires_in_hours must be between 1 and 2160")
+		return
+	}
+
+	id := r.PathValue("id")
+	session, err := s.db.GetSession(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+
+	expires := time.Now().UTC().Add(time.Duration(hours) * time.Hour).
+		Truncate(time.Second)
+	token := s.db.ShareToken(id, expires)
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"token":      token,
+		"path":       "/api/v1/shared/" + token,
+		"expires_at": expires.Format(time.RFC3339),
+	})
+}
+
+// handleGetSharedSession returns the session a share token
+// grants access to, along with the link's expiry.
+func (s *Server) handleGetSharedSession(
+	w http.ResponseWriter, r *http.Request,
+) {
+	link, ok := s.sharedLink(w, r)
+	if !ok {
+		return
+	}
+	session, err := s.db.GetSession(r.Context(), link.SessionID)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"session": session,
+		"expires_at": time.Unix(link.ExpiresAt, 0).UTC().
+			Format(time.RFC3339),
+	})
+}
+
+// handleGetSharedMessages pages through a shared session's
+// messages with the same parameters as the messages endpoint.
+func (s *Server) handleGetSharedMessages(
+	w http.ResponseWriter, r *http.Request,
+) {
+	link, ok := s.sharedLink(w, r)
+	if !ok {
+		return
+	}
+	s.writeMessagesPage(w, r, link.SessionID)
+}
+
+// sharedLink verifies the request's share token, writing an
+// error response when it is invalid (404) or expired (410).
+func (s *Server) sharedLink(
+	w http.ResponseWriter, r *http.Request,
+) (db.ShareLink, bool) {
+	link, err := s.db.DecodeShareToken(
+		r.PathValue("token"), time.Now(),
+	)
+	switch {
+	case err == nil:
+		return link, true
+	case errors.Is(err, db.ErrShareExpired):
+		writeError(w, http.StatusGone, err.Error())
+	default:
+		writeError(w, http.StatusNotFound, "share link not found")
+	}
+	return db.ShareLink{}, false
+}
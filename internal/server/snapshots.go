@@ -0,0 +1,172 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// maxSnapshotImportBytes bounds an imported snapshot body.
+const maxSnapshotImportBytes = 32 << 20
+
+// handleCreateAnalyticsSnapshot captures the analytics for the
+// filter in the query string into a stored snapshot. The body
+// is {"name": "..."}.
+func (s *Server) handleCreateAnalyticsSnapshot(
+	w http.ResponseWriter, r *http.Request,
+) {
+	f, ok := s.parseAnalyticsFilter(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+
+	data, err := s.db.CaptureAnalytics(r.Context(), f)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		slog.Error("analytics snapshot error", "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"internal server error")
+		return
+	}
+	snap := db.AnalyticsSnapshot{Name: req.Name, Filter: f, Data: data}
+	s.storeAnalyticsSnapshot(w, r, snap)
+}
+
+// handleImportAnalyticsSnapshot stores a snapshot previously
+// downloaded from this or another instance. Its name, filter,
+// data, and creation time are kept; it gets a new ID.
+func (s *Server) handleImportAnalyticsSnapshot(
+	w http.ResponseWriter, r *http.Request,
+) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxSnapshotImportBytes)
+	var snap db.AnalyticsSnapshot
+	if err := json.NewDecoder(r.Body).Decode(&snap); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if snap.CreatedAt != "" && !isValidTimestamp(snap.CreatedAt) {
+		writeError(w, http.StatusBadRequest,
+			"invalid created_at: use RFC3339 timestamp")
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	snap.ID = 0
+	snap.ImportedAt = &now
+	s.storeAnalyticsSnapshot(w, r, snap)
+}
+
+func (s *Server) storeAnalyticsSnapshot(
+	w http.ResponseWriter, r *http.Request, snap db.AnalyticsSnapshot,
+) {
+	if err := db.ValidateSnapshot(snap); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := s.db.InsertAnalyticsSnapshot(snap)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	stored, err := s.db.GetAnalyticsSnapshot(r.Context(), id)
+	if err != nil || stored == nil {
+		if err != nil && handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError,
+			"reading stored snapshot failed")
+		return
+	}
+	writeJSON(w, http.StatusCreated, stored)
+}
+
+func (s *Server) handleListAnalyticsSnapshots(
+	w http.ResponseWriter, r *http.Request,
+) {
+	snaps, err := s.db.ListAnalyticsSnapshots(r.Context())
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"snapshots": snaps,
+	})
+}
+
+// handleGetAnalyticsSnapshot returns a snapshot with its data.
+// With download=true it is served as a file for export.
+func (s *Server) handleGetAnalyticsSnapshot(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	snap, err := s.db.GetAnalyticsSnapshot(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if snap == nil {
+		writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+
+	if r.URL.Query().Get("download") == "true" {
+		filename := sanitizeFilename(
+			"analytics-" + snap.Name + ".json",
+		)
+		w.Header().Set(
+			"Content-Disposition",
+			fmt.Sprintf(`attachment; filename="%s"`, filename),
+		)
+	}
+	writeJSON(w, http.StatusOK, snap)
+}
+
+func (s *Server) handleDeleteAnalyticsSnapshot(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	existing, err := s.db.GetAnalyticsSnapshot(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if existing == nil {
+		writeError(w, http.StatusNotFound, "snapshot not found")
+		return
+	}
+	if err := s.db.DeleteAnalyticsSnapshot(id); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+type listSnapshotsResponse struct {
+	Snapshots []db.AnalyticsSnapshot `json:"snapshots"`
+}
+
+func TestAnalyticsSnapshots(t *testing.T) {
+	te := setup(t)
+	seedAnalyticsEnv(t, te)
+
+	w := te.post(t, buildURLWithRange("snapshots", map[string]string{
+		"timezone": "UTC",
+	}), `{"name":"Q2 close"}`)
+	assertStatus(t, w, http.StatusCreated)
+	created := decode[db.AnalyticsSnapshot](t, w)
+	if created.Name != "Q2 close" || created.Filter.From != "2024-06-01" {
+		t.Errorf("created = %+v", created)
+	}
+	var summary db.AnalyticsSummary
+	if err := json.Unmarshal(created.Data["summary"], &summary); err != nil {
+		t.Fatalf("decoding summary: %v", err)
+	}
+	if summary.TotalSessions != 3 {
+		t.Errorf("summary.TotalSessions = %d, want 3", summary.TotalSessions)
+	}
+
+	path := fmt.Sprintf("/api/v1/analytics/snapshots/%d", created.ID)
+	w = te.get(t, path+"?download=true")
+	assertStatus(t, w, http.StatusOK)
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(
+		cd, "analytics-Q2_close.json",
+	) {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	exported := w.Body.String()
+
+	w = te.post(t, "/api/v1/analytics/snapshots/import", exported)
+	assertStatus(t, w, http.StatusCreated)
+	imported := decode[db.AnalyticsSnapshot](t, w)
+	if imported.ID == created.ID {
+		t.Error("import should get a new ID")
+	}
+	if imported.CreatedAt != created.CreatedAt {
+		t.Errorf("imported created_at = %q, want %q",
+			imported.CreatedAt, created.CreatedAt)
+	}
+	if imported.ImportedAt == nil {
+		t.Error("imported_at not set")
+	}
+
+	w = te.get(t, "/api/v1/analytics/snapshots")
+	assertStatus(t, w, http.StatusOK)
+	if got := decode[listSnapshotsResponse](t, w); len(got.Snapshots) != 2 {
+		t.Errorf("len(snapshots) = %d, want 2", len(got.Snapshots))
+	}
+
+	assertStatus(t, te.del(t, path), http.StatusNoContent)
+	assertStatus(t, te.get(t, path), http.StatusNotFound)
+	assertStatus(t, te.del(t, path), http.StatusNotFound)
+}
+
+func TestAnalyticsSnapshots_Invalid(t *testing.T) {
+	te := setup(t)
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"missing name", buildURLWithRange("snapshots", nil), `{}`},
+		{"bad json", buildURLWithRange("snapshots", nil), `nope`},
+		{"bad range", buildURL("snapshots", map[string]string{
+			"from": "2024-06-05", "to": "2024-06-01",
+		}), `{"name":"x"}`},
+		{"import without data", "/api/v1/analytics/snapshots/import",
+			`{"name":"x"}`},
+		{"import bad created_at", "/api/v1/analytics/snapshots/import",
+			`{"name":"x","created_at":"yesterday","data":{"summary":{}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertStatus(t, te.post(t, tt.path, tt.body),
+				http.StatusBadRequest)
+		})
+	}
+	assertStatus(t, te.get(t, "/api/v1/analytics/snapshots/abc"),
+		http.StatusBadRequest)
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// sessionSource describes where a session came from: the
+// file it was last synced from (as recorded in the database)
+// and, when it can be found, the file on disk now.
+type sessionSource struct {
+	SessionID string `json:"session_id"`
+	Agent     string `json:"agent"`
+	Machine   string `json:"machine"`
+
+	// Path is the source file as located now. Root is the
+	// configured agent directory containing it, empty for
+	// uploaded or imported sessions.
+	Path   string `json:"path,omitempty"`
+	Root   string `json:"root,omitempty"`
+	Exists bool   `json:"exists"`
+	Size   *int64 `json:"size,omitempty"`
+	Mtime  *int64 `json:"mtime,omitempty"` // unix nanoseconds
+
+	StoredPath  *string `json:"stored_path,omitempty"`
+	StoredSize  *int64  `json:"stored_size,omitempty"`
+	StoredMtime *int64  `json:"stored_mtime,omitempty"`
+	StoredHash  *string `json:"stored_hash,omitempty"`
+
+	// Changed reports that the file on disk differs in size
+	// or mtime from what was last synced.
+	Changed bool `json:"changed"`
+}
+
+func (s *Server) handleGetSessionSource(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id := r.PathValue("id")
+	sess, err := s.db.GetSessionFull(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sess == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+
+	src := sessionSource{
+		SessionID:   sess.ID,
+		Agent:       sess.Agent,
+		Machine:     sess.Machine,
+		StoredPath:  sess.FilePath,
+		StoredSize:  sess.FileSize,
+		StoredMtime: sess.FileMtime,
+		StoredHash:  sess.FileHash,
+	}
+
+	var statPath string
+	src.Path, src.Root, statPath = s.locateSessionSource(sess)
+	if statPath != "" {
+		if info, err := os.Stat(statPath); err == nil {
+			size, mtime := info.Size(), info.ModTime().UnixNano()
+			src.Exists = true
+			src.Size = &size
+			src.Mtime = &mtime
+			src.Changed = (sess.FileSize != nil &&
+				*sess.FileSize != size) ||
+				(sess.FileMtime != nil && *sess.FileMtime != mtime)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, src)
+}
+
+// locateSessionSource returns the session's source file as
+// located now (falling back to the stored path), the agent
+// directory containing it, and the path to stat on disk.
+func (s *Server) locateSessionSource(
+	sess *db.Session,
+) (path, root, statPath string) {
+	agent := parser.AgentType(sess.Agent)
+	path, root = s.engine.LocateSourceFile(sess.ID)
+	if path == "" && sess.FilePath != nil {
+		path = s.engine.BridgeSourcePath(agent, *sess.FilePath)
+		root = s.engine.SourceRoot(agent, path)
+	}
+	statPath = path
+	if def, ok := parser.AgentByType(agent); ok && !def.FileBased {
+		// Database-backed agents record "<db path>#<id>".
+		statPath, _, _ = strings.Cut(statPath, "#")
+	}
+	return path, root, statPath
+}
+
+// RevealFunc opens a directory in the OS file manager.
+type RevealFunc func(dir string) error
+
+// revealInFileManager opens dir with the platform's file
+// manager. It does not wait for the file manager to exit.
+func revealInFileManager(dir string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", dir)
+	case "linux":
+		cmd = exec.Command("xdg-open", dir)
+	case "windows":
+		cmd = exec.Command("explorer", dir)
+	default:
+		return fmt.Errorf("unsupported platform %s", runtime.GOOS)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+// isLoopbackRequest reports whether the request came from the
+// machine the server runs on.
+func isLoopbackRequest(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+type revealResponse struct {
+	Path string `json:"path"`
+	Dir  string `json:"dir"`
+}
+
+// handleRevealSessionSource opens the directory holding a
+// session's source file in the OS file manager. It only serves
+// loopback clients, since the window opens on the server's
+// desktop, and requires {"confirm": true} so a stray request
+// cannot launch programs.
+func (s *Server) handleRevealSessionSource(
+	w http.ResponseWriter, r *http.Request,
+) {
+	if !isLoopbackRequest(r) {
+		writeError(w, http.StatusForbidden,
+			"reveal is only available on the local machine")
+		return
+	}
+	var req struct {
+		Confirm bool `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	if !req.Confirm {
+		writeError(w, http.StatusBadRequest, "confirmation required")
+		return
+	}
+
+	sess, err := s.db.GetSessionFull(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sess == nil {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+
+	_, _, path := s.locateSessionSource(sess)
+	if path == "" {
+		writeError(w, http.StatusNotFound, "session has no source file")
+		return
+	}
+	if _, err := os.Stat(path); err != nil {
+		writeError(w, http.StatusNotFound, "source file not found")
+		return
+	}
+
+	dir := filepath.Dir(path)
+	if err := s.revealFunc(dir); err != nil {
+		slog.Error("reveal source failed", "dir", dir, "err", err)
+		writeError(w, http.StatusInternalServerError,
+			"could not open file manager")
+		return
+	}
+	writeJSON(w, http.StatusOK, revealResponse{Path: path, Dir: dir})
+}
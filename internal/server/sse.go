@@ -3,7 +3,7 @@ package server
 import (
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -41,7 +41,7 @@ func (s *SSEStream) Send(event, data string) bool {
 	defer func() { _ = rc.SetWriteDeadline(time.Time{}) }()
 
 	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
-		log.Printf("SSE write error for %q: %v", event, err)
+		slog.Warn("SSE write error", "event", event, "err", err)
 		return false
 	}
 	s.f.Flush()
@@ -53,7 +53,7 @@ func (s *SSEStream) Send(event, data string) bool {
 func (s *SSEStream) SendJSON(event string, v any) bool {
 	data, err := json.Marshal(v)
 	if err != nil {
-		log.Printf("SSE marshal error for %q: %v", event, err)
+		slog.Error("SSE marshal error", "event", event, "err", err)
 		return false
 	}
 	return s.Send(event, string(data))
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// defaultTodoDays is how far back /todos looks when neither
+// since nor days is given.
+const defaultTodoDays = 14
+
+func (s *Server) handleGetSessionTodos(
+	w http.ResponseWriter, r *http.Request,
+) {
+	id := r.PathValue("id")
+	todos, err := s.db.GetSessionTodos(r.Context(), id)
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, todos)
+}
+
+// handleListOpenTodos lists unfinished todo items from the
+// latest todo list of recently active sessions, grouped by
+// session. since (a date or RFC3339 timestamp) bounds how old
+// a list may be; otherwise days does, defaulting to
+// defaultTodoDays.
+func (s *Server) handleListOpenTodos(
+	w http.ResponseWriter, r *http.Request,
+) {
+	q := r.URL.Query()
+
+	limit, ok := parseIntParam(w, r, "limit")
+	if !ok {
+		return
+	}
+	days, ok := parseIntParam(w, r, "days")
+	if !ok {
+		return
+	}
+	if days < 0 {
+		writeError(w, http.StatusBadRequest, "days must be >= 0")
+		return
+	}
+	if days == 0 {
+		days = defaultTodoDays
+	}
+
+	since := q.Get("since")
+	if since != "" {
+		if !isValidDate(since) && !isValidTimestamp(since) {
+			writeError(w, http.StatusBadRequest,
+				"invalid since: use YYYY-MM-DD or RFC3339 timestamp")
+			return
+		}
+	} else {
+		since = time.Now().UTC().
+			AddDate(0, 0, -days).Format(time.RFC3339)
+	}
+
+	resp, err := s.db.ListOpenTodos(r.Context(), db.TodoFilter{
+		Project: q.Get("project"),
+		Agent:   q.Get("agent"),
+		Machine: q.Get("machine"),
+		Since:   since,
+		Limit:   clampLimit(limit, db.DefaultTodoSessionLimit, db.MaxTodoSessionLimit),
+	})
+	if err != nil {
+		if handleContextError(w, err) {
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -3,7 +3,7 @@ package server
 import (
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -114,6 +114,12 @@ func (s *Server) saveSessionToDB(
 		Agent:            string(sess.Agent),
 		MessageCount:     sess.MessageCount,
 		UserMessageCount: sess.UserMessageCount,
+		InterruptCount:   sess.InterruptCount,
+		Headless:         sess.Automated,
+		GitBranch:        sess.GitBranch,
+		Worktree:         sess.Worktree,
+		EntryPoint:       sess.EntryPoint,
+		Client:           sess.Client,
 		ParentSessionID:  strPtr(sess.ParentSessionID),
 		RelationshipType: string(sess.RelationshipType),
 		FilePath:         strPtr(sess.File.Path),
@@ -134,6 +140,15 @@ func (s *Server) saveSessionToDB(
 	if err := s.db.UpsertSession(dbSess); err != nil {
 		return fmt.Errorf("storing session: %w", err)
 	}
+	if reason := db.BotReason(
+		sess.Machine, sess.Automated,
+	); reason != "" {
+		if err := s.db.MarkBotMachine(
+			sess.Machine, reason,
+		); err != nil {
+			return fmt.Errorf("labeling machine: %w", err)
+		}
+	}
 
 	dbMsgs := make([]db.Message, len(msgs))
 	for i, m := range msgs {
@@ -146,6 +161,15 @@ func (s *Server) saveSessionToDB(
 			HasThinking:   m.HasThinking,
 			HasToolUse:    m.HasToolUse,
 			ContentLength: m.ContentLength,
+			Model:         m.Model,
+			ModelSwitch:   m.ModelSwitch,
+			Kind:          m.Kind,
+
+			InputTokens:         m.Usage.InputTokens,
+			OutputTokens:        m.Usage.OutputTokens,
+			CacheReadTokens:     m.Usage.CacheReadTokens,
+			CacheCreationTokens: m.Usage.CacheCreationTokens,
+			ReasoningTokens:     m.Usage.ReasoningTokens,
 		}
 	}
 
@@ -154,6 +178,11 @@ func (s *Server) saveSessionToDB(
 	); err != nil {
 		return fmt.Errorf("storing messages: %w", err)
 	}
+	if err := s.db.ReplaceUserOrigins(
+		sess.ID, parser.CountUserOrigins(sess, msgs),
+	); err != nil {
+		return fmt.Errorf("storing user origins: %w", err)
+	}
 	return nil
 }
 
@@ -171,7 +200,7 @@ func (s *Server) handleUploadSession(
 		req.project, req.filename, req.file,
 	)
 	if err != nil {
-		log.Printf("Error saving upload: %v", err)
+		slog.Error("saving upload", "err", err)
 		writeError(w, http.StatusInternalServerError,
 			"failed to save upload")
 		return
@@ -195,7 +224,7 @@ func (s *Server) handleUploadSession(
 
 	for _, pr := range results {
 		if err := s.saveSessionToDB(pr.Session, pr.Messages); err != nil {
-			log.Printf("Error saving session to DB: %v", err)
+			slog.Error("saving uploaded session to DB", "err", err)
 			writeError(w, http.StatusInternalServerError,
 				"failed to save session to database")
 			return
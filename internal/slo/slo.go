@@ -0,0 +1,14 @@
+// Package slo names the latency metrics an SLO can target. It
+// has no dependencies so config can validate SLOs and db can
+// measure them without importing each other.
+package slo
+
+// Metrics measured from message timestamps.
+const (
+	// MetricFirstResponse is the delay from the first user
+	// message to the first assistant reply in a session.
+	MetricFirstResponse = "first_response"
+	// MetricTurnCycle is the delay of every user→assistant
+	// transition, as in the velocity turn cycle.
+	MetricTurnCycle = "turn_cycle"
+)
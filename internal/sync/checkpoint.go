@@ -0,0 +1,144 @@
+package sync
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// resyncCheckpoint tracks a full resync's progress in the
+// database being built so an interrupted resync can resume.
+// A file is recorded once the batch holding its sessions is
+// written, and an agent directory once all of its files are.
+// Methods are no-ops on a nil checkpoint, which plain syncs
+// use.
+type resyncCheckpoint struct {
+	db    *db.DB
+	saved db.ResyncCheckpoint
+
+	// resumed counts discovered files already done in the
+	// interrupted run.
+	resumed int
+
+	dirOf   map[string]string // file path → its agent dir
+	left    map[string]int    // agent dir → files not recorded
+	pending db.ResyncCheckpoint
+	log     *slog.Logger
+}
+
+func newResyncCheckpoint(
+	d *db.DB, saved db.ResyncCheckpoint, log *slog.Logger,
+) *resyncCheckpoint {
+	return &resyncCheckpoint{
+		log:     log,
+		db:      d,
+		saved:   saved,
+		dirOf:   map[string]string{},
+		left:    map[string]int{},
+		pending: emptyCheckpoint(),
+	}
+}
+
+func emptyCheckpoint() db.ResyncCheckpoint {
+	return db.ResyncCheckpoint{
+		Files:   map[string]int64{},
+		Skipped: map[string]int64{},
+		Dirs:    map[string]bool{},
+	}
+}
+
+// resuming reports whether the resync continues an
+// interrupted one.
+func (c *resyncCheckpoint) resuming() bool {
+	return c != nil && !c.saved.Empty()
+}
+
+// resumedFiles returns the number of files carried over from
+// the interrupted run.
+func (c *resyncCheckpoint) resumedFiles() int {
+	if c == nil {
+		return 0
+	}
+	return c.resumed
+}
+
+// filter drops the files of dir that the interrupted run
+// already finished and are unchanged since, and starts
+// tracking the rest.
+func (c *resyncCheckpoint) filter(
+	dir string, files []parser.DiscoveredFile,
+) []parser.DiscoveredFile {
+	if c == nil {
+		return files
+	}
+	if c.saved.Dirs[dir] {
+		c.resumed += len(files)
+		return nil
+	}
+	var todo []parser.DiscoveredFile
+	for _, f := range files {
+		if c.finished(f.Path) {
+			c.resumed++
+			continue
+		}
+		todo = append(todo, f)
+		c.dirOf[f.Path] = dir
+		c.left[dir]++
+	}
+	if c.left[dir] == 0 {
+		c.pending.Dirs[dir] = true
+	}
+	return todo
+}
+
+// finished reports whether path was checkpointed at its
+// current mtime.
+func (c *resyncCheckpoint) finished(path string) bool {
+	mtime, ok := c.saved.Files[path]
+	if !ok {
+		mtime, ok = c.saved.Skipped[path]
+	}
+	if !ok {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.ModTime().UnixNano() == mtime
+}
+
+// done marks path finished as of the next flush. Files with
+// sessions must only be flushed after their batch is written.
+func (c *resyncCheckpoint) done(
+	path string, mtime int64, hasSessions bool,
+) {
+	if c == nil {
+		return
+	}
+	if hasSessions {
+		c.pending.Files[path] = mtime
+	} else {
+		c.pending.Skipped[path] = mtime
+	}
+	dir, ok := c.dirOf[path]
+	if !ok {
+		return
+	}
+	c.left[dir]--
+	if c.left[dir] == 0 {
+		c.pending.Dirs[dir] = true
+	}
+}
+
+// flush records the files and directories finished since the
+// last flush. Failures are logged: at worst a resumed resync
+// redoes some work.
+func (c *resyncCheckpoint) flush() {
+	if c == nil || c.pending.Empty() {
+		return
+	}
+	if err := c.db.SaveResyncCheckpoint(c.pending); err != nil {
+		c.log.Warn("resync: saving checkpoint", "err", err)
+	}
+	c.pending = emptyCheckpoint()
+}
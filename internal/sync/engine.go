@@ -3,11 +3,12 @@ package sync
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	gosync "sync"
 	"time"
@@ -28,6 +29,19 @@ type EngineConfig struct {
 	AgentDirs               map[parser.AgentType][]string
 	Machine                 string
 	BlockedResultCategories []string
+
+	// Workers is how many files sync parses at once. Zero
+	// uses the CPU count, capped at maxWorkers.
+	Workers int
+
+	// OnSessionWritten, when set, is called after each session
+	// and its messages are stored. It runs on the sync path, so
+	// it must return quickly.
+	OnSessionWritten func(db.Session)
+
+	// Logger receives the engine's log output. Nil uses
+	// slog.Default.
+	Logger *slog.Logger
 }
 
 // Engine orchestrates session file discovery and sync.
@@ -36,30 +50,58 @@ type Engine struct {
 	agentDirs               map[parser.AgentType][]string
 	machine                 string
 	blockedResultCategories map[string]bool
+	workers                 int
 	syncMu                  gosync.Mutex // serializes all sync operations
 	mu                      gosync.RWMutex
 	lastSync                time.Time
 	lastSyncStats           SyncStats
 	// skipCache tracks paths that should be skipped on
 	// subsequent syncs, keyed by path with the file mtime
-	// at time of caching. Covers parse errors and
-	// non-interactive sessions (nil result). The file is
-	// retried when its mtime changes.
+	// at time of caching. Covers non-interactive sessions
+	// (nil result). The file is retried when its mtime
+	// changes.
 	skipMu    gosync.RWMutex
 	skipCache map[string]int64
+	// quarantine tracks files whose last parse failed, keyed
+	// by path. They are retried on a backoff schedule rather
+	// than whenever their mtime changes.
+	quarMu     gosync.RWMutex
+	quarantine map[string]db.QuarantinedFile
+	// tails records where the last sync stopped parsing each
+	// recently written Claude session file, keyed by path, so
+	// a file that only grew is parsed from there.
+	tailMu gosync.Mutex
+	tails  map[string]*claudeTail
+	// checkpoint records progress while ResyncAll syncs into
+	// its temp database; nil otherwise. Guarded by syncMu.
+	checkpoint *resyncCheckpoint
+
+	onSessionWritten func(db.Session)
+	log              *slog.Logger
 }
 
 // NewEngine creates a sync engine. It pre-populates the
-// in-memory skip cache from the database so that files
-// skipped in a prior run are not re-parsed on startup.
+// in-memory skip cache and parse-failure quarantine from the
+// database so that files skipped in a prior run are not
+// re-parsed on startup.
 func NewEngine(
 	database *db.DB, cfg EngineConfig,
 ) *Engine {
+	log := cfg.Logger
+	if log == nil {
+		log = slog.Default()
+	}
 	skipCache := make(map[string]int64)
 	if loaded, err := database.LoadSkippedFiles(); err == nil {
 		skipCache = loaded
 	} else {
-		log.Printf("loading skip cache: %v", err)
+		log.Warn("loading skip cache", "err", err)
+	}
+	quarantine := make(map[string]db.QuarantinedFile)
+	if loaded, err := database.LoadQuarantine(); err == nil {
+		quarantine = loaded
+	} else {
+		log.Warn("loading quarantine", "err", err)
 	}
 
 	dirs := make(map[parser.AgentType][]string, len(cfg.AgentDirs))
@@ -72,7 +114,12 @@ func NewEngine(
 		agentDirs:               dirs,
 		machine:                 cfg.Machine,
 		blockedResultCategories: blockedCategorySet(cfg.BlockedResultCategories),
+		workers:                 cfg.Workers,
 		skipCache:               skipCache,
+		quarantine:              quarantine,
+		tails:                   make(map[string]*claudeTail),
+		onSessionWritten:        cfg.OnSessionWritten,
+		log:                     log,
 	}
 }
 
@@ -102,6 +149,12 @@ func (e *Engine) LastSync() time.Time {
 	return e.lastSync
 }
 
+// Machine returns the label given to sessions found on this
+// machine.
+func (e *Engine) Machine() string {
+	return e.machine
+}
+
 // LastSyncStats returns statistics from the last sync.
 func (e *Engine) LastSyncStats() SyncStats {
 	e.mu.RLock()
@@ -111,7 +164,8 @@ func (e *Engine) LastSyncStats() SyncStats {
 
 type syncJob struct {
 	processResult
-	path string
+	path  string
+	agent parser.AgentType
 }
 
 // SyncPaths syncs only the specified changed file paths
@@ -126,12 +180,14 @@ func (e *Engine) SyncPaths(paths []string) {
 
 	e.syncMu.Lock()
 	defer e.syncMu.Unlock()
+	t0 := time.Now()
 
-	results := e.startWorkers(files)
+	results := e.startWorkers(context.Background(), files)
 	stats := e.collectAndBatch(
 		results, len(files), nil,
 	)
 	e.persistSkipCache()
+	syncDuration.Observe(time.Since(t0).Seconds(), "paths")
 
 	e.mu.Lock()
 	e.lastSync = time.Now()
@@ -139,9 +195,7 @@ func (e *Engine) SyncPaths(paths []string) {
 	e.mu.Unlock()
 
 	if stats.Synced > 0 {
-		log.Printf(
-			"sync: %d file(s) updated", stats.Synced,
-		)
+		e.log.Info("sync: files updated", "count", stats.Synced)
 	}
 }
 
@@ -152,8 +206,12 @@ func (e *Engine) classifyPaths(
 	paths []string,
 ) []parser.DiscoveredFile {
 	geminiProjectsByDir := make(map[string]map[string]string)
+	ignores := make(map[string]*parser.IgnoreMatcher)
 	var files []parser.DiscoveredFile
 	for _, p := range paths {
+		if e.pathIgnored(p, ignores) {
+			continue
+		}
 		if df, ok := e.classifyOnePath(
 			p, geminiProjectsByDir,
 		); ok {
@@ -163,6 +221,32 @@ func (e *Engine) classifyPaths(
 	return files
 }
 
+// discoverFiles runs def's discovery in dir, dropping files
+// excluded by ignore files inside dir.
+
+// pathIgnored reports whether path is excluded by ignore files
+// in the agent root containing it. Matchers are cached in
+// ignores by root.
+func (e *Engine) pathIgnored(
+	path string, ignores map[string]*parser.IgnoreMatcher,
+) bool {
+	for _, dirs := range e.agentDirs {
+		root := findContainingDir(dirs, path)
+		if root == "" {
+			continue
+		}
+		m := ignores[root]
+		if m == nil {
+			m = parser.NewIgnoreMatcher(root)
+			ignores[root] = m
+		}
+		if m.Ignored(path) {
+			return true
+		}
+	}
+	return false
+}
+
 // isUnder checks whether path is strictly inside dir after
 // cleaning both paths. Returns the relative path on success.
 func isUnder(dir, path string) (string, bool) {
@@ -245,12 +329,29 @@ func (e *Engine) classifyOnePath(
 	}
 
 	// Codex: <codexDir>/<year>/<month>/<day>/<file>.jsonl
+	//    or: <archived_sessions>/rollout-<...>.jsonl
+	//    or: <codexDir>/../history.jsonl
 	for _, codexDir := range e.agentDirs[parser.AgentCodex] {
 		if codexDir == "" {
 			continue
 		}
+		if h := parser.CodexHistoryPath(codexDir); h != "" &&
+			path == h {
+			return parser.DiscoveredFile{
+				Path:  path,
+				Agent: parser.AgentCodex,
+			}, true
+		}
 		if rel, ok := isUnder(codexDir, path); ok {
 			parts := strings.Split(rel, sep)
+			if len(parts) == 1 &&
+				parser.IsCodexArchiveDir(codexDir) &&
+				parser.IsCodexRolloutName(parts[0]) {
+				return parser.DiscoveredFile{
+					Path:  path,
+					Agent: parser.AgentCodex,
+				}, true
+			}
 			if len(parts) != 4 {
 				continue
 			}
@@ -480,6 +581,35 @@ func (e *Engine) classifyOnePath(
 		}
 	}
 
+	// Goose: <gooseDir>/<name>.jsonl
+	for _, gooseDir := range e.agentDirs[parser.AgentGoose] {
+		if gooseDir == "" {
+			continue
+		}
+		if rel, ok := isUnder(gooseDir, path); ok &&
+			strings.Count(rel, sep) == 0 &&
+			parser.IsGooseSessionFileName(rel) {
+			return parser.DiscoveredFile{
+				Path:  path,
+				Agent: parser.AgentGoose,
+			}, true
+		}
+	}
+
+	// Aider: <projectRoot>/.../.aider.chat.history.md
+	for _, aiderDir := range e.agentDirs[parser.AgentAider] {
+		if aiderDir == "" {
+			continue
+		}
+		if rel, ok := isUnder(aiderDir, path); ok &&
+			parser.IsAiderHistoryPath(rel) {
+			return parser.DiscoveredFile{
+				Path:  path,
+				Agent: parser.AgentAider,
+			}, true
+		}
+	}
+
 	return parser.DiscoveredFile{}, false
 }
 
@@ -504,6 +634,10 @@ const resyncTempSuffix = "-resync"
 // atomically swaps the files and reopens the original DB
 // handle. This avoids the per-row trigger overhead of bulk
 // deleting hundreds of thousands of messages in place.
+//
+// Progress is checkpointed in the fresh database, so a resync
+// interrupted by a crash or shutdown resumes where it stopped
+// the next time ResyncAll runs.
 func (e *Engine) ResyncAll(
 	onProgress ProgressFunc,
 ) SyncStats {
@@ -522,31 +656,38 @@ func (e *Engine) ResyncAll(
 	ctx := context.Background()
 	oldFileSessions, err := origDB.FileBackedSessionCount(ctx)
 	if err != nil {
-		log.Printf("resync: get old file count: %v", err)
+		e.log.Warn("resync: get old file count", "err", err)
 		oldFileSessions = 1
 	}
 
-	// Clean up stale temp DB from a prior crash.
-	removeTempDB(tempPath)
-
-	// 1. Snapshot and clear in-memory skip cache. The
-	// snapshot is restored on early failure so behavior
-	// matches the persisted DB until the next restart.
+	// 1. Snapshot and clear the in-memory skip cache and
+	// quarantine so every file is retried. Failures during
+	// the resync are quarantined in the new DB. The snapshots
+	// are restored on early failure so behavior matches the
+	// persisted DB until the next restart.
 	e.skipMu.Lock()
 	savedSkipCache := e.skipCache
 	e.skipCache = make(map[string]int64)
 	e.skipMu.Unlock()
+	e.quarMu.Lock()
+	savedQuarantine := e.quarantine
+	e.quarantine = make(map[string]db.QuarantinedFile)
+	e.quarMu.Unlock()
 
 	restoreSkipCache := func() {
 		e.skipMu.Lock()
 		e.skipCache = savedSkipCache
 		e.skipMu.Unlock()
+		e.quarMu.Lock()
+		e.quarantine = savedQuarantine
+		e.quarMu.Unlock()
 	}
 
-	// 2. Open a fresh DB at the temp path.
-	newDB, err := db.Open(tempPath)
+	// 2. Open a fresh DB at the temp path, or the one an
+	// interrupted resync left there.
+	newDB, cp, err := e.openResyncDB(tempPath)
 	if err != nil {
-		log.Printf("resync: open temp db: %v", err)
+		e.log.Error("resync: open temp db", "err", err)
 		restoreSkipCache()
 		stats := SyncStats{
 			Aborted: true,
@@ -560,10 +701,25 @@ func (e *Engine) ResyncAll(
 		return stats
 	}
 
+	// Files the interrupted run found without sessions or
+	// failed on stay skipped and quarantined.
+	if cp.resuming() {
+		e.skipMu.Lock()
+		maps.Copy(e.skipCache, cp.saved.Skipped)
+		e.skipMu.Unlock()
+		if loaded, err := newDB.LoadQuarantine(); err == nil {
+			e.quarMu.Lock()
+			e.quarantine = loaded
+			e.quarMu.Unlock()
+		}
+	}
+
 	// 3. Point engine at newDB and sync into it.
 	e.db = newDB
-	stats := e.syncAllLocked(onProgress)
+	e.checkpoint = cp
+	stats := e.syncAllLocked(context.Background(), onProgress)
 	e.db = origDB // restore immediately
+	e.checkpoint = nil
 
 	// Abort swap when the fresh DB would be worse than the
 	// original:
@@ -577,12 +733,13 @@ func (e *Engine) ResyncAll(
 		stats.filesOK == 0 &&
 		oldFileSessions > 0
 	abortSwap := emptyDiscovery ||
-		(stats.Synced == 0 && stats.TotalSessions > 0) ||
+		(stats.Synced+stats.Resumed == 0 && stats.TotalSessions > 0) ||
 		(stats.Failed > 0 && stats.Failed > stats.filesOK)
 	if abortSwap {
-		log.Printf(
-			"resync: aborting swap, %d synced / %d failed / %d total",
-			stats.Synced, stats.Failed, stats.TotalSessions,
+		e.log.Warn(
+			"resync: aborting swap",
+			"synced", stats.Synced, "failed", stats.Failed,
+			"total", stats.TotalSessions,
 		)
 		newDB.Close()
 		removeTempDB(tempPath)
@@ -600,11 +757,11 @@ func (e *Engine) ResyncAll(
 	}
 
 	// 4. Close origDB connections first to quiesce writes,
-	// then copy insights into newDB (which is still open).
-	// This ensures no insight writes land in the old DB
-	// after the copy.
+	// then copy user data into newDB (which is still open).
+	// This ensures no insight or curation writes land in the
+	// old DB after the copy.
 	if err := origDB.CloseConnections(); err != nil {
-		log.Printf("resync: close orig db: %v", err)
+		e.log.Error("resync: close orig db", "err", err)
 		stats.Aborted = true
 		stats.Warnings = append(stats.Warnings,
 			"close before swap failed: "+err.Error(),
@@ -615,7 +772,7 @@ func (e *Engine) ResyncAll(
 		// Connections may be partially closed; reopen to
 		// restore service before returning.
 		if rerr := origDB.Reopen(); rerr != nil {
-			log.Printf("resync: recovery reopen: %v", rerr)
+			e.log.Error("resync: recovery reopen", "err", rerr)
 		}
 		e.mu.Lock()
 		e.lastSyncStats = stats
@@ -623,30 +780,46 @@ func (e *Engine) ResyncAll(
 		return stats
 	}
 
-	// origDB connections are now closed; copy insights into
-	// newDB (still open) from the quiesced old DB file.
-	tInsights := time.Now()
-	if err := newDB.CopyInsightsFrom(origPath); err != nil {
-		log.Printf("resync: copy insights: %v", err)
+	// origDB connections are now closed; copy insights,
+	// machine labels, session curation, and analytics
+	// snapshots into newDB (still open) from the quiesced old
+	// DB file.
+	tUserData := time.Now()
+	for _, c := range []struct {
+		what string
+		copy func(string) error
+	}{
+		{"insights", newDB.CopyInsightsFrom},
+		{"machines", newDB.CopyMachinesFrom},
+		{"curation", newDB.CopyCurationFrom},
+		{"analytics snapshots", newDB.CopyAnalyticsSnapshotsFrom},
+	} {
+		if err = c.copy(origPath); err != nil {
+			err = fmt.Errorf("copying %s: %w", c.what, err)
+			break
+		}
+	}
+	if err != nil {
+		e.log.Error("resync: copy user data", "err", err)
 		stats.Aborted = true
 		stats.Warnings = append(stats.Warnings,
-			"insights copy failed, aborting swap: "+
+			"user data copy failed, aborting swap: "+
 				err.Error(),
 		)
 		newDB.Close()
 		removeTempDB(tempPath)
 		restoreSkipCache()
 		if rerr := origDB.Reopen(); rerr != nil {
-			log.Printf("resync: recovery reopen: %v", rerr)
+			e.log.Error("resync: recovery reopen", "err", rerr)
 		}
 		e.mu.Lock()
 		e.lastSyncStats = stats
 		e.mu.Unlock()
 		return stats
 	}
-	log.Printf(
-		"resync: copy insights: %s",
-		time.Since(tInsights).Round(time.Millisecond),
+	e.log.Info(
+		"resync: copy user data",
+		"elapsed", time.Since(tUserData).Round(time.Millisecond),
 	)
 
 	// Copy orphaned sessions (source files gone) from the
@@ -654,7 +827,7 @@ func (e *Engine) ResyncAll(
 	// the swap to avoid losing archived sessions.
 	orphaned, err := newDB.CopyOrphanedDataFrom(origPath)
 	if err != nil {
-		log.Printf("resync: copy orphaned sessions: %v", err)
+		e.log.Error("resync: copy orphaned sessions", "err", err)
 		stats.Aborted = true
 		stats.Warnings = append(stats.Warnings,
 			"orphaned session copy failed, aborting swap: "+
@@ -664,7 +837,7 @@ func (e *Engine) ResyncAll(
 		removeTempDB(tempPath)
 		restoreSkipCache()
 		if rerr := origDB.Reopen(); rerr != nil {
-			log.Printf("resync: recovery reopen: %v", rerr)
+			e.log.Error("resync: recovery reopen", "err", rerr)
 		}
 		e.mu.Lock()
 		e.lastSyncStats = stats
@@ -673,13 +846,18 @@ func (e *Engine) ResyncAll(
 	}
 	stats.OrphanedCopied = orphaned
 
+	// The checkpoint only matters until the swap.
+	if err := newDB.ClearResyncCheckpoint(); err != nil {
+		e.log.Warn("resync: clear checkpoint", "err", err)
+	}
+
 	// 5. Close newDB and swap files, then reopen origDB.
 	newDB.Close()
 
 	removeWAL(origPath)
 
 	if err := os.Rename(tempPath, origPath); err != nil {
-		log.Printf("resync: rename temp db: %v", err)
+		e.log.Error("resync: rename temp db", "err", err)
 		stats.Aborted = true
 		stats.Warnings = append(stats.Warnings,
 			"resync swap failed: "+err.Error(),
@@ -688,7 +866,7 @@ func (e *Engine) ResyncAll(
 		restoreSkipCache()
 		// Restore service even on rename failure.
 		if rerr := origDB.Reopen(); rerr != nil {
-			log.Printf("resync: recovery reopen: %v", rerr)
+			e.log.Error("resync: recovery reopen", "err", rerr)
 		}
 		e.mu.Lock()
 		e.lastSyncStats = stats
@@ -698,7 +876,7 @@ func (e *Engine) ResyncAll(
 	removeWAL(tempPath)
 
 	if err := origDB.Reopen(); err != nil {
-		log.Printf("resync: reopen db: %v", err)
+		e.log.Error("resync: reopen db", "err", err)
 		stats.Warnings = append(stats.Warnings,
 			"reopen after resync failed: "+err.Error(),
 		)
@@ -714,6 +892,69 @@ func (e *Engine) ResyncAll(
 	return stats
 }
 
+// openResyncDB opens the temp database for a resync. One left
+// by an interrupted resync is reused when it holds a checkpoint
+// and is at the current data version; sessions it wrote
+// without checkpointing are discarded so they are parsed
+// again. Otherwise the resync starts from an empty database.
+func (e *Engine) openResyncDB(
+	tempPath string,
+) (*db.DB, *resyncCheckpoint, error) {
+	if _, err := os.Stat(tempPath); err == nil {
+		if d, cp := e.resumeResyncDB(tempPath); d != nil {
+			return d, cp, nil
+		}
+	}
+	removeTempDB(tempPath)
+	d, err := db.Open(tempPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, newResyncCheckpoint(d, db.ResyncCheckpoint{}, e.log), nil
+}
+
+// resumeResyncDB opens an interrupted resync's temp database,
+// returning nil if it cannot be resumed.
+func (e *Engine) resumeResyncDB(
+	tempPath string,
+) (*db.DB, *resyncCheckpoint) {
+	d, err := db.Open(tempPath)
+	if err != nil {
+		e.log.Warn("resync: open interrupted temp db", "err", err)
+		return nil, nil
+	}
+	if d.NeedsResync() {
+		d.Close()
+		return nil, nil
+	}
+	saved, err := d.LoadResyncCheckpoint()
+	if err != nil || saved.Empty() {
+		d.Close()
+		return nil, nil
+	}
+	discarded, err := d.DeleteUncheckpointedSessions()
+	if err != nil {
+		e.log.Warn("resync: discard partial sessions", "err", err)
+		d.Close()
+		return nil, nil
+	}
+	e.log.Info(
+		"resync: resuming interrupted resync",
+		"files", len(saved.Files)+len(saved.Skipped),
+		"dirs", len(saved.Dirs),
+		"discarded_sessions", discarded,
+	)
+	return d, newResyncCheckpoint(d, saved, e.log)
+}
+
+// ResyncInterrupted reports whether a resync of the database at
+// dbPath was interrupted, leaving a temp database that the next
+// ResyncAll may resume from.
+func ResyncInterrupted(dbPath string) bool {
+	_, err := os.Stat(dbPath + resyncTempSuffix)
+	return err == nil
+}
+
 // removeTempDB removes a temp database and its WAL/SHM files.
 func removeTempDB(path string) {
 	for _, suffix := range []string{"", "-wal", "-shm"} {
@@ -729,13 +970,24 @@ func removeWAL(path string) {
 
 // SyncAll discovers and syncs all session files from all agents.
 func (e *Engine) SyncAll(onProgress ProgressFunc) SyncStats {
+	stats, _ := e.SyncAllContext(context.Background(), onProgress)
+	return stats
+}
+
+// SyncAllContext is SyncAll stopping early once ctx is done.
+// Files not yet parsed are left for the next sync, and ctx's
+// error is returned with the stats so far.
+func (e *Engine) SyncAllContext(
+	ctx context.Context, onProgress ProgressFunc,
+) (SyncStats, error) {
 	e.syncMu.Lock()
 	defer e.syncMu.Unlock()
-	return e.syncAllLocked(onProgress)
+	stats := e.syncAllLocked(ctx, onProgress)
+	return stats, ctx.Err()
 }
 
 func (e *Engine) syncAllLocked(
-	onProgress ProgressFunc,
+	ctx context.Context, onProgress ProgressFunc,
 ) SyncStats {
 	t0 := time.Now()
 
@@ -746,48 +998,57 @@ func (e *Engine) syncAllLocked(
 			continue
 		}
 		for _, d := range e.agentDirs[def.Type] {
-			found := def.DiscoverFunc(d)
+			found := parser.Discover(def.Type, d)
 			counts[def.Type] += len(found)
-			all = append(all, found...)
+			all = append(all, e.checkpoint.filter(d, found)...)
 		}
 	}
 
 	verbose := onProgress == nil
 
 	if verbose {
-		log.Printf(
-			"discovered %d files (%d claude, %d codex, %d copilot, %d gemini, %d cursor, %d amp, %d vscode-copilot) in %s",
-			len(all),
-			counts[parser.AgentClaude],
-			counts[parser.AgentCodex],
-			counts[parser.AgentCopilot],
-			counts[parser.AgentGemini],
-			counts[parser.AgentCursor],
-			counts[parser.AgentAmp],
-			counts[parser.AgentVSCodeCopilot],
-			time.Since(t0).Round(time.Millisecond),
+		e.log.Info(
+			"discovered files",
+			"total", len(all),
+			"claude", counts[parser.AgentClaude],
+			"codex", counts[parser.AgentCodex],
+			"copilot", counts[parser.AgentCopilot],
+			"gemini", counts[parser.AgentGemini],
+			"cursor", counts[parser.AgentCursor],
+			"amp", counts[parser.AgentAmp],
+			"vscode_copilot", counts[parser.AgentVSCodeCopilot],
+			"aider", counts[parser.AgentAider],
+			"goose", counts[parser.AgentGoose],
+			"elapsed", time.Since(t0).Round(time.Millisecond),
 		)
 	}
+	resumed := e.checkpoint.resumedFiles()
 
 	if onProgress != nil {
 		onProgress(Progress{
 			Phase:         PhaseSyncing,
-			SessionsTotal: len(all),
+			SessionsTotal: len(all) + resumed,
+			SessionsDone:  resumed,
+			ResumedFiles:  resumed,
 		})
 	}
 
 	tWorkers := time.Now()
-	results := e.startWorkers(all)
+	results := e.startWorkers(ctx, all)
 	stats := e.collectAndBatch(
 		results, len(all), onProgress,
 	)
 	if verbose {
-		log.Printf(
-			"file sync: %d synced, %d skipped in %s",
-			stats.Synced, stats.Skipped,
-			time.Since(tWorkers).Round(time.Millisecond),
+		e.log.Info(
+			"file sync",
+			"synced", stats.Synced, "skipped", stats.Skipped,
+			"elapsed", time.Since(tWorkers).Round(time.Millisecond),
 		)
 	}
+	if ctx.Err() != nil {
+		e.persistSkipCache()
+		return stats
+	}
 
 	// Sync OpenCode sessions (DB-backed, not file-based).
 	// Uses full replace because OpenCode messages can change
@@ -802,30 +1063,32 @@ func (e *Engine) syncAllLocked(
 			e.writeSessionFull(pw)
 		}
 		if verbose {
-			log.Printf(
-				"opencode write: %d sessions in %s",
-				len(ocPending),
-				time.Since(tWrite).Round(time.Millisecond),
+			e.log.Info(
+				"opencode write",
+				"sessions", len(ocPending),
+				"elapsed", time.Since(tWrite).Round(time.Millisecond),
 			)
 		}
 	}
 	if verbose {
-		log.Printf(
-			"opencode sync: %s",
-			time.Since(tOC).Round(time.Millisecond),
+		e.log.Info(
+			"opencode sync",
+			"elapsed", time.Since(tOC).Round(time.Millisecond),
 		)
 	}
 
 	tPersist := time.Now()
 	skipCount := e.persistSkipCache()
 	if verbose {
-		log.Printf(
-			"persist skip cache (%d entries): %s",
-			skipCount,
-			time.Since(tPersist).Round(time.Millisecond),
+		e.log.Info(
+			"persist skip cache",
+			"entries", skipCount,
+			"elapsed", time.Since(tPersist).Round(time.Millisecond),
 		)
 	}
 
+	syncDuration.Observe(time.Since(t0).Seconds(), "full")
+
 	e.mu.Lock()
 	e.lastSync = time.Now()
 	e.lastSyncStats = stats
@@ -853,7 +1116,7 @@ func (e *Engine) syncOneOpenCode(dir string) []pendingWrite {
 
 	metas, err := parser.ListOpenCodeSessionMeta(dbPath)
 	if err != nil {
-		log.Printf("sync opencode: %v", err)
+		e.log.Warn("sync opencode", "err", err)
 		return nil
 	}
 	if len(metas) == 0 {
@@ -879,8 +1142,8 @@ func (e *Engine) syncOneOpenCode(dir string) []pendingWrite {
 			dbPath, sid, e.machine,
 		)
 		if err != nil {
-			log.Printf(
-				"opencode session %s: %v", sid, err,
+			e.log.Warn(
+				"opencode session", "session", sid, "err", err,
 			)
 			continue
 		}
@@ -896,12 +1159,12 @@ func (e *Engine) syncOneOpenCode(dir string) []pendingWrite {
 	return pending
 }
 
-// startWorkers fans out file processing across a worker pool
-// and returns a channel of results.
+// startWorkers parses files on a worker pool. Once ctx is done,
+// the remaining files are reported cancelled without parsing.
 func (e *Engine) startWorkers(
-	files []parser.DiscoveredFile,
+	ctx context.Context, files []parser.DiscoveredFile,
 ) <-chan syncJob {
-	workers := min(max(runtime.NumCPU(), 2), maxWorkers)
+	workers := e.workerCount()
 
 	jobs := make(chan parser.DiscoveredFile, len(files))
 	results := make(chan syncJob, len(files))
@@ -909,9 +1172,18 @@ func (e *Engine) startWorkers(
 	for range workers {
 		go func() {
 			for file := range jobs {
+				if ctx.Err() != nil {
+					results <- syncJob{
+						processResult: processResult{cancelled: true},
+						path:          file.Path,
+						agent:         file.Agent,
+					}
+					continue
+				}
 				results <- syncJob{
 					processResult: e.processFile(file),
 					path:          file.Path,
+					agent:         file.Agent,
 				}
 			}
 		}()
@@ -924,19 +1196,36 @@ func (e *Engine) startWorkers(
 	return results
 }
 
+// workerCount returns how many files are parsed at once: the
+// configured count, or else one per CPU up to maxWorkers.
+func (e *Engine) workerCount() int {
+	if e.workers > 0 {
+		return e.workers
+	}
+	return min(max(runtime.NumCPU(), 2), maxWorkers)
+}
+
 // collectAndBatch drains the results channel, batches
 // successful parses, and writes them to the database.
 func (e *Engine) collectAndBatch(
 	results <-chan syncJob, total int,
 	onProgress ProgressFunc,
 ) SyncStats {
+	// Files finished by an interrupted resync count as
+	// discovered and done.
+	cp := e.checkpoint
+	resumed := cp.resumedFiles()
 	var stats SyncStats
-	stats.TotalSessions = total
-	stats.filesDiscovered = total
+	stats.TotalSessions = total + resumed
+	stats.filesDiscovered = total + resumed
+	stats.filesOK = resumed
+	stats.Resumed = resumed
 
 	progress := Progress{
 		Phase:         PhaseSyncing,
-		SessionsTotal: total,
+		SessionsTotal: total + resumed,
+		SessionsDone:  resumed,
+		ResumedFiles:  resumed,
 	}
 
 	var pending []pendingWrite
@@ -944,15 +1233,27 @@ func (e *Engine) collectAndBatch(
 	for range total {
 		r := <-results
 
+		if r.cancelled {
+			// Not parsed; left for the next sync.
+			continue
+		}
 		if r.err != nil {
 			stats.RecordFailed()
-			if r.mtime != 0 {
-				e.cacheSkip(r.path, r.mtime)
+			parseErrors.Inc(string(r.agent))
+			// Failures are not retried on resume; the
+			// quarantine entry is in the database too.
+			cp.done(r.path, r.mtime, false)
+			if r.mtime == 0 {
+				// Stat failed: the file is gone or
+				// inaccessible, not unparseable.
+				e.log.Warn("sync error", "err", r.err)
+				continue
 			}
-			log.Printf("sync error: %v", r.err)
+			e.quarantineFile(r.path, r.agent, r.err)
 			continue
 		}
 		if r.skip {
+			cp.done(r.path, r.mtime, false)
 			stats.RecordSkip()
 			progress.SessionsDone++
 			if onProgress != nil {
@@ -960,7 +1261,10 @@ func (e *Engine) collectAndBatch(
 			}
 			continue
 		}
+		e.releaseQuarantine(r.path)
+		filesParsed.Inc(string(r.agent))
 		if len(r.results) == 0 {
+			cp.done(r.path, r.mtime, false)
 			e.cacheSkip(r.path, r.mtime)
 			progress.SessionsDone++
 			if onProgress != nil {
@@ -970,19 +1274,16 @@ func (e *Engine) collectAndBatch(
 		}
 		e.clearSkip(r.path)
 		stats.filesOK++
+		cp.done(r.path, r.mtime, true)
 
-		for _, pr := range r.results {
-			pending = append(pending, pendingWrite{
-				sess: pr.Session,
-				msgs: pr.Messages,
-			})
-		}
+		pending = append(pending, r.writes()...)
 
 		if len(pending) >= batchSize {
 			stats.RecordSynced(len(pending))
 			progress.MessagesIndexed += countMessages(pending)
 			e.writeBatch(pending)
 			pending = pending[:0]
+			cp.flush()
 		}
 
 		progress.SessionsDone++
@@ -996,6 +1297,7 @@ func (e *Engine) collectAndBatch(
 		progress.MessagesIndexed += countMessages(pending)
 		e.writeBatch(pending)
 	}
+	cp.flush()
 
 	progress.Phase = PhaseDone
 	if onProgress != nil {
@@ -1005,10 +1307,39 @@ func (e *Engine) collectAndBatch(
 }
 
 type processResult struct {
-	results []parser.ParseResult
-	skip    bool
-	mtime   int64
-	err     error
+	results   []parser.ParseResult
+	skip      bool
+	cancelled bool
+	mtime     int64
+	err       error
+
+	// tail is where parsing of a Claude file stopped, for the
+	// file's main session. When appended is set, that session's
+	// messages are only those parsed from lines appended since
+	// the previous tail, carried the earlier tool calls the
+	// lines completed, and uncredited the ordinals of earlier
+	// messages whose usage moved to a new one (see appendClaude).
+	tail       *claudeTail
+	appended   bool
+	carried    []parser.ParsedToolCall
+	uncredited []int
+}
+
+// writes returns the pending writes for r's parse results,
+// attaching r's tail to the file's main session.
+func (r processResult) writes() []pendingWrite {
+	pending := make([]pendingWrite, 0, len(r.results))
+	for _, pr := range r.results {
+		pw := pendingWrite{sess: pr.Session, msgs: pr.Messages}
+		if r.tail != nil && pr.Session.ID == r.tail.sessionID {
+			pw.tail = r.tail
+			pw.appended = r.appended
+			pw.carried = r.carried
+			pw.uncredited = r.uncredited
+		}
+		pending = append(pending, pw)
+	}
+	return pending
 }
 
 func (e *Engine) processFile(
@@ -1026,13 +1357,21 @@ func (e *Engine) processFile(
 	// downstream cache operations use a consistent value.
 	mtime := info.ModTime().UnixNano()
 
-	// Skip files cached from a previous sync (parse errors
-	// or non-interactive sessions) whose mtime is unchanged.
-	e.skipMu.RLock()
-	cachedMtime, cached := e.skipCache[file.Path]
-	e.skipMu.RUnlock()
-	if cached && cachedMtime == mtime {
-		return processResult{skip: true, mtime: mtime}
+	// Quarantined files (parse errors) are retried on their
+	// backoff schedule whether or not they changed. Other
+	// files cached from a previous sync (non-interactive
+	// sessions) are skipped while their mtime is unchanged.
+	if due, ok := e.quarantineDue(file.Path, time.Now()); ok {
+		if !due {
+			return processResult{skip: true, mtime: mtime}
+		}
+	} else {
+		e.skipMu.RLock()
+		cachedMtime, cached := e.skipCache[file.Path]
+		e.skipMu.RUnlock()
+		if cached && cachedMtime == mtime {
+			return processResult{skip: true, mtime: mtime}
+		}
 	}
 
 	var res processResult
@@ -1053,6 +1392,10 @@ func (e *Engine) processFile(
 		res = e.processVSCodeCopilot(file, info)
 	case parser.AgentOpenClaw:
 		res = e.processOpenClaw(file, info)
+	case parser.AgentAider:
+		res = e.processAider(file, info)
+	case parser.AgentGoose:
+		res = e.processGoose(file, info)
 	default:
 		res = processResult{
 			err: fmt.Errorf(
@@ -1091,7 +1434,7 @@ func (e *Engine) persistSkipCache() int {
 	e.skipMu.RUnlock()
 
 	if err := e.db.ReplaceSkippedFiles(snapshot); err != nil {
-		log.Printf("persisting skip cache: %v", err)
+		e.log.Warn("persisting skip cache", "err", err)
 	}
 	return len(snapshot)
 }
@@ -1131,6 +1474,19 @@ func (e *Engine) shouldSkipByPath(
 		storedMtime == info.ModTime().UnixNano()
 }
 
+// shouldSkipFilePart checks file size and mtime against what is
+// stored for a file merged into a session as an extra part.
+func (e *Engine) shouldSkipFilePart(
+	path string, info os.FileInfo,
+) bool {
+	storedSize, storedMtime, ok := e.db.GetFilePartInfo(path)
+	if !ok {
+		return false
+	}
+	return storedSize == info.Size() &&
+		storedMtime == info.ModTime().UnixNano()
+}
+
 func (e *Engine) processClaude(
 	file parser.DiscoveredFile, info os.FileInfo,
 ) processResult {
@@ -1148,28 +1504,32 @@ func (e *Engine) processClaude(
 		}
 	}
 
-	// Determine project name from cwd if possible
-	project := parser.GetProjectName(file.Project)
+	if res, ok := e.appendClaude(file, info); ok {
+		return res
+	}
+
+	// Determine project name, preferring cwd over the lossy
+	// directory encoding.
 	cwd, gitBranch := parser.ExtractClaudeProjectHints(
 		file.Path,
 	)
-	if cwd != "" {
-		if p := parser.ExtractProjectFromCwdWithBranch(
-			cwd, gitBranch,
-		); p != "" {
-			project = p
-		}
-	}
+	project := parser.DecodeClaudeProject(
+		file.Path, file.Project, cwd, gitBranch,
+	)
 
-	results, err := parser.ParseClaudeSession(
+	results, parsed, err := parser.ParseClaudeSessionTail(
 		file.Path, project, e.machine,
 	)
 	if err != nil {
 		return processResult{err: err}
 	}
 
-	hash, err := ComputeFileHash(file.Path)
-	if err == nil {
+	tail := e.newTail(file.Path, info, results, parsed)
+	if tail != nil {
+		for i := range results {
+			results[i].Session.File.Hash = tail.hash
+		}
+	} else if hash, err := ComputeFileHash(file.Path); err == nil {
 		for i := range results {
 			results[i].Session.File.Hash = hash
 		}
@@ -1177,15 +1537,21 @@ func (e *Engine) processClaude(
 
 	parser.InferRelationshipTypes(results)
 
-	return processResult{results: results}
+	return processResult{results: results, tail: tail}
 }
 
 func (e *Engine) processCodex(
 	file parser.DiscoveredFile, info os.FileInfo,
 ) processResult {
+	if parser.IsCodexHistoryPath(file.Path) {
+		return e.processCodexHistory(file, info)
+	}
 
 	// Fast path: skip by file_path + mtime before parsing.
-	if e.shouldSkipByPath(file.Path, info) {
+	// Rollouts merged into another file's session are tracked
+	// as file parts instead.
+	if e.shouldSkipByPath(file.Path, info) ||
+		e.shouldSkipFilePart(file.Path, info) {
 		return processResult{skip: true}
 	}
 
@@ -1211,6 +1577,61 @@ func (e *Engine) processCodex(
 	}
 }
 
+// processCodexHistory stores the sessions in a Codex
+// history.jsonl that no rollout file covers, so pruned sessions
+// still count. A session whose rollout is stored, even if the
+// rollout has since been deleted, keeps that richer copy.
+func (e *Engine) processCodexHistory(
+	file parser.DiscoveredFile, info os.FileInfo,
+) processResult {
+	if e.shouldSkipByPath(file.Path, info) {
+		return processResult{skip: true}
+	}
+
+	results, err := parser.ParseCodexHistory(file.Path, e.machine)
+	if err != nil {
+		return processResult{err: err}
+	}
+
+	rollouts := e.codexRolloutIDs()
+	hash, hashErr := ComputeFileHash(file.Path)
+	kept := results[:0]
+	for _, r := range results {
+		if rollouts[r.Session.ID] {
+			continue
+		}
+		stored, err := e.db.GetSessionFull(
+			context.Background(), r.Session.ID,
+		)
+		if err == nil && stored != nil && stored.FilePath != nil &&
+			*stored.FilePath != file.Path {
+			continue
+		}
+		if hashErr == nil {
+			r.Session.File.Hash = hash
+		}
+		kept = append(kept, r)
+	}
+	return processResult{results: kept}
+}
+
+// codexRolloutIDs returns the session IDs of the rollout files
+// in the configured Codex directories.
+func (e *Engine) codexRolloutIDs() map[string]bool {
+	ids := map[string]bool{}
+	for _, dir := range e.agentDirs[parser.AgentCodex] {
+		if dir == "" {
+			continue
+		}
+		for _, f := range parser.DiscoverCodexSessions(dir) {
+			if uuid := parser.CodexRolloutUUID(f.Path); uuid != "" {
+				ids["codex:"+uuid] = true
+			}
+		}
+	}
+	return ids
+}
+
 func (e *Engine) processCopilot(
 	file parser.DiscoveredFile, info os.FileInfo,
 ) processResult {
@@ -1300,6 +1721,35 @@ func (e *Engine) processAmp(
 	}
 }
 
+func (e *Engine) processGoose(
+	file parser.DiscoveredFile, info os.FileInfo,
+) processResult {
+	if e.shouldSkipByPath(file.Path, info) {
+		return processResult{skip: true}
+	}
+
+	sess, msgs, err := parser.ParseGooseSession(
+		file.Path, e.machine,
+	)
+	if err != nil {
+		return processResult{err: err}
+	}
+	if sess == nil {
+		return processResult{}
+	}
+
+	hash, err := ComputeFileHash(file.Path)
+	if err == nil {
+		sess.File.Hash = hash
+	}
+
+	return processResult{
+		results: []parser.ParseResult{
+			{Session: *sess, Messages: msgs},
+		},
+	}
+}
+
 func (e *Engine) processVSCodeCopilot(
 	file parser.DiscoveredFile, info os.FileInfo,
 ) processResult {
@@ -1358,6 +1808,30 @@ func (e *Engine) processOpenClaw(
 	}
 }
 
+// processAider parses an Aider chat log, which holds one
+// session per Aider run in the project.
+func (e *Engine) processAider(
+	file parser.DiscoveredFile, info os.FileInfo,
+) processResult {
+	if e.shouldSkipByPath(file.Path, info) {
+		return processResult{skip: true}
+	}
+
+	results, err := parser.ParseAiderHistory(file.Path, e.machine)
+	if err != nil {
+		return processResult{err: err}
+	}
+
+	hash, err := ComputeFileHash(file.Path)
+	if err == nil {
+		for i := range results {
+			results[i].Session.File.Hash = hash
+		}
+	}
+
+	return processResult{results: results}
+}
+
 func (e *Engine) processCursor(
 	file parser.DiscoveredFile, info os.FileInfo,
 ) processResult {
@@ -1441,63 +1915,368 @@ func validateCursorContainment(
 type pendingWrite struct {
 	sess parser.ParsedSession
 	msgs []parser.ParsedMessage
+
+	// fileParts lists extra source files merged into sess.
+	// partsChanged is set when they differ from what is stored,
+	// so message ordinals may have shifted.
+	fileParts    []db.SessionFilePart
+	partsChanged bool
+
+	// tail, appended, carried and uncredited come from
+	// processResult.
+	tail       *claudeTail
+	appended   bool
+	carried    []parser.ParsedToolCall
+	uncredited []int
 }
 
 func (e *Engine) writeBatch(batch []pendingWrite) {
 	for _, pw := range batch {
+		if pw.appended {
+			e.writeAppended(pw)
+			continue
+		}
+		pw = e.mergeSplitCodex(pw)
 		msgs := toDBMessages(pw, e.blockedResultCategories)
 		s := toDBSession(pw)
 		s.MessageCount, s.UserMessageCount =
 			postFilterCounts(msgs)
+		// Checked before the upsert overwrites the stored hash.
+		appendOnly := !pw.partsChanged && e.appendedOnly(pw)
 		if err := e.db.UpsertSession(s); err != nil {
-			log.Printf("upsert session %s: %v", s.ID, err)
+			e.log.Error("upsert session", "session", s.ID, "err", err)
 			continue
 		}
-		e.writeMessages(pw.sess.ID, msgs)
+		stored := true
+		if appendOnly {
+			stored = e.writeMessages(pw.sess.ID, msgs)
+		} else if err := e.db.ReplaceSessionMessages(
+			pw.sess.ID, msgs,
+		); err != nil {
+			e.log.Error(
+				"replace messages",
+				"session", pw.sess.ID, "err", err,
+			)
+			stored = false
+		} else {
+			messagesIndexed.Add(float64(len(msgs)))
+		}
+		if pw.partsChanged {
+			e.writeFileParts(pw)
+		}
+		e.writeHookEvents(pw)
+		e.writeCommands(pw)
+		e.writeTodos(pw)
+		e.writeEnv(pw)
+		e.writeUnknownRecords(pw)
+		e.writeUserOrigins(pw)
+		e.writeQuality(pw.sess.ID)
+		e.writeLanguage(pw.sess.ID)
+		if stored {
+			e.keepTail(pw, s)
+		}
+		e.sessionWritten(s)
 	}
 }
 
-// writeMessages uses an incremental append when possible.
-// Session files are append-only, so if the DB already has
-// messages for this session and the new set is larger, we
-// only insert the new messages (avoiding expensive FTS5
-// delete+reinsert of existing content).
-func (e *Engine) writeMessages(
-	sessionID string, msgs []db.Message,
-) {
-	maxOrd := e.db.MaxOrdinal(sessionID)
+// sessionWritten reports a stored session to the
+// OnSessionWritten callback.
+func (e *Engine) sessionWritten(s db.Session) {
+	if e.onSessionWritten != nil {
+		e.onSessionWritten(s)
+	}
+}
 
-	// No existing messages — insert all.
-	if maxOrd < 0 {
-		if err := e.db.InsertMessages(msgs); err != nil {
-			log.Printf(
-				"insert messages for %s: %v",
-				sessionID, err,
-			)
+// mergeSplitCodex folds the other rollout files of a Codex
+// session into pw. Codex can continue one session in a new
+// rollout file after a restart; both carry the same session_meta
+// id, so without merging each file would overwrite the other.
+// Other parts are found through the stored session's file_path
+// and recorded file parts, so a split is picked up as soon as
+// the second file is written.
+func (e *Engine) mergeSplitCodex(pw pendingWrite) pendingWrite {
+	if pw.sess.Agent != parser.AgentCodex {
+		return pw
+	}
+	id := pw.sess.ID
+	stored, err := e.db.GetSessionFull(context.Background(), id)
+	if err != nil || stored == nil {
+		return pw
+	}
+	oldParts, err := e.db.GetSessionFileParts(id)
+	if err != nil {
+		e.log.Warn("loading file parts", "session", id, "err", err)
+		return pw
+	}
+
+	seen := map[string]bool{pw.sess.File.Path: true}
+	var others []string
+	// A session first stored from history.jsonl is superseded
+	// by its rollout, not merged with it.
+	if stored.FilePath != nil && !seen[*stored.FilePath] &&
+		!parser.IsCodexHistoryPath(*stored.FilePath) {
+		seen[*stored.FilePath] = true
+		others = append(others, *stored.FilePath)
+	}
+	for _, p := range oldParts {
+		if !seen[p.Path] {
+			seen[p.Path] = true
+			others = append(others, p.Path)
 		}
+	}
+	if len(others) == 0 {
+		return pw
+	}
+
+	results := []parser.ParseResult{
+		{Session: pw.sess, Messages: pw.msgs},
+	}
+	for _, path := range others {
+		// Parts that vanished or now belong to another session
+		// are dropped from the merge.
+		sess, msgs, err := parser.ParseCodexSession(
+			path, e.machine, true,
+		)
+		if err != nil || sess == nil || sess.ID != id {
+			continue
+		}
+		if h, herr := ComputeFileHash(path); herr == nil {
+			sess.File.Hash = h
+		}
+		results = append(results, parser.ParseResult{
+			Session: *sess, Messages: msgs,
+		})
+	}
+
+	merged := parser.MergeCodexRollouts(results)
+	var parts []db.SessionFilePart
+	for _, r := range results {
+		f := r.Session.File
+		if f.Path == merged.Session.File.Path {
+			continue
+		}
+		parts = append(parts, db.SessionFilePart{
+			Path: f.Path, Size: f.Size, Mtime: f.Mtime,
+		})
+	}
+	slices.SortFunc(parts, func(a, b db.SessionFilePart) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return pendingWrite{
+		sess:         merged.Session,
+		msgs:         merged.Messages,
+		fileParts:    parts,
+		partsChanged: !slices.Equal(parts, oldParts),
+	}
+}
+
+// writeFileParts records the extra source files merged into a
+// session.
+func (e *Engine) writeFileParts(pw pendingWrite) {
+	if err := e.db.ReplaceSessionFileParts(
+		pw.sess.ID, pw.fileParts,
+	); err != nil {
+		e.log.Error(
+			"replace file parts",
+			"session", pw.sess.ID, "err", err,
+		)
+	}
+}
+
+// writeHookEvents stores a session's hook events. Sessions
+// without hooks are skipped to avoid a delete per write; files
+// are append-only, so recorded hooks do not disappear.
+func (e *Engine) writeHookEvents(pw pendingWrite) {
+	if len(pw.sess.HookEvents) == 0 {
+		return
+	}
+	if err := e.db.ReplaceHookEvents(
+		pw.sess.ID, toDBHookEvents(pw),
+	); err != nil {
+		e.log.Error(
+			"replace hook events",
+			"session", pw.sess.ID, "err", err,
+		)
+	}
+}
+
+// writeCommands stores a session's slash command invocations.
+// Sessions without any are skipped, like hook events.
+func (e *Engine) writeCommands(pw pendingWrite) {
+	if len(pw.sess.Commands) == 0 {
+		return
+	}
+	if err := e.db.ReplaceSessionCommands(
+		pw.sess.ID, toDBCommands(pw),
+	); err != nil {
+		e.log.Error(
+			"replace commands",
+			"session", pw.sess.ID, "err", err,
+		)
+	}
+}
+
+// writeUnknownRecords stores counts of record types the parser
+// did not recognize. Sessions without any are skipped, like
+// hook events.
+func (e *Engine) writeUnknownRecords(pw pendingWrite) {
+	if len(pw.sess.UnknownRecords) == 0 {
+		return
+	}
+	if err := e.db.ReplaceUnknownRecords(
+		pw.sess.ID, toDBUnknownRecords(pw),
+	); err != nil {
+		e.log.Error(
+			"replace unknown records",
+			"session", pw.sess.ID, "err", err,
+		)
+	}
+}
+
+// writeUserOrigins stores the session's user message counts
+// by origin.
+func (e *Engine) writeUserOrigins(pw pendingWrite) {
+	counts := parser.CountUserOrigins(pw.sess, pw.msgs)
+	if pw.appended {
+		// Messages stored before the appended ones.
+		for origin, n := range pw.tail.origins {
+			counts[origin] += n
+		}
+	}
+	if err := e.db.ReplaceUserOrigins(pw.sess.ID, counts); err != nil {
+		e.log.Error(
+			"replace user origins",
+			"session", pw.sess.ID, "err", err,
+		)
+	}
+}
+
+// writeTodos stores a session's latest todo list. Sessions
+// that never wrote one are skipped, like hook events.
+func (e *Engine) writeTodos(pw pendingWrite) {
+	if pw.sess.Todos == nil {
 		return
 	}
+	if err := e.db.ReplaceSessionTodos(
+		pw.sess.ID, toDBTodos(pw),
+		timeutil.Format(pw.sess.TodosAt),
+	); err != nil {
+		e.log.Error(
+			"replace todos",
+			"session", pw.sess.ID, "err", err,
+		)
+	}
+}
+
+// writeEnv stores a session's environment snapshot. Sessions
+// that recorded none are skipped, like hook events.
+func (e *Engine) writeEnv(pw pendingWrite) {
+	if len(pw.sess.Environment) == 0 {
+		return
+	}
+	if err := e.db.ReplaceSessionEnv(
+		pw.sess.ID, pw.sess.Environment,
+	); err != nil {
+		e.log.Error(
+			"replace env",
+			"session", pw.sess.ID, "err", err,
+		)
+	}
+}
+
+// writeQuality recomputes a session's quality score once its
+// messages and tool calls are stored.
+func (e *Engine) writeQuality(sessionID string) {
+	if err := e.db.UpdateSessionQuality(sessionID); err != nil {
+		e.log.Error(
+			"update quality score",
+			"session", sessionID, "err", err,
+		)
+	}
+}
+
+// writeLanguage recomputes a session's dominant prompt
+// language once its messages are stored.
+func (e *Engine) writeLanguage(sessionID string) {
+	if err := e.db.UpdateSessionLanguage(sessionID); err != nil {
+		e.log.Error(
+			"update session language",
+			"session", sessionID, "err", err,
+		)
+	}
+}
 
-	// Find new messages (ordinal > maxOrd).
-	delta := 0
+// writeMessages stores messages appended to a session whose
+// source file only grew (see appendedOnly): messages past the
+// stored max ordinal are inserted, and stored tool calls pick
+// up results that arrived in the appended lines, as do stored
+// messages the usage credited to them by then, such as Codex
+// token counts reported after the reply. Earlier messages are
+// otherwise left in place instead of being replaced. Reports
+// whether the messages were stored.
+func (e *Engine) writeMessages(
+	sessionID string, msgs []db.Message,
+) bool {
+	maxOrd := e.db.MaxOrdinal(sessionID)
+	split := len(msgs)
 	for i, m := range msgs {
 		if m.Ordinal > maxOrd {
-			delta = len(msgs) - i
-			msgs = msgs[i:]
+			split = i
 			break
 		}
 	}
 
-	if delta == 0 {
-		return
+	var paired []db.ToolCall
+	var usage []db.Message
+	for _, m := range msgs[:split] {
+		for _, tc := range m.ToolCalls {
+			if tc.ResultContentLength > 0 || tc.ResultError {
+				paired = append(paired, tc)
+			}
+		}
+		if m.HasTokenUsage() {
+			usage = append(usage, m)
+		}
+	}
+	if split == len(msgs) && len(paired) == 0 && len(usage) == 0 {
+		return true
 	}
 
-	if err := e.db.InsertMessages(msgs); err != nil {
-		log.Printf(
-			"append messages for %s: %v",
-			sessionID, err,
+	if err := e.db.AppendSessionMessages(
+		sessionID, msgs[split:], paired, usage,
+	); err != nil {
+		e.log.Error(
+			"append messages",
+			"session", sessionID, "err", err,
 		)
+		return false
 	}
+	messagesIndexed.Add(float64(len(msgs) - split))
+	return true
+}
+
+// appendedOnly reports whether pw's source file was only
+// appended to since the session was last stored: the stored
+// file hash must match a hash of the file's first stored-size
+// bytes. Sessions merged from several files, or without a
+// stored hash, are never treated as append-only.
+func (e *Engine) appendedOnly(pw pendingWrite) bool {
+	file := pw.sess.File
+	if len(pw.fileParts) > 0 || file.Path == "" ||
+		file.Hash == "" {
+		return false
+	}
+	path, size, hash, ok := e.db.GetSessionFileHash(pw.sess.ID)
+	if !ok || hash == "" || path != file.Path ||
+		size > file.Size {
+		return false
+	}
+	if size == file.Size {
+		return hash == file.Hash
+	}
+	prefix, err := ComputePrefixHash(path, size)
+	return err == nil && prefix == hash
 }
 
 // writeSessionFull upserts a session and does a full
@@ -1505,22 +2284,112 @@ func (e *Engine) writeMessages(
 // single-session re-syncs where existing content may have
 // changed (not just appended).
 func (e *Engine) writeSessionFull(pw pendingWrite) {
+	pw = e.mergeSplitCodex(pw)
 	msgs := toDBMessages(pw, e.blockedResultCategories)
 	s := toDBSession(pw)
 	s.MessageCount, s.UserMessageCount =
 		postFilterCounts(msgs)
 	if err := e.db.UpsertSession(s); err != nil {
-		log.Printf("upsert session %s: %v", s.ID, err)
+		e.log.Error("upsert session", "session", s.ID, "err", err)
 		return
 	}
+	stored := true
 	if err := e.db.ReplaceSessionMessages(
 		pw.sess.ID, msgs,
 	); err != nil {
-		log.Printf(
-			"replace messages for %s: %v",
-			pw.sess.ID, err,
+		e.log.Error(
+			"replace messages",
+			"session", pw.sess.ID, "err", err,
 		)
+		stored = false
+	} else {
+		messagesIndexed.Add(float64(len(msgs)))
+	}
+	if pw.partsChanged {
+		e.writeFileParts(pw)
+	}
+	e.writeHookEvents(pw)
+	e.writeCommands(pw)
+	e.writeTodos(pw)
+	e.writeEnv(pw)
+	e.writeUnknownRecords(pw)
+	e.writeUserOrigins(pw)
+	e.writeQuality(pw.sess.ID)
+	e.writeLanguage(pw.sess.ID)
+	if stored {
+		e.keepTail(pw, s)
+	}
+	e.sessionWritten(s)
+}
+
+// WriteSession stores a parsed session and its messages
+// outside of a sync pass, applying the same conversion and
+// tool-result filtering as sync. Used by importers.
+func WriteSession(
+	database *db.DB,
+	sess parser.ParsedSession,
+	msgs []parser.ParsedMessage,
+	blockedCategories []string,
+) error {
+	pw := pendingWrite{sess: sess, msgs: msgs}
+	dbMsgs := toDBMessages(pw, blockedCategorySet(blockedCategories))
+	s := toDBSession(pw)
+	s.MessageCount, s.UserMessageCount = postFilterCounts(dbMsgs)
+	if err := database.UpsertSession(s); err != nil {
+		return fmt.Errorf("storing session: %w", err)
+	}
+	if err := database.ReplaceSessionMessages(
+		sess.ID, dbMsgs,
+	); err != nil {
+		return fmt.Errorf("storing messages: %w", err)
+	}
+	if len(sess.HookEvents) > 0 {
+		if err := database.ReplaceHookEvents(
+			sess.ID, toDBHookEvents(pw),
+		); err != nil {
+			return fmt.Errorf("storing hook events: %w", err)
+		}
+	}
+	if len(sess.Commands) > 0 {
+		if err := database.ReplaceSessionCommands(
+			sess.ID, toDBCommands(pw),
+		); err != nil {
+			return fmt.Errorf("storing commands: %w", err)
+		}
+	}
+	if sess.Todos != nil {
+		if err := database.ReplaceSessionTodos(
+			sess.ID, toDBTodos(pw), timeutil.Format(sess.TodosAt),
+		); err != nil {
+			return fmt.Errorf("storing todos: %w", err)
+		}
+	}
+	if len(sess.Environment) > 0 {
+		if err := database.ReplaceSessionEnv(
+			sess.ID, sess.Environment,
+		); err != nil {
+			return fmt.Errorf("storing env: %w", err)
+		}
+	}
+	if len(sess.UnknownRecords) > 0 {
+		if err := database.ReplaceUnknownRecords(
+			sess.ID, toDBUnknownRecords(pw),
+		); err != nil {
+			return fmt.Errorf("storing unknown records: %w", err)
+		}
 	}
+	if err := database.ReplaceUserOrigins(
+		sess.ID, parser.CountUserOrigins(sess, msgs),
+	); err != nil {
+		return fmt.Errorf("storing user origins: %w", err)
+	}
+	if err := database.UpdateSessionQuality(sess.ID); err != nil {
+		return fmt.Errorf("storing quality score: %w", err)
+	}
+	if err := database.UpdateSessionLanguage(sess.ID); err != nil {
+		return fmt.Errorf("storing session language: %w", err)
+	}
+	return nil
 }
 
 // toDBSession converts a pendingWrite to a db.Session.
@@ -1532,6 +2401,12 @@ func toDBSession(pw pendingWrite) db.Session {
 		Agent:            string(pw.sess.Agent),
 		MessageCount:     pw.sess.MessageCount,
 		UserMessageCount: pw.sess.UserMessageCount,
+		InterruptCount:   pw.sess.InterruptCount,
+		Headless:         pw.sess.Automated,
+		GitBranch:        pw.sess.GitBranch,
+		Worktree:         pw.sess.Worktree,
+		EntryPoint:       pw.sess.EntryPoint,
+		Client:           pw.sess.Client,
 		ParentSessionID:  strPtr(pw.sess.ParentSessionID),
 		RelationshipType: string(pw.sess.RelationshipType),
 		FilePath:         strPtr(pw.sess.File.Path),
@@ -1565,15 +2440,103 @@ func toDBMessages(pw pendingWrite, blocked map[string]bool) []db.Message {
 			HasThinking:   m.HasThinking,
 			HasToolUse:    m.HasToolUse,
 			ContentLength: m.ContentLength,
+			Model:         m.Model,
+			ModelSwitch:   m.ModelSwitch,
+			Kind:          m.Kind,
+			SourceUUID:    m.SourceUUID,
+			PastedChars:   pastedChars(m),
+			Language:      promptLanguage(m),
 			ToolCalls: convertToolCalls(
 				pw.sess.ID, m.ToolCalls,
 			),
 			ToolResults: convertToolResults(m.ToolResults),
+
+			InputTokens:         m.Usage.InputTokens,
+			OutputTokens:        m.Usage.OutputTokens,
+			CacheReadTokens:     m.Usage.CacheReadTokens,
+			CacheCreationTokens: m.Usage.CacheCreationTokens,
+			ReasoningTokens:     m.Usage.ReasoningTokens,
 		}
 	}
 	return pairAndFilter(msgs, blocked)
 }
 
+// pastedChars estimates the pasted text in a typed user
+// message, counting attachments and pasted-looking blocks.
+func pastedChars(m parser.ParsedMessage) int {
+	if m.Role != parser.RoleUser || m.Kind != "" {
+		return 0
+	}
+	return m.AttachedChars + parser.PastedChars(m.Content)
+}
+
+// promptLanguage detects the language of a typed user
+// message.
+func promptLanguage(m parser.ParsedMessage) string {
+	if m.Role != parser.RoleUser || m.Kind != "" {
+		return ""
+	}
+	return parser.DetectLanguage(m.Content)
+}
+
+// toDBHookEvents converts parsed hook events to db rows.
+func toDBHookEvents(pw pendingWrite) []db.HookEvent {
+	events := make([]db.HookEvent, len(pw.sess.HookEvents))
+	for i, h := range pw.sess.HookEvents {
+		events[i] = db.HookEvent{
+			SessionID: pw.sess.ID,
+			HookEvent: h.Event,
+			HookName:  h.Name,
+			ToolName:  h.ToolName,
+			Command:   h.Command,
+			Decision:  h.Decision,
+			Message:   h.Message,
+			Timestamp: timeutil.Format(h.Timestamp),
+		}
+	}
+	return events
+}
+
+// toDBCommands converts parsed slash commands to db rows.
+func toDBCommands(pw pendingWrite) []db.Command {
+	cmds := make([]db.Command, len(pw.sess.Commands))
+	for i, c := range pw.sess.Commands {
+		cmds[i] = db.Command{
+			Name:      c.Name,
+			Timestamp: timeutil.Format(c.Timestamp),
+		}
+	}
+	return cmds
+}
+
+// toDBUnknownRecords converts parsed unknown record counts to
+// db rows.
+func toDBUnknownRecords(pw pendingWrite) []db.UnknownRecord {
+	records := make([]db.UnknownRecord, len(pw.sess.UnknownRecords))
+	for i, r := range pw.sess.UnknownRecords {
+		records[i] = db.UnknownRecord{
+			Type:   r.Type,
+			Count:  r.Count,
+			Sample: r.Sample,
+		}
+	}
+	return records
+}
+
+// toDBTodos converts a parsed todo list to db rows.
+func toDBTodos(pw pendingWrite) []db.Todo {
+	todos := make([]db.Todo, len(pw.sess.Todos))
+	for i, t := range pw.sess.Todos {
+		todos[i] = db.Todo{
+			Position:   i,
+			Content:    t.Content,
+			Status:     t.Status,
+			ActiveForm: t.ActiveForm,
+		}
+	}
+	return todos
+}
+
 // postFilterCounts returns the total and user message counts
 // from a filtered message slice.
 func postFilterCounts(msgs []db.Message) (total, user int) {
@@ -1596,14 +2559,63 @@ func countMessages(batch []pendingWrite) int {
 // FindSourceFile locates the original source file for a
 // session ID.
 func (e *Engine) FindSourceFile(sessionID string) string {
+	path, _ := e.LocateSourceFile(sessionID)
+	return path
+}
+
+// LocateSourceFile is FindSourceFile that also reports which
+// configured agent directory the file was found under.
+func (e *Engine) LocateSourceFile(sessionID string) (path, root string) {
 	def, ok := parser.AgentByPrefix(sessionID)
 	if !ok || !def.FileBased || def.FindSourceFunc == nil {
-		return ""
+		return "", ""
 	}
 	rawID := strings.TrimPrefix(sessionID, def.IDPrefix)
 	for _, d := range e.agentDirs[def.Type] {
 		if f := def.FindSourceFunc(d, rawID); f != "" {
-			return f
+			return f, d
+		}
+	}
+	return "", ""
+}
+
+// BridgeSourcePath returns a stored source path as this
+// process can open it. A path recorded on the other side of a
+// Windows/WSL boundary is looked up through the configured
+// directories of agent (see parser.BridgePath); path is
+// returned unchanged when it exists or no bridged path does.
+func (e *Engine) BridgeSourcePath(
+	agent parser.AgentType, path string,
+) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	for _, d := range e.agentDirs[agent] {
+		p := parser.BridgePath(d, path)
+		if p == path {
+			continue
+		}
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return path
+}
+
+// SourceRoot returns the configured directory for agent that
+// contains path, or "" if path is outside all of them (e.g.
+// uploaded or imported sessions).
+func (e *Engine) SourceRoot(
+	agent parser.AgentType, path string,
+) string {
+	for _, d := range e.agentDirs[agent] {
+		if d == "" {
+			continue
+		}
+		rel, err := filepath.Rel(d, path)
+		if err == nil && rel != ".." &&
+			!strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return d
 		}
 	}
 	return ""
@@ -1637,6 +2649,8 @@ func (e *Engine) SyncSingleSession(sessionID string) error {
 	// the file, even if it was cached as non-interactive
 	// during a bulk SyncAll.
 	e.clearSkip(path)
+	// Likewise parse the whole file, not just appended lines.
+	e.dropTail(path)
 
 	// Reuse processFile for stat and DB-skip logic. For
 	// Claude this is the full pipeline; for Codex we need
@@ -1696,10 +2710,8 @@ func (e *Engine) SyncSingleSession(sessionID string) error {
 		return nil
 	}
 
-	for _, pr := range res.results {
-		e.writeSessionFull(
-			pendingWrite{sess: pr.Session, msgs: pr.Messages},
-		)
+	for _, pw := range res.writes() {
+		e.writeSessionFull(pw)
 	}
 	return nil
 }
@@ -1815,9 +2827,12 @@ func convertToolResults(
 	results := make([]db.ToolResult, len(parsed))
 	for i, tr := range parsed {
 		results[i] = db.ToolResult{
-			ToolUseID:     tr.ToolUseID,
-			ContentLength: tr.ContentLength,
-			ContentRaw:    tr.ContentRaw,
+			ToolUseID:      tr.ToolUseID,
+			ContentLength:  tr.ContentLength,
+			ContentRaw:     tr.ContentRaw,
+			IsError:        tr.IsError,
+			Truncated:      tr.Truncated,
+			OriginalLength: tr.OriginalLength,
 		}
 	}
 	return results
@@ -1860,6 +2875,9 @@ func pairToolResults(msgs []db.Message, blocked map[string]bool) {
 		for _, tr := range m.ToolResults {
 			if tc, ok := idx[tr.ToolUseID]; ok {
 				tc.ResultContentLength = tr.ContentLength
+				tc.ResultError = tr.IsError
+				tc.ResultTruncated = tr.Truncated
+				tc.ResultOriginalLength = tr.OriginalLength
 				if !blocked[tc.Category] {
 					tc.ResultContent = parser.DecodeContent(tr.ContentRaw)
 				}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	gosync "sync"
 	"testing"
@@ -31,9 +32,10 @@ type testEnv struct {
 }
 
 type testEnvOpts struct {
-	claudeDirs []string
-	codexDirs  []string
-	cursorDirs []string
+	claudeDirs       []string
+	codexDirs        []string
+	cursorDirs       []string
+	onSessionWritten func(db.Session)
 }
 
 type TestEnvOption func(*testEnvOpts)
@@ -56,6 +58,12 @@ func WithCursorDirs(dirs []string) TestEnvOption {
 	}
 }
 
+func WithOnSessionWritten(fn func(db.Session)) TestEnvOption {
+	return func(o *testEnvOpts) {
+		o.onSessionWritten = fn
+	}
+}
+
 func setupTestEnv(t *testing.T, opts ...TestEnvOption) *testEnv {
 	t.Helper()
 	if testing.Short() {
@@ -107,7 +115,8 @@ func setupTestEnv(t *testing.T, opts ...TestEnvOption) *testEnv {
 			parser.AgentOpenCode: {env.opencodeDir},
 			parser.AgentAmp:      {env.ampDir},
 		},
-		Machine: "local",
+		Machine:          "local",
+		OnSessionWritten: options.onSessionWritten,
 	})
 	return env
 }
@@ -227,6 +236,60 @@ func TestSyncEngineIntegration(t *testing.T) {
 	}
 }
 
+func TestSyncEngineOnSessionWritten(t *testing.T) {
+	var written []db.Session
+	env := setupTestEnv(t, WithOnSessionWritten(func(s db.Session) {
+		written = append(written, s)
+	}))
+
+	content := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsEarly, "Hello", "/Users/alice/code/my-app").
+		AddClaudeAssistant(tsEarlyS5, "Hi there!").
+		String()
+	env.writeClaudeSessionForProject(
+		t, "/Users/alice/code/my-app", "hooked.jsonl", content,
+	)
+
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+	if len(written) != 1 {
+		t.Fatalf("callback got %d sessions, want 1", len(written))
+	}
+	if s := written[0]; s.ID != "hooked" || s.MessageCount != 2 {
+		t.Errorf("callback session = %+v", s)
+	}
+
+	// Unchanged files are skipped and not reported again.
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Skipped: 1})
+	if len(written) != 1 {
+		t.Errorf("callback got %d sessions after skip, want 1", len(written))
+	}
+}
+
+func TestSyncEngineSyncAllContextCancelled(t *testing.T) {
+	env := setupTestEnv(t)
+	content := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsEarly, "Hello", "/Users/alice/code/my-app").
+		AddClaudeAssistant(tsEarlyS5, "Hi there!").
+		String()
+	env.writeClaudeSessionForProject(
+		t, "/Users/alice/code/my-app", "later.jsonl", content,
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	stats, err := env.engine.SyncAllContext(ctx, nil)
+	if err != context.Canceled {
+		t.Fatalf("SyncAllContext err = %v, want context.Canceled", err)
+	}
+	if stats.Synced != 0 || stats.Skipped != 0 || stats.Failed != 0 {
+		t.Errorf("cancelled stats = %+v, want nothing processed", stats)
+	}
+
+	// The file was left alone, not cached or quarantined.
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+	assertSessionMessageCount(t, env.db, "later", 2)
+}
+
 func TestSyncEngineWorktreesShareProject(t *testing.T) {
 	env := setupTestEnv(t)
 
@@ -335,6 +398,72 @@ func TestSyncEngineCodex(t *testing.T) {
 	})
 }
 
+func TestSyncEngineCodexSplitRollouts(t *testing.T) {
+	env := setupTestEnv(t)
+
+	uuid := "split-uuid"
+	first := testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsZero, uuid, "/home/user/code/api", "user").
+		AddCodexMessage(tsZero, "user", "Start the refactor").
+		AddCodexMessage(tsZeroS5, "assistant", "Starting.").
+		String()
+	second := testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsEarly, uuid, "/home/user/code/api", "user").
+		AddCodexMessage(tsEarlyS1, "user", "Continue after restart").
+		AddCodexMessage(tsEarlyS5, "assistant", "Continuing.").
+		String()
+
+	env.writeCodexSession(
+		t, filepath.Join("2024", "01", "01"),
+		"rollout-a-"+uuid+".jsonl", first,
+	)
+	secondPath := env.writeCodexSession(
+		t, filepath.Join("2024", "01", "02"),
+		"rollout-b-"+uuid+".jsonl", second,
+	)
+
+	env.engine.SyncAll(nil)
+
+	sid := "codex:" + uuid
+	assertMessageContent(t, env.db, sid,
+		"Start the refactor", "Starting.",
+		"Continue after restart", "Continuing.",
+	)
+	full, err := env.db.GetSessionFull(context.Background(), sid)
+	if err != nil || full == nil {
+		t.Fatalf("GetSessionFull: %v", err)
+	}
+	if full.FilePath == nil || *full.FilePath != secondPath {
+		t.Errorf("file_path = %v, want %s",
+			full.FilePath, secondPath)
+	}
+	if full.FirstMessage == nil ||
+		*full.FirstMessage != "Start the refactor" {
+		t.Errorf("first_message = %v", full.FirstMessage)
+	}
+
+	// Both parts are unchanged, so neither is re-parsed.
+	runSyncAndAssert(t, env.engine, sync.SyncStats{
+		TotalSessions: 2, Synced: 0, Skipped: 2,
+	})
+
+	// Appending to the live part keeps the earlier part merged.
+	appended := second + testjsonl.NewSessionBuilder().
+		AddCodexMessage(tsEarlyS5, "user", "One more thing").
+		String()
+	env.writeCodexSession(
+		t, filepath.Join("2024", "01", "02"),
+		"rollout-b-"+uuid+".jsonl", appended,
+	)
+	env.engine.SyncPaths([]string{secondPath})
+
+	assertMessageContent(t, env.db, sid,
+		"Start the refactor", "Starting.",
+		"Continue after restart", "Continuing.",
+		"One more thing",
+	)
+}
+
 func TestSyncEngineProgress(t *testing.T) {
 	env := setupTestEnv(t)
 
@@ -449,6 +578,300 @@ func TestSyncEngineFileAppend(t *testing.T) {
 	assertSessionMessageCount(t, env.db, "append-test", 2)
 }
 
+// TestSyncEngineAppendFastPath verifies that appended lines are
+// inserted without rewriting stored messages, that results
+// arriving later are attached to stored tool calls, and that a
+// file whose earlier content changed is fully replaced.
+func TestSyncEngineAppendFastPath(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+
+	initial := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsEarly, "Read main.go").
+		AddRaw(testjsonl.ClaudeAssistantJSON(
+			[]map[string]any{{
+				"type":  "tool_use",
+				"id":    "toolu_1",
+				"name":  "Read",
+				"input": map[string]string{"file_path": "main.go"},
+			}},
+			tsEarlyS1,
+		)).
+		String()
+	path := env.writeClaudeSession(
+		t, "test-proj", "fast-append.jsonl", initial,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	before, err := env.db.GetAllMessages(ctx, "fast-append")
+	if err != nil {
+		t.Fatalf("GetAllMessages: %v", err)
+	}
+
+	appended := initial + testjsonl.NewSessionBuilder().
+		AddRaw(testjsonl.ClaudeToolResultUserJSON(
+			"toolu_1", "package main", tsEarlyS5,
+		)).
+		AddClaudeAssistant(tsEarlyS5, "Here it is.").
+		String()
+	if err := os.WriteFile(path, []byte(appended), 0o644); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	after, err := env.db.GetAllMessages(ctx, "fast-append")
+	if err != nil {
+		t.Fatalf("GetAllMessages: %v", err)
+	}
+	if len(after) != 3 {
+		t.Fatalf("got %d messages, want 3", len(after))
+	}
+	for i := range before {
+		if after[i].ID != before[i].ID {
+			t.Errorf(
+				"message %d rewritten: id %d -> %d",
+				i, before[i].ID, after[i].ID,
+			)
+		}
+	}
+	if got := after[1].ToolCalls; len(got) != 1 ||
+		got[0].ResultContent != "package main" {
+		t.Errorf("tool call result not filled: %+v", got)
+	}
+	assertSessionMessageCount(t, env.db, "fast-append", 3)
+
+	// Rewriting earlier content defeats the prefix check and
+	// falls back to a full replace.
+	rewritten := strings.Replace(
+		appended, "Read main.go", "Read util.go", 1,
+	)
+	if err := os.WriteFile(path, []byte(rewritten+"\n"), 0o644); err != nil {
+		t.Fatalf("rewrite: %v", err)
+	}
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+	msgs := fetchMessages(t, env.db, "fast-append")
+	if len(msgs) != 3 || msgs[0].Content != "Read util.go" {
+		t.Errorf("after rewrite: %d msgs, first %q", len(msgs), msgs[0].Content)
+	}
+}
+
+// TestSyncEngineAppendCodexTokenCount verifies that a Codex
+// token_count event appended after its reply was stored credits
+// the stored reply, as a full parse of the file would.
+func TestSyncEngineAppendCodexTokenCount(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+
+	initial := testjsonl.NewSessionBuilder().
+		AddCodexMeta(tsEarly, "tok-uuid", "/home/user/code/api", "user").
+		AddCodexMessage(tsEarlyS1, "user", "Run the tests").
+		AddCodexMessage(tsEarlyS5, "assistant", "All pass.").
+		String()
+	path := env.writeCodexSession(
+		t, filepath.Join("2024", "01", "15"),
+		"rollout-20240115-tok-uuid.jsonl", initial,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+	before := fetchMessages(t, env.db, "codex:tok-uuid")
+
+	appended := initial + `{"type":"event_msg","timestamp":"` +
+		tsEarlyS5 + `","payload":{"type":"token_count","info":{` +
+		`"total_token_usage":{"total_tokens":110},` +
+		`"last_token_usage":{"input_tokens":100,` +
+		`"cached_input_tokens":0,"output_tokens":10}}}}` + "\n"
+	if err := os.WriteFile(path, []byte(appended), 0o644); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	after := fetchMessages(t, env.db, "codex:tok-uuid")
+	if len(after) != 2 || after[1].ID != before[1].ID {
+		t.Fatalf("reply rewritten instead of updated: %+v", after)
+	}
+	if after[1].InputTokens != 100 || after[1].OutputTokens != 10 {
+		t.Errorf("reply tokens = %d/%d, want 100/10",
+			after[1].InputTokens, after[1].OutputTokens)
+	}
+	usage, err := env.db.GetAnalyticsUsage(ctx, db.AnalyticsFilter{
+		From: "2024-01-01", To: "2024-12-31", Timezone: "UTC",
+	}, "")
+	if err != nil {
+		t.Fatalf("GetAnalyticsUsage: %v", err)
+	}
+	if got := usage.Total.InputTokens + usage.Total.OutputTokens; got != 110 {
+		t.Errorf("usage tokens = %d, want 110", got)
+	}
+}
+
+// TestSyncEngineAppendClaudeUsage verifies that a Claude
+// response continued in lines appended after a sync keeps its
+// usage once, on its latest message, as a full parse does.
+func TestSyncEngineAppendClaudeUsage(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+	asst := func(uuid, parent, id, block string, output int) string {
+		return `{"type":"assistant","uuid":"` + uuid +
+			`","parentUuid":"` + parent + `","timestamp":"` +
+			tsEarlyS1 + `","message":{"id":"` + id +
+			`","model":"claude-sonnet-4-5","content":[` + block +
+			`],"usage":{"input_tokens":10,"output_tokens":` +
+			fmt.Sprint(output) + `}}}` + "\n"
+	}
+	initial := testjsonl.ClaudeEntryJSON(
+		"user", "list files", tsEarly, "a", "",
+	) + "\n" + asst("b", "a", "msg_1",
+		`{"type":"text","text":"Looking."}`, 2)
+	path := env.writeClaudeSession(
+		t, "test-proj", "usage-append.jsonl", initial,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	appended := initial + asst("c", "b", "msg_1",
+		`{"type":"tool_use","id":"tu1","name":"Bash","input":{"command":"ls"}}`, 25) +
+		asst("d", "c", "msg_2", `{"type":"text","text":"Done."}`, 7)
+	if err := os.WriteFile(path, []byte(appended), 0o644); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	msgs := fetchMessages(t, env.db, "usage-append")
+	var outputs []int
+	for _, m := range msgs {
+		outputs = append(outputs, m.OutputTokens)
+	}
+	if want := []int{0, 0, 25, 7}; !slices.Equal(outputs, want) {
+		t.Errorf("output tokens = %v, want %v", outputs, want)
+	}
+	usage, err := env.db.GetAnalyticsUsage(ctx, db.AnalyticsFilter{
+		From: "2024-01-01", To: "2024-12-31", Timezone: "UTC",
+	}, "")
+	if err != nil {
+		t.Fatalf("GetAnalyticsUsage: %v", err)
+	}
+	if usage.Total.OutputTokens != 32 {
+		t.Errorf("usage output = %d, want 32", usage.Total.OutputTokens)
+	}
+}
+
+// TestSyncEngineAppendParsesNewLines verifies that a Claude
+// session file which only grew is parsed from where the last
+// sync stopped: earlier lines are not read again, and results
+// and subagent links for earlier tool calls still land.
+func TestSyncEngineSessionLanguage(t *testing.T) {
+	env := setupTestEnv(t)
+
+	content := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsEarly, "このテストが失敗する原因を調べてください").
+		AddClaudeAssistant(tsEarlyS1, "I'll look into it.").
+		AddClaudeUser(tsEarlyS1, "ありがとう、修正してください").
+		AddClaudeUser(tsEarlyS5, "ok").
+		String()
+	env.writeClaudeSession(t, "test-proj", "lang.jsonl", content)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	assertSessionState(t, env.db, "lang", func(sess *db.Session) {
+		if sess.Language != "ja" {
+			t.Errorf("language = %q, want ja", sess.Language)
+		}
+	})
+	msgs := fetchMessages(t, env.db, "lang")
+	for _, m := range msgs {
+		want := ""
+		if m.Role == "user" && m.Content != "ok" {
+			want = "ja"
+		}
+		if m.Language != want {
+			t.Errorf("message %d language = %q, want %q",
+				m.Ordinal, m.Language, want)
+		}
+	}
+}
+
+func TestSyncEngineAppendParsesNewLines(t *testing.T) {
+	env := setupTestEnv(t)
+
+	initial := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsEarly, strings.Repeat("context ", 600)).
+		AddRaw(testjsonl.ClaudeAssistantJSON(
+			[]map[string]any{{
+				"type":  "tool_use",
+				"id":    "toolu_1",
+				"name":  "Task",
+				"input": map[string]string{"prompt": "look around"},
+			}},
+			tsEarlyS1,
+		)).
+		AddClaudeAssistant(tsEarlyS1, "first reply").
+		AddClaudeUser(tsEarlyS1, strings.Repeat("keep going ", 40)).
+		String()
+	path := env.writeClaudeSession(
+		t, "test-proj", "tail-append.jsonl", initial,
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+	before := fetchMessages(t, env.db, "tail-append")
+
+	appendLines := func(lines ...string) {
+		t.Helper()
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		defer f.Close()
+		if _, err := f.WriteString(testjsonl.JoinJSONL(lines...)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	appendLines(
+		`{"type":"progress","parentToolUseID":"toolu_1","timestamp":"`+tsEarlyS5+`","data":{"type":"agent_progress","agentId":"x1"}}`,
+		testjsonl.ClaudeToolResultUserJSON("toolu_1", "found it", tsEarlyS5),
+		testjsonl.ClaudeAssistantJSON("done", tsEarlyS5),
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+
+	after := fetchMessages(t, env.db, "tail-append")
+	if len(after) != 5 {
+		t.Fatalf("got %d messages, want 5", len(after))
+	}
+	for i := range before {
+		if after[i].ID != before[i].ID {
+			t.Errorf("message %d rewritten: id %d -> %d",
+				i, before[i].ID, after[i].ID)
+		}
+	}
+	if got := after[1].ToolCalls; len(got) != 1 ||
+		got[0].ResultContent != "found it" ||
+		got[0].SubagentSessionID != "agent-x1" {
+		t.Errorf("tool call not completed: %+v", got)
+	}
+	if after[4].Content != "done" || after[4].Ordinal != 5 {
+		t.Errorf("appended message = %d %q, want 5 \"done\"",
+			after[4].Ordinal, after[4].Content)
+	}
+	assertSessionMessageCount(t, env.db, "tail-append", 5)
+	_, _, hash, _ := env.db.GetSessionFileHash("tail-append")
+	if want, _ := sync.ComputeFileHash(path); hash != want {
+		t.Errorf("file hash = %q, want %q", hash, want)
+	}
+
+	// An in-place edit to a line already parsed is not seen
+	// while the file keeps growing.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	edited := strings.Replace(string(data), "first reply", "FIRST REPLY", 1)
+	if err := os.WriteFile(path, []byte(edited), 0o644); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	appendLines(testjsonl.ClaudeUserJSON("thanks", tsEarlyS5))
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+	msgs := fetchMessages(t, env.db, "tail-append")
+	if len(msgs) != 6 || msgs[2].Content != "first reply" ||
+		msgs[5].Content != "thanks" {
+		t.Errorf("after edit: %d msgs, [2] %q", len(msgs), msgs[2].Content)
+	}
+}
+
 // TestSyncSingleSessionReplacesContent verifies that an
 // explicit SyncSingleSession replaces existing message
 // content (same ordinals, different text).
@@ -701,6 +1124,34 @@ func TestSyncPathsClaude(t *testing.T) {
 	assertSessionMessageCount(t, env.db, "paths-test", 2)
 }
 
+func TestSyncHonorsIgnoreFiles(t *testing.T) {
+	env := setupTestEnv(t)
+	content := testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsZero, "msg").
+		String()
+	env.writeClaudeSession(t, "kept", "kept.jsonl", content)
+	env.writeClaudeSession(t, "client-acme", "secret.jsonl", content)
+	env.writeClaudeSession(t, "kept", "scratch-1.jsonl", content)
+	env.writeSession(t, env.claudeDir, parser.IgnoreFileName,
+		"client-*\n")
+	env.writeSession(t, env.claudeDir,
+		filepath.Join("kept", parser.IgnoreFileName), "scratch-*\n")
+
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 1, Synced: 1})
+	assertSessionMessageCount(t, env.db, "kept", 1)
+
+	// Watcher-triggered syncs skip ignored paths too.
+	added := env.writeClaudeSession(
+		t, "client-acme", "later.jsonl", content,
+	)
+	env.engine.SyncPaths([]string{added})
+	for _, id := range []string{"secret", "scratch-1", "later"} {
+		if s, _ := env.db.GetSession(context.Background(), id); s != nil {
+			t.Errorf("ignored session %q was synced", id)
+		}
+	}
+}
+
 func TestSyncPathsOnlyProcessesChanged(t *testing.T) {
 	env := setupTestEnv(t)
 
@@ -2761,3 +3212,421 @@ func TestSyncPathsVSCodeCopilotJSONLPriority(t *testing.T) {
 		)
 	}
 }
+
+func TestSyncQuarantinesParseFailures(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+
+	sessionID := "gem-quarantine"
+	hash := "abcdef1234567890"
+	path := env.writeGeminiSession(
+		t,
+		filepath.Join("tmp", hash, "chats", "session-001.json"),
+		`{"sessionId": "truncated`,
+	)
+
+	assertQuarantine := func(wantAttempts int) {
+		t.Helper()
+		files, err := env.db.ListQuarantinedFiles(ctx)
+		if err != nil {
+			t.Fatalf("ListQuarantinedFiles: %v", err)
+		}
+		if wantAttempts == 0 {
+			if len(files) != 0 {
+				t.Fatalf("quarantine = %+v, want empty", files)
+			}
+			return
+		}
+		if len(files) != 1 {
+			t.Fatalf("quarantine = %+v, want 1 file", files)
+		}
+		f := files[0]
+		if f.Path != path || f.Agent != "gemini" || f.Error == "" {
+			t.Errorf("quarantined file = %+v", f)
+		}
+		if f.Attempts != wantAttempts {
+			t.Errorf("attempts = %d, want %d",
+				f.Attempts, wantAttempts)
+		}
+	}
+
+	stats := env.engine.SyncAll(nil)
+	if stats.Failed != 1 {
+		t.Fatalf("Failed = %d, want 1", stats.Failed)
+	}
+	assertQuarantine(1)
+
+	// Within the backoff the file is skipped even though it
+	// changed.
+	dbtest.WriteTestFile(t, path, []byte(`{"sessionId": "still bad`))
+	stats = env.engine.SyncAll(nil)
+	if stats.Failed != 0 {
+		t.Errorf("Failed = %d during backoff, want 0", stats.Failed)
+	}
+	assertQuarantine(1)
+
+	// Retry now ignores the backoff.
+	if n := env.engine.RetryQuarantined(nil); n != 1 {
+		t.Fatalf("retried %d files, want 1", n)
+	}
+	assertQuarantine(2)
+
+	// A successful retry releases the file.
+	content := testjsonl.GeminiSessionJSON(
+		sessionID, hash, tsEarly, tsEarlyS5,
+		[]map[string]any{
+			testjsonl.GeminiUserMsg("m1", tsEarly, "Hello Gemini"),
+			testjsonl.GeminiAssistantMsg(
+				"m2", tsEarlyS5, "Hi there!", nil,
+			),
+		},
+	)
+	dbtest.WriteTestFile(t, path, []byte(content))
+	if n := env.engine.RetryQuarantined([]string{path}); n != 1 {
+		t.Fatalf("retried %d files, want 1", n)
+	}
+	assertQuarantine(0)
+	assertSessionMessageCount(t, env.db, "gemini:"+sessionID, 2)
+
+	if n := env.engine.RetryQuarantined(nil); n != 0 {
+		t.Errorf("retried %d files with empty quarantine, want 0", n)
+	}
+}
+
+// TestResyncAllResumesInterrupted verifies that ResyncAll
+// picks up the temp database of an interrupted resync: files
+// it checkpointed are carried over, and sessions it wrote
+// without a checkpoint are parsed again.
+func TestResyncAllResumesInterrupted(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+
+	paths := map[string]string{}
+	for _, id := range []string{"done", "partial"} {
+		paths[id] = env.writeClaudeSession(t, "proj", id+".jsonl",
+			testjsonl.NewSessionBuilder().
+				AddClaudeUser(tsEarly, "hello "+id).
+				AddClaudeAssistant(tsEarlyS5, "hi").
+				String())
+	}
+	env.engine.SyncAll(nil)
+
+	// Build the state an interrupted resync leaves behind:
+	// both sessions written, only one checkpointed. Mark the
+	// copies so the test can tell which were re-parsed.
+	tempPath := env.db.Path() + "-resync"
+	tempDB, err := db.Open(tempPath)
+	if err != nil {
+		t.Fatalf("open temp db: %v", err)
+	}
+	interrupted := sync.NewEngine(tempDB, sync.EngineConfig{
+		AgentDirs: map[parser.AgentType][]string{
+			parser.AgentClaude: {env.claudeDir},
+		},
+		Machine: "local",
+	})
+	interrupted.SyncAll(nil)
+	for _, id := range []string{"done", "partial"} {
+		s, err := tempDB.GetSession(ctx, id)
+		if err != nil || s == nil {
+			t.Fatalf("temp session %s: %v", id, err)
+		}
+		s.Project = "from-temp"
+		path := paths[id]
+		s.FilePath = &path
+		if err := tempDB.UpsertSession(*s); err != nil {
+			t.Fatalf("UpsertSession: %v", err)
+		}
+	}
+	donePath := paths["done"]
+	info, err := os.Stat(donePath)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if err := tempDB.SaveResyncCheckpoint(db.ResyncCheckpoint{
+		Files: map[string]int64{donePath: info.ModTime().UnixNano()},
+	}); err != nil {
+		t.Fatalf("SaveResyncCheckpoint: %v", err)
+	}
+	tempDB.Close()
+	if !sync.ResyncInterrupted(env.db.Path()) {
+		t.Fatal("ResyncInterrupted = false, want true")
+	}
+
+	var first sync.Progress
+	stats := env.engine.ResyncAll(func(p sync.Progress) {
+		if first.Phase == "" {
+			first = p
+		}
+	})
+	if stats.Aborted {
+		t.Fatalf("resync aborted: %v", stats.Warnings)
+	}
+	if stats.Resumed != 1 || stats.Synced != 1 {
+		t.Errorf("resumed %d synced %d, want 1 and 1",
+			stats.Resumed, stats.Synced)
+	}
+	if first.ResumedFiles != 1 || first.SessionsDone != 1 ||
+		first.SessionsTotal != 2 {
+		t.Errorf("first progress = %+v, want 1 of 2 resumed", first)
+	}
+
+	assertSessionProject(t, env.db, "done", "from-temp")
+	assertSessionProject(t, env.db, "partial", "proj")
+	assertSessionMessageCount(t, env.db, "partial", 2)
+
+	cp, err := env.db.LoadResyncCheckpoint()
+	if err != nil {
+		t.Fatalf("LoadResyncCheckpoint: %v", err)
+	}
+	if !cp.Empty() {
+		t.Errorf("checkpoint left after swap: %+v", cp)
+	}
+	if sync.ResyncInterrupted(env.db.Path()) {
+		t.Error("temp db left after resync")
+	}
+}
+
+func TestShadowComparesWithStoredSessions(t *testing.T) {
+	env := setupTestEnv(t)
+	changed := env.writeClaudeSession(
+		t, "test-proj", "changed.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddClaudeUser(tsZero, "first").
+			AddClaudeAssistant(tsZeroS5, "reply").
+			String(),
+	)
+	env.writeClaudeSession(
+		t, "test-proj", "same.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddClaudeUser(tsZero, "unchanged").
+			String(),
+	)
+	runSyncAndAssert(t, env.engine, sync.SyncStats{TotalSessions: 2, Synced: 2})
+
+	// A stored session the current parser no longer produces.
+	if err := env.db.UpsertSession(db.Session{
+		ID: "ghost", Project: "test_proj", Machine: "local",
+		Agent: "claude", MessageCount: 4, FilePath: &changed,
+	}); err != nil {
+		t.Fatalf("UpsertSession: %v", err)
+	}
+	// Files changed since the last sync stand in for a
+	// parser that reads them differently.
+	os.WriteFile(changed, []byte(testjsonl.NewSessionBuilder().
+		AddClaudeUser(tsZero, "first").
+		AddClaudeAssistant(tsZeroS5, "reply").
+		AddClaudeUser(tsZeroS5, "again").
+		String()), 0o644)
+	env.writeClaudeSession(
+		t, "test-proj", "added.jsonl",
+		testjsonl.NewSessionBuilder().
+			AddClaudeUser(tsZero, "new").
+			String(),
+	)
+
+	report, err := env.engine.Shadow(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("Shadow: %v", err)
+	}
+	if report.FilesSampled != 3 || report.Sessions != 4 ||
+		report.Unchanged != 1 || len(report.Errors) != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	var got []string
+	for _, d := range report.Diffs {
+		got = append(got, d.Status+":"+d.SessionID)
+	}
+	want := []string{"changed:changed", "dropped:ghost", "new:added"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("diffs = %v, want %v", got, want)
+	}
+	if c := report.Diffs[0]; c.Stored.MessageCount != 2 ||
+		c.Shadow.MessageCount != 3 {
+		t.Errorf("changed counts = %d -> %d, want 2 -> 3",
+			c.Stored.MessageCount, c.Shadow.MessageCount)
+	}
+
+	// The stored data is untouched.
+	assertSessionMessageCount(t, env.db, "changed", 2)
+	if s, _ := env.db.GetSession(context.Background(), "added"); s != nil {
+		t.Error("shadow run stored a new session")
+	}
+
+	sampled, err := env.engine.Shadow(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Shadow sample: %v", err)
+	}
+	if sampled.FilesDiscovered != 3 || sampled.FilesSampled != 1 {
+		t.Errorf("sampled = %d of %d, want 1 of 3",
+			sampled.FilesSampled, sampled.FilesDiscovered)
+	}
+}
+
+func TestSyncAiderHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	root := t.TempDir()
+	database := dbtest.OpenTestDB(t)
+	engine := sync.NewEngine(database, sync.EngineConfig{
+		AgentDirs: map[parser.AgentType][]string{
+			parser.AgentAider: {root},
+		},
+		Machine: "local",
+	})
+
+	path := filepath.Join(
+		root, "webapp", parser.AiderHistoryFileName,
+	)
+	run := "\n# aider chat started at 2024-05-01 10:00:00\n\n" +
+		"#### add a route\n\nAdded it.\n\n" +
+		"> Tokens: 1.2k sent, 80 received.\n"
+	dbtest.WriteTestFile(t, path, []byte(run))
+	engine.SyncAll(nil)
+
+	ctx := context.Background()
+	listAider := func() []db.Session {
+		t.Helper()
+		page, err := database.ListSessions(
+			ctx, db.SessionFilter{Agent: "aider", Limit: 10},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return page.Sessions
+	}
+	sessions := listAider()
+	if len(sessions) != 1 {
+		t.Fatalf("got %d aider sessions, want 1", len(sessions))
+	}
+	sess := sessions[0]
+	if sess.Project != "webapp" || sess.MessageCount != 2 {
+		t.Errorf("session = %+v", sess)
+	}
+	if got := engine.FindSourceFile(sess.ID); got != path {
+		t.Errorf("FindSourceFile = %q, want %q", got, path)
+	}
+
+	// A later run appends a second session to the same log.
+	run2 := "\n# aider chat started at 2024-05-02 09:00:00\n\n" +
+		"#### add a test\n\nDone.\n"
+	dbtest.WriteTestFile(t, path, []byte(run+run2))
+	engine.SyncPaths([]string{path})
+	if got := len(listAider()); got != 2 {
+		t.Errorf("got %d aider sessions after append, want 2", got)
+	}
+}
+
+func TestSyncEngineCodexArchivedAndHistory(t *testing.T) {
+	home := t.TempDir()
+	sessionsDir := filepath.Join(home, "sessions")
+	archiveDir := filepath.Join(home, "archived_sessions")
+	env := setupTestEnv(t, WithCodexDirs([]string{sessionsDir, archiveDir}))
+
+	archived := "a1b2c3d4-1111-2222-3333-444455556666"
+	pruned := "b1b2c3d4-1111-2222-3333-444455556666"
+	rollout := func(uuid, prompt string) string {
+		return testjsonl.NewSessionBuilder().
+			AddCodexMeta(tsEarly, uuid, "/home/user/code/api", "user").
+			AddCodexMessage(tsEarlyS1, "user", prompt).
+			AddCodexMessage(tsEarlyS5, "assistant", "Done.").
+			String()
+	}
+	env.writeSession(t, archiveDir,
+		"rollout-20240115-"+archived+".jsonl",
+		rollout(archived, "Archived prompt"))
+	history := testjsonl.JoinJSONL(
+		`{"session_id":"`+archived+`","ts":1705300000,"text":"Archived prompt"}`,
+		`{"session_id":"`+pruned+`","ts":1705300100,"text":"First pruned"}`,
+		`{"session_id":"`+pruned+`","ts":1705300200,"text":"Second pruned"}`,
+	)
+	historyPath := env.writeSession(
+		t, home, parser.CodexHistoryFileName, history,
+	)
+
+	env.engine.SyncAll(nil)
+
+	assertMessageContent(t, env.db, "codex:"+archived,
+		"Archived prompt", "Done.")
+	assertMessageContent(t, env.db, "codex:"+pruned,
+		"First pruned", "Second pruned")
+	full, err := env.db.GetSessionFull(context.Background(), "codex:"+pruned)
+	if err != nil || full == nil {
+		t.Fatalf("GetSessionFull: %v", err)
+	}
+	if full.FilePath == nil || *full.FilePath != historyPath {
+		t.Errorf("file_path = %v, want %s", full.FilePath, historyPath)
+	}
+
+	// A rollout found later supersedes the history copy, and
+	// further prompts in history.jsonl leave it alone.
+	rolloutPath := env.writeCodexSession(t, filepath.Join("2024", "01", "16"),
+		"rollout-20240116-"+pruned+".jsonl",
+		rollout(pruned, "First pruned"))
+	env.engine.SyncAll(nil)
+	assertMessageContent(t, env.db, "codex:"+pruned,
+		"First pruned", "Done.")
+
+	// That holds after Codex prunes the rollout again.
+	if err := os.Remove(rolloutPath); err != nil {
+		t.Fatal(err)
+	}
+	env.writeSession(t, home, parser.CodexHistoryFileName, history+
+		testjsonl.JoinJSONL(
+			`{"session_id":"`+pruned+`","ts":1705300300,"text":"Third"}`,
+		))
+	env.engine.SyncPaths([]string{historyPath})
+	assertMessageContent(t, env.db, "codex:"+pruned,
+		"First pruned", "Done.")
+}
+
+func TestSyncGooseSession(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test")
+	}
+
+	dir := t.TempDir()
+	database := dbtest.OpenTestDB(t)
+	engine := sync.NewEngine(database, sync.EngineConfig{
+		AgentDirs: map[parser.AgentType][]string{
+			parser.AgentGoose: {dir},
+		},
+		Machine: "local",
+	})
+
+	path := filepath.Join(dir, "20250101_100000.jsonl")
+	content := `{"working_dir":"/home/user/webapp","description":"Run the tests"}
+{"role":"user","created":1735725600,"content":[{"type":"text","text":"run the tests"}]}
+{"role":"assistant","created":1735725610,"content":[{"type":"toolRequest","id":"c1","toolCall":{"status":"success","value":{"name":"developer__shell","arguments":{"command":"go test ./..."}}}}]}
+{"role":"user","created":1735725620,"content":[{"type":"toolResponse","id":"c1","toolResult":{"status":"success","value":[{"type":"text","text":"ok"}]}}]}
+{"role":"assistant","created":1735725630,"content":[{"type":"text","text":"All tests pass."}]}
+`
+	dbtest.WriteTestFile(t, path, []byte(content))
+	engine.SyncAll(nil)
+
+	ctx := context.Background()
+	sess, err := database.GetSession(ctx, "goose:20250101_100000")
+	if err != nil || sess == nil {
+		t.Fatalf("GetSession: %v, %v", sess, err)
+	}
+	if sess.Project != "webapp" || sess.Agent != "goose" ||
+		sess.MessageCount != 3 {
+		t.Errorf("session = %+v", sess)
+	}
+	if got := engine.FindSourceFile(sess.ID); got != path {
+		t.Errorf("FindSourceFile = %q, want %q", got, path)
+	}
+	msgs, err := database.GetAllMessages(ctx, sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 3 || len(msgs[1].ToolCalls) != 1 {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	if tc := msgs[1].ToolCalls[0]; tc.Category != "Bash" ||
+		tc.ResultContentLength != 2 {
+		t.Errorf("tool call = %+v", tc)
+	}
+}
@@ -4,6 +4,7 @@ package sync
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/wesm/agentsview/internal/db"
@@ -410,3 +411,36 @@ func TestBlockedCategorySet(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkerCount(t *testing.T) {
+	if got := (&Engine{workers: 24}).workerCount(); got != 24 {
+		t.Errorf("configured workerCount = %d, want 24", got)
+	}
+	got := (&Engine{}).workerCount()
+	if got < 2 || got > maxWorkers {
+		t.Errorf(
+			"default workerCount = %d, want 2..%d",
+			got, maxWorkers,
+		)
+	}
+}
+
+func TestQuarantineBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{5, 16 * time.Minute},
+		{11, 1024 * time.Minute},
+		{12, quarantineMaxBackoff},
+		{100, quarantineMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := quarantineBackoff(tt.attempts); got != tt.want {
+			t.Errorf("quarantineBackoff(%d) = %v, want %v",
+				tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package sync
 
 import (
 	"crypto/sha256"
+	"encoding"
 	"fmt"
 	"io"
 	"os"
@@ -29,3 +30,60 @@ func ComputeFileHash(path string) (string, error) {
 	}
 	return hash, nil
 }
+
+// ComputePrefixHash returns the SHA-256 hex digest of the first
+// n bytes of the file at path. It fails if the file is shorter
+// than n, since the result would not be a prefix hash.
+func ComputePrefixHash(path string, n int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	copied, err := io.CopyN(h, f, n)
+	if err != nil {
+		return "", fmt.Errorf(
+			"hashing %s: read %d of %d bytes: %w",
+			path, copied, n, err,
+		)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// hashRange extends a SHA-256 digest with bytes from to of the
+// file at path. state is the digest's marshaled state after the
+// bytes before from, or nil to start a new digest at 0. It
+// returns the hex digest and the state after to, so a stored
+// hash can follow a growing file without rereading it.
+func hashRange(
+	path string, state []byte, from, to int64,
+) (string, []byte, error) {
+	h := sha256.New()
+	if state != nil {
+		err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state)
+		if err != nil {
+			return "", nil, fmt.Errorf("restoring hash state: %w", err)
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer f.Close()
+	copied, err := io.Copy(h, io.NewSectionReader(f, from, to-from))
+	if err == nil && copied < to-from {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf(
+			"hashing %s: read %d of %d bytes: %w",
+			path, copied, to-from, err,
+		)
+	}
+	next, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return "", nil, fmt.Errorf("saving hash state: %w", err)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), next, nil
+}
@@ -120,3 +120,41 @@ func TestComputeFileHash_ReadError(t *testing.T) {
 	// On most systems, reading a directory fails.
 	requirePathError(t, err)
 }
+
+func TestComputePrefixHash(t *testing.T) {
+	path := createTempFile(t, []byte("hello world"))
+	full, err := ComputeFileHash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ComputePrefixHash(path, 11)
+	if err != nil || got != full {
+		t.Errorf("full-length prefix = %q, %v; want %q", got, err, full)
+	}
+	want, _ := ComputeHash(strings.NewReader("hello"))
+	if got, err := ComputePrefixHash(path, 5); err != nil || got != want {
+		t.Errorf("prefix 5 = %q, %v; want %q", got, err, want)
+	}
+	if _, err := ComputePrefixHash(path, 12); err == nil {
+		t.Error("expected error for prefix longer than file")
+	}
+}
+
+func TestHashRange(t *testing.T) {
+	path := createTempFile(t, []byte("hello world"))
+	full, err := ComputeFileHash(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix, state, err := hashRange(path, nil, 0, 5)
+	if want, _ := ComputePrefixHash(path, 5); err != nil || prefix != want {
+		t.Fatalf("hashRange(0, 5) = %q, %v; want %q", prefix, err, want)
+	}
+	got, _, err := hashRange(path, state, 5, 11)
+	if err != nil || got != full {
+		t.Errorf("extended hash = %q, %v; want %q", got, err, full)
+	}
+	if _, _, err := hashRange(path, state, 5, 12); err == nil {
+		t.Error("expected error for range past end of file")
+	}
+}
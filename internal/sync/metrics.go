@@ -0,0 +1,29 @@
+package sync
+
+import "github.com/wesm/agentsview/internal/metrics"
+
+// Ingestion metrics exposed on /metrics. A stalled watcher or
+// a parser broken by an upstream format change shows up as a
+// flat files_parsed rate or a rising parse_errors count.
+var (
+	filesParsed = metrics.NewCounter(
+		"agentsview_files_parsed_total",
+		"Session files parsed successfully.", "agent",
+	)
+	parseErrors = metrics.NewCounter(
+		"agentsview_parse_errors_total",
+		"Session files that failed to parse.", "agent",
+	)
+	messagesIndexed = metrics.NewCounter(
+		"agentsview_messages_indexed_total",
+		"Messages written to the database.",
+	)
+	syncDuration = metrics.NewHistogram(
+		"agentsview_sync_duration_seconds",
+		"Duration of sync runs.", nil, "mode",
+	)
+	watcherEvents = metrics.NewCounter(
+		"agentsview_watcher_events_total",
+		"File system events received by the watcher.", "op",
+	)
+)
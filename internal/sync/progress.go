@@ -19,6 +19,9 @@ type Progress struct {
 	SessionsTotal   int    `json:"sessions_total"`
 	SessionsDone    int    `json:"sessions_done"`
 	MessagesIndexed int    `json:"messages_indexed"`
+	// ResumedFiles counts files an interrupted resync had
+	// already finished; they are included in SessionsDone.
+	ResumedFiles int `json:"resumed_files,omitempty"`
 }
 
 // SyncResult describes the outcome of syncing a single session.
@@ -34,7 +37,9 @@ type SyncResult struct {
 // TotalSessions counts discovered files plus OpenCode sessions.
 // Synced counts sessions (one file can produce multiple via fork
 // detection; OpenCode adds sessions directly). Failed counts
-// files with hard parse/stat errors. filesOK counts files that
+// files with hard parse/stat errors. Resumed counts files an
+// interrupted resync had already finished, which are included
+// in TotalSessions but not Synced. filesOK counts files that
 // produced at least one session — used by ResyncAll to compare
 // against Failed on the same unit.
 type SyncStats struct {
@@ -43,6 +48,7 @@ type SyncStats struct {
 	Skipped        int      `json:"skipped"`
 	Failed         int      `json:"failed"`
 	OrphanedCopied int      `json:"orphaned_copied,omitempty"`
+	Resumed        int      `json:"resumed,omitempty"`
 	Warnings       []string `json:"warnings,omitempty"`
 	Aborted        bool     `json:"aborted,omitempty"`
 
@@ -0,0 +1,136 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+const (
+	// quarantineBaseBackoff is the delay before the first
+	// retry of a file that failed to parse. It doubles with
+	// each further failure up to quarantineMaxBackoff.
+	quarantineBaseBackoff = time.Minute
+	quarantineMaxBackoff  = 24 * time.Hour
+)
+
+// quarantineBackoff returns the retry delay after the given
+// number of consecutive failures.
+func quarantineBackoff(attempts int) time.Duration {
+	d := quarantineBaseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= quarantineMaxBackoff {
+			return quarantineMaxBackoff
+		}
+	}
+	return d
+}
+
+// quarantineDue reports whether path is quarantined (ok) and,
+// if so, whether its backoff has elapsed (due). The backoff
+// holds even if the file changed, so an actively written file
+// that keeps failing is not re-parsed on every sync.
+func (e *Engine) quarantineDue(
+	path string, now time.Time,
+) (due, ok bool) {
+	e.quarMu.RLock()
+	f, ok := e.quarantine[path]
+	e.quarMu.RUnlock()
+	if !ok {
+		return false, false
+	}
+	retryAt, err := time.Parse(time.RFC3339, f.NextRetryAt)
+	return err != nil || !now.Before(retryAt), true
+}
+
+// quarantineFile records a parse failure for path and
+// schedules its next retry. Only the first failure is logged
+// as a warning; repeats are logged at debug level.
+func (e *Engine) quarantineFile(
+	path string, agent parser.AgentType, parseErr error,
+) {
+	now := time.Now().UTC()
+	e.quarMu.Lock()
+	f, ok := e.quarantine[path]
+	if !ok {
+		f = db.QuarantinedFile{
+			Path:          path,
+			FirstFailedAt: now.Format(time.RFC3339),
+		}
+	}
+	f.Agent = string(agent)
+	f.Error = parseErr.Error()
+	f.Attempts++
+	f.LastFailedAt = now.Format(time.RFC3339)
+	f.NextRetryAt = now.Add(
+		quarantineBackoff(f.Attempts),
+	).Format(time.RFC3339)
+	e.quarantine[path] = f
+	e.quarMu.Unlock()
+
+	if err := e.db.UpsertQuarantinedFile(f); err != nil {
+		e.log.Warn("persisting quarantine", "path", path, "err", err)
+	}
+
+	if f.Attempts == 1 {
+		e.log.Warn("sync error, quarantining file",
+			"path", path, "err", parseErr,
+			"retry_at", f.NextRetryAt,
+		)
+	} else {
+		e.log.Debug("sync error, file still quarantined",
+			"path", path, "err", parseErr,
+			"attempts", f.Attempts, "retry_at", f.NextRetryAt,
+		)
+	}
+}
+
+// releaseQuarantine removes path from quarantine after it
+// parsed successfully.
+func (e *Engine) releaseQuarantine(path string) {
+	e.quarMu.Lock()
+	_, ok := e.quarantine[path]
+	delete(e.quarantine, path)
+	e.quarMu.Unlock()
+	if !ok {
+		return
+	}
+	if err := e.db.DeleteQuarantinedFile(path); err != nil {
+		e.log.Warn("releasing quarantine", "path", path, "err", err)
+	}
+	e.log.Info("quarantined file parsed", "path", path)
+}
+
+// RetryQuarantined re-parses quarantined files now, ignoring
+// their backoff. With no paths, every
+// quarantined file is retried. Files that fail again stay
+// quarantined with a longer backoff. It returns the number
+// of files retried.
+func (e *Engine) RetryQuarantined(paths []string) int {
+	e.quarMu.Lock()
+	if len(paths) == 0 {
+		for p := range e.quarantine {
+			paths = append(paths, p)
+		}
+	}
+	retry := make([]string, 0, len(paths))
+	for _, p := range paths {
+		f, ok := e.quarantine[p]
+		if !ok {
+			continue
+		}
+		f.NextRetryAt = ""
+		e.quarantine[p] = f
+		retry = append(retry, p)
+	}
+	e.quarMu.Unlock()
+
+	if len(retry) == 0 {
+		return 0
+	}
+
+	e.SyncPaths(retry)
+	return len(retry)
+}
@@ -0,0 +1,168 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// Shadow comparison statuses for a session.
+const (
+	ShadowChanged = "changed" // stored and reparsed shapes differ
+	ShadowDropped = "dropped" // stored, but the reparse lost it
+	ShadowNew     = "new"     // only the reparse produced it
+)
+
+// ShadowDiff is one session whose reparse differs from what is
+// stored. Stored is nil for new sessions and Shadow is nil for
+// dropped ones.
+type ShadowDiff struct {
+	SessionID string           `json:"session_id"`
+	Status    string           `json:"status"`
+	Stored    *db.SessionShape `json:"stored,omitempty"`
+	Shadow    *db.SessionShape `json:"shadow,omitempty"`
+}
+
+// ShadowError is a sampled file the current parser failed on.
+type ShadowError struct {
+	Path  string `json:"path"`
+	Agent string `json:"agent"`
+	Error string `json:"error"`
+}
+
+// ShadowReport compares a reparse of sampled files with the
+// stored data.
+type ShadowReport struct {
+	FilesDiscovered int           `json:"files_discovered"`
+	FilesSampled    int           `json:"files_sampled"`
+	Sessions        int           `json:"sessions"`
+	Unchanged       int           `json:"unchanged"`
+	Diffs           []ShadowDiff  `json:"diffs"`
+	Errors          []ShadowError `json:"errors"`
+}
+
+// Shadow reparses up to sample discovered files (all of them
+// when sample is zero or negative) into a scratch database and
+// compares per-session message, tool call, and skipped-record
+// counts with the stored sessions from the same files. The
+// stored database is not modified, so a parser change can be
+// checked before a full resync commits it.
+func (e *Engine) Shadow(
+	ctx context.Context, sample int,
+) (ShadowReport, error) {
+	var files []parser.DiscoveredFile
+	for _, def := range parser.Registry {
+		if !def.FileBased || def.DiscoverFunc == nil {
+			continue
+		}
+		for _, d := range e.agentDirs[def.Type] {
+			files = append(files, parser.Discover(def.Type, d)...)
+		}
+	}
+	report := ShadowReport{
+		FilesDiscovered: len(files),
+		Diffs:           []ShadowDiff{},
+		Errors:          []ShadowError{},
+	}
+	if sample > 0 && len(files) > sample {
+		rand.Shuffle(len(files), func(i, j int) {
+			files[i], files[j] = files[j], files[i]
+		})
+		files = files[:sample]
+	}
+	report.FilesSampled = len(files)
+	if len(files) == 0 {
+		return report, nil
+	}
+
+	dir, err := os.MkdirTemp("", "agentsview-shadow-*")
+	if err != nil {
+		return ShadowReport{}, fmt.Errorf("creating shadow dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	shadowDB, err := db.Open(filepath.Join(dir, "shadow.db"))
+	if err != nil {
+		return ShadowReport{}, fmt.Errorf("opening shadow db: %w", err)
+	}
+	defer shadowDB.Close()
+
+	// A bare engine over the scratch database: no skip cache,
+	// quarantine, or write callbacks carry over, so every
+	// sampled file is parsed and stored the way a resync would.
+	shadow := &Engine{
+		db:                      shadowDB,
+		agentDirs:               e.agentDirs,
+		machine:                 e.machine,
+		blockedResultCategories: e.blockedResultCategories,
+		workers:                 e.workers,
+		skipCache:               map[string]int64{},
+		quarantine:              map[string]db.QuarantinedFile{},
+		tails:                   map[string]*claudeTail{},
+	}
+	shadow.collectAndBatch(shadow.startWorkers(context.Background(), files), len(files), nil)
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.Path
+	}
+	stored, err := e.db.GetSessionShapes(ctx, paths)
+	if err != nil {
+		return ShadowReport{}, err
+	}
+	reparsed, err := shadowDB.GetSessionShapes(ctx, paths)
+	if err != nil {
+		return ShadowReport{}, err
+	}
+	failed, err := shadowDB.LoadQuarantine()
+	if err != nil {
+		return ShadowReport{}, err
+	}
+	for _, f := range failed {
+		report.Errors = append(report.Errors, ShadowError{
+			Path: f.Path, Agent: f.Agent, Error: f.Error,
+		})
+	}
+	sort.Slice(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Path < report.Errors[j].Path
+	})
+
+	ids := map[string]bool{}
+	for id := range stored {
+		ids[id] = true
+	}
+	for id := range reparsed {
+		ids[id] = true
+	}
+	report.Sessions = len(ids)
+	for id := range ids {
+		s, inStored := stored[id]
+		r, inShadow := reparsed[id]
+		d := ShadowDiff{SessionID: id}
+		switch {
+		case !inShadow:
+			d.Status, d.Stored = ShadowDropped, &s
+		case !inStored:
+			d.Status, d.Shadow = ShadowNew, &r
+		case s != r:
+			d.Status, d.Stored, d.Shadow = ShadowChanged, &s, &r
+		default:
+			report.Unchanged++
+			continue
+		}
+		report.Diffs = append(report.Diffs, d)
+	}
+	sort.Slice(report.Diffs, func(i, j int) bool {
+		a, b := report.Diffs[i], report.Diffs[j]
+		if a.Status != b.Status {
+			return a.Status < b.Status
+		}
+		return a.SessionID < b.SessionID
+	})
+	return report, nil
+}
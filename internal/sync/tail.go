@@ -0,0 +1,227 @@
+package sync
+
+import (
+	"os"
+	"time"
+
+	"github.com/wesm/agentsview/internal/db"
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+// tailMaxAge is how long after its file was last written a
+// Claude tail is kept. Tails serve sessions that are still
+// being written; an older file is parsed in full if it changes.
+const tailMaxAge = time.Hour
+
+// claudeTail is where the engine stopped parsing a Claude Code
+// session file, kept so that a file which only grew is parsed
+// from there on the next sync instead of from the start, which
+// takes seconds for long sessions.
+type claudeTail struct {
+	sessionID string
+	parse     *parser.ClaudeTail
+	mtime     time.Time
+	// hash is the stored file_hash of the parsed bytes, and
+	// hashState the digest's state after them.
+	hash      string
+	hashState []byte
+	// msgCount and userCount are the session's stored message
+	// counts, and origins the user origins of its stored
+	// messages. They are set once the session is written.
+	msgCount, userCount int
+	origins             map[string]int
+}
+
+// newTail returns a tail for a Claude file just parsed in full,
+// or nil when the parser gave none or the file is not recent.
+func (e *Engine) newTail(
+	path string, info os.FileInfo,
+	results []parser.ParseResult, parsed *parser.ClaudeTail,
+) *claudeTail {
+	if parsed == nil || time.Since(info.ModTime()) > tailMaxAge {
+		return nil
+	}
+	hash, state, err := hashRange(path, nil, 0, parsed.Offset)
+	if err != nil {
+		return nil
+	}
+	return &claudeTail{
+		sessionID: results[0].Session.ID,
+		parse:     parsed,
+		mtime:     info.ModTime(),
+		hash:      hash,
+		hashState: state,
+	}
+}
+
+// appendClaude parses only the lines appended to a Claude
+// session file since the sync that left its tail. It reports
+// false, dropping the tail, when the file must be parsed in
+// full: no tail is held, the stored session no longer matches
+// it, or the file changed other than by appending lines that
+// continue the session.
+func (e *Engine) appendClaude(
+	file parser.DiscoveredFile, info os.FileInfo,
+) (processResult, bool) {
+	e.tailMu.Lock()
+	t := e.tails[file.Path]
+	e.tailMu.Unlock()
+	if t == nil {
+		return processResult{}, false
+	}
+	res, ok := e.appendTail(t, file, info)
+	if !ok {
+		e.dropTail(file.Path)
+	}
+	return res, ok
+}
+
+func (e *Engine) appendTail(
+	t *claudeTail, file parser.DiscoveredFile, info os.FileInfo,
+) (processResult, bool) {
+	offset := t.parse.Offset
+	if info.Size() <= offset {
+		return processResult{}, false
+	}
+	path, size, hash, ok := e.db.GetSessionFileHash(t.sessionID)
+	if !ok || path != file.Path || size != offset || hash != t.hash {
+		return processResult{}, false
+	}
+	app, ok, err := t.parse.Append(file.Path)
+	if err != nil {
+		e.log.Debug("parse appended lines",
+			"path", file.Path, "err", err)
+		return processResult{}, false
+	}
+	if !ok {
+		return processResult{}, false
+	}
+	if app.Tail.Offset == offset {
+		// Only a partial line so far; wait for the rest.
+		return processResult{skip: true}, true
+	}
+
+	hash, state, err := hashRange(
+		file.Path, t.hashState, offset, app.Tail.Offset,
+	)
+	if err != nil {
+		return processResult{}, false
+	}
+	app.Result.Session.File.Hash = hash
+	results := []parser.ParseResult{app.Result}
+	parser.InferRelationshipTypes(results)
+
+	next := *t
+	next.parse = app.Tail
+	next.mtime = info.ModTime()
+	next.hash = hash
+	next.hashState = state
+	return processResult{
+		results:    results,
+		tail:       &next,
+		appended:   true,
+		carried:    app.Carried,
+		uncredited: app.Uncredited,
+	}, true
+}
+
+// keepTail records pw's tail once its session s is stored, so
+// the next sync of the file starts where this one stopped.
+// Tails of files idle for tailMaxAge are dropped along the way.
+func (e *Engine) keepTail(pw pendingWrite, s db.Session) {
+	t := pw.tail
+	if t == nil {
+		return
+	}
+	origins := parser.CountUserOrigins(parser.ParsedSession{}, pw.msgs)
+	if pw.appended {
+		for origin, n := range t.origins {
+			origins[origin] += n
+		}
+	}
+	t.msgCount, t.userCount = s.MessageCount, s.UserMessageCount
+	t.origins = origins
+
+	e.tailMu.Lock()
+	defer e.tailMu.Unlock()
+	for path, old := range e.tails {
+		if time.Since(old.mtime) > tailMaxAge {
+			delete(e.tails, path)
+		}
+	}
+	e.tails[pw.sess.File.Path] = t
+}
+
+// dropTail forgets where parsing of the file at path stopped.
+func (e *Engine) dropTail(path string) {
+	e.tailMu.Lock()
+	delete(e.tails, path)
+	e.tailMu.Unlock()
+}
+
+// writeAppended stores a session parsed from lines appended to
+// its file: the new messages are added after the stored ones,
+// earlier tool calls take the results and subagent links the
+// lines carried, earlier messages whose usage moved to a new
+// one are cleared, and the session's counts are extended.
+func (e *Engine) writeAppended(pw pendingWrite) {
+	msgs := toDBMessages(pw, e.blockedResultCategories)
+	s := toDBSession(pw)
+	total, user := postFilterCounts(msgs)
+	s.MessageCount = pw.tail.msgCount + total
+	s.UserMessageCount = pw.tail.userCount + user
+	if err := e.db.UpsertSession(s); err != nil {
+		e.log.Error("upsert session", "session", s.ID, "err", err)
+		e.dropTail(pw.sess.File.Path)
+		return
+	}
+	if err := e.db.AppendSessionMessages(
+		s.ID, msgs, e.carriedCalls(pw), uncreditedUsage(pw),
+	); err != nil {
+		e.log.Error(
+			"append messages",
+			"session", s.ID, "err", err,
+		)
+		e.dropTail(pw.sess.File.Path)
+		return
+	}
+	messagesIndexed.Add(float64(len(msgs)))
+	e.writeHookEvents(pw)
+	e.writeCommands(pw)
+	e.writeTodos(pw)
+	e.writeEnv(pw)
+	e.writeUnknownRecords(pw)
+	e.writeUserOrigins(pw)
+	e.writeQuality(s.ID)
+	e.writeLanguage(s.ID)
+	e.keepTail(pw, s)
+	e.sessionWritten(s)
+}
+
+// uncreditedUsage returns pw's earlier messages whose usage
+// moved to an appended message, with their usage cleared.
+func uncreditedUsage(pw pendingWrite) []db.Message {
+	out := make([]db.Message, len(pw.uncredited))
+	for i, ord := range pw.uncredited {
+		out[i] = db.Message{SessionID: pw.sess.ID, Ordinal: ord}
+	}
+	return out
+}
+
+// carriedCalls pairs the earlier tool calls pw carried with the
+// results in its appended messages, for updating stored calls.
+func (e *Engine) carriedCalls(pw pendingWrite) []db.ToolCall {
+	if len(pw.carried) == 0 {
+		return nil
+	}
+	var results []db.ToolResult
+	for _, m := range pw.msgs {
+		results = append(results, convertToolResults(m.ToolResults)...)
+	}
+	msgs := []db.Message{
+		{ToolCalls: convertToolCalls(pw.sess.ID, pw.carried)},
+		{ToolResults: results},
+	}
+	pairToolResults(msgs, e.blockedResultCategories)
+	return msgs[0].ToolCalls
+}
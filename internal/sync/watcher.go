@@ -3,13 +3,15 @@ package sync
 import (
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/wesm/agentsview/internal/parser"
 )
 
 // Watcher uses fsnotify to watch session directories for changes
@@ -19,6 +21,8 @@ type Watcher struct {
 	watcher  *fsnotify.Watcher
 	debounce time.Duration
 	pending  map[string]time.Time
+	roots    []string        // directories passed to WatchRecursive
+	lost     map[string]bool // roots deleted or moved away
 	mu       sync.Mutex
 	stop     chan struct{}
 	done     chan struct{}
@@ -45,6 +49,7 @@ func NewWatcher(
 		watcher:  fsw,
 		debounce: debounce,
 		pending:  make(map[string]time.Time),
+		lost:     make(map[string]bool),
 		stop:     make(chan struct{}),
 		done:     make(chan struct{}),
 		now:      time.Now,
@@ -53,24 +58,54 @@ func NewWatcher(
 }
 
 // WatchRecursive walks a directory tree and adds all
-// subdirectories to the watch list. Returns the number
-// of directories watched and unwatched (failed to add).
+// subdirectories to the watch list, skipping subtrees excluded
+// by ignore files. Returns the number of directories watched
+// and unwatched (failed to add).
+//
+// The root is remembered: if it is later deleted or moved away,
+// the watcher polls for it and, once it is back, watches it
+// again and reports every file in it as changed.
 func (w *Watcher) WatchRecursive(root string) (watched int, unwatched int, err error) {
-	err = filepath.WalkDir(root,
+	root = filepath.Clean(root)
+	w.mu.Lock()
+	if !slices.Contains(w.roots, root) {
+		w.roots = append(w.roots, root)
+	}
+	w.mu.Unlock()
+	watched, unwatched, _, err = w.watchTree(root, root)
+	return watched, unwatched, err
+}
+
+// watchTree watches dir and the directories below it, skipping
+// subtrees excluded by ignore files in root, and returns the
+// regular files it passed.
+func (w *Watcher) watchTree(
+	root, dir string,
+) (watched, unwatched int, files []string, err error) {
+	ignore := parser.NewIgnoreMatcher(root)
+	err = filepath.WalkDir(dir,
 		func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return nil // skip inaccessible dirs
 			}
+			if ignore.Ignored(path) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
 			if d.IsDir() {
 				if addErr := w.watcher.Add(path); addErr != nil {
 					unwatched++
 				} else {
 					watched++
 				}
+			} else if d.Type().IsRegular() {
+				files = append(files, path)
 			}
 			return nil
 		})
-	return watched, unwatched, err
+	return watched, unwatched, files, err
 }
 
 // Start begins processing file events in a goroutine.
@@ -107,37 +142,132 @@ func (w *Watcher) loop() {
 			if !ok {
 				return
 			}
-			log.Printf("watcher error: %v", err)
+			slog.Error("watcher error", "err", err)
 
 		case <-ticker.C:
+			w.recoverRoots()
 			w.flush()
 		}
 	}
 }
 
 // handleEvent processes a single fsnotify event, auto-watching
-// newly created directories and recording pending changes.
+// newly created directories, dropping watches on removed ones,
+// and recording pending changes.
 func (w *Watcher) handleEvent(event fsnotify.Event) {
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		if event.Op&fsnotify.Remove != 0 {
+			watcherEvents.Inc("remove")
+		} else {
+			watcherEvents.Inc("rename")
+		}
+		w.dropWatches(event.Name)
+	}
 	if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
 		return
 	}
 
+	var files []string
 	if event.Op&fsnotify.Create != 0 {
-		w.watchIfDir(event.Name)
+		watcherEvents.Inc("create")
+		files = w.watchIfDir(event.Name)
+	} else {
+		watcherEvents.Inc("write")
 	}
 
 	w.mu.Lock()
-	w.pending[event.Name] = w.now()
+	now := w.now()
+	w.pending[event.Name] = now
+	for _, f := range files {
+		w.pending[f] = now
+	}
 	w.mu.Unlock()
 }
 
-// watchIfDir adds a path to the watch list if it is a directory.
-func (w *Watcher) watchIfDir(path string) {
+// watchIfDir watches a newly created directory and everything
+// below it, returning the files already inside. A directory
+// recreated or moved in with contents has files whose own
+// events were missed, so they are rescanned.
+func (w *Watcher) watchIfDir(path string) []string {
 	info, err := os.Stat(path)
 	if err != nil || !info.IsDir() {
-		return
+		return nil
+	}
+	root := w.rootOf(path)
+	if root == "" {
+		root = path
 	}
-	_ = w.watcher.Add(path)
+	_, _, files, _ := w.watchTree(root, path)
+	return files
+}
+
+// dropWatches removes the watches on a deleted or moved-away
+// directory and its subdirectories. A moved directory's watches
+// follow it under the old names, so leaving them would report
+// events for paths that no longer exist. A root that goes
+// away is marked lost for recoverRoots.
+func (w *Watcher) dropWatches(path string) {
+	path = filepath.Clean(path)
+	for _, p := range w.watcher.WatchList() {
+		if p == path || isUnderDir(path, p) {
+			_ = w.watcher.Remove(p)
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, r := range w.roots {
+		if r == path || isUnderDir(path, r) {
+			w.lost[r] = true
+		}
+	}
+}
+
+// recoverRoots re-watches lost roots that exist again and
+// queues every file in them, since the files were written while
+// no watch could report them.
+func (w *Watcher) recoverRoots() {
+	w.mu.Lock()
+	var back []string
+	for r := range w.lost {
+		if info, err := os.Stat(r); err == nil && info.IsDir() {
+			back = append(back, r)
+			delete(w.lost, r)
+		}
+	}
+	w.mu.Unlock()
+
+	for _, r := range back {
+		watched, _, files, _ := w.watchTree(r, r)
+		slog.Info(
+			"watcher: root recreated, rescanning",
+			"root", r, "dirs", watched, "files", len(files),
+		)
+		w.mu.Lock()
+		now := w.now()
+		for _, f := range files {
+			w.pending[f] = now
+		}
+		w.mu.Unlock()
+	}
+}
+
+// rootOf returns the watched root containing path, or "".
+func (w *Watcher) rootOf(path string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	best := ""
+	for _, r := range w.roots {
+		if (r == path || isUnderDir(r, path)) && len(r) > len(best) {
+			best = r
+		}
+	}
+	return best
+}
+
+// isUnderDir reports whether path is strictly below dir.
+func isUnderDir(dir, path string) bool {
+	_, ok := isUnder(dir, path)
+	return ok
 }
 
 func (w *Watcher) flush() {
@@ -161,8 +291,10 @@ func (w *Watcher) flush() {
 	w.mu.Unlock()
 
 	if len(ready) > 0 {
-		log.Printf("watcher: %d file(s) changed, triggering sync",
-			len(ready))
+		slog.Info(
+			"watcher: files changed, triggering sync",
+			"count", len(ready),
+		)
 		w.onChange(ready)
 	}
 }
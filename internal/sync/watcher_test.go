@@ -118,6 +118,36 @@ func TestWatcherAutoWatchesNewDirs(t *testing.T) {
 	}
 }
 
+func TestWatchRecursiveSkipsIgnoredDirs(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"kept/a", "client-x/a"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatalf("MkdirAll: %v", err)
+		}
+	}
+	if err := os.WriteFile(
+		filepath.Join(dir, ".agentsviewignore"), []byte("client-*\n"), 0o644,
+	); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	w, err := NewWatcher(time.Second, func([]string) {})
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	defer w.watcher.Close()
+
+	watched, _, err := w.WatchRecursive(dir)
+	if err != nil {
+		t.Fatalf("WatchRecursive: %v", err)
+	}
+	if watched != 3 {
+		t.Errorf("watched = %d, want 3 (root, kept, kept/a)", watched)
+	}
+	if slices.Contains(w.watcher.WatchList(), filepath.Join(dir, "client-x")) {
+		t.Error("ignored directory is watched")
+	}
+}
+
 func TestWatcherStopIsClean(t *testing.T) {
 	w, _ := startTestWatcherNoCleanup(t, func(_ []string) {}, 50*time.Millisecond)
 
@@ -297,3 +327,122 @@ func TestNewWatcher_NilOnChange(t *testing.T) {
 		t.Errorf("expected error message to contain %q, got %q", expectedMsg, err.Error())
 	}
 }
+
+// waitForPath waits for an onChange batch containing path.
+func waitForPath(t *testing.T, pathsCh <-chan []string, path string) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case paths := <-pathsCh:
+			if slices.Contains(paths, path) {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("timed out waiting for change to %s", path)
+		}
+	}
+}
+
+func TestWatcherRewatchesRecreatedDir(t *testing.T) {
+	pathsCh := make(chan []string, 10)
+	w, dir := startTestWatcher(t, func(paths []string) {
+		pathsCh <- paths
+	})
+
+	wt := filepath.Join(dir, "worktree")
+	if err := os.Mkdir(wt, 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	pollUntil(t, func() bool {
+		return slices.Contains(w.watcher.WatchList(), wt)
+	})
+	if err := os.RemoveAll(wt); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	pollUntil(t, func() bool {
+		return !slices.Contains(w.watcher.WatchList(), wt)
+	})
+
+	// The nested file is written before the new directories can
+	// be watched, so only the rescan reports it.
+	nested := filepath.Join(wt, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	path := filepath.Join(nested, "s.jsonl")
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	waitForPath(t, pathsCh, path)
+	pollUntil(t, func() bool {
+		return slices.Contains(w.watcher.WatchList(), nested)
+	})
+}
+
+func TestWatcherDropsWatchesOnRename(t *testing.T) {
+	w, dir := startTestWatcher(t, func([]string) {})
+
+	oldDir := filepath.Join(dir, "old")
+	if err := os.MkdirAll(filepath.Join(oldDir, "sub"), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	pollUntil(t, func() bool {
+		return slices.Contains(
+			w.watcher.WatchList(), filepath.Join(oldDir, "sub"),
+		)
+	})
+
+	newDir := filepath.Join(dir, "new")
+	if err := os.Rename(oldDir, newDir); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	pollUntil(t, func() bool {
+		list := w.watcher.WatchList()
+		return slices.Contains(list, filepath.Join(newDir, "sub")) &&
+			!slices.Contains(list, oldDir) &&
+			!slices.Contains(list, filepath.Join(oldDir, "sub"))
+	})
+}
+
+func TestWatcherRecoversDeletedRoot(t *testing.T) {
+	pathsCh := make(chan []string, 10)
+	root := filepath.Join(t.TempDir(), "root")
+	if err := os.Mkdir(root, 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	w, err := NewWatcher(20*time.Millisecond, func(paths []string) {
+		pathsCh <- paths
+	})
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	if _, _, err := w.WatchRecursive(root); err != nil {
+		t.Fatalf("WatchRecursive: %v", err)
+	}
+	w.Start()
+	t.Cleanup(w.Stop)
+
+	if err := os.RemoveAll(root); err != nil {
+		t.Fatalf("RemoveAll: %v", err)
+	}
+	pollUntil(t, func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.lost[root]
+	})
+
+	path := filepath.Join(root, "proj", "s.jsonl")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	waitForPath(t, pathsCh, path)
+	pollUntil(t, func() bool {
+		return slices.Contains(
+			w.watcher.WatchList(), filepath.Dir(path),
+		)
+	})
+}
@@ -0,0 +1,133 @@
+// Package synchook forwards sessions stored by sync to
+// configured external receivers, such as a webhook URL or an
+// indexing script.
+package synchook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+)
+
+// queueSize bounds the sessions waiting for delivery. A full
+// resync can write thousands of sessions at once; past this
+// many, sessions are dropped rather than stalling sync.
+const queueSize = 1024
+
+// EventHeader names the event in webhook requests and in the
+// AGENTSVIEW_EVENT variable for commands.
+const (
+	EventHeader        = "X-Agentsview-Event"
+	EventSessionStored = "session.stored"
+)
+
+// Dispatcher delivers stored sessions to hooks in the
+// background, one at a time and in the order they were stored.
+type Dispatcher struct {
+	hooks  []config.SyncHook
+	queue  chan db.Session
+	client *http.Client
+}
+
+// New returns a dispatcher for hooks. Call Run to start
+// delivering.
+func New(hooks []config.SyncHook) *Dispatcher {
+	return &Dispatcher{
+		hooks:  hooks,
+		queue:  make(chan db.Session, queueSize),
+		client: &http.Client{},
+	}
+}
+
+// Enqueue schedules s for delivery without blocking. It is
+// suitable as sync.EngineConfig.OnSessionWritten.
+func (d *Dispatcher) Enqueue(s db.Session) {
+	select {
+	case d.queue <- s:
+	default:
+		slog.Warn("sync hook queue full, dropping session",
+			"session", s.ID)
+	}
+}
+
+// Run delivers queued sessions until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s := <-d.queue:
+			d.deliver(ctx, s)
+		}
+	}
+}
+
+// deliver sends s to every hook. Failures are logged and not
+// retried; the next sync of the session sends it again.
+func (d *Dispatcher) deliver(ctx context.Context, s db.Session) {
+	body, err := json.Marshal(s)
+	if err != nil {
+		slog.Warn("sync hook: encoding session",
+			"session", s.ID, "err", err)
+		return
+	}
+	for _, h := range d.hooks {
+		hctx, cancel := context.WithTimeout(ctx, h.Timeout())
+		if h.URL != "" {
+			err = d.post(hctx, h.URL, body)
+		} else {
+			err = runCommand(hctx, h.Command, s.ID, body)
+		}
+		cancel()
+		if err != nil {
+			slog.Warn("sync hook failed",
+				"session", s.ID, "err", err)
+		}
+	}
+}
+
+func (d *Dispatcher) post(
+	ctx context.Context, url string, body []byte,
+) error {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, url, bytes.NewReader(body),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, EventSessionStored)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// runCommand runs a hook command with the session JSON on
+// stdin and the session ID and event in the environment.
+func runCommand(
+	ctx context.Context, argv []string, sessionID string, body []byte,
+) error {
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(cmd.Environ(),
+		"AGENTSVIEW_EVENT="+EventSessionStored,
+		"AGENTSVIEW_SESSION_ID="+sessionID,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", argv[0], err, out)
+	}
+	return nil
+}
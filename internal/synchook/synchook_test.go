@@ -0,0 +1,96 @@
+package synchook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/config"
+	"github.com/wesm/agentsview/internal/db"
+)
+
+func TestDeliverURL(t *testing.T) {
+	type request struct {
+		event string
+		body  db.Session
+	}
+	got := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			var s db.Session
+			if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+				t.Errorf("decoding body: %v", err)
+			}
+			got <- request{r.Header.Get(EventHeader), s}
+		},
+	))
+	defer srv.Close()
+
+	d := New([]config.SyncHook{{URL: srv.URL, TimeoutSeconds: 5}})
+	d.deliver(context.Background(), db.Session{
+		ID: "s1", Project: "proj", MessageCount: 3,
+	})
+
+	req := <-got
+	if req.event != EventSessionStored {
+		t.Errorf("event header = %q", req.event)
+	}
+	if req.body.ID != "s1" || req.body.Project != "proj" ||
+		req.body.MessageCount != 3 {
+		t.Errorf("body = %+v", req.body)
+	}
+}
+
+func TestDeliverCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	d := New([]config.SyncHook{{
+		Command: []string{
+			"sh", "-c",
+			`{ echo "$AGENTSVIEW_EVENT $AGENTSVIEW_SESSION_ID"; cat; } > "$0"`,
+			out,
+		},
+		TimeoutSeconds: 5,
+	}})
+	d.deliver(context.Background(), db.Session{ID: "s2"})
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, body, _ := strings.Cut(string(data), "\n")
+	if header != "session.stored s2" {
+		t.Errorf("env line = %q", header)
+	}
+	var s db.Session
+	if err := json.Unmarshal([]byte(body), &s); err != nil {
+		t.Fatalf("stdin was not session JSON: %v", err)
+	}
+	if s.ID != "s2" {
+		t.Errorf("stdin session ID = %q", s.ID)
+	}
+}
+
+func TestEnqueueDoesNotBlock(t *testing.T) {
+	d := New(nil)
+	for range queueSize + 10 {
+		d.Enqueue(db.Session{ID: "s"})
+	}
+	if len(d.queue) != queueSize {
+		t.Errorf("queue len = %d, want %d", len(d.queue), queueSize)
+	}
+}
@@ -153,6 +153,28 @@ func CodexMsgJSON(role, text, timestamp string) string {
 	return mustMarshal(m)
 }
 
+// CodexReasoningJSON returns a Codex reasoning response_item
+// with one summary block per text.
+func CodexReasoningJSON(timestamp string, summary ...string) string {
+	blocks := make([]map[string]string, len(summary))
+	for i, text := range summary {
+		blocks[i] = map[string]string{
+			"type": "summary_text",
+			"text": text,
+		}
+	}
+	m := map[string]any{
+		"type":      "response_item",
+		"timestamp": timestamp,
+		"payload": map[string]any{
+			"type":              "reasoning",
+			"summary":           blocks,
+			"encrypted_content": "gAAAAB",
+		},
+	}
+	return mustMarshal(m)
+}
+
 // CodexFunctionCallJSON returns a Codex function_call
 // response_item as a JSON string.
 func CodexFunctionCallJSON(
@@ -403,6 +425,17 @@ type GeminiMsgOpts struct {
 	Thoughts  []GeminiThought
 	ToolCalls []GeminiToolCall
 	Model     string
+	Tokens    *GeminiTokens
+}
+
+// GeminiTokens defines per-message token counts for Gemini
+// test fixtures.
+type GeminiTokens struct {
+	Input    int
+	Output   int
+	Cached   int
+	Thoughts int
+	Tool     int
 }
 
 // GeminiUserMsg builds a Gemini user message object.
@@ -433,6 +466,17 @@ func GeminiAssistantMsg(
 	if opts.Model != "" {
 		m["model"] = opts.Model
 	}
+	if tk := opts.Tokens; tk != nil {
+		m["tokens"] = map[string]int{
+			"input":    tk.Input,
+			"output":   tk.Output,
+			"cached":   tk.Cached,
+			"thoughts": tk.Thoughts,
+			"tool":     tk.Tool,
+			"total": tk.Input + tk.Output +
+				tk.Thoughts + tk.Tool,
+		}
+	}
 	if len(opts.Thoughts) > 0 {
 		var thoughts []map[string]string
 		for _, th := range opts.Thoughts {
@@ -0,0 +1,128 @@
+// Package parse reads the session files of AI coding agents,
+// such as Claude Code and Codex, into sessions and messages.
+// It is the parser agentsview syncs with, exported for Go
+// programs that build their own pipelines instead of running
+// the agentsview binary.
+//
+// The names in this package are its stable API. The types are
+// aliases of agentsview's internal ones, whose other fields and
+// methods may change between releases.
+package parse
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/wesm/agentsview/internal/parser"
+)
+
+type (
+	// Agent identifies the agent that wrote a session.
+	Agent = parser.AgentType
+	// File is a session file found by Discover.
+	File = parser.DiscoveredFile
+	// Result is one session parsed from a file.
+	Result = parser.ParseResult
+	// Session is a parsed session's metadata.
+	Session = parser.ParsedSession
+	// Message is one message of a session.
+	Message = parser.ParsedMessage
+	// ToolCall is a tool invocation in an assistant message.
+	ToolCall = parser.ParsedToolCall
+	// ToolResult is a tool's output, paired to its call by
+	// tool use ID.
+	ToolResult = parser.ParsedToolResult
+)
+
+// Agents with file-based sessions that Discover and ParseFile
+// support.
+const (
+	Claude        = parser.AgentClaude
+	Codex         = parser.AgentCodex
+	Copilot       = parser.AgentCopilot
+	Gemini        = parser.AgentGemini
+	Cursor        = parser.AgentCursor
+	Amp           = parser.AgentAmp
+	VSCodeCopilot = parser.AgentVSCodeCopilot
+	OpenClaw      = parser.AgentOpenClaw
+	Aider         = parser.AgentAider
+	Goose         = parser.AgentGoose
+)
+
+// AgentInfo describes where an agent keeps its sessions.
+type AgentInfo struct {
+	Agent       Agent
+	DisplayName string
+	// DefaultDirs are the session directories under the user's
+	// home directory that agentsview reads by default.
+	DefaultDirs []string
+}
+
+// Agents lists the agents with file-based sessions.
+func Agents() []AgentInfo {
+	var out []AgentInfo
+	for _, def := range parser.Registry {
+		if !def.FileBased {
+			continue
+		}
+		out = append(out, AgentInfo{
+			Agent:       def.Type,
+			DisplayName: def.DisplayName,
+			DefaultDirs: append([]string(nil), def.DefaultDirs...),
+		})
+	}
+	return out
+}
+
+// DefaultDirs returns an agent's default session directories
+// under home that exist.
+func DefaultDirs(agent Agent, home string) []string {
+	def, ok := parser.AgentByType(agent)
+	if !ok {
+		return nil
+	}
+	var dirs []string
+	for _, d := range def.DefaultDirs {
+		dir := filepath.Join(home, d)
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// Discover returns an agent's session files under dir, minus
+// those excluded by .agentsviewignore files.
+func Discover(
+	ctx context.Context, agent Agent, dir string,
+) ([]File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return parser.Discover(agent, dir), nil
+}
+
+// Options configures ParseFile.
+type Options struct {
+	// Machine labels the parsed sessions, as agentsview labels
+	// sessions with the host they were synced from.
+	Machine string
+}
+
+// ParseFile parses a file from Discover. A file holds zero or
+// more sessions; files that are not interactive sessions yield
+// none.
+func ParseFile(
+	ctx context.Context, file File, opts Options,
+) ([]Result, error) {
+	return parser.ParseFile(ctx, file, opts.Machine)
+}
+
+// SetLogger directs parser warnings, such as unreadable
+// timestamps, to l instead of slog.Default. Pass a logger with
+// a discarding handler to silence them.
+func SetLogger(l *slog.Logger) {
+	parser.SetLogger(l)
+}
@@ -0,0 +1,66 @@
+package parse_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wesm/agentsview/internal/testjsonl"
+	"github.com/wesm/agentsview/pkg/parse"
+)
+
+func TestDiscoverAndParseFile(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	projectDir := filepath.Join(dir, "-home-me-my-app")
+	if err := os.MkdirAll(projectDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := testjsonl.NewSessionBuilder().
+		AddClaudeUser("2024-01-01T00:00:00Z", "fix the build").
+		AddClaudeAssistant("2024-01-01T00:00:05Z", "done").
+		String()
+	path := filepath.Join(projectDir, "s1.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := parse.Discover(ctx, parse.Claude, dir)
+	if err != nil || len(files) != 1 || files[0].Path != path {
+		t.Fatalf("Discover = %+v, %v", files, err)
+	}
+	results, err := parse.ParseFile(ctx, files[0], parse.Options{
+		Machine: "laptop",
+	})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("ParseFile = %+v, %v", results, err)
+	}
+	sess := results[0].Session
+	if sess.ID != "s1" || sess.Machine != "laptop" ||
+		sess.Agent != parse.Claude || len(results[0].Messages) != 2 {
+		t.Errorf("session = %+v, %d messages",
+			sess, len(results[0].Messages))
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = parse.ParseFile(cancelled, files[0], parse.Options{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseFile with cancelled ctx: err = %v", err)
+	}
+}
+
+func TestAgents(t *testing.T) {
+	var found bool
+	for _, a := range parse.Agents() {
+		if a.Agent == parse.Codex {
+			found = len(a.DefaultDirs) > 0
+		}
+	}
+	if !found {
+		t.Errorf("Agents() has no Codex with default dirs: %+v",
+			parse.Agents())
+	}
+}